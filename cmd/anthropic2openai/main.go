@@ -0,0 +1,89 @@
+// anthropic2openai translates an Anthropic Messages request on stdin to an
+// OpenAI Chat Completions request on stdout, as the proxy does for a local
+// route, for shell pipelines and test harnesses in other languages.
+//
+// Usage: anthropic2openai [-model NAME] [-max-tokens N] [-transform a,b] [-raw] < request.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/translate"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: anthropic2openai [flags] < anthropic-request.json > openai-request.json\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	model := flag.String("model", "", "backend model name to send (default: the request's model)")
+	maxTokens := flag.Int("max-tokens", 0, "cap max_tokens at N (0 = no cap), like a provider's max_tokens")
+	transform := flag.String("transform", "", "comma-separated request transforms to run, as in a provider's transform: list")
+	raw := flag.Bool("raw", false, "adapt the envelope only, like a raw=openai marker")
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(os.Stdin, os.Stdout, *model, *maxTokens, *transform, *raw); err != nil {
+		fmt.Fprintf(os.Stderr, "anthropic2openai: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(r io.Reader, w io.Writer, model string, maxTokens int, transform string, raw bool) error {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if model == "" {
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.Unmarshal(body, &req)
+		model = req.Model
+	}
+	chain, err := translate.BuildChain(splitList(transform))
+	if err != nil {
+		return err
+	}
+
+	var out []byte
+	if raw {
+		out, err = translate.PassthroughToOpenAI(body, model, maxTokens)
+		chain = translate.NewTransformChain() // request transforms don't run on raw routes
+	} else {
+		out, err = translate.RequestToOpenAI(body, model, maxTokens)
+	}
+	if err != nil {
+		return err
+	}
+	var oaiReq map[string]interface{}
+	if err := json.Unmarshal(out, &oaiReq); err != nil {
+		return err
+	}
+	if err := chain.RunRequest(oaiReq, translate.NewTransformContext(model, "cli")); err != nil {
+		return fmt.Errorf("transform: %w", err)
+	}
+	if out, err = json.Marshal(oaiReq); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", out)
+	return err
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			out = append(out, name)
+		}
+	}
+	return out
+}
@@ -0,0 +1,476 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/proxy"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/router"
+)
+
+// benchURL is where bench requests are sent. The marker keeps them local,
+// so Anthropic is never contacted.
+const benchURL = "https://api.anthropic.com/v1/messages"
+
+// benchScenario is one kind of request the benchmark sends.
+type benchScenario struct {
+	name string
+	body func(maxTokens, contextTokens int) map[string]interface{}
+}
+
+// benchTools are Claude Code-style tool definitions for the tools scenario.
+var benchTools = []map[string]interface{}{
+	benchTool("Read", "Reads a file from the local filesystem.", "file_path"),
+	benchTool("Grep", "Searches file contents with a regular expression.", "pattern", "path"),
+	benchTool("Glob", "Finds files by name pattern.", "pattern"),
+	benchTool("Edit", "Replaces old_string with new_string in a file.", "file_path", "old_string", "new_string"),
+	benchTool("Write", "Writes a file to the local filesystem.", "file_path", "content"),
+	benchTool("Bash", "Runs a shell command.", "command"),
+}
+
+func benchTool(name, desc string, params ...string) map[string]interface{} {
+	props := map[string]interface{}{}
+	for _, p := range params {
+		props[p] = map[string]string{"type": "string"}
+	}
+	return map[string]interface{}{
+		"name":         name,
+		"description":  desc,
+		"input_schema": map[string]interface{}{"type": "object", "properties": props, "required": params},
+	}
+}
+
+var benchScenarios = []benchScenario{
+	{"chat", func(maxTokens, _ int) map[string]interface{} {
+		return benchBody(maxTokens, "Write a Go function that reverses a UTF-8 string, then explain it in two sentences.", nil)
+	}},
+	{"tools", func(maxTokens, _ int) map[string]interface{} {
+		return benchBody(maxTokens, "Find where the HTTP server is started in this repository and open that file.", benchTools)
+	}},
+	{"long", func(maxTokens, contextTokens int) map[string]interface{} {
+		return benchBody(maxTokens, benchContext(contextTokens)+"\n\nSummarize what this file does in three bullet points.", nil)
+	}},
+}
+
+func benchBody(maxTokens int, prompt string, tools []map[string]interface{}) map[string]interface{} {
+	body := map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"max_tokens": maxTokens,
+		"stream":     true,
+		"system":     "You are Claude Code, a coding assistant. Be concise.",
+		"messages":   []map[string]string{{"role": "user", "content": prompt}},
+	}
+	if tools != nil {
+		body["tools"] = tools
+	}
+	return body
+}
+
+// benchContext returns Go-like source of roughly tokens tokens, at about
+// four characters a token.
+func benchContext(tokens int) string {
+	var b strings.Builder
+	b.WriteString("package store\n\n")
+	for i := 0; b.Len() < tokens*4; i++ {
+		fmt.Fprintf(&b, "// Get%d returns the value stored under key %d, or false.\n", i, i)
+		fmt.Fprintf(&b, "func (s *Store) Get%d(ctx context.Context) (string, bool) {\n", i)
+		fmt.Fprintf(&b, "\ts.mu.RLock()\n\tdefer s.mu.RUnlock()\n\tv, ok := s.values[%d]\n\treturn v, ok\n}\n\n", i)
+	}
+	return b.String()
+}
+
+// benchRun is the outcome of one request.
+type benchRun struct {
+	ttft, total  time.Duration
+	outputTokens int
+	toolUse      bool
+	err          error
+}
+
+// benchSummary aggregates one scenario's runs.
+type benchSummary struct {
+	Scenario     string  `json:"scenario"`
+	Runs         int     `json:"runs"`
+	Errors       int     `json:"errors"`
+	AvgTTFTMs    int64   `json:"avg_ttft_ms"`
+	TokensPerSec float64 `json:"output_tokens_per_sec"` // after the first token
+	AvgTotalMs   int64   `json:"avg_total_ms"`
+	ToolUse      int     `json:"tool_use"` // runs that called a tool
+	FirstError   string  `json:"first_error,omitempty"`
+}
+
+func summarize(name string, runs []benchRun) benchSummary {
+	s := benchSummary{Scenario: name, Runs: len(runs)}
+	var ttft, total, gen time.Duration
+	var ok, tokens int
+	for _, r := range runs {
+		if r.err != nil {
+			s.Errors++
+			if s.FirstError == "" {
+				s.FirstError = r.err.Error()
+			}
+			continue
+		}
+		ok++
+		ttft += r.ttft
+		total += r.total
+		gen += r.total - r.ttft
+		tokens += r.outputTokens
+		if r.toolUse {
+			s.ToolUse++
+		}
+	}
+	if ok > 0 {
+		s.AvgTTFTMs = (ttft / time.Duration(ok)).Milliseconds()
+		s.AvgTotalMs = (total / time.Duration(ok)).Milliseconds()
+	}
+	if gen > 0 {
+		s.TokensPerSec = float64(tokens) / gen.Seconds()
+	}
+	return s
+}
+
+// runBench implements `claude-hybrid bench`.
+func runBench(args []string) int {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: claude-hybrid bench --label NAME [flags]
+
+Sends Claude Code-style requests to a label through an in-process proxy and
+reports time to first token, output tokens/sec, tool use and errors per
+scenario, to compare models before routing to them. Built-in scenarios:
+
+  chat   a short coding question
+  tools  a request with Claude Code's file and shell tools, which should
+         call one
+  long   a question about a long source file (--context-tokens)
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	label := fs.String("label", "", "label, alias or label group to benchmark (required)")
+	profile := fs.String("profile", "", "apply this profile from config.yaml")
+	runs := fs.Int("runs", 3, "requests per scenario, sent one at a time")
+	scenarioFlag := fs.String("scenarios", "chat,tools,long", "comma-separated built-in scenarios to run (empty = none)")
+	var files []string
+	fs.Func("request", "also send this Anthropic Messages body as a scenario named after the file (repeatable)", func(s string) error {
+		files = append(files, s)
+		return nil
+	})
+	maxTokens := fs.Int("max-tokens", 512, "max_tokens of the built-in scenarios")
+	contextTokens := fs.Int("context-tokens", 8000, "approximate prompt size of the long scenario")
+	timeout := fs.Duration("timeout", 5*time.Minute, "give up on a request after this long")
+	verbose := fs.Bool("verbose", false, "print the proxy's log to stderr")
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	fs.Parse(args)
+	if *label == "" || *runs < 1 {
+		fs.Usage()
+		return 2
+	}
+
+	var scenarios []benchScenario
+	for _, name := range splitList(*scenarioFlag) {
+		found := false
+		for _, s := range benchScenarios {
+			if s.name == name {
+				scenarios, found = append(scenarios, s), true
+			}
+		}
+		if !found {
+			fmt.Fprintf(os.Stderr, "claude-hybrid: unknown scenario %q (chat, tools, long)\n", name)
+			return 2
+		}
+	}
+	for _, path := range files {
+		s, err := fileScenario(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+			return 2
+		}
+		scenarios = append(scenarios, s)
+	}
+	if len(scenarios) == 0 {
+		fmt.Fprintln(os.Stderr, "claude-hybrid: no scenarios to run")
+		return 2
+	}
+	lc, code := newLabelClient(*label, *profile, *timeout, *verbose)
+	if lc == nil {
+		return code
+	}
+	defer lc.close()
+
+	target := lc.target
+	if !*asJSON {
+		fmt.Printf("Benchmarking %s → %s/%s, %d run(s) per scenario\n\n", *label, target.Provider, target.Model, *runs)
+	}
+	var summaries []benchSummary
+	for _, s := range scenarios {
+		var results []benchRun
+		for i := 0; i < *runs; i++ {
+			results = append(results, benchOnce(lc, s.body(*maxTokens, *contextTokens)))
+		}
+		summaries = append(summaries, summarize(s.name, results))
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(map[string]interface{}{
+			"label": *label, "provider": target.Provider, "model": target.Model, "scenarios": summaries,
+		})
+	} else {
+		printBench(summaries)
+	}
+	for _, s := range summaries {
+		if s.Errors > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// fileScenario loads an Anthropic Messages body from path. It is sent
+// streaming, whatever it says.
+func fileScenario(path string) (benchScenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return benchScenario{}, err
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return benchScenario{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if _, ok := body["messages"]; !ok {
+		return benchScenario{}, fmt.Errorf("%s: not a Messages request (no messages)", path)
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return benchScenario{name, func(int, int) map[string]interface{} {
+		b := make(map[string]interface{}, len(body)+1)
+		for k, v := range body {
+			b[k] = v
+		}
+		b["stream"] = true
+		return b
+	}}, nil
+}
+
+// labelClient sends Messages requests to a label through an in-process
+// proxy with a throwaway CA, so they take the same path as Claude Code's:
+// translation, transforms and label options all apply.
+type labelClient struct {
+	client *http.Client
+	marker string
+	target config.ResolvedModel // the label's first candidate
+	close  func()
+}
+
+// newLabelClient loads config.yaml with profile and starts a proxy for
+// label. On failure it prints the problem and returns nil with the exit
+// code.
+func newLabelClient(label, profile string, timeout time.Duration, verbose bool) (*labelClient, int) {
+	marker, err := proxy.FormatMarker(label)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+		return nil, 2
+	}
+	baseDir := filepath.Dir(defaultCertsDir())
+	cfg, _, _, err := loadConfig(baseDir, profile, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: load config: %v\n", err)
+		return nil, exitConfigError
+	}
+	if cfg == nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: no %s\n", filepath.Join(baseDir, "config.yaml"))
+		return nil, exitConfigError
+	}
+	resolver, err := config.NewModelResolver(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+		return nil, exitConfigError
+	}
+	candidates, err := resolver.Candidates(label)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+		return nil, exitConfigError
+	}
+
+	var logOut io.Writer = io.Discard
+	if verbose {
+		logOut = os.Stderr
+	}
+	rt, err := router.New(cfg, router.WithLogOutput(logOut), router.WithVerbose(verbose))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+		return nil, exitConfigError
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+		return nil, exitProxyStartup
+	}
+	srv := &http.Server{Handler: rt}
+	go srv.Serve(ln)
+
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(rt.CACert())
+	proxyURL := &url.URL{Scheme: "http", Host: ln.Addr().String()}
+	return &labelClient{
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyURL(proxyURL),
+				TLSClientConfig: &tls.Config{RootCAs: pool},
+			},
+		},
+		marker: marker,
+		target: candidates[0],
+		close: func() {
+			srv.Close()
+			rt.Close(context.Background())
+		},
+	}, 0
+}
+
+// send posts body with the routing marker added to its system prompt.
+func (lc *labelClient) send(body map[string]interface{}) (*http.Response, error) {
+	body["system"] = withMarker(body["system"], lc.marker)
+	data, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", benchURL, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("anthropic-version", "2023-06-01")
+	return lc.client.Do(req)
+}
+
+// withMarker puts the routing marker at the start of a system prompt given
+// as a string, a list of blocks, or not at all.
+func withMarker(system interface{}, marker string) interface{} {
+	switch s := system.(type) {
+	case string:
+		return marker + " " + s
+	case []interface{}:
+		return append([]interface{}{map[string]interface{}{"type": "text", "text": marker}}, s...)
+	}
+	return marker
+}
+
+// benchOnce sends body and times its stream.
+func benchOnce(lc *labelClient, body map[string]interface{}) benchRun {
+	start := time.Now()
+	resp, err := lc.send(body)
+	if err != nil {
+		return benchRun{err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return benchRun{err: fmt.Errorf("HTTP %d: %s", resp.StatusCode, apiErrorMessage(resp.Body))}
+	}
+
+	var run benchRun
+	var event string
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if e, ok := strings.CutPrefix(line, "event: "); ok {
+			event = e
+			continue
+		}
+		payload, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		var ev struct {
+			ContentBlock struct {
+				Type string `json:"type"`
+			} `json:"content_block"`
+			Usage struct {
+				OutputTokens int `json:"output_tokens"`
+			} `json:"usage"`
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.Unmarshal([]byte(payload), &ev)
+		switch event {
+		case "content_block_start":
+			if ev.ContentBlock.Type == "tool_use" {
+				run.toolUse = true
+			}
+		case "content_block_delta":
+			if run.ttft == 0 {
+				run.ttft = time.Since(start)
+			}
+		case "message_delta":
+			run.outputTokens = ev.Usage.OutputTokens
+		case "error":
+			run.err = errors.New(ev.Error.Message)
+		}
+	}
+	run.total = time.Since(start)
+	if run.err == nil {
+		run.err = sc.Err()
+	}
+	if run.err == nil && run.ttft == 0 {
+		run.err = errors.New("stream ended without a token")
+	}
+	return run
+}
+
+// apiErrorMessage returns the message of an Anthropic error body, or the
+// body itself.
+func apiErrorMessage(r io.Reader) string {
+	data, _ := io.ReadAll(io.LimitReader(r, 4096))
+	var e struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(data, &e) == nil && e.Error.Message != "" {
+		return e.Error.Message
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func printBench(rows []benchSummary) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SCENARIO\tRUNS\tERRORS\tAVG TTFT\tTOKENS/SEC\tAVG TOTAL\tTOOL USE")
+	for _, r := range rows {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%dms\t%.1f\t%dms\t%d/%d\n",
+			r.Scenario, r.Runs, r.Errors, r.AvgTTFTMs, r.TokensPerSec, r.AvgTotalMs, r.ToolUse, r.Runs-r.Errors)
+	}
+	tw.Flush()
+	for _, r := range rows {
+		if r.FirstError != "" {
+			fmt.Printf("\n%s: %s\n", r.Scenario, r.FirstError)
+		}
+	}
+}
+
+// splitList splits a comma-separated flag, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			out = append(out, name)
+		}
+	}
+	return out
+}
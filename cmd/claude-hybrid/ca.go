@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/mitm"
+)
+
+// caLockStale is how old a ca.lock must be before rotate assumes its owner
+// died. Generation takes well under a second.
+const caLockStale = time.Minute
+
+// unlockCAKey reads the CA key at keyPath. A passphrase-protected key is
+// unlocked with $CLAUDE_HYBRID_CA_PASSPHRASE, or by prompting on the
+// controlling terminal.
+func unlockCAKey(keyPath string) ([]byte, error) {
+	pass := []byte(os.Getenv(mitm.PassphraseEnv))
+	key, err := mitm.LoadCAKey(keyPath, pass)
+	if !errors.Is(err, mitm.ErrPassphraseRequired) {
+		return key, err
+	}
+	pass, err = readPassphrase("CA key passphrase: ", false)
+	if err != nil {
+		return nil, err
+	}
+	return mitm.LoadCAKey(keyPath, pass)
+}
+
+// runCA implements `claude-hybrid ca <command>`.
+func runCA(args []string) int {
+	fs := flag.NewFlagSet("ca", flag.ExitOnError)
+	certsDir := fs.String("certs-dir", defaultCertsDir(), "directory for CA cert/key")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: claude-hybrid ca [--certs-dir dir] <command> [flags]
+
+Commands:
+  protect --storage keyring|passphrase|file
+        move the CA private key into the OS keyring (macOS Keychain or
+        Secret Service), encrypt it with a passphrase, or store it as a
+        plain 0600 file
+  info
+        show the CA certificate's fingerprint, validity and key storage
+  regenerate
+        replace the CA certificate and key, keeping the key's storage;
+        the old certificate stays trusted until it expires
+  rotate
+        like regenerate, but stop trusting the old certificate at once
+        (use after a suspected key leak); restart running sessions
+
+A passphrase is read from $%s, or prompted for.
+
+Flags:
+`, mitm.PassphraseEnv)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	certPath := filepath.Join(*certsDir, "ca.crt")
+	keyPath := filepath.Join(*certsDir, "ca.key")
+	var err error
+	switch cmd, rest := fs.Arg(0), fs.Args()[1:]; cmd {
+	case "info":
+		err = caInfo(*certsDir)
+	case "protect":
+		err = caProtect(keyPath, rest)
+	case "regenerate", "rotate":
+		if err = regenerateCA(*certsDir, cmd == "regenerate"); err == nil {
+			fmt.Printf("New CA certificate written to %s.\n", certPath)
+			fmt.Println("Restart running claude-hybrid sessions to pick it up.")
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "claude-hybrid: unknown ca command %q\n", cmd)
+		fs.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+		return exitCAError
+	}
+	return 0
+}
+
+func caProtect(keyPath string, args []string) error {
+	fs := flag.NewFlagSet("ca protect", flag.ExitOnError)
+	storage := fs.String("storage", string(mitm.StorageKeyring), "where to keep the CA key: keyring, passphrase or file")
+	fs.Parse(args)
+
+	key, err := unlockCAKey(keyPath)
+	if err != nil {
+		return fmt.Errorf("read CA key: %w", err)
+	}
+	var pass []byte
+	if mitm.KeyStorage(*storage) == mitm.StoragePassphrase {
+		if pass, err = newPassphrase(); err != nil {
+			return err
+		}
+	}
+	if err := mitm.StoreCAKey(keyPath, key, mitm.KeyStorage(*storage), pass); err != nil {
+		return err
+	}
+	fmt.Printf("CA key now stored as %s\n", *storage)
+	return nil
+}
+
+// errCALocked means another instance holds ca.lock.
+var errCALocked = errors.New("CA is being generated by another instance")
+
+// CA files in the certs dir besides ca.crt and ca.key. After a regenerate the
+// replaced certificate is kept as ca.prev.crt until it expires, and
+// ca-bundle.crt holds both so clients started from either generation keep
+// working while sessions restart.
+const (
+	caPrevFile   = "ca.prev.crt"
+	caBundleFile = "ca-bundle.crt"
+)
+
+// regenerateCA replaces the CA certificate and key, keeping the key's
+// storage. With overlap, the old certificate stays trusted through
+// ca-bundle.crt until it expires; without, it is dropped at once (for a key
+// that may have leaked).
+func regenerateCA(certsDir string, overlap bool) error {
+	certPath := filepath.Join(certsDir, "ca.crt")
+	keyPath := filepath.Join(certsDir, "ca.key")
+
+	// Hold the same lock first-run generation uses, so a starting instance
+	// never reads a cert and key from different generations.
+	lockPath := filepath.Join(certsDir, "ca.lock")
+	if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > caLockStale {
+		os.Remove(lockPath)
+	}
+	lock, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("%w (remove %s if stale)", errCALocked, lockPath)
+	}
+	lock.Close()
+	defer os.Remove(lockPath)
+
+	storage, err := mitm.KeyStorageOf(keyPath)
+	if err != nil {
+		return fmt.Errorf("read CA key: %w", err)
+	}
+	var pass []byte
+	if storage == mitm.StoragePassphrase {
+		// Reuse the current passphrase, verifying it against the old key.
+		if pass = []byte(os.Getenv(mitm.PassphraseEnv)); len(pass) == 0 {
+			if pass, err = readPassphrase("Current CA key passphrase: ", false); err != nil {
+				return err
+			}
+		}
+		if _, err := mitm.LoadCAKey(keyPath, pass); err != nil {
+			return err
+		}
+	}
+
+	oldPEM, _ := os.ReadFile(certPath)
+	certPEM, keyPEM, err := mitm.GenerateCA()
+	if err != nil {
+		return fmt.Errorf("generate CA: %w", err)
+	}
+	if err := mitm.StoreCAKey(keyPath, keyPEM, storage, pass); err != nil {
+		return fmt.Errorf("write CA key: %w", err)
+	}
+	if err := os.WriteFile(certPath, certPEM, 0644); err != nil {
+		return fmt.Errorf("write CA cert: %w", err)
+	}
+
+	prevPath := filepath.Join(certsDir, caPrevFile)
+	bundlePath := filepath.Join(certsDir, caBundleFile)
+	old, err := mitm.ParseCACert(oldPEM)
+	if !overlap || err != nil || time.Now().After(old.NotAfter) {
+		os.Remove(prevPath)
+		os.Remove(bundlePath)
+		return nil
+	}
+	if err := os.WriteFile(prevPath, oldPEM, 0644); err != nil {
+		return fmt.Errorf("write previous CA cert: %w", err)
+	}
+	bundle := append(append([]byte{}, certPEM...), oldPEM...)
+	if err := os.WriteFile(bundlePath, bundle, 0644); err != nil {
+		return fmt.Errorf("write CA bundle: %w", err)
+	}
+	return nil
+}
+
+// caTrustPath returns the file to export as NODE_EXTRA_CA_CERTS: the bundle
+// while a previous certificate is still valid, otherwise ca.crt. An expired
+// previous certificate is cleaned up.
+func caTrustPath(certsDir string) string {
+	certPath := filepath.Join(certsDir, "ca.crt")
+	prevPath := filepath.Join(certsDir, caPrevFile)
+	bundlePath := filepath.Join(certsDir, caBundleFile)
+	prevPEM, err := os.ReadFile(prevPath)
+	if err != nil {
+		return certPath
+	}
+	if prev, err := mitm.ParseCACert(prevPEM); err != nil || time.Now().After(prev.NotAfter) {
+		os.Remove(prevPath)
+		os.Remove(bundlePath)
+		return certPath
+	}
+	if _, err := os.Stat(bundlePath); err != nil {
+		return certPath
+	}
+	return bundlePath
+}
+
+// renewCAIfExpiring regenerates the CA at startup when it has expired or is
+// within defaults.CARenewBefore of expiring. If renewal fails, a still-valid
+// certificate keeps being used; an expired one is an error, since Claude Code
+// would reject every connection.
+func renewCAIfExpiring(certsDir string) error {
+	certPath := filepath.Join(certsDir, "ca.crt")
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return fmt.Errorf("read CA cert: %w", err)
+	}
+	cert, err := mitm.ParseCACert(certPEM)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if !mitm.NeedsRenewal(cert, now) {
+		return nil
+	}
+	if now.After(cert.NotAfter) {
+		log.Printf("CA certificate expired on %s — regenerating", cert.NotAfter.Format("2006-01-02"))
+	} else {
+		log.Printf("CA certificate expires on %s — regenerating", cert.NotAfter.Format("2006-01-02"))
+	}
+
+	err = regenerateCA(certsDir, true)
+	if errors.Is(err, errCALocked) {
+		// Another instance is renewing; wait for it to finish.
+		lockPath := filepath.Join(certsDir, "ca.lock")
+		for i := 0; i < 50; i++ {
+			time.Sleep(100 * time.Millisecond)
+			if _, statErr := os.Stat(lockPath); os.IsNotExist(statErr) {
+				return nil
+			}
+		}
+	}
+	if err != nil {
+		if now.After(cert.NotAfter) {
+			return fmt.Errorf("regenerate expired CA: %w", err)
+		}
+		log.Printf("CA renewal failed, continuing with the current certificate: %v", err)
+		return nil
+	}
+	log.Printf("New CA certificate written to %s", certPath)
+	return nil
+}
+
+// caInfo prints the current CA certificate and how its key is stored.
+func caInfo(certsDir string) error {
+	certPath := filepath.Join(certsDir, "ca.crt")
+	keyPath := filepath.Join(certsDir, "ca.key")
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return fmt.Errorf("read CA cert: %w", err)
+	}
+	cert, err := mitm.ParseCACert(certPEM)
+	if err != nil {
+		return err
+	}
+	storage, err := mitm.KeyStorageOf(keyPath)
+	if err != nil {
+		return fmt.Errorf("read CA key: %w", err)
+	}
+
+	now := time.Now()
+	status := fmt.Sprintf("valid, %d days left", int(cert.NotAfter.Sub(now).Hours()/24))
+	switch {
+	case now.After(cert.NotAfter):
+		status = "EXPIRED"
+	case mitm.NeedsRenewal(cert, now):
+		status += " (renewed at next start)"
+	}
+	fmt.Printf("Certificate:  %s\n", certPath)
+	fmt.Printf("Subject:      %s\n", cert.Subject.CommonName)
+	fmt.Printf("Serial:       %x\n", cert.SerialNumber)
+	fmt.Printf("SHA-256:      %s\n", mitm.Fingerprint(cert))
+	fmt.Printf("Not before:   %s\n", cert.NotBefore.Local().Format(time.RFC1123))
+	fmt.Printf("Not after:    %s\n", cert.NotAfter.Local().Format(time.RFC1123))
+	fmt.Printf("Status:       %s\n", status)
+	fmt.Printf("Key storage:  %s\n", storage)
+	if prevPEM, err := os.ReadFile(filepath.Join(certsDir, caPrevFile)); err == nil {
+		if prev, err := mitm.ParseCACert(prevPEM); err == nil && now.Before(prev.NotAfter) {
+			fmt.Printf("Previous CA:  trusted until %s\n", prev.NotAfter.Local().Format(time.RFC1123))
+		}
+	}
+	fmt.Printf("Trust file:   %s\n", caTrustPath(certsDir))
+	return nil
+}
+
+// newPassphrase asks for a passphrase twice, unless $CLAUDE_HYBRID_CA_PASSPHRASE
+// is set.
+func newPassphrase() ([]byte, error) {
+	if p := os.Getenv(mitm.PassphraseEnv); p != "" {
+		return []byte(p), nil
+	}
+	return readPassphrase("New CA key passphrase: ", true)
+}
+
+// readPassphrase prompts on the controlling terminal with echo turned off.
+func readPassphrase(prompt string, confirm bool) ([]byte, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("no terminal to prompt for the CA key passphrase: set %s", mitm.PassphraseEnv)
+	}
+	defer tty.Close()
+	r := bufio.NewReader(tty)
+	read := func(prompt string) ([]byte, error) {
+		fmt.Fprint(tty, prompt)
+		stty(tty, "-echo")
+		line, err := r.ReadString('\n')
+		stty(tty, "echo")
+		fmt.Fprintln(tty)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(strings.TrimRight(line, "\r\n")), nil
+	}
+	pass, err := read(prompt)
+	if err != nil {
+		return nil, err
+	}
+	if len(pass) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+	if confirm {
+		again, err := read("Repeat passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(pass, again) {
+			return nil, errors.New("passphrases do not match")
+		}
+	}
+	return pass, nil
+}
+
+func stty(tty *os.File, arg string) {
+	cmd := exec.Command("stty", arg)
+	cmd.Stdin = tty
+	cmd.Run()
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+// runConfig prints the config schema or checks a config file.
+func runConfig(args []string) int {
+	usage := func() {
+		fmt.Fprint(os.Stderr, `Usage: claude-hybrid config schema
+       claude-hybrid config check [--profile NAME] [file]
+
+schema  prints a JSON Schema for config.yaml, for editor completion, e.g.
+        claude-hybrid config schema > ~/.claude-hybrid/config.schema.json
+        and "# yaml-language-server: $schema=config.schema.json" at the
+        top of config.yaml
+check   loads a config file (default ~/.claude-hybrid/config.yaml) with
+        CLAUDE_HYBRID_* overrides and reports every problem, as startup would
+`)
+	}
+	if len(args) == 0 {
+		usage()
+		return 2
+	}
+	switch args[0] {
+	case "schema":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(config.Schema()); err != nil {
+			fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+			return 1
+		}
+		return 0
+	case "check":
+		fs := flag.NewFlagSet("config check", flag.ExitOnError)
+		profile := fs.String("profile", "", "apply this profile")
+		fs.Usage = usage
+		fs.Parse(args[1:])
+		baseDir := filepath.Dir(defaultCertsDir())
+		if fs.NArg() > 0 {
+			// A file elsewhere is checked with profiles from beside it.
+			abs, err := filepath.Abs(fs.Arg(0))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+				return 1
+			}
+			if filepath.Base(abs) != "config.yaml" {
+				return checkConfigFile(abs, *profile)
+			}
+			baseDir = filepath.Dir(abs)
+		}
+		return checkConfigDir(baseDir, *profile)
+	}
+	usage()
+	return 2
+}
+
+// checkConfigDir checks baseDir/config.yaml the way startup loads it.
+func checkConfigDir(baseDir, profile string) int {
+	cfg, _, overrides, err := loadConfig(baseDir, profile, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitConfigError
+	}
+	if cfg == nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: no %s\n", filepath.Join(baseDir, "config.yaml"))
+		return exitConfigError
+	}
+	return reportConfig(cfg, filepath.Join(baseDir, "config.yaml"), len(overrides))
+}
+
+// checkConfigFile checks a file not named config.yaml, such as a profile.
+func checkConfigFile(path, profile string) int {
+	if profile != "" {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: --profile needs a config.yaml\n")
+		return 2
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitConfigError
+	}
+	return reportConfig(cfg, path, 0)
+}
+
+// reportConfig resolves every label, which catches what the schema can't,
+// such as a provider with no endpoint.
+func reportConfig(cfg *config.ProvidersConfig, path string, overrides int) int {
+	resolver, err := config.NewModelResolver(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		return exitConfigError
+	}
+	n := len(resolver.Models())
+	msg := fmt.Sprintf("%s: OK, %d labels", path, n)
+	if n == 1 {
+		msg = fmt.Sprintf("%s: OK, 1 label", path)
+	}
+	if n := len(resolver.Patterns()); n == 1 {
+		msg += ", 1 pattern"
+	} else if n > 1 {
+		msg += fmt.Sprintf(", %d patterns", n)
+	}
+	if n := len(resolver.Embeddings()); n == 1 {
+		msg += ", 1 embedding label"
+	} else if n > 1 {
+		msg += fmt.Sprintf(", %d embedding labels", n)
+	}
+	if overrides > 0 {
+		msg += fmt.Sprintf(", %d overrides from the environment", overrides)
+	}
+	fmt.Println(msg)
+	return 0
+}
@@ -0,0 +1,547 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// conformanceCase is one canned request and the invariants its answer must
+// hold. check returns the problems found; none is a pass.
+type conformanceCase struct {
+	name    string
+	feature string
+	stream  bool
+	body    func() map[string]interface{}
+	check   func(m *transcript) []string
+}
+
+// transcript is a Messages answer, assembled from a JSON body or an SSE
+// stream.
+type transcript struct {
+	blocks       []transcriptBlock
+	stopReason   string
+	outputTokens int
+}
+
+type transcriptBlock struct {
+	typ       string
+	text      string // text, or thinking
+	signature string
+	id, name  string // tool_use
+	input     map[string]interface{}
+}
+
+func (m *transcript) text() string {
+	var b strings.Builder
+	for _, bl := range m.blocks {
+		if bl.typ == "text" {
+			b.WriteString(bl.text)
+		}
+	}
+	return b.String()
+}
+
+func (m *transcript) toolUses() []transcriptBlock {
+	var out []transcriptBlock
+	for _, bl := range m.blocks {
+		if bl.typ == "tool_use" {
+			out = append(out, bl)
+		}
+	}
+	return out
+}
+
+var weatherTool = []map[string]interface{}{{
+	"name":        "get_weather",
+	"description": "Returns the current weather for a city.",
+	"input_schema": map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"city": map[string]string{"type": "string"}},
+		"required":   []string{"city"},
+	},
+}}
+
+func userMessage(text string) []interface{} {
+	return []interface{}{map[string]interface{}{"role": "user", "content": text}}
+}
+
+func conformanceBody(maxTokens int, messages []interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"max_tokens": maxTokens,
+		"messages":   messages,
+	}
+}
+
+// wantStop reports a stop_reason other than one of want.
+func wantStop(m *transcript, want ...string) []string {
+	for _, w := range want {
+		if m.stopReason == w {
+			return nil
+		}
+	}
+	return []string{fmt.Sprintf("stop_reason %q, want %s", m.stopReason, strings.Join(want, " or "))}
+}
+
+func wantText(m *transcript) []string {
+	if strings.TrimSpace(m.text()) == "" {
+		return []string{"no text in the answer"}
+	}
+	return nil
+}
+
+var conformanceCases = []conformanceCase{
+	{
+		name: "text", feature: "plain JSON answer",
+		body: func() map[string]interface{} {
+			return conformanceBody(256, userMessage("Say hello in one short sentence."))
+		},
+		check: func(m *transcript) []string {
+			p := append(wantText(m), wantStop(m, "end_turn")...)
+			if m.outputTokens == 0 {
+				p = append(p, "usage.output_tokens is 0")
+			}
+			return p
+		},
+	},
+	{
+		name: "stream", feature: "SSE event lifecycle", stream: true,
+		body: func() map[string]interface{} {
+			return conformanceBody(256, userMessage("Say hello in one short sentence."))
+		},
+		check: func(m *transcript) []string {
+			p := append(wantText(m), wantStop(m, "end_turn")...)
+			if m.outputTokens == 0 {
+				p = append(p, "message_delta usage.output_tokens is 0")
+			}
+			return p
+		},
+	},
+	{
+		name: "tool_use", feature: "a forced tool call with valid input", stream: true,
+		body: func() map[string]interface{} {
+			b := conformanceBody(512, userMessage("What is the weather in Paris?"))
+			b["tools"] = weatherTool
+			b["tool_choice"] = map[string]string{"type": "tool", "name": "get_weather"}
+			return b
+		},
+		check: func(m *transcript) []string {
+			uses := m.toolUses()
+			if len(uses) == 0 {
+				return []string{"no tool_use block"}
+			}
+			var p []string
+			if u := uses[0]; u.name != "get_weather" || u.id == "" {
+				p = append(p, fmt.Sprintf("tool_use name %q id %q", u.name, u.id))
+			} else if city, _ := u.input["city"].(string); city == "" {
+				p = append(p, fmt.Sprintf("tool_use input has no city: %v", u.input))
+			}
+			return append(p, wantStop(m, "tool_use")...)
+		},
+	},
+	{
+		name: "parallel_tools", feature: "several tool calls in one turn", stream: true,
+		body: func() map[string]interface{} {
+			b := conformanceBody(512, userMessage("Get the weather in Paris and in Tokyo. Call get_weather once for each city, both in this turn, before answering."))
+			b["tools"] = weatherTool
+			return b
+		},
+		check: func(m *transcript) []string {
+			uses := m.toolUses()
+			if len(uses) < 2 {
+				return []string{fmt.Sprintf("%d tool_use block(s), want 2", len(uses))}
+			}
+			var p []string
+			if uses[0].id == uses[1].id {
+				p = append(p, "tool_use blocks share an id")
+			}
+			return append(p, wantStop(m, "tool_use")...)
+		},
+	},
+	{
+		name: "tool_result", feature: "answering from a tool result", stream: true,
+		body: func() map[string]interface{} {
+			b := conformanceBody(256, []interface{}{
+				map[string]interface{}{"role": "user", "content": "What is the weather in Paris?"},
+				map[string]interface{}{"role": "assistant", "content": []interface{}{map[string]interface{}{
+					"type": "tool_use", "id": "toolu_conformance1", "name": "get_weather",
+					"input": map[string]string{"city": "Paris"},
+				}}},
+				map[string]interface{}{"role": "user", "content": []interface{}{map[string]interface{}{
+					"type": "tool_result", "tool_use_id": "toolu_conformance1", "content": "17°C, light rain",
+				}}},
+			})
+			b["tools"] = weatherTool
+			return b
+		},
+		check: func(m *transcript) []string {
+			p := append(wantText(m), wantStop(m, "end_turn")...)
+			if !strings.Contains(m.text(), "17") {
+				p = append(p, "answer does not use the tool result (17°C)")
+			}
+			return p
+		},
+	},
+	{
+		name: "thinking", feature: "thinking block before the answer", stream: true,
+		body: func() map[string]interface{} {
+			b := conformanceBody(4096, userMessage("Is 391 a prime number? Answer yes or no."))
+			b["thinking"] = map[string]interface{}{"type": "enabled", "budget_tokens": 2048}
+			return b
+		},
+		check: func(m *transcript) []string {
+			if len(m.blocks) == 0 || m.blocks[0].typ != "thinking" {
+				return []string{"no thinking block first (does the label need a reasoning transform?)"}
+			}
+			var p []string
+			if strings.TrimSpace(m.blocks[0].text) == "" || m.blocks[0].signature == "" {
+				p = append(p, "thinking block is empty or unsigned")
+			}
+			return append(p, wantText(m)...)
+		},
+	},
+	{
+		name: "stop_sequences", feature: "stop_sequences end the answer", stream: true,
+		body: func() map[string]interface{} {
+			b := conformanceBody(256, userMessage("Repeat exactly, with nothing else: alpha beta HALT gamma delta"))
+			b["stop_sequences"] = []string{"HALT"}
+			return b
+		},
+		check: func(m *transcript) []string {
+			var p []string
+			if t := m.text(); strings.Contains(t, "HALT") || strings.Contains(t, "gamma") {
+				p = append(p, fmt.Sprintf("text runs past the stop sequence: %q", t))
+			}
+			return append(p, wantStop(m, "stop_sequence", "end_turn")...)
+		},
+	},
+	{
+		name: "max_tokens", feature: "max_tokens cuts the answer off", stream: true,
+		body: func() map[string]interface{} {
+			return conformanceBody(16, userMessage("Write a 500-word essay about compilers."))
+		},
+		check: func(m *transcript) []string {
+			p := wantStop(m, "max_tokens")
+			if m.outputTokens > 32 {
+				p = append(p, fmt.Sprintf("%d output tokens for max_tokens 16", m.outputTokens))
+			}
+			return p
+		},
+	},
+	{
+		name: "long_output", feature: "a long answer streams to the end", stream: true,
+		body: func() map[string]interface{} {
+			return conformanceBody(8192, userMessage("Write the numbers from 1 to 400, one per line, with nothing else."))
+		},
+		check: func(m *transcript) []string {
+			p := wantStop(m, "end_turn")
+			if !strings.Contains(m.text(), "400") {
+				p = append(p, "answer stops before 400")
+			}
+			return p
+		},
+	},
+}
+
+// conformanceResult is one case's outcome.
+type conformanceResult struct {
+	Case     string   `json:"case"`
+	Feature  string   `json:"feature"`
+	Pass     bool     `json:"pass"`
+	Problems []string `json:"problems,omitempty"`
+	Ms       int64    `json:"ms"`
+}
+
+// runConformance implements `claude-hybrid conformance`.
+func runConformance(args []string) int {
+	fs := flag.NewFlagSet("conformance", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: claude-hybrid conformance --label NAME [flags]
+
+Sends canned Anthropic requests to a label through an in-process proxy and
+checks the translated answers: the SSE event sequence, stop reasons, tool
+calls and their input, thinking blocks and usage. Reports which features
+the backend (with the label's transforms) breaks. Cases:
+
+`)
+		for _, c := range conformanceCases {
+			fmt.Fprintf(os.Stderr, "  %-15s %s\n", c.name, c.feature)
+		}
+		fmt.Fprint(os.Stderr, "\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	label := fs.String("label", "", "label, alias or label group to test (required)")
+	profile := fs.String("profile", "", "apply this profile from config.yaml")
+	only := fs.String("cases", "", "comma-separated cases to run (empty = all)")
+	timeout := fs.Duration("timeout", 5*time.Minute, "give up on a request after this long")
+	verbose := fs.Bool("verbose", false, "print the proxy's log to stderr")
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	fs.Parse(args)
+	if *label == "" {
+		fs.Usage()
+		return 2
+	}
+	cases := conformanceCases
+	if names := splitList(*only); len(names) > 0 {
+		cases = nil
+		for _, name := range names {
+			found := false
+			for _, c := range conformanceCases {
+				if c.name == name {
+					cases, found = append(cases, c), true
+				}
+			}
+			if !found {
+				fmt.Fprintf(os.Stderr, "claude-hybrid: unknown case %q\n", name)
+				return 2
+			}
+		}
+	}
+
+	lc, code := newLabelClient(*label, *profile, *timeout, *verbose)
+	if lc == nil {
+		return code
+	}
+	defer lc.close()
+
+	if !*asJSON {
+		fmt.Printf("Conformance of %s → %s/%s\n\n", *label, lc.target.Provider, lc.target.Model)
+	}
+	var results []conformanceResult
+	failed := 0
+	for _, c := range cases {
+		start := time.Now()
+		problems := runCase(lc, c)
+		r := conformanceResult{Case: c.name, Feature: c.feature, Pass: len(problems) == 0,
+			Problems: problems, Ms: time.Since(start).Milliseconds()}
+		if !r.Pass {
+			failed++
+		}
+		results = append(results, r)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(map[string]interface{}{
+			"label": *label, "provider": lc.target.Provider, "model": lc.target.Model, "results": results,
+		})
+	} else {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "CASE\tRESULT\tTIME\tDETAIL")
+		for _, r := range results {
+			result, detail := "pass", r.Feature
+			if !r.Pass {
+				// Provider errors may span lines, which would break the table.
+				result, detail = "FAIL", strings.Join(strings.Fields(strings.Join(r.Problems, "; ")), " ")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%dms\t%s\n", r.Case, result, r.Ms, detail)
+		}
+		tw.Flush()
+		fmt.Printf("\n%d of %d passed\n", len(results)-failed, len(results))
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runCase sends c's request and returns the problems with the answer.
+func runCase(lc *labelClient, c conformanceCase) []string {
+	body := c.body()
+	body["stream"] = c.stream
+	resp, err := lc.send(body)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return []string{fmt.Sprintf("HTTP %d: %s", resp.StatusCode, apiErrorMessage(resp.Body))}
+	}
+	var m *transcript
+	var problems []string
+	if c.stream {
+		if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+			return []string{fmt.Sprintf("Content-Type %q for a stream", ct)}
+		}
+		m, problems = readStream(resp.Body)
+	} else {
+		m, problems = readMessage(resp.Body)
+	}
+	if m == nil {
+		return problems
+	}
+	return append(problems, c.check(m)...)
+}
+
+// readMessage decodes a non-streaming answer and checks its shape.
+func readMessage(r io.Reader) (*transcript, []string) {
+	var msg struct {
+		Type       string `json:"type"`
+		Role       string `json:"role"`
+		StopReason string `json:"stop_reason"`
+		Content    []struct {
+			Type      string                 `json:"type"`
+			Text      string                 `json:"text"`
+			Thinking  string                 `json:"thinking"`
+			Signature string                 `json:"signature"`
+			ID        string                 `json:"id"`
+			Name      string                 `json:"name"`
+			Input     map[string]interface{} `json:"input"`
+		} `json:"content"`
+		Usage struct {
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.NewDecoder(r).Decode(&msg); err != nil {
+		return nil, []string{"invalid JSON answer: " + err.Error()}
+	}
+	var problems []string
+	if msg.Type != "message" || msg.Role != "assistant" {
+		problems = append(problems, fmt.Sprintf("type %q role %q, want message assistant", msg.Type, msg.Role))
+	}
+	m := &transcript{stopReason: msg.StopReason, outputTokens: msg.Usage.OutputTokens}
+	for _, c := range msg.Content {
+		text := c.Text
+		if c.Type == "thinking" {
+			text = c.Thinking
+		}
+		m.blocks = append(m.blocks, transcriptBlock{typ: c.Type, text: text, signature: c.Signature,
+			id: c.ID, name: c.Name, input: c.Input})
+	}
+	return m, problems
+}
+
+// readStream assembles an SSE answer and checks the event lifecycle:
+// message_start first, content blocks opened, filled and closed in index
+// order with deltas of their own type, one message_delta, message_stop
+// last, and no error events.
+func readStream(r io.Reader) (*transcript, []string) {
+	m := &transcript{}
+	var problems []string
+	problem := func(format string, args ...interface{}) {
+		if len(problems) < 5 {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+	var events []string
+	open := -1 // index of the open block
+	var partial strings.Builder
+	var event string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if e, ok := strings.CutPrefix(line, "event: "); ok {
+			event = e
+			continue
+		}
+		payload, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		var ev struct {
+			Type         string `json:"type"`
+			Index        int    `json:"index"`
+			ContentBlock struct {
+				Type string `json:"type"`
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"content_block"`
+			Delta struct {
+				Type        string `json:"type"`
+				Text        string `json:"text"`
+				Thinking    string `json:"thinking"`
+				Signature   string `json:"signature"`
+				PartialJSON string `json:"partial_json"`
+				StopReason  string `json:"stop_reason"`
+			} `json:"delta"`
+			Usage struct {
+				OutputTokens int `json:"output_tokens"`
+			} `json:"usage"`
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(payload), &ev); err != nil {
+			problem("%s event is not JSON", event)
+			continue
+		}
+		if ev.Type != event {
+			problem("event %q carries type %q", event, ev.Type)
+		}
+		if event == "ping" {
+			continue
+		}
+		if len(events) == 0 && event != "message_start" {
+			problem("first event is %s, not message_start", event)
+		}
+		events = append(events, event)
+		switch event {
+		case "content_block_start":
+			if open >= 0 {
+				problem("block %d starts before block %d stops", ev.Index, open)
+			}
+			if ev.Index != len(m.blocks) {
+				problem("block index %d, want %d", ev.Index, len(m.blocks))
+			}
+			open = ev.Index
+			partial.Reset()
+			m.blocks = append(m.blocks, transcriptBlock{typ: ev.ContentBlock.Type, id: ev.ContentBlock.ID, name: ev.ContentBlock.Name})
+		case "content_block_delta":
+			if open < 0 || ev.Index != open {
+				problem("delta for block %d, which is not open", ev.Index)
+				continue
+			}
+			bl := &m.blocks[open]
+			want := map[string]string{"text_delta": "text", "thinking_delta": "thinking",
+				"signature_delta": "thinking", "input_json_delta": "tool_use"}[ev.Delta.Type]
+			if want != bl.typ {
+				problem("%s in a %s block", ev.Delta.Type, bl.typ)
+			}
+			bl.text += ev.Delta.Text + ev.Delta.Thinking
+			bl.signature += ev.Delta.Signature
+			partial.WriteString(ev.Delta.PartialJSON)
+		case "content_block_stop":
+			if open < 0 || ev.Index != open {
+				problem("stop for block %d, which is not open", ev.Index)
+				continue
+			}
+			if bl := &m.blocks[open]; bl.typ == "tool_use" {
+				in := partial.String()
+				if in == "" {
+					in = "{}"
+				}
+				if err := json.Unmarshal([]byte(in), &bl.input); err != nil {
+					problem("tool_use input is not a JSON object: %q", in)
+				}
+			}
+			open = -1
+		case "message_delta":
+			if m.stopReason != "" {
+				problem("more than one message_delta")
+			}
+			m.stopReason, m.outputTokens = ev.Delta.StopReason, ev.Usage.OutputTokens
+		case "error":
+			problem("error event: %s", ev.Error.Message)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		problem("reading stream: %v", err)
+	}
+	if open >= 0 {
+		problem("block %d never stops", open)
+	}
+	if len(events) == 0 || events[len(events)-1] != "message_stop" {
+		problem("stream does not end with message_stop")
+	}
+	return m, problems
+}
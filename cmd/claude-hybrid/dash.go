@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/proxy"
+)
+
+// dashRows caps each list on the dashboard so a frame fits a terminal.
+const dashRows = 8
+
+// runDash implements `claude-hybrid dash`: a live terminal view of a
+// running proxy, redrawn from the admin API.
+func runDash(args []string) int {
+	fs := flag.NewFlagSet("dash", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: claude-hybrid dash [flags]
+
+Shows live local routing from a proxy started with --admin-addr: requests
+in flight, per-label latency and token throughput, provider health, and
+recent errors. Ctrl+C quits.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	addr := fs.String("addr", "127.0.0.1:9901", "admin API address of the running proxy (its --admin-addr)")
+	token := fs.String("token", os.Getenv("CLAUDE_HYBRID_ADMIN_TOKEN"), "admin read token (default $CLAUDE_HYBRID_ADMIN_TOKEN)")
+	interval := fs.Duration("interval", time.Second, "refresh interval")
+	once := fs.Bool("once", false, "print one frame without clearing the screen, then exit")
+	fs.Parse(args)
+
+	c := &dashClient{base: "http://" + strings.TrimPrefix(*addr, "http://"), token: *token,
+		http: &http.Client{Timeout: 5 * time.Second}}
+	for {
+		var frame bytes.Buffer
+		renderDash(&frame, c, *addr)
+		if *once {
+			os.Stdout.Write(frame.Bytes())
+			return 0
+		}
+		// Home the cursor and clear, then draw the frame in one write so the
+		// screen doesn't flicker.
+		os.Stdout.Write(append([]byte("\x1b[H\x1b[2J"), frame.Bytes()...))
+		time.Sleep(*interval)
+	}
+}
+
+type dashClient struct {
+	base  string
+	token string
+	http  *http.Client
+}
+
+func (c *dashClient) get(path string, v interface{}) error {
+	req, err := http.NewRequest("GET", c.base+path, nil)
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func renderDash(w *bytes.Buffer, c *dashClient, addr string) {
+	fmt.Fprintf(w, "claude-hybrid dash — %s — %s\n\n", addr, time.Now().Format("15:04:05"))
+	var act proxy.Activity
+	var m proxy.Metrics
+	if err := c.get("/admin/activity", &act); err != nil {
+		fmt.Fprintf(w, "admin API unavailable: %v\n(start the proxy with --admin-addr %s)\n", err, addr)
+		return
+	}
+	if err := c.get("/admin/metrics", &m); err == nil {
+		fmt.Fprintf(w, "Tunnels %d/%d   waiting %d   refused %d   bypassed %d\n\n",
+			m.Admission.InFlight, m.Admission.MaxConcurrent, m.Admission.Waiting,
+			m.Admission.Rejected+m.Admission.TimedOut, m.Bypass.Tunnels)
+	}
+	var paused struct {
+		Paused []proxy.PausedRequest `json:"paused"`
+	}
+	if err := c.get("/admin/paused", &paused); err == nil && len(paused.Paused) > 0 {
+		fmt.Fprintf(w, "PAUSED %d request(s) — release with claude-hybrid paused\n\n", len(paused.Paused))
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "IN FLIGHT (%d)\n", len(act.InFlight))
+	fmt.Fprintln(tw, "  LABEL\tPROVIDER\tMODE\tELAPSED")
+	for _, ev := range head(act.InFlight) {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", ev.Label, dashOr(ev.Provider), streamMode(ev.Stream), ms(ev.LatencyMs))
+	}
+
+	fmt.Fprintln(tw, "\nLABELS")
+	fmt.Fprintln(tw, "  LABEL\tREQS\tERRS\tAVG\tLAST\tIN TOK\tOUT TOK\tOUT TOK/S")
+	for _, l := range act.Labels {
+		fmt.Fprintf(tw, "  %s\t%d\t%d\t%s\t%s\t%d\t%d\t%.1f\n", l.Label, l.Requests, l.Errors,
+			ms(l.AvgLatencyMs), ms(l.LastLatencyMs), l.InputTokens, l.OutputTokens, l.TokensPerSec)
+	}
+
+	if len(m.Budgets) > 0 {
+		fmt.Fprintln(tw, "\nBUDGETS (today)")
+		fmt.Fprintln(tw, "  LABEL\tSPENT\tDAILY\tACTION")
+		for _, b := range m.Budgets {
+			over := ""
+			if b.Exceeded {
+				over = " (exceeded)"
+			}
+			fmt.Fprintf(tw, "  %s\t$%.2f\t$%.2f\t%s%s\n", b.Label, b.SpentUSD, b.DailyUSD, b.Action, over)
+		}
+	}
+
+	fmt.Fprintln(tw, "\nPROVIDERS")
+	fmt.Fprintln(tw, "  PROVIDER\tHEALTH\tLAST STATUS\tLAST SEEN")
+	for _, h := range act.Providers {
+		health := "ok"
+		if h.ConsecutiveErrors > 0 {
+			health = fmt.Sprintf("failing (%d in a row)", h.ConsecutiveErrors)
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s ago\n", h.Provider, health, h.LastStatus,
+			time.Since(h.LastSeen).Truncate(time.Second))
+	}
+
+	var errs []proxy.RouteEvent
+	for _, ev := range act.Recent {
+		if ev.Status != "ok" && ev.Status != "dedupe" {
+			errs = append(errs, ev)
+		}
+	}
+	fmt.Fprintln(tw, "\nRECENT ERRORS")
+	for _, ev := range head(errs) {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", ev.Start.Local().Format("15:04:05"), ev.Label, ev.Status, ms(ev.LatencyMs))
+	}
+
+	fmt.Fprintln(tw, "\nRECENT ROUTES")
+	fmt.Fprintln(tw, "  TIME\tLABEL\tPROVIDER/MODEL\tMODE\tSTATUS\tLATENCY\tTOKENS IN/OUT")
+	for _, ev := range head(act.Recent) {
+		fmt.Fprintf(tw, "  %s\t%s\t%s/%s\t%s\t%s\t%s\t%d/%d\n", ev.Start.Local().Format("15:04:05"), ev.Label,
+			dashOr(ev.Provider), dashOr(ev.Model), streamMode(ev.Stream), ev.Status, ms(ev.LatencyMs),
+			ev.InputTokens, ev.OutputTokens)
+	}
+	tw.Flush()
+}
+
+func head(evs []proxy.RouteEvent) []proxy.RouteEvent {
+	return evs[:min(len(evs), dashRows)]
+}
+
+func streamMode(stream bool) string {
+	if stream {
+		return "stream"
+	}
+	return "sync"
+}
+
+func dashOr(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func ms(n int64) string {
+	return fmt.Sprintf("%dms", n)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+// Exit codes returned by claude-hybrid itself. When claude runs and exits,
+// its own exit code is passed through unchanged; the codes below are only
+// used when claude-hybrid fails before or around launching it, so wrapper
+// scripts can branch on the failure class instead of parsing stderr.
+//
+// The values sit in a block claude does not use and are part of the CLI
+// contract: never renumber an existing code, only append new ones.
+const (
+	exitProxyStartup = 70 // proxy failed to listen or start
+	exitConfigError  = 71 // config.yaml could not be loaded or resolved
+	exitCAError      = 72 // MITM CA could not be generated, read, or parsed
+	exitPreflight    = 73 // environment checks failed (dirs, log file, claude binary)
+)
+
+// fatalf logs the message to the proxy log and stderr, then exits with code.
+func fatalf(code int, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	log.Print(msg)
+	fmt.Fprintf(os.Stderr, "claude-hybrid: %s\n", msg)
+	os.Exit(code)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+// runImport converts another router's config into config.yaml.
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	from := fs.String("from", "", "source router: "+config.ImportClaudeCodeRouter+" or "+config.ImportYRouter)
+	out := fs.String("o", filepath.Join(filepath.Dir(defaultCertsDir()), "config.yaml"), "where to write the config (- = stdout)")
+	force := fs.Bool("force", false, "overwrite an existing config file")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: claude-hybrid import --from %s|%s [-o path] [--force] [file]
+
+Converts another router's providers, routes and transformer lists into a
+claude-hybrid config.yaml, and prints a report of what was mapped, changed
+or dropped. The file defaults to ~/.claude-code-router/config.json for
+%s and wrangler.toml for %s.
+
+Flags:
+`, config.ImportClaudeCodeRouter, config.ImportYRouter, config.ImportClaudeCodeRouter, config.ImportYRouter)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	src := fs.Arg(0)
+	switch {
+	case src != "":
+	case *from == config.ImportClaudeCodeRouter:
+		home, _ := os.UserHomeDir()
+		src = filepath.Join(home, ".claude-code-router", "config.json")
+	case *from == config.ImportYRouter:
+		src = "wrangler.toml"
+	default:
+		fs.Usage()
+		return 2
+	}
+
+	data, err := os.ReadFile(src)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+		return 1
+	}
+	cfg, report, err := config.Import(*from, data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+		return exitConfigError
+	}
+	yml, err := config.MarshalConfig(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+		return 1
+	}
+	yml = append([]byte(fmt.Sprintf("# Imported from %s (%s). Review before use.\n", src, *from)), yml...)
+
+	fmt.Fprintf(os.Stderr, "Import report (%s):\n", src)
+	for _, line := range report {
+		fmt.Fprintf(os.Stderr, "  %s\n", line)
+	}
+
+	if *out == "-" {
+		os.Stdout.Write(yml)
+		return 0
+	}
+	if _, err := os.Stat(*out); err == nil && !*force {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: %s exists; pass --force to overwrite or -o to write elsewhere\n", *out)
+		return 1
+	}
+	if err := os.MkdirAll(filepath.Dir(*out), 0700); err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+		return 1
+	}
+	if err := os.WriteFile(*out, yml, 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s\n", *out)
+	return 0
+}
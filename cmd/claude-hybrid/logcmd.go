@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/logfile"
+)
+
+// logPollInterval is how often --follow checks proxy.log for new lines.
+const logPollInterval = 250 * time.Millisecond
+
+// logColors are ANSI colors for lines carrying a log prefix, first match
+// wins.
+var logColors = []struct{ marker, color string }{
+	{"[LOCAL_ERR", "31"},    // red
+	{"[UPSTREAM_ERR", "31"}, // red
+	{"LOCAL_OK", "32"},      // green
+	{"LOCAL_ROUTE", "36"},   // cyan
+	{"LOCAL_COUNT", "36"},   // cyan
+	{"[PROXY_", "33"},       // yellow: busy, auth, denied, rate
+	{"LOCAL_BUDGET", "33"},  // yellow: over budget (fallback or warn)
+	{"[TOKENIZER]", "33"},   // yellow
+	{"[TRACING]", "33"},     // yellow
+	{"WARNING", "33"},       // yellow
+}
+
+// runLog implements `claude-hybrid log`: print proxy.log, optionally one
+// session's lines only, and follow it as it grows.
+func runLog(args []string) int {
+	fs := flag.NewFlagSet("log", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: claude-hybrid log [flags]
+
+Prints proxy.log, which every claude-hybrid session shares. Lines are
+colored by outcome (LOCAL_ROUTE, LOCAL_OK, LOCAL_ERR) on a terminal.
+
+Examples:
+  claude-hybrid log --follow --session s12345
+  claude-hybrid log -n 50 --rotated
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	var follow bool
+	fs.BoolVar(&follow, "follow", false, "keep printing lines as they are written, across rotations")
+	fs.BoolVar(&follow, "f", false, "shorthand for --follow")
+	session := fs.String("session", "", "only show this session ID, e.g. s12345 (the [sNNN] prefix)")
+	lines := fs.Int("n", 0, "start with the last N matching lines (0 = all)")
+	rotated := fs.Bool("rotated", false, "also read rotated logs (proxy.log.N.gz), oldest first")
+	colorMode := fs.String("color", "auto", "color output: auto, always or never")
+	path := fs.String("file", filepath.Join(filepath.Dir(defaultCertsDir()), "proxy.log"), "log file to read")
+	fs.Parse(args)
+
+	sid := *session
+	if sid != "" && !strings.HasPrefix(sid, "s") {
+		sid = "s" + sid
+	}
+	out := &logPrinter{
+		w:       bufio.NewWriter(os.Stdout),
+		session: sid,
+		color:   useColor(*colorMode),
+		tail:    *lines,
+	}
+
+	if *rotated {
+		for i := rotatedCount(*path); i >= 1; i-- {
+			if err := out.readGz(logfile.Rotated(*path, i)); err != nil {
+				fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+			}
+		}
+	}
+	f, err := os.Open(*path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+		return exitPreflight
+	}
+	r := bufio.NewReader(f)
+	if err := out.copyLines(r); err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+		return exitPreflight
+	}
+	out.flushTail()
+	out.w.Flush()
+	if !follow {
+		f.Close()
+		return 0
+	}
+
+	for {
+		time.Sleep(logPollInterval)
+		out.copyLines(r)
+		out.w.Flush()
+
+		// Reopen when another instance rotated the log, after draining what
+		// was written to the old file.
+		cur, err1 := f.Stat()
+		now, err2 := os.Stat(*path)
+		if err1 != nil || err2 != nil || os.SameFile(cur, now) {
+			continue
+		}
+		nf, err := os.Open(*path)
+		if err != nil {
+			continue
+		}
+		f.Close()
+		f, r = nf, bufio.NewReader(nf)
+	}
+}
+
+// rotatedCount returns how many consecutive rotated logs exist.
+func rotatedCount(path string) int {
+	n := 0
+	for {
+		if _, err := os.Stat(logfile.Rotated(path, n+1)); err != nil {
+			return n
+		}
+		n++
+	}
+}
+
+// useColor resolves --color, honoring NO_COLOR for auto.
+func useColor(mode string) bool {
+	switch mode {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// logPrinter filters log lines by session and prints them.
+type logPrinter struct {
+	w       *bufio.Writer
+	session string
+	color   bool
+
+	tail    int      // before following, keep only the last tail lines (0 = all)
+	held    []string // last lines, while tail applies
+	partial string   // unterminated line at the end of the file
+	inSess  bool     // the current entry belongs to session; continuation lines follow it
+}
+
+// copyLines prints the complete lines available from r. A trailing partial
+// line is kept until the rest of it is written.
+func (p *logPrinter) copyLines(r *bufio.Reader) error {
+	for {
+		chunk, err := r.ReadString('\n')
+		if err != nil {
+			p.partial += chunk
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		line := p.partial + chunk
+		p.partial = ""
+		p.line(strings.TrimRight(line, "\r\n"))
+	}
+}
+
+func (p *logPrinter) readGz(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	err = p.copyLines(bufio.NewReader(zr))
+	if p.partial != "" {
+		p.line(p.partial)
+		p.partial = ""
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func (p *logPrinter) line(line string) {
+	if id, ok := sessionOf(line); ok {
+		p.inSess = p.session == "" || id == p.session
+	} else if p.session == "" {
+		p.inSess = true
+	}
+	if !p.inSess {
+		return
+	}
+	if p.tail > 0 {
+		p.held = append(p.held, line)
+		if len(p.held) > p.tail {
+			p.held = p.held[1:]
+		}
+		return
+	}
+	p.print(line)
+}
+
+// flushTail prints the held lines and stops holding.
+func (p *logPrinter) flushTail() {
+	for _, l := range p.held {
+		p.print(l)
+	}
+	p.held, p.tail = nil, 0
+}
+
+func (p *logPrinter) print(line string) {
+	if p.color {
+		for _, c := range logColors {
+			if strings.Contains(line, c.marker) {
+				fmt.Fprintf(p.w, "\x1b[%sm%s\x1b[0m\n", c.color, line)
+				return
+			}
+		}
+	}
+	fmt.Fprintln(p.w, line)
+}
+
+// sessionOf returns the session ID prefix ("[s12345] ") of a log entry.
+// Lines without one continue the previous entry.
+func sessionOf(line string) (string, bool) {
+	if !strings.HasPrefix(line, "[s") {
+		return "", false
+	}
+	end := strings.Index(line, "] ")
+	if end < 0 {
+		return "", false
+	}
+	id := line[1:end]
+	for _, c := range id[1:] {
+		if c < '0' || c > '9' {
+			return "", false
+		}
+	}
+	return id, len(id) > 1
+}
@@ -3,25 +3,71 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"syscall"
+	"regexp"
+	"runtime"
+	"strings"
 	"time"
 
-	"github.com/peter-wagstaff/claude-hybrid-router/internal/config"
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/defaults"
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/logfile"
 	"github.com/peter-wagstaff/claude-hybrid-router/internal/mitm"
-	"github.com/peter-wagstaff/claude-hybrid-router/internal/proxy"
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/routerproxy"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/redact"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/router"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "usage":
+			os.Exit(runUsage(os.Args[2:]))
+		case "ca":
+			os.Exit(runCA(os.Args[2:]))
+		case "import":
+			os.Exit(runImport(os.Args[2:]))
+		case "log":
+			os.Exit(runLog(os.Args[2:]))
+		case "dash":
+			os.Exit(runDash(os.Args[2:]))
+		case "paused":
+			os.Exit(runPaused(os.Args[2:]))
+		case "marker":
+			os.Exit(runMarker(os.Args[2:]))
+		case "config":
+			os.Exit(runConfig(os.Args[2:]))
+		case "bench":
+			os.Exit(runBench(os.Args[2:]))
+		case "conformance":
+			os.Exit(runConformance(os.Args[2:]))
+		case "mock-provider":
+			os.Exit(runMockProvider(os.Args[2:]))
+		}
+	}
+
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, `Usage: claude-hybrid [proxy-flags] [-- claude-flags]
+       claude-hybrid usage [--transforms] [--speed] [--since 24h]
+       claude-hybrid ca protect|rotate
+       claude-hybrid import --from claude-code-router|y-router [file]
+       claude-hybrid log [--follow] [--session sNNN] [-n lines] [--rotated]
+       claude-hybrid dash [--addr 127.0.0.1:9901]
+       claude-hybrid paused [show|resume|edit|drop ID]
+       claude-hybrid marker [--install agent|output-style] <label> [option=value ...]
+       claude-hybrid config schema|check [file]
+       claude-hybrid bench --label NAME [--runs 3] [--scenarios chat,tools,long]
+       claude-hybrid conformance --label NAME [--cases tool_use,thinking]
+       claude-hybrid mock-provider [--port 8089] [--behavior tools|reasoning|slow|flaky] [--script file]
 
 Starts a local MITM routing proxy and launches Claude Code through it.
 Arguments after -- are passed directly to claude.
@@ -29,6 +75,7 @@ Arguments after -- are passed directly to claude.
 Examples:
   claude-hybrid
   claude-hybrid --verbose
+  claude-hybrid --profile work
   claude-hybrid -- --dangerously-skip-permissions
   claude-hybrid --verbose -- --dangerously-skip-permissions
 
@@ -41,34 +88,78 @@ Proxy flags:
 	certsDir := flag.String("certs-dir", defaultCertsDir(), "directory for CA cert/key")
 	proxyOnly := flag.Bool("proxy-only", false, "run proxy without launching claude")
 	verbose := flag.Bool("verbose", false, "enable verbose logging")
+	validateSSE := flag.Bool("validate-sse", false, "check the event order of every translated stream and log violations as [SSE_INVALID] (debugging)")
+	adminAddr := flag.String("admin-addr", "", "serve the admin API on this address, e.g. 127.0.0.1:9901 (empty = disabled)")
+	certCacheSize := flag.Int("cert-cache-size", defaults.MitmCacheMaxSize, "maximum MITM leaf certificates kept in memory (least recently used are evicted)")
+	var flagLimits config.Limits
+	flag.Int64Var(&flagLimits.MaxBodyBytes, "max-body-bytes", 0, "largest request/response body buffered, in bytes (0 = config or 10MB)")
+	flag.DurationVar(&flagLimits.UpstreamTimeout, "upstream-timeout", 0, "per-request timeout for Anthropic and providers (0 = config or 30s)")
+	flag.DurationVar(&flagLimits.ClientRecvTimeout, "client-recv-timeout", 0, "idle time allowed on a client tunnel (0 = config or 5m)")
+	flag.IntVar(&flagLimits.MaxConcurrent, "max-concurrent", 0, "tunnels handled at once (0 = config or 128)")
+	flag.IntVar(&flagLimits.MaxQueued, "max-queued", 0, "CONNECTs allowed to wait for a free tunnel slot, -1 to refuse at once (0 = config or 256)")
+	flag.DurationVar(&flagLimits.QueueTimeout, "queue-timeout", 0, "longest a CONNECT waits for a tunnel slot (0 = config or 30s)")
+	interceptFlag := flag.String("intercept", "", "comma-separated hosts to decrypt, e.g. api.anthropic.com,*.corp.example (empty = config or api.anthropic.com; * = all)")
+	proxyTokenFlag := flag.String("proxy-token", "", `require this shared secret from proxy clients; "auto" generates one per run (empty = config or none)`)
+	allowedFlag := flag.String("allowed-clients", "", "comma-separated CIDRs or addresses allowed to CONNECT; loopback always is (empty = config, or private ranges when --bind is not loopback)")
+	rateFlag := flag.Int("client-rate-limit", 0, "CONNECTs plus requests allowed per minute per client IP (0 = config or unlimited)")
+	logMaxSize := flag.String("log-max-size", "", "rotate proxy.log at this size, e.g. 50MB (empty = config or 20MB)")
+	logRetention := flag.Int("log-retention", 0, "rotated logs kept as proxy.log.N.gz, -1 for none (0 = config or 5)")
+	var sets []config.Override
+	flag.Func("set", "override a config key, e.g. --set providers.ollama.endpoint=http://gpu:11434/v1 (repeatable; wins over CLAUDE_HYBRID_* variables)", func(s string) error {
+		o, err := config.ParseSet(s)
+		if err != nil {
+			return err
+		}
+		sets = append(sets, o)
+		return nil
+	})
+	profile := flag.String("profile", "", "apply a named profile from config.yaml's profiles: or ~/.claude-hybrid/profiles/NAME.yaml")
+	pauseFlag := flag.String("pause", "", `hold tunnel requests whose "METHOD URL" matches this regexp until released with claude-hybrid paused or the admin API (empty = off)`)
+	harPath := flag.String("har", "", "record intercepted requests and responses, with bodies and timings, to this HAR file (empty = off)")
+	openaiAddr := flag.String("openai-addr", "", "serve an OpenAI-compatible API for configured labels on this address, e.g. 127.0.0.1:9902 (empty = disabled)")
 	flag.Parse()
 
 	// Ensure base directory exists
 	baseDir := filepath.Dir(*certsDir)
 	if err := os.MkdirAll(baseDir, 0700); err != nil {
 		fmt.Fprintf(os.Stderr, "create base dir: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitPreflight)
 	}
 
-	// Open log file with daily rotation. Use an exclusive lock for
-	// truncation to prevent races between concurrent instances.
+	// Open the log, rotated by size. Flags set the rotation now; config.yaml
+	// can change it once loaded.
 	logPath := filepath.Join(baseDir, "proxy.log")
-	if shouldTruncateLog(logPath) {
-		tryTruncateLog(logPath)
+	logOpts, err := logOptions(*logMaxSize, *logRetention, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitPreflight)
 	}
-	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	logFile, err := logfile.Open(logPath, logOpts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "open log file: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitPreflight)
 	}
 	defer logFile.Close()
 	sessionID := fmt.Sprintf("s%d", os.Getpid())
-	log.SetOutput(logFile)
+	// Secrets are scrubbed before they reach the log; config.yaml can add
+	// rules once it is loaded.
+	builtinRedact, _ := redact.New(nil, os.Environ())
+	logWriter := redact.NewWriter(logFile, builtinRedact)
+	log.SetOutput(logWriter)
 	log.SetPrefix(fmt.Sprintf("[%s] ", sessionID))
 
+	// Preflight: make sure claude is launchable before doing any work
+	var claudePath string
+	if !*proxyOnly {
+		claudePath, err = exec.LookPath("claude")
+		if err != nil {
+			fatalf(exitPreflight, "claude CLI not found in PATH: %v", err)
+		}
+	}
+
 	// Ensure certs directory exists
 	if err := os.MkdirAll(*certsDir, 0700); err != nil {
-		log.Fatalf("create certs dir: %v", err)
+		fatalf(exitCAError, "create certs dir: %v", err)
 	}
 
 	certPath := filepath.Join(*certsDir, "ca.crt")
@@ -89,95 +180,223 @@ Proxy flags:
 				}
 			}
 			if _, err := os.Stat(certPath); os.IsNotExist(err) {
-				log.Fatalf("timed out waiting for CA certificate generation")
+				fatalf(exitCAError, "timed out waiting for CA certificate generation")
 			}
 		} else {
 			// We won the lock — generate the CA
 			lockFile.Close()
-			defer os.Remove(lockPath)
 
 			log.Println("Generating MITM CA certificate...")
 			certPEM, keyPEM, err := mitm.GenerateCA()
 			if err != nil {
-				log.Fatalf("generate CA: %v", err)
+				fatalf(exitCAError, "generate CA: %v", err)
 			}
 			if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
-				log.Fatalf("write CA key: %v", err)
+				fatalf(exitCAError, "write CA key: %v", err)
 			}
 			// Write cert last — other instances wait for this file
 			if err := os.WriteFile(certPath, certPEM, 0644); err != nil {
-				log.Fatalf("write CA cert: %v", err)
+				fatalf(exitCAError, "write CA cert: %v", err)
 			}
+			os.Remove(lockPath)
 			log.Printf("CA certificate written to %s", certPath)
 		}
+	} else if err := renewCAIfExpiring(*certsDir); err != nil {
+		fatalf(exitCAError, "%v", err)
 	}
 
 	// Load CA
 	certPEM, err := os.ReadFile(certPath)
 	if err != nil {
-		log.Fatalf("read CA cert: %v", err)
+		fatalf(exitCAError, "read CA cert: %v", err)
 	}
-	keyPEM, err := os.ReadFile(keyPath)
+	keyPEM, err := unlockCAKey(keyPath)
 	if err != nil {
-		log.Fatalf("read CA key: %v", err)
-	}
-
-	certCache, err := mitm.NewCertCache(certPEM, keyPEM)
-	if err != nil {
-		log.Fatalf("create cert cache: %v", err)
+		fatalf(exitCAError, "read CA key: %v", err)
 	}
 
 	// Load provider config (optional)
-	opts := []proxy.Option{proxy.WithVerbose(*verbose)}
 	cfgPath := filepath.Join(baseDir, "config.yaml")
-	if _, err := os.Stat(cfgPath); err == nil {
-		cfg, err := config.LoadConfig(cfgPath)
-		if err != nil {
-			log.Fatalf("load config: %v", err)
+	cfg, persistPath, overrides, err := loadConfig(baseDir, *profile, sets)
+	if err != nil {
+		fatalf(exitConfigError, "load config: %v", err)
+	}
+	ropts := []router.Option{
+		router.WithCA(certPEM, keyPEM),
+		router.WithCertCacheSize(*certCacheSize),
+		router.WithStateDir(baseDir),
+		router.WithSessionID(sessionID),
+		router.WithVerbose(*verbose),
+		router.WithValidateSSE(*validateSSE),
+		router.WithLimits(flagLimits), // flags win over config.yaml limits
+	}
+	var allowedClients []string
+	var rateLimit config.ClientRateLimit
+	if cfg != nil {
+		if cfg.LogRedact != nil {
+			r, err := redact.New(cfg.LogRedact, os.Environ())
+			if err != nil {
+				fatalf(exitConfigError, "%v", err)
+			}
+			logWriter.SetRedactor(r)
 		}
-		resolver, err := config.NewModelResolver(cfg)
-		if err != nil {
-			log.Fatalf("build model resolver: %v", err)
+		if cfg.Log != nil {
+			opts, err := logOptions(*logMaxSize, *logRetention, cfg.Log)
+			if err != nil {
+				fatalf(exitConfigError, "%v", err)
+			}
+			logFile.SetOptions(opts)
+		}
+		allowedClients = cfg.AllowedClients
+		if cfg.ClientRateLimit != nil {
+			rateLimit = *cfg.ClientRateLimit
+		}
+		if persistPath != "" {
+			ropts = append(ropts, router.WithConfigPath(persistPath))
+		}
+		if *profile != "" {
+			log.Printf("Loaded provider config from %s with profile %s", cfgPath, *profile)
+		} else {
+			log.Printf("Loaded provider config from %s", cfgPath)
+		}
+		for _, o := range overrides {
+			log.Printf("Config override: %s", o)
 		}
-		opts = append(opts, proxy.WithModelResolver(resolver))
-		log.Printf("Loaded provider config from %s", cfgPath)
 	} else {
 		log.Printf("No config at %s — local routes will return stub responses", cfgPath)
 	}
+	adminAuth := false
+	if cfg != nil && cfg.Admin != nil {
+		read, write := cfg.Admin.Tokens()
+		adminAuth = read != "" || write != ""
+	}
+
+	// The flag wins over config.yaml's intercept list
+	if *interceptFlag != "" {
+		ropts = append(ropts, router.WithIntercept(strings.Split(*interceptFlag, ",")))
+	}
+
+	if *pauseFlag != "" {
+		filter, err := regexp.Compile(*pauseFlag)
+		if err != nil {
+			fatalf(exitConfigError, "--pause: %v", err)
+		}
+		ropts = append(ropts, router.WithPause(filter))
+		log.Printf("Pausing requests matching %s for review", filter)
+		if *adminAddr == "" {
+			log.Printf("--pause without --admin-addr: paused requests are only released by the 5 minute timeout")
+		}
+	}
+	if *harPath != "" {
+		ropts = append(ropts, router.WithHAR(*harPath))
+		log.Printf("Recording intercepted traffic to %s (request credentials are masked; bodies are not)", *harPath)
+	}
+
+	// The flag wins over config.yaml's proxy token
+	if *proxyTokenFlag != "" {
+		ropts = append(ropts, router.WithProxyToken(*proxyTokenFlag))
+	}
+
+	// Flags win over config.yaml's client access settings. A proxy or
+	// OpenAI listener bound beyond loopback with no allowlist only accepts
+	// private networks.
+	if *allowedFlag != "" {
+		allowedClients = strings.Split(*allowedFlag, ",")
+	}
+	if *rateFlag > 0 {
+		rateLimit = config.ClientRateLimit{RequestsPerMinute: *rateFlag}
+		ropts = append(ropts, router.WithClientRateLimit(*rateFlag))
+	}
+	exposed := !isLoopbackBind(*bind)
+	openaiExposed := *openaiAddr != "" && !isLoopbackAddr(*openaiAddr)
+	if (exposed || openaiExposed) && len(allowedClients) == 0 {
+		allowedClients = config.LANClients
+	}
+	ropts = append(ropts, router.WithAllowedClients(allowedClients))
+
+	rt, err := router.New(cfg, ropts...)
+	if errors.Is(err, router.ErrCA) {
+		fatalf(exitCAError, "create cert cache: %v", err)
+	} else if errors.Is(err, router.ErrHAR) {
+		fatalf(exitProxyStartup, "open %v", err)
+	} else if err != nil {
+		fatalf(exitConfigError, "%v", err)
+	}
+	p := routerproxy.Of(rt)
+	proxyToken := rt.ProxyToken()
 
 	// Start proxy
-	p := proxy.New(certCache, opts...)
 	ln, err := net.Listen("tcp", fmt.Sprintf("%s:%d", *bind, *port))
 	if err != nil {
-		log.Fatalf("listen: %v", err)
+		fatalf(exitProxyStartup, "listen: %v", err)
 	}
 	proxyAddr := ln.Addr().String()
 	log.Printf("Proxy listening on %s", proxyAddr)
+	if exposed {
+		warnExposed(fmt.Sprintf("proxy listening on %s", proxyAddr),
+			"Clients it accepts are MITM'd with this machine's CA and can use its provider API keys.",
+			allowedClients, proxyToken != "", rateLimit.RequestsPerMinute)
+	}
+	proxyURL := "http://" + proxyAddr
+	if proxyToken != "" {
+		proxyURL = (&url.URL{Scheme: "http", User: url.UserPassword("claude", proxyToken), Host: proxyAddr}).String()
+	}
 
-	srv := &http.Server{Handler: p}
+	srv := &http.Server{Handler: rt}
 	go srv.Serve(ln)
+	go rt.Preload()
+	usage := startUsageRecorder(p, filepath.Join(baseDir, "usage"), sessionID)
+
+	if *adminAddr != "" {
+		adminLn, err := net.Listen("tcp", *adminAddr)
+		if err != nil {
+			fatalf(exitProxyStartup, "admin listen: %v", err)
+		}
+		log.Printf("Admin API listening on %s", adminLn.Addr())
+		if tcp, ok := adminLn.Addr().(*net.TCPAddr); ok && !tcp.IP.IsLoopback() && !adminAuth {
+			log.Printf("Admin API is reachable beyond loopback without a token — set admin.read_token and admin.write_token in config.yaml")
+		}
+		go http.Serve(adminLn, rt.AdminHandler())
+	}
+
+	if *openaiAddr != "" {
+		openaiLn, err := net.Listen("tcp", *openaiAddr)
+		if err != nil {
+			fatalf(exitProxyStartup, "openai listen: %v", err)
+		}
+		log.Printf("OpenAI-compatible API listening on %s", openaiLn.Addr())
+		if openaiExposed {
+			warnExposed(fmt.Sprintf("OpenAI-compatible API on %s", openaiLn.Addr()),
+				"Clients it accepts can use this machine's labels and their provider API keys.",
+				allowedClients, proxyToken != "", rateLimit.RequestsPerMinute)
+		}
+		go http.Serve(openaiLn, rt.OpenAIHandler())
+	}
 
 	if *proxyOnly {
-		log.Println("Running in proxy-only mode (Ctrl+C to stop)")
+		log.Printf("Running in proxy-only mode (Ctrl+C to stop); clients should trust %s", caTrustPath(*certsDir))
+		if proxyToken != "" {
+			// The log is shared and redacted, so the token only goes to the terminal.
+			fmt.Fprintf(os.Stderr, "HTTPS_PROXY=%s\n", proxyURL)
+		}
 		// Block forever (until signal kills us)
 		select {}
 	}
 
 	// Launch claude with proxy env vars
 	claudeArgs := flag.Args()
-	cmd := exec.Command("claude", claudeArgs...)
+	cmd := exec.Command(claudePath, claudeArgs...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	cmd.Env = append(os.Environ(),
-		"HTTPS_PROXY=http://"+proxyAddr,
-		"NODE_EXTRA_CA_CERTS="+certPath,
-	)
+	cmd.Env = claudeEnv(os.Environ(), proxyURL, caTrustPath(*certsDir))
 
 	shutdown := func() {
+		usage.flush()
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 		srv.Shutdown(ctx)
+		rt.Close(ctx)
 	}
 
 	if err := cmd.Run(); err != nil {
@@ -185,51 +404,125 @@ Proxy flags:
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			os.Exit(exitErr.ExitCode())
 		}
-		log.Fatalf("claude: %v", err)
+		fatalf(exitPreflight, "claude: %v", err)
 	}
 	shutdown()
 }
 
-// shouldTruncateLog returns true if the log file was last modified before today.
-func shouldTruncateLog(path string) bool {
-	info, err := os.Stat(path)
-	if err != nil {
-		return false // file doesn't exist, will be created fresh
+// logOptions builds log rotation settings. Flags win over config.yaml's
+// log: section (cfg may be nil).
+func logOptions(maxSize string, retention int, cfg *config.LogConfig) (logfile.Options, error) {
+	var opts logfile.Options
+	if cfg != nil {
+		opts.Retention = cfg.Retention
+		if maxSize == "" {
+			maxSize = cfg.MaxSize
+		}
+	}
+	if retention != 0 {
+		opts.Retention = retention
 	}
-	now := time.Now()
-	modTime := info.ModTime()
-	return modTime.Year() != now.Year() || modTime.YearDay() != now.YearDay()
+	if maxSize != "" {
+		n, err := logfile.ParseSize(maxSize)
+		if err != nil {
+			return opts, fmt.Errorf("log max size: %w", err)
+		}
+		opts.MaxSize = n
+	}
+	return opts, nil
 }
 
-// tryTruncateLog truncates the log file while holding an exclusive lock,
-// preventing races between concurrent instances.
-func tryTruncateLog(path string) {
-	f, err := os.OpenFile(path, os.O_WRONLY, 0644)
-	if err != nil {
-		return
+// isLoopbackBind reports whether a --bind address only accepts local
+// connections.
+func isLoopbackBind(bind string) bool {
+	if bind == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(bind)
+	return ip != nil && ip.IsLoopback()
+}
+
+// isLoopbackAddr reports whether a host:port listen address, such as
+// --openai-addr, only accepts local connections. An empty host listens on
+// every interface.
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	return err == nil && host != "" && isLoopbackBind(host)
+}
+
+// warnExposed prints a banner to the terminal and the log when a listener
+// is reachable from other hosts: what it is, what a client it accepts can
+// do, and how clients are restricted.
+func warnExposed(what, risk string, allowed []string, token bool, perMinute int) {
+	auth := "none — set --proxy-token"
+	if token {
+		auth = "proxy token required"
+	}
+	rate := "unlimited — set --client-rate-limit"
+	if perMinute > 0 {
+		rate = fmt.Sprintf("%d requests/min per client", perMinute)
 	}
-	defer f.Close()
-	// Try non-blocking exclusive lock — if another instance holds it, skip truncation
-	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
-		return
+	lines := []string{
+		fmt.Sprintf("WARNING: %s is reachable from other hosts.", what),
+		risk,
+		"  allowed clients: loopback, " + strings.Join(allowed, ", "),
+		"  authentication:  " + auth,
+		"  rate limit:      " + rate,
 	}
-	defer syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
-	// Re-check after acquiring lock (another instance may have already truncated)
-	info, err := f.Stat()
+	for _, l := range lines {
+		fmt.Fprintln(os.Stderr, l)
+		log.Print(l)
+	}
+}
+
+// claudeEnv returns environ with claude pointed at the proxy. Inherited
+// proxy and CA variables are dropped first in any letter case: Windows
+// treats HTTPS_PROXY and https_proxy as one variable, and elsewhere a stale
+// lowercase https_proxy would take precedence in some HTTP stacks.
+func claudeEnv(environ []string, proxyURL, caPath string) []string {
+	env := make([]string, 0, len(environ)+2)
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		switch strings.ToUpper(name) {
+		case "HTTPS_PROXY", "NODE_EXTRA_CA_CERTS":
+			continue
+		}
+		env = append(env, kv)
+	}
+	return append(env, "HTTPS_PROXY="+proxyURL, "NODE_EXTRA_CA_CERTS="+caPath)
+}
+
+// loadConfig loads config.yaml from baseDir with the named profile, then
+// CLAUDE_HYBRID_* environment overrides, then sets. It returns a nil config
+// when there is nothing to load, the path runtime labels persist to, and the
+// overrides applied.
+func loadConfig(baseDir, profile string, sets []config.Override) (*config.ProvidersConfig, string, []config.Override, error) {
+	cfg, persistPath, err := config.LoadConfigWithProfile(filepath.Join(baseDir, "config.yaml"), filepath.Join(baseDir, "profiles"), profile)
 	if err != nil {
-		return
+		return nil, "", nil, err
+	}
+	overrides := append(config.EnvOverrides(os.Environ()), sets...)
+	if len(overrides) == 0 {
+		return cfg, persistPath, nil, nil
 	}
-	now := time.Now()
-	modTime := info.ModTime()
-	if modTime.Year() != now.Year() || modTime.YearDay() != now.YearDay() {
-		f.Truncate(0)
+	if cfg == nil {
+		cfg = &config.ProvidersConfig{}
 	}
+	cfg, err = cfg.WithOverrides(overrides)
+	return cfg, persistPath, overrides, err
 }
 
+// defaultCertsDir is certs/ under the base directory: ~/.claude-hybrid, or
+// %APPDATA%\claude-hybrid on Windows.
 func defaultCertsDir() string {
+	if runtime.GOOS == "windows" {
+		if dir, err := os.UserConfigDir(); err == nil {
+			return filepath.Join(dir, "claude-hybrid", "certs")
+		}
+	}
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return ".claude-hybrid/certs"
+		return filepath.Join(".claude-hybrid", "certs")
 	}
 	return filepath.Join(home, ".claude-hybrid", "certs")
 }
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/proxy"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+// Where --install puts the marker. Both kinds of file reach the system
+// prompt, which is the only place the proxy looks for a marker; slash
+// command files become user messages and so can't carry one.
+const (
+	installAgent       = "agent"        // ~/.claude/agents/<name>.md, dispatched as a subagent
+	installOutputStyle = "output-style" // ~/.claude/output-styles/<name>.md, picked with /output-style
+)
+
+// runMarker prints the routing marker for a label, and optionally writes it
+// into a Claude Code agent or output style.
+func runMarker(args []string) int {
+	fs := flag.NewFlagSet("marker", flag.ExitOnError)
+	install := fs.String("install", "", "also write the marker into a Claude Code file: "+installAgent+" or "+installOutputStyle)
+	name := fs.String("name", "", "name of the installed agent or output style (default: the label)")
+	claudeDir := fs.String("claude-dir", defaultClaudeDir(), "Claude Code's config directory")
+	force := fs.Bool("force", false, "overwrite an existing installed file")
+	profile := fs.String("profile", "", "check the label against this profile's providers")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: claude-hybrid marker [--install agent|output-style] [--name NAME] [--profile NAME] <label> [option=value ...]
+
+Prints the routing marker for a label in config.yaml, with optional
+per-request overrides such as temp=0.2 or transform=+reasoning. Paste it
+into an agent's system prompt, or use --install to write one:
+
+  agent         %s/agents/NAME.md, a subagent Claude Code dispatches
+  output-style  %s/output-styles/NAME.md, selected with /output-style NAME
+
+Flags:
+`, *claudeDir, *claudeDir)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return 2
+	}
+	label := fs.Arg(0)
+
+	marker, err := proxy.FormatMarker(label, fs.Args()[1:]...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+		return 2
+	}
+	if code := checkMarkerLabel(label, *profile); code != 0 {
+		return code
+	}
+
+	if *install == "" {
+		fmt.Println(marker)
+		return 0
+	}
+	if *name == "" {
+		*name = label
+	}
+	path, content, err := markerFile(*install, *claudeDir, *name, label, marker)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+		return 2
+	}
+	if _, err := os.Stat(path); err == nil && !*force {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: %s exists; pass --force to overwrite or --name to pick another name\n", path)
+		return 1
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+		return 1
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+		return 1
+	}
+	fmt.Println(marker)
+	fmt.Fprintf(os.Stderr, "Wrote %s\n", path)
+	return 0
+}
+
+// checkMarkerLabel warns when there is no config to check label against and
+// fails when the config (with profile applied) doesn't define it.
+func checkMarkerLabel(label, profile string) int {
+	baseDir := filepath.Dir(defaultCertsDir())
+	cfgPath := filepath.Join(baseDir, "config.yaml")
+	cfg, _, _, err := loadConfig(baseDir, profile, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: load config: %v\n", err)
+		return exitConfigError
+	}
+	if cfg == nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: warning: no %s; label %q is not checked\n", cfgPath, label)
+		return 0
+	}
+	resolver, err := config.NewModelResolver(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: build model resolver: %v\n", err)
+		return exitConfigError
+	}
+	if _, err := resolver.Resolve(label); err == nil {
+		return 0
+	}
+	var labels []string
+	for _, m := range resolver.Models() {
+		labels = append(labels, m.Label)
+	}
+	for name := range resolver.Aliases() {
+		labels = append(labels, name)
+	}
+	sort.Strings(labels)
+	for _, m := range resolver.Patterns() {
+		labels = append(labels, m.Label)
+	}
+	where := cfgPath
+	if profile != "" {
+		where += " with profile " + profile
+	}
+	fmt.Fprintf(os.Stderr, "claude-hybrid: label %q is not in %s (labels: %s)\n", label, where, strings.Join(labels, ", "))
+	return exitConfigError
+}
+
+// markerFile returns the path and contents of an agent or output style
+// file carrying marker.
+func markerFile(kind, claudeDir, name, label, marker string) (string, string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return "", "", fmt.Errorf("invalid name %q", name)
+	}
+	switch kind {
+	case installAgent:
+		return filepath.Join(claudeDir, "agents", name+".md"), fmt.Sprintf(`---
+name: %s
+description: Handles tasks on the %s model through claude-hybrid. Use for work suited to that model.
+---
+
+%s
+
+You are a focused assistant. Complete the task you are given and report the result concisely.
+`, name, label, marker), nil
+	case installOutputStyle:
+		return filepath.Join(claudeDir, "output-styles", name+".md"), fmt.Sprintf(`---
+name: %s
+description: Routes the main conversation to the %s model through claude-hybrid
+keep-coding-instructions: true
+---
+
+%s
+`, name, label, marker), nil
+	}
+	return "", "", fmt.Errorf("--install must be %s or %s, not %q", installAgent, installOutputStyle, kind)
+}
+
+// defaultClaudeDir is Claude Code's config directory, ~/.claude unless
+// CLAUDE_CONFIG_DIR is set.
+func defaultClaudeDir() string {
+	if dir := os.Getenv("CLAUDE_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".claude")
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/mockprovider"
+)
+
+// runMockProvider implements `claude-hybrid mock-provider`.
+func runMockProvider(args []string) int {
+	fs := flag.NewFlagSet("mock-provider", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: claude-hybrid mock-provider [flags]
+
+Serves a fake OpenAI-compatible chat completions API, so transforms and
+routing can be developed without a real model. Behaviors:
+
+  echo       a canned reply naming the model; calls Read when tools are offered
+  tools      calls the first offered tool with arguments that fit its schema,
+             then answers with text once it sees the tool result
+  reasoning  sends reasoning_content before the reply
+  slow       echo, waiting --delay before the reply and between stream chunks
+  flaky      echo, but every --fail-every-th request fails with a 500, a 429
+             or (streaming) a dropped connection, in turn
+
+--script replies from a YAML list of steps first; see the README.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	port := fs.Int("port", 8089, "port to listen on, on 127.0.0.1")
+	behavior := fs.String("behavior", mockprovider.BehaviorEcho, "how to answer: "+strings.Join(mockprovider.Behaviors, ", "))
+	delay := fs.Duration("delay", 200*time.Millisecond, "slow: wait before the reply and between stream chunks")
+	failEvery := fs.Int("fail-every", 3, "flaky: fail every Nth request")
+	script := fs.String("script", "", "YAML file of scripted replies, tried before --behavior")
+	fs.Parse(args)
+
+	opts := mockprovider.Options{Behavior: *behavior, Delay: *delay, FailEvery: *failEvery}
+	if *script != "" {
+		steps, err := mockprovider.LoadScript(*script)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+			return 2
+		}
+		opts.Script = steps
+	}
+	mock, err := mockprovider.New(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+		return 2
+	}
+	ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", *port))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+		return exitProxyStartup
+	}
+	endpoint := "http://" + ln.Addr().String() + "/v1"
+	fmt.Fprintf(os.Stderr, `Mock provider (%s) listening on %s
+Add it to config.yaml:
+
+providers:
+  - name: mock
+    endpoint: %s
+    models:
+      mock: mock-model
+
+`, *behavior, endpoint, endpoint)
+	if err := http.Serve(ln, mock); err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/proxy"
+)
+
+// runPaused implements `claude-hybrid paused`: lists the requests a proxy
+// started with --pause is holding, and resumes (optionally after editing
+// the body) or drops them through the admin API.
+func runPaused(args []string) int {
+	fs := flag.NewFlagSet("paused", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: claude-hybrid paused [flags]                 list held requests
+       claude-hybrid paused [flags] show ID         print one, with its body
+       claude-hybrid paused [flags] resume ID       send it on unchanged
+       claude-hybrid paused [flags] edit ID         edit the body in $EDITOR, then send it
+       claude-hybrid paused [flags] drop ID         answer it with an error
+
+Works with a proxy started with --pause and --admin-addr. A held request
+that gets no decision is sent on unchanged after 5 minutes.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	addr := fs.String("addr", "127.0.0.1:9901", "admin API address of the running proxy (its --admin-addr)")
+	token := fs.String("token", os.Getenv("CLAUDE_HYBRID_ADMIN_TOKEN"), "admin write token (default $CLAUDE_HYBRID_ADMIN_TOKEN)")
+	fs.Parse(args)
+
+	c := &dashClient{base: "http://" + strings.TrimPrefix(*addr, "http://"), token: *token,
+		http: &http.Client{Timeout: 5 * time.Second}}
+	if fs.NArg() == 0 {
+		return listPaused(c)
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+	cmd, id := fs.Arg(0), fs.Arg(1)
+	var err error
+	switch cmd {
+	case "show":
+		var held *proxy.PausedRequest
+		if held, err = findPaused(c, id); err == nil {
+			fmt.Printf("%s %s\n", held.Method, held.URL)
+			names := make([]string, 0, len(held.Headers))
+			for name := range held.Headers {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				for _, v := range held.Headers[name] {
+					fmt.Printf("%s: %s\n", name, v)
+				}
+			}
+			fmt.Printf("\n%s\n", indentJSON(held.Body))
+		}
+	case "resume":
+		err = c.post("/admin/paused/"+id+"/resume", nil)
+	case "edit":
+		err = editPaused(c, id)
+	case "drop":
+		err = c.post("/admin/paused/"+id+"/drop", nil)
+	default:
+		fs.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func listPaused(c *dashClient) int {
+	var resp struct {
+		Paused []proxy.PausedRequest `json:"paused"`
+	}
+	if err := c.get("/admin/paused", &resp); err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: %v\n", err)
+		return 1
+	}
+	if len(resp.Paused) == 0 {
+		fmt.Println("No paused requests.")
+		return 0
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tWAITING\tREQUEST\tBODY")
+	for _, r := range resp.Paused {
+		fmt.Fprintf(tw, "%s\t%s\t%s %s\t%d bytes\n", r.ID, time.Since(r.Since).Truncate(time.Second),
+			r.Method, r.URL, len(r.Body))
+	}
+	tw.Flush()
+	return 0
+}
+
+func findPaused(c *dashClient, id string) (*proxy.PausedRequest, error) {
+	var resp struct {
+		Paused []proxy.PausedRequest `json:"paused"`
+	}
+	if err := c.get("/admin/paused", &resp); err != nil {
+		return nil, err
+	}
+	for _, r := range resp.Paused {
+		if r.ID == id {
+			return &r, nil
+		}
+	}
+	return nil, fmt.Errorf("no paused request %s (already sent, dropped or timed out?)", id)
+}
+
+// editPaused opens the request body in $EDITOR (indented when it's JSON)
+// and resumes the request with the saved file. An unchanged file sends
+// the original body.
+func editPaused(c *dashClient, id string) error {
+	held, err := findPaused(c, id)
+	if err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp("", "claude-hybrid-paused")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "request-"+id+".json")
+	original := indentJSON(held.Body)
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		return err
+	}
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor: %v", err)
+	}
+	edited, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if string(edited) == original {
+		return c.post("/admin/paused/"+id+"/resume", nil)
+	}
+	body := string(edited)
+	if json.Valid(edited) {
+		// Compact it again, as clients send it.
+		var buf bytes.Buffer
+		json.Compact(&buf, edited)
+		body = buf.String()
+	}
+	return c.post("/admin/paused/"+id+"/resume", map[string]string{"body": body})
+}
+
+// indentJSON pretty-prints body when it's JSON and returns it as is
+// otherwise.
+func indentJSON(body string) string {
+	var buf bytes.Buffer
+	if json.Indent(&buf, []byte(body), "", "  ") != nil {
+		return body
+	}
+	return buf.String() + "\n"
+}
+
+func (c *dashClient) post(path string, v interface{}) error {
+	var body io.Reader
+	if v != nil {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest("POST", c.base+path, body)
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&e)
+		return fmt.Errorf("%s: %s %s", path, resp.Status, e.Error)
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/proxy"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/translate"
+)
+
+const (
+	usageFlushInterval = 30 * time.Second
+	usageRetention     = 30 * 24 * time.Hour
+)
+
+// sessionUsage is one proxy session's counters, as written to
+// ~/.claude-hybrid/usage/<started>-<session>.json.
+type sessionUsage struct {
+	Session    string                     `json:"session"`
+	Started    time.Time                  `json:"started"`
+	Updated    time.Time                  `json:"updated"`
+	Transforms []translate.TransformCount `json:"transforms"`
+	Speed      []proxy.SpeedStats         `json:"speed,omitempty"`
+}
+
+// usageRecorder periodically saves the running proxy's counters so
+// `claude-hybrid usage` can report on sessions after they end. Each session
+// owns its file, so concurrent instances never contend.
+type usageRecorder struct {
+	p    *proxy.Proxy
+	path string
+	rec  sessionUsage
+}
+
+func startUsageRecorder(p *proxy.Proxy, dir, session string) *usageRecorder {
+	os.MkdirAll(dir, 0700)
+	pruneUsage(dir)
+	started := time.Now()
+	r := &usageRecorder{
+		p:    p,
+		path: filepath.Join(dir, started.Format("20060102-150405")+"-"+session+".json"),
+		rec:  sessionUsage{Session: session, Started: started},
+	}
+	go func() {
+		for range time.Tick(usageFlushInterval) {
+			r.flush()
+		}
+	}()
+	return r
+}
+
+// flush writes the session file. Sessions that never ran a transform or
+// finished a local stream leave no file behind.
+func (r *usageRecorder) flush() {
+	m := r.p.Metrics()
+	r.rec.Transforms, r.rec.Speed = m.Transforms, m.Speed
+	if len(r.rec.Transforms) == 0 && len(r.rec.Speed) == 0 {
+		return
+	}
+	r.rec.Updated = time.Now()
+	data, err := json.MarshalIndent(r.rec, "", "  ")
+	if err != nil {
+		return
+	}
+	tmp := r.path + ".tmp"
+	if os.WriteFile(tmp, data, 0600) == nil {
+		os.Rename(tmp, r.path)
+	}
+}
+
+// pruneUsage removes session files older than usageRetention.
+func pruneUsage(dir string) {
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if info, err := e.Info(); err == nil && time.Since(info.ModTime()) > usageRetention {
+			os.Remove(filepath.Join(dir, e.Name()))
+		}
+	}
+}
+
+func loadUsage(dir string, since time.Duration, session string) ([]sessionUsage, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var out []sessionUsage
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var s sessionUsage
+		if json.Unmarshal(data, &s) != nil {
+			continue
+		}
+		if session != "" && s.Session != session {
+			continue
+		}
+		if since > 0 && time.Since(s.Updated) > since {
+			continue
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// sumTransforms merges per-session rows by transform, provider and model,
+// worst offenders first.
+func sumTransforms(sessions []sessionUsage) []translate.TransformCount {
+	type key struct{ transform, provider, model string }
+	totals := map[key]*translate.TransformCount{}
+	for _, s := range sessions {
+		for _, c := range s.Transforms {
+			k := key{c.Transform, c.Provider, c.Model}
+			t, ok := totals[k]
+			if !ok {
+				t = &translate.TransformCount{Transform: c.Transform, Provider: c.Provider, Model: c.Model}
+				totals[k] = t
+			}
+			t.Errors += c.Errors
+			t.Suppressed += c.Suppressed
+			t.Repairs += c.Repairs
+		}
+	}
+	out := make([]translate.TransformCount, 0, len(totals))
+	for _, t := range totals {
+		out = append(out, *t)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Errors != b.Errors {
+			return a.Errors > b.Errors
+		}
+		if a.Repairs != b.Repairs {
+			return a.Repairs > b.Repairs
+		}
+		return a.Transform+a.Provider+a.Model < b.Transform+b.Provider+b.Model
+	})
+	return out
+}
+
+// sumSpeed merges per-session stream speeds by label, provider and model.
+func sumSpeed(sessions []sessionUsage) []proxy.SpeedStats {
+	type key struct{ label, provider, model string }
+	totals := map[key]*proxy.SpeedStats{}
+	for _, s := range sessions {
+		for _, sp := range s.Speed {
+			k := key{sp.Label, sp.Provider, sp.Model}
+			t, ok := totals[k]
+			if !ok {
+				t = &proxy.SpeedStats{Label: sp.Label, Provider: sp.Provider, Model: sp.Model}
+				totals[k] = t
+			}
+			t.Add(sp)
+		}
+	}
+	out := make([]proxy.SpeedStats, 0, len(totals))
+	for _, t := range totals {
+		out = append(out, *t)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Label != b.Label {
+			return a.Label < b.Label
+		}
+		return a.Provider+a.Model < b.Provider+b.Model
+	})
+	return out
+}
+
+// runUsage implements `claude-hybrid usage`.
+func runUsage(args []string) int {
+	fs := flag.NewFlagSet("usage", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: claude-hybrid usage [flags]
+
+Reports counters saved by past and running proxy sessions.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	transforms := fs.Bool("transforms", false, "report per-transform errors, suppressed chunks and repairs")
+	speed := fs.Bool("speed", false, "report time to first token and output tokens/sec per label and provider")
+	since := fs.Duration("since", 0, "only include sessions active within this window, e.g. 24h (0 = all kept sessions)")
+	session := fs.String("session", "", "only include this session ID, e.g. s12345 (the [sNNN] prefix in proxy.log)")
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	dir := fs.String("dir", filepath.Join(filepath.Dir(defaultCertsDir()), "usage"), "directory holding session usage files")
+	fs.Parse(args)
+
+	// With no report flag, show every report.
+	all := !*transforms && !*speed
+
+	sessions, err := loadUsage(*dir, *since, *session)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "claude-hybrid: read usage: %v\n", err)
+		return exitPreflight
+	}
+
+	report := map[string]interface{}{"sessions": len(sessions)}
+	if *transforms || all {
+		report["transforms"] = sumTransforms(sessions)
+	}
+	if *speed || all {
+		report["speed"] = sumSpeed(sessions)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+		return 0
+	}
+	if rows, ok := report["transforms"].([]translate.TransformCount); ok {
+		printTransforms(rows, len(sessions))
+	}
+	if rows, ok := report["speed"].([]proxy.SpeedStats); ok {
+		if all {
+			fmt.Println()
+		}
+		printSpeed(rows, len(sessions))
+	}
+	return 0
+}
+
+func printTransforms(rows []translate.TransformCount, sessions int) {
+	if len(rows) == 0 {
+		fmt.Println("No transform activity recorded.")
+		return
+	}
+	fmt.Printf("Transforms across %d session(s):\n\n", sessions)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TRANSFORM\tPROVIDER\tMODEL\tERRORS\tSUPPRESSED\tREPAIRS")
+	for _, r := range rows {
+		model := r.Model
+		if model == "" {
+			model = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%d\n", r.Transform, r.Provider, model, r.Errors, r.Suppressed, r.Repairs)
+	}
+	tw.Flush()
+}
+
+func printSpeed(rows []proxy.SpeedStats, sessions int) {
+	if len(rows) == 0 {
+		fmt.Println("No local streams recorded.")
+		return
+	}
+	fmt.Printf("Local stream speed across %d session(s):\n\n", sessions)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LABEL\tPROVIDER\tMODEL\tSTREAMS\tAVG TTFT\tTOKENS/SEC")
+	for _, r := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%dms\t%.1f\n", r.Label, r.Provider, r.Model, r.Streams, r.AvgTTFTMs, r.TokensPerSec)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,86 @@
+// openai2anthropic translates an OpenAI Chat Completions response on stdin
+// to an Anthropic Messages response on stdout, as the proxy does for a
+// local route. A server-sent event stream is translated event by event as
+// it arrives, so it can sit at the end of a curl -N pipeline.
+//
+// Usage: openai2anthropic [-label NAME] [-stream] [-transform a,b] < response
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/translate"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: openai2anthropic [flags] < openai-response > anthropic-response\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	label := flag.String("label", "local", "model name to report in the response, like a marker label")
+	stream := flag.Bool("stream", false, "read an SSE stream (detected when the input starts with an SSE field)")
+	transform := flag.String("transform", "", "comma-separated response transforms to run, as in a provider's transform: list")
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(os.Stdin, os.Stdout, *label, *stream, *transform); err != nil {
+		fmt.Fprintf(os.Stderr, "openai2anthropic: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(r io.Reader, w io.Writer, label string, stream bool, transform string) error {
+	chain, err := translate.BuildChain(splitList(transform))
+	if err != nil {
+		return err
+	}
+	ctx := translate.NewTransformContext(label, "cli")
+
+	br := bufio.NewReader(r)
+	if !stream {
+		// Peeking blocks until the first bytes arrive, which a live stream
+		// sends at once.
+		head, _ := br.Peek(16)
+		head = bytes.TrimLeft(head, " \t\r\n")
+		stream = bytes.HasPrefix(head, []byte("data:")) || bytes.HasPrefix(head, []byte("event:")) ||
+			bytes.HasPrefix(head, []byte(":"))
+	}
+	if stream {
+		st := translate.NewStreamTranslator(label)
+		st.SetTransformChain(chain, ctx)
+		return st.TranslateStream(br, w)
+	}
+
+	body, err := io.ReadAll(br)
+	if err != nil {
+		return err
+	}
+	if body, err = chain.RunResponse(body, ctx); err != nil {
+		return fmt.Errorf("transform: %w", err)
+	}
+	out, err := translate.ResponseToAnthropic(body, label)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", out)
+	return err
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			out = append(out, name)
+		}
+	}
+	return out
+}
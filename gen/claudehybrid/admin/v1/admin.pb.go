@@ -0,0 +1,1632 @@
+// The admin API of a running claude-hybrid, for typed clients. It is
+// served on the --admin-addr port over the Connect protocol with the JSON
+// codec (https://connectrpc.com/docs/protocol), next to the REST endpoints
+// under /admin/, with the same bearer tokens and read-only mode.
+//
+// Generate a client with buf, e.g. for connect-es or connect-go, and
+// configure it for JSON: useBinaryFormat: false in connect-es,
+// connect.WithProtoJSON() in connect-go. The binary protobuf codec and
+// gRPC framing are not served. buf.gen.yaml at the repository root
+// writes the Go client to gen/claudehybrid/admin/v1/adminv1connect.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: claudehybrid/admin/v1/admin.proto
+
+package adminv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type HealthRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HealthRequest) Reset() {
+	*x = HealthRequest{}
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HealthRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthRequest) ProtoMessage() {}
+
+func (x *HealthRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HealthRequest.ProtoReflect.Descriptor instead.
+func (*HealthRequest) Descriptor() ([]byte, []int) {
+	return file_claudehybrid_admin_v1_admin_proto_rawDescGZIP(), []int{0}
+}
+
+type HealthResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HealthResponse) Reset() {
+	*x = HealthResponse{}
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HealthResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthResponse) ProtoMessage() {}
+
+func (x *HealthResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HealthResponse.ProtoReflect.Descriptor instead.
+func (*HealthResponse) Descriptor() ([]byte, []int) {
+	return file_claudehybrid_admin_v1_admin_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *HealthResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+type GetMetricsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMetricsRequest) Reset() {
+	*x = GetMetricsRequest{}
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMetricsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMetricsRequest) ProtoMessage() {}
+
+func (x *GetMetricsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMetricsRequest.ProtoReflect.Descriptor instead.
+func (*GetMetricsRequest) Descriptor() ([]byte, []int) {
+	return file_claudehybrid_admin_v1_admin_proto_rawDescGZIP(), []int{2}
+}
+
+type GetActivityRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetActivityRequest) Reset() {
+	*x = GetActivityRequest{}
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetActivityRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetActivityRequest) ProtoMessage() {}
+
+func (x *GetActivityRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetActivityRequest.ProtoReflect.Descriptor instead.
+func (*GetActivityRequest) Descriptor() ([]byte, []int) {
+	return file_claudehybrid_admin_v1_admin_proto_rawDescGZIP(), []int{3}
+}
+
+type WatchActivityRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Milliseconds between snapshots; 0 means 1000, and less than 100 is
+	// raised to 100.
+	IntervalMs    int32 `protobuf:"varint,1,opt,name=interval_ms,json=intervalMs,proto3" json:"interval_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchActivityRequest) Reset() {
+	*x = WatchActivityRequest{}
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchActivityRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchActivityRequest) ProtoMessage() {}
+
+func (x *WatchActivityRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchActivityRequest.ProtoReflect.Descriptor instead.
+func (*WatchActivityRequest) Descriptor() ([]byte, []int) {
+	return file_claudehybrid_admin_v1_admin_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *WatchActivityRequest) GetIntervalMs() int32 {
+	if x != nil {
+		return x.IntervalMs
+	}
+	return 0
+}
+
+type ListConversationsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListConversationsRequest) Reset() {
+	*x = ListConversationsRequest{}
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListConversationsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListConversationsRequest) ProtoMessage() {}
+
+func (x *ListConversationsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListConversationsRequest.ProtoReflect.Descriptor instead.
+func (*ListConversationsRequest) Descriptor() ([]byte, []int) {
+	return file_claudehybrid_admin_v1_admin_proto_rawDescGZIP(), []int{5}
+}
+
+type ConversationSummary struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// The start of the first user message.
+	Title         string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Started       *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=started,proto3" json:"started,omitempty"`
+	Updated       *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=updated,proto3" json:"updated,omitempty"`
+	Local         int32                  `protobuf:"varint,5,opt,name=local,proto3" json:"local,omitempty"`
+	Upstream      int32                  `protobuf:"varint,6,opt,name=upstream,proto3" json:"upstream,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConversationSummary) Reset() {
+	*x = ConversationSummary{}
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConversationSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConversationSummary) ProtoMessage() {}
+
+func (x *ConversationSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConversationSummary.ProtoReflect.Descriptor instead.
+func (*ConversationSummary) Descriptor() ([]byte, []int) {
+	return file_claudehybrid_admin_v1_admin_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ConversationSummary) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ConversationSummary) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *ConversationSummary) GetStarted() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Started
+	}
+	return nil
+}
+
+func (x *ConversationSummary) GetUpdated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Updated
+	}
+	return nil
+}
+
+func (x *ConversationSummary) GetLocal() int32 {
+	if x != nil {
+		return x.Local
+	}
+	return 0
+}
+
+func (x *ConversationSummary) GetUpstream() int32 {
+	if x != nil {
+		return x.Upstream
+	}
+	return 0
+}
+
+type ListConversationsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Conversations []*ConversationSummary `protobuf:"bytes,1,rep,name=conversations,proto3" json:"conversations,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListConversationsResponse) Reset() {
+	*x = ListConversationsResponse{}
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListConversationsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListConversationsResponse) ProtoMessage() {}
+
+func (x *ListConversationsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListConversationsResponse.ProtoReflect.Descriptor instead.
+func (*ListConversationsResponse) Descriptor() ([]byte, []int) {
+	return file_claudehybrid_admin_v1_admin_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListConversationsResponse) GetConversations() []*ConversationSummary {
+	if x != nil {
+		return x.Conversations
+	}
+	return nil
+}
+
+type GetConversationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetConversationRequest) Reset() {
+	*x = GetConversationRequest{}
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetConversationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetConversationRequest) ProtoMessage() {}
+
+func (x *GetConversationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetConversationRequest.ProtoReflect.Descriptor instead.
+func (*GetConversationRequest) Descriptor() ([]byte, []int) {
+	return file_claudehybrid_admin_v1_admin_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetConversationRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type HistoryEntry struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Time  *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	// Messages in the request; the entry records who wrote the next one.
+	Turn int32 `protobuf:"varint,2,opt,name=turn,proto3" json:"turn,omitempty"`
+	// "local" or "upstream".
+	Route    string `protobuf:"bytes,3,opt,name=route,proto3" json:"route,omitempty"`
+	Label    string `protobuf:"bytes,4,opt,name=label,proto3" json:"label,omitempty"`
+	Provider string `protobuf:"bytes,5,opt,name=provider,proto3" json:"provider,omitempty"`
+	Model    string `protobuf:"bytes,6,opt,name=model,proto3" json:"model,omitempty"`
+	// Local routes: "ok", "dedupe" or the LOCAL_ERR category.
+	Status        string `protobuf:"bytes,7,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HistoryEntry) Reset() {
+	*x = HistoryEntry{}
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HistoryEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistoryEntry) ProtoMessage() {}
+
+func (x *HistoryEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HistoryEntry.ProtoReflect.Descriptor instead.
+func (*HistoryEntry) Descriptor() ([]byte, []int) {
+	return file_claudehybrid_admin_v1_admin_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *HistoryEntry) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *HistoryEntry) GetTurn() int32 {
+	if x != nil {
+		return x.Turn
+	}
+	return 0
+}
+
+func (x *HistoryEntry) GetRoute() string {
+	if x != nil {
+		return x.Route
+	}
+	return ""
+}
+
+func (x *HistoryEntry) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *HistoryEntry) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *HistoryEntry) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *HistoryEntry) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+type Conversation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Title         string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Started       *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=started,proto3" json:"started,omitempty"`
+	Updated       *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=updated,proto3" json:"updated,omitempty"`
+	Local         int32                  `protobuf:"varint,5,opt,name=local,proto3" json:"local,omitempty"`
+	Upstream      int32                  `protobuf:"varint,6,opt,name=upstream,proto3" json:"upstream,omitempty"`
+	Entries       []*HistoryEntry        `protobuf:"bytes,7,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Conversation) Reset() {
+	*x = Conversation{}
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Conversation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Conversation) ProtoMessage() {}
+
+func (x *Conversation) ProtoReflect() protoreflect.Message {
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Conversation.ProtoReflect.Descriptor instead.
+func (*Conversation) Descriptor() ([]byte, []int) {
+	return file_claudehybrid_admin_v1_admin_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Conversation) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Conversation) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Conversation) GetStarted() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Started
+	}
+	return nil
+}
+
+func (x *Conversation) GetUpdated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Updated
+	}
+	return nil
+}
+
+func (x *Conversation) GetLocal() int32 {
+	if x != nil {
+		return x.Local
+	}
+	return 0
+}
+
+func (x *Conversation) GetUpstream() int32 {
+	if x != nil {
+		return x.Upstream
+	}
+	return 0
+}
+
+func (x *Conversation) GetEntries() []*HistoryEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+type ListModelsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListModelsRequest) Reset() {
+	*x = ListModelsRequest{}
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListModelsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListModelsRequest) ProtoMessage() {}
+
+func (x *ListModelsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListModelsRequest.ProtoReflect.Descriptor instead.
+func (*ListModelsRequest) Descriptor() ([]byte, []int) {
+	return file_claudehybrid_admin_v1_admin_proto_rawDescGZIP(), []int{11}
+}
+
+type Model struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Label     string                 `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
+	Provider  string                 `protobuf:"bytes,2,opt,name=provider,proto3" json:"provider,omitempty"`
+	Model     string                 `protobuf:"bytes,3,opt,name=model,proto3" json:"model,omitempty"`
+	Endpoint  string                 `protobuf:"bytes,4,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	Transform []string               `protobuf:"bytes,5,rep,name=transform,proto3" json:"transform,omitempty"`
+	Preload   bool                   `protobuf:"varint,6,opt,name=preload,proto3" json:"preload,omitempty"`
+	KeepAlive string                 `protobuf:"bytes,7,opt,name=keep_alive,json=keepAlive,proto3" json:"keep_alive,omitempty"`
+	// A wildcard label; model may hold {label} or {N}.
+	Pattern bool `protobuf:"varint,8,opt,name=pattern,proto3" json:"pattern,omitempty"`
+	// The embeddings wire format, for embedding labels.
+	Embedding     string `protobuf:"bytes,9,opt,name=embedding,proto3" json:"embedding,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Model) Reset() {
+	*x = Model{}
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Model) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Model) ProtoMessage() {}
+
+func (x *Model) ProtoReflect() protoreflect.Message {
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Model.ProtoReflect.Descriptor instead.
+func (*Model) Descriptor() ([]byte, []int) {
+	return file_claudehybrid_admin_v1_admin_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Model) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *Model) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *Model) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *Model) GetEndpoint() string {
+	if x != nil {
+		return x.Endpoint
+	}
+	return ""
+}
+
+func (x *Model) GetTransform() []string {
+	if x != nil {
+		return x.Transform
+	}
+	return nil
+}
+
+func (x *Model) GetPreload() bool {
+	if x != nil {
+		return x.Preload
+	}
+	return false
+}
+
+func (x *Model) GetKeepAlive() string {
+	if x != nil {
+		return x.KeepAlive
+	}
+	return ""
+}
+
+func (x *Model) GetPattern() bool {
+	if x != nil {
+		return x.Pattern
+	}
+	return false
+}
+
+func (x *Model) GetEmbedding() string {
+	if x != nil {
+		return x.Embedding
+	}
+	return ""
+}
+
+type ListModelsResponse struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Models []*Model               `protobuf:"bytes,1,rep,name=models,proto3" json:"models,omitempty"`
+	// Alias name to its labels, in order: {"fast": ["a", "b"]}.
+	Aliases       *structpb.Struct `protobuf:"bytes,2,opt,name=aliases,proto3" json:"aliases,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListModelsResponse) Reset() {
+	*x = ListModelsResponse{}
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListModelsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListModelsResponse) ProtoMessage() {}
+
+func (x *ListModelsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListModelsResponse.ProtoReflect.Descriptor instead.
+func (*ListModelsResponse) Descriptor() ([]byte, []int) {
+	return file_claudehybrid_admin_v1_admin_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ListModelsResponse) GetModels() []*Model {
+	if x != nil {
+		return x.Models
+	}
+	return nil
+}
+
+func (x *ListModelsResponse) GetAliases() *structpb.Struct {
+	if x != nil {
+		return x.Aliases
+	}
+	return nil
+}
+
+type AddLabelRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Label     string                 `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
+	Provider  string                 `protobuf:"bytes,2,opt,name=provider,proto3" json:"provider,omitempty"`
+	Model     string                 `protobuf:"bytes,3,opt,name=model,proto3" json:"model,omitempty"`
+	MaxTokens int32                  `protobuf:"varint,4,opt,name=max_tokens,json=maxTokens,proto3" json:"max_tokens,omitempty"`
+	Transform []string               `protobuf:"bytes,5,rep,name=transform,proto3" json:"transform,omitempty"`
+	// Settings for a new provider; rejected for one that exists.
+	Endpoint string `protobuf:"bytes,6,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	ApiKey   string `protobuf:"bytes,7,opt,name=api_key,json=apiKey,proto3" json:"api_key,omitempty"`
+	Api      string `protobuf:"bytes,8,opt,name=api,proto3" json:"api,omitempty"`
+	Group    string `protobuf:"bytes,9,opt,name=group,proto3" json:"group,omitempty"`
+	// Also write the label to config.yaml.
+	Persist       bool `protobuf:"varint,10,opt,name=persist,proto3" json:"persist,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddLabelRequest) Reset() {
+	*x = AddLabelRequest{}
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddLabelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddLabelRequest) ProtoMessage() {}
+
+func (x *AddLabelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddLabelRequest.ProtoReflect.Descriptor instead.
+func (*AddLabelRequest) Descriptor() ([]byte, []int) {
+	return file_claudehybrid_admin_v1_admin_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *AddLabelRequest) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *AddLabelRequest) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *AddLabelRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *AddLabelRequest) GetMaxTokens() int32 {
+	if x != nil {
+		return x.MaxTokens
+	}
+	return 0
+}
+
+func (x *AddLabelRequest) GetTransform() []string {
+	if x != nil {
+		return x.Transform
+	}
+	return nil
+}
+
+func (x *AddLabelRequest) GetEndpoint() string {
+	if x != nil {
+		return x.Endpoint
+	}
+	return ""
+}
+
+func (x *AddLabelRequest) GetApiKey() string {
+	if x != nil {
+		return x.ApiKey
+	}
+	return ""
+}
+
+func (x *AddLabelRequest) GetApi() string {
+	if x != nil {
+		return x.Api
+	}
+	return ""
+}
+
+func (x *AddLabelRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *AddLabelRequest) GetPersist() bool {
+	if x != nil {
+		return x.Persist
+	}
+	return false
+}
+
+type UnloadModelRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Label         string                 `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnloadModelRequest) Reset() {
+	*x = UnloadModelRequest{}
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnloadModelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnloadModelRequest) ProtoMessage() {}
+
+func (x *UnloadModelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnloadModelRequest.ProtoReflect.Descriptor instead.
+func (*UnloadModelRequest) Descriptor() ([]byte, []int) {
+	return file_claudehybrid_admin_v1_admin_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *UnloadModelRequest) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+type UnloadModelResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	Label         string                 `protobuf:"bytes,2,opt,name=label,proto3" json:"label,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnloadModelResponse) Reset() {
+	*x = UnloadModelResponse{}
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnloadModelResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnloadModelResponse) ProtoMessage() {}
+
+func (x *UnloadModelResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnloadModelResponse.ProtoReflect.Descriptor instead.
+func (*UnloadModelResponse) Descriptor() ([]byte, []int) {
+	return file_claudehybrid_admin_v1_admin_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *UnloadModelResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *UnloadModelResponse) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+type ListPausedRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPausedRequest) Reset() {
+	*x = ListPausedRequest{}
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPausedRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPausedRequest) ProtoMessage() {}
+
+func (x *ListPausedRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPausedRequest.ProtoReflect.Descriptor instead.
+func (*ListPausedRequest) Descriptor() ([]byte, []int) {
+	return file_claudehybrid_admin_v1_admin_proto_rawDescGZIP(), []int{17}
+}
+
+type PausedRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Id     string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Method string                 `protobuf:"bytes,2,opt,name=method,proto3" json:"method,omitempty"`
+	Url    string                 `protobuf:"bytes,3,opt,name=url,proto3" json:"url,omitempty"`
+	// Header name to its values; credentials are masked.
+	Headers       *structpb.Struct       `protobuf:"bytes,4,opt,name=headers,proto3" json:"headers,omitempty"`
+	Body          string                 `protobuf:"bytes,5,opt,name=body,proto3" json:"body,omitempty"`
+	Since         *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=since,proto3" json:"since,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PausedRequest) Reset() {
+	*x = PausedRequest{}
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PausedRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PausedRequest) ProtoMessage() {}
+
+func (x *PausedRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PausedRequest.ProtoReflect.Descriptor instead.
+func (*PausedRequest) Descriptor() ([]byte, []int) {
+	return file_claudehybrid_admin_v1_admin_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *PausedRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *PausedRequest) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *PausedRequest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *PausedRequest) GetHeaders() *structpb.Struct {
+	if x != nil {
+		return x.Headers
+	}
+	return nil
+}
+
+func (x *PausedRequest) GetBody() string {
+	if x != nil {
+		return x.Body
+	}
+	return ""
+}
+
+func (x *PausedRequest) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+type ListPausedResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Paused        []*PausedRequest       `protobuf:"bytes,1,rep,name=paused,proto3" json:"paused,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPausedResponse) Reset() {
+	*x = ListPausedResponse{}
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPausedResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPausedResponse) ProtoMessage() {}
+
+func (x *ListPausedResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPausedResponse.ProtoReflect.Descriptor instead.
+func (*ListPausedResponse) Descriptor() ([]byte, []int) {
+	return file_claudehybrid_admin_v1_admin_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ListPausedResponse) GetPaused() []*PausedRequest {
+	if x != nil {
+		return x.Paused
+	}
+	return nil
+}
+
+type ResumePausedRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Replaces the request body; leave unset to send it unchanged.
+	Body          *string `protobuf:"bytes,2,opt,name=body,proto3,oneof" json:"body,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResumePausedRequest) Reset() {
+	*x = ResumePausedRequest{}
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResumePausedRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResumePausedRequest) ProtoMessage() {}
+
+func (x *ResumePausedRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResumePausedRequest.ProtoReflect.Descriptor instead.
+func (*ResumePausedRequest) Descriptor() ([]byte, []int) {
+	return file_claudehybrid_admin_v1_admin_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ResumePausedRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ResumePausedRequest) GetBody() string {
+	if x != nil && x.Body != nil {
+		return *x.Body
+	}
+	return ""
+}
+
+type ResumePausedResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	Id            string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResumePausedResponse) Reset() {
+	*x = ResumePausedResponse{}
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResumePausedResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResumePausedResponse) ProtoMessage() {}
+
+func (x *ResumePausedResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResumePausedResponse.ProtoReflect.Descriptor instead.
+func (*ResumePausedResponse) Descriptor() ([]byte, []int) {
+	return file_claudehybrid_admin_v1_admin_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ResumePausedResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ResumePausedResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type DropPausedRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DropPausedRequest) Reset() {
+	*x = DropPausedRequest{}
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DropPausedRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DropPausedRequest) ProtoMessage() {}
+
+func (x *DropPausedRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DropPausedRequest.ProtoReflect.Descriptor instead.
+func (*DropPausedRequest) Descriptor() ([]byte, []int) {
+	return file_claudehybrid_admin_v1_admin_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *DropPausedRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type DropPausedResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	Id            string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DropPausedResponse) Reset() {
+	*x = DropPausedResponse{}
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DropPausedResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DropPausedResponse) ProtoMessage() {}
+
+func (x *DropPausedResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_claudehybrid_admin_v1_admin_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DropPausedResponse.ProtoReflect.Descriptor instead.
+func (*DropPausedResponse) Descriptor() ([]byte, []int) {
+	return file_claudehybrid_admin_v1_admin_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *DropPausedResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *DropPausedResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+var File_claudehybrid_admin_v1_admin_proto protoreflect.FileDescriptor
+
+const file_claudehybrid_admin_v1_admin_proto_rawDesc = "" +
+	"\n" +
+	"!claudehybrid/admin/v1/admin.proto\x12\x15claudehybrid.admin.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x0f\n" +
+	"\rHealthRequest\"(\n" +
+	"\x0eHealthResponse\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\"\x13\n" +
+	"\x11GetMetricsRequest\"\x14\n" +
+	"\x12GetActivityRequest\"7\n" +
+	"\x14WatchActivityRequest\x12\x1f\n" +
+	"\vinterval_ms\x18\x01 \x01(\x05R\n" +
+	"intervalMs\"\x1a\n" +
+	"\x18ListConversationsRequest\"\xd9\x01\n" +
+	"\x13ConversationSummary\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x124\n" +
+	"\astarted\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\astarted\x124\n" +
+	"\aupdated\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\aupdated\x12\x14\n" +
+	"\x05local\x18\x05 \x01(\x05R\x05local\x12\x1a\n" +
+	"\bupstream\x18\x06 \x01(\x05R\bupstream\"m\n" +
+	"\x19ListConversationsResponse\x12P\n" +
+	"\rconversations\x18\x01 \x03(\v2*.claudehybrid.admin.v1.ConversationSummaryR\rconversations\"(\n" +
+	"\x16GetConversationRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\xc8\x01\n" +
+	"\fHistoryEntry\x12.\n" +
+	"\x04time\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x12\n" +
+	"\x04turn\x18\x02 \x01(\x05R\x04turn\x12\x14\n" +
+	"\x05route\x18\x03 \x01(\tR\x05route\x12\x14\n" +
+	"\x05label\x18\x04 \x01(\tR\x05label\x12\x1a\n" +
+	"\bprovider\x18\x05 \x01(\tR\bprovider\x12\x14\n" +
+	"\x05model\x18\x06 \x01(\tR\x05model\x12\x16\n" +
+	"\x06status\x18\a \x01(\tR\x06status\"\x91\x02\n" +
+	"\fConversation\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x124\n" +
+	"\astarted\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\astarted\x124\n" +
+	"\aupdated\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\aupdated\x12\x14\n" +
+	"\x05local\x18\x05 \x01(\x05R\x05local\x12\x1a\n" +
+	"\bupstream\x18\x06 \x01(\x05R\bupstream\x12=\n" +
+	"\aentries\x18\a \x03(\v2#.claudehybrid.admin.v1.HistoryEntryR\aentries\"\x13\n" +
+	"\x11ListModelsRequest\"\xfa\x01\n" +
+	"\x05Model\x12\x14\n" +
+	"\x05label\x18\x01 \x01(\tR\x05label\x12\x1a\n" +
+	"\bprovider\x18\x02 \x01(\tR\bprovider\x12\x14\n" +
+	"\x05model\x18\x03 \x01(\tR\x05model\x12\x1a\n" +
+	"\bendpoint\x18\x04 \x01(\tR\bendpoint\x12\x1c\n" +
+	"\ttransform\x18\x05 \x03(\tR\ttransform\x12\x18\n" +
+	"\apreload\x18\x06 \x01(\bR\apreload\x12\x1d\n" +
+	"\n" +
+	"keep_alive\x18\a \x01(\tR\tkeepAlive\x12\x18\n" +
+	"\apattern\x18\b \x01(\bR\apattern\x12\x1c\n" +
+	"\tembedding\x18\t \x01(\tR\tembedding\"}\n" +
+	"\x12ListModelsResponse\x124\n" +
+	"\x06models\x18\x01 \x03(\v2\x1c.claudehybrid.admin.v1.ModelR\x06models\x121\n" +
+	"\aaliases\x18\x02 \x01(\v2\x17.google.protobuf.StructR\aaliases\"\x8d\x02\n" +
+	"\x0fAddLabelRequest\x12\x14\n" +
+	"\x05label\x18\x01 \x01(\tR\x05label\x12\x1a\n" +
+	"\bprovider\x18\x02 \x01(\tR\bprovider\x12\x14\n" +
+	"\x05model\x18\x03 \x01(\tR\x05model\x12\x1d\n" +
+	"\n" +
+	"max_tokens\x18\x04 \x01(\x05R\tmaxTokens\x12\x1c\n" +
+	"\ttransform\x18\x05 \x03(\tR\ttransform\x12\x1a\n" +
+	"\bendpoint\x18\x06 \x01(\tR\bendpoint\x12\x17\n" +
+	"\aapi_key\x18\a \x01(\tR\x06apiKey\x12\x10\n" +
+	"\x03api\x18\b \x01(\tR\x03api\x12\x14\n" +
+	"\x05group\x18\t \x01(\tR\x05group\x12\x18\n" +
+	"\apersist\x18\n" +
+	" \x01(\bR\apersist\"*\n" +
+	"\x12UnloadModelRequest\x12\x14\n" +
+	"\x05label\x18\x01 \x01(\tR\x05label\"C\n" +
+	"\x13UnloadModelResponse\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12\x14\n" +
+	"\x05label\x18\x02 \x01(\tR\x05label\"\x13\n" +
+	"\x11ListPausedRequest\"\xc2\x01\n" +
+	"\rPausedRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06method\x18\x02 \x01(\tR\x06method\x12\x10\n" +
+	"\x03url\x18\x03 \x01(\tR\x03url\x121\n" +
+	"\aheaders\x18\x04 \x01(\v2\x17.google.protobuf.StructR\aheaders\x12\x12\n" +
+	"\x04body\x18\x05 \x01(\tR\x04body\x120\n" +
+	"\x05since\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\"R\n" +
+	"\x12ListPausedResponse\x12<\n" +
+	"\x06paused\x18\x01 \x03(\v2$.claudehybrid.admin.v1.PausedRequestR\x06paused\"G\n" +
+	"\x13ResumePausedRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
+	"\x04body\x18\x02 \x01(\tH\x00R\x04body\x88\x01\x01B\a\n" +
+	"\x05_body\">\n" +
+	"\x14ResumePausedResponse\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\"#\n" +
+	"\x11DropPausedRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"<\n" +
+	"\x12DropPausedResponse\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id2\x8b\t\n" +
+	"\fAdminService\x12U\n" +
+	"\x06Health\x12$.claudehybrid.admin.v1.HealthRequest\x1a%.claudehybrid.admin.v1.HealthResponse\x12O\n" +
+	"\n" +
+	"GetMetrics\x12(.claudehybrid.admin.v1.GetMetricsRequest\x1a\x17.google.protobuf.Struct\x12Q\n" +
+	"\vGetActivity\x12).claudehybrid.admin.v1.GetActivityRequest\x1a\x17.google.protobuf.Struct\x12W\n" +
+	"\rWatchActivity\x12+.claudehybrid.admin.v1.WatchActivityRequest\x1a\x17.google.protobuf.Struct0\x01\x12v\n" +
+	"\x11ListConversations\x12/.claudehybrid.admin.v1.ListConversationsRequest\x1a0.claudehybrid.admin.v1.ListConversationsResponse\x12e\n" +
+	"\x0fGetConversation\x12-.claudehybrid.admin.v1.GetConversationRequest\x1a#.claudehybrid.admin.v1.Conversation\x12a\n" +
+	"\n" +
+	"ListModels\x12(.claudehybrid.admin.v1.ListModelsRequest\x1a).claudehybrid.admin.v1.ListModelsResponse\x12P\n" +
+	"\bAddLabel\x12&.claudehybrid.admin.v1.AddLabelRequest\x1a\x1c.claudehybrid.admin.v1.Model\x12d\n" +
+	"\vUnloadModel\x12).claudehybrid.admin.v1.UnloadModelRequest\x1a*.claudehybrid.admin.v1.UnloadModelResponse\x12a\n" +
+	"\n" +
+	"ListPaused\x12(.claudehybrid.admin.v1.ListPausedRequest\x1a).claudehybrid.admin.v1.ListPausedResponse\x12g\n" +
+	"\fResumePaused\x12*.claudehybrid.admin.v1.ResumePausedRequest\x1a+.claudehybrid.admin.v1.ResumePausedResponse\x12a\n" +
+	"\n" +
+	"DropPaused\x12(.claudehybrid.admin.v1.DropPausedRequest\x1a).claudehybrid.admin.v1.DropPausedResponseBRZPgithub.com/peter-wagstaff/claude-hybrid-router/gen/claudehybrid/admin/v1;adminv1b\x06proto3"
+
+var (
+	file_claudehybrid_admin_v1_admin_proto_rawDescOnce sync.Once
+	file_claudehybrid_admin_v1_admin_proto_rawDescData []byte
+)
+
+func file_claudehybrid_admin_v1_admin_proto_rawDescGZIP() []byte {
+	file_claudehybrid_admin_v1_admin_proto_rawDescOnce.Do(func() {
+		file_claudehybrid_admin_v1_admin_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_claudehybrid_admin_v1_admin_proto_rawDesc), len(file_claudehybrid_admin_v1_admin_proto_rawDesc)))
+	})
+	return file_claudehybrid_admin_v1_admin_proto_rawDescData
+}
+
+var file_claudehybrid_admin_v1_admin_proto_msgTypes = make([]protoimpl.MessageInfo, 24)
+var file_claudehybrid_admin_v1_admin_proto_goTypes = []any{
+	(*HealthRequest)(nil),             // 0: claudehybrid.admin.v1.HealthRequest
+	(*HealthResponse)(nil),            // 1: claudehybrid.admin.v1.HealthResponse
+	(*GetMetricsRequest)(nil),         // 2: claudehybrid.admin.v1.GetMetricsRequest
+	(*GetActivityRequest)(nil),        // 3: claudehybrid.admin.v1.GetActivityRequest
+	(*WatchActivityRequest)(nil),      // 4: claudehybrid.admin.v1.WatchActivityRequest
+	(*ListConversationsRequest)(nil),  // 5: claudehybrid.admin.v1.ListConversationsRequest
+	(*ConversationSummary)(nil),       // 6: claudehybrid.admin.v1.ConversationSummary
+	(*ListConversationsResponse)(nil), // 7: claudehybrid.admin.v1.ListConversationsResponse
+	(*GetConversationRequest)(nil),    // 8: claudehybrid.admin.v1.GetConversationRequest
+	(*HistoryEntry)(nil),              // 9: claudehybrid.admin.v1.HistoryEntry
+	(*Conversation)(nil),              // 10: claudehybrid.admin.v1.Conversation
+	(*ListModelsRequest)(nil),         // 11: claudehybrid.admin.v1.ListModelsRequest
+	(*Model)(nil),                     // 12: claudehybrid.admin.v1.Model
+	(*ListModelsResponse)(nil),        // 13: claudehybrid.admin.v1.ListModelsResponse
+	(*AddLabelRequest)(nil),           // 14: claudehybrid.admin.v1.AddLabelRequest
+	(*UnloadModelRequest)(nil),        // 15: claudehybrid.admin.v1.UnloadModelRequest
+	(*UnloadModelResponse)(nil),       // 16: claudehybrid.admin.v1.UnloadModelResponse
+	(*ListPausedRequest)(nil),         // 17: claudehybrid.admin.v1.ListPausedRequest
+	(*PausedRequest)(nil),             // 18: claudehybrid.admin.v1.PausedRequest
+	(*ListPausedResponse)(nil),        // 19: claudehybrid.admin.v1.ListPausedResponse
+	(*ResumePausedRequest)(nil),       // 20: claudehybrid.admin.v1.ResumePausedRequest
+	(*ResumePausedResponse)(nil),      // 21: claudehybrid.admin.v1.ResumePausedResponse
+	(*DropPausedRequest)(nil),         // 22: claudehybrid.admin.v1.DropPausedRequest
+	(*DropPausedResponse)(nil),        // 23: claudehybrid.admin.v1.DropPausedResponse
+	(*timestamppb.Timestamp)(nil),     // 24: google.protobuf.Timestamp
+	(*structpb.Struct)(nil),           // 25: google.protobuf.Struct
+}
+var file_claudehybrid_admin_v1_admin_proto_depIdxs = []int32{
+	24, // 0: claudehybrid.admin.v1.ConversationSummary.started:type_name -> google.protobuf.Timestamp
+	24, // 1: claudehybrid.admin.v1.ConversationSummary.updated:type_name -> google.protobuf.Timestamp
+	6,  // 2: claudehybrid.admin.v1.ListConversationsResponse.conversations:type_name -> claudehybrid.admin.v1.ConversationSummary
+	24, // 3: claudehybrid.admin.v1.HistoryEntry.time:type_name -> google.protobuf.Timestamp
+	24, // 4: claudehybrid.admin.v1.Conversation.started:type_name -> google.protobuf.Timestamp
+	24, // 5: claudehybrid.admin.v1.Conversation.updated:type_name -> google.protobuf.Timestamp
+	9,  // 6: claudehybrid.admin.v1.Conversation.entries:type_name -> claudehybrid.admin.v1.HistoryEntry
+	12, // 7: claudehybrid.admin.v1.ListModelsResponse.models:type_name -> claudehybrid.admin.v1.Model
+	25, // 8: claudehybrid.admin.v1.ListModelsResponse.aliases:type_name -> google.protobuf.Struct
+	25, // 9: claudehybrid.admin.v1.PausedRequest.headers:type_name -> google.protobuf.Struct
+	24, // 10: claudehybrid.admin.v1.PausedRequest.since:type_name -> google.protobuf.Timestamp
+	18, // 11: claudehybrid.admin.v1.ListPausedResponse.paused:type_name -> claudehybrid.admin.v1.PausedRequest
+	0,  // 12: claudehybrid.admin.v1.AdminService.Health:input_type -> claudehybrid.admin.v1.HealthRequest
+	2,  // 13: claudehybrid.admin.v1.AdminService.GetMetrics:input_type -> claudehybrid.admin.v1.GetMetricsRequest
+	3,  // 14: claudehybrid.admin.v1.AdminService.GetActivity:input_type -> claudehybrid.admin.v1.GetActivityRequest
+	4,  // 15: claudehybrid.admin.v1.AdminService.WatchActivity:input_type -> claudehybrid.admin.v1.WatchActivityRequest
+	5,  // 16: claudehybrid.admin.v1.AdminService.ListConversations:input_type -> claudehybrid.admin.v1.ListConversationsRequest
+	8,  // 17: claudehybrid.admin.v1.AdminService.GetConversation:input_type -> claudehybrid.admin.v1.GetConversationRequest
+	11, // 18: claudehybrid.admin.v1.AdminService.ListModels:input_type -> claudehybrid.admin.v1.ListModelsRequest
+	14, // 19: claudehybrid.admin.v1.AdminService.AddLabel:input_type -> claudehybrid.admin.v1.AddLabelRequest
+	15, // 20: claudehybrid.admin.v1.AdminService.UnloadModel:input_type -> claudehybrid.admin.v1.UnloadModelRequest
+	17, // 21: claudehybrid.admin.v1.AdminService.ListPaused:input_type -> claudehybrid.admin.v1.ListPausedRequest
+	20, // 22: claudehybrid.admin.v1.AdminService.ResumePaused:input_type -> claudehybrid.admin.v1.ResumePausedRequest
+	22, // 23: claudehybrid.admin.v1.AdminService.DropPaused:input_type -> claudehybrid.admin.v1.DropPausedRequest
+	1,  // 24: claudehybrid.admin.v1.AdminService.Health:output_type -> claudehybrid.admin.v1.HealthResponse
+	25, // 25: claudehybrid.admin.v1.AdminService.GetMetrics:output_type -> google.protobuf.Struct
+	25, // 26: claudehybrid.admin.v1.AdminService.GetActivity:output_type -> google.protobuf.Struct
+	25, // 27: claudehybrid.admin.v1.AdminService.WatchActivity:output_type -> google.protobuf.Struct
+	7,  // 28: claudehybrid.admin.v1.AdminService.ListConversations:output_type -> claudehybrid.admin.v1.ListConversationsResponse
+	10, // 29: claudehybrid.admin.v1.AdminService.GetConversation:output_type -> claudehybrid.admin.v1.Conversation
+	13, // 30: claudehybrid.admin.v1.AdminService.ListModels:output_type -> claudehybrid.admin.v1.ListModelsResponse
+	12, // 31: claudehybrid.admin.v1.AdminService.AddLabel:output_type -> claudehybrid.admin.v1.Model
+	16, // 32: claudehybrid.admin.v1.AdminService.UnloadModel:output_type -> claudehybrid.admin.v1.UnloadModelResponse
+	19, // 33: claudehybrid.admin.v1.AdminService.ListPaused:output_type -> claudehybrid.admin.v1.ListPausedResponse
+	21, // 34: claudehybrid.admin.v1.AdminService.ResumePaused:output_type -> claudehybrid.admin.v1.ResumePausedResponse
+	23, // 35: claudehybrid.admin.v1.AdminService.DropPaused:output_type -> claudehybrid.admin.v1.DropPausedResponse
+	24, // [24:36] is the sub-list for method output_type
+	12, // [12:24] is the sub-list for method input_type
+	12, // [12:12] is the sub-list for extension type_name
+	12, // [12:12] is the sub-list for extension extendee
+	0,  // [0:12] is the sub-list for field type_name
+}
+
+func init() { file_claudehybrid_admin_v1_admin_proto_init() }
+func file_claudehybrid_admin_v1_admin_proto_init() {
+	if File_claudehybrid_admin_v1_admin_proto != nil {
+		return
+	}
+	file_claudehybrid_admin_v1_admin_proto_msgTypes[20].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_claudehybrid_admin_v1_admin_proto_rawDesc), len(file_claudehybrid_admin_v1_admin_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   24,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_claudehybrid_admin_v1_admin_proto_goTypes,
+		DependencyIndexes: file_claudehybrid_admin_v1_admin_proto_depIdxs,
+		MessageInfos:      file_claudehybrid_admin_v1_admin_proto_msgTypes,
+	}.Build()
+	File_claudehybrid_admin_v1_admin_proto = out.File
+	file_claudehybrid_admin_v1_admin_proto_goTypes = nil
+	file_claudehybrid_admin_v1_admin_proto_depIdxs = nil
+}
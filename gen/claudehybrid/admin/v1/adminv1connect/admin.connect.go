@@ -0,0 +1,466 @@
+// The admin API of a running claude-hybrid, for typed clients. It is
+// served on the --admin-addr port over the Connect protocol with the JSON
+// codec (https://connectrpc.com/docs/protocol), next to the REST endpoints
+// under /admin/, with the same bearer tokens and read-only mode.
+//
+// Generate a client with buf, e.g. for connect-es or connect-go, and
+// configure it for JSON: useBinaryFormat: false in connect-es,
+// connect.WithProtoJSON() in connect-go. The binary protobuf codec and
+// gRPC framing are not served. buf.gen.yaml at the repository root
+// writes the Go client to gen/claudehybrid/admin/v1/adminv1connect.
+
+// Code generated by protoc-gen-connect-go. DO NOT EDIT.
+//
+// Source: claudehybrid/admin/v1/admin.proto
+
+package adminv1connect
+
+import (
+	connect "connectrpc.com/connect"
+	context "context"
+	errors "errors"
+	v1 "github.com/peter-wagstaff/claude-hybrid-router/gen/claudehybrid/admin/v1"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	http "net/http"
+	strings "strings"
+)
+
+// This is a compile-time assertion to ensure that this generated file and the connect package are
+// compatible. If you get a compiler error that this constant is not defined, this code was
+// generated with a version of connect newer than the one compiled into your binary. You can fix the
+// problem by either regenerating this code with an older version of connect or updating the connect
+// version compiled into your binary.
+const _ = connect.IsAtLeastVersion1_13_0
+
+const (
+	// AdminServiceName is the fully-qualified name of the AdminService service.
+	AdminServiceName = "claudehybrid.admin.v1.AdminService"
+)
+
+// These constants are the fully-qualified names of the RPCs defined in this package. They're
+// exposed at runtime as Spec.Procedure and as the final two segments of the HTTP route.
+//
+// Note that these are different from the fully-qualified method names used by
+// google.golang.org/protobuf/reflect/protoreflect. To convert from these constants to
+// reflection-formatted method names, remove the leading slash and convert the remaining slash to a
+// period.
+const (
+	// AdminServiceHealthProcedure is the fully-qualified name of the AdminService's Health RPC.
+	AdminServiceHealthProcedure = "/claudehybrid.admin.v1.AdminService/Health"
+	// AdminServiceGetMetricsProcedure is the fully-qualified name of the AdminService's GetMetrics RPC.
+	AdminServiceGetMetricsProcedure = "/claudehybrid.admin.v1.AdminService/GetMetrics"
+	// AdminServiceGetActivityProcedure is the fully-qualified name of the AdminService's GetActivity
+	// RPC.
+	AdminServiceGetActivityProcedure = "/claudehybrid.admin.v1.AdminService/GetActivity"
+	// AdminServiceWatchActivityProcedure is the fully-qualified name of the AdminService's
+	// WatchActivity RPC.
+	AdminServiceWatchActivityProcedure = "/claudehybrid.admin.v1.AdminService/WatchActivity"
+	// AdminServiceListConversationsProcedure is the fully-qualified name of the AdminService's
+	// ListConversations RPC.
+	AdminServiceListConversationsProcedure = "/claudehybrid.admin.v1.AdminService/ListConversations"
+	// AdminServiceGetConversationProcedure is the fully-qualified name of the AdminService's
+	// GetConversation RPC.
+	AdminServiceGetConversationProcedure = "/claudehybrid.admin.v1.AdminService/GetConversation"
+	// AdminServiceListModelsProcedure is the fully-qualified name of the AdminService's ListModels RPC.
+	AdminServiceListModelsProcedure = "/claudehybrid.admin.v1.AdminService/ListModels"
+	// AdminServiceAddLabelProcedure is the fully-qualified name of the AdminService's AddLabel RPC.
+	AdminServiceAddLabelProcedure = "/claudehybrid.admin.v1.AdminService/AddLabel"
+	// AdminServiceUnloadModelProcedure is the fully-qualified name of the AdminService's UnloadModel
+	// RPC.
+	AdminServiceUnloadModelProcedure = "/claudehybrid.admin.v1.AdminService/UnloadModel"
+	// AdminServiceListPausedProcedure is the fully-qualified name of the AdminService's ListPaused RPC.
+	AdminServiceListPausedProcedure = "/claudehybrid.admin.v1.AdminService/ListPaused"
+	// AdminServiceResumePausedProcedure is the fully-qualified name of the AdminService's ResumePaused
+	// RPC.
+	AdminServiceResumePausedProcedure = "/claudehybrid.admin.v1.AdminService/ResumePaused"
+	// AdminServiceDropPausedProcedure is the fully-qualified name of the AdminService's DropPaused RPC.
+	AdminServiceDropPausedProcedure = "/claudehybrid.admin.v1.AdminService/DropPaused"
+)
+
+// AdminServiceClient is a client for the claudehybrid.admin.v1.AdminService service.
+type AdminServiceClient interface {
+	// Health needs no token, like GET /admin/health.
+	Health(context.Context, *connect.Request[v1.HealthRequest]) (*connect.Response[v1.HealthResponse], error)
+	// GetMetrics returns the same object as GET /admin/metrics.
+	GetMetrics(context.Context, *connect.Request[v1.GetMetricsRequest]) (*connect.Response[structpb.Struct], error)
+	// GetActivity returns the same object as GET /admin/activity: in-flight
+	// and recent local routes, per-label totals and provider health.
+	GetActivity(context.Context, *connect.Request[v1.GetActivityRequest]) (*connect.Response[structpb.Struct], error)
+	// WatchActivity sends an activity snapshot at once and then every
+	// interval until the client cancels.
+	WatchActivity(context.Context, *connect.Request[v1.WatchActivityRequest]) (*connect.ServerStreamForClient[structpb.Struct], error)
+	// ListConversations lists the conversations in the routing history,
+	// most recently active first, like GET /admin/conversations.
+	ListConversations(context.Context, *connect.Request[v1.ListConversationsRequest]) (*connect.Response[v1.ListConversationsResponse], error)
+	// GetConversation returns which route, label and model served each
+	// request of one conversation.
+	GetConversation(context.Context, *connect.Request[v1.GetConversationRequest]) (*connect.Response[v1.Conversation], error)
+	// ListModels lists configured labels and aliases, like GET /admin/models.
+	ListModels(context.Context, *connect.Request[v1.ListModelsRequest]) (*connect.Response[v1.ListModelsResponse], error)
+	// AddLabel registers a label at runtime, like POST /admin/labels.
+	AddLabel(context.Context, *connect.Request[v1.AddLabelRequest]) (*connect.Response[v1.Model], error)
+	// UnloadModel frees a label's model on its backend.
+	UnloadModel(context.Context, *connect.Request[v1.UnloadModelRequest]) (*connect.Response[v1.UnloadModelResponse], error)
+	// ListPaused lists requests held by --pause, oldest first.
+	ListPaused(context.Context, *connect.Request[v1.ListPausedRequest]) (*connect.Response[v1.ListPausedResponse], error)
+	// ResumePaused sends a held request on, optionally with a new body.
+	ResumePaused(context.Context, *connect.Request[v1.ResumePausedRequest]) (*connect.Response[v1.ResumePausedResponse], error)
+	// DropPaused answers a held request with an error instead.
+	DropPaused(context.Context, *connect.Request[v1.DropPausedRequest]) (*connect.Response[v1.DropPausedResponse], error)
+}
+
+// NewAdminServiceClient constructs a client for the claudehybrid.admin.v1.AdminService service. By
+// default, it uses the Connect protocol with the binary Protobuf Codec, asks for gzipped responses,
+// and sends uncompressed requests. To use the gRPC or gRPC-Web protocols, supply the
+// connect.WithGRPC() or connect.WithGRPCWeb() options.
+//
+// The URL supplied here should be the base URL for the Connect or gRPC server (for example,
+// http://api.acme.com or https://acme.com/grpc).
+func NewAdminServiceClient(httpClient connect.HTTPClient, baseURL string, opts ...connect.ClientOption) AdminServiceClient {
+	baseURL = strings.TrimRight(baseURL, "/")
+	adminServiceMethods := v1.File_claudehybrid_admin_v1_admin_proto.Services().ByName("AdminService").Methods()
+	return &adminServiceClient{
+		health: connect.NewClient[v1.HealthRequest, v1.HealthResponse](
+			httpClient,
+			baseURL+AdminServiceHealthProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("Health")),
+			connect.WithClientOptions(opts...),
+		),
+		getMetrics: connect.NewClient[v1.GetMetricsRequest, structpb.Struct](
+			httpClient,
+			baseURL+AdminServiceGetMetricsProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("GetMetrics")),
+			connect.WithClientOptions(opts...),
+		),
+		getActivity: connect.NewClient[v1.GetActivityRequest, structpb.Struct](
+			httpClient,
+			baseURL+AdminServiceGetActivityProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("GetActivity")),
+			connect.WithClientOptions(opts...),
+		),
+		watchActivity: connect.NewClient[v1.WatchActivityRequest, structpb.Struct](
+			httpClient,
+			baseURL+AdminServiceWatchActivityProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("WatchActivity")),
+			connect.WithClientOptions(opts...),
+		),
+		listConversations: connect.NewClient[v1.ListConversationsRequest, v1.ListConversationsResponse](
+			httpClient,
+			baseURL+AdminServiceListConversationsProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("ListConversations")),
+			connect.WithClientOptions(opts...),
+		),
+		getConversation: connect.NewClient[v1.GetConversationRequest, v1.Conversation](
+			httpClient,
+			baseURL+AdminServiceGetConversationProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("GetConversation")),
+			connect.WithClientOptions(opts...),
+		),
+		listModels: connect.NewClient[v1.ListModelsRequest, v1.ListModelsResponse](
+			httpClient,
+			baseURL+AdminServiceListModelsProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("ListModels")),
+			connect.WithClientOptions(opts...),
+		),
+		addLabel: connect.NewClient[v1.AddLabelRequest, v1.Model](
+			httpClient,
+			baseURL+AdminServiceAddLabelProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("AddLabel")),
+			connect.WithClientOptions(opts...),
+		),
+		unloadModel: connect.NewClient[v1.UnloadModelRequest, v1.UnloadModelResponse](
+			httpClient,
+			baseURL+AdminServiceUnloadModelProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("UnloadModel")),
+			connect.WithClientOptions(opts...),
+		),
+		listPaused: connect.NewClient[v1.ListPausedRequest, v1.ListPausedResponse](
+			httpClient,
+			baseURL+AdminServiceListPausedProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("ListPaused")),
+			connect.WithClientOptions(opts...),
+		),
+		resumePaused: connect.NewClient[v1.ResumePausedRequest, v1.ResumePausedResponse](
+			httpClient,
+			baseURL+AdminServiceResumePausedProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("ResumePaused")),
+			connect.WithClientOptions(opts...),
+		),
+		dropPaused: connect.NewClient[v1.DropPausedRequest, v1.DropPausedResponse](
+			httpClient,
+			baseURL+AdminServiceDropPausedProcedure,
+			connect.WithSchema(adminServiceMethods.ByName("DropPaused")),
+			connect.WithClientOptions(opts...),
+		),
+	}
+}
+
+// adminServiceClient implements AdminServiceClient.
+type adminServiceClient struct {
+	health            *connect.Client[v1.HealthRequest, v1.HealthResponse]
+	getMetrics        *connect.Client[v1.GetMetricsRequest, structpb.Struct]
+	getActivity       *connect.Client[v1.GetActivityRequest, structpb.Struct]
+	watchActivity     *connect.Client[v1.WatchActivityRequest, structpb.Struct]
+	listConversations *connect.Client[v1.ListConversationsRequest, v1.ListConversationsResponse]
+	getConversation   *connect.Client[v1.GetConversationRequest, v1.Conversation]
+	listModels        *connect.Client[v1.ListModelsRequest, v1.ListModelsResponse]
+	addLabel          *connect.Client[v1.AddLabelRequest, v1.Model]
+	unloadModel       *connect.Client[v1.UnloadModelRequest, v1.UnloadModelResponse]
+	listPaused        *connect.Client[v1.ListPausedRequest, v1.ListPausedResponse]
+	resumePaused      *connect.Client[v1.ResumePausedRequest, v1.ResumePausedResponse]
+	dropPaused        *connect.Client[v1.DropPausedRequest, v1.DropPausedResponse]
+}
+
+// Health calls claudehybrid.admin.v1.AdminService.Health.
+func (c *adminServiceClient) Health(ctx context.Context, req *connect.Request[v1.HealthRequest]) (*connect.Response[v1.HealthResponse], error) {
+	return c.health.CallUnary(ctx, req)
+}
+
+// GetMetrics calls claudehybrid.admin.v1.AdminService.GetMetrics.
+func (c *adminServiceClient) GetMetrics(ctx context.Context, req *connect.Request[v1.GetMetricsRequest]) (*connect.Response[structpb.Struct], error) {
+	return c.getMetrics.CallUnary(ctx, req)
+}
+
+// GetActivity calls claudehybrid.admin.v1.AdminService.GetActivity.
+func (c *adminServiceClient) GetActivity(ctx context.Context, req *connect.Request[v1.GetActivityRequest]) (*connect.Response[structpb.Struct], error) {
+	return c.getActivity.CallUnary(ctx, req)
+}
+
+// WatchActivity calls claudehybrid.admin.v1.AdminService.WatchActivity.
+func (c *adminServiceClient) WatchActivity(ctx context.Context, req *connect.Request[v1.WatchActivityRequest]) (*connect.ServerStreamForClient[structpb.Struct], error) {
+	return c.watchActivity.CallServerStream(ctx, req)
+}
+
+// ListConversations calls claudehybrid.admin.v1.AdminService.ListConversations.
+func (c *adminServiceClient) ListConversations(ctx context.Context, req *connect.Request[v1.ListConversationsRequest]) (*connect.Response[v1.ListConversationsResponse], error) {
+	return c.listConversations.CallUnary(ctx, req)
+}
+
+// GetConversation calls claudehybrid.admin.v1.AdminService.GetConversation.
+func (c *adminServiceClient) GetConversation(ctx context.Context, req *connect.Request[v1.GetConversationRequest]) (*connect.Response[v1.Conversation], error) {
+	return c.getConversation.CallUnary(ctx, req)
+}
+
+// ListModels calls claudehybrid.admin.v1.AdminService.ListModels.
+func (c *adminServiceClient) ListModels(ctx context.Context, req *connect.Request[v1.ListModelsRequest]) (*connect.Response[v1.ListModelsResponse], error) {
+	return c.listModels.CallUnary(ctx, req)
+}
+
+// AddLabel calls claudehybrid.admin.v1.AdminService.AddLabel.
+func (c *adminServiceClient) AddLabel(ctx context.Context, req *connect.Request[v1.AddLabelRequest]) (*connect.Response[v1.Model], error) {
+	return c.addLabel.CallUnary(ctx, req)
+}
+
+// UnloadModel calls claudehybrid.admin.v1.AdminService.UnloadModel.
+func (c *adminServiceClient) UnloadModel(ctx context.Context, req *connect.Request[v1.UnloadModelRequest]) (*connect.Response[v1.UnloadModelResponse], error) {
+	return c.unloadModel.CallUnary(ctx, req)
+}
+
+// ListPaused calls claudehybrid.admin.v1.AdminService.ListPaused.
+func (c *adminServiceClient) ListPaused(ctx context.Context, req *connect.Request[v1.ListPausedRequest]) (*connect.Response[v1.ListPausedResponse], error) {
+	return c.listPaused.CallUnary(ctx, req)
+}
+
+// ResumePaused calls claudehybrid.admin.v1.AdminService.ResumePaused.
+func (c *adminServiceClient) ResumePaused(ctx context.Context, req *connect.Request[v1.ResumePausedRequest]) (*connect.Response[v1.ResumePausedResponse], error) {
+	return c.resumePaused.CallUnary(ctx, req)
+}
+
+// DropPaused calls claudehybrid.admin.v1.AdminService.DropPaused.
+func (c *adminServiceClient) DropPaused(ctx context.Context, req *connect.Request[v1.DropPausedRequest]) (*connect.Response[v1.DropPausedResponse], error) {
+	return c.dropPaused.CallUnary(ctx, req)
+}
+
+// AdminServiceHandler is an implementation of the claudehybrid.admin.v1.AdminService service.
+type AdminServiceHandler interface {
+	// Health needs no token, like GET /admin/health.
+	Health(context.Context, *connect.Request[v1.HealthRequest]) (*connect.Response[v1.HealthResponse], error)
+	// GetMetrics returns the same object as GET /admin/metrics.
+	GetMetrics(context.Context, *connect.Request[v1.GetMetricsRequest]) (*connect.Response[structpb.Struct], error)
+	// GetActivity returns the same object as GET /admin/activity: in-flight
+	// and recent local routes, per-label totals and provider health.
+	GetActivity(context.Context, *connect.Request[v1.GetActivityRequest]) (*connect.Response[structpb.Struct], error)
+	// WatchActivity sends an activity snapshot at once and then every
+	// interval until the client cancels.
+	WatchActivity(context.Context, *connect.Request[v1.WatchActivityRequest], *connect.ServerStream[structpb.Struct]) error
+	// ListConversations lists the conversations in the routing history,
+	// most recently active first, like GET /admin/conversations.
+	ListConversations(context.Context, *connect.Request[v1.ListConversationsRequest]) (*connect.Response[v1.ListConversationsResponse], error)
+	// GetConversation returns which route, label and model served each
+	// request of one conversation.
+	GetConversation(context.Context, *connect.Request[v1.GetConversationRequest]) (*connect.Response[v1.Conversation], error)
+	// ListModels lists configured labels and aliases, like GET /admin/models.
+	ListModels(context.Context, *connect.Request[v1.ListModelsRequest]) (*connect.Response[v1.ListModelsResponse], error)
+	// AddLabel registers a label at runtime, like POST /admin/labels.
+	AddLabel(context.Context, *connect.Request[v1.AddLabelRequest]) (*connect.Response[v1.Model], error)
+	// UnloadModel frees a label's model on its backend.
+	UnloadModel(context.Context, *connect.Request[v1.UnloadModelRequest]) (*connect.Response[v1.UnloadModelResponse], error)
+	// ListPaused lists requests held by --pause, oldest first.
+	ListPaused(context.Context, *connect.Request[v1.ListPausedRequest]) (*connect.Response[v1.ListPausedResponse], error)
+	// ResumePaused sends a held request on, optionally with a new body.
+	ResumePaused(context.Context, *connect.Request[v1.ResumePausedRequest]) (*connect.Response[v1.ResumePausedResponse], error)
+	// DropPaused answers a held request with an error instead.
+	DropPaused(context.Context, *connect.Request[v1.DropPausedRequest]) (*connect.Response[v1.DropPausedResponse], error)
+}
+
+// NewAdminServiceHandler builds an HTTP handler from the service implementation. It returns the
+// path on which to mount the handler and the handler itself.
+//
+// By default, handlers support the Connect, gRPC, and gRPC-Web protocols with the binary Protobuf
+// and JSON codecs. They also support gzip compression.
+func NewAdminServiceHandler(svc AdminServiceHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	adminServiceMethods := v1.File_claudehybrid_admin_v1_admin_proto.Services().ByName("AdminService").Methods()
+	adminServiceHealthHandler := connect.NewUnaryHandler(
+		AdminServiceHealthProcedure,
+		svc.Health,
+		connect.WithSchema(adminServiceMethods.ByName("Health")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceGetMetricsHandler := connect.NewUnaryHandler(
+		AdminServiceGetMetricsProcedure,
+		svc.GetMetrics,
+		connect.WithSchema(adminServiceMethods.ByName("GetMetrics")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceGetActivityHandler := connect.NewUnaryHandler(
+		AdminServiceGetActivityProcedure,
+		svc.GetActivity,
+		connect.WithSchema(adminServiceMethods.ByName("GetActivity")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceWatchActivityHandler := connect.NewServerStreamHandler(
+		AdminServiceWatchActivityProcedure,
+		svc.WatchActivity,
+		connect.WithSchema(adminServiceMethods.ByName("WatchActivity")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceListConversationsHandler := connect.NewUnaryHandler(
+		AdminServiceListConversationsProcedure,
+		svc.ListConversations,
+		connect.WithSchema(adminServiceMethods.ByName("ListConversations")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceGetConversationHandler := connect.NewUnaryHandler(
+		AdminServiceGetConversationProcedure,
+		svc.GetConversation,
+		connect.WithSchema(adminServiceMethods.ByName("GetConversation")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceListModelsHandler := connect.NewUnaryHandler(
+		AdminServiceListModelsProcedure,
+		svc.ListModels,
+		connect.WithSchema(adminServiceMethods.ByName("ListModels")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceAddLabelHandler := connect.NewUnaryHandler(
+		AdminServiceAddLabelProcedure,
+		svc.AddLabel,
+		connect.WithSchema(adminServiceMethods.ByName("AddLabel")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceUnloadModelHandler := connect.NewUnaryHandler(
+		AdminServiceUnloadModelProcedure,
+		svc.UnloadModel,
+		connect.WithSchema(adminServiceMethods.ByName("UnloadModel")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceListPausedHandler := connect.NewUnaryHandler(
+		AdminServiceListPausedProcedure,
+		svc.ListPaused,
+		connect.WithSchema(adminServiceMethods.ByName("ListPaused")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceResumePausedHandler := connect.NewUnaryHandler(
+		AdminServiceResumePausedProcedure,
+		svc.ResumePaused,
+		connect.WithSchema(adminServiceMethods.ByName("ResumePaused")),
+		connect.WithHandlerOptions(opts...),
+	)
+	adminServiceDropPausedHandler := connect.NewUnaryHandler(
+		AdminServiceDropPausedProcedure,
+		svc.DropPaused,
+		connect.WithSchema(adminServiceMethods.ByName("DropPaused")),
+		connect.WithHandlerOptions(opts...),
+	)
+	return "/claudehybrid.admin.v1.AdminService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case AdminServiceHealthProcedure:
+			adminServiceHealthHandler.ServeHTTP(w, r)
+		case AdminServiceGetMetricsProcedure:
+			adminServiceGetMetricsHandler.ServeHTTP(w, r)
+		case AdminServiceGetActivityProcedure:
+			adminServiceGetActivityHandler.ServeHTTP(w, r)
+		case AdminServiceWatchActivityProcedure:
+			adminServiceWatchActivityHandler.ServeHTTP(w, r)
+		case AdminServiceListConversationsProcedure:
+			adminServiceListConversationsHandler.ServeHTTP(w, r)
+		case AdminServiceGetConversationProcedure:
+			adminServiceGetConversationHandler.ServeHTTP(w, r)
+		case AdminServiceListModelsProcedure:
+			adminServiceListModelsHandler.ServeHTTP(w, r)
+		case AdminServiceAddLabelProcedure:
+			adminServiceAddLabelHandler.ServeHTTP(w, r)
+		case AdminServiceUnloadModelProcedure:
+			adminServiceUnloadModelHandler.ServeHTTP(w, r)
+		case AdminServiceListPausedProcedure:
+			adminServiceListPausedHandler.ServeHTTP(w, r)
+		case AdminServiceResumePausedProcedure:
+			adminServiceResumePausedHandler.ServeHTTP(w, r)
+		case AdminServiceDropPausedProcedure:
+			adminServiceDropPausedHandler.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// UnimplementedAdminServiceHandler returns CodeUnimplemented from all methods.
+type UnimplementedAdminServiceHandler struct{}
+
+func (UnimplementedAdminServiceHandler) Health(context.Context, *connect.Request[v1.HealthRequest]) (*connect.Response[v1.HealthResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("claudehybrid.admin.v1.AdminService.Health is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) GetMetrics(context.Context, *connect.Request[v1.GetMetricsRequest]) (*connect.Response[structpb.Struct], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("claudehybrid.admin.v1.AdminService.GetMetrics is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) GetActivity(context.Context, *connect.Request[v1.GetActivityRequest]) (*connect.Response[structpb.Struct], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("claudehybrid.admin.v1.AdminService.GetActivity is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) WatchActivity(context.Context, *connect.Request[v1.WatchActivityRequest], *connect.ServerStream[structpb.Struct]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("claudehybrid.admin.v1.AdminService.WatchActivity is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) ListConversations(context.Context, *connect.Request[v1.ListConversationsRequest]) (*connect.Response[v1.ListConversationsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("claudehybrid.admin.v1.AdminService.ListConversations is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) GetConversation(context.Context, *connect.Request[v1.GetConversationRequest]) (*connect.Response[v1.Conversation], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("claudehybrid.admin.v1.AdminService.GetConversation is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) ListModels(context.Context, *connect.Request[v1.ListModelsRequest]) (*connect.Response[v1.ListModelsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("claudehybrid.admin.v1.AdminService.ListModels is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) AddLabel(context.Context, *connect.Request[v1.AddLabelRequest]) (*connect.Response[v1.Model], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("claudehybrid.admin.v1.AdminService.AddLabel is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) UnloadModel(context.Context, *connect.Request[v1.UnloadModelRequest]) (*connect.Response[v1.UnloadModelResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("claudehybrid.admin.v1.AdminService.UnloadModel is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) ListPaused(context.Context, *connect.Request[v1.ListPausedRequest]) (*connect.Response[v1.ListPausedResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("claudehybrid.admin.v1.AdminService.ListPaused is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) ResumePaused(context.Context, *connect.Request[v1.ResumePausedRequest]) (*connect.Response[v1.ResumePausedResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("claudehybrid.admin.v1.AdminService.ResumePaused is not implemented"))
+}
+
+func (UnimplementedAdminServiceHandler) DropPaused(context.Context, *connect.Request[v1.DropPausedRequest]) (*connect.Response[v1.DropPausedResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("claudehybrid.admin.v1.AdminService.DropPaused is not implemented"))
+}
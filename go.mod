@@ -2,4 +2,10 @@ module github.com/peter-wagstaff/claude-hybrid-router
 
 go 1.24.1
 
-require gopkg.in/yaml.v3 v3.0.1 // indirect
+require (
+	connectrpc.com/connect v1.19.1
+	github.com/andybalholm/brotli v1.2.6
+	github.com/klauspost/compress v1.19.2
+	google.golang.org/protobuf v1.36.12
+	gopkg.in/yaml.v3 v3.0.1
+)
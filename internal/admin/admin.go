@@ -0,0 +1,358 @@
+// Package admin serves the local control API for a running proxy.
+package admin
+
+import (
+	"crypto/subtle"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/defaults"
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/proxy"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+// uiFiles is the web dashboard served at /admin/ui/.
+//
+//go:embed ui
+var uiFiles embed.FS
+
+// Server is an http.Handler exposing admin endpoints under /admin/.
+type Server struct {
+	proxy      *proxy.Proxy
+	mux        *http.ServeMux
+	configPath string
+	readOnly   bool
+	readToken  string
+	writeToken string
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithConfigPath lets POST /admin/labels persist new labels to the config
+// file at path.
+func WithConfigPath(path string) Option {
+	return func(s *Server) { s.configPath = path }
+}
+
+// WithReadOnly refuses every mutating endpoint with 403 while leaving
+// health, metrics and model listings available.
+func WithReadOnly(readOnly bool) Option {
+	return func(s *Server) { s.readOnly = readOnly }
+}
+
+// WithTokens requires bearer tokens. GET endpoints need the read token (the
+// write token also works); mutating endpoints need the write token, or the
+// read token when no write token is set. An empty read token leaves GET
+// endpoints open.
+// /admin/health is never authenticated so liveness probes keep working.
+func WithTokens(read, write string) Option {
+	return func(s *Server) {
+		s.readToken = read
+		s.writeToken = write
+	}
+}
+
+// New creates an admin Server for the given proxy.
+func New(p *proxy.Proxy, opts ...Option) *Server {
+	s := &Server{proxy: p, mux: http.NewServeMux()}
+	for _, o := range opts {
+		o(s)
+	}
+	s.mux.HandleFunc("GET /admin/health", s.handleHealth)
+	s.mux.HandleFunc("GET /admin/metrics", s.handleMetrics)
+	s.mux.HandleFunc("GET /admin/activity", s.handleActivity)
+	s.mux.HandleFunc("GET /admin/events", s.handleEvents)
+	s.mux.HandleFunc("GET /admin/conversations", s.handleConversations)
+	s.mux.HandleFunc("GET /admin/conversations/{id}", s.handleConversation)
+	s.mux.HandleFunc("GET /admin/models", s.handleModels)
+	s.mux.HandleFunc("POST /admin/models/{label}/unload", s.handleUnload)
+	s.mux.HandleFunc("POST /admin/labels", s.handleAddLabel)
+	s.mux.HandleFunc("GET /admin/paused", s.handlePaused)
+	s.mux.HandleFunc("POST /admin/paused/{id}/resume", s.handleResume)
+	s.mux.HandleFunc("POST /admin/paused/{id}/drop", s.handleDrop)
+	ui, _ := fs.Sub(uiFiles, "ui")
+	s.mux.Handle("GET /admin/ui/", http.StripPrefix("/admin/ui/", http.FileServerFS(ui)))
+	s.mux.Handle("GET /admin/ui", http.RedirectHandler("/admin/ui/", http.StatusMovedPermanently))
+	return s
+}
+
+// ServeHTTP checks access and dispatches admin requests, REST and Connect.
+// The dashboard's static files hold no data and are served without a
+// token; the page asks for one and sends it with its API calls.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, connectPrefix) {
+		s.serveConnect(w, r) // checks access per method
+		return
+	}
+	if r.URL.Path != "/admin/health" && r.URL.Path != "/admin/ui" && !strings.HasPrefix(r.URL.Path, "/admin/ui/") {
+		write := r.Method != http.MethodGet && r.Method != http.MethodHead
+		if !s.authorized(r, write) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="claude-hybrid admin"`)
+			writeError(w, http.StatusUnauthorized, "missing or invalid admin token")
+			return
+		}
+		if write && s.readOnly {
+			writeError(w, http.StatusForbidden, "admin API is read-only")
+			return
+		}
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// authorized reports whether r may make a read or, when write is set, a
+// mutating request.
+func (s *Server) authorized(r *http.Request, write bool) bool {
+	if write && s.writeToken != "" {
+		return hasToken(r, s.writeToken)
+	}
+	if s.readToken == "" {
+		return true
+	}
+	return hasToken(r, s.readToken) || (s.writeToken != "" && hasToken(r, s.writeToken))
+}
+
+// hasToken reports whether r presents token as its bearer credential.
+func hasToken(r *http.Request, token string) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.proxy.Metrics())
+}
+
+func (s *Server) handleActivity(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.proxy.Activity())
+}
+
+func (s *Server) handleConversations(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"conversations": s.proxy.Conversations()})
+}
+
+func (s *Server) handleConversation(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	c, ok := s.proxy.ConversationHistory(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no conversation %q in the history", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+// handleEvents streams routing events as they happen, one JSON object per
+// line, or as Server-Sent Events when the client accepts text/event-stream.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	events, cancel := s.proxy.Events()
+	defer cancel()
+	sse := strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+	if sse {
+		w.Header().Set("Content-Type", "text/event-stream")
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	for {
+		select {
+		case ev := <-events:
+			data, _ := json.Marshal(ev)
+			var err error
+			if sse {
+				_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data)
+			} else {
+				_, err = fmt.Fprintf(w, "%s\n", data)
+			}
+			if err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// modelInfo is the public view of a resolved label. API keys are never exposed.
+type modelInfo struct {
+	Label     string   `json:"label"`
+	Provider  string   `json:"provider"`
+	Model     string   `json:"model"`
+	Endpoint  string   `json:"endpoint"`
+	Transform []string `json:"transform"`
+	Preload   bool     `json:"preload,omitempty"`
+	KeepAlive string   `json:"keep_alive,omitempty"`
+	Pattern   bool     `json:"pattern,omitempty"`   // a wildcard label; model may hold {label} or {N}
+	Embedding string   `json:"embedding,omitempty"` // embeddings wire format, for embedding labels
+}
+
+func newModelInfo(m config.ResolvedModel) modelInfo {
+	return modelInfo{
+		Label:     m.Label,
+		Provider:  m.Provider,
+		Model:     m.Model,
+		Endpoint:  m.Endpoint,
+		Transform: m.Transform,
+		Preload:   m.Preload,
+		KeepAlive: m.KeepAlive,
+		Pattern:   m.Pattern != "",
+	}
+}
+
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	models, aliases := s.models()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"models": models, "aliases": aliases})
+}
+
+// models lists the labels (wildcard and embedding ones included) and the
+// aliases of the loaded config.
+func (s *Server) models() ([]modelInfo, map[string][]string) {
+	models := []modelInfo{}
+	aliases := map[string][]string{}
+	if resolver := s.proxy.ModelResolver(); resolver != nil {
+		for _, m := range append(resolver.Models(), resolver.Patterns()...) {
+			models = append(models, newModelInfo(m))
+		}
+		for _, e := range resolver.Embeddings() {
+			info := newModelInfo(e.ResolvedModel)
+			info.Embedding = e.Format
+			models = append(models, info)
+		}
+		aliases = resolver.Aliases()
+	}
+	return models, aliases
+}
+
+// addLabelRequest is the body of POST /admin/labels.
+type addLabelRequest struct {
+	config.LabelSpec
+	Persist bool `json:"persist,omitempty"` // also write the label to config.yaml
+}
+
+func (s *Server) handleAddLabel(w http.ResponseWriter, r *http.Request) {
+	var req addLabelRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	info, status, err := s.addLabel(req)
+	if err != nil {
+		writeError(w, status, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, info)
+}
+
+// addLabel registers a label, saving it to the config file when asked. On
+// failure it returns the HTTP status to answer with.
+func (s *Server) addLabel(req addLabelRequest) (modelInfo, int, error) {
+	resolver := s.proxy.ModelResolver()
+	if resolver == nil {
+		return modelInfo{}, http.StatusNotFound, errors.New("no provider config loaded")
+	}
+	if req.Persist && s.configPath == "" {
+		return modelInfo{}, http.StatusBadRequest, errors.New("persist requested but no config file is loaded")
+	}
+	m, err := resolver.AddLabel(req.LabelSpec)
+	if err != nil {
+		return modelInfo{}, http.StatusBadRequest, err
+	}
+	log.Printf("ADMIN added label %s → %s/%s", m.Label, m.Provider, m.Model)
+
+	if req.Persist {
+		if err := config.PersistLabel(s.configPath, req.LabelSpec); err != nil {
+			log.Printf("ADMIN persist label %s failed: %v", m.Label, err)
+			return modelInfo{}, http.StatusInternalServerError, fmt.Errorf("label is active but was not saved: %w", err)
+		}
+		log.Printf("ADMIN saved label %s to %s", m.Label, s.configPath)
+	}
+	return newModelInfo(m), http.StatusCreated, nil
+}
+
+func (s *Server) handleUnload(w http.ResponseWriter, r *http.Request) {
+	label := r.PathValue("label")
+	if status, err := s.unload(label); err != nil {
+		writeError(w, status, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "unloaded", "label": label})
+}
+
+// unload frees the backend memory of label's model. On failure it returns
+// the HTTP status to answer with.
+func (s *Server) unload(label string) (int, error) {
+	resolver := s.proxy.ModelResolver()
+	if resolver == nil {
+		return http.StatusNotFound, errors.New("no provider config loaded")
+	}
+	if _, err := resolver.Resolve(label); err != nil {
+		return http.StatusNotFound, err
+	}
+	if err := s.proxy.UnloadModel(label); err != nil {
+		log.Printf("ADMIN unload %s failed: %v", label, err)
+		return http.StatusBadGateway, err
+	}
+	log.Printf("ADMIN unloaded model for label %s", label)
+	return http.StatusOK, nil
+}
+
+func (s *Server) handlePaused(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"paused": s.proxy.Paused()})
+}
+
+// resumeRequest is the optional body of POST /admin/paused/{id}/resume.
+type resumeRequest struct {
+	Body *string `json:"body,omitempty"` // replaces the request body; omit to send it unchanged
+}
+
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	var req resumeRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, defaults.MaxBodyBytes)).Decode(&req); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	var body []byte
+	if req.Body != nil {
+		body = []byte(*req.Body)
+	}
+	if err := s.proxy.ResumePaused(r.PathValue("id"), body); err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "resumed", "id": r.PathValue("id")})
+}
+
+func (s *Server) handleDrop(w http.ResponseWriter, r *http.Request) {
+	if err := s.proxy.DropPaused(r.PathValue("id")); err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "dropped", "id": r.PathValue("id")})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,308 @@
+package admin
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/mitm"
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/proxy"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+// newTestServer builds an admin Server over a proxy whose single provider
+// points at endpoint.
+func newTestServer(t *testing.T, endpoint string) *Server {
+	t.Helper()
+	resolver, err := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "ollama",
+			Endpoint: endpoint,
+			Models: map[string]config.ModelConfig{
+				"coder": {Model: "qwen3:32b", KeepAlive: "30m"},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("resolver: %v", err)
+	}
+	return New(proxy.New(nil, proxy.WithModelResolver(resolver)))
+}
+
+func TestHealth(t *testing.T) {
+	s := newTestServer(t, "http://127.0.0.1:1/v1")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/health", nil))
+	if rec.Code != 200 {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+}
+
+func TestListModels(t *testing.T) {
+	s := newTestServer(t, "http://127.0.0.1:1/v1")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/models", nil))
+
+	var resp struct {
+		Models []modelInfo `json:"models"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(resp.Models) != 1 || resp.Models[0].Label != "coder" || resp.Models[0].KeepAlive != "30m" {
+		t.Errorf("unexpected models: %+v", resp.Models)
+	}
+}
+
+func TestUnloadModel(t *testing.T) {
+	var mu sync.Mutex
+	var gotPath string
+	var gotBody map[string]interface{}
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		gotPath = r.URL.Path
+		json.Unmarshal(body, &gotBody)
+		mu.Unlock()
+		w.Write([]byte(`{"done":true}`))
+	}))
+	defer ollama.Close()
+
+	s := newTestServer(t, ollama.URL+"/v1")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("POST", "/admin/models/coder/unload", nil))
+	if rec.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if gotPath != "/api/generate" {
+		t.Errorf("expected native /api/generate, got %s", gotPath)
+	}
+	if gotBody["model"] != "qwen3:32b" || gotBody["keep_alive"] != float64(0) {
+		t.Errorf("unexpected unload body: %v", gotBody)
+	}
+}
+
+func TestUnloadUnknownLabel(t *testing.T) {
+	s := newTestServer(t, "http://127.0.0.1:1/v1")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("POST", "/admin/models/nope/unload", nil))
+	if rec.Code != 404 {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestMetricsIncludesCertCache(t *testing.T) {
+	certPEM, keyPEM, err := mitm.GenerateCA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := mitm.NewCertCache(certPEM, keyPEM, mitm.WithMaxEntries(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.GetTLSConfig("api.anthropic.com")
+	s := New(proxy.New(cache))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/metrics", nil))
+	var m proxy.Metrics
+	if err := json.Unmarshal(rec.Body.Bytes(), &m); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if m.CertCache == nil || m.CertCache.Entries != 1 || m.CertCache.MaxEntries != 8 {
+		t.Errorf("unexpected cert cache metrics: %+v", m.CertCache)
+	}
+}
+
+func TestActivity(t *testing.T) {
+	s := newTestServer(t, "http://127.0.0.1:1/v1")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/activity", nil))
+	if rec.Code != 200 {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var act proxy.Activity
+	if err := json.Unmarshal(rec.Body.Bytes(), &act); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if act.InFlight == nil || act.Recent == nil || act.Labels == nil {
+		t.Errorf("empty activity should use empty lists: %s", rec.Body)
+	}
+}
+
+func TestConversations(t *testing.T) {
+	s := newTestServer(t, "http://127.0.0.1:1/v1")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/conversations", nil))
+	if rec.Code != 200 || strings.TrimSpace(rec.Body.String()) != `{"conversations":[]}` {
+		t.Errorf("empty history = %d %s", rec.Code, rec.Body)
+	}
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/conversations/abc", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown conversation = %d", rec.Code)
+	}
+}
+
+func TestEventsStream(t *testing.T) {
+	srv := httptest.NewServer(New(proxy.New(nil), WithTokens("r-token", "")))
+	t.Cleanup(srv.Close)
+
+	for accept, want := range map[string]string{"": "application/x-ndjson", "text/event-stream": "text/event-stream"} {
+		req, _ := http.NewRequest("GET", srv.URL+"/admin/events", nil)
+		req.Header.Set("Authorization", "Bearer r-token")
+		req.Header.Set("Accept", accept)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Headers are flushed before the first event.
+		if resp.StatusCode != 200 || resp.Header.Get("Content-Type") != want {
+			t.Errorf("Accept %q: %d %q", accept, resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		resp.Body.Close()
+	}
+
+	resp, err := http.Get(srv.URL + "/admin/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("without token = %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestDashboardUI(t *testing.T) {
+	s := New(proxy.New(nil), WithTokens("r", "w"))
+	for path, want := range map[string]string{
+		"/admin/ui/":       "<title>claude-hybrid</title>",
+		"/admin/ui/app.js": "/admin/",
+	} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != 200 || !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("%s: %d, body missing %q", path, rec.Code, want)
+		}
+	}
+	// The page is public; the data behind it is not.
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/activity", nil))
+	if rec.Code != 401 {
+		t.Errorf("activity without token: %d, want 401", rec.Code)
+	}
+}
+
+func TestAddLabel(t *testing.T) {
+	s := newTestServer(t, "http://127.0.0.1:1/v1")
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("POST", "/admin/labels", strings.NewReader(
+		`{"label":"burst","provider":"vllm-burst","model":"Qwen/Qwen3-32B","endpoint":"http://10.0.0.7:8000/v1"}`)))
+	if rec.Code != 201 {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	m, err := s.proxy.ModelResolver().Resolve("burst")
+	if err != nil || m.Endpoint != "http://10.0.0.7:8000/v1" {
+		t.Errorf("label not routable: %+v, %v", m, err)
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("POST", "/admin/labels", strings.NewReader(
+		`{"label":"coder","provider":"ollama","model":"x"}`)))
+	if rec.Code != 400 {
+		t.Errorf("duplicate label: expected 400, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("POST", "/admin/labels", strings.NewReader(
+		`{"label":"kept","provider":"ollama","model":"x","persist":true}`)))
+	if rec.Code != 400 {
+		t.Errorf("persist without config path: expected 400, got %d", rec.Code)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	s := New(proxy.New(nil), WithReadOnly(true))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/metrics", nil))
+	if rec.Code != 200 {
+		t.Errorf("metrics: expected 200, got %d", rec.Code)
+	}
+
+	for _, path := range []string{"/admin/labels", "/admin/models/coder/unload"} {
+		rec = httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest("POST", path, strings.NewReader(`{}`)))
+		if rec.Code != 403 {
+			t.Errorf("POST %s: expected 403, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestTokens(t *testing.T) {
+	s := New(proxy.New(nil), WithTokens("r-token", "w-token"))
+
+	tests := []struct {
+		method, path, token string
+		want                int
+	}{
+		{"GET", "/admin/health", "", 200},
+		{"GET", "/admin/metrics", "", 401},
+		{"GET", "/admin/metrics", "wrong", 401},
+		{"GET", "/admin/metrics", "r-token", 200},
+		{"GET", "/admin/metrics", "w-token", 200},
+		{"POST", "/admin/models/coder/unload", "r-token", 401},
+		{"POST", "/admin/models/coder/unload", "w-token", 404}, // authorized; no config loaded
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s with %q: expected %d, got %d", tt.method, tt.path, tt.token, tt.want, rec.Code)
+		}
+	}
+
+	// Without a write token the read token guards mutating endpoints too.
+	s = New(proxy.New(nil), WithTokens("r-token", ""))
+	req := httptest.NewRequest("POST", "/admin/models/coder/unload", nil)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != 401 {
+		t.Errorf("unauthenticated write: expected 401, got %d", rec.Code)
+	}
+}
+
+func TestPausedEndpoints(t *testing.T) {
+	s := New(proxy.New(nil, proxy.WithPause(regexp.MustCompile(`/v1/messages`))))
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/paused", nil))
+	if rec.Code != 200 || strings.TrimSpace(rec.Body.String()) != `{"paused":[]}` {
+		t.Errorf("list: %d %s", rec.Code, rec.Body)
+	}
+	for _, path := range []string{"/admin/paused/7/resume", "/admin/paused/7/drop"} {
+		rec = httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest("POST", path, nil))
+		if rec.Code != 404 {
+			t.Errorf("%s for an unknown ID: expected 404, got %d", path, rec.Code)
+		}
+	}
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("POST", "/admin/paused/7/resume", strings.NewReader(`{"body":`)))
+	if rec.Code != 400 {
+		t.Errorf("bad JSON: expected 400, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,303 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/defaults"
+)
+
+// connectPrefix is the path of the AdminService in
+// proto/claudehybrid/admin/v1/admin.proto.
+const connectPrefix = "/claudehybrid.admin.v1.AdminService/"
+
+// connectMethod is one AdminService RPC. call decodes its request from a
+// JSON message and returns the response, or a status from the REST API's
+// vocabulary and an error.
+type connectMethod struct {
+	write  bool // needs the write token and is refused when read-only
+	stream bool // server streaming; handled by watchActivity
+	call   func(s *Server, msg []byte) (interface{}, int, error)
+}
+
+var connectMethods = map[string]connectMethod{
+	"Health": {call: func(s *Server, _ []byte) (interface{}, int, error) {
+		return map[string]string{"status": "ok"}, http.StatusOK, nil
+	}},
+	"GetMetrics": {call: func(s *Server, _ []byte) (interface{}, int, error) {
+		return s.proxy.Metrics(), http.StatusOK, nil
+	}},
+	"GetActivity": {call: func(s *Server, _ []byte) (interface{}, int, error) {
+		return s.proxy.Activity(), http.StatusOK, nil
+	}},
+	"WatchActivity": {stream: true},
+	"ListConversations": {call: func(s *Server, _ []byte) (interface{}, int, error) {
+		return map[string]interface{}{"conversations": s.proxy.Conversations()}, http.StatusOK, nil
+	}},
+	"GetConversation": {call: func(s *Server, msg []byte) (interface{}, int, error) {
+		var req struct {
+			ID string `json:"id"`
+		}
+		if err := decodeMessage(msg, &req); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		c, ok := s.proxy.ConversationHistory(req.ID)
+		if !ok {
+			return nil, http.StatusNotFound, fmt.Errorf("no conversation %q in the history", req.ID)
+		}
+		return c, http.StatusOK, nil
+	}},
+	"ListModels": {call: func(s *Server, _ []byte) (interface{}, int, error) {
+		models, aliases := s.models()
+		return map[string]interface{}{"models": models, "aliases": aliases}, http.StatusOK, nil
+	}},
+	"AddLabel": {write: true, call: func(s *Server, msg []byte) (interface{}, int, error) {
+		var req addLabelRequest
+		if err := decodeMessage(msg, &req); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		info, status, err := s.addLabel(req)
+		return info, status, err
+	}},
+	"UnloadModel": {write: true, call: func(s *Server, msg []byte) (interface{}, int, error) {
+		var req struct {
+			Label string `json:"label"`
+		}
+		if err := decodeMessage(msg, &req); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		if status, err := s.unload(req.Label); err != nil {
+			return nil, status, err
+		}
+		return map[string]string{"status": "unloaded", "label": req.Label}, http.StatusOK, nil
+	}},
+	"ListPaused": {call: func(s *Server, _ []byte) (interface{}, int, error) {
+		return map[string]interface{}{"paused": s.proxy.Paused()}, http.StatusOK, nil
+	}},
+	"ResumePaused": {write: true, call: func(s *Server, msg []byte) (interface{}, int, error) {
+		var req struct {
+			ID string `json:"id"`
+			resumeRequest
+		}
+		if err := decodeMessage(msg, &req); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		var body []byte
+		if req.Body != nil {
+			body = []byte(*req.Body)
+		}
+		if err := s.proxy.ResumePaused(req.ID, body); err != nil {
+			return nil, http.StatusNotFound, err
+		}
+		return map[string]string{"status": "resumed", "id": req.ID}, http.StatusOK, nil
+	}},
+	"DropPaused": {write: true, call: func(s *Server, msg []byte) (interface{}, int, error) {
+		var req struct {
+			ID string `json:"id"`
+		}
+		if err := decodeMessage(msg, &req); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		if err := s.proxy.DropPaused(req.ID); err != nil {
+			return nil, http.StatusNotFound, err
+		}
+		return map[string]string{"status": "dropped", "id": req.ID}, http.StatusOK, nil
+	}},
+}
+
+// connectErrors maps REST statuses to Connect error codes and the HTTP
+// status the protocol pairs with each.
+var connectErrors = map[int]struct {
+	code   string
+	status int
+}{
+	http.StatusBadRequest:   {"invalid_argument", http.StatusBadRequest},
+	http.StatusUnauthorized: {"unauthenticated", http.StatusUnauthorized},
+	http.StatusForbidden:    {"permission_denied", http.StatusForbidden},
+	http.StatusNotFound:     {"not_found", http.StatusNotFound},
+	http.StatusBadGateway:   {"unavailable", http.StatusServiceUnavailable},
+}
+
+// serveConnect answers an AdminService call over the Connect protocol with
+// the JSON codec: unary calls as plain JSON POSTs, WatchActivity as an
+// enveloped stream.
+func (s *Server) serveConnect(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, connectPrefix)
+	m, ok := connectMethods[name]
+	if !ok || r.Method != http.MethodPost {
+		writeConnectError(w, http.StatusNotFound, "unimplemented", "no AdminService method "+name)
+		return
+	}
+	if name != "Health" {
+		if !s.authorized(r, m.write) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="claude-hybrid admin"`)
+			writeConnectError(w, http.StatusUnauthorized, "unauthenticated", "missing or invalid admin token")
+			return
+		}
+		if m.write && s.readOnly {
+			writeConnectError(w, http.StatusForbidden, "permission_denied", "admin API is read-only")
+			return
+		}
+	}
+	if enc := r.Header.Get("Content-Encoding") + r.Header.Get("Connect-Content-Encoding"); enc != "" && enc != "identity" {
+		writeConnectError(w, http.StatusNotImplemented, "unimplemented", "compression "+enc+" is not supported")
+		return
+	}
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if m.stream {
+		if mt != "application/connect+json" {
+			w.Header().Set("Accept-Post", "application/connect+json")
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		s.watchActivity(w, r)
+		return
+	}
+	if mt != "application/json" {
+		w.Header().Set("Accept-Post", "application/json")
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	msg, err := io.ReadAll(io.LimitReader(r.Body, defaults.MaxBodyBytes))
+	if err != nil {
+		writeConnectError(w, http.StatusBadRequest, "invalid_argument", err.Error())
+		return
+	}
+	resp, status, err := m.call(s, msg)
+	if err != nil {
+		e, ok := connectErrors[status]
+		if !ok {
+			e.code, e.status = "internal", http.StatusInternalServerError
+		}
+		writeConnectError(w, e.status, e.code, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// watchActivity streams activity snapshots until the client goes away.
+func (s *Server) watchActivity(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		IntervalMS int `json:"interval_ms"`
+	}
+	msg, err := readEnvelope(r.Body)
+	if err == nil {
+		err = decodeMessage(msg, &req)
+	}
+	w.Header().Set("Content-Type", "application/connect+json")
+	w.WriteHeader(http.StatusOK)
+	if err != nil {
+		writeEndStream(w, "invalid_argument", err.Error())
+		return
+	}
+	interval := time.Second
+	if req.IntervalMS > 0 {
+		interval = max(time.Duration(req.IntervalMS)*time.Millisecond, 100*time.Millisecond)
+	}
+	flusher, _ := w.(http.Flusher)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		data, _ := json.Marshal(s.proxy.Activity())
+		if writeEnvelope(w, 0, data) != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		select {
+		case <-ticker.C:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// readEnvelope reads one enveloped message: a flags byte, a big-endian
+// uint32 length, then the message.
+func readEnvelope(r io.Reader) ([]byte, error) {
+	var head [5]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return nil, fmt.Errorf("read request envelope: %w", err)
+	}
+	if head[0]&1 != 0 {
+		return nil, errors.New("compressed messages are not supported")
+	}
+	n := binary.BigEndian.Uint32(head[1:])
+	if int64(n) > defaults.MaxBodyBytes {
+		return nil, errors.New("request message too large")
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, fmt.Errorf("read request message: %w", err)
+	}
+	return msg, nil
+}
+
+func writeEnvelope(w io.Writer, flags byte, data []byte) error {
+	var head [5]byte
+	head[0] = flags
+	binary.BigEndian.PutUint32(head[1:], uint32(len(data)))
+	if _, err := w.Write(head[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// writeEndStream ends a stream with an error.
+func writeEndStream(w io.Writer, code, msg string) {
+	data, _ := json.Marshal(map[string]interface{}{"error": map[string]string{"code": code, "message": msg}})
+	writeEnvelope(w, 2, data)
+}
+
+func writeConnectError(w http.ResponseWriter, status int, code, msg string) {
+	writeJSON(w, status, map[string]string{"code": code, "message": msg})
+}
+
+// decodeMessage decodes a JSON request message into v, whose json tags are
+// the proto field names. Clients usually send the lowerCamelCase names
+// protobuf's JSON mapping prefers, so those are accepted too. An empty
+// message is the zero value.
+func decodeMessage(msg []byte, v interface{}) error {
+	if len(bytes.TrimSpace(msg)) == 0 {
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(msg, &fields); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	named := make(map[string]json.RawMessage, len(fields))
+	for name, value := range fields {
+		named[protoName(name)] = value
+	}
+	data, _ := json.Marshal(named)
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return nil
+}
+
+// protoName turns a lowerCamelCase JSON name into its snake_case proto
+// field name ("maxTokens" → "max_tokens").
+func protoName(name string) string {
+	var b strings.Builder
+	for _, c := range name {
+		if unicode.IsUpper(c) {
+			b.WriteByte('_')
+			c = unicode.ToLower(c)
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
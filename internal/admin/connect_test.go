@@ -0,0 +1,213 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+
+	adminv1 "github.com/peter-wagstaff/claude-hybrid-router/gen/claudehybrid/admin/v1"
+	"github.com/peter-wagstaff/claude-hybrid-router/gen/claudehybrid/admin/v1/adminv1connect"
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/proxy"
+)
+
+// callConnect makes a unary AdminService call and returns the status and
+// decoded body.
+func callConnect(t *testing.T, s *Server, method, body, token string) (int, map[string]interface{}) {
+	t.Helper()
+	req := httptest.NewRequest("POST", connectPrefix+method, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	var out map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("%s: body is not JSON: %q", method, rec.Body)
+	}
+	return rec.Code, out
+}
+
+func TestConnectUnary(t *testing.T) {
+	s := newTestServer(t, "http://127.0.0.1:1/v1")
+
+	code, out := callConnect(t, s, "ListModels", "{}", "")
+	models, _ := out["models"].([]interface{})
+	if code != 200 || len(models) != 1 || models[0].(map[string]interface{})["keep_alive"] != "30m" {
+		t.Errorf("ListModels = %d %v", code, out)
+	}
+
+	// Request fields in protobuf JSON's lowerCamelCase are accepted.
+	code, out = callConnect(t, s, "AddLabel",
+		`{"label":"quick","provider":"ollama","model":"llama3","maxTokens":512}`, "")
+	if code != 200 || out["label"] != "quick" {
+		t.Errorf("AddLabel = %d %v", code, out)
+	}
+	m, err := s.proxy.ModelResolver().Resolve("quick")
+	if err != nil || m.MaxTokens != 512 {
+		t.Errorf("added label = %+v, %v", m, err)
+	}
+
+	code, out = callConnect(t, s, "UnloadModel", `{"label":"missing"}`, "")
+	if code != 404 || out["code"] != "not_found" {
+		t.Errorf("UnloadModel unknown label = %d %v", code, out)
+	}
+	code, out = callConnect(t, s, "NoSuchMethod", "{}", "")
+	if code != 404 || out["code"] != "unimplemented" {
+		t.Errorf("unknown method = %d %v", code, out)
+	}
+
+	req := httptest.NewRequest("POST", connectPrefix+"ListModels", strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "application/proto")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnsupportedMediaType || rec.Header().Get("Accept-Post") != "application/json" {
+		t.Errorf("binary codec = %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestConnectAuth(t *testing.T) {
+	s := New(proxy.New(nil), WithTokens("r-token", "w-token"))
+
+	if code, out := callConnect(t, s, "Health", "{}", ""); code != 200 || out["status"] != "ok" {
+		t.Errorf("Health without token = %d %v", code, out)
+	}
+	if code, out := callConnect(t, s, "GetMetrics", "{}", ""); code != 401 || out["code"] != "unauthenticated" {
+		t.Errorf("GetMetrics without token = %d %v", code, out)
+	}
+	if code, _ := callConnect(t, s, "GetMetrics", "{}", "r-token"); code != 200 {
+		t.Errorf("GetMetrics with read token = %d", code)
+	}
+	// A POST is not a write by itself: the method decides.
+	if code, _ := callConnect(t, s, "DropPaused", `{"id":"1"}`, "r-token"); code != 401 {
+		t.Errorf("DropPaused with read token = %d, want 401", code)
+	}
+	if code, out := callConnect(t, s, "DropPaused", `{"id":"1"}`, "w-token"); code != 404 || out["code"] != "not_found" {
+		t.Errorf("DropPaused unknown id = %d %v", code, out)
+	}
+
+	s = New(proxy.New(nil), WithReadOnly(true))
+	if code, out := callConnect(t, s, "DropPaused", `{"id":"1"}`, ""); code != 403 || out["code"] != "permission_denied" {
+		t.Errorf("DropPaused read-only = %d %v", code, out)
+	}
+}
+
+func TestConnectWatchActivity(t *testing.T) {
+	srv := httptest.NewServer(New(proxy.New(nil)))
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var msg bytes.Buffer
+	writeEnvelope(&msg, 0, []byte(`{"intervalMs":100}`))
+	req, _ := http.NewRequestWithContext(ctx, "POST", srv.URL+connectPrefix+"WatchActivity", &msg)
+	req.Header.Set("Content-Type", "application/connect+json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 || resp.Header.Get("Content-Type") != "application/connect+json" {
+		t.Fatalf("got %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	for i := 0; i < 2; i++ {
+		var head [5]byte
+		if _, err := io.ReadFull(resp.Body, head[:]); err != nil {
+			t.Fatalf("snapshot %d: %v", i, err)
+		}
+		data := make([]byte, binary.BigEndian.Uint32(head[1:]))
+		io.ReadFull(resp.Body, data)
+		var snap map[string]interface{}
+		if head[0] != 0 || json.Unmarshal(data, &snap) != nil {
+			t.Fatalf("snapshot %d: flags %d, %q", i, head[0], data)
+		}
+	}
+}
+
+// TestConnectGeneratedClient calls serveConnect through the connect-go
+// client generated from admin.proto, configured for JSON as the proto
+// comment says.
+func TestConnectGeneratedClient(t *testing.T) {
+	s := newTestServer(t, "http://127.0.0.1:1/v1")
+	s.readToken, s.writeToken = "r-token", "w-token"
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	client := adminv1connect.NewAdminServiceClient(srv.Client(), srv.URL, connect.WithProtoJSON())
+	ctx := context.Background()
+	withToken := func(req connect.AnyRequest, token string) {
+		req.Header().Set("Authorization", "Bearer "+token)
+	}
+
+	health, err := client.Health(ctx, connect.NewRequest(&adminv1.HealthRequest{}))
+	if err != nil || health.Msg.GetStatus() != "ok" {
+		t.Errorf("Health = %v, %v", health, err)
+	}
+
+	_, err = client.ListModels(ctx, connect.NewRequest(&adminv1.ListModelsRequest{}))
+	if connect.CodeOf(err) != connect.CodeUnauthenticated {
+		t.Errorf("ListModels without token: %v, want unauthenticated", err)
+	}
+	listReq := connect.NewRequest(&adminv1.ListModelsRequest{})
+	withToken(listReq, "r-token")
+	models, err := client.ListModels(ctx, listReq)
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	if ms := models.Msg.GetModels(); len(ms) != 1 || ms[0].GetLabel() != "coder" || ms[0].GetKeepAlive() != "30m" {
+		t.Errorf("ListModels = %v", models.Msg)
+	}
+
+	addReq := connect.NewRequest(&adminv1.AddLabelRequest{
+		Label: "quick", Provider: "ollama", Model: "llama3", MaxTokens: 512,
+	})
+	withToken(addReq, "r-token")
+	if _, err := client.AddLabel(ctx, addReq); connect.CodeOf(err) != connect.CodeUnauthenticated {
+		t.Errorf("AddLabel with read token: %v, want unauthenticated", err)
+	}
+	withToken(addReq, "w-token")
+	added, err := client.AddLabel(ctx, addReq)
+	if err != nil || added.Msg.GetLabel() != "quick" || added.Msg.GetModel() != "llama3" {
+		t.Errorf("AddLabel = %v, %v", added, err)
+	}
+	if m, err := s.proxy.ModelResolver().Resolve("quick"); err != nil || m.MaxTokens != 512 {
+		t.Errorf("added label = %+v, %v", m, err)
+	}
+
+	convReq := connect.NewRequest(&adminv1.GetConversationRequest{Id: "missing"})
+	withToken(convReq, "r-token")
+	if _, err := client.GetConversation(ctx, convReq); connect.CodeOf(err) != connect.CodeNotFound {
+		t.Errorf("GetConversation unknown id: %v, want not_found", err)
+	}
+
+	metricsReq := connect.NewRequest(&adminv1.GetMetricsRequest{})
+	withToken(metricsReq, "r-token")
+	if metrics, err := client.GetMetrics(ctx, metricsReq); err != nil || len(metrics.Msg.GetFields()) == 0 {
+		t.Errorf("GetMetrics = %v, %v", metrics, err)
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	watchReq := connect.NewRequest(&adminv1.WatchActivityRequest{IntervalMs: 100})
+	withToken(watchReq, "r-token")
+	stream, err := client.WatchActivity(watchCtx, watchReq)
+	if err != nil {
+		t.Fatalf("WatchActivity: %v", err)
+	}
+	defer stream.Close()
+	for i := 0; i < 2; i++ {
+		if !stream.Receive() {
+			t.Fatalf("snapshot %d: %v", i, stream.Err())
+		}
+		if stream.Msg() == nil {
+			t.Fatalf("snapshot %d is nil", i)
+		}
+	}
+}
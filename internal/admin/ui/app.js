@@ -0,0 +1,137 @@
+// Admin dashboard: polls the admin API and renders it. All model output and
+// config values are inserted as text, never as HTML.
+"use strict";
+
+const TOKEN_KEY = "claudeHybridAdminToken";
+const HISTORY_SECONDS = 300;
+const series = []; // [{t, out}] total output tokens over time
+
+function el(tag, attrs, ...children) {
+  const e = document.createElement(tag);
+  for (const [k, v] of Object.entries(attrs || {})) e.setAttribute(k, v);
+  for (const c of children) e.append(c instanceof Node ? c : document.createTextNode(String(c)));
+  return e;
+}
+
+function svg(tag, attrs, text) {
+  const e = document.createElementNS("http://www.w3.org/2000/svg", tag);
+  for (const [k, v] of Object.entries(attrs)) e.setAttribute(k, v);
+  if (text !== undefined) e.textContent = text;
+  return e;
+}
+
+async function api(path) {
+  const headers = {};
+  const token = localStorage.getItem(TOKEN_KEY);
+  if (token) headers.Authorization = "Bearer " + token;
+  const resp = await fetch("/admin/" + path, { headers });
+  if (resp.status === 401) {
+    document.getElementById("login").hidden = false;
+    throw new Error("admin token required");
+  }
+  if (!resp.ok) throw new Error(path + ": " + resp.status);
+  return resp.json();
+}
+
+function fillTable(id, rows) {
+  const body = document.querySelector("#" + id + " tbody");
+  body.replaceChildren(...rows.map(cells => el("tr", {}, ...cells.map(c => el("td", {}, c)))));
+}
+
+function statusCell(status) {
+  const ok = status === "ok" || status === "dedupe";
+  return el("span", { class: ok ? "ok" : "err" }, status);
+}
+
+const ms = n => n + " ms";
+const time = s => new Date(s).toLocaleTimeString();
+const ago = s => Math.round((Date.now() - new Date(s)) / 1000) + "s ago";
+
+function renderInFlight(list) {
+  const box = document.getElementById("inflight");
+  if (!list.length) {
+    box.replaceChildren(el("p", { class: "muted" }, "Nothing routed right now."));
+    return;
+  }
+  box.replaceChildren(...list.map(ev => el("div", { class: "card" },
+    el("strong", {}, ev.label), " ",
+    el("span", { class: "muted" }, `${ev.provider || "-"}/${ev.model || "-"} · ${ev.stream ? "stream" : "sync"} · ${ms(ev.latency_ms)}`),
+    ev.preview ? el("pre", {}, ev.preview) : el("p", { class: "muted" }, "waiting for output…"))));
+}
+
+function barChart(id, items) {
+  const chart = document.getElementById(id);
+  const max = Math.max(1, ...items.map(i => i.value));
+  const w = 400 / Math.max(items.length, 1);
+  chart.replaceChildren(...items.flatMap((it, n) => {
+    const h = (it.value / max) * 130;
+    return [
+      svg("rect", { x: n * w + 4, y: 140 - h, width: w - 8, height: h }),
+      svg("text", { x: n * w + 4, y: 155 }, `${it.label} ${it.value.toFixed(1)}`),
+    ];
+  }));
+}
+
+function lineChart(id, points) {
+  const chart = document.getElementById(id);
+  if (points.length < 2) {
+    chart.replaceChildren(svg("text", { x: 8, y: 20 }, "collecting…"));
+    return;
+  }
+  const t0 = points[0].t, span = Math.max(1, points[points.length - 1].t - t0);
+  const min = points[0].out, max = Math.max(min + 1, points[points.length - 1].out);
+  const coords = points.map(p => `${((p.t - t0) / span) * 400},${150 - ((p.out - min) / (max - min)) * 140}`);
+  chart.replaceChildren(
+    svg("polyline", { points: coords.join(" ") }),
+    svg("text", { x: 8, y: 14 }, `+${max - min} tokens`));
+}
+
+async function refresh() {
+  const status = document.getElementById("status");
+  try {
+    const [act, metrics] = await Promise.all([api("activity"), api("metrics")]);
+    status.textContent = "live · " + new Date().toLocaleTimeString();
+    status.className = "ok";
+    const a = metrics.admission;
+    document.getElementById("tunnels").textContent =
+      `tunnels ${a.in_flight}/${a.max_concurrent} · waiting ${a.waiting} · bypassed ${metrics.bypass.tunnels}`;
+
+    renderInFlight(act.in_flight);
+    fillTable("labels", act.labels.map(l => [l.label, l.requests, l.errors, ms(l.avg_latency_ms), ms(l.last_latency_ms),
+      l.input_tokens, l.output_tokens, l.output_tokens_per_sec.toFixed(1)]));
+    fillTable("providers", act.providers.map(p => [p.provider,
+      el("span", { class: p.consecutive_errors ? "err" : "ok" }, p.consecutive_errors ? `failing (${p.consecutive_errors} in a row)` : "ok"),
+      p.last_status, ago(p.last_seen)]));
+    fillTable("history", act.recent.map(ev => [time(ev.start), ev.label, `${ev.provider || "-"} / ${ev.model || "-"}`,
+      ev.stream ? "stream" : "sync", statusCell(ev.status), ms(ev.latency_ms), `${ev.input_tokens || 0} / ${ev.output_tokens || 0}`]));
+
+    barChart("chart-labels", act.labels.map(l => ({ label: l.label, value: l.output_tokens_per_sec })));
+    const now = Date.now() / 1000;
+    series.push({ t: now, out: act.labels.reduce((s, l) => s + l.output_tokens, 0) });
+    while (series.length && series[0].t < now - HISTORY_SECONDS) series.shift();
+    lineChart("chart-tokens", series);
+  } catch (e) {
+    status.textContent = e.message;
+    status.className = "err";
+  }
+}
+
+async function refreshModels() {
+  try {
+    const { models } = await api("models");
+    fillTable("models", models.map(m => [m.label, m.provider, m.model, m.endpoint, (m.transform || []).join(", ")]));
+  } catch (e) { /* reported by refresh */ }
+}
+
+document.getElementById("login").addEventListener("submit", e => {
+  e.preventDefault();
+  localStorage.setItem(TOKEN_KEY, document.getElementById("token").value);
+  document.getElementById("login").hidden = true;
+  refresh();
+  refreshModels();
+});
+
+refresh();
+refreshModels();
+setInterval(refresh, 1000);
+setInterval(refreshModels, 10000);
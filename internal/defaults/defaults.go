@@ -0,0 +1,29 @@
+// Package defaults holds the proxy's built-in limits, timeouts, certificate
+// lifetimes and routing marker. They are internal so they can change between
+// releases; the tunable ones reach other programs through
+// config.DefaultLimits.
+package defaults
+
+import "time"
+
+const (
+	UpstreamTimeout    = 30 * time.Second
+	MaxBodyBytes       = 10 << 20 // 10 MB
+	ClientRecvTimeout  = 5 * time.Minute
+	MaxProxyGoroutines = 128
+	MaxQueuedTunnels   = 256              // CONNECTs waiting for a slot before new ones are refused
+	QueueTimeout       = 30 * time.Second // longest a CONNECT waits for a slot
+	PreloadTimeout     = 10 * time.Minute // cold model loads can take minutes
+	SSEMaxLineBytes    = 16 << 20         // default cap on a single provider SSE line
+	StreamBufferBytes  = 1 << 20          // translated SSE buffered per client before backpressure
+	ClientWriteTimeout = 30 * time.Second // a client that can't take a write this long is dropped
+
+	MitmCacheMaxSize      = 256
+	MitmCertValidityHours = 1.0
+	CAValidity            = 365 * 24 * time.Hour
+	CARenewBefore         = 30 * 24 * time.Hour // regenerate the CA at startup once it's this close to expiry
+)
+
+// RouteMarker opens a routing marker, <!-- @proxy-local-route:af83e9 ... -->.
+// The hash keeps ordinary prompt text from being mistaken for a marker.
+const RouteMarker = "<!-- @proxy-local-route:af83e9"
@@ -0,0 +1,25 @@
+// Package filelock takes exclusive, non-blocking locks on open files, so
+// concurrent claude-hybrid instances can coordinate on shared files such as
+// proxy.log. Locks are flock(2) on Unix and LockFileEx on Windows; they are
+// held until Unlock or until the file is closed.
+package filelock
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrLocked is returned by TryLock when another process holds the lock.
+var ErrLocked = errors.New("filelock: locked by another process")
+
+// TryLock takes an exclusive lock on f without waiting. It returns
+// ErrLocked if another process holds one, and errors.ErrUnsupported on
+// platforms without file locking.
+func TryLock(f *os.File) error {
+	return tryLock(f)
+}
+
+// Unlock releases a lock taken with TryLock.
+func Unlock(f *os.File) error {
+	return unlock(f)
+}
@@ -0,0 +1,35 @@
+package filelock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTryLockExcludesOtherHandles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "proxy.log")
+	open := func() *os.File {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { f.Close() })
+		return f
+	}
+	a, b := open(), open()
+
+	if err := TryLock(a); err != nil {
+		t.Fatalf("first lock: %v", err)
+	}
+	if err := TryLock(b); !errors.Is(err, ErrLocked) {
+		t.Fatalf("second lock = %v, want ErrLocked", err)
+	}
+	if err := Unlock(a); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	if err := TryLock(b); err != nil {
+		t.Fatalf("lock after unlock: %v", err)
+	}
+	Unlock(b)
+}
@@ -0,0 +1,227 @@
+// Package logfile writes proxy.log, rotating it by size. Rotated logs are
+// kept gzip-compressed as proxy.log.1.gz (newest) through proxy.log.N.gz.
+//
+// Several claude-hybrid instances append to the same log. Rotation is done
+// by whichever instance first sees the file over the limit, under a lock on
+// proxy.log.lock; the others notice the file was replaced on their next
+// write and reopen it.
+package logfile
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/filelock"
+)
+
+// Defaults used for zero Options fields.
+const (
+	DefaultMaxSize   = 20 << 20
+	DefaultRetention = 5
+)
+
+// Options controls rotation.
+type Options struct {
+	MaxSize   int64 // rotate once the log reaches this many bytes
+	Retention int   // compressed logs kept; -1 keeps none
+}
+
+func (o Options) withDefaults() Options {
+	if o.MaxSize <= 0 {
+		o.MaxSize = DefaultMaxSize
+	}
+	if o.Retention == 0 {
+		o.Retention = DefaultRetention
+	}
+	return o
+}
+
+// File is an append-only log file that rotates by size. It is safe for
+// concurrent use.
+type File struct {
+	path string
+
+	mu   sync.Mutex
+	opts Options
+	f    *os.File
+	fi   os.FileInfo // identity of f, to notice rotation by another instance
+}
+
+// Open opens or creates the log at path, rotating it first if it is
+// already over the limit.
+func Open(path string, opts Options) (*File, error) {
+	l := &File{path: path, opts: opts.withDefaults()}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.fi.Size() >= l.opts.MaxSize {
+		l.rotate()
+	}
+	return l, nil
+}
+
+// SetOptions changes the rotation settings, so the log can be opened
+// before config.yaml is read.
+func (l *File) SetOptions(opts Options) {
+	l.mu.Lock()
+	l.opts = opts.withDefaults()
+	l.mu.Unlock()
+}
+
+func (l *File) open() error {
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.f, l.fi = f, fi
+	return nil
+}
+
+// Write appends p, reopening the log if another instance rotated it and
+// rotating it if this write takes it over the limit.
+func (l *File) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	size := l.follow()
+	n, err := l.f.Write(p)
+	if err == nil && size+int64(n) >= l.opts.MaxSize {
+		l.rotate()
+	}
+	return n, err
+}
+
+// follow reopens the log when the file at path is no longer the one held
+// open, and returns the current size.
+func (l *File) follow() int64 {
+	fi, err := os.Stat(l.path)
+	if err == nil && os.SameFile(fi, l.fi) {
+		return fi.Size()
+	}
+	old := l.f
+	if err := l.open(); err != nil {
+		return 0 // keep writing to the old file rather than lose lines
+	}
+	old.Close()
+	return l.fi.Size()
+}
+
+// Close closes the log.
+func (l *File) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
+
+// Rotated returns the path of the nth retained log (1 = newest).
+func Rotated(path string, n int) string {
+	return fmt.Sprintf("%s.%d.gz", path, n)
+}
+
+// rotate moves the log aside, starts a new one, and compresses the old
+// one into the retained set. If another instance holds the rotation lock
+// it is already rotating, and this one just follows. Called with mu held.
+func (l *File) rotate() {
+	lock, err := os.OpenFile(l.path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return
+	}
+	defer lock.Close()
+	if filelock.TryLock(lock) != nil {
+		return
+	}
+	defer filelock.Unlock(lock)
+
+	// Re-check under the lock: another instance may have just rotated.
+	if fi, err := os.Stat(l.path); err != nil || fi.Size() < l.opts.MaxSize {
+		l.follow()
+		return
+	}
+
+	aside := l.path + ".rotating"
+	l.f.Close()
+	renameErr := os.Rename(l.path, aside)
+	if err := l.open(); err != nil {
+		return
+	}
+	if renameErr != nil {
+		return // e.g. Windows refuses to rename a log open in another instance
+	}
+
+	keep := max(l.opts.Retention, 0)
+	// Drop logs left over from a higher retention.
+	for i := keep + 1; os.Remove(Rotated(l.path, i)) == nil; i++ {
+	}
+	if keep > 0 {
+		os.Remove(Rotated(l.path, keep))
+		for i := keep - 1; i >= 1; i-- {
+			os.Rename(Rotated(l.path, i), Rotated(l.path, i+1))
+		}
+		compress(aside, Rotated(l.path, 1))
+	}
+	os.Remove(aside)
+}
+
+// compress gzips src into dst, writing dst atomically.
+func compress(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp := dst + ".part"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	_, err = io.Copy(zw, in)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// ParseSize parses a byte count such as "20MB", "512K" or "1048576".
+// Units are powers of 1024.
+func ParseSize(s string) (int64, error) {
+	t := strings.ToUpper(strings.TrimSpace(s))
+	t = strings.TrimSuffix(strings.TrimSuffix(t, "IB"), "B")
+	mult := int64(1)
+	if n := len(t); n > 0 {
+		switch t[n-1] {
+		case 'K':
+			mult = 1 << 10
+		case 'M':
+			mult = 1 << 20
+		case 'G':
+			mult = 1 << 30
+		}
+		if mult > 1 {
+			t = strings.TrimSpace(t[:n-1])
+		}
+	}
+	v, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return v * mult, nil
+}
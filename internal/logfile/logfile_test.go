@@ -0,0 +1,110 @@
+package logfile
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readGz(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(zr)
+	return string(b)
+}
+
+func TestRotateBySizeWithRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "proxy.log")
+	l, err := Open(path, Options{MaxSize: 10, Retention: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	for _, line := range []string{"first-line\n", "second-line\n", "third-line\n", "tail\n"} {
+		if _, err := l.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := readGz(t, Rotated(path, 1)); got != "third-line\n" {
+		t.Errorf(".1.gz = %q", got)
+	}
+	if got := readGz(t, Rotated(path, 2)); got != "second-line\n" {
+		t.Errorf(".2.gz = %q", got)
+	}
+	if _, err := os.Stat(Rotated(path, 3)); !os.IsNotExist(err) {
+		t.Error("retention 2 should keep only two rotated logs")
+	}
+	if b, _ := os.ReadFile(path); string(b) != "tail\n" {
+		t.Errorf("current log = %q", b)
+	}
+}
+
+func TestOtherInstanceFollowsRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "proxy.log")
+	a, err := Open(path, Options{MaxSize: 16, Retention: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := Open(path, Options{MaxSize: 16, Retention: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	a.Write([]byte("a: 0123456789abcdef\n")) // over the limit: a rotates
+	b.Write([]byte("b: after\n"))
+	if got, _ := os.ReadFile(path); string(got) != "b: after\n" {
+		t.Errorf("second instance kept writing to the rotated file: current log = %q", got)
+	}
+	if got := readGz(t, Rotated(path, 1)); !strings.HasPrefix(got, "a: ") {
+		t.Errorf(".1.gz = %q", got)
+	}
+}
+
+func TestOpenRotatesOversizedLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "proxy.log")
+	os.WriteFile(path, []byte(strings.Repeat("x", 100)), 0644)
+	l, err := Open(path, Options{MaxSize: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if fi, _ := os.Stat(path); fi.Size() != 0 {
+		t.Errorf("log not rotated at open: %d bytes", fi.Size())
+	}
+	if len(readGz(t, Rotated(path, 1))) != 100 {
+		t.Error("old contents not kept")
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for in, want := range map[string]int64{
+		"1048576": 1 << 20,
+		"20MB":    20 << 20,
+		"512k":    512 << 10,
+		"1GiB":    1 << 30,
+		"100B":    100,
+	} {
+		if got, err := ParseSize(in); err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "MB", "-1", "ten"} {
+		if _, err := ParseSize(bad); err == nil {
+			t.Errorf("ParseSize(%q) should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package mitm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// keyring is a minimal OS secret store. Secrets are kept base64-encoded so
+// every backend only ever handles text.
+type keyring interface {
+	name() string
+	get(service, account string) ([]byte, error)
+	set(service, account string, secret []byte) error
+	delete(service, account string) error
+}
+
+// systemKeyring returns the platform keyring. It shells out to the OS tools
+// rather than linking platform libraries; it is a variable so tests can
+// substitute an in-memory store.
+var systemKeyring = func() (keyring, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		if _, err := exec.LookPath("security"); err != nil {
+			return nil, errors.New("macOS keychain unavailable: security tool not found")
+		}
+		return macKeychain{}, nil
+	case "linux", "freebsd", "openbsd":
+		if _, err := exec.LookPath("secret-tool"); err != nil {
+			return nil, errors.New("Secret Service unavailable: install secret-tool (libsecret-tools)")
+		}
+		return secretService{}, nil
+	}
+	return nil, fmt.Errorf("no OS keyring support on %s", runtime.GOOS)
+}
+
+// macKeychain drives /usr/bin/security. Writes go through `security -i` on
+// stdin so the secret never appears in a process listing.
+type macKeychain struct{}
+
+func (macKeychain) name() string { return "macOS Keychain" }
+
+func (macKeychain) get(service, account string) ([]byte, error) {
+	out, err := runTool(nil, "security", "find-generic-password", "-s", service, "-a", account, "-w")
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(out)))
+}
+
+func (macKeychain) set(service, account string, secret []byte) error {
+	cmd := fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+		quoteSecurityArg(service), quoteSecurityArg(account), base64.StdEncoding.EncodeToString(secret))
+	_, err := runTool([]byte(cmd), "security", "-i")
+	return err
+}
+
+func (macKeychain) delete(service, account string) error {
+	_, err := runTool(nil, "security", "delete-generic-password", "-s", service, "-a", account)
+	return err
+}
+
+func quoteSecurityArg(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// secretService drives secret-tool (libsecret), which reads the secret from
+// stdin.
+type secretService struct{}
+
+func (secretService) name() string { return "Secret Service" }
+
+func (secretService) get(service, account string) ([]byte, error) {
+	out, err := runTool(nil, "secret-tool", "lookup", "service", service, "account", account)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("no keyring entry")
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(out)))
+}
+
+func (secretService) set(service, account string, secret []byte) error {
+	_, err := runTool([]byte(base64.StdEncoding.EncodeToString(secret)),
+		"secret-tool", "store", "--label=claude-hybrid MITM CA key", "service", service, "account", account)
+	return err
+}
+
+func (secretService) delete(service, account string) error {
+	_, err := runTool(nil, "secret-tool", "clear", "service", service, "account", account)
+	return err
+}
+
+func runTool(stdin []byte, name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %s", name, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return out, nil
+}
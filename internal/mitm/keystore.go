@@ -0,0 +1,243 @@
+package mitm
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// KeyStorage says where the CA private key lives.
+type KeyStorage string
+
+const (
+	// StorageFile keeps the key as a plaintext PEM file (mode 0600).
+	StorageFile KeyStorage = "file"
+	// StoragePassphrase keeps the key file encrypted with a passphrase.
+	StoragePassphrase KeyStorage = "passphrase"
+	// StorageKeyring keeps the key in the OS keyring; the key file only
+	// records where.
+	StorageKeyring KeyStorage = "keyring"
+)
+
+// PassphraseEnv supplies the passphrase for an encrypted CA key, so the
+// proxy can unlock it at start without a prompt.
+const PassphraseEnv = "CLAUDE_HYBRID_CA_PASSPHRASE"
+
+// ErrPassphraseRequired is returned by LoadCAKey for an encrypted key when
+// no passphrase was given.
+var ErrPassphraseRequired = errors.New("CA key is passphrase-protected")
+
+const (
+	encryptedKeyType = "CLAUDE-HYBRID ENCRYPTED KEY"
+	keyringRefType   = "CLAUDE-HYBRID KEYRING KEY"
+	keyringService   = "claude-hybrid"
+	pbkdf2Iterations = 600_000
+)
+
+// KeyStorageOf reports how the key file at path is stored.
+func KeyStorageOf(path string) (KeyStorage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return "", fmt.Errorf("%s: not a PEM file", path)
+	}
+	switch block.Type {
+	case encryptedKeyType:
+		return StoragePassphrase, nil
+	case keyringRefType:
+		return StorageKeyring, nil
+	}
+	return StorageFile, nil
+}
+
+// LoadCAKey returns the PEM-encoded CA key stored at path, fetching it from
+// the OS keyring or decrypting it with passphrase as the file requires.
+// passphrase is ignored for other storage kinds.
+func LoadCAKey(path string, passphrase []byte) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: not a PEM file", path)
+	}
+	switch block.Type {
+	case encryptedKeyType:
+		if len(passphrase) == 0 {
+			return nil, ErrPassphraseRequired
+		}
+		return decryptKey(block, passphrase)
+	case keyringRefType:
+		kr, err := systemKeyring()
+		if err != nil {
+			return nil, err
+		}
+		key, err := kr.get(keyringService, block.Headers["Account"])
+		if err != nil {
+			return nil, fmt.Errorf("read CA key from %s: %w", kr.name(), err)
+		}
+		return key, nil
+	}
+	return data, nil
+}
+
+// StoreCAKey writes keyPEM to path using storage. Passphrase storage needs a
+// non-empty passphrase. Moving a key out of the keyring removes the keyring
+// entry once the new file is in place.
+func StoreCAKey(path string, keyPEM []byte, storage KeyStorage, passphrase []byte) error {
+	prev, _ := os.ReadFile(path)
+
+	var out []byte
+	switch storage {
+	case StorageFile:
+		out = keyPEM
+	case StoragePassphrase:
+		if len(passphrase) == 0 {
+			return errors.New("passphrase storage needs a passphrase")
+		}
+		block, err := encryptKey(keyPEM, passphrase)
+		if err != nil {
+			return err
+		}
+		out = pem.EncodeToMemory(block)
+	case StorageKeyring:
+		kr, err := systemKeyring()
+		if err != nil {
+			return err
+		}
+		account, err := keyringAccount(path)
+		if err != nil {
+			return err
+		}
+		if err := kr.set(keyringService, account, keyPEM); err != nil {
+			return fmt.Errorf("store CA key in %s: %w", kr.name(), err)
+		}
+		out = pem.EncodeToMemory(&pem.Block{
+			Type:    keyringRefType,
+			Headers: map[string]string{"Service": keyringService, "Account": account, "Keyring": kr.name()},
+		})
+	default:
+		return fmt.Errorf("unknown key storage %q", storage)
+	}
+
+	if err := writeFileAtomic(path, out, 0600); err != nil {
+		return err
+	}
+	if storage != StorageKeyring {
+		if block, _ := pem.Decode(prev); block != nil && block.Type == keyringRefType {
+			if kr, err := systemKeyring(); err == nil {
+				kr.delete(keyringService, block.Headers["Account"])
+			}
+		}
+	}
+	return nil
+}
+
+// keyringAccount names the keyring entry after the key file's absolute path,
+// so separate certs directories never share an entry.
+func keyringAccount(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return "ca-key:" + abs, nil
+}
+
+func encryptKey(keyPEM, passphrase []byte) (*pem.Block, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := keyCipher(passphrase, salt, pbkdf2Iterations)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return &pem.Block{
+		Type: encryptedKeyType,
+		Headers: map[string]string{
+			"KDF":        "pbkdf2-sha256",
+			"Iterations": strconv.Itoa(pbkdf2Iterations),
+			"Salt":       hex.EncodeToString(salt),
+			"Cipher":     "aes-256-gcm",
+		},
+		Bytes: gcm.Seal(nonce, nonce, keyPEM, nil),
+	}, nil
+}
+
+func decryptKey(block *pem.Block, passphrase []byte) ([]byte, error) {
+	if block.Headers["KDF"] != "pbkdf2-sha256" || block.Headers["Cipher"] != "aes-256-gcm" {
+		return nil, fmt.Errorf("unsupported CA key encryption %s/%s", block.Headers["KDF"], block.Headers["Cipher"])
+	}
+	iter, err := strconv.Atoi(block.Headers["Iterations"])
+	if err != nil || iter <= 0 {
+		return nil, fmt.Errorf("bad CA key iteration count %q", block.Headers["Iterations"])
+	}
+	salt, err := hex.DecodeString(block.Headers["Salt"])
+	if err != nil {
+		return nil, fmt.Errorf("bad CA key salt: %w", err)
+	}
+	gcm, err := keyCipher(passphrase, salt, iter)
+	if err != nil {
+		return nil, err
+	}
+	if len(block.Bytes) < gcm.NonceSize() {
+		return nil, errors.New("CA key ciphertext truncated")
+	}
+	nonce, ct := block.Bytes[:gcm.NonceSize()], block.Bytes[gcm.NonceSize():]
+	keyPEM, err := gcm.Open(nil, nonce, ct, nil)
+	if err != nil {
+		return nil, errors.New("wrong passphrase for CA key")
+	}
+	return keyPEM, nil
+}
+
+func keyCipher(passphrase, salt []byte, iter int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, string(passphrase), salt, iter, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// writeFileAtomic replaces path with data via a temp file and rename, so a
+// crash never leaves a half-written key.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".ca-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,117 @@
+package mitm
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// memKeyring is an in-memory keyring for tests.
+type memKeyring map[string][]byte
+
+func (m memKeyring) name() string { return "test keyring" }
+
+func (m memKeyring) get(service, account string) ([]byte, error) {
+	v, ok := m[service+"/"+account]
+	if !ok {
+		return nil, errors.New("no keyring entry")
+	}
+	return v, nil
+}
+
+func (m memKeyring) set(service, account string, secret []byte) error {
+	m[service+"/"+account] = secret
+	return nil
+}
+
+func (m memKeyring) delete(service, account string) error {
+	delete(m, service+"/"+account)
+	return nil
+}
+
+func useMemKeyring(t *testing.T) memKeyring {
+	kr := memKeyring{}
+	orig := systemKeyring
+	systemKeyring = func() (keyring, error) { return kr, nil }
+	t.Cleanup(func() { systemKeyring = orig })
+	return kr
+}
+
+func TestStoreCAKeyPassphrase(t *testing.T) {
+	certPEM, keyPEM := mustGenerateCA(t)
+	path := filepath.Join(t.TempDir(), "ca.key")
+
+	if err := StoreCAKey(path, keyPEM, StoragePassphrase, []byte("hunter2")); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := os.ReadFile(path)
+	if bytes.Contains(raw, []byte("EC PRIVATE KEY")) || !strings.Contains(string(raw), encryptedKeyType) {
+		t.Fatalf("key file not encrypted:\n%s", raw)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+	if s, _ := KeyStorageOf(path); s != StoragePassphrase {
+		t.Errorf("KeyStorageOf = %q", s)
+	}
+
+	if _, err := LoadCAKey(path, nil); !errors.Is(err, ErrPassphraseRequired) {
+		t.Errorf("no passphrase: got %v", err)
+	}
+	if _, err := LoadCAKey(path, []byte("wrong")); err == nil {
+		t.Error("wrong passphrase accepted")
+	}
+	got, err := LoadCAKey(path, []byte("hunter2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewCertCache(certPEM, got); err != nil {
+		t.Errorf("decrypted key unusable: %v", err)
+	}
+}
+
+func TestStoreCAKeyKeyring(t *testing.T) {
+	kr := useMemKeyring(t)
+	_, keyPEM := mustGenerateCA(t)
+	path := filepath.Join(t.TempDir(), "ca.key")
+
+	if err := StoreCAKey(path, keyPEM, StorageKeyring, nil); err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := os.ReadFile(path)
+	if bytes.Contains(raw, keyPEM) || len(kr) != 1 {
+		t.Fatalf("key not moved to keyring: file=%s entries=%d", raw, len(kr))
+	}
+	got, err := LoadCAKey(path, nil)
+	if err != nil || !bytes.Equal(got, keyPEM) {
+		t.Fatalf("LoadCAKey = %q, %v", got, err)
+	}
+
+	// Moving back to a plain file removes the keyring entry.
+	if err := StoreCAKey(path, keyPEM, StorageFile, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(kr) != 0 {
+		t.Errorf("keyring entry left behind: %v", kr)
+	}
+	if got, _ := LoadCAKey(path, nil); !bytes.Equal(got, keyPEM) {
+		t.Errorf("plain key = %q", got)
+	}
+}
+
+func TestLoadCAKeyPlainFile(t *testing.T) {
+	_, keyPEM := mustGenerateCA(t)
+	path := filepath.Join(t.TempDir(), "ca.key")
+	os.WriteFile(path, keyPEM, 0600)
+
+	got, err := LoadCAKey(path, []byte("ignored"))
+	if err != nil || !bytes.Equal(got, keyPEM) {
+		t.Errorf("LoadCAKey = %q, %v", got, err)
+	}
+	if s, _ := KeyStorageOf(path); s != StorageFile {
+		t.Errorf("KeyStorageOf = %q", s)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
@@ -13,10 +14,11 @@ import (
 	"fmt"
 	"math/big"
 	"net"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/peter-wagstaff/claude-hybrid-router/internal/config"
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/defaults"
 )
 
 // CertCache generates and caches per-domain TLS certificates signed by a MITM CA.
@@ -26,26 +28,74 @@ type CertCache struct {
 	maxSize  int
 	validity time.Duration
 
-	mu    sync.Mutex
-	cache map[string]*list.Element
-	order *list.List // LRU: front = most recently used
+	mu       sync.Mutex
+	cache    map[string]*list.Element
+	order    *list.List              // LRU: front = most recently used
+	inflight map[string]*pendingCert // hosts being minted; concurrent callers wait on these
+	minted   int                     // certificates generated (cache misses)
+
+	hits      int64
+	evictions int64 // entries dropped to stay within maxSize
+	expired   int64 // entries dropped because the certificate aged out
+	bytes     int64 // approximate memory held by cached certificates
+}
+
+// Option configures a CertCache.
+type Option func(*CertCache)
+
+// WithMaxEntries caps the number of cached certificates (LRU eviction).
+// Values <= 0 keep the default.
+func WithMaxEntries(n int) Option {
+	return func(c *CertCache) {
+		if n > 0 {
+			c.maxSize = n
+		}
+	}
+}
+
+// Stats is a snapshot of cache occupancy and counters.
+type Stats struct {
+	Entries     int   `json:"entries"`
+	MaxEntries  int   `json:"max_entries"`
+	ApproxBytes int64 `json:"approx_bytes"`
+	Hits        int64 `json:"hits"`
+	Misses      int64 `json:"misses"` // certificates minted
+	Evictions   int64 `json:"evictions"`
+	Expired     int64 `json:"expired"`
+}
+
+// pendingCert is an in-progress certificate generation shared by every
+// concurrent request for the same host.
+type pendingCert struct {
+	done chan struct{}
+	cert tls.Certificate
+	err  error
 }
 
 type cacheEntry struct {
 	hostname string
 	cert     tls.Certificate
 	created  time.Time
+	size     int64
 }
 
-// NewCertCache creates a CertCache from PEM-encoded CA certificate and key.
-func NewCertCache(caCertPEM, caKeyPEM []byte) (*CertCache, error) {
-	certBlock, _ := pem.Decode(caCertPEM)
-	if certBlock == nil {
-		return nil, fmt.Errorf("failed to decode CA certificate PEM")
+// certOverhead approximates per-entry memory beyond the DER bytes: the
+// parsed leaf, P-256 key, list element, and map slot.
+const certOverhead = 2048
+
+func entrySize(cert tls.Certificate) int64 {
+	n := int64(certOverhead)
+	for _, der := range cert.Certificate {
+		n += int64(len(der))
 	}
-	caCert, err := x509.ParseCertificate(certBlock.Bytes)
+	return n
+}
+
+// NewCertCache creates a CertCache from PEM-encoded CA certificate and key.
+func NewCertCache(caCertPEM, caKeyPEM []byte, opts ...Option) (*CertCache, error) {
+	caCert, err := ParseCACert(caCertPEM)
 	if err != nil {
-		return nil, fmt.Errorf("parse CA certificate: %w", err)
+		return nil, err
 	}
 
 	keyBlock, _ := pem.Decode(caKeyPEM)
@@ -66,59 +116,102 @@ func NewCertCache(caCertPEM, caKeyPEM []byte) (*CertCache, error) {
 		}
 	}
 
-	return &CertCache{
+	c := &CertCache{
 		caCert:   caCert,
 		caKey:    rawKey,
-		maxSize:  config.MitmCacheMaxSize,
-		validity: time.Duration(config.MitmCertValidityHours * float64(time.Hour)),
+		maxSize:  defaults.MitmCacheMaxSize,
+		validity: time.Duration(defaults.MitmCertValidityHours * float64(time.Hour)),
 		cache:    make(map[string]*list.Element),
 		order:    list.New(),
-	}, nil
+		inflight: make(map[string]*pendingCert),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
 }
 
 // GetTLSConfig returns a *tls.Config with a certificate for the given hostname.
-// Results are cached with LRU eviction.
+// Results are cached with LRU eviction. Concurrent calls for the same uncached
+// host share a single generation. Hosts one label below a registrable domain
+// (api.example.com, www.example.com) share a wildcard certificate.
 func (c *CertCache) GetTLSConfig(hostname string) (*tls.Config, error) {
+	cert, err := c.getCert(certName(hostname))
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS13,
+		NextProtos:   []string{"http/1.1"},
+	}, nil
+}
+
+func (c *CertCache) getCert(hostname string) (tls.Certificate, error) {
 	c.mu.Lock()
 	if el, ok := c.cache[hostname]; ok {
 		entry := el.Value.(*cacheEntry)
 		if time.Since(entry.created) < c.validity {
 			c.order.MoveToFront(el)
-			cert := entry.cert
+			c.hits++
 			c.mu.Unlock()
-			return &tls.Config{
-				Certificates: []tls.Certificate{cert},
-				MinVersion:   tls.VersionTLS13,
-				NextProtos:   []string{"http/1.1"},
-			}, nil
+			return entry.cert, nil
 		}
-		// Expired
-		c.order.Remove(el)
-		delete(c.cache, hostname)
+		c.remove(el)
+		c.expired++
 	}
+	if p, ok := c.inflight[hostname]; ok {
+		c.mu.Unlock()
+		<-p.done
+		return p.cert, p.err
+	}
+	p := &pendingCert{done: make(chan struct{})}
+	c.inflight[hostname] = p
+	c.minted++
 	c.mu.Unlock()
 
-	cert, err := c.generateCert(hostname)
-	if err != nil {
-		return nil, err
-	}
+	// Generate outside the lock so other hosts aren't serialized behind
+	// the (comparatively slow) key generation and signing.
+	p.cert, p.err = c.generateCert(hostname)
 
 	c.mu.Lock()
-	entry := &cacheEntry{hostname: hostname, cert: cert, created: time.Now()}
-	el := c.order.PushFront(entry)
-	c.cache[hostname] = el
-	for c.order.Len() > c.maxSize {
-		oldest := c.order.Back()
-		c.order.Remove(oldest)
-		delete(c.cache, oldest.Value.(*cacheEntry).hostname)
+	delete(c.inflight, hostname)
+	if p.err == nil {
+		entry := &cacheEntry{hostname: hostname, cert: p.cert, created: time.Now(), size: entrySize(p.cert)}
+		c.cache[hostname] = c.order.PushFront(entry)
+		c.bytes += entry.size
+		for c.order.Len() > c.maxSize {
+			c.remove(c.order.Back())
+			c.evictions++
+		}
 	}
 	c.mu.Unlock()
+	close(p.done)
 
-	return &tls.Config{
-		Certificates: []tls.Certificate{cert},
-		MinVersion:   tls.VersionTLS13,
-		NextProtos:   []string{"http/1.1"},
-	}, nil
+	return p.cert, p.err
+}
+
+// remove drops el from the cache. Caller holds c.mu.
+func (c *CertCache) remove(el *list.Element) {
+	entry := el.Value.(*cacheEntry)
+	c.order.Remove(el)
+	delete(c.cache, entry.hostname)
+	c.bytes -= entry.size
+}
+
+// Stats returns current occupancy and counters.
+func (c *CertCache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Stats{
+		Entries:     c.order.Len(),
+		MaxEntries:  c.maxSize,
+		ApproxBytes: c.bytes,
+		Hits:        c.hits,
+		Misses:      int64(c.minted),
+		Evictions:   c.evictions,
+		Expired:     c.expired,
+	}
 }
 
 func (c *CertCache) generateCert(hostname string) (tls.Certificate, error) {
@@ -176,7 +269,7 @@ func GenerateCA() (certPEM, keyPEM []byte, err error) {
 		SerialNumber:          serial,
 		Subject:               pkix.Name{CommonName: "claude-hybrid MITM CA"},
 		NotBefore:             time.Now(),
-		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		NotAfter:              time.Now().Add(defaults.CAValidity),
 		IsCA:                  true,
 		BasicConstraintsValid: true,
 		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
@@ -197,3 +290,85 @@ func GenerateCA() (certPEM, keyPEM []byte, err error) {
 
 	return certPEM, keyPEM, nil
 }
+
+// certName returns the name a leaf certificate is minted and cached under:
+// the IP for an IP target (without any IPv6 zone), "*.<domain>" for a host
+// exactly one label below a registrable domain, and the lower-cased host
+// otherwise. Wildcards match a single label, so deeper names and the
+// registrable domain itself get their own certificate.
+func certName(hostname string) string {
+	host := strings.ToLower(strings.TrimSuffix(hostname, "."))
+	if i := strings.IndexByte(host, '%'); i >= 0 {
+		if ip := net.ParseIP(host[:i]); ip != nil {
+			return ip.String()
+		}
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String()
+	}
+	base := registrableDomain(host)
+	if base == "" || host == base {
+		return host
+	}
+	if parent := host[strings.IndexByte(host, '.')+1:]; parent == base {
+		return "*." + base
+	}
+	return host
+}
+
+// secondLevelLabels are common second-level registries under two-letter
+// country TLDs (co.uk, com.au, ne.jp, ...).
+var secondLevelLabels = map[string]bool{
+	"ac": true, "co": true, "com": true, "edu": true, "gov": true,
+	"net": true, "org": true, "or": true, "ne": true, "go": true, "gob": true,
+}
+
+// registrableDomain approximates the eTLD+1 of host without a public suffix
+// list: the last two labels, or three under a known country second-level
+// registry. It returns "" for single-label hosts and when host is itself a
+// public suffix.
+func registrableDomain(host string) string {
+	labels := strings.Split(host, ".")
+	n := len(labels)
+	if n < 2 {
+		return ""
+	}
+	want := 2
+	if len(labels[n-1]) == 2 && secondLevelLabels[labels[n-2]] {
+		want = 3
+	}
+	if n < want {
+		return ""
+	}
+	return strings.Join(labels[n-want:], ".")
+}
+
+// ParseCACert decodes the first certificate in certPEM.
+func ParseCACert(certPEM []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode CA certificate PEM")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse CA certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// NeedsRenewal reports whether cert has expired, or will within
+// defaults.CARenewBefore of now.
+func NeedsRenewal(cert *x509.Certificate, now time.Time) bool {
+	return now.Add(defaults.CARenewBefore).After(cert.NotAfter)
+}
+
+// Fingerprint returns the SHA-256 fingerprint of cert as colon-separated hex,
+// the form browsers and `openssl x509 -fingerprint` print.
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
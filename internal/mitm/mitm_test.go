@@ -4,8 +4,14 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
+	"fmt"
 	"net"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/defaults"
 )
 
 func mustGenerateCA(t *testing.T) ([]byte, []byte) {
@@ -35,6 +41,32 @@ func TestGenerateCA(t *testing.T) {
 	}
 }
 
+func TestParseCACertAndRenewal(t *testing.T) {
+	certPEM, _ := mustGenerateCA(t)
+	cert, err := ParseCACert(certPEM)
+	if err != nil {
+		t.Fatalf("ParseCACert: %v", err)
+	}
+	if fp := Fingerprint(cert); len(fp) != 32*3-1 || strings.ToUpper(fp) != fp {
+		t.Errorf("unexpected fingerprint format %q", fp)
+	}
+
+	now := time.Now()
+	if NeedsRenewal(cert, now) {
+		t.Error("fresh CA should not need renewal")
+	}
+	if !NeedsRenewal(cert, cert.NotAfter.Add(-defaults.CARenewBefore/2)) {
+		t.Error("CA inside the renewal window should need renewal")
+	}
+	if !NeedsRenewal(cert, cert.NotAfter.Add(time.Hour)) {
+		t.Error("expired CA should need renewal")
+	}
+
+	if _, err := ParseCACert([]byte("not pem")); err == nil {
+		t.Error("expected error for invalid PEM")
+	}
+}
+
 func TestCertCacheDNS(t *testing.T) {
 	certPEM, keyPEM := mustGenerateCA(t)
 	cache, err := NewCertCache(certPEM, keyPEM)
@@ -89,6 +121,62 @@ func TestCertCacheIP(t *testing.T) {
 	}
 }
 
+func TestCertName(t *testing.T) {
+	cases := map[string]string{
+		"api.anthropic.com":   "*.anthropic.com",
+		"API.Anthropic.com.":  "*.anthropic.com",
+		"anthropic.com":       "anthropic.com",
+		"a.b.example.com":     "a.b.example.com",
+		"bbc.co.uk":           "bbc.co.uk",
+		"www.bbc.co.uk":       "*.bbc.co.uk",
+		"co.uk":               "co.uk",
+		"localhost":           "localhost",
+		"127.0.0.1":           "127.0.0.1",
+		"::1":                 "::1",
+		"fe80::1%eth0":        "fe80::1",
+		"0:0:0:0:0:0:0:1":     "::1",
+		"statsig.example.com": "*.example.com",
+	}
+	for host, want := range cases {
+		if got := certName(host); got != want {
+			t.Errorf("certName(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestCertCacheWildcardReuse(t *testing.T) {
+	certPEM, keyPEM := mustGenerateCA(t)
+	cache, err := NewCertCache(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("NewCertCache: %v", err)
+	}
+
+	var serials []string
+	for _, host := range []string{"api.example.com", "www.example.com", "Api.Example.com"} {
+		cfg, err := cache.GetTLSConfig(host)
+		if err != nil {
+			t.Fatalf("GetTLSConfig(%s): %v", host, err)
+		}
+		leaf, _ := x509.ParseCertificate(cfg.Certificates[0].Certificate[0])
+		if err := leaf.VerifyHostname(host); err != nil {
+			t.Errorf("certificate does not cover %s: %v", host, err)
+		}
+		serials = append(serials, leaf.SerialNumber.String())
+	}
+	if serials[0] != serials[1] || serials[1] != serials[2] {
+		t.Errorf("expected one shared certificate, got serials %v", serials)
+	}
+	if st := cache.Stats(); st.Misses != 1 || st.Entries != 1 {
+		t.Errorf("expected one minted entry, got %+v", st)
+	}
+
+	cfg, _ := cache.GetTLSConfig("deep.api.example.com")
+	leaf, _ := x509.ParseCertificate(cfg.Certificates[0].Certificate[0])
+	if err := leaf.VerifyHostname("deep.api.example.com"); err != nil {
+		t.Errorf("deeper name not covered: %v", err)
+	}
+}
+
 func TestCertCacheReuse(t *testing.T) {
 	certPEM, keyPEM := mustGenerateCA(t)
 	cache, err := NewCertCache(certPEM, keyPEM)
@@ -148,3 +236,137 @@ func TestCertCacheTLSVersion(t *testing.T) {
 		t.Errorf("unexpected ALPN: %v", cfg.NextProtos)
 	}
 }
+
+func TestCertCacheConcurrentSameHost(t *testing.T) {
+	certPEM, keyPEM := mustGenerateCA(t)
+	cache, err := NewCertCache(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("NewCertCache: %v", err)
+	}
+
+	const n = 200
+	serials := make([]string, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cfg, err := cache.GetTLSConfig("api.anthropic.com")
+			if err != nil {
+				t.Errorf("GetTLSConfig: %v", err)
+				return
+			}
+			leaf, _ := x509.ParseCertificate(cfg.Certificates[0].Certificate[0])
+			serials[i] = leaf.SerialNumber.String()
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 1; i < n; i++ {
+		if serials[i] != serials[0] {
+			t.Fatalf("caller %d got a different certificate", i)
+		}
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.minted != 1 {
+		t.Errorf("expected 1 certificate minted, got %d", cache.minted)
+	}
+	if cache.order.Len() != 1 || len(cache.cache) != 1 {
+		t.Errorf("cache holds %d list entries / %d map entries, want 1/1", cache.order.Len(), len(cache.cache))
+	}
+}
+
+func TestCertCacheConcurrentDistinctHosts(t *testing.T) {
+	certPEM, keyPEM := mustGenerateCA(t)
+	cache, err := NewCertCache(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("NewCertCache: %v", err)
+	}
+	cache.maxSize = 64
+
+	// Bursts of CONNECTs to many hosts, each host requested several times,
+	// with more hosts than the cache holds so eviction runs concurrently.
+	const hosts, perHost = 300, 3
+	var wg sync.WaitGroup
+	for h := 0; h < hosts; h++ {
+		for r := 0; r < perHost; r++ {
+			wg.Add(1)
+			go func(h int) {
+				defer wg.Done()
+				host := fmt.Sprintf("host%d.example.com", h)
+				cfg, err := cache.GetTLSConfig(host)
+				if err != nil {
+					t.Errorf("GetTLSConfig(%s): %v", host, err)
+					return
+				}
+				leaf, _ := x509.ParseCertificate(cfg.Certificates[0].Certificate[0])
+				if err := leaf.VerifyHostname(host); err != nil {
+					t.Errorf("wrong certificate for %s: %v", host, err)
+				}
+			}(h)
+		}
+	}
+	wg.Wait()
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.order.Len() != len(cache.cache) {
+		t.Errorf("LRU list (%d) and map (%d) out of sync", cache.order.Len(), len(cache.cache))
+	}
+	if len(cache.cache) > cache.maxSize {
+		t.Errorf("cache grew past max size: %d", len(cache.cache))
+	}
+	if len(cache.inflight) != 0 {
+		t.Errorf("%d generations left in flight", len(cache.inflight))
+	}
+	for host, el := range cache.cache {
+		if el.Value.(*cacheEntry).hostname != host {
+			t.Errorf("map entry %s points at %s", host, el.Value.(*cacheEntry).hostname)
+		}
+	}
+}
+
+func TestCertCacheStats(t *testing.T) {
+	certPEM, keyPEM := mustGenerateCA(t)
+	cache, err := NewCertCache(certPEM, keyPEM, WithMaxEntries(2))
+	if err != nil {
+		t.Fatalf("NewCertCache: %v", err)
+	}
+
+	cache.GetTLSConfig("a.com")
+	cache.GetTLSConfig("a.com")
+	cache.GetTLSConfig("b.com")
+	cache.GetTLSConfig("c.com") // evicts a.com
+
+	s := cache.Stats()
+	if s.Entries != 2 || s.MaxEntries != 2 {
+		t.Errorf("entries %d/%d, want 2/2", s.Entries, s.MaxEntries)
+	}
+	if s.Hits != 1 || s.Misses != 3 || s.Evictions != 1 {
+		t.Errorf("hits=%d misses=%d evictions=%d, want 1/3/1", s.Hits, s.Misses, s.Evictions)
+	}
+	if s.ApproxBytes <= 2*certOverhead {
+		t.Errorf("approx bytes %d should cover two entries", s.ApproxBytes)
+	}
+
+	// Expire everything: the next lookup drops the stale entry and re-mints.
+	cache.mu.Lock()
+	cache.validity = 0
+	cache.mu.Unlock()
+	cache.GetTLSConfig("b.com")
+	if s := cache.Stats(); s.Expired != 1 || s.Entries != 2 {
+		t.Errorf("expired=%d entries=%d, want 1/2", s.Expired, s.Entries)
+	}
+}
+
+func TestCertCacheMaxEntriesOptionIgnoresNonPositive(t *testing.T) {
+	certPEM, keyPEM := mustGenerateCA(t)
+	cache, err := NewCertCache(certPEM, keyPEM, WithMaxEntries(0))
+	if err != nil {
+		t.Fatalf("NewCertCache: %v", err)
+	}
+	if cache.Stats().MaxEntries <= 0 {
+		t.Error("non-positive max entries should keep the default")
+	}
+}
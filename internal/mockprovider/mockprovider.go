@@ -0,0 +1,419 @@
+// Package mockprovider is a configurable OpenAI-compatible chat completions
+// server, for developing transforms and routing without a real model.
+package mockprovider
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Behaviors select how the server answers requests no script step matches.
+const (
+	BehaviorEcho      = "echo"      // a canned text reply naming the model; a Read call when tools are offered
+	BehaviorTools     = "tools"     // calls the first offered tool with arguments that fit its schema
+	BehaviorReasoning = "reasoning" // reasoning_content before the reply, as DeepSeek and vLLM send it
+	BehaviorSlow      = "slow"      // echo, waiting Delay before the reply and between stream chunks
+	BehaviorFlaky     = "flaky"     // echo, but every FailEvery-th request fails
+)
+
+// Behaviors lists the valid Options.Behavior values.
+var Behaviors = []string{BehaviorEcho, BehaviorTools, BehaviorReasoning, BehaviorSlow, BehaviorFlaky}
+
+const (
+	defaultDelay     = 200 * time.Millisecond
+	defaultFailEvery = 3
+)
+
+// Options configures a Server.
+type Options struct {
+	Behavior  string        // default BehaviorEcho
+	Delay     time.Duration // slow: default 200ms
+	FailEvery int           // flaky: default 3
+	Script    []Step        // tried before Behavior
+}
+
+// Step is one scripted reply. A step with Match answers requests whose last
+// message contains it; steps without Match take turns answering the rest.
+type Step struct {
+	Match        string        `yaml:"match,omitempty" json:"match,omitempty"`
+	Status       int           `yaml:"status,omitempty" json:"status,omitempty"` // >= 400 answers with an error
+	Error        string        `yaml:"error,omitempty" json:"error,omitempty"`
+	Delay        time.Duration `yaml:"delay,omitempty" json:"delay,omitempty"` // before the reply and between stream chunks
+	Reasoning    string        `yaml:"reasoning,omitempty" json:"reasoning,omitempty"`
+	Text         string        `yaml:"text,omitempty" json:"text,omitempty"`
+	ToolCalls    []ToolCall    `yaml:"tool_calls,omitempty" json:"tool_calls,omitempty"`
+	FinishReason string        `yaml:"finish_reason,omitempty" json:"finish_reason,omitempty"`
+	Disconnect   bool          `yaml:"disconnect,omitempty" json:"disconnect,omitempty"` // drop a stream before it finishes
+}
+
+// ToolCall is a scripted function call. Arguments is sent as is, so it may
+// be malformed on purpose.
+type ToolCall struct {
+	Name      string `yaml:"name" json:"name"`
+	Arguments string `yaml:"arguments" json:"arguments"`
+}
+
+// LoadScript reads a YAML (or JSON) list of steps.
+func LoadScript(path string) ([]Step, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var steps []Step
+	if err := yaml.Unmarshal(data, &steps); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, s := range steps {
+		if s.Status != 0 && (s.Status < 200 || s.Status > 599) {
+			return nil, fmt.Errorf("%s: step %d: status %d is not an HTTP status", path, i+1, s.Status)
+		}
+	}
+	return steps, nil
+}
+
+// Server answers /v1/chat/completions and /v1/models.
+type Server struct {
+	opts Options
+	mux  *http.ServeMux
+
+	mu       sync.Mutex
+	requests int // answered so far, for flaky
+	next     int // the next unmatched step
+}
+
+// New returns a Server, or an error for an unknown behavior.
+func New(opts Options) (*Server, error) {
+	if opts.Behavior == "" {
+		opts.Behavior = BehaviorEcho
+	}
+	known := false
+	for _, b := range Behaviors {
+		known = known || b == opts.Behavior
+	}
+	if !known {
+		return nil, fmt.Errorf("unknown behavior %q (%s)", opts.Behavior, strings.Join(Behaviors, ", "))
+	}
+	if opts.Delay <= 0 {
+		opts.Delay = defaultDelay
+	}
+	if opts.FailEvery <= 0 {
+		opts.FailEvery = defaultFailEvery
+	}
+	s := &Server{opts: opts, mux: http.NewServeMux()}
+	s.mux.HandleFunc("/v1/chat/completions", s.handleChatCompletions)
+	s.mux.HandleFunc("/v1/models", s.handleModels)
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// chatRequest is the part of a chat completions request the mock reads.
+type chatRequest struct {
+	Model    string            `json:"model"`
+	Messages []json.RawMessage `json:"messages"`
+	Stream   bool              `json:"stream"`
+	Tools    []struct {
+		Function struct {
+			Name       string `json:"name"`
+			Parameters struct {
+				Properties map[string]struct {
+					Type string `json:"type"`
+				} `json:"properties"`
+				Required []string `json:"required"`
+			} `json:"parameters"`
+		} `json:"function"`
+	} `json:"tools"`
+	StreamOptions struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+}
+
+// lastRole returns the role of the request's last message.
+func (r *chatRequest) lastRole() string {
+	if len(r.Messages) == 0 {
+		return ""
+	}
+	var m struct {
+		Role string `json:"role"`
+	}
+	json.Unmarshal(r.Messages[len(r.Messages)-1], &m)
+	return m.Role
+}
+
+func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var req chatRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	step, scripted := s.pick(&req)
+	if !scripted {
+		step = s.behave(&req)
+	}
+	if step.Status >= 400 {
+		if step.Status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "1")
+		}
+		msg := step.Error
+		if msg == "" {
+			msg = http.StatusText(step.Status)
+		}
+		writeError(w, step.Status, msg)
+		return
+	}
+	if step.Reasoning == "" && step.Text == "" && len(step.ToolCalls) == 0 {
+		step.Text = echoText(req.Model, req.Stream)
+	}
+	if step.FinishReason == "" {
+		step.FinishReason = "stop"
+		if len(step.ToolCalls) > 0 {
+			step.FinishReason = "tool_calls"
+		}
+	}
+	if !sleep(r, step.Delay) {
+		return
+	}
+	if req.Stream {
+		stream(w, r, &req, step)
+		return
+	}
+	reply(w, &req, step)
+}
+
+// pick returns the script step for req, if there is one.
+func (s *Server) pick(req *chatRequest) (Step, bool) {
+	if len(s.opts.Script) == 0 {
+		return Step{}, false
+	}
+	var last string
+	if len(req.Messages) > 0 {
+		last = string(req.Messages[len(req.Messages)-1])
+	}
+	var unmatched []Step
+	for _, st := range s.opts.Script {
+		if st.Match == "" {
+			unmatched = append(unmatched, st)
+		} else if strings.Contains(last, st.Match) {
+			return st, true
+		}
+	}
+	if len(unmatched) == 0 {
+		return Step{}, false
+	}
+	s.mu.Lock()
+	st := unmatched[s.next%len(unmatched)]
+	s.next++
+	s.mu.Unlock()
+	return st, true
+}
+
+// behave returns the reply the configured behavior gives req.
+func (s *Server) behave(req *chatRequest) Step {
+	s.mu.Lock()
+	s.requests++
+	n := s.requests
+	s.mu.Unlock()
+
+	switch s.opts.Behavior {
+	case BehaviorTools:
+		if len(req.Tools) == 0 || req.lastRole() == "tool" {
+			return Step{Text: "Done. The tool result is above."}
+		}
+		f := req.Tools[0].Function
+		args := map[string]interface{}{}
+		for _, name := range f.Parameters.Required {
+			switch f.Parameters.Properties[name].Type {
+			case "integer", "number":
+				args[name] = 1
+			case "boolean":
+				args[name] = true
+			case "array":
+				args[name] = []string{}
+			case "object":
+				args[name] = map[string]string{}
+			default:
+				args[name] = "mock"
+			}
+		}
+		data, _ := json.Marshal(args)
+		return Step{ToolCalls: []ToolCall{{Name: f.Name, Arguments: string(data)}}}
+	case BehaviorReasoning:
+		return Step{
+			Reasoning: "The user sent a message. A short, direct reply is best here.",
+			Text:      echoText(req.Model, req.Stream),
+		}
+	case BehaviorSlow:
+		return Step{Delay: s.opts.Delay}
+	case BehaviorFlaky:
+		if n%s.opts.FailEvery == 0 {
+			// Cycle through the failures a real provider produces.
+			switch (n / s.opts.FailEvery) % 3 {
+			case 1:
+				return Step{Status: http.StatusInternalServerError, Error: "mock provider failure"}
+			case 2:
+				return Step{Status: http.StatusTooManyRequests, Error: "mock rate limit"}
+			default:
+				if req.Stream {
+					return Step{Disconnect: true}
+				}
+				return Step{Status: http.StatusBadGateway, Error: "mock upstream disconnected"}
+			}
+		}
+	}
+	if len(req.Tools) > 0 {
+		return Step{ToolCalls: []ToolCall{{Name: "Read", Arguments: `{"file_path":"/tmp/test.txt"}`}}}
+	}
+	return Step{}
+}
+
+func echoText(model string, stream bool) string {
+	if stream {
+		return fmt.Sprintf("Mock streaming response from %s", model)
+	}
+	return fmt.Sprintf("Mock response from %s", model)
+}
+
+// sleep waits d, or until the client goes away, which it reports as false.
+func sleep(r *http.Request, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	select {
+	case <-time.After(d):
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+var mockUsage = map[string]int{"prompt_tokens": 100, "completion_tokens": 20, "total_tokens": 120}
+
+func reply(w http.ResponseWriter, req *chatRequest, st Step) {
+	msg := map[string]interface{}{"role": "assistant", "content": st.Text}
+	if st.Reasoning != "" {
+		msg["reasoning_content"] = st.Reasoning
+	}
+	if len(st.ToolCalls) > 0 {
+		var calls []map[string]interface{}
+		for i, c := range st.ToolCalls {
+			calls = append(calls, map[string]interface{}{
+				"id":       fmt.Sprintf("call_mock_%d", i+1),
+				"type":     "function",
+				"function": map[string]string{"name": c.Name, "arguments": c.Arguments},
+			})
+		}
+		msg["tool_calls"] = calls
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      "chatcmpl-mock",
+		"object":  "chat.completion",
+		"model":   req.Model,
+		"choices": []map[string]interface{}{{"index": 0, "message": msg, "finish_reason": st.FinishReason}},
+		"usage":   mockUsage,
+	})
+}
+
+// stream sends st as chat.completion.chunk events: reasoning and text a
+// word at a time, then each tool call's name and its arguments in two
+// pieces, then the finish reason and, when asked for, usage.
+func stream(w http.ResponseWriter, r *http.Request, req *chatRequest, st Step) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+	first := true
+	send := func(delta map[string]interface{}, finish interface{}) bool {
+		if !first && !sleep(r, st.Delay) {
+			return false
+		}
+		if first {
+			delta["role"] = "assistant"
+			first = false
+		}
+		fmt.Fprintf(w, "data: %s\n\n", mustJSON(map[string]interface{}{
+			"id":      "chatcmpl-mock",
+			"object":  "chat.completion.chunk",
+			"model":   req.Model,
+			"choices": []map[string]interface{}{{"index": 0, "delta": delta, "finish_reason": finish}},
+		}))
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return true
+	}
+
+	for _, word := range strings.SplitAfter(st.Reasoning, " ") {
+		if word != "" && !send(map[string]interface{}{"reasoning_content": word}, nil) {
+			return
+		}
+	}
+	for _, word := range strings.SplitAfter(st.Text, " ") {
+		if word != "" && !send(map[string]interface{}{"content": word}, nil) {
+			return
+		}
+	}
+	if st.Disconnect {
+		// Abort the response so the client sees a truncated stream.
+		panic(http.ErrAbortHandler)
+	}
+	for i, c := range st.ToolCalls {
+		head := map[string]interface{}{"index": i, "id": fmt.Sprintf("call_mock_%d", i+1), "type": "function",
+			"function": map[string]string{"name": c.Name, "arguments": ""}}
+		if !send(map[string]interface{}{"tool_calls": []interface{}{head}}, nil) {
+			return
+		}
+		half := len(c.Arguments) / 2
+		for _, part := range []string{c.Arguments[:half], c.Arguments[half:]} {
+			frag := map[string]interface{}{"index": i, "function": map[string]string{"arguments": part}}
+			if !send(map[string]interface{}{"tool_calls": []interface{}{frag}}, nil) {
+				return
+			}
+		}
+	}
+	if !send(map[string]interface{}{}, st.FinishReason) {
+		return
+	}
+	if req.StreamOptions.IncludeUsage {
+		fmt.Fprintf(w, "data: %s\n\n", mustJSON(map[string]interface{}{
+			"id": "chatcmpl-mock", "object": "chat.completion.chunk", "model": req.Model,
+			"choices": []interface{}{}, "usage": mockUsage,
+		}))
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"object": "list",
+		"data":   []map[string]string{{"id": "mock-model", "object": "model", "owned_by": "mockprovider"}},
+	})
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{"message": msg, "type": "mock_error"},
+	})
+}
+
+func mustJSON(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
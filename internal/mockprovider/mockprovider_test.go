@@ -0,0 +1,169 @@
+package mockprovider
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func post(t *testing.T, s *Server, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(body)))
+	return rec
+}
+
+// message decodes a non-streaming reply's first choice.
+func message(t *testing.T, rec *httptest.ResponseRecorder) (msg map[string]interface{}, finish string) {
+	t.Helper()
+	var resp struct {
+		Choices []struct {
+			Message      map[string]interface{} `json:"message"`
+			FinishReason string                 `json:"finish_reason"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || len(resp.Choices) != 1 {
+		t.Fatalf("reply %d %q: %v", rec.Code, rec.Body, err)
+	}
+	return resp.Choices[0].Message, resp.Choices[0].FinishReason
+}
+
+const weatherTools = `"tools":[{"type":"function","function":{"name":"get_weather","parameters":{
+	"type":"object","properties":{"city":{"type":"string"},"days":{"type":"integer"}},"required":["city","days"]}}}]`
+
+func TestToolsBehavior(t *testing.T) {
+	s, _ := New(Options{Behavior: BehaviorTools})
+
+	msg, finish := message(t, post(t, s, `{"model":"m","messages":[{"role":"user","content":"hi"}],`+weatherTools+`}`))
+	calls, _ := msg["tool_calls"].([]interface{})
+	if finish != "tool_calls" || len(calls) != 1 {
+		t.Fatalf("finish %q, tool_calls %v", finish, msg["tool_calls"])
+	}
+	fn := calls[0].(map[string]interface{})["function"].(map[string]interface{})
+	if fn["name"] != "get_weather" || fn["arguments"] != `{"city":"mock","days":1}` {
+		t.Errorf("function = %v", fn)
+	}
+
+	// After a tool result the loop ends with text.
+	msg, finish = message(t, post(t, s, `{"model":"m","messages":[{"role":"tool","content":"17C"}],`+weatherTools+`}`))
+	if finish != "stop" || msg["content"] == "" || msg["tool_calls"] != nil {
+		t.Errorf("after tool result: %q %v", finish, msg)
+	}
+}
+
+func TestReasoningStream(t *testing.T) {
+	s, _ := New(Options{Behavior: BehaviorReasoning})
+	rec := post(t, s, `{"model":"m","stream":true,"stream_options":{"include_usage":true},"messages":[{"role":"user","content":"hi"}]}`)
+
+	var reasoning, content, finish string
+	var usage bool
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Reasoning string `json:"reasoning_content"`
+					Content   string `json:"content"`
+				} `json:"delta"`
+				FinishReason *string `json:"finish_reason"`
+			} `json:"choices"`
+			Usage json.RawMessage `json:"usage"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("chunk %q: %v", data, err)
+		}
+		usage = usage || chunk.Usage != nil
+		for _, c := range chunk.Choices {
+			if content != "" && c.Delta.Reasoning != "" {
+				t.Error("reasoning after content")
+			}
+			reasoning += c.Delta.Reasoning
+			content += c.Delta.Content
+			if c.FinishReason != nil {
+				finish = *c.FinishReason
+			}
+		}
+	}
+	if reasoning == "" || content != "Mock streaming response from m" || finish != "stop" || !usage {
+		t.Errorf("reasoning %q content %q finish %q usage %v", reasoning, content, finish, usage)
+	}
+	if !strings.HasSuffix(rec.Body.String(), "data: [DONE]\n\n") {
+		t.Error("stream does not end with [DONE]")
+	}
+}
+
+func TestFlakyBehavior(t *testing.T) {
+	s, _ := New(Options{Behavior: BehaviorFlaky, FailEvery: 2})
+	body := `{"model":"m","messages":[{"role":"user","content":"hi"}]}`
+	var codes []int
+	for i := 0; i < 6; i++ {
+		codes = append(codes, post(t, s, body).Code)
+	}
+	want := []int{200, 500, 200, 429, 200, 502}
+	for i := range want {
+		if codes[i] != want[i] {
+			t.Fatalf("codes = %v, want %v", codes, want)
+		}
+	}
+}
+
+func TestSlowBehavior(t *testing.T) {
+	s, _ := New(Options{Behavior: BehaviorSlow, Delay: 30 * time.Millisecond})
+	start := time.Now()
+	post(t, s, `{"model":"m","stream":true,"messages":[{"role":"user","content":"hi"}]}`)
+	// One wait before the reply and one between each of its chunks.
+	if d := time.Since(start); d < 150*time.Millisecond {
+		t.Errorf("slow stream took %v", d)
+	}
+}
+
+func TestScript(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.yaml")
+	os.WriteFile(path, []byte(`
+- match: "deploy"
+  status: 503
+  error: "overloaded"
+- text: "first"
+- tool_calls:
+    - name: Bash
+      arguments: '{"command": "ls",}'
+  delay: 5ms
+`), 0600)
+	steps, err := LoadScript(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if steps[2].Delay != 5*time.Millisecond {
+		t.Errorf("delay = %v", steps[2].Delay)
+	}
+	s, _ := New(Options{Script: steps})
+
+	rec := post(t, s, `{"model":"m","messages":[{"role":"user","content":"please deploy"}]}`)
+	if body, _ := io.ReadAll(rec.Body); rec.Code != http.StatusServiceUnavailable || !strings.Contains(string(body), "overloaded") {
+		t.Errorf("matched step = %d %s", rec.Code, body)
+	}
+	// Unmatched steps take turns.
+	for _, want := range []string{"first", "Bash", "first"} {
+		msg, _ := message(t, post(t, s, `{"model":"m","messages":[{"role":"user","content":"hi"}]}`))
+		if got, _ := json.Marshal(msg); !strings.Contains(string(got), want) {
+			t.Errorf("reply %s, want %q", got, want)
+		}
+	}
+
+	os.WriteFile(path, []byte("- status: 42\n"), 0600)
+	if _, err := LoadScript(path); err == nil {
+		t.Error("status 42 accepted")
+	}
+	if _, err := New(Options{Behavior: "chaotic"}); err == nil {
+		t.Error("unknown behavior accepted")
+	}
+}
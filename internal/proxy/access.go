@@ -0,0 +1,160 @@
+package proxy
+
+import (
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxBuckets bounds the per-client rate limit table; idle, refilled buckets
+// are pruned when it fills.
+const maxBuckets = 4096
+
+// accessControl decides which clients may use the proxy and how fast.
+// Loopback clients are always allowed, since the launched claude connects
+// over loopback.
+type accessControl struct {
+	allowed []netip.Prefix // empty = every client
+	rate    float64        // requests per second per client; 0 = unlimited
+	burst   float64
+
+	mu      sync.Mutex
+	buckets map[netip.Addr]*tokenBucket
+
+	denied  atomic.Int64
+	limited atomic.Int64
+}
+
+// tokenBucket is one client's rate limit state.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// AccessStats reports clients refused by the allowlist or rate limit.
+type AccessStats struct {
+	Denied      int64 `json:"denied"`       // CONNECTs and OpenAI listener requests from addresses outside allowed_clients
+	RateLimited int64 `json:"rate_limited"` // CONNECTs and requests refused with 429
+	Clients     int   `json:"clients"`      // clients with rate limit state
+}
+
+func (a *accessControl) snapshot() AccessStats {
+	a.mu.Lock()
+	n := len(a.buckets)
+	a.mu.Unlock()
+	return AccessStats{Denied: a.denied.Load(), RateLimited: a.limited.Load(), Clients: n}
+}
+
+// clientAddr returns the IP of a request's remote address.
+func clientAddr(remote string) netip.Addr {
+	ap, err := netip.ParseAddrPort(remote)
+	if err != nil {
+		host, _, _ := net.SplitHostPort(remote)
+		addr, _ := netip.ParseAddr(host)
+		return addr.Unmap()
+	}
+	return ap.Addr().Unmap()
+}
+
+// permits reports whether addr is on the allowlist.
+func (a *accessControl) permits(addr netip.Addr) bool {
+	if len(a.allowed) == 0 || addr.IsLoopback() {
+		return true
+	}
+	for _, pre := range a.allowed {
+		if pre.Contains(addr) {
+			return true
+		}
+	}
+	a.denied.Add(1)
+	return false
+}
+
+// take spends one of addr's tokens. When none is left it returns false and
+// the whole seconds until one is available.
+func (a *accessControl) take(addr netip.Addr) (ok bool, retryAfter string) {
+	if a.rate <= 0 {
+		return true, ""
+	}
+	now := time.Now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.buckets == nil {
+		a.buckets = make(map[netip.Addr]*tokenBucket)
+	}
+	b := a.buckets[addr]
+	if b == nil {
+		if len(a.buckets) >= maxBuckets {
+			a.prune(now)
+		}
+		b = &tokenBucket{tokens: a.burst, last: now}
+		a.buckets[addr] = b
+	}
+	b.tokens = math.Min(a.burst, b.tokens+now.Sub(b.last).Seconds()*a.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, ""
+	}
+	a.limited.Add(1)
+	wait := math.Ceil((1 - b.tokens) / a.rate)
+	return false, strconv.Itoa(int(max(wait, 1)))
+}
+
+// prune drops buckets that have refilled completely, which hold no state a
+// fresh bucket wouldn't. Called with mu held.
+func (a *accessControl) prune(now time.Time) {
+	for addr, b := range a.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*a.rate >= a.burst {
+			delete(a.buckets, addr)
+		}
+	}
+}
+
+// limitClients applies the allowlist and per-client rate limit to an HTTP
+// API served beside the proxy, the OpenAI-compatible listener, answering
+// refusals with OpenAI errors.
+func (p *Proxy) limitClients(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := clientAddr(r.RemoteAddr)
+		if !p.access.permits(client) {
+			log.Printf("[PROXY_DENIED] %s %s from %s refused: not in allowed_clients", r.Method, r.URL.Path, r.RemoteAddr)
+			sendOpenAIError(w, http.StatusForbidden, "permission_error", "client not allowed")
+			return
+		}
+		if ok, retryAfter := p.access.take(client); !ok {
+			p.logVerbose("[PROXY_RATE] %s %s from %s refused: rate limit", r.Method, r.URL.Path, r.RemoteAddr)
+			w.Header().Set("Retry-After", retryAfter)
+			sendOpenAIError(w, http.StatusTooManyRequests, "rate_limit_error", "claude-hybrid: per-client rate limit exceeded")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// WithAllowedClients restricts CONNECTs and the OpenAI-compatible listener
+// to client addresses in prefixes, plus loopback. Anything else is refused
+// with 403. An empty list allows every client.
+func WithAllowedClients(prefixes []netip.Prefix) Option {
+	return func(p *Proxy) { p.access.allowed = prefixes }
+}
+
+// WithClientRateLimit limits each client IP to perMinute CONNECTs plus
+// requests inside its tunnels and on the OpenAI-compatible listener, with
+// bursts of up to burst (perMinute when burst is 0). Refusals are 429 with
+// Retry-After. perMinute 0 disables the limit.
+func WithClientRateLimit(perMinute, burst int) Option {
+	return func(p *Proxy) {
+		if burst <= 0 {
+			burst = perMinute
+		}
+		p.access.rate = float64(perMinute) / 60
+		p.access.burst = float64(burst)
+	}
+}
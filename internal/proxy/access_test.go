@@ -0,0 +1,96 @@
+package proxy
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestAllowedClients(t *testing.T) {
+	p := New(nil, WithAllowedClients([]netip.Prefix{netip.MustParsePrefix("192.168.1.0/24")}))
+	for remote, want := range map[string]bool{
+		"192.168.1.20:5000": true,
+		"127.0.0.1:5000":    true,
+		"[::1]:5000":        true,
+		"10.0.0.7:5000":     false,
+		"[fe80::1]:5000":    false,
+	} {
+		req := httptest.NewRequest("CONNECT", "127.0.0.1:1", nil)
+		req.RemoteAddr = remote
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, req)
+		if got := rec.Code != 403; got != want {
+			t.Errorf("%s: allowed = %v (status %d), want %v", remote, got, rec.Code, want)
+		}
+	}
+	if m := p.Metrics(); m.Access.Denied != 2 {
+		t.Errorf("denied = %d, want 2", m.Access.Denied)
+	}
+}
+
+func TestClientRateLimitOnConnect(t *testing.T) {
+	p := New(nil, WithClientRateLimit(60, 2))
+	status := func(remote string) (int, string) {
+		req := httptest.NewRequest("CONNECT", "127.0.0.1:1", nil)
+		req.RemoteAddr = remote
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, req)
+		return rec.Code, rec.Header().Get("Retry-After")
+	}
+	for i := 0; i < 2; i++ {
+		if code, _ := status("10.0.0.1:1000"); code == 429 {
+			t.Fatalf("CONNECT %d within burst was limited", i+1)
+		}
+	}
+	code, retry := status("10.0.0.1:1001")
+	if code != 429 || retry != "1" {
+		t.Errorf("over burst: status %d Retry-After %q, want 429 and 1", code, retry)
+	}
+	if code, _ := status("10.0.0.2:1000"); code == 429 {
+		t.Error("rate limit should be per client")
+	}
+	if m := p.Metrics(); m.Access.RateLimited != 1 || m.Access.Clients != 2 {
+		t.Errorf("access metrics = %+v", m.Access)
+	}
+}
+
+func TestClientRateLimitInsideTunnel(t *testing.T) {
+	// The CONNECT and its request take two of three tokens; the second
+	// tunnel's CONNECT takes the last, so its request is refused.
+	infra := setupInfraWithOptions(t, nil, WithClientRateLimit(1, 3))
+	if status, body, _ := proxyRequest(t, infra, "GET", "/v1/models", nil, nil); status != 200 {
+		t.Fatalf("first request: %d %s", status, body)
+	}
+	status, body, _ := proxyRequest(t, infra, "GET", "/v1/models", nil, nil)
+	if status != 429 || !strings.Contains(body, "rate_limit_error") {
+		t.Errorf("second request: %d %s, want 429 rate_limit_error", status, body)
+	}
+}
+
+func TestOpenAIListenerAccess(t *testing.T) {
+	p := New(nil,
+		WithAllowedClients([]netip.Prefix{netip.MustParsePrefix("192.168.1.0/24")}),
+		WithClientRateLimit(60, 1))
+	h := p.OpenAIHandler()
+	get := func(remote string) (int, string, string) {
+		req := httptest.NewRequest("GET", "/v1/models", nil)
+		req.RemoteAddr = remote
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code, rec.Header().Get("Retry-After"), rec.Body.String()
+	}
+	if code, _, body := get("10.0.0.7:5000"); code != 403 || !strings.Contains(body, "permission_error") {
+		t.Errorf("client outside allowed_clients: %d %s, want 403 permission_error", code, body)
+	}
+	if code, _, body := get("192.168.1.20:5000"); code != 200 {
+		t.Errorf("allowed client: %d %s", code, body)
+	}
+	code, retry, body := get("192.168.1.20:5001")
+	if code != 429 || retry != "1" || !strings.Contains(body, "rate_limit_error") {
+		t.Errorf("over burst: %d Retry-After %q %s, want 429 rate_limit_error", code, retry, body)
+	}
+	if m := p.Metrics(); m.Access.Denied != 1 || m.Access.RateLimited != 1 {
+		t.Errorf("access metrics = %+v", m.Access)
+	}
+}
@@ -0,0 +1,352 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// recentRoutes is how many finished local routes Activity reports.
+const recentRoutes = 100
+
+// previewBytes is how much of an in-flight generation's text is kept for
+// live previews.
+const previewBytes = 2048
+
+// RouteEvent is one local-route request, finished or in flight.
+type RouteEvent struct {
+	Start        time.Time `json:"start"`
+	Label        string    `json:"label"`
+	Provider     string    `json:"provider,omitempty"`
+	Model        string    `json:"model,omitempty"`
+	Stream       bool      `json:"stream"`
+	Status       string    `json:"status"` // "ok", "dedupe", or the LOCAL_ERR category; empty while in flight
+	LatencyMs    int64     `json:"latency_ms"`
+	InputTokens  int       `json:"input_tokens,omitempty"`
+	OutputTokens int       `json:"output_tokens,omitempty"`
+	Preview      string    `json:"preview,omitempty"`               // tail of the text streamed so far; in-flight streams only
+	TTFTMs       int64     `json:"ttft_ms,omitempty"`               // streams: time to the first token
+	TokensPerSec float64   `json:"output_tokens_per_sec,omitempty"` // streams: output tokens over the time after the first token
+
+	id         uint64
+	conv       conversationRef // for the routing history
+	firstToken time.Time       // set by previewWriter
+}
+
+// SpeedStats aggregates successful local streams that produced a token, by
+// label, provider and model. The totals add up across sessions; Add keeps
+// the averages in step.
+type SpeedStats struct {
+	Label            string  `json:"label"`
+	Provider         string  `json:"provider"`
+	Model            string  `json:"model"`
+	Streams          int64   `json:"streams"`
+	TTFTMs           int64   `json:"ttft_ms_total"`
+	GenerationMs     int64   `json:"generation_ms_total"` // after the first token
+	GenerationTokens int64   `json:"generation_tokens"`
+	AvgTTFTMs        int64   `json:"avg_ttft_ms"`
+	TokensPerSec     float64 `json:"output_tokens_per_sec"`
+}
+
+// Add merges o's totals into s and recomputes the averages.
+func (s *SpeedStats) Add(o SpeedStats) {
+	s.Streams += o.Streams
+	s.TTFTMs += o.TTFTMs
+	s.GenerationMs += o.GenerationMs
+	s.GenerationTokens += o.GenerationTokens
+	if s.Streams > 0 {
+		s.AvgTTFTMs = s.TTFTMs / s.Streams
+	}
+	if s.GenerationMs > 0 {
+		s.TokensPerSec = float64(s.GenerationTokens) / (float64(s.GenerationMs) / 1000)
+	}
+}
+
+// streamSpeed measures a stream that ended at end: its time to first token
+// and output tokens per second after it. ok is false when no token arrived.
+func streamSpeed(ev *RouteEvent, end time.Time) (ttft, gen time.Duration, tps float64, ok bool) {
+	if ev.firstToken.IsZero() {
+		return 0, 0, 0, false
+	}
+	ttft, gen = ev.firstToken.Sub(ev.Start), end.Sub(ev.firstToken)
+	if gen > 0 {
+		tps = float64(ev.OutputTokens) / gen.Seconds()
+	}
+	return ttft, gen, tps, true
+}
+
+// LabelActivity aggregates finished routes for one label.
+type LabelActivity struct {
+	Label         string  `json:"label"`
+	Requests      int64   `json:"requests"`
+	Errors        int64   `json:"errors"`
+	AvgLatencyMs  int64   `json:"avg_latency_ms"`
+	LastLatencyMs int64   `json:"last_latency_ms"`
+	InputTokens   int64   `json:"input_tokens"`
+	OutputTokens  int64   `json:"output_tokens"`
+	TokensPerSec  float64 `json:"output_tokens_per_sec"` // over successful requests
+}
+
+// ProviderHealth summarizes a provider by its most recent routes.
+type ProviderHealth struct {
+	Provider          string    `json:"provider"`
+	LastStatus        string    `json:"last_status"`
+	LastSeen          time.Time `json:"last_seen"`
+	ConsecutiveErrors int       `json:"consecutive_errors"`
+}
+
+// Activity is a snapshot of local routing, served by the admin API.
+type Activity struct {
+	InFlight  []RouteEvent     `json:"in_flight"`
+	Recent    []RouteEvent     `json:"recent"` // newest first
+	Labels    []LabelActivity  `json:"labels"`
+	Providers []ProviderHealth `json:"providers"`
+}
+
+type labelTotals struct {
+	requests, errors    int64
+	latencyMs, lastMs   int64
+	okMs                int64
+	inTokens, outTokens int64
+}
+
+// activityLog records local routes for the admin API and dashboards.
+type activityLog struct {
+	mu        sync.Mutex
+	nextID    uint64
+	inFlight  map[uint64]*RouteEvent
+	recent    []RouteEvent // ring, oldest overwritten
+	pos       int
+	labels    map[string]*labelTotals
+	providers map[string]*ProviderHealth
+	speed     map[[3]string]*SpeedStats // label, provider, model
+}
+
+// begin records a route starting. The caller sets Status and the token
+// counts, which are only read after finish, and passes the event to finish.
+// Fields shown while in flight are set through setTarget and preview.
+func (a *activityLog) begin(label string, stream bool) *RouteEvent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.inFlight == nil {
+		a.inFlight = make(map[uint64]*RouteEvent)
+		a.labels = make(map[string]*labelTotals)
+		a.providers = make(map[string]*ProviderHealth)
+		a.speed = make(map[[3]string]*SpeedStats)
+	}
+	a.nextID++
+	ev := &RouteEvent{Start: time.Now(), Label: label, Stream: stream, id: a.nextID}
+	a.inFlight[ev.id] = ev
+	return ev
+}
+
+// setTarget records the provider and model a route resolved to.
+func (a *activityLog) setTarget(ev *RouteEvent, provider, model string) {
+	a.mu.Lock()
+	ev.Provider, ev.Model = provider, model
+	a.mu.Unlock()
+}
+
+// markFirstToken records when ev's first token arrived.
+func (a *activityLog) markFirstToken(ev *RouteEvent) {
+	if !ev.firstToken.IsZero() {
+		return
+	}
+	a.mu.Lock()
+	ev.firstToken = time.Now()
+	a.mu.Unlock()
+}
+
+// preview appends streamed text to ev's preview, keeping the tail.
+func (a *activityLog) preview(ev *RouteEvent, text string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	p := ev.Preview + text
+	if len(p) > previewBytes {
+		p = p[len(p)-previewBytes:]
+		for len(p) > 0 && !utf8.RuneStart(p[0]) {
+			p = p[1:]
+		}
+	}
+	ev.Preview = p
+}
+
+// finish records ev's outcome. An event with no status failed before
+// reaching the provider.
+func (a *activityLog) finish(ev *RouteEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.inFlight, ev.id)
+	if ev.Status == "" {
+		ev.Status = "ERROR"
+	}
+	end := time.Now()
+	ev.LatencyMs = end.Sub(ev.Start).Milliseconds()
+	ev.Preview = ""
+	ttft, gen, tps, gotToken := streamSpeed(ev, end)
+	if gotToken {
+		ev.TTFTMs, ev.TokensPerSec = ttft.Milliseconds(), tps
+	}
+
+	if len(a.recent) < recentRoutes {
+		a.recent = append(a.recent, *ev)
+	} else {
+		a.recent[a.pos] = *ev
+	}
+	a.pos = (a.pos + 1) % recentRoutes
+
+	ok := ev.Status == "ok" || ev.Status == "dedupe"
+	t := a.labels[ev.Label]
+	if t == nil {
+		t = &labelTotals{}
+		a.labels[ev.Label] = t
+	}
+	t.requests++
+	t.latencyMs += ev.LatencyMs
+	t.lastMs = ev.LatencyMs
+	t.inTokens += int64(ev.InputTokens)
+	t.outTokens += int64(ev.OutputTokens)
+	if ok {
+		t.okMs += ev.LatencyMs
+	} else {
+		t.errors++
+	}
+	if ev.Status == "ok" && gotToken {
+		k := [3]string{ev.Label, ev.Provider, ev.Model}
+		sp := a.speed[k]
+		if sp == nil {
+			sp = &SpeedStats{Label: ev.Label, Provider: ev.Provider, Model: ev.Model}
+			a.speed[k] = sp
+		}
+		sp.Add(SpeedStats{Streams: 1, TTFTMs: ttft.Milliseconds(),
+			GenerationMs: gen.Milliseconds(), GenerationTokens: int64(ev.OutputTokens)})
+	}
+
+	if ev.Provider != "" {
+		h := a.providers[ev.Provider]
+		if h == nil {
+			h = &ProviderHealth{Provider: ev.Provider}
+			a.providers[ev.Provider] = h
+		}
+		h.LastStatus, h.LastSeen = ev.Status, time.Now()
+		if ok {
+			h.ConsecutiveErrors = 0
+		} else {
+			h.ConsecutiveErrors++
+		}
+	}
+}
+
+func (a *activityLog) snapshot() Activity {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	act := Activity{
+		InFlight:  []RouteEvent{},
+		Recent:    make([]RouteEvent, 0, len(a.recent)),
+		Labels:    []LabelActivity{},
+		Providers: []ProviderHealth{},
+	}
+	for _, ev := range a.inFlight {
+		// Only the fields guarded by mu; Status and tokens are still being set.
+		act.InFlight = append(act.InFlight, RouteEvent{
+			Start:     ev.Start,
+			Label:     ev.Label,
+			Provider:  ev.Provider,
+			Model:     ev.Model,
+			Stream:    ev.Stream,
+			LatencyMs: time.Since(ev.Start).Milliseconds(),
+			Preview:   ev.Preview,
+		})
+	}
+	sort.Slice(act.InFlight, func(i, j int) bool { return act.InFlight[i].Start.Before(act.InFlight[j].Start) })
+	for i := range a.recent {
+		act.Recent = append(act.Recent, a.recent[(a.pos-1-i+2*len(a.recent))%len(a.recent)])
+	}
+	for label, t := range a.labels {
+		la := LabelActivity{
+			Label:         label,
+			Requests:      t.requests,
+			Errors:        t.errors,
+			AvgLatencyMs:  t.latencyMs / t.requests,
+			LastLatencyMs: t.lastMs,
+			InputTokens:   t.inTokens,
+			OutputTokens:  t.outTokens,
+		}
+		if t.okMs > 0 {
+			la.TokensPerSec = float64(t.outTokens) / (float64(t.okMs) / 1000)
+		}
+		act.Labels = append(act.Labels, la)
+	}
+	sort.Slice(act.Labels, func(i, j int) bool { return act.Labels[i].Label < act.Labels[j].Label })
+	for _, h := range a.providers {
+		act.Providers = append(act.Providers, *h)
+	}
+	sort.Slice(act.Providers, func(i, j int) bool { return act.Providers[i].Provider < act.Providers[j].Provider })
+	return act
+}
+
+// speedStats returns the per-label stream speeds, sorted.
+func (a *activityLog) speedStats() []SpeedStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make([]SpeedStats, 0, len(a.speed))
+	for _, sp := range a.speed {
+		out = append(out, *sp)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		return a.Label+"\x00"+a.Provider+"\x00"+a.Model < b.Label+"\x00"+b.Provider+"\x00"+b.Model
+	})
+	return out
+}
+
+// Activity returns recent and in-flight local routes with per-label and
+// per-provider summaries.
+func (p *Proxy) Activity() Activity {
+	return p.activity.snapshot()
+}
+
+// previewWriter feeds the text deltas of a translated Anthropic SSE stream
+// into a route's preview, and marks the route's first token at the first
+// delta of any kind. The translator writes whole events, but lines are
+// reassembled anyway.
+type previewWriter struct {
+	log  *activityLog
+	ev   *RouteEvent
+	line []byte
+}
+
+func (pw *previewWriter) Write(b []byte) (int, error) {
+	pw.line = append(pw.line, b...)
+	for {
+		i := bytes.IndexByte(pw.line, '\n')
+		if i < 0 {
+			break
+		}
+		if data, ok := bytes.CutPrefix(pw.line[:i], []byte("data: ")); ok {
+			var ev struct {
+				Delta struct {
+					Type     string `json:"type"`
+					Text     string `json:"text"`
+					Thinking string `json:"thinking"`
+				} `json:"delta"`
+			}
+			if json.Unmarshal(data, &ev) == nil {
+				if ev.Delta.Type != "" {
+					pw.log.markFirstToken(pw.ev)
+				}
+				switch ev.Delta.Type {
+				case "text_delta":
+					pw.log.preview(pw.ev, ev.Delta.Text)
+				case "thinking_delta":
+					pw.log.preview(pw.ev, ev.Delta.Thinking)
+				}
+			}
+		}
+		pw.line = pw.line[i+1:]
+	}
+	return len(b), nil
+}
@@ -0,0 +1,187 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+func TestActivityRecordsLocalRoutes(t *testing.T) {
+	port, _, _ := capturingMockOpenAI(t)
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{
+			{
+				Name:     "mock",
+				Endpoint: fmt.Sprintf("http://127.0.0.1:%d/v1", port),
+				Models:   map[string]config.ModelConfig{"fast": {Model: "m1"}},
+			},
+			{
+				Name:     "dead",
+				Endpoint: "http://127.0.0.1:1/v1",
+				Models:   map[string]config.ModelConfig{"down": {Model: "x"}},
+			},
+		},
+	})
+	infra := setupInfra(t, resolver)
+
+	send := func(label string) {
+		body, _ := json.Marshal(map[string]interface{}{
+			"model":      "claude-sonnet-4-20250514",
+			"system":     "<!-- @proxy-local-route:af83e9 model=" + label + " -->",
+			"messages":   []map[string]string{{"role": "user", "content": "hi"}},
+			"max_tokens": 64,
+		})
+		proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+	}
+	send("fast")
+	send("fast")
+	send("down")
+
+	act := infra.proxy.Activity()
+	if len(act.InFlight) != 0 {
+		t.Errorf("in flight = %+v", act.InFlight)
+	}
+	if len(act.Recent) != 3 || act.Recent[0].Label != "down" || act.Recent[0].Status != "CONNECTION" {
+		t.Fatalf("recent = %+v", act.Recent)
+	}
+	if ev := act.Recent[1]; ev.Status != "ok" || ev.Provider != "mock" || ev.InputTokens != 10 || ev.OutputTokens != 5 {
+		t.Errorf("ok route = %+v", ev)
+	}
+	if len(act.Labels) != 2 || act.Labels[1].Label != "fast" || act.Labels[1].Requests != 2 || act.Labels[1].OutputTokens != 10 {
+		t.Errorf("labels = %+v", act.Labels)
+	}
+	for _, h := range act.Providers {
+		if want := map[string]int{"mock": 0, "dead": 1}[h.Provider]; h.ConsecutiveErrors != want {
+			t.Errorf("%s consecutive errors = %d, want %d", h.Provider, h.ConsecutiveErrors, want)
+		}
+	}
+}
+
+func TestActivityRecentIsBounded(t *testing.T) {
+	var a activityLog
+	for i := 0; i < recentRoutes+5; i++ {
+		ev := a.begin(fmt.Sprintf("l%d", i), false)
+		ev.Status = "ok"
+		a.finish(ev)
+	}
+	act := a.snapshot()
+	if len(act.Recent) != recentRoutes {
+		t.Fatalf("recent = %d entries", len(act.Recent))
+	}
+	if newest, oldest := act.Recent[0].Label, act.Recent[recentRoutes-1].Label; newest != fmt.Sprintf("l%d", recentRoutes+4) || oldest != "l5" {
+		t.Errorf("newest %s, oldest %s", newest, oldest)
+	}
+}
+
+func TestPreviewWriterKeepsStreamedText(t *testing.T) {
+	var a activityLog
+	ev := a.begin("fast", true)
+	pw := &previewWriter{log: &a, ev: ev}
+	stream := "event: message_start\ndata: {\"type\":\"message_start\"}\n\n" +
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"Hello\"}}\n\n" +
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\", world\"}}\n\n"
+	// Split mid-line to check reassembly.
+	pw.Write([]byte(stream[:70]))
+	pw.Write([]byte(stream[70:]))
+
+	if got := a.snapshot().InFlight[0].Preview; got != "Hello, world" {
+		t.Errorf("preview = %q", got)
+	}
+	a.preview(ev, strings.Repeat("x", previewBytes))
+	if got := a.snapshot().InFlight[0].Preview; len(got) != previewBytes {
+		t.Errorf("preview not capped: %d bytes", len(got))
+	}
+	a.finish(ev)
+	if a.snapshot().Recent[0].Preview != "" {
+		t.Error("finished routes should drop their preview")
+	}
+}
+
+func TestEventsFollowLocalRoutes(t *testing.T) {
+	port, _, _ := capturingMockOpenAI(t)
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "mock",
+			Endpoint: fmt.Sprintf("http://127.0.0.1:%d/v1", port),
+			Models:   map[string]config.ModelConfig{"fast": {Model: "m1"}},
+		}},
+	})
+	infra := setupInfra(t, resolver)
+	events, cancel := infra.proxy.Events()
+	defer cancel()
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"system":     "<!-- @proxy-local-route:af83e9 model=fast -->",
+		"messages":   []map[string]string{{"role": "user", "content": "hi"}},
+		"max_tokens": 64,
+	})
+	proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+	proxyRequest(t, infra, "POST", "/v1/messages", []byte(`{"model":"claude-sonnet-4-20250514"}`), nil)
+
+	var got []string
+	for len(got) < 4 {
+		select {
+		case ev := <-events:
+			got = append(got, ev.Type+":"+ev.Route+ev.Status)
+			if ev.Type == EventStreamFinished && (ev.Provider != "mock" || ev.Model != "m1" || ev.OutputTokens != 5) {
+				t.Errorf("stream_finished = %+v", ev)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out after %v", got)
+		}
+	}
+	want := "route_decided:local provider_called: stream_finished:ok route_decided:upstream"
+	if strings.Join(got, " ") != want {
+		t.Errorf("events = %v, want %s", got, want)
+	}
+}
+
+func TestEventsDropForSlowSubscribers(t *testing.T) {
+	var b eventBus
+	events, cancel := b.subscribe()
+	for i := 0; i < eventBuffer+10; i++ {
+		b.publish(RoutingEvent{Type: EventError})
+	}
+	if len(events) != eventBuffer {
+		t.Errorf("buffered %d events, want %d", len(events), eventBuffer)
+	}
+	cancel()
+	cancel()
+	b.publish(RoutingEvent{Type: EventError}) // no subscribers left; must not block
+}
+
+func TestActivityMeasuresStreamSpeed(t *testing.T) {
+	infra := setupInfra(t, annotateResolver(t))
+	proxyRequest(t, infra, "POST", "/v1/messages", routedBody("test_model", true), nil)
+	proxyRequest(t, infra, "POST", "/v1/messages", routedBody("test_model", false), nil)
+
+	act := infra.proxy.Activity()
+	if len(act.Recent) != 2 {
+		t.Fatalf("recent = %+v", act.Recent)
+	}
+	// The mock reports no usage for streams, so only the first token is timed.
+	if ev := act.Recent[1]; !ev.Stream || ev.Status != "ok" || ev.firstToken.IsZero() || ev.TTFTMs > ev.LatencyMs {
+		t.Errorf("stream route = %+v", ev)
+	}
+	if ev := act.Recent[0]; ev.TTFTMs != 0 || ev.TokensPerSec != 0 {
+		t.Errorf("non-streaming route has speed %+v", ev)
+	}
+	speed := infra.proxy.Metrics().Speed
+	if len(speed) != 1 || speed[0].Label != "test_model" || speed[0].Provider != "mock" || speed[0].Streams != 1 {
+		t.Errorf("speed = %+v", speed)
+	}
+}
+
+func TestSpeedStatsAdd(t *testing.T) {
+	var s SpeedStats
+	s.Add(SpeedStats{Streams: 1, TTFTMs: 100, GenerationMs: 1000, GenerationTokens: 50})
+	s.Add(SpeedStats{Streams: 3, TTFTMs: 500, GenerationMs: 1000, GenerationTokens: 150})
+	if s.AvgTTFTMs != 150 || s.TokensPerSec != 100 {
+		t.Errorf("averages = %dms, %.1f tok/s", s.AvgTTFTMs, s.TokensPerSec)
+	}
+}
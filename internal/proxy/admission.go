@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	errQueueFull    = errors.New("proxy overloaded: wait queue full")
+	errQueueTimeout = errors.New("proxy overloaded: timed out waiting for a slot")
+)
+
+// overloadRetryAfter is the Retry-After, in seconds, sent with a refused
+// CONNECT. Tunnels turn over quickly once Claude Code's parallel burst ends,
+// so a short backoff is enough.
+const overloadRetryAfter = "2"
+
+// admission caps concurrent tunnels. A CONNECT that finds every slot busy
+// waits in a bounded queue instead of failing outright; it is refused only
+// when the queue is full, it waited longer than timeout, or the client gave
+// up first.
+type admission struct {
+	slots     chan struct{}
+	maxQueued int64
+	timeout   time.Duration
+
+	waiting   atomic.Int64
+	queued    atomic.Int64 // CONNECTs that found every slot busy
+	rejected  atomic.Int64 // refused because the queue was full
+	timedOut  atomic.Int64 // refused after waiting timeout
+	abandoned atomic.Int64 // client disconnected while queued
+	waitNanos atomic.Int64
+	maxWait   atomic.Int64
+}
+
+func newAdmission(maxConcurrent, maxQueued int, timeout time.Duration) *admission {
+	return &admission{
+		slots:     make(chan struct{}, maxConcurrent),
+		maxQueued: int64(max(maxQueued, 0)),
+		timeout:   timeout,
+	}
+}
+
+// acquire takes a slot, waiting in the queue if necessary. The returned
+// release func must be called when the tunnel ends.
+func (a *admission) acquire(ctx context.Context) (release func(), err error) {
+	release = func() { <-a.slots }
+	select {
+	case a.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	a.queued.Add(1)
+	if a.waiting.Add(1) > a.maxQueued {
+		a.waiting.Add(-1)
+		a.rejected.Add(1)
+		return nil, errQueueFull
+	}
+	defer a.waiting.Add(-1)
+
+	start := time.Now()
+	timer := time.NewTimer(a.timeout)
+	defer timer.Stop()
+	select {
+	case a.slots <- struct{}{}:
+		a.observeWait(time.Since(start))
+		return release, nil
+	case <-timer.C:
+		a.observeWait(time.Since(start))
+		a.timedOut.Add(1)
+		return nil, errQueueTimeout
+	case <-ctx.Done():
+		a.abandoned.Add(1)
+		return nil, ctx.Err()
+	}
+}
+
+func (a *admission) observeWait(d time.Duration) {
+	a.waitNanos.Add(int64(d))
+	for {
+		cur := a.maxWait.Load()
+		if int64(d) <= cur || a.maxWait.CompareAndSwap(cur, int64(d)) {
+			return
+		}
+	}
+}
+
+// AdmissionStats reports tunnel slot usage and saturation.
+type AdmissionStats struct {
+	InFlight      int   `json:"in_flight"`
+	MaxConcurrent int   `json:"max_concurrent"`
+	Waiting       int64 `json:"waiting"`
+	Queued        int64 `json:"queued"`    // CONNECTs that found every slot busy
+	Rejected      int64 `json:"rejected"`  // refused: queue full
+	TimedOut      int64 `json:"timed_out"` // refused: waited queue_timeout
+	Abandoned     int64 `json:"abandoned"` // client disconnected while queued
+	WaitMs        int64 `json:"wait_ms"`   // total time spent queued
+	MaxWaitMs     int64 `json:"max_wait_ms"`
+}
+
+func (a *admission) stats() AdmissionStats {
+	return AdmissionStats{
+		InFlight:      len(a.slots),
+		MaxConcurrent: cap(a.slots),
+		Waiting:       a.waiting.Load(),
+		Queued:        a.queued.Load(),
+		Rejected:      a.rejected.Load(),
+		TimedOut:      a.timedOut.Load(),
+		Abandoned:     a.abandoned.Load(),
+		WaitMs:        time.Duration(a.waitNanos.Load()).Milliseconds(),
+		MaxWaitMs:     time.Duration(a.maxWait.Load()).Milliseconds(),
+	}
+}
@@ -0,0 +1,92 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+func TestAdmissionQueuesUntilSlotFrees(t *testing.T) {
+	a := newAdmission(1, 4, 5*time.Second)
+	release, err := a.acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(chan error, 1)
+	go func() {
+		r, err := a.acquire(context.Background())
+		if err == nil {
+			r()
+		}
+		got <- err
+	}()
+	for a.waiting.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	release()
+
+	if err := <-got; err != nil {
+		t.Fatalf("queued acquire failed: %v", err)
+	}
+	s := a.stats()
+	if s.Queued != 1 || s.Rejected != 0 || s.TimedOut != 0 || s.MaxWaitMs < 20 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+func TestAdmissionRefusals(t *testing.T) {
+	a := newAdmission(1, 1, 30*time.Millisecond)
+	release, _ := a.acquire(context.Background())
+	defer release()
+
+	// One waiter fills the queue; the next is refused immediately.
+	done := make(chan error, 1)
+	go func() {
+		_, err := a.acquire(context.Background())
+		done <- err
+	}()
+	for a.waiting.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := a.acquire(context.Background()); !errors.Is(err, errQueueFull) {
+		t.Errorf("expected errQueueFull, got %v", err)
+	}
+	if err := <-done; !errors.Is(err, errQueueTimeout) {
+		t.Errorf("expected errQueueTimeout, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := a.acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	s := a.stats()
+	if s.Rejected != 1 || s.TimedOut != 1 || s.Abandoned != 1 || s.Waiting != 0 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+func TestConnectRefusedWithRetryAfter(t *testing.T) {
+	p := New(nil, WithLimits(config.Limits{MaxConcurrent: 1, MaxQueued: -1}))
+	release, _ := p.admit.acquire(context.Background())
+	defer release()
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("CONNECT", "api.anthropic.com:443", nil))
+	if rec.Code != 503 {
+		t.Fatalf("expected 503, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("missing Retry-After")
+	}
+	if m := p.Metrics(); m.Admission.Rejected != 1 || m.Admission.InFlight != 1 {
+		t.Errorf("admission metrics = %+v", m.Admission)
+	}
+}
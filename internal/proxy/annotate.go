@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+// WithAnnotations sets how locally answered responses report where they
+// were routed. By default they carry X-Hybrid-* headers and streams have no
+// trailing comment.
+func WithAnnotations(cfg config.AnnotationsConfig) Option {
+	return func(p *Proxy) { p.annotations = cfg }
+}
+
+// routeAnnotator adds X-Hybrid-* headers to a locally answered response. It
+// wraps the tunnel and inserts the headers after the status line of the
+// first write, which every response writer here sends in one piece.
+//
+// The route fields are set by the request's goroutine before the response
+// starts; the first write may come from a stream writer's sender.
+type routeAnnotator struct {
+	dst   io.Writer
+	off   bool
+	start time.Time
+
+	route    string // local, dedupe, count_tokens or stub
+	label    string // the label that answered (a budget fallback's, if rerouted)
+	provider string
+	model    string
+
+	wrote bool
+}
+
+// annotate wraps w for a response to a request routed to label.
+func (p *Proxy) annotate(w io.Writer, route, label string) *routeAnnotator {
+	return &routeAnnotator{dst: w, off: p.annotations.Off, start: time.Now(), route: route, label: label}
+}
+
+// setTarget records the model that serves the request.
+func (a *routeAnnotator) setTarget(m config.ResolvedModel) {
+	a.label, a.provider, a.model = m.Label, m.Provider, m.Model
+}
+
+func (a *routeAnnotator) Write(b []byte) (int, error) {
+	if a.wrote || a.off {
+		return a.dst.Write(b)
+	}
+	a.wrote = true
+	i := bytes.Index(b, []byte("\r\n"))
+	if i < 0 || !bytes.HasPrefix(b, []byte("HTTP/")) {
+		return a.dst.Write(b)
+	}
+	var buf bytes.Buffer
+	buf.Write(b[:i+2])
+	fmt.Fprintf(&buf, "X-Hybrid-Route: %s\r\nX-Hybrid-Label: %s\r\n", a.route, a.label)
+	if a.provider != "" {
+		fmt.Fprintf(&buf, "X-Hybrid-Provider: %s\r\nX-Hybrid-Model: %s\r\n", a.provider, a.model)
+	}
+	buf.WriteString("X-Hybrid-Latency-Ms: " + strconv.FormatInt(time.Since(a.start).Milliseconds(), 10) + "\r\n")
+	buf.Write(b[i+2:])
+	if _, err := a.dst.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// sseComment is the summary a finished stream ends with when
+// annotations.sse_comment is set. SSE clients ignore comment lines.
+func (a *routeAnnotator) sseComment(inputTokens, outputTokens int) []byte {
+	return fmt.Appendf(nil, ": hybrid route=%s label=%s provider=%s model=%s latency_ms=%d input_tokens=%d output_tokens=%d\n\n",
+		a.route, a.label, a.provider, a.model, time.Since(a.start).Milliseconds(), inputTokens, outputTokens)
+}
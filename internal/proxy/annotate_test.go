@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/testutil"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+func annotateResolver(t *testing.T) *config.ModelResolver {
+	t.Helper()
+	oaiSrv, oaiPort, err := testutil.MockOpenAIServer()
+	if err != nil {
+		t.Fatalf("mock openai: %v", err)
+	}
+	t.Cleanup(func() { oaiSrv.Close() })
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "mock",
+			Endpoint: fmt.Sprintf("http://127.0.0.1:%d/v1", oaiPort),
+			Models:   map[string]config.ModelConfig{"test_model": {Model: "mock-model-v1"}},
+		}},
+	})
+	return resolver
+}
+
+func routedBody(label string, stream bool) []byte {
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"system":     "<!-- @proxy-local-route:af83e9 model=" + label + " -->",
+		"messages":   []map[string]string{{"role": "user", "content": "hello"}},
+		"max_tokens": 64,
+		"stream":     stream,
+	})
+	return body
+}
+
+func TestAnnotationHeaders(t *testing.T) {
+	infra := setupInfra(t, annotateResolver(t))
+
+	status, h, body := proxyRequestHeaders(t, infra, "POST", "/v1/messages", routedBody("test_model", false), nil)
+	if status != 200 {
+		t.Fatalf("status %d: %s", status, body)
+	}
+	for k, want := range map[string]string{
+		"X-Hybrid-Route":    "local",
+		"X-Hybrid-Label":    "test_model",
+		"X-Hybrid-Provider": "mock",
+		"X-Hybrid-Model":    "mock-model-v1",
+	} {
+		if got := h.Get(k); got != want {
+			t.Errorf("%s = %q, want %q", k, got, want)
+		}
+	}
+	if _, err := strconv.Atoi(h.Get("X-Hybrid-Latency-Ms")); err != nil {
+		t.Errorf("X-Hybrid-Latency-Ms = %q", h.Get("X-Hybrid-Latency-Ms"))
+	}
+
+	// Errors answered by the proxy are annotated too.
+	status, h, _ = proxyRequestHeaders(t, infra, "POST", "/v1/messages", routedBody("nope", false), nil)
+	if status != 400 || h.Get("X-Hybrid-Route") != "local" || h.Get("X-Hybrid-Provider") != "" {
+		t.Errorf("unknown label: status %d, headers %v", status, h)
+	}
+
+	status, h, _ = proxyRequestHeaders(t, infra, "POST", "/v1/messages/count_tokens", routedBody("test_model", false), nil)
+	if status != 200 || h.Get("X-Hybrid-Route") != "count_tokens" || h.Get("X-Hybrid-Provider") != "mock" {
+		t.Errorf("count_tokens: status %d, headers %v", status, h)
+	}
+
+	// Requests passed through to the upstream are not.
+	_, h, _ = proxyRequestHeaders(t, infra, "GET", "/v1/models", nil, nil)
+	if h.Get("X-Hybrid-Route") != "" {
+		t.Errorf("upstream response annotated: %v", h)
+	}
+}
+
+func TestAnnotationSSEComment(t *testing.T) {
+	infra := setupInfraWithOptions(t, annotateResolver(t), WithAnnotations(config.AnnotationsConfig{SSEComment: true}))
+
+	status, h, body := proxyRequestHeaders(t, infra, "POST", "/v1/messages", routedBody("test_model", true), nil)
+	if status != 200 || h.Get("X-Hybrid-Route") != "local" {
+		t.Fatalf("status %d, headers %v: %s", status, h, body)
+	}
+	assertSSELifecycle(t, body)
+	i := strings.LastIndex(body, "\n: hybrid ")
+	if i < 0 || !strings.Contains(body[i:], "route=local label=test_model provider=mock model=mock-model-v1 latency_ms=") {
+		t.Errorf("no trailing hybrid comment in stream:\n%s", body)
+	}
+	if strings.Contains(body[i:], "event:") {
+		t.Errorf("comment is not the last thing in the stream:\n%s", body[i:])
+	}
+}
+
+func TestAnnotationsOff(t *testing.T) {
+	infra := setupInfraWithOptions(t, annotateResolver(t), WithAnnotations(config.AnnotationsConfig{Off: true}))
+	_, h, body := proxyRequestHeaders(t, infra, "POST", "/v1/messages", routedBody("test_model", true), nil)
+	if h.Get("X-Hybrid-Route") != "" || strings.Contains(body, ": hybrid ") {
+		t.Errorf("annotations off, got headers %v", h)
+	}
+}
@@ -0,0 +1,74 @@
+package proxy
+
+import (
+	"encoding/base64"
+	"net"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConnectRequiresProxyToken(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close() // an authorized CONNECT reaches the dial and gets 502
+
+	basic := func(cred string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(cred))
+	}
+	p := New(nil, WithProxyToken("s3cret"))
+	for _, tt := range []struct {
+		header string
+		want   int
+	}{
+		{"", 407},
+		{basic("claude:wrong"), 407},
+		{"Bearer wrong", 407},
+		{"Basic !!!", 407},
+		{basic("claude:s3cret"), 502},
+		{basic("s3cret:"), 502},
+		{"Bearer s3cret", 502},
+	} {
+		req := httptest.NewRequest("CONNECT", addr, nil)
+		if tt.header != "" {
+			req.Header.Set("Proxy-Authorization", tt.header)
+		}
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("Proxy-Authorization %q: got %d, want %d", tt.header, rec.Code, tt.want)
+		}
+		if tt.want == 407 && rec.Header().Get("Proxy-Authenticate") == "" {
+			t.Errorf("Proxy-Authorization %q: 407 without a challenge", tt.header)
+		}
+	}
+}
+
+func TestNoProxyTokenLeavesProxyOpen(t *testing.T) {
+	rec := httptest.NewRecorder()
+	New(nil).ServeHTTP(rec, httptest.NewRequest("CONNECT", "127.0.0.1:1", nil))
+	if rec.Code == 407 {
+		t.Fatal("proxy without a token should not ask for authentication")
+	}
+}
+
+func TestOpenAIHandlerRequiresProxyToken(t *testing.T) {
+	h := New(nil, WithProxyToken("s3cret")).OpenAIHandler()
+	for auth, want := range map[string]int{
+		"":              401,
+		"Bearer wrong":  401,
+		"Bearer s3cret": 200,
+	} {
+		req := httptest.NewRequest("GET", "/v1/models", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("Authorization %q: got %d, want %d", auth, rec.Code, want)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package proxy
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+const (
+	// budgetRefresh is how often other instances' spend is re-read.
+	budgetRefresh = 2 * time.Second
+	// budgetRetention is how long daily ledgers are kept.
+	budgetRetention = 7 * 24 * time.Hour
+	// maxFallbackHops bounds how far an over-budget label is rerouted.
+	maxFallbackHops = 4
+)
+
+// budgetLedger records each label's spend for the current local day. With a
+// directory, the spend is shared by every claude-hybrid instance: each one
+// writes its own totals to <dir>/<date>/<session>.json and adds up the
+// others', so no file is ever written by two processes. Without one, spend
+// is tracked in memory only.
+type budgetLedger struct {
+	dir     string
+	session string
+
+	mu         sync.Mutex
+	day        string             // YYYY-MM-DD the totals are for
+	own        map[string]float64 // this instance's spend by label
+	others     map[string]float64 // other instances' spend by label
+	othersRead time.Time
+	warned     map[string]bool // labels whose warn action has been logged today
+}
+
+// WithBudgetLedger shares label spend with other instances through dir,
+// which holds one subdirectory per day. session names this instance's file.
+func WithBudgetLedger(dir, session string) Option {
+	return func(p *Proxy) {
+		p.budgets.dir, p.budgets.session = dir, session
+		pruneBudgetDays(dir)
+	}
+}
+
+// pruneBudgetDays removes day directories older than budgetRetention.
+func pruneBudgetDays(dir string) {
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if info, err := e.Info(); err == nil && e.IsDir() && time.Since(info.ModTime()) > budgetRetention {
+			os.RemoveAll(filepath.Join(dir, e.Name()))
+		}
+	}
+}
+
+// rollover resets the totals when the local date changes. Called with mu
+// held.
+func (b *budgetLedger) rollover() {
+	today := time.Now().Format("2006-01-02")
+	if b.day == today {
+		return
+	}
+	b.day = today
+	b.own = make(map[string]float64)
+	b.others = make(map[string]float64)
+	b.othersRead = time.Time{}
+	b.warned = make(map[string]bool)
+}
+
+// spent returns label's spend today across all instances.
+func (b *budgetLedger) spent(label string) float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover()
+	b.readOthers()
+	return b.own[label] + b.others[label]
+}
+
+// charge adds usd to label's spend and saves this instance's totals.
+func (b *budgetLedger) charge(label string, usd float64) {
+	if usd <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover()
+	b.own[label] += usd
+	if b.dir == "" {
+		return
+	}
+	dayDir := filepath.Join(b.dir, b.day)
+	if err := os.MkdirAll(dayDir, 0700); err != nil {
+		return
+	}
+	data, _ := json.Marshal(b.own)
+	path := filepath.Join(dayDir, b.session+".json")
+	if os.WriteFile(path+".tmp", data, 0600) == nil {
+		os.Rename(path+".tmp", path)
+	}
+}
+
+// warnOnce reports whether label's warn action should be logged, which is
+// once per day.
+func (b *budgetLedger) warnOnce(label string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollover()
+	if b.warned[label] {
+		return false
+	}
+	b.warned[label] = true
+	return true
+}
+
+// readOthers re-reads the other instances' files for today when the cached
+// totals are stale. Called with mu held.
+func (b *budgetLedger) readOthers() {
+	if b.dir == "" || time.Since(b.othersRead) < budgetRefresh {
+		return
+	}
+	b.othersRead = time.Now()
+	others := make(map[string]float64)
+	dayDir := filepath.Join(b.dir, b.day)
+	entries, _ := os.ReadDir(dayDir)
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasSuffix(name, ".json") || name == b.session+".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dayDir, name))
+		if err != nil {
+			continue
+		}
+		var spend map[string]float64
+		if json.Unmarshal(data, &spend) != nil {
+			continue
+		}
+		for label, usd := range spend {
+			others[label] += usd
+		}
+	}
+	b.others = others
+}
+
+// BudgetStatus is one budgeted label's spend today.
+type BudgetStatus struct {
+	Label    string  `json:"label"`
+	SpentUSD float64 `json:"spent_usd"` // across all instances
+	DailyUSD float64 `json:"daily_usd"`
+	Action   string  `json:"action"`
+	Exceeded bool    `json:"exceeded"`
+}
+
+// budgetStatus reports every label with a budget.
+func (p *Proxy) budgetStatus() []BudgetStatus {
+	out := []BudgetStatus{}
+	if p.modelResolver == nil {
+		return out
+	}
+	for _, m := range p.modelResolver.Models() {
+		if m.Budget == nil {
+			continue
+		}
+		spent := p.budgets.spent(m.Label)
+		out = append(out, BudgetStatus{
+			Label:    m.Label,
+			SpentUSD: spent,
+			DailyUSD: m.Budget.DailyUSD,
+			Action:   m.Budget.Action,
+			Exceeded: spent >= m.Budget.DailyUSD,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Label < out[j].Label })
+	return out
+}
+
+// chargeBudget records what a finished request on m cost. Spend is kept for
+// every priced label, budgeted or not.
+func (p *Proxy) chargeBudget(m config.ResolvedModel, inputTokens, outputTokens int) {
+	if m.Price != nil {
+		p.budgets.charge(m.Label, m.Price.Cost(inputTokens, outputTokens))
+	}
+}
+
+// overBudget reports whether m has a budget and today's spend has reached
+// it, with the spend.
+func (p *Proxy) overBudget(m config.ResolvedModel) (bool, float64) {
+	if m.Budget == nil {
+		return false, 0
+	}
+	spent := p.budgets.spent(m.Label)
+	return spent >= m.Budget.DailyUSD, spent
+}
+
+// applyBudget checks m's budget before a request is sent. It returns the
+// model to use, which is a fallback when m is over a fallback budget, or
+// ok=false with the over-budget model when the request must be refused.
+func (p *Proxy) applyBudget(m config.ResolvedModel) (use config.ResolvedModel, ok bool, spent float64) {
+	for hop := 0; ; hop++ {
+		over, spent := p.overBudget(m)
+		if !over {
+			return m, true, 0
+		}
+		switch m.Budget.Action {
+		case config.BudgetWarn:
+			if p.budgets.warnOnce(m.Label) {
+				log.Printf("[LOCAL_BUDGET] %s is over its $%.2f daily budget ($%.2f spent); routing anyway (action: warn)",
+					m.Label, m.Budget.DailyUSD, spent)
+			}
+			return m, true, spent
+		case config.BudgetFallback:
+			next, err := p.modelResolver.Resolve(m.Fallback)
+			if err != nil || hop == maxFallbackHops {
+				return m, false, spent
+			}
+			log.Printf("LOCAL_BUDGET %s is over its $%.2f daily budget ($%.2f spent) → fallback %s",
+				m.Label, m.Budget.DailyUSD, spent, next.Label)
+			m = next
+		default:
+			return m, false, spent
+		}
+	}
+}
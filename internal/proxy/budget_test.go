@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+// budgetInfra routes "paid" to a mock that reports 10 input tokens per
+// request; at $100,000 per million input tokens each request costs $1.
+func budgetInfra(t *testing.T, budget config.Budget, opts ...Option) (*testInfra, func() (int, string)) {
+	t.Helper()
+	port, _, _ := capturingMockOpenAI(t)
+	endpoint := fmt.Sprintf("http://127.0.0.1:%d/v1", port)
+	resolver, err := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "mock",
+			Endpoint: endpoint,
+			Models: map[string]config.ModelConfig{
+				"paid": {Model: "m1", Price: &config.Price{Input: 100_000}, Budget: &budget, Fallback: "free"},
+				"free": {Model: "m2"},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	infra := setupInfraWithOptions(t, resolver, opts...)
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"system":     "<!-- @proxy-local-route:af83e9 model=paid -->",
+		"messages":   []map[string]string{{"role": "user", "content": "hi"}},
+		"max_tokens": 64,
+	})
+	return infra, func() (int, string) {
+		status, resp, _ := proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+		return status, resp
+	}
+}
+
+func TestBudgetBlock(t *testing.T) {
+	infra, send := budgetInfra(t, config.Budget{DailyUSD: 2, Action: config.BudgetBlock})
+	for i := 0; i < 2; i++ {
+		if status, body := send(); status != 200 {
+			t.Fatalf("request %d within budget: %d %s", i+1, status, body)
+		}
+	}
+	status, body := send()
+	if status != 402 || !strings.Contains(body, "billing_error") || !strings.Contains(body, "$2.00 of its $2.00 daily budget") {
+		t.Errorf("over budget: %d %s, want 402 billing_error naming the spend", status, body)
+	}
+	if act := infra.proxy.Activity(); act.Recent[0].Status != "BUDGET" {
+		t.Errorf("activity status = %q, want BUDGET", act.Recent[0].Status)
+	}
+	b := infra.proxy.Metrics().Budgets
+	if len(b) != 1 || b[0].Label != "paid" || b[0].SpentUSD != 2 || !b[0].Exceeded {
+		t.Errorf("budget metrics = %+v", b)
+	}
+}
+
+func TestBudgetFallbackAndWarn(t *testing.T) {
+	infra, send := budgetInfra(t, config.Budget{DailyUSD: 1, Action: config.BudgetFallback})
+	send()
+	if status, body := send(); status != 200 {
+		t.Fatalf("fallback request: %d %s", status, body)
+	}
+	if ev := infra.proxy.Activity().Recent[0]; ev.Model != "m2" {
+		t.Errorf("over-budget request went to %q, want the fallback's m2", ev.Model)
+	}
+
+	infra, send = budgetInfra(t, config.Budget{DailyUSD: 1, Action: config.BudgetWarn})
+	send()
+	if status, body := send(); status != 200 {
+		t.Fatalf("warn request: %d %s", status, body)
+	}
+	if ev := infra.proxy.Activity().Recent[0]; ev.Model != "m1" {
+		t.Errorf("warn routed to %q, want m1", ev.Model)
+	}
+}
+
+func TestBudgetSharedAcrossInstances(t *testing.T) {
+	dir := t.TempDir()
+	_, sendA := budgetInfra(t, config.Budget{DailyUSD: 1}, WithBudgetLedger(dir, "s1"))
+	infraB, _ := budgetInfra(t, config.Budget{DailyUSD: 1}, WithBudgetLedger(dir, "s2"))
+	if status, body := sendA(); status != 200 {
+		t.Fatalf("instance A: %d %s", status, body)
+	}
+	if b := infraB.proxy.Metrics().Budgets; len(b) != 1 || b[0].SpentUSD != 1 || !b[0].Exceeded {
+		t.Errorf("instance B sees %+v, want A's $1 spend", b)
+	}
+}
+
+func TestBudgetLedgerRefreshesOthers(t *testing.T) {
+	dir := t.TempDir()
+	a := &budgetLedger{dir: dir, session: "s1"}
+	b := &budgetLedger{dir: dir, session: "s2"}
+	if got := b.spent("x"); got != 0 {
+		t.Fatalf("spent = %v before any charge", got)
+	}
+	a.charge("x", 0.5)
+	a.charge("x", 0.25)
+	if got := b.spent("x"); got != 0 {
+		t.Errorf("spent = %v, want the cached 0 until the refresh interval passes", got)
+	}
+	b.othersRead = time.Time{}
+	if got := b.spent("x"); got != 0.75 {
+		t.Errorf("spent = %v, want 0.75", got)
+	}
+}
@@ -0,0 +1,149 @@
+package proxy
+
+import (
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// bypassDialTimeout bounds the dial for a blind tunnel.
+const bypassDialTimeout = 10 * time.Second
+
+// interceptList holds the host patterns the proxy decrypts. See
+// config.DefaultIntercept for the pattern syntax.
+type interceptList []string
+
+func newInterceptList(patterns []string) interceptList {
+	l := make(interceptList, 0, len(patterns))
+	for _, pat := range patterns {
+		pat = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(pat), "."))
+		// An IPv6 literal may be written as in a URL; CONNECT hosts are
+		// matched without brackets.
+		pat = strings.TrimSuffix(strings.TrimPrefix(pat, "["), "]")
+		if pat != "" {
+			l = append(l, pat)
+		}
+	}
+	return l
+}
+
+// match reports whether host should be intercepted.
+func (l interceptList) match(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, pat := range l {
+		switch {
+		case pat == "*", pat == host:
+			return true
+		case strings.HasPrefix(pat, "*.") && strings.HasSuffix(host, pat[1:]):
+			return true
+		}
+	}
+	return false
+}
+
+// bypassStats counts blind tunnels.
+type bypassStats struct {
+	tunnels  atomic.Int64
+	active   atomic.Int64
+	failed   atomic.Int64
+	bytesIn  atomic.Int64 // upstream → client
+	bytesOut atomic.Int64 // client → upstream
+}
+
+// BypassStats reports CONNECTs tunneled without MITM.
+type BypassStats struct {
+	Tunnels  int64 `json:"tunnels"`
+	Active   int64 `json:"active"`
+	Failed   int64 `json:"failed"` // upstream dial failed
+	BytesIn  int64 `json:"bytes_in"`
+	BytesOut int64 `json:"bytes_out"`
+}
+
+func (s *bypassStats) snapshot() BypassStats {
+	return BypassStats{
+		Tunnels:  s.tunnels.Load(),
+		Active:   s.active.Load(),
+		Failed:   s.failed.Load(),
+		BytesIn:  s.bytesIn.Load(),
+		BytesOut: s.bytesOut.Load(),
+	}
+}
+
+// blindTunnel connects the client to r.Host and copies bytes both ways
+// without terminating TLS, so cert-pinned hosts keep working and their
+// traffic is never decrypted. Tunnels don't take admission slots: they
+// hold no request state and are bounded by the client's own connections.
+func (p *Proxy) blindTunnel(w http.ResponseWriter, r *http.Request) {
+	addr, plain := p.overrideAddr(r.Host)
+	if plain {
+		log.Printf("[BYPASS] %s: dns_overrides gives a plain-HTTP address, which only works for intercepted hosts", r.Host)
+		http.Error(w, "upstream unreachable", http.StatusBadGateway)
+		return
+	}
+	upstream, err := net.DialTimeout("tcp", addr, bypassDialTimeout)
+	if err != nil {
+		p.bypass.failed.Add(1)
+		p.logVerbose("BYPASS %s dial failed: %v", r.Host, err)
+		http.Error(w, "upstream unreachable", http.StatusBadGateway)
+		return
+	}
+	defer upstream.Close()
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "hijack not supported", http.StatusInternalServerError)
+		return
+	}
+	conn, buf, err := hj.Hijack()
+	if err != nil {
+		p.logVerbose("hijack error: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	p.bypass.tunnels.Add(1)
+	p.bypass.active.Add(1)
+	defer p.bypass.active.Add(-1)
+	p.logVerbose("BYPASS %s", r.Host)
+
+	conn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"))
+
+	// Bytes the client sent after the CONNECT (a pipelined ClientHello) are
+	// already in buf.
+	var client io.Reader = conn
+	if n := buf.Reader.Buffered(); n > 0 {
+		client = io.MultiReader(io.LimitReader(buf.Reader, int64(n)), conn)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		n, _ := io.Copy(upstream, client)
+		p.bypass.bytesOut.Add(n)
+		closeWrite(upstream)
+	}()
+	go func() {
+		defer wg.Done()
+		n, _ := io.Copy(conn, upstream)
+		p.bypass.bytesIn.Add(n)
+		closeWrite(conn)
+	}()
+	wg.Wait()
+}
+
+// closeWrite half-closes c so the peer sees EOF while replies can still
+// arrive; connections that can't half-close are closed outright.
+func closeWrite(c net.Conn) {
+	if hc, ok := c.(interface{ CloseWrite() error }); ok {
+		if hc.CloseWrite() == nil {
+			return
+		}
+	}
+	c.Close()
+}
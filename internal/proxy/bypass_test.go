@@ -0,0 +1,115 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInterceptListMatch(t *testing.T) {
+	l := newInterceptList([]string{"api.anthropic.com", " *.Example.com ", "", "[::1]", "FD00::5"})
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"api.anthropic.com", true},
+		{"API.Anthropic.com.", true},
+		{"statsig.anthropic.com", false},
+		{"foo.example.com", true},
+		{"a.b.example.com", true},
+		{"example.com", false},
+		{"notexample.com", false},
+		{"github.com", false},
+		{"::1", true}, // CONNECT [::1]:443 splits to ::1
+		{"fd00::5", true},
+		{"fd00::6", false},
+	}
+	for _, tt := range tests {
+		if got := l.match(tt.host); got != tt.want {
+			t.Errorf("match(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+
+	if !newInterceptList([]string{"*"}).match("anything.test") {
+		t.Error(`"*" should intercept every host`)
+	}
+}
+
+func TestDefaultInterceptList(t *testing.T) {
+	p := New(nil)
+	if !p.intercept.match("api.anthropic.com") {
+		t.Error("default list should intercept api.anthropic.com")
+	}
+	if p.intercept.match("registry.npmjs.org") {
+		t.Error("default list should not intercept other hosts")
+	}
+
+	p = New(nil, WithIntercept(nil))
+	if !p.intercept.match("api.anthropic.com") {
+		t.Error("empty WithIntercept should keep the default")
+	}
+}
+
+func TestBypassTunnelsWithoutMITM(t *testing.T) {
+	// Intercept only the API host, so the localhost echo server is bypassed.
+	infra := setupInfraWithOptions(t, nil, WithIntercept([]string{"api.anthropic.com"}))
+
+	conn, err := net.Dial("tcp", infra.proxyAddr)
+	if err != nil {
+		t.Fatalf("connect to proxy: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "CONNECT localhost:%d HTTP/1.1\r\nHost: localhost\r\n\r\n", infra.upstreamPort)
+	buf := make([]byte, 4096)
+	n, _ := conn.Read(buf)
+	if !strings.Contains(string(buf[:n]), "200") {
+		t.Fatalf("CONNECT failed: %s", buf[:n])
+	}
+
+	tlsConn := tls.Client(conn, &tls.Config{InsecureSkipVerify: true, ServerName: "localhost"})
+	if err := tlsConn.Handshake(); err != nil {
+		t.Fatalf("TLS handshake: %v", err)
+	}
+
+	// The server certificate must be the upstream's, not one minted by the MITM CA.
+	mitmPool := x509.NewCertPool()
+	mitmPool.AppendCertsFromPEM(infra.mitmCACert)
+	leaf := tlsConn.ConnectionState().PeerCertificates[0]
+	if _, err := leaf.Verify(x509.VerifyOptions{Roots: mitmPool, DNSName: "localhost"}); err == nil {
+		t.Fatal("bypassed host was served a MITM certificate")
+	}
+
+	fmt.Fprintf(tlsConn, "GET /v1/models HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n")
+	resp, _ := io.ReadAll(tlsConn)
+	if !strings.HasPrefix(string(resp), "HTTP/1.1 200") {
+		t.Fatalf("unexpected response through tunnel: %q", resp)
+	}
+
+	if m := infra.proxy.Metrics(); m.Bypass.Tunnels != 1 {
+		t.Errorf("bypass tunnels = %d, want 1", m.Bypass.Tunnels)
+	}
+}
+
+func TestBypassUpstreamUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	p := New(nil)
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest("CONNECT", addr, nil))
+	if rec.Code != 502 {
+		t.Fatalf("expected 502, got %d", rec.Code)
+	}
+	if m := p.Metrics(); m.Bypass.Failed != 1 || m.Bypass.Tunnels != 0 {
+		t.Errorf("bypass metrics = %+v", m.Bypass)
+	}
+}
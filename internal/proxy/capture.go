@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// exchangeCapture records one tunnel request and the bytes written back
+// for it, for the HAR log and Middleware.OnResponse.
+type exchangeCapture struct {
+	req       *http.Request
+	url       string
+	started   time.Time
+	firstByte time.Time
+	reqBody   capBuffer
+	resp      capBuffer
+}
+
+// newExchangeCapture begins recording req, sent to origin
+// ("https://host[:port]"). The request body is captured as the handler
+// reads it; each side keeps at most limit bytes.
+func newExchangeCapture(req *http.Request, origin string, limit int64) *exchangeCapture {
+	c := &exchangeCapture{
+		req:     req,
+		url:     origin + req.URL.RequestURI(),
+		started: time.Now(),
+		reqBody: capBuffer{limit: limit},
+		resp:    capBuffer{limit: limit},
+	}
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.TeeReader(req.Body, &c.reqBody), req.Body}
+	return c
+}
+
+// wrap returns conn with its writes captured as the response.
+func (c *exchangeCapture) wrap(conn net.Conn) net.Conn {
+	return &captureConn{Conn: conn, c: c}
+}
+
+type captureConn struct {
+	net.Conn
+	c *exchangeCapture
+}
+
+func (cc *captureConn) Write(b []byte) (int, error) {
+	if cc.c.firstByte.IsZero() {
+		cc.c.firstByte = time.Now()
+	}
+	n, err := cc.Conn.Write(b)
+	cc.c.resp.Write(b[:n])
+	return n, err
+}
+
+// capturedResponse is a response parsed back from the bytes written.
+type capturedResponse struct {
+	resp      *http.Response // status line and headers as sent; nil when none was
+	body      []byte         // without chunked framing or a decodeBody encoding
+	truncated bool           // over the capture limit, or cut off
+}
+
+// response parses the captured bytes, skipping interim 1xx responses.
+func (c *exchangeCapture) response() capturedResponse {
+	out := capturedResponse{truncated: c.resp.truncated()}
+	br := bufio.NewReader(bytes.NewReader(c.resp.buf.Bytes()))
+	for {
+		resp, err := http.ReadResponse(br, c.req)
+		if err != nil {
+			return out
+		}
+		if resp.StatusCode >= 200 || resp.StatusCode == http.StatusSwitchingProtocols {
+			out.resp = resp
+			break
+		}
+	}
+	header := out.resp.Header.Clone()
+	decodeBody(out.resp) // a body in an encoding the proxy can't decode is kept as sent
+	data, err := io.ReadAll(out.resp.Body)
+	out.resp.Header = header
+	out.body = data
+	if err != nil {
+		out.truncated = true
+	}
+	return out
+}
+
+// capBuffer keeps the first limit bytes written to it and counts the rest.
+type capBuffer struct {
+	buf   bytes.Buffer
+	limit int64
+	n     int64
+}
+
+func (b *capBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - int64(b.buf.Len()); room > 0 {
+		b.buf.Write(p[:min(int64(len(p)), room)])
+	}
+	b.n += int64(len(p))
+	return len(p), nil
+}
+
+func (b *capBuffer) truncated() bool {
+	return b.n > int64(b.buf.Len())
+}
@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/tokenizer"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/translate"
+)
+
+// isCountTokens reports whether path is the Messages token counting endpoint.
+func isCountTokens(path string) bool {
+	return strings.HasSuffix(path, "/messages/count_tokens")
+}
+
+// tokenizerFor returns the tokenizer configured for m, built once per
+// label and spec. A tokenizer that can't be built (say, a missing tiktoken
+// file) is logged and replaced by the heuristic.
+func (p *Proxy) tokenizerFor(m config.ResolvedModel) tokenizer.Tokenizer {
+	key := m.Label + "\x00" + m.Tokenizer
+	if t, ok := p.tokenizers.Load(key); ok {
+		return t.(tokenizer.Tokenizer)
+	}
+	apiKey, err := m.Key()
+	if err != nil {
+		log.Printf("[TOKENIZER] %s: %v", m.Label, err)
+	}
+	t, err := tokenizer.New(m.Tokenizer, m.Endpoint, apiKey, m.Model)
+	if err != nil {
+		log.Printf("[TOKENIZER] %s: %v — using heuristic", m.Label, err)
+		t = tokenizer.Heuristic{}
+	}
+	actual, _ := p.tokenizers.LoadOrStore(key, t)
+	return actual.(tokenizer.Tokenizer)
+}
+
+// countTokensLocal answers a count_tokens request for a locally routed label
+// with the label's tokenizer, instead of sending it to the backend as a chat
+// completion.
+func (p *Proxy) countTokensLocal(w io.Writer, rr routeRequest) {
+	label := rr.Route.Model
+	ann := p.annotate(w, "count_tokens", label)
+	w = ann
+	var tok tokenizer.Tokenizer = tokenizer.Heuristic{}
+	if p.modelResolver != nil {
+		m, err := p.modelResolver.Resolve(p.routeTarget(label, rr.Body))
+		if err != nil {
+			sendAnthropicError(w, 400, translate.FormatError("invalid_request_error",
+				fmt.Sprintf("Unknown model label %q — check ~/.claude-hybrid/config.yaml", label)))
+			return
+		}
+		ann.setTarget(m)
+		tok = p.tokenizerFor(m)
+	}
+
+	n, err := tokenizer.EstimateMessages(tok, rr.Body)
+	if err != nil {
+		sendAnthropicError(w, 400, translate.FormatError("invalid_request_error", err.Error()))
+		return
+	}
+	log.Printf("LOCAL_COUNT %s → %d input tokens (%s)", label, n, tok.Name())
+	body, _ := json.Marshal(map[string]int{"input_tokens": n})
+	fmt.Fprintf(w, "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: %d\r\n\r\n", len(body))
+	w.Write(body)
+}
@@ -0,0 +1,193 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+const (
+	defaultDedupeTTL       = 30 * time.Second
+	defaultDedupeMaxTokens = 512
+)
+
+// dedupeCache stores translated local responses for background-class requests
+// (no tools, small max_tokens — e.g. Claude Code's title and topic
+// generation) in a directory shared by all claude-hybrid instances, so
+// identical requests fired by concurrent sessions hit the GPU only once.
+// Within one instance, a request identical to one still in flight waits for
+// its response instead of sending its own; across instances, requests reuse
+// a response once it is stored.
+type dedupeCache struct {
+	dir       string
+	ttl       time.Duration
+	maxTokens int
+
+	mu      sync.Mutex
+	pending map[string]*dedupeCall // by key, requests in flight
+}
+
+// dedupeCall is a request in flight that identical requests wait on.
+type dedupeCall struct {
+	done   chan struct{} // closed when the leader finishes
+	entry  dedupeEntry
+	stored time.Time
+	ok     bool // entry holds the leader's response
+}
+
+type dedupeEntry struct {
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+func newDedupeCache(dir string, cfg *config.DedupeConfig) *dedupeCache {
+	c := &dedupeCache{dir: dir, ttl: cfg.TTL, maxTokens: cfg.MaxTokens, pending: make(map[string]*dedupeCall)}
+	if c.ttl <= 0 {
+		c.ttl = defaultDedupeTTL
+	}
+	if c.maxTokens <= 0 {
+		c.maxTokens = defaultDedupeMaxTokens
+	}
+	return c
+}
+
+// key returns the cache key for a request, or "" if it is not background-class.
+func (c *dedupeCache) key(label string, body []byte) string {
+	var req struct {
+		MaxTokens int               `json:"max_tokens"`
+		Tools     []json.RawMessage `json:"tools"`
+	}
+	if json.Unmarshal(body, &req) != nil {
+		return ""
+	}
+	if len(req.Tools) > 0 || req.MaxTokens <= 0 || req.MaxTokens > c.maxTokens {
+		return ""
+	}
+	canonical, err := dedupeCanonical(body)
+	if err != nil {
+		return ""
+	}
+	h := sha256.New()
+	io.WriteString(h, label)
+	h.Write([]byte{0})
+	h.Write(canonical)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// dedupeSessionFields are top-level request fields that differ between
+// sessions sending the same request. Claude Code puts its session in
+// metadata.user_id.
+var dedupeSessionFields = []string{"metadata"}
+
+// dedupeCanonical returns body without its per-session fields, re-marshalled
+// with sorted keys so that field order and spacing don't change the key.
+func dedupeCanonical(body []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var req map[string]interface{}
+	if err := dec.Decode(&req); err != nil {
+		return nil, err
+	}
+	for _, f := range dedupeSessionFields {
+		delete(req, f)
+	}
+	return json.Marshal(req)
+}
+
+// lookup returns a response for key: one stored by any instance within the
+// TTL, or that of an identical request in flight here, once it finishes.
+// On a miss the caller leads: it must call done after storing its response
+// with put, or after failing, to release the requests waiting on it. A
+// request whose leader failed gets a miss with a no-op done and goes to the
+// backend itself.
+func (c *dedupeCache) lookup(key string) (e dedupeEntry, age time.Duration, hit bool, done func()) {
+	c.mu.Lock()
+	if call, ok := c.pending[key]; ok {
+		c.mu.Unlock()
+		<-call.done
+		if !call.ok {
+			return dedupeEntry{}, 0, false, func() {}
+		}
+		return call.entry, time.Since(call.stored), true, nil
+	}
+	call := &dedupeCall{done: make(chan struct{})}
+	c.pending[key] = call
+	c.mu.Unlock()
+
+	done = func() {
+		c.mu.Lock()
+		delete(c.pending, key)
+		c.mu.Unlock()
+		close(call.done)
+	}
+	if e, age, ok := c.get(key); ok {
+		call.entry, call.stored, call.ok = e, time.Now().Add(-age), true
+		done()
+		return e, age, true, nil
+	}
+	return dedupeEntry{}, 0, false, done
+}
+
+// get returns a cached response younger than the TTL.
+func (c *dedupeCache) get(key string) (dedupeEntry, time.Duration, bool) {
+	path := filepath.Join(c.dir, key+".json")
+	info, err := os.Stat(path)
+	if err != nil {
+		return dedupeEntry{}, 0, false
+	}
+	age := time.Since(info.ModTime())
+	if age >= c.ttl {
+		os.Remove(path)
+		return dedupeEntry{}, 0, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return dedupeEntry{}, 0, false
+	}
+	var e dedupeEntry
+	if json.Unmarshal(data, &e) != nil {
+		return dedupeEntry{}, 0, false
+	}
+	return e, age, true
+}
+
+// put stores a response and hands it to the requests waiting on key. The
+// write goes through a temp file and rename so concurrent instances never
+// read a partial entry.
+func (c *dedupeCache) put(key, contentType string, body []byte) error {
+	c.mu.Lock()
+	if call := c.pending[key]; call != nil {
+		call.entry, call.stored, call.ok = dedupeEntry{ContentType: contentType, Body: body}, time.Now(), true
+	}
+	c.mu.Unlock()
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return err
+	}
+	data, _ := json.Marshal(dedupeEntry{ContentType: contentType, Body: body})
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	tmp.Close()
+	return os.Rename(tmp.Name(), filepath.Join(c.dir, key+".json"))
+}
+
+// writeDedupeHit replays a cached response to the client.
+func writeDedupeHit(w io.Writer, e dedupeEntry) {
+	fmt.Fprintf(w, "HTTP/1.1 200 OK\r\nContent-Type: %s\r\nContent-Length: %d\r\n\r\n", e.ContentType, len(e.Body))
+	w.Write(e.Body)
+}
@@ -0,0 +1,198 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+// countingMockOpenAI starts a mock provider that counts chat completion
+// calls and answers each after delay.
+func countingMockOpenAI(t *testing.T, delay time.Duration) (port int, calls *int32) {
+	t.Helper()
+	calls = new(int32)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		time.Sleep(delay)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id": "chatcmpl-count",
+			"choices": []map[string]interface{}{{
+				"message":       map[string]interface{}{"role": "assistant", "content": "Fix login bug"},
+				"finish_reason": "stop",
+			}},
+		})
+	})
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+	return ln.Addr().(*net.TCPAddr).Port, calls
+}
+
+func dedupeResolver(t *testing.T, port int) *config.ModelResolver {
+	t.Helper()
+	resolver, err := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "mock",
+			Endpoint: fmt.Sprintf("http://127.0.0.1:%d/v1", port),
+			Models:   map[string]config.ModelConfig{"bg": {Model: "small"}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("resolver: %v", err)
+	}
+	return resolver
+}
+
+func TestDedupeReusesBackgroundResponse(t *testing.T) {
+	port, calls := countingMockOpenAI(t, 0)
+	dir := t.TempDir()
+	cfg := &config.DedupeConfig{TTL: time.Minute}
+
+	// Two separate proxies sharing one cache dir stand in for two sessions.
+	infraA := setupInfraWithOptions(t, dedupeResolver(t, port), WithDedupe(dir, cfg))
+	infraB := setupInfraWithOptions(t, dedupeResolver(t, port), WithDedupe(dir, cfg))
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":      "claude-haiku",
+		"system":     "<!-- @proxy-local-route:af83e9 model=bg --> Generate a title",
+		"messages":   []map[string]string{{"role": "user", "content": "fix the login bug"}},
+		"max_tokens": 100,
+	})
+
+	statusA, respA, _ := proxyRequest(t, infraA, "POST", "/v1/messages", body, nil)
+	statusB, respB, _ := proxyRequest(t, infraB, "POST", "/v1/messages", body, nil)
+	if statusA != 200 || statusB != 200 {
+		t.Fatalf("expected 200s, got %d and %d", statusA, statusB)
+	}
+	if respA != respB {
+		t.Errorf("expected identical responses:\n%s\n%s", respA, respB)
+	}
+	if n := atomic.LoadInt32(calls); n != 1 {
+		t.Errorf("expected provider to be called once, got %d", n)
+	}
+}
+
+func TestDedupeCoalescesConcurrentRequests(t *testing.T) {
+	port, calls := countingMockOpenAI(t, 300*time.Millisecond)
+	infra := setupInfraWithOptions(t, dedupeResolver(t, port), WithDedupe(t.TempDir(), &config.DedupeConfig{TTL: time.Minute}))
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":      "claude-haiku",
+		"system":     "<!-- @proxy-local-route:af83e9 model=bg --> Generate a title",
+		"messages":   []map[string]string{{"role": "user", "content": "fix the login bug"}},
+		"max_tokens": 100,
+	})
+	const n = 5
+	var wg sync.WaitGroup
+	resps := make([]string, n)
+	statuses := make([]int, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i], resps[i], _ = proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+		}()
+	}
+	wg.Wait()
+	for i := range n {
+		if statuses[i] != 200 || resps[i] != resps[0] {
+			t.Errorf("request %d: %d %s; want 200 %s", i, statuses[i], resps[i], resps[0])
+		}
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("expected 1 provider call for %d identical requests in flight, got %d", n, got)
+	}
+}
+
+func TestDedupeIgnoresSessionMetadata(t *testing.T) {
+	port, calls := countingMockOpenAI(t, 0)
+	dir := t.TempDir()
+	cfg := &config.DedupeConfig{TTL: time.Minute}
+	infraA := setupInfraWithOptions(t, dedupeResolver(t, port), WithDedupe(dir, cfg))
+	infraB := setupInfraWithOptions(t, dedupeResolver(t, port), WithDedupe(dir, cfg))
+
+	request := func(session string) []byte {
+		body, _ := json.Marshal(map[string]interface{}{
+			"model":      "claude-haiku",
+			"system":     "<!-- @proxy-local-route:af83e9 model=bg --> Generate a title",
+			"messages":   []map[string]string{{"role": "user", "content": "fix the login bug"}},
+			"max_tokens": 100,
+			"metadata":   map[string]string{"user_id": "user_abc_account__session_" + session},
+		})
+		return body
+	}
+	statusA, respA, _ := proxyRequest(t, infraA, "POST", "/v1/messages", request("1111"), nil)
+	statusB, respB, _ := proxyRequest(t, infraB, "POST", "/v1/messages", request("2222"), nil)
+	if statusA != 200 || statusB != 200 {
+		t.Fatalf("expected 200s, got %d and %d", statusA, statusB)
+	}
+	if respA != respB {
+		t.Errorf("expected identical responses:\n%s\n%s", respA, respB)
+	}
+	if n := atomic.LoadInt32(calls); n != 1 {
+		t.Errorf("requests differing only in metadata.user_id should share a cache entry; provider called %d times", n)
+	}
+}
+
+func TestDedupeSkipsToolRequests(t *testing.T) {
+	port, calls := countingMockOpenAI(t, 0)
+	infra := setupInfraWithOptions(t, dedupeResolver(t, port),
+		WithDedupe(t.TempDir(), &config.DedupeConfig{TTL: time.Minute}))
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":      "claude-haiku",
+		"system":     "<!-- @proxy-local-route:af83e9 model=bg -->",
+		"messages":   []map[string]string{{"role": "user", "content": "hi"}},
+		"max_tokens": 100,
+		"tools":      []map[string]interface{}{{"name": "Read", "input_schema": map[string]string{"type": "object"}}},
+	})
+	proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+	proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+
+	if n := atomic.LoadInt32(calls); n != 2 {
+		t.Errorf("tool requests must not be deduped; expected 2 calls, got %d", n)
+	}
+}
+
+func TestDedupeKeyRespectsMaxTokens(t *testing.T) {
+	c := newDedupeCache(t.TempDir(), &config.DedupeConfig{MaxTokens: 256})
+	if c.key("bg", []byte(`{"max_tokens":4096,"messages":[]}`)) != "" {
+		t.Error("large max_tokens should not be background-class")
+	}
+	if c.key("bg", []byte(`{"max_tokens":128,"messages":[]}`)) == "" {
+		t.Error("small max_tokens should be background-class")
+	}
+	if c.key("a", []byte(`{"max_tokens":128}`)) == c.key("b", []byte(`{"max_tokens":128}`)) {
+		t.Error("keys must differ by label")
+	}
+	if c.key("bg", []byte(`{"max_tokens":128,"messages":[]}`)) != c.key("bg", []byte(`{ "messages": [], "max_tokens": 128 }`)) {
+		t.Error("keys must not depend on field order or spacing")
+	}
+}
+
+func TestDedupeEntryExpires(t *testing.T) {
+	c := newDedupeCache(t.TempDir(), &config.DedupeConfig{TTL: 10 * time.Millisecond})
+	if err := c.put("k", "application/json", []byte(`{}`)); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if _, _, ok := c.get("k"); !ok {
+		t.Fatal("expected fresh entry")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, _, ok := c.get("k"); ok {
+		t.Error("expected entry to expire")
+	}
+}
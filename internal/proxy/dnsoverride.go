@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WithDNSOverrides sends connections for some hosts to other addresses, as
+// a hosts file scoped to the proxy would. m is config.ParseDNSOverrides'
+// output. TLS is still checked against the original host name and requests
+// keep its Host header. An "http://host:port" address is spoken to in plain
+// HTTP, for gateways such as LiteLLM that serve no TLS; that only works for
+// intercepted hosts, since a blind tunnel carries the client's TLS.
+func WithDNSOverrides(m map[string]string) Option {
+	return func(p *Proxy) { p.dnsOverrides = m }
+}
+
+// overrideAddr returns the address to dial for addr (host:port), and
+// whether it takes plain HTTP. Hosts without an override keep addr.
+func (p *Proxy) overrideAddr(addr string) (string, bool) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr, false
+	}
+	to, ok := p.dnsOverrides[strings.ToLower(strings.TrimSuffix(host, "."))]
+	if !ok {
+		return addr, false
+	}
+	if rest, plain := strings.CutPrefix(to, "http://"); plain {
+		return rest, true
+	}
+	if _, _, err := net.SplitHostPort(to); err == nil {
+		return to, false
+	}
+	return net.JoinHostPort(to, port), false
+}
+
+// overrideDial clones base to dial overridden hosts at their new address.
+// Only the dial changes, so certificates are verified as if DNS had
+// answered. Round trippers other than *http.Transport are returned as is.
+func (p *Proxy) overrideDial(base http.RoundTripper) http.RoundTripper {
+	ht, ok := base.(*http.Transport)
+	if !ok {
+		return base
+	}
+	ht = ht.Clone()
+	dial := ht.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	}
+	ht.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		addr, _ = p.overrideAddr(addr)
+		return dial(ctx, network, addr)
+	}
+	return ht
+}
@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/testutil"
+)
+
+func TestOverrideAddr(t *testing.T) {
+	p := New(nil, WithDNSOverrides(map[string]string{
+		"api.anthropic.com": "127.0.0.1:8080",
+		"bare.test":         "10.0.0.2",
+		"gw.test":           "http://127.0.0.1:4000",
+		"::1":               "fd00::1",
+	}))
+	tests := []struct {
+		addr, want string
+		plain      bool
+	}{
+		{"api.anthropic.com:443", "127.0.0.1:8080", false},
+		{"API.Anthropic.com.:443", "127.0.0.1:8080", false},
+		{"bare.test:8443", "10.0.0.2:8443", false},
+		{"gw.test:443", "127.0.0.1:4000", true},
+		{"[::1]:443", "[fd00::1]:443", false},
+		{"other.test:443", "other.test:443", false},
+	}
+	for _, tt := range tests {
+		got, plain := p.overrideAddr(tt.addr)
+		if got != tt.want || plain != tt.plain {
+			t.Errorf("overrideAddr(%q) = %q, %v; want %q, %v", tt.addr, got, plain, tt.want, tt.plain)
+		}
+	}
+}
+
+func TestDNSOverrideUpstream(t *testing.T) {
+	// The upstream's certificate is for localhost, so the client skips
+	// verification; what matters is where the proxy dials.
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	infra := setupInfraWithOptions(t, nil,
+		WithHTTPClient(client),
+		WithIntercept([]string{"api.example.test"}),
+		WithDNSOverrides(map[string]string{"api.example.test": "localhost"}))
+
+	conn := dialTunnelTo(t, infra, "api.example.test", strconv.Itoa(infra.upstreamPort))
+	defer conn.Close()
+	fmt.Fprintf(conn, "GET /v1/models HTTP/1.1\r\nHost: api.example.test\r\nConnection: close\r\n\r\n")
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	defer resp.Body.Close()
+	var echo testutil.EchoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&echo); err != nil || resp.StatusCode != 200 {
+		t.Fatalf("status %d, decode: %v", resp.StatusCode, err)
+	}
+	if echo.Path != "/v1/models" {
+		t.Errorf("upstream path = %q", echo.Path)
+	}
+}
+
+func TestDNSOverridePlainHTTP(t *testing.T) {
+	var gotHost string
+	gw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost = r.Host
+		io.WriteString(w, `{"gateway":true}`)
+	}))
+	t.Cleanup(gw.Close)
+	infra := setupInfraWithOptions(t, nil,
+		WithDNSOverrides(map[string]string{"localhost": "http://" + gw.Listener.Addr().String()}))
+
+	status, body, _ := proxyRequest(t, infra, "GET", "/v1/models", nil, nil)
+	if status != 200 || body != `{"gateway":true}` {
+		t.Fatalf("got %d %q, want the gateway's answer", status, body)
+	}
+	if want := net.JoinHostPort("localhost", strconv.Itoa(infra.upstreamPort)); gotHost != want {
+		t.Errorf("gateway saw Host %q, want %q", gotHost, want)
+	}
+}
+
+func TestDNSOverrideBypass(t *testing.T) {
+	infra := setupInfraWithOptions(t, nil,
+		WithIntercept([]string{"api.anthropic.com"}),
+		WithDNSOverrides(map[string]string{
+			"blind.example.test": "localhost",
+			"plain.example.test": "http://127.0.0.1:1",
+		}))
+
+	conn, err := net.Dial("tcp", infra.proxyAddr)
+	if err != nil {
+		t.Fatalf("connect to proxy: %v", err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "CONNECT blind.example.test:%d HTTP/1.1\r\nHost: blind.example.test\r\n\r\n", infra.upstreamPort)
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatalf("CONNECT: %v %v", resp, err)
+	}
+	tlsConn := tls.Client(conn, &tls.Config{InsecureSkipVerify: true, ServerName: "localhost"})
+	if err := tlsConn.Handshake(); err != nil {
+		t.Fatalf("TLS handshake through overridden tunnel: %v", err)
+	}
+
+	// A plain-HTTP address can't carry the client's TLS.
+	rec := httptest.NewRecorder()
+	infra.proxy.ServeHTTP(rec, httptest.NewRequest("CONNECT", "plain.example.test:443", nil))
+	if rec.Code != 502 || !strings.Contains(rec.Body.String(), "unreachable") {
+		t.Errorf("plain override on a blind tunnel: %d %q", rec.Code, rec.Body)
+	}
+}
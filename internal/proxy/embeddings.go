@@ -0,0 +1,289 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/tokenizer"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/translate"
+)
+
+// embedRequest is an OpenAI embeddings request. Voyage AI, which Anthropic
+// recommends for embeddings, uses the same shape; its input_type is
+// accepted and ignored.
+type embedRequest struct {
+	Model          string          `json:"model"`
+	Input          json.RawMessage `json:"input"`
+	EncodingFormat string          `json:"encoding_format"` // float (default) or base64
+	Dimensions     int             `json:"dimensions"`
+}
+
+// embedError is a failed embeddings request, reported in OpenAI's format.
+type embedError struct {
+	status int
+	typ    string
+	msg    string
+}
+
+// isEmbeddings reports whether an intercepted path is an embeddings call.
+func isEmbeddings(path string) bool {
+	return strings.HasSuffix(path, "/embeddings")
+}
+
+// embeddingLabel returns the embedding label an intercepted embeddings
+// request names, if it is one, so it is answered locally rather than
+// forwarded.
+func (p *Proxy) embeddingLabel(body []byte) (string, bool) {
+	if p.modelResolver == nil {
+		return "", false
+	}
+	var req embedRequest
+	if json.Unmarshal(body, &req) != nil {
+		return "", false
+	}
+	if _, err := p.modelResolver.Embedding(req.Model); err != nil {
+		return "", false
+	}
+	return req.Model, true
+}
+
+// embeddingsLocal answers an intercepted embeddings request for an
+// embedding label.
+func (p *Proxy) embeddingsLocal(w io.Writer, body []byte) {
+	out, eerr := p.embed(body)
+	if eerr != nil {
+		msg := translate.FormatOpenAIError(eerr.typ, eerr.msg)
+		fmt.Fprintf(w, "HTTP/1.1 %d Error\r\nContent-Type: application/json\r\nContent-Length: %d\r\nConnection: close\r\n\r\n",
+			eerr.status, len(msg))
+		w.Write(msg)
+		return
+	}
+	fmt.Fprintf(w, "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: %d\r\n\r\n", len(out))
+	w.Write(out)
+}
+
+func (p *Proxy) handleOpenAIEmbeddings(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, p.limits.MaxBodyBytes+1))
+	if err != nil {
+		sendOpenAIError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("read body: %v", err))
+		return
+	}
+	if int64(len(body)) > p.limits.MaxBodyBytes {
+		sendOpenAIError(w, http.StatusRequestEntityTooLarge, "invalid_request_error", "request body too large")
+		return
+	}
+	out, eerr := p.embed(body)
+	if eerr != nil {
+		sendOpenAIError(w, eerr.status, eerr.typ, eerr.msg)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
+// embed answers an OpenAI embeddings request from the label's backend,
+// sending the inputs in batches of the label's batch_size and returning an
+// OpenAI embeddings response.
+func (p *Proxy) embed(body []byte) ([]byte, *embedError) {
+	var req embedRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, &embedError{http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("parse request: %v", err)}
+	}
+	if p.modelResolver == nil {
+		return nil, &embedError{http.StatusServiceUnavailable, "api_error", "no providers configured — create ~/.claude-hybrid/config.yaml"}
+	}
+	m, err := p.modelResolver.Embedding(req.Model)
+	if err != nil {
+		return nil, &embedError{http.StatusNotFound, "invalid_request_error", fmt.Sprintf("unknown embedding model label %q", req.Model)}
+	}
+	inputs, err := parseEmbedInput(req.Input)
+	if err != nil {
+		return nil, &embedError{http.StatusBadRequest, "invalid_request_error", err.Error()}
+	}
+	if req.Dimensions > 0 && m.Format != config.EmbedOpenAI {
+		return nil, &embedError{http.StatusBadRequest, "invalid_request_error",
+			fmt.Sprintf("dimensions is only supported by format: %s backends; %q is %s", config.EmbedOpenAI, m.Label, m.Format)}
+	}
+	switch req.EncodingFormat {
+	case "", "float", "base64":
+	default:
+		return nil, &embedError{http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("unknown encoding_format %q", req.EncodingFormat)}
+	}
+
+	release, err := p.hosts.acquire(m.ResolvedModel)
+	if err != nil {
+		log.Printf("[LOCAL_ERR:CAPACITY] %s refused: %v", m.Label, err)
+		return nil, &embedError{http.StatusServiceUnavailable, "server_error", fmt.Sprintf("model %q unavailable: %v", m.Label, err)}
+	}
+	defer release()
+
+	start := time.Now()
+	var vectors [][]float64
+	tokens, batches := 0, 0
+	for i := 0; i < len(inputs); i += m.BatchSize {
+		batch := inputs[i:min(i+m.BatchSize, len(inputs))]
+		vecs, n, err := p.embedBatch(m, batch, req.Dimensions)
+		if err != nil {
+			log.Printf("[LOCAL_ERR:EMBED] %s: batch %d: %v", m.Label, batches+1, err)
+			return nil, &embedError{http.StatusBadGateway, "api_error", fmt.Sprintf("embedding model '%s': %v", m.Label, err)}
+		}
+		if len(vecs) != len(batch) {
+			log.Printf("[LOCAL_ERR:EMBED] %s: %d vectors for %d inputs", m.Label, len(vecs), len(batch))
+			return nil, &embedError{http.StatusBadGateway, "api_error",
+				fmt.Sprintf("embedding model '%s' returned %d vectors for %d inputs", m.Label, len(vecs), len(batch))}
+		}
+		vectors = append(vectors, vecs...)
+		tokens += n
+		batches++
+	}
+	log.Printf("LOCAL_EMBED %s → %s/%s (%d inputs, %d batches, %dms)",
+		m.Label, m.Provider, m.Model, len(inputs), batches, time.Since(start).Milliseconds())
+
+	data := make([]map[string]interface{}, len(vectors))
+	for i, v := range vectors {
+		var emb interface{} = v
+		if req.EncodingFormat == "base64" {
+			emb = encodeEmbedding(v)
+		}
+		data[i] = map[string]interface{}{"object": "embedding", "index": i, "embedding": emb}
+	}
+	out, _ := json.Marshal(map[string]interface{}{
+		"object": "list",
+		"data":   data,
+		"model":  m.Label,
+		"usage":  map[string]int{"prompt_tokens": tokens, "total_tokens": tokens},
+	})
+	return out, nil
+}
+
+// parseEmbedInput accepts a string or a list of strings. Pre-tokenized
+// input (lists of token IDs) is rejected, since local servers tokenize
+// with their own vocabulary.
+func parseEmbedInput(raw json.RawMessage) ([]string, error) {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return []string{s}, nil
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("input must be a string or a list of strings")
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("input is empty")
+	}
+	return list, nil
+}
+
+// embedBatch sends one batch to the backend in its format and returns the
+// vectors in input order with the tokens used. TEI doesn't report tokens,
+// so they are estimated.
+func (p *Proxy) embedBatch(m config.ResolvedEmbedding, batch []string, dimensions int) ([][]float64, int, error) {
+	var url string
+	var payload interface{}
+	switch m.Format {
+	case config.EmbedOllama:
+		url = ollamaBaseURL(m.Endpoint) + "/api/embed"
+		req := map[string]interface{}{"model": m.Model, "input": batch}
+		if m.KeepAlive != "" {
+			req["keep_alive"] = m.KeepAlive
+		}
+		payload = req
+	case config.EmbedTEI:
+		// TEI's native routes are at the server root, like Ollama's.
+		url = ollamaBaseURL(m.Endpoint) + "/embed"
+		payload = map[string]interface{}{"inputs": batch, "truncate": true}
+	default:
+		url = m.Endpoint + "/embeddings"
+		req := map[string]interface{}{"model": m.Model, "input": batch}
+		if dimensions > 0 {
+			req["dimensions"] = dimensions
+		}
+		payload = req
+	}
+	body, _ := json.Marshal(payload)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := setProviderHeaders(req, m.ResolvedModel); err != nil {
+		return nil, 0, fmt.Errorf("[SECRET] %w", err)
+	}
+	resp, err := p.pools.get(m.ResolvedModel).do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("[%s] unreachable: %v", translate.ClassifyError(err), err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusUnauthorized && m.Secret != nil {
+			m.Secret.Invalidate()
+		}
+		return nil, 0, fmt.Errorf("HTTP %d: %s", resp.StatusCode, sanitizeForLog(clip(string(data), 200)))
+	}
+
+	switch m.Format {
+	case config.EmbedOllama:
+		var out struct {
+			Embeddings      [][]float64 `json:"embeddings"`
+			PromptEvalCount int         `json:"prompt_eval_count"`
+		}
+		if err := json.Unmarshal(data, &out); err != nil {
+			return nil, 0, fmt.Errorf("parse response: %v", err)
+		}
+		return out.Embeddings, out.PromptEvalCount, nil
+	case config.EmbedTEI:
+		var out [][]float64
+		if err := json.Unmarshal(data, &out); err != nil {
+			return nil, 0, fmt.Errorf("parse response: %v", err)
+		}
+		tokens := 0
+		for _, s := range batch {
+			tokens += tokenizer.Heuristic{}.Count(s)
+		}
+		return out, tokens, nil
+	}
+	var out struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+		Usage struct {
+			PromptTokens int `json:"prompt_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, 0, fmt.Errorf("parse response: %v", err)
+	}
+	vecs := make([][]float64, len(out.Data))
+	for _, d := range out.Data {
+		if d.Index < 0 || d.Index >= len(vecs) {
+			return nil, 0, fmt.Errorf("response index %d out of range", d.Index)
+		}
+		vecs[d.Index] = d.Embedding
+	}
+	return vecs, out.Usage.PromptTokens, nil
+}
+
+// encodeEmbedding returns v as base64 little-endian float32s, as OpenAI
+// does for encoding_format: base64.
+func encodeEmbedding(v []float64) string {
+	buf := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(float32(f)))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
@@ -0,0 +1,132 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+// mockEmbedder serves Ollama's /api/embed and TEI's /embed with vectors
+// [len(input), i], recording each batch's size.
+func mockEmbedder(t *testing.T) (url string, batches func() []int) {
+	t.Helper()
+	var mu sync.Mutex
+	var sizes []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input  []string `json:"input"`
+			Inputs []string `json:"inputs"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		inputs := append(req.Input, req.Inputs...)
+		mu.Lock()
+		sizes = append(sizes, len(inputs))
+		mu.Unlock()
+		vecs := make([][]float64, len(inputs))
+		for i, s := range inputs {
+			vecs[i] = []float64{float64(len(s)), float64(i)}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/embed":
+			json.NewEncoder(w).Encode(map[string]interface{}{"embeddings": vecs, "prompt_eval_count": 3 * len(inputs)})
+		case "/embed":
+			json.NewEncoder(w).Encode(vecs)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, func() []int {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]int(nil), sizes...)
+	}
+}
+
+func TestOpenAIListenerEmbeddingsBatches(t *testing.T) {
+	url, batches := mockEmbedder(t)
+	srv := newOpenAIListener(t, config.ProviderConfig{
+		Name: "ollama", Endpoint: url + "/v1",
+		EmbeddingModels: map[string]config.EmbeddingConfig{
+			"embed": {Model: "nomic-embed-text", Format: config.EmbedOllama, BatchSize: 2},
+			"tei":   {Model: "bge", Format: config.EmbedTEI},
+		},
+	})
+
+	resp, err := http.Post(srv.URL+"/v1/embeddings", "application/json",
+		strings.NewReader(`{"model":"embed","input":["a","bb","ccc","dddd","eeeee"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+		Model string `json:"model"`
+		Usage struct {
+			PromptTokens int `json:"prompt_tokens"`
+		} `json:"usage"`
+	}
+	json.NewDecoder(resp.Body).Decode(&out)
+	if resp.StatusCode != 200 || len(out.Data) != 5 || out.Model != "embed" || out.Usage.PromptTokens != 15 {
+		t.Fatalf("status %d, %+v", resp.StatusCode, out)
+	}
+	for i, d := range out.Data {
+		if d.Index != i || d.Embedding[0] != float64(i+1) {
+			t.Errorf("data[%d] = %+v, want index %d for input of length %d", i, d, i, i+1)
+		}
+	}
+	if got := fmt.Sprint(batches()); got != "[2 2 1]" {
+		t.Errorf("batches = %s, want [2 2 1]", got)
+	}
+
+	resp, err = http.Post(srv.URL+"/v1/embeddings", "application/json",
+		strings.NewReader(`{"model":"tei","input":"hello","encoding_format":"base64"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	// [5, 0] as little-endian float32s.
+	if resp.StatusCode != 200 || !strings.Contains(string(body), `"embedding":"AACgQAAAAAA="`) {
+		t.Errorf("tei base64: %d %s", resp.StatusCode, body)
+	}
+
+	resp, err = http.Post(srv.URL+"/v1/embeddings", "application/json",
+		strings.NewReader(`{"model":"embed","input":[[1,2,3]]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 400 {
+		t.Errorf("token input: status %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestInterceptedEmbeddingsRouteLocally(t *testing.T) {
+	url, _ := mockEmbedder(t)
+	resolver, err := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name: "ollama", Endpoint: url + "/v1",
+			EmbeddingModels: map[string]config.EmbeddingConfig{"embed": {Model: "nomic-embed-text", Format: config.EmbedOllama}},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	infra := setupInfra(t, resolver)
+	status, body, _ := proxyRequest(t, infra, "POST", "/v1/embeddings", []byte(`{"model":"embed","input":"hi"}`), nil)
+	if status != 200 || !strings.Contains(string(body), `"embedding":[2,0]`) {
+		t.Errorf("local embeddings: %d %s", status, body)
+	}
+}
@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// providerAcceptEncoding is the compression offered to providers: the
+// encodings decodeBody reads, since every provider response is read by the
+// proxy. It replaces any Accept-Encoding in a provider's configured headers.
+const providerAcceptEncoding = "gzip, br, zstd"
+
+// decodeBody replaces an encoded provider response body with its decoded
+// bytes and drops the headers that described the encoded form, so
+// translators and relays only ever see plain JSON or SSE. gzip, deflate,
+// brotli and zstd are decoded; any other encoding is an error.
+func decodeBody(resp *http.Response) error {
+	enc := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	var open func(io.Reader) (io.ReadCloser, error)
+	switch enc {
+	case "", "identity":
+		return nil
+	case "gzip", "x-gzip":
+		open = func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) }
+	case "deflate":
+		open = zlib.NewReader
+	case "br":
+		open = func(r io.Reader) (io.ReadCloser, error) { return io.NopCloser(brotli.NewReader(r)), nil }
+	case "zstd":
+		open = func(r io.Reader) (io.ReadCloser, error) {
+			d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				return nil, err
+			}
+			return d.IOReadCloser(), nil
+		}
+	default:
+		return fmt.Errorf("unsupported Content-Encoding %q (the proxy asks for %s)", enc, providerAcceptEncoding)
+	}
+	resp.Body = &decodedBody{src: resp.Body, open: open}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+// decodedBody decodes its source on first read, so a stream's headers
+// aren't held up waiting for the compressor's header bytes, and an empty
+// body reads as empty.
+type decodedBody struct {
+	src  io.ReadCloser
+	open func(io.Reader) (io.ReadCloser, error)
+	dec  io.ReadCloser
+	err  error
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.dec == nil && b.err == nil {
+		b.dec, b.err = b.open(b.src)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.dec.Read(p)
+}
+
+// Close releases the decoder (zstd holds buffers until closed) and closes
+// the source.
+func (b *decodedBody) Close() error {
+	if b.dec != nil {
+		b.dec.Close()
+	}
+	return b.src.Close()
+}
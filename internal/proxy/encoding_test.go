@@ -0,0 +1,193 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+func compress(t *testing.T, enc string, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch enc {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "br":
+		w = brotli.NewWriter(&buf)
+	case "zstd":
+		w, _ = zstd.NewWriter(&buf)
+	default:
+		// An encoding the proxy can't decode, so any bytes do.
+		return []byte("\x1f\x9d" + enc + ":" + data)
+	}
+	io.WriteString(w, data)
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestUpstreamEncodedResponsesRelayedVerbatim(t *testing.T) {
+	for _, enc := range []string{"gzip", "br", "zstd"} {
+		for _, chunked := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/chunked=%v", enc, chunked), func(t *testing.T) {
+				payload := compress(t, enc, `{"ok":true}`)
+				var gotAE string
+				client := upstreamHandler(t, func(w http.ResponseWriter, r *http.Request) {
+					gotAE = r.Header.Get("Accept-Encoding")
+					w.Header().Set("Content-Encoding", enc)
+					w.Header().Set("Content-Type", "application/json")
+					if chunked {
+						w.(http.Flusher).Flush()
+					} else {
+						w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+					}
+					w.Write(payload)
+				})
+				infra := setupInfraWithOptions(t, nil, WithHTTPClient(client))
+
+				status, header, body := proxyRequestHeaders(t, infra, "GET", "/data", nil,
+					map[string]string{"Accept-Encoding": "gzip, br, zstd"})
+				if status != 200 {
+					t.Fatalf("status %d: %q", status, body)
+				}
+				if gotAE != "gzip, br, zstd" {
+					t.Errorf("upstream saw Accept-Encoding %q", gotAE)
+				}
+				if body != string(payload) {
+					t.Errorf("body changed in transit: %q, want %q", body, payload)
+				}
+				if got := header.Get("Content-Encoding"); got != enc {
+					t.Errorf("Content-Encoding = %q, want %q", got, enc)
+				}
+				if chunked {
+					if got := header.Get("Transfer-Encoding"); got != "chunked" {
+						t.Errorf("Transfer-Encoding = %q, want chunked", got)
+					}
+				} else if got := header.Get("Content-Length"); got != strconv.Itoa(len(payload)) {
+					t.Errorf("Content-Length = %q, want %d", got, len(payload))
+				}
+			})
+		}
+	}
+}
+
+func TestUpstreamDecodedWhenClientAcceptsNoEncoding(t *testing.T) {
+	const plain = `{"ok":true}`
+	client := upstreamHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			w.Write([]byte(plain))
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(compress(t, "gzip", plain))
+	})
+	infra := setupInfraWithOptions(t, nil, WithHTTPClient(client))
+
+	status, header, body := proxyRequestHeaders(t, infra, "GET", "/data", nil, nil)
+	if status != 200 || body != plain {
+		t.Fatalf("got %d %q, want 200 %q", status, body, plain)
+	}
+	if got := header.Get("Content-Encoding"); got != "" {
+		t.Errorf("decoded body still labeled Content-Encoding %q", got)
+	}
+	if got := header.Get("Content-Length"); got != "" {
+		t.Errorf("Content-Length %q sent for a body decoded in transit", got)
+	}
+}
+
+// encodedProvider answers chat completions with a body compressed as enc,
+// failing the request if the proxy offered anything but the encodings it
+// decodes.
+func encodedProvider(t *testing.T, enc string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ae := r.Header.Get("Accept-Encoding"); ae != providerAcceptEncoding {
+			http.Error(w, "unexpected Accept-Encoding "+ae, http.StatusBadRequest)
+			return
+		}
+		var req struct {
+			Stream bool `json:"stream"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		var data string
+		if req.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			data = "data: {\"id\":\"c1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"squeezed\"}}]}\n\n" +
+				"data: {\"id\":\"c1\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n"
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+			data = `{"id":"c1","choices":[{"message":{"role":"assistant","content":"squeezed"},"finish_reason":"stop"}]}`
+		}
+		w.Header().Set("Content-Encoding", enc)
+		w.Write(compress(t, enc, data))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestLocalRouteDecodesProviderEncoding(t *testing.T) {
+	for _, enc := range []string{"gzip", "deflate", "br", "zstd"} {
+		for _, stream := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/stream=%v", enc, stream), func(t *testing.T) {
+				srv := encodedProvider(t, enc)
+				resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+					Providers: []config.ProviderConfig{{
+						Name:     "mock",
+						Endpoint: srv.URL + "/v1",
+						// Overridden: providers are offered what decodeBody reads.
+						Headers: map[string]string{"Accept-Encoding": "br"},
+						Models:  map[string]config.ModelConfig{"test_model": {Model: "m"}},
+					}},
+				})
+				infra := setupInfra(t, resolver)
+
+				body, _ := json.Marshal(map[string]interface{}{
+					"model":      "claude-sonnet-4-20250514",
+					"system":     "<!-- @proxy-local-route:af83e9 model=test_model --> You are helpful",
+					"messages":   []map[string]string{{"role": "user", "content": "hello"}},
+					"max_tokens": 64,
+					"stream":     stream,
+				})
+				status, respBody, _ := proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+				if status != 200 || !strings.Contains(respBody, "squeezed") {
+					t.Fatalf("got %d: %s", status, respBody)
+				}
+				if stream {
+					assertSSELifecycle(t, respBody)
+				}
+			})
+		}
+	}
+}
+
+func TestLocalRouteRejectsUndecodableProviderEncoding(t *testing.T) {
+	srv := encodedProvider(t, "compress")
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "mock",
+			Endpoint: srv.URL + "/v1",
+			Models:   map[string]config.ModelConfig{"test_model": {Model: "m"}},
+		}},
+	})
+	infra := setupInfra(t, resolver)
+
+	status, respBody, _ := proxyRequest(t, infra, "POST", "/v1/messages", streamBody(), nil)
+	if status != 502 || !strings.Contains(respBody, `unsupported Content-Encoding \"compress\"`) {
+		t.Errorf("got %d: %s", status, respBody)
+	}
+}
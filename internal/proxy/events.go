@@ -0,0 +1,123 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Routing event types published to Events subscribers.
+const (
+	EventRouteDecided   = "route_decided"   // a tunneled request was routed
+	EventProviderCalled = "provider_called" // a local route is sending its request to the provider
+	EventStreamFinished = "stream_finished" // a local route completed, streaming or not
+	EventError          = "error"           // a local route failed; Status holds the LOCAL_ERR category
+)
+
+// eventBuffer is how many events a subscriber may fall behind by before
+// events are dropped for it.
+const eventBuffer = 256
+
+// RoutingEvent is one entry in the live feed of router activity.
+type RoutingEvent struct {
+	Type         string    `json:"type"`
+	Time         time.Time `json:"time"`
+	Route        string    `json:"route,omitempty"` // route_decided: "local", "upstream", "count_tokens" or "embeddings"
+	Method       string    `json:"method,omitempty"`
+	Host         string    `json:"host,omitempty"`
+	Path         string    `json:"path,omitempty"`
+	Label        string    `json:"label,omitempty"`
+	Provider     string    `json:"provider,omitempty"`
+	Model        string    `json:"model,omitempty"`
+	Stream       bool      `json:"stream,omitempty"`
+	Status       string    `json:"status,omitempty"`
+	LatencyMs    int64     `json:"latency_ms,omitempty"`
+	InputTokens  int       `json:"input_tokens,omitempty"`
+	OutputTokens int       `json:"output_tokens,omitempty"`
+}
+
+// eventBus fans routing events out to subscribers. Publishing never
+// blocks: a subscriber that falls behind loses events rather than slowing
+// requests down.
+type eventBus struct {
+	mu   sync.RWMutex
+	subs map[chan RoutingEvent]struct{}
+}
+
+func (b *eventBus) publish(ev RoutingEvent) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if len(b.subs) == 0 {
+		return
+	}
+	ev.Time = time.Now()
+	for ch := range b.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+func (b *eventBus) subscribe() (<-chan RoutingEvent, func()) {
+	ch := make(chan RoutingEvent, eventBuffer)
+	b.mu.Lock()
+	if b.subs == nil {
+		b.subs = make(map[chan RoutingEvent]struct{})
+	}
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, ch)
+			b.mu.Unlock()
+		})
+	}
+}
+
+// Events subscribes to the live feed of routing events. The returned
+// cancel func ends the subscription; events are dropped for a subscriber
+// more than a few hundred behind.
+func (p *Proxy) Events() (<-chan RoutingEvent, func()) {
+	return p.events.subscribe()
+}
+
+// decideRoute records where a tunneled request is going, for middleware
+// and event subscribers.
+func (p *Proxy) decideRoute(ex *Exchange, req *http.Request, host, port, route, label string) {
+	ex.setRoute(route, label)
+	p.events.publish(RoutingEvent{
+		Type:   EventRouteDecided,
+		Route:  route,
+		Method: req.Method,
+		Host:   net.JoinHostPort(host, port),
+		Path:   req.URL.Path,
+		Label:  label,
+	})
+}
+
+// finishRoute records a finished local route in the activity log and the
+// conversation history, and publishes its outcome.
+func (p *Proxy) finishRoute(ev *RouteEvent) {
+	p.activity.finish(ev)
+	p.history.record(ev.conv, HistoryEntry{Route: "local", Label: ev.Label,
+		Provider: ev.Provider, Model: ev.Model, Status: ev.Status})
+	typ := EventStreamFinished
+	if ev.Status != "ok" && ev.Status != "dedupe" {
+		typ = EventError
+	}
+	p.events.publish(RoutingEvent{
+		Type:         typ,
+		Label:        ev.Label,
+		Provider:     ev.Provider,
+		Model:        ev.Model,
+		Stream:       ev.Stream,
+		Status:       ev.Status,
+		LatencyMs:    ev.LatencyMs,
+		InputTokens:  ev.InputTokens,
+		OutputTokens: ev.OutputTokens,
+	})
+}
@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// firstTokenDeadline cancels a provider stream that produces no token within
+// the provider's first_token_timeout. Once a token arrives the deadline is
+// disarmed and the stream may run as long as it likes.
+type firstTokenDeadline struct {
+	timer  *time.Timer
+	missed atomic.Bool
+}
+
+// armFirstToken returns a context for the provider request that is canceled
+// if d passes before disarm is called.
+func armFirstToken(d time.Duration) (context.Context, *firstTokenDeadline, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ft := &firstTokenDeadline{}
+	ft.timer = time.AfterFunc(d, func() {
+		ft.missed.Store(true)
+		cancel()
+	})
+	return ctx, ft, func() {
+		ft.timer.Stop()
+		cancel()
+	}
+}
+
+// disarm stops the deadline. It is a no-op once the deadline has fired.
+func (ft *firstTokenDeadline) disarm() {
+	if ft != nil {
+		ft.timer.Stop()
+	}
+}
+
+// expired reports whether the deadline fired.
+func (ft *firstTokenDeadline) expired() bool {
+	return ft != nil && ft.missed.Load()
+}
+
+// deltaEvent marks the translated event that carries a token.
+var deltaEvent = []byte("event: content_block_delta")
+
+// firstTokenGate holds translated output back until the first
+// content_block_delta, so a stream that times out before its first token
+// has sent the client nothing and can still be answered with a plain error.
+// After that it passes writes straight through.
+type firstTokenGate struct {
+	dst     io.Writer
+	onToken func()
+	held    []byte
+	open    bool
+}
+
+func (g *firstTokenGate) Write(p []byte) (int, error) {
+	if g.open {
+		return g.dst.Write(p)
+	}
+	g.held = append(g.held, p...)
+	if !bytes.Contains(p, deltaEvent) {
+		return len(p), nil
+	}
+	g.open = true
+	g.onToken()
+	_, err := g.dst.Write(g.held)
+	g.held = nil
+	return len(p), err
+}
+
+// flush releases held output from a stream that ended without a token.
+func (g *firstTokenGate) flush() {
+	if !g.open && len(g.held) > 0 {
+		g.dst.Write(g.held)
+		g.held = nil
+	}
+	g.open = true
+}
@@ -0,0 +1,57 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// Fuzz targets for routing marker detection. The seeds run with go test; to
+// fuzz:
+//
+//	go test ./internal/proxy -run '^$' -fuzz FuzzParseRouteRequest -fuzztime 1m
+
+func FuzzParseRouteRequest(f *testing.F) {
+	f.Add([]byte(`{"model":"claude","system":"<!-- @proxy-local-route:af83e9 model=coder temp=0.2 -->be brief","stream":true,"messages":[]}`))
+	f.Add([]byte(`{"system":[{"type":"text","text":"a"},{"type":"text","text":"<!-- @proxy-local-route:af83e9 model=x transform=+groq,-enhancetool max_tokens=9 -->"}],"messages":[{"role":"user","content":"hi"}]}`))
+	f.Add([]byte(`{"system":"<!-- @proxy-local-route:af83e9 model=x model=y -->","stream":"yes"}`))
+	f.Add([]byte(`{"system":null} trailing`))
+	f.Add([]byte(`[1,2]`))
+	f.Fuzz(func(t *testing.T, body []byte) {
+		rr := parseRouteRequest(body)
+		if rr.Route.Model == "" && rr.MarkerErr == nil {
+			if !bytes.Equal(rr.Body, body) {
+				t.Fatalf("body without a marker was rewritten:\n%s\n%s", body, rr.Body)
+			}
+			return
+		}
+		// A stripped marker is spliced out of a body that parsed, so the
+		// result still parses.
+		if !json.Valid(rr.Body) {
+			t.Fatalf("body with the marker stripped is not JSON:\n%s\n%s", body, rr.Body)
+		}
+		if rr.MarkerErr == nil {
+			if _, err := FormatMarker(rr.Route.Model, rr.Route.Opts...); err != nil {
+				t.Fatalf("parsed marker does not round-trip: %v", err)
+			}
+		}
+	})
+}
+
+func FuzzParseRouteMarker(f *testing.F) {
+	f.Add(" model=coder temp=0.2 top_p=1 max_tokens=100 raw=openai transform=+groq,-enhancetool ")
+	f.Add("model= temp=NaN")
+	f.Add("model=x transform=a,+b")
+	f.Fuzz(func(t *testing.T, opts string) {
+		route, err := parseRouteMarker(opts)
+		if err != nil {
+			return
+		}
+		if route.Model == "" {
+			t.Fatalf("%q parsed without a model", opts)
+		}
+		if route.MaxTokens < 0 || route.MaxTokens > maxMarkerTokens {
+			t.Fatalf("%q parsed max_tokens %d", opts, route.MaxTokens)
+		}
+	})
+}
@@ -0,0 +1,257 @@
+package proxy
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// harTrailer closes the entries array and the log. It is written after
+// every entry and overwritten by the next, so the file is always a
+// complete HAR document, even if the proxy is killed.
+const harTrailer = "\n]}}\n"
+
+// harMasked are request headers whose values never reach the capture file.
+var harMasked = map[string]bool{"authorization": true, "proxy-authorization": true, "x-api-key": true}
+
+// HARLog writes every request answered inside an intercepted tunnel, with
+// its response, to a HAR 1.2 file that browser devtools and mitmproxy can
+// open. Bodies are captured up to the max_body_bytes limit; credentials
+// in request headers are masked.
+type HARLog struct {
+	mu      sync.Mutex
+	f       *os.File
+	entries int
+}
+
+// OpenHAR creates (or truncates) a HAR file at path.
+func OpenHAR(path string) (*HARLog, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	version := "(devel)"
+	if bi, ok := debug.ReadBuildInfo(); ok && bi.Main.Version != "" {
+		version = bi.Main.Version
+	}
+	creator, _ := json.Marshal(map[string]string{"name": "claude-hybrid", "version": version})
+	h := &HARLog{f: f}
+	if err := h.write(`{"log":{"version":"1.2","creator":` + string(creator) + `,"entries":[`); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return h, nil
+}
+
+// Close closes the file, which is complete after every entry.
+func (h *HARLog) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.f.Close()
+}
+
+// WithHAR records tunnel traffic to h.
+func WithHAR(h *HARLog) Option {
+	return func(p *Proxy) { p.har = h }
+}
+
+// write appends s and the trailer, then steps back over the trailer.
+func (h *HARLog) write(s string) error {
+	if _, err := io.WriteString(h.f, s+harTrailer); err != nil {
+		return err
+	}
+	_, err := h.f.Seek(-int64(len(harTrailer)), io.SeekCurrent)
+	return err
+}
+
+func (h *HARLog) add(e harEntry) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sep := "\n"
+	if h.entries > 0 {
+		sep = ",\n"
+	}
+	if h.write(sep+string(data)) == nil {
+		h.entries++
+	}
+}
+
+// record adds an entry for a finished exchange, whose response was parsed
+// from c as cr.
+func (h *HARLog) record(c *exchangeCapture, cr capturedResponse) {
+	end := time.Now()
+	wait, receive := end.Sub(c.started), time.Duration(0)
+	if !c.firstByte.IsZero() {
+		wait, receive = c.firstByte.Sub(c.started), end.Sub(c.firstByte)
+	}
+	e := harEntry{
+		Started: c.started.Format("2006-01-02T15:04:05.000Z07:00"),
+		Time:    ms(wait + receive),
+		Request: harRequest{
+			Method:      c.req.Method,
+			URL:         c.url,
+			HTTPVersion: c.req.Proto,
+			Cookies:     []harPair{},
+			Headers:     harHeaders(c.req.Header, c.req.Host),
+			QueryString: harQuery(c.req),
+			HeadersSize: -1,
+			BodySize:    c.reqBody.n,
+		},
+		Response: harResponseOf(cr),
+		Cache:    struct{}{},
+		Timings:  harTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Wait: ms(wait), Receive: ms(receive)},
+	}
+	if c.reqBody.n > 0 {
+		text, _ := harText(c.reqBody.buf.Bytes())
+		e.Request.PostData = &harPostData{MimeType: c.req.Header.Get("Content-Type"), Text: text}
+		if c.reqBody.truncated() {
+			e.Request.PostData.Comment = "truncated"
+		}
+	}
+	h.add(e)
+}
+
+// harResponseOf converts a captured response. A request that got no
+// response is recorded with status 0, as browsers do.
+func harResponseOf(cr capturedResponse) harResponse {
+	out := harResponse{Cookies: []harPair{}, Headers: []harPair{}, HeadersSize: -1, BodySize: -1}
+	if cr.truncated {
+		out.Content.Comment = "truncated"
+	}
+	resp := cr.resp
+	if resp == nil {
+		return out
+	}
+	out.Status = resp.StatusCode
+	out.StatusText = strings.TrimSpace(strings.TrimPrefix(resp.Status, strconv.Itoa(resp.StatusCode)))
+	out.HTTPVersion = resp.Proto
+	out.Headers = harHeaders(resp.Header, "")
+	out.Content.MimeType = resp.Header.Get("Content-Type")
+	out.Content.Size = int64(len(cr.body))
+	out.Content.Text, out.Content.Encoding = harText(cr.body)
+	return out
+}
+
+// harText returns data as HAR text: as is when it's UTF-8, else base64.
+func harText(data []byte) (text, encoding string) {
+	if utf8.Valid(data) {
+		return string(data), ""
+	}
+	return base64.StdEncoding.EncodeToString(data), "base64"
+}
+
+func harHeaders(h http.Header, host string) []harPair {
+	out := []harPair{}
+	if host != "" {
+		out = append(out, harPair{Name: "Host", Value: host})
+	}
+	for _, name := range sortedHeaderNames(h) {
+		for _, v := range h[name] {
+			if harMasked[strings.ToLower(name)] {
+				v = "[REDACTED]"
+			}
+			out = append(out, harPair{Name: name, Value: v})
+		}
+	}
+	return out
+}
+
+func sortedHeaderNames(h http.Header) []string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func harQuery(req *http.Request) []harPair {
+	out := []harPair{}
+	q := req.URL.Query()
+	for _, name := range sortedHeaderNames(http.Header(q)) {
+		for _, v := range q[name] {
+			out = append(out, harPair{Name: name, Value: v})
+		}
+	}
+	return out
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// HAR 1.2 structures (http://www.softwareishard.com/blog/har-12-spec/).
+type harEntry struct {
+	Started  string      `json:"startedDateTime"`
+	Time     float64     `json:"time"`
+	Request  harRequest  `json:"request"`
+	Response harResponse `json:"response"`
+	Cache    struct{}    `json:"cache"`
+	Timings  harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string       `json:"method"`
+	URL         string       `json:"url"`
+	HTTPVersion string       `json:"httpVersion"`
+	Cookies     []harPair    `json:"cookies"`
+	Headers     []harPair    `json:"headers"`
+	QueryString []harPair    `json:"queryString"`
+	PostData    *harPostData `json:"postData,omitempty"`
+	HeadersSize int64        `json:"headersSize"`
+	BodySize    int64        `json:"bodySize"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harResponse struct {
+	Status      int        `json:"status"`
+	StatusText  string     `json:"statusText"`
+	HTTPVersion string     `json:"httpVersion"`
+	Cookies     []harPair  `json:"cookies"`
+	Headers     []harPair  `json:"headers"`
+	Content     harContent `json:"content"`
+	RedirectURL string     `json:"redirectURL"`
+	HeadersSize int64      `json:"headersSize"`
+	BodySize    int64      `json:"bodySize"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harPair struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
@@ -0,0 +1,123 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/testutil"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+type harFile struct {
+	Log struct {
+		Version string     `json:"version"`
+		Creator harPair    `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+func readHAR(t *testing.T, path string) harFile {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f harFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		t.Fatalf("HAR file is not valid JSON: %v\n%s", err, data)
+	}
+	return f
+}
+
+func TestHARCapture(t *testing.T) {
+	oaiSrv, oaiPort, _ := testutil.MockOpenAIServer()
+	t.Cleanup(func() { oaiSrv.Close() })
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "mock",
+			Endpoint: fmt.Sprintf("http://127.0.0.1:%d/v1", oaiPort),
+			Models:   map[string]config.ModelConfig{"test_model": {Model: "mock-model-v1"}},
+		}},
+	})
+	path := filepath.Join(t.TempDir(), "out.har")
+	har, err := OpenHAR(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { har.Close() })
+	infra := setupInfraWithOptions(t, resolver, WithHAR(har))
+
+	if f := readHAR(t, path); f.Log.Version != "1.2" || f.Log.Creator.Name != "claude-hybrid" || len(f.Log.Entries) != 0 {
+		t.Fatalf("new HAR file = %+v", f.Log)
+	}
+
+	status, _, _ := proxyRequest(t, infra, "GET", "/v1/models?limit=2", nil,
+		map[string]string{"Authorization": "Bearer sk-ant-secret-token"})
+	if status != 200 {
+		t.Fatalf("upstream status %d", status)
+	}
+	status, respBody, _ := proxyRequest(t, infra, "POST", "/v1/messages", streamBody(), nil)
+	if status != 200 {
+		t.Fatalf("local status %d: %s", status, respBody)
+	}
+
+	entries := readHAR(t, path).Log.Entries
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+
+	up := entries[0]
+	wantURL := fmt.Sprintf("https://localhost:%d/v1/models?limit=2", infra.upstreamPort)
+	if up.Request.Method != "GET" || up.Request.URL != wantURL {
+		t.Errorf("request = %s %s, want GET %s", up.Request.Method, up.Request.URL, wantURL)
+	}
+	if len(up.Request.QueryString) != 1 || up.Request.QueryString[0] != (harPair{"limit", "2"}) {
+		t.Errorf("queryString = %v", up.Request.QueryString)
+	}
+	for _, h := range up.Request.Headers {
+		if h.Name == "Authorization" && h.Value != "[REDACTED]" {
+			t.Errorf("credential written to HAR: %q", h.Value)
+		}
+	}
+	if up.Response.Status != 200 || !strings.Contains(up.Response.Content.Text, `"path":"/v1/models?limit=2"`) {
+		t.Errorf("upstream response = %d %q", up.Response.Status, up.Response.Content.Text)
+	}
+
+	local := entries[1]
+	if local.Request.PostData == nil || !strings.Contains(local.Request.PostData.Text, "@proxy-local-route") {
+		t.Errorf("request body not captured: %+v", local.Request.PostData)
+	}
+	if local.Response.Content.MimeType != "text/event-stream" || !strings.Contains(local.Response.Content.Text, "event: message_stop") {
+		t.Errorf("streamed response = %q %q", local.Response.Content.MimeType, local.Response.Content.Text)
+	}
+	if local.Time < local.Timings.Wait || local.Started == "" {
+		t.Errorf("timings = %v %+v", local.Time, local.Timings)
+	}
+}
+
+func TestHARTruncatesAtBodyLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.har")
+	har, err := OpenHAR(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { har.Close() })
+	infra := setupInfraWithOptions(t, nil, WithHAR(har), WithLimits(config.Limits{MaxBodyBytes: 150}))
+
+	// Not a Messages call, so it streams through past the limit.
+	body := strings.Repeat("x", 200) // echoed back, so the response is over the limit too
+	if status, _, _ := proxyRequest(t, infra, "POST", "/upload", []byte(body), nil); status != 200 {
+		t.Fatalf("status %d", status)
+	}
+	e := readHAR(t, path).Log.Entries[0]
+	if e.Request.BodySize != 200 || len(e.Request.PostData.Text) != 150 || e.Request.PostData.Comment != "truncated" {
+		t.Errorf("postData = %d bytes, %+v", e.Request.BodySize, e.Request.PostData)
+	}
+	if e.Response.Content.Comment != "truncated" {
+		t.Errorf("response over the limit should be marked truncated: %+v", e.Response.Content)
+	}
+}
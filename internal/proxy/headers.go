@@ -0,0 +1,167 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// destClass is where a request carrying Claude Code's headers is going.
+// Each class has its own header policy so credentials only ever reach the
+// destination they were issued for.
+type destClass int
+
+const (
+	destAnthropic destClass = iota // an Anthropic API host (see WithAnthropicHosts)
+	destLocal                      // a configured provider reached through a route marker
+	destOther                      // any other intercepted host
+)
+
+func (d destClass) String() string {
+	switch d {
+	case destAnthropic:
+		return "anthropic"
+	case destLocal:
+		return "local"
+	}
+	return "other"
+}
+
+// defaultAnthropicHosts are the hosts that receive Anthropic credentials.
+var defaultAnthropicHosts = []string{"anthropic.com", "*.anthropic.com"}
+
+// headerPolicy lists the client headers forwarded to a destination class,
+// by lowercase name or prefix. all forwards every end-to-end header.
+// Hop-by-hop headers are never forwarded.
+type headerPolicy struct {
+	exact    map[string]bool
+	prefixes []string
+	all      bool
+}
+
+func (hp headerPolicy) allows(name string) bool {
+	name = strings.ToLower(name)
+	if hopByHop[name] {
+		return false
+	}
+	if hp.all || hp.exact[name] {
+		return true
+	}
+	for _, pre := range hp.prefixes {
+		if strings.HasPrefix(name, pre) {
+			return true
+		}
+	}
+	return false
+}
+
+var headerPolicies = map[destClass]headerPolicy{
+	// Anthropic gets what the Messages API and Claude Code's SDK send, and
+	// nothing else: headers added by other software on the way stay local.
+	destAnthropic: {
+		exact: map[string]bool{
+			"accept": true, "accept-encoding": true, "accept-language": true,
+			"authorization": true, "x-api-key": true,
+			"cache-control": true, "content-type": true, "user-agent": true,
+			"x-app": true, "x-client-request-id": true, "idempotency-key": true,
+		},
+		prefixes: []string{"anthropic-", "x-stainless-", "x-claude-"},
+	},
+	// Providers authenticate with their own configured key; the client's
+	// credentials and Anthropic-specific headers are never sent.
+	destLocal: {
+		exact: map[string]bool{"user-agent": true, "x-client-request-id": true},
+	},
+	// Other hosts keep their own headers, minus Anthropic credentials (see
+	// stripAnthropicCredentials).
+	destOther: {all: true},
+}
+
+// forwardHeaders copies the headers src may send to d into dst.
+func (p *Proxy) forwardHeaders(dst, src http.Header, d destClass) {
+	policy := headerPolicies[d]
+	for k, vals := range src {
+		if !policy.allows(k) {
+			p.noteDroppedHeader(d, k)
+			continue
+		}
+		for _, v := range vals {
+			dst.Add(k, v)
+		}
+	}
+	switch d {
+	case destAnthropic:
+		if p.apiKey != "" {
+			dst.Del("Authorization")
+			dst.Set("X-Api-Key", p.apiKey)
+		}
+	case destOther:
+		stripAnthropicCredentials(dst)
+	}
+}
+
+// stripAnthropicCredentials removes Anthropic API keys and OAuth tokens
+// (both sk-ant-…) from headers bound for a non-Anthropic host. Other
+// credentials are the host's own and are left alone.
+func stripAnthropicCredentials(h http.Header) {
+	if strings.HasPrefix(h.Get("X-Api-Key"), "sk-ant-") {
+		h.Del("X-Api-Key")
+	}
+	auth := h.Get("Authorization")
+	if token, ok := strings.CutPrefix(auth, "Bearer "); ok && strings.HasPrefix(token, "sk-ant-") {
+		h.Del("Authorization")
+	}
+}
+
+// classifyHost returns the destination class of an intercepted host.
+func (p *Proxy) classifyHost(host string) destClass {
+	if p.apiHosts.match(host) {
+		return destAnthropic
+	}
+	return destOther
+}
+
+// droppedHeaders remembers which header names were already logged as
+// dropped, so verbose logs name each one once per destination class.
+var droppedHeaders sync.Map
+
+func (p *Proxy) noteDroppedHeader(d destClass, name string) {
+	if !p.verbose || hopByHop[strings.ToLower(name)] {
+		return
+	}
+	if _, seen := droppedHeaders.LoadOrStore(d.String()+"\x00"+strings.ToLower(name), true); !seen {
+		p.logVerbose("HEADER %s not forwarded to %s destination", name, d)
+	}
+}
+
+// WithAnthropicHosts sets the hosts treated as Anthropic's API, which alone
+// receive Anthropic credentials. Patterns use the intercept list syntax. An
+// empty list keeps the default, anthropic.com and its subdomains; list a
+// custom ANTHROPIC_BASE_URL gateway here.
+func WithAnthropicHosts(patterns []string) Option {
+	return func(p *Proxy) {
+		if len(patterns) > 0 {
+			p.apiHosts = newInterceptList(patterns)
+		}
+	}
+}
+
+// WithAnthropicKey replaces the client's Anthropic credential with key on
+// requests to Anthropic hosts. An empty key keeps the client's.
+func WithAnthropicKey(key string) Option {
+	return func(p *Proxy) { p.apiKey = key }
+}
+
+// providerRequestID returns the ID a provider gave its response in
+// X-Request-Id (or Request-Id), or "" when there is none or it is not a
+// plain header value.
+func providerRequestID(h http.Header) string {
+	id := h.Get("X-Request-Id")
+	if id == "" {
+		id = h.Get("Request-Id")
+	}
+	if len(id) > 200 || strings.ContainsAny(id, "\r\n") {
+		return ""
+	}
+	return id
+}
@@ -0,0 +1,269 @@
+package proxy
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxConversations bounds the in-memory history; the least recently
+	// active conversation is forgotten first.
+	maxConversations = 500
+	// maxConversationEntries bounds one conversation's history, oldest
+	// dropped first.
+	maxConversationEntries = 1000
+	// historyTitleChars is how much of a conversation's first user message
+	// names it in listings.
+	historyTitleChars = 80
+	// defaultHistoryRetention is how long persisted history files are kept.
+	defaultHistoryRetention = 30 * 24 * time.Hour
+)
+
+// HistoryEntry is one routed Messages request of a conversation: which
+// route, label and model wrote the reply that followed message Turn.
+type HistoryEntry struct {
+	Time     time.Time `json:"time"`
+	Turn     int       `json:"turn"`  // messages in the request
+	Route    string    `json:"route"` // "local" or "upstream"
+	Label    string    `json:"label,omitempty"`
+	Provider string    `json:"provider,omitempty"`
+	Model    string    `json:"model,omitempty"`
+	Status   string    `json:"status,omitempty"` // local routes: "ok", "dedupe" or the LOCAL_ERR category
+}
+
+// ConversationSummary describes one conversation in the history.
+type ConversationSummary struct {
+	ID       string    `json:"id"`
+	Title    string    `json:"title"` // start of the first user message
+	Started  time.Time `json:"started"`
+	Updated  time.Time `json:"updated"`
+	Local    int       `json:"local"`
+	Upstream int       `json:"upstream"`
+}
+
+// Conversation is a conversation's routing history, oldest first.
+type Conversation struct {
+	ConversationSummary
+	Entries []HistoryEntry `json:"entries"`
+}
+
+// conversationRef identifies the conversation a request belongs to.
+type conversationRef struct {
+	id    string
+	title string
+	turn  int
+	model string // the model the client asked for
+}
+
+// conversationOf identifies body's conversation. Claude Code resends the
+// whole conversation each turn, so its first user message and user_id
+// identify it, as for the judge. A body without messages has no
+// conversation.
+func conversationOf(body []byte) conversationRef {
+	var req struct {
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+		Model    string `json:"model"`
+		Metadata struct {
+			UserID string `json:"user_id"`
+		} `json:"metadata"`
+	}
+	if json.Unmarshal(body, &req) != nil || len(req.Messages) == 0 {
+		return conversationRef{}
+	}
+	ref := conversationRef{turn: len(req.Messages), model: req.Model}
+	h := sha256.New()
+	io.WriteString(h, req.Metadata.UserID)
+	h.Write([]byte{0})
+	for _, m := range req.Messages {
+		if m.Role == "user" {
+			h.Write(m.Content)
+			ref.title = strings.Join(strings.Fields(contentText(m.Content, false)), " ")
+			if r := []rune(ref.title); len(r) > historyTitleChars {
+				ref.title = string(r[:historyTitleChars]) + "…"
+			}
+			break
+		}
+	}
+	ref.id = hex.EncodeToString(h.Sum(nil))[:16]
+	return ref
+}
+
+// historyRecord is one line of a persisted history file.
+type historyRecord struct {
+	Conversation string `json:"conversation"`
+	Title        string `json:"title,omitempty"`
+	HistoryEntry
+}
+
+// routeHistory keeps recent conversations' routing decisions in memory
+// and, when dir is set, appends them to one JSON-lines file per day shared
+// by all instances.
+type routeHistory struct {
+	mu    sync.Mutex
+	convs map[string]*Conversation
+	dir   string
+}
+
+// WithHistoryDir persists conversation history under dir, loading what is
+// there from the last retention (default 30 days) and deleting older days.
+func WithHistoryDir(dir string, retention time.Duration) Option {
+	return func(p *Proxy) {
+		if retention <= 0 {
+			retention = defaultHistoryRetention
+		}
+		p.history.dir = dir
+		if err := p.history.load(retention); err != nil {
+			log.Printf("[HISTORY] loading %s: %v", dir, err)
+		}
+	}
+}
+
+// record adds e to ref's conversation and, when persisting, to today's file.
+func (h *routeHistory) record(ref conversationRef, e HistoryEntry) {
+	if ref.id == "" {
+		return
+	}
+	e.Time, e.Turn = time.Now(), ref.turn
+	h.mu.Lock()
+	h.add(ref.id, ref.title, e)
+	h.mu.Unlock()
+	if h.dir == "" {
+		return
+	}
+	line, _ := json.Marshal(historyRecord{Conversation: ref.id, Title: ref.title, HistoryEntry: e})
+	if err := appendLine(filepath.Join(h.dir, e.Time.Format("2006-01-02")+".jsonl"), line); err != nil {
+		log.Printf("[HISTORY] %v", err)
+	}
+}
+
+// add records e in memory. The caller holds mu.
+func (h *routeHistory) add(id, title string, e HistoryEntry) {
+	if h.convs == nil {
+		h.convs = make(map[string]*Conversation)
+	}
+	c := h.convs[id]
+	if c == nil {
+		if len(h.convs) >= maxConversations {
+			var oldest *Conversation
+			for _, o := range h.convs {
+				if oldest == nil || o.Updated.Before(oldest.Updated) {
+					oldest = o
+				}
+			}
+			delete(h.convs, oldest.ID)
+		}
+		c = &Conversation{ConversationSummary: ConversationSummary{ID: id, Title: title, Started: e.Time}}
+		h.convs[id] = c
+	}
+	if len(c.Entries) >= maxConversationEntries {
+		c.Entries = append(c.Entries[:0], c.Entries[1:]...)
+	}
+	c.Entries = append(c.Entries, e)
+	c.Updated = e.Time
+	if e.Route == "local" {
+		c.Local++
+	} else {
+		c.Upstream++
+	}
+}
+
+func appendLine(path string, line []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	// One write per record keeps concurrent instances' lines whole.
+	_, err = f.Write(append(line, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// load reads the day files within retention, oldest first, and removes
+// the rest. Unparseable lines are skipped.
+func (h *routeHistory) load(retention time.Duration) error {
+	files, err := os.ReadDir(h.dir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-retention).Format("2006-01-02")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, f := range files { // ReadDir sorts by name, so by day
+		day, ok := strings.CutSuffix(f.Name(), ".jsonl")
+		if !ok || f.IsDir() {
+			continue
+		}
+		path := filepath.Join(h.dir, f.Name())
+		if day < cutoff {
+			os.Remove(path)
+			continue
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		sc := bufio.NewScanner(file)
+		for sc.Scan() {
+			var r historyRecord
+			if json.Unmarshal(sc.Bytes(), &r) == nil && r.Conversation != "" {
+				h.add(r.Conversation, r.Title, r.HistoryEntry)
+			}
+		}
+		file.Close()
+	}
+	return nil
+}
+
+func (h *routeHistory) list() []ConversationSummary {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]ConversationSummary, 0, len(h.convs))
+	for _, c := range h.convs {
+		out = append(out, c.ConversationSummary)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Updated.After(out[j].Updated) })
+	return out
+}
+
+func (h *routeHistory) get(id string) (Conversation, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c, ok := h.convs[id]
+	if !ok {
+		return Conversation{}, false
+	}
+	out := *c
+	out.Entries = append([]HistoryEntry(nil), c.Entries...)
+	return out, true
+}
+
+// Conversations lists the conversations in the routing history, most
+// recently active first.
+func (p *Proxy) Conversations() []ConversationSummary {
+	return p.history.list()
+}
+
+// ConversationHistory returns one conversation's routing decisions.
+func (p *Proxy) ConversationHistory(id string) (Conversation, bool) {
+	return p.history.get(id)
+}
@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+func TestHistoryFollowsConversation(t *testing.T) {
+	port, _, _ := capturingMockOpenAI(t)
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "mock",
+			Endpoint: fmt.Sprintf("http://127.0.0.1:%d/v1", port),
+			Models:   map[string]config.ModelConfig{"fast": {Model: "m1"}},
+		}},
+	})
+	dir := t.TempDir()
+	infra := setupInfraWithOptions(t, resolver, WithHistoryDir(dir, 0))
+
+	messages := []map[string]string{{"role": "user", "content": "Fix the   flaky test"}}
+	send := func(system string) {
+		body, _ := json.Marshal(map[string]interface{}{
+			"model":      "claude-sonnet-4-20250514",
+			"system":     system,
+			"messages":   messages,
+			"metadata":   map[string]string{"user_id": "u1"},
+			"max_tokens": 64,
+		})
+		proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+	}
+	send("You are helpful")
+	messages = append(messages, map[string]string{"role": "assistant", "content": "Done"},
+		map[string]string{"role": "user", "content": "Now add a test"})
+	send("<!-- @proxy-local-route:af83e9 model=fast -->")
+
+	convs := infra.proxy.Conversations()
+	if len(convs) != 1 || convs[0].Title != "Fix the flaky test" || convs[0].Local != 1 || convs[0].Upstream != 1 {
+		t.Fatalf("conversations = %+v", convs)
+	}
+	c, ok := infra.proxy.ConversationHistory(convs[0].ID)
+	if !ok || len(c.Entries) != 2 {
+		t.Fatalf("history = %+v", c)
+	}
+	if e := c.Entries[0]; e.Route != "upstream" || e.Model != "claude-sonnet-4-20250514" || e.Turn != 1 {
+		t.Errorf("upstream entry = %+v", e)
+	}
+	if e := c.Entries[1]; e.Route != "local" || e.Label != "fast" || e.Provider != "mock" || e.Model != "m1" || e.Status != "ok" || e.Turn != 3 {
+		t.Errorf("local entry = %+v", e)
+	}
+
+	// A restarted proxy reads the history back.
+	var restarted Proxy
+	WithHistoryDir(dir, 0)(&restarted)
+	if got, ok := restarted.ConversationHistory(convs[0].ID); !ok || len(got.Entries) != 2 || got.Title != convs[0].Title {
+		t.Errorf("reloaded history = %+v", got)
+	}
+}
+
+func TestHistoryRetention(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, time.Now().AddDate(0, 0, -3).Format("2006-01-02")+".jsonl")
+	line := `{"conversation":"c1","title":"old","time":"2020-01-01T00:00:00Z","turn":1,"route":"local"}` + "\n"
+	os.WriteFile(old, []byte(line), 0600)
+	os.WriteFile(filepath.Join(dir, time.Now().Format("2006-01-02")+".jsonl"),
+		[]byte(strings.Replace(line, "c1", "c2", 1)+"not json\n"), 0600)
+
+	var p Proxy
+	WithHistoryDir(dir, 48*time.Hour)(&p)
+	if convs := p.Conversations(); len(convs) != 1 || convs[0].ID != "c2" {
+		t.Errorf("conversations = %+v", convs)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("expired day file kept: %v", err)
+	}
+}
+
+func TestHistoryIsBounded(t *testing.T) {
+	var h routeHistory
+	for i := 0; i < maxConversations+1; i++ {
+		h.record(conversationRef{id: fmt.Sprint(i), turn: 1}, HistoryEntry{Route: "local"})
+	}
+	if _, ok := h.get("0"); ok || len(h.list()) != maxConversations {
+		t.Errorf("kept %d conversations, oldest present: %v", len(h.list()), ok)
+	}
+	for i := 0; i < maxConversationEntries+1; i++ {
+		h.record(conversationRef{id: "long", turn: i}, HistoryEntry{Route: "upstream"})
+	}
+	if c, _ := h.get("long"); len(c.Entries) != maxConversationEntries || c.Entries[0].Turn != 1 || c.Upstream != maxConversationEntries+1 {
+		t.Errorf("long conversation: %d entries, first turn %d, upstream %d", len(c.Entries), c.Entries[0].Turn, c.Upstream)
+	}
+}
@@ -0,0 +1,284 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/tokenizer"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+const (
+	// maxJudgePicks bounds the per-conversation cache; expired picks are
+	// dropped first, then the oldest.
+	maxJudgePicks = 1024
+	// judgeInstructionChars is how much of the latest user instruction the
+	// judge sees, from the end, where the request usually is.
+	judgeInstructionChars = 2000
+)
+
+var thinkRE = regexp.MustCompile(`(?s)<think>.*?</think>`)
+
+// judgePicks caches each conversation's judge decision.
+type judgePicks struct {
+	mu    sync.Mutex
+	picks map[string]judgePick
+}
+
+type judgePick struct {
+	label   string
+	expires time.Time
+}
+
+func (c *judgePicks) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.picks[key]
+	if !ok || time.Now().After(p.expires) {
+		return "", false
+	}
+	return p.label, true
+}
+
+func (c *judgePicks) put(key, label string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.picks == nil {
+		c.picks = make(map[string]judgePick)
+	}
+	now := time.Now()
+	if len(c.picks) >= maxJudgePicks {
+		oldest := ""
+		for k, p := range c.picks {
+			if now.After(p.expires) {
+				delete(c.picks, k)
+			} else if oldest == "" || p.expires.Before(c.picks[oldest].expires) {
+				oldest = k
+			}
+		}
+		if len(c.picks) >= maxJudgePicks {
+			delete(c.picks, oldest)
+		}
+	}
+	c.picks[key] = judgePick{label: label, expires: now.Add(ttl)}
+}
+
+// judgeRequest is what the judge sees of a request.
+type judgeRequest struct {
+	key         string // conversation: metadata.user_id and first user message
+	instruction string // latest user instruction, truncated
+	tools       int
+	tokens      int
+}
+
+// condenseForJudge extracts the parts of an Anthropic Messages body the
+// judge decides on. Claude Code resends the whole conversation each turn,
+// so its first user message and user_id identify the conversation.
+func condenseForJudge(name string, body []byte) judgeRequest {
+	var req struct {
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+		Tools    []json.RawMessage `json:"tools"`
+		Metadata struct {
+			UserID string `json:"user_id"`
+		} `json:"metadata"`
+	}
+	json.Unmarshal(body, &req)
+	h := sha256.New()
+	io.WriteString(h, name)
+	h.Write([]byte{0})
+	io.WriteString(h, req.Metadata.UserID)
+	h.Write([]byte{0})
+	for _, m := range req.Messages {
+		if m.Role == "user" {
+			h.Write(m.Content)
+			break
+		}
+	}
+	jr := judgeRequest{key: hex.EncodeToString(h.Sum(nil)), tools: len(req.Tools)}
+	jr.tokens, _ = tokenizer.EstimateMessages(tokenizer.Heuristic{}, body)
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		m := req.Messages[i]
+		if m.Role != "user" {
+			continue
+		}
+		if text := strings.TrimSpace(contentText(m.Content, false)); text != "" {
+			if r := []rune(text); len(r) > judgeInstructionChars {
+				text = "…" + string(r[len(r)-judgeInstructionChars:])
+			}
+			jr.instruction = text
+			break
+		}
+	}
+	return jr
+}
+
+// judgePrompt returns the system and user messages asking the judge to pick
+// one of j's choices for jr.
+func judgePrompt(j config.JudgeConfig, jr judgeRequest) (string, string) {
+	var sys strings.Builder
+	sys.WriteString("You route coding-assistant requests to the model best suited to them. The options are:\n\n")
+	choices := make([]string, 0, len(j.Choices))
+	for c := range j.Choices {
+		choices = append(choices, c)
+	}
+	sort.Strings(choices)
+	for _, c := range choices {
+		fmt.Fprintf(&sys, "- %s: %s\n", c, j.Choices[c])
+	}
+	sys.WriteString("\nReply with only the name of one option, nothing else.")
+	user := fmt.Sprintf("Request (%d tools available, about %d tokens of context):\n\n%s", jr.tools, jr.tokens, jr.instruction)
+	return sys.String(), user
+}
+
+// parseJudgeReply finds the choice in the judge's reply: the whole reply,
+// or failing that the one choice it mentions. Reasoning in <think> tags,
+// even unclosed, and surrounding quotes or punctuation are ignored.
+func parseJudgeReply(reply string, choices map[string]string) (string, bool) {
+	reply = thinkRE.ReplaceAllString(reply, "")
+	if i := strings.Index(reply, "<think>"); i >= 0 {
+		reply = reply[:i] // cut off by max_tokens while reasoning
+	}
+	reply = strings.TrimSpace(reply)
+	trimmed := strings.Trim(reply, "`'\"*.,:;!- \n")
+	for c := range choices {
+		if strings.EqualFold(trimmed, c) {
+			return c, true
+		}
+	}
+	found := ""
+	lower := strings.ToLower(reply)
+	for c := range choices {
+		if !containsWord(lower, strings.ToLower(c)) {
+			continue
+		}
+		if found != "" {
+			return "", false
+		}
+		found = c
+	}
+	return found, found != ""
+}
+
+// containsWord reports whether word appears in s not as part of a longer
+// name.
+func containsWord(s, word string) bool {
+	for i := 0; ; {
+		j := strings.Index(s[i:], word)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(word)
+		if (start == 0 || !isNameByte(s[start-1])) && (end == len(s) || !isNameByte(s[end])) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func isNameByte(b byte) bool {
+	return b == '-' || b == '_' || b == '.' || b == '/' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z'
+}
+
+// judgeTarget returns the choice for model=judge:NAME: the conversation's
+// cached pick, or the judge's answer, or j's default when the judge fails.
+// Failures aren't cached, so the next turn asks again.
+func (p *Proxy) judgeTarget(label string, j config.JudgeConfig, body []byte) string {
+	jr := condenseForJudge(label, body)
+	if pick, ok := p.judgePicks.get(jr.key); ok {
+		log.Printf("LOCAL_JUDGE %s → %s (cached)", label, pick)
+		return pick
+	}
+	start := time.Now()
+	reply, err := p.askJudge(j, jr)
+	if err == nil {
+		pick, ok := parseJudgeReply(reply, j.Choices)
+		if ok {
+			p.judgePicks.put(jr.key, pick, j.CacheTTL)
+			log.Printf("LOCAL_JUDGE %s → %s (%s said, %s)", label, pick, j.Judge, time.Since(start).Round(time.Millisecond))
+			return pick
+		}
+		err = fmt.Errorf("answer %q is not one of the choices", clip(reply, 80))
+	}
+	log.Printf("[LOCAL_JUDGE] %s: judge %s: %v; using default %s", label, j.Judge, err, j.Default)
+	return j.Default
+}
+
+// askJudge sends the condensed request to the judge model and returns its
+// reply text. The judge is called directly, not routed: no budget,
+// capacity or fallback applies.
+func (p *Proxy) askJudge(j config.JudgeConfig, jr judgeRequest) (string, error) {
+	m, err := p.modelResolver.Resolve(j.Judge)
+	if err != nil {
+		return "", err
+	}
+	if m.API == config.APIAnthropic {
+		return "", fmt.Errorf("label %s is on an api: anthropic provider; judges need an OpenAI-compatible one", j.Judge)
+	}
+	sys, user := judgePrompt(j, jr)
+	payload, _ := json.Marshal(map[string]interface{}{
+		"model": m.Model,
+		"messages": []map[string]string{
+			{"role": "system", "content": sys},
+			{"role": "user", "content": user},
+		},
+		"max_tokens":  16,
+		"temperature": 0,
+		"stream":      false,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), j.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimRight(m.Endpoint, "/")+"/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := setProviderHeaders(req, m); err != nil {
+		return "", err
+	}
+	resp, err := p.pools.get(m).do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, sanitizeForLog(clip(string(data), 200)))
+	}
+	var out struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil || len(out.Choices) == 0 {
+		return "", fmt.Errorf("unreadable response")
+	}
+	return out.Choices[0].Message.Content, nil
+}
+
+// clip shortens s to n bytes for a log line.
+func clip(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "…"
+}
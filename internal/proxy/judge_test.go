@@ -0,0 +1,105 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+func TestParseJudgeReply(t *testing.T) {
+	choices := map[string]string{"coder": "", "coder-large": "", "chat": ""}
+	tests := []struct {
+		reply, want string
+	}{
+		{"coder", "coder"},
+		{" `Coder-Large`.\n", "coder-large"},
+		{"<think>chat or coder?</think>coder", "coder"},
+		{"I would pick coder-large for this.", "coder-large"},
+		{"<think>maybe chat", ""},
+		{"chat or coder", ""},
+		{"none", ""},
+	}
+	for _, tt := range tests {
+		got, ok := parseJudgeReply(tt.reply, choices)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("parseJudgeReply(%q) = %q, %v; want %q", tt.reply, got, ok, tt.want)
+		}
+	}
+}
+
+func TestJudgeRouteCachesPerConversation(t *testing.T) {
+	var judged atomic.Int32
+	var answer atomic.Value
+	answer.Store("big")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req struct {
+			Model string `json:"model"`
+		}
+		json.Unmarshal(body, &req)
+		content := "ok"
+		if req.Model == "judge-m" {
+			judged.Add(1)
+			content = answer.Load().(string)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []map[string]interface{}{{
+				"message":       map[string]string{"role": "assistant", "content": content},
+				"finish_reason": "stop",
+			}},
+			"usage": map[string]int{"prompt_tokens": 10, "completion_tokens": 1},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	resolver, err := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "mock",
+			Endpoint: srv.URL + "/v1",
+			Models:   map[string]config.ModelConfig{"judge": {Model: "judge-m"}, "small": {Model: "small-m"}, "big": {Model: "big-m"}},
+		}},
+		Judges: map[string]config.JudgeConfig{"pick": {
+			Judge:   "judge",
+			Choices: map[string]string{"small": "quick edits", "big": "design and debugging"},
+			Default: "small",
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	infra := setupInfra(t, resolver)
+	request := func(first string, turns ...string) string {
+		t.Helper()
+		msgs := []map[string]string{{"role": "user", "content": first}}
+		for _, turn := range turns {
+			msgs = append(msgs, map[string]string{"role": "assistant", "content": "ok"}, map[string]string{"role": "user", "content": turn})
+		}
+		body, _ := json.Marshal(map[string]interface{}{
+			"model":      "claude-sonnet-4-20250514",
+			"system":     "<!-- @proxy-local-route:af83e9 model=judge:pick -->",
+			"messages":   msgs,
+			"max_tokens": 1024,
+		})
+		status, resp, _ := proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+		if status != 200 {
+			t.Fatalf("%d %s", status, resp)
+		}
+		return infra.proxy.Activity().Recent[0].Model
+	}
+
+	if got := request("why does this deadlock?"); got != "big-m" {
+		t.Errorf("first turn went to %s, want the judge's pick big-m", got)
+	}
+	if got := request("why does this deadlock?", "and how do I fix it?"); got != "big-m" || judged.Load() != 1 {
+		t.Errorf("second turn went to %s after %d judge calls; want the cached pick", got, judged.Load())
+	}
+	answer.Store("no idea")
+	if got := request("rename foo"); got != "small-m" || judged.Load() != 2 {
+		t.Errorf("new conversation went to %s after %d judge calls; want the default after asking", got, judged.Load())
+	}
+}
@@ -0,0 +1,36 @@
+package proxy
+
+import (
+	"log"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+// pickMember returns the first member of a label group that is within its
+// budget and has capacity, holding a slot on its provider that release
+// frees. Members that can't take the request are skipped with a log line.
+// The last member is returned unchecked with a nil release, so the caller
+// refuses it with the usual budget or capacity error. api: anthropic members
+// are skipped unless bridged is set, as on the OpenAI-compatible listener.
+func (p *Proxy) pickMember(group string, members []config.ResolvedModel, bridged bool) (config.ResolvedModel, func()) {
+	last := len(members) - 1
+	for i, m := range members[:last] {
+		next := members[i+1].Label
+		if m.API == config.APIAnthropic && !bridged {
+			log.Printf("LOCAL_GROUP %s: %s is on an api: anthropic provider → %s", group, m.Label, next)
+			continue
+		}
+		use, ok, spent := p.applyBudget(m)
+		if !ok {
+			log.Printf("LOCAL_GROUP %s: %s has spent $%.2f of its $%.2f daily budget → %s", group, m.Label, spent, m.Budget.DailyUSD, next)
+			continue
+		}
+		release, err := p.hosts.acquire(use)
+		if err != nil {
+			log.Printf("LOCAL_GROUP %s: %s unavailable: %v → %s", group, use.Label, err, next)
+			continue
+		}
+		return use, release
+	}
+	return members[last], nil
+}
@@ -0,0 +1,89 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+func TestLabelGroupSkipsMemberOverBudget(t *testing.T) {
+	port, _, _ := capturingMockOpenAI(t)
+	resolver, err := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "mock",
+			Endpoint: fmt.Sprintf("http://127.0.0.1:%d/v1", port),
+			Models: map[string]config.ModelConfig{
+				"paid": {Model: "m1", Price: &config.Price{Input: 100_000}, Budget: &config.Budget{DailyUSD: 1}},
+				"free": {Model: "m2"},
+			},
+		}},
+		Aliases:     map[string]string{"coder": "cheap"},
+		LabelGroups: map[string][]string{"cheap": {"paid", "free"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	infra := setupInfra(t, resolver)
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"system":     "<!-- @proxy-local-route:af83e9 model=coder -->",
+		"messages":   []map[string]string{{"role": "user", "content": "hi"}},
+		"max_tokens": 64,
+	})
+
+	for i, want := range []string{"m1", "m2", "m2"} {
+		status, resp, _ := proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+		if status != 200 {
+			t.Fatalf("request %d: %d %s", i+1, status, resp)
+		}
+		ev := infra.proxy.Activity().Recent[0]
+		if ev.Model != want || ev.Label != "coder" {
+			t.Errorf("request %d went to %s as %q, want %s as coder", i+1, ev.Model, ev.Label, want)
+		}
+	}
+	if b := infra.proxy.Metrics().Budgets; len(b) != 1 || b[0].Label != "paid" || b[0].SpentUSD != 1 {
+		t.Errorf("spend should be booked to the member label: %+v", b)
+	}
+}
+
+func TestLabelGroupFailsOverOnOpenAIListener(t *testing.T) {
+	port, getLastBody, _ := capturingMockOpenAI(t)
+	resolver, err := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "mock",
+			Endpoint: fmt.Sprintf("http://127.0.0.1:%d/v1", port),
+			Models: map[string]config.ModelConfig{
+				"paid": {Model: "m1", Price: &config.Price{Input: 100_000}, Budget: &config.Budget{DailyUSD: 1}},
+				"free": {Model: "m2"},
+			},
+		}},
+		LabelGroups: map[string][]string{"cheap": {"paid", "free"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(New(nil, WithModelResolver(resolver)).OpenAIHandler())
+	t.Cleanup(srv.Close)
+
+	for i, want := range []string{"m1", "m2"} {
+		resp, err := http.Post(srv.URL+"/v1/chat/completions", "application/json",
+			strings.NewReader(`{"model":"cheap","messages":[{"role":"user","content":"hi"}]}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != 200 {
+			t.Fatalf("request %d: status %d", i+1, resp.StatusCode)
+		}
+		var sent map[string]interface{}
+		json.Unmarshal(getLastBody(), &sent)
+		if sent["model"] != want {
+			t.Errorf("request %d went to %v, want %s", i+1, sent["model"], want)
+		}
+	}
+}
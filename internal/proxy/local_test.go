@@ -6,13 +6,19 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
-	"github.com/peter-wagstaff/claude-hybrid-router/internal/config"
 	"github.com/peter-wagstaff/claude-hybrid-router/internal/testutil"
-	"github.com/peter-wagstaff/claude-hybrid-router/internal/translate"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/translate"
 )
 
 // capturingMockOpenAI starts a mock OpenAI server that captures the last request body and headers.
@@ -297,6 +303,57 @@ func TestLocalRouteProviderDown(t *testing.T) {
 	}
 }
 
+func TestLocalRouteProviderErrorTypes(t *testing.T) {
+	var providerStatus atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if providerStatus.Load() == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "7")
+		}
+		w.WriteHeader(int(providerStatus.Load()))
+		fmt.Fprint(w, `{"error":{"message":"nope"}}`)
+	}))
+	t.Cleanup(srv.Close)
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "mock",
+			Endpoint: srv.URL + "/v1",
+			Models:   map[string]config.ModelConfig{"m": {Model: "x"}},
+		}},
+	})
+	infra := setupInfra(t, resolver)
+
+	tests := []struct {
+		provider, status int
+		errType          string
+	}{
+		{400, 400, "invalid_request_error"},
+		{422, 400, "invalid_request_error"},
+		{401, 401, "authentication_error"},
+		{403, 403, "authentication_error"},
+		{404, 404, "not_found_error"},
+		{429, 429, "rate_limit_error"},
+		{500, 502, "api_error"},
+		{503, 529, "overloaded_error"},
+	}
+	for i, tt := range tests {
+		providerStatus.Store(int32(tt.provider))
+		body, _ := json.Marshal(map[string]interface{}{
+			"model":    "claude-sonnet-4-20250514",
+			"system":   "<!-- @proxy-local-route:af83e9 model=m -->",
+			"messages": []map[string]string{{"role": "user", "content": fmt.Sprintf("request %d", i)}},
+		})
+		status, header, respBody := proxyRequestHeaders(t, infra, "POST", "/v1/messages", body, nil)
+		var errResp translate.AErrorResponse
+		json.Unmarshal([]byte(respBody), &errResp)
+		if status != tt.status || errResp.Error.Type != tt.errType {
+			t.Errorf("provider %d: got %d %s, want %d %s", tt.provider, status, errResp.Error.Type, tt.status, tt.errType)
+		}
+		if tt.provider == 429 && header.Get("Retry-After") != "7" {
+			t.Errorf("provider 429: Retry-After %q, want the provider's 7", header.Get("Retry-After"))
+		}
+	}
+}
+
 func TestLocalRouteResponseReadError(t *testing.T) {
 	// Start a server that sends an incomplete response body (triggers read error)
 	ln, err := net.Listen("tcp", "127.0.0.1:0")
@@ -683,3 +740,736 @@ func TestLocalRouteStreamTranslationError(t *testing.T) {
 		t.Error("expected message_stop event in response")
 	}
 }
+
+func TestLocalRouteRawOpenAISkipsTranslation(t *testing.T) {
+	port, getLastBody, _ := capturingMockOpenAI(t)
+
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:      "mock",
+			Endpoint:  fmt.Sprintf("http://127.0.0.1:%d/v1", port),
+			Transform: []string{"tooluse"},
+			Models:    map[string]config.ModelConfig{"raw_model": {Model: "qwen3:32b"}},
+		}},
+	})
+	infra := setupInfra(t, resolver)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":  "claude-sonnet-4-20250514",
+		"system": "<!-- @proxy-local-route:af83e9 model=raw_model raw=openai --> /no_think",
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": []map[string]string{{"type": "text", "text": "exact"}}},
+		},
+		"max_tokens": 100,
+		"tools":      []map[string]interface{}{{"name": "Read", "input_schema": map[string]string{"type": "object"}}},
+	})
+	status, respBody, _ := proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+	if status != 200 {
+		t.Fatalf("expected 200, got %d: %s", status, respBody)
+	}
+
+	var req map[string]interface{}
+	json.Unmarshal(getLastBody(), &req)
+	msgs := req["messages"].([]interface{})
+	// Content block arrays stay verbatim instead of being flattened.
+	if _, ok := msgs[1].(map[string]interface{})["content"].([]interface{}); !ok {
+		t.Errorf("expected verbatim content array, got %v", msgs[1])
+	}
+	// Request transforms (tooluse would inject ExitTool) are skipped.
+	if tools := req["tools"].([]interface{}); len(tools) != 1 {
+		t.Errorf("expected request transforms to be skipped, got %d tools", len(tools))
+	}
+
+	var resp translate.AResponse
+	if err := json.Unmarshal([]byte(respBody), &resp); err != nil || resp.Type != "message" {
+		t.Errorf("response should still be translated to Anthropic format: %s", respBody)
+	}
+}
+
+func TestLocalRouteSendsGroupHeaders(t *testing.T) {
+	oaiPort, _, getLastHeaders := capturingMockOpenAI(t)
+
+	resolver, err := config.NewModelResolver(&config.ProvidersConfig{
+		Groups: map[string]config.GroupConfig{"shared": {
+			Endpoint: fmt.Sprintf("http://127.0.0.1:%d/v1", oaiPort),
+			APIKey:   "group-key",
+			Headers:  map[string]string{"X-Title": "hybrid", "HTTP-Referer": "https://example.com"},
+		}},
+		Providers: []config.ProviderConfig{{
+			Name:    "mock",
+			Group:   "shared",
+			Headers: map[string]string{"X-Title": "override"},
+			Models:  map[string]config.ModelConfig{"test_model": {Model: "mock-model-v1"}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("resolver: %v", err)
+	}
+
+	infra := setupInfra(t, resolver)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"system":     "<!-- @proxy-local-route:af83e9 model=test_model --> You are helpful",
+		"messages":   []map[string]string{{"role": "user", "content": "hello"}},
+		"max_tokens": 1024,
+	})
+	status, respBody, _ := proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+	if status != 200 {
+		t.Fatalf("expected 200, got %d: %s", status, respBody)
+	}
+
+	headers := getLastHeaders()
+	if got := headers.Get("Authorization"); got != "Bearer group-key" {
+		t.Errorf("Authorization = %q, want group key", got)
+	}
+	if got := headers.Get("X-Title"); got != "override" {
+		t.Errorf("X-Title = %q, want provider override", got)
+	}
+	if got := headers.Get("HTTP-Referer"); got != "https://example.com" {
+		t.Errorf("HTTP-Referer = %q, want group header", got)
+	}
+}
+
+func TestLocalRouteCountsTransformRepairs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"c1","object":"chat.completion","model":"mock-model-v1","choices":[{"index":0,`+
+			`"message":{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function",`+
+			`"function":{"name":"Read","arguments":"{'file_path': '/tmp/x',}"}}]},"finish_reason":"tool_calls"}],`+
+			`"usage":{"prompt_tokens":5,"completion_tokens":5,"total_tokens":10}}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:      "mock",
+			Endpoint:  srv.URL + "/v1",
+			Transform: []string{"enhancetool"},
+			Models:    map[string]config.ModelConfig{"test_model": {Model: "mock-model-v1"}},
+		}},
+	})
+	infra := setupInfra(t, resolver)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"system":     "<!-- @proxy-local-route:af83e9 model=test_model --> You are helpful",
+		"messages":   []map[string]string{{"role": "user", "content": "read a file"}},
+		"max_tokens": 1024,
+	})
+	status, respBody, _ := proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+	if status != 200 {
+		t.Fatalf("expected 200, got %d: %s", status, respBody)
+	}
+
+	counts := infra.proxy.Metrics().Transforms
+	if len(counts) != 1 {
+		t.Fatalf("expected one transform row, got %+v", counts)
+	}
+	want := translate.TransformCount{Transform: "enhancetool", Provider: "mock", Model: "mock-model-v1", Repairs: 1}
+	if counts[0] != want {
+		t.Errorf("got %+v, want %+v", counts[0], want)
+	}
+}
+
+func TestLocalRouteCountTokens(t *testing.T) {
+	var hits int
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits++
+		mu.Unlock()
+		if r.URL.Path == "/tokenize" {
+			fmt.Fprint(w, `{"tokens":[1,2,3,4,5]}`)
+			return
+		}
+		http.Error(w, "count_tokens must not reach the chat endpoint", 500)
+	}))
+	t.Cleanup(srv.Close)
+
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:      "mock",
+			Endpoint:  srv.URL + "/v1",
+			Tokenizer: "llamacpp",
+			Models:    map[string]config.ModelConfig{"test_model": {Model: "mock-model-v1"}},
+		}},
+	})
+	infra := setupInfra(t, resolver)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":    "claude-sonnet-4-20250514",
+		"system":   "<!-- @proxy-local-route:af83e9 model=test_model --> You are helpful",
+		"messages": []map[string]string{{"role": "user", "content": "hello"}},
+	})
+	status, respBody, _ := proxyRequest(t, infra, "POST", "/v1/messages/count_tokens", body, nil)
+	if status != 200 {
+		t.Fatalf("expected 200, got %d: %s", status, respBody)
+	}
+	var resp struct {
+		InputTokens int `json:"input_tokens"`
+	}
+	if err := json.Unmarshal([]byte(respBody), &resp); err != nil {
+		t.Fatalf("parse response: %v\nbody: %s", err, respBody)
+	}
+	// 5 counted by the server plus request and message overhead.
+	if resp.InputTokens != 5+3+4 {
+		t.Errorf("input_tokens = %d, want 12", resp.InputTokens)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if hits != 1 {
+		t.Errorf("expected one /tokenize call, got %d backend hits", hits)
+	}
+}
+
+// slowStreamServer streams three text chunks, waiting delay before the first
+// and between the rest.
+func slowStreamServer(t *testing.T, firstDelay, gap time.Duration) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(200)
+		w.(http.Flusher).Flush()
+		select {
+		case <-time.After(firstDelay):
+		case <-r.Context().Done():
+			return
+		}
+		for i, word := range []string{"one ", "two ", "three"} {
+			if i > 0 {
+				time.Sleep(gap)
+			}
+			fmt.Fprintf(w, "data: {\"id\":\"c1\",\"model\":\"m\",\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", word)
+			w.(http.Flusher).Flush()
+		}
+		fmt.Fprint(w, "data: {\"id\":\"c1\",\"model\":\"m\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n")
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func streamBody() []byte {
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"system":     "<!-- @proxy-local-route:af83e9 model=test_model --> You are helpful",
+		"messages":   []map[string]string{{"role": "user", "content": "count"}},
+		"max_tokens": 1024,
+		"stream":     true,
+	})
+	return body
+}
+
+func TestLocalRouteFirstTokenTimeout(t *testing.T) {
+	srv := slowStreamServer(t, 5*time.Second, 0)
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:              "mock",
+			Endpoint:          srv.URL + "/v1",
+			FirstTokenTimeout: 200 * time.Millisecond,
+			Models: map[string]config.ModelConfig{
+				"test_model": {Model: "m", Fallback: "backup"},
+				"backup":     {Model: "m"},
+			},
+		}},
+	})
+	infra := setupInfra(t, resolver)
+
+	start := time.Now()
+	status, respBody, _ := proxyRequest(t, infra, "POST", "/v1/messages", streamBody(), nil)
+	if status != 529 {
+		t.Fatalf("expected 529, got %d: %s", status, respBody)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("timeout took %s, want about 200ms", elapsed)
+	}
+	for _, want := range []string{"overloaded_error", "[FIRST_TOKEN]", "backup"} {
+		if !strings.Contains(respBody, want) {
+			t.Errorf("response missing %q: %s", want, respBody)
+		}
+	}
+}
+
+func TestLocalRouteFirstTokenTimeoutAllowsLongStreams(t *testing.T) {
+	// The stream outlasts the provider's total timeout, but its first token
+	// arrives in time, so it must complete.
+	srv := slowStreamServer(t, 50*time.Millisecond, 200*time.Millisecond)
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:              "mock",
+			Endpoint:          srv.URL + "/v1",
+			Timeout:           250 * time.Millisecond,
+			FirstTokenTimeout: time.Second,
+			Models:            map[string]config.ModelConfig{"test_model": {Model: "m"}},
+		}},
+	})
+	infra := setupInfra(t, resolver)
+
+	status, respBody, _ := proxyRequest(t, infra, "POST", "/v1/messages", streamBody(), nil)
+	if status != 200 {
+		t.Fatalf("expected 200, got %d: %s", status, respBody)
+	}
+	assertSSELifecycle(t, respBody)
+	if !strings.Contains(respBody, "three") {
+		t.Errorf("stream cut short: %s", respBody)
+	}
+	if strings.Contains(respBody, "event: error") {
+		t.Errorf("unexpected stream error: %s", respBody)
+	}
+}
+
+func TestLocalRouteMarkerOverrides(t *testing.T) {
+	oaiPort, getLastReq, _ := capturingMockOpenAI(t)
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "mock",
+			Endpoint: fmt.Sprintf("http://127.0.0.1:%d/v1", oaiPort),
+			Models: map[string]config.ModelConfig{"test_model": {
+				Model:     "mock-model-v1",
+				MaxTokens: 4096,
+				Params:    map[string]interface{}{"seed": 7},
+			}},
+		}},
+	})
+	infra := setupInfra(t, resolver)
+	send := func(marker string) (int, string) {
+		body, _ := json.Marshal(map[string]interface{}{
+			"model":       "claude-sonnet-4-20250514",
+			"system":      marker + " You are helpful",
+			"messages":    []map[string]string{{"role": "user", "content": "hi"}},
+			"max_tokens":  8000,
+			"temperature": 1,
+		})
+		status, resp, _ := proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+		return status, resp
+	}
+
+	status, resp := send("<!-- @proxy-local-route:af83e9 model=test_model temp=0.2 max_tokens=2048 transform=+customparams -->")
+	if status != 200 {
+		t.Fatalf("status %d: %s", status, resp)
+	}
+	var oaiReq map[string]interface{}
+	if err := json.Unmarshal(getLastReq(), &oaiReq); err != nil {
+		t.Fatalf("parse captured request: %v", err)
+	}
+	if oaiReq["temperature"] != 0.2 || oaiReq["max_completion_tokens"] != float64(2048) || oaiReq["seed"] != float64(7) {
+		t.Errorf("overrides not applied: temperature %v max_completion_tokens %v seed %v",
+			oaiReq["temperature"], oaiReq["max_completion_tokens"], oaiReq["seed"])
+	}
+
+	before := len(getLastReq())
+	status, resp = send("<!-- @proxy-local-route:af83e9 model=test_model temprature=0.2 -->")
+	if status != 400 || !strings.Contains(resp, "invalid_request_error") || !strings.Contains(resp, `[MARKER] Malformed routing marker: unknown option \"temprature\"`) {
+		t.Errorf("malformed marker: %d %s, want 400 naming the option", status, resp)
+	}
+	if len(getLastReq()) != before {
+		t.Error("malformed marker reached the provider")
+	}
+}
+
+func TestLocalRouteAPIKeyFile(t *testing.T) {
+	oaiPort, _, getLastHeaders := capturingMockOpenAI(t)
+	keyPath := filepath.Join(t.TempDir(), "key")
+	os.WriteFile(keyPath, []byte("sk-from-file\n"), 0600)
+
+	resolver, err := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:       "mock",
+			Endpoint:   fmt.Sprintf("http://127.0.0.1:%d/v1", oaiPort),
+			APIKeyFile: keyPath,
+			Models:     map[string]config.ModelConfig{"test_model": {Model: "mock-model-v1"}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("resolver: %v", err)
+	}
+	infra := setupInfra(t, resolver)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"system":     "<!-- @proxy-local-route:af83e9 model=test_model --> You are helpful",
+		"messages":   []map[string]string{{"role": "user", "content": "hello"}},
+		"max_tokens": 1024,
+	})
+	status, respBody, _ := proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+	if status != 200 {
+		t.Fatalf("expected 200, got %d: %s", status, respBody)
+	}
+	if got := getLastHeaders().Get("Authorization"); got != "Bearer sk-from-file" {
+		t.Errorf("Authorization = %q, want key from file", got)
+	}
+
+	os.Remove(keyPath)
+	body = []byte(strings.Replace(string(body), "hello", "hello again", 1))
+	status, respBody, _ = proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+	if status != 502 || !strings.Contains(respBody, "[SECRET]") {
+		t.Errorf("unreadable key file: %d %s, want 502 [SECRET]", status, respBody)
+	}
+}
+
+func TestLocalRoutePatternLabel(t *testing.T) {
+	oaiPort, getLastReq, _ := capturingMockOpenAI(t)
+	resolver, err := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "mock",
+			Endpoint: fmt.Sprintf("http://127.0.0.1:%d/v1", oaiPort),
+			Models:   map[string]config.ModelConfig{"ollama/*": {Model: "{1}"}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("resolver: %v", err)
+	}
+	infra := setupInfra(t, resolver)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"system":     "<!-- @proxy-local-route:af83e9 model=ollama/qwen3:32b --> You are helpful",
+		"messages":   []map[string]string{{"role": "user", "content": "hello"}},
+		"max_tokens": 1024,
+	})
+	status, respBody, _ := proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+	if status != 200 {
+		t.Fatalf("expected 200, got %d: %s", status, respBody)
+	}
+	var oaiReq map[string]interface{}
+	if err := json.Unmarshal(getLastReq(), &oaiReq); err != nil {
+		t.Fatalf("parse captured request: %v", err)
+	}
+	if oaiReq["model"] != "qwen3:32b" {
+		t.Errorf("backend model = %v, want qwen3:32b", oaiReq["model"])
+	}
+}
+
+func TestLocalRouteIPv6Provider(t *testing.T) {
+	ln, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("no IPv6 loopback: %v", err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"id":"c1","choices":[{"message":{"role":"assistant","content":"over v6"},"finish_reason":"stop"}]}`)
+	}))
+	srv.Listener = ln
+	srv.Start()
+	t.Cleanup(srv.Close)
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	// The host comes from a var holding a bare IPv6 address, as
+	// OLLAMA_HOST=::1 would.
+	resolver, err := config.NewModelResolver(&config.ProvidersConfig{
+		Vars: map[string]string{"v6host": "::1"},
+		Providers: []config.ProviderConfig{{
+			Name:     "v6",
+			Endpoint: fmt.Sprintf("http://{v6host}:%d/v1", port),
+			Models:   map[string]config.ModelConfig{"test_model": {Model: "m"}},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	infra := setupInfra(t, resolver)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"system":     "<!-- @proxy-local-route:af83e9 model=test_model --> You are helpful",
+		"messages":   []map[string]string{{"role": "user", "content": "hello"}},
+		"max_tokens": 64,
+	})
+	status, respBody, _ := proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+	if status != 200 || !strings.Contains(respBody, "over v6") {
+		t.Errorf("got %d: %s", status, respBody)
+	}
+}
+
+// A tool call ID cleaned for Claude Code in one turn reaches the provider as
+// the ID it issued when the tool_result comes back in the next.
+func TestLocalRouteRestoresProviderToolIDs(t *testing.T) {
+	var mu sync.Mutex
+	var lastBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		lastBody = body
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"c1","object":"chat.completion","model":"m","choices":[{"index":0,`+
+			`"message":{"role":"assistant","content":null,"tool_calls":[{"id":"functions.Read:0","type":"function",`+
+			`"function":{"name":"Read","arguments":"{}"}}]},"finish_reason":"tool_calls"}],`+
+			`"usage":{"prompt_tokens":5,"completion_tokens":5,"total_tokens":10}}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "mock",
+			Endpoint: srv.URL + "/v1",
+			Models:   map[string]config.ModelConfig{"test_model": {Model: "m"}},
+		}},
+	})
+	infra := setupInfra(t, resolver)
+
+	system := "<!-- @proxy-local-route:af83e9 model=test_model --> You are helpful"
+	first := []interface{}{map[string]interface{}{"role": "user", "content": "read a file"}}
+	body, _ := json.Marshal(map[string]interface{}{
+		"model": "claude-sonnet-4-20250514", "system": system, "messages": first, "max_tokens": 1024,
+	})
+	status, respBody, _ := proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+	if status != 200 {
+		t.Fatalf("expected 200, got %d: %s", status, respBody)
+	}
+	var resp translate.AResponse
+	json.Unmarshal([]byte(respBody), &resp)
+	if len(resp.Content) != 1 || resp.Content[0].ID != "functions_Read_0" {
+		t.Fatalf("unexpected content: %+v", resp.Content)
+	}
+
+	next := append(first,
+		map[string]interface{}{"role": "assistant", "content": resp.Content},
+		map[string]interface{}{"role": "user", "content": []interface{}{
+			map[string]interface{}{"type": "tool_result", "tool_use_id": "functions_Read_0", "content": "data"},
+		}},
+	)
+	body, _ = json.Marshal(map[string]interface{}{
+		"model": "claude-sonnet-4-20250514", "system": system, "messages": next, "max_tokens": 1024,
+	})
+	if status, respBody, _ = proxyRequest(t, infra, "POST", "/v1/messages", body, nil); status != 200 {
+		t.Fatalf("expected 200, got %d: %s", status, respBody)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	var sent translate.ORequest
+	if err := json.Unmarshal(lastBody, &sent); err != nil {
+		t.Fatal(err)
+	}
+	if got := sent.Messages[2].ToolCalls[0].ID; got != "functions.Read:0" {
+		t.Errorf("assistant tool call ID = %q, want provider's", got)
+	}
+	if got := sent.Messages[3].ToolCallID; got != "functions.Read:0" {
+		t.Errorf("tool message ID = %q, want provider's", got)
+	}
+}
+
+func TestLocalRouteRequestIDs(t *testing.T) {
+	var mu sync.Mutex
+	var lastBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		lastBody = body
+		mu.Unlock()
+		w.Header().Set("X-Request-Id", "req-provider-42")
+		if strings.Contains(string(body), `"stream":true`) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"id\":\"c1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"hi\"}}]}\n\n"+
+				"data: {\"id\":\"c1\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"c1","object":"chat.completion","model":"m","choices":[{"index":0,`+
+			`"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}],`+
+			`"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "mock",
+			Endpoint: srv.URL + "/v1",
+			Models:   map[string]config.ModelConfig{"test_model": {Model: "m"}},
+		}},
+	})
+	infra := setupInfra(t, resolver)
+
+	for _, stream := range []bool{false, true} {
+		body, _ := json.Marshal(map[string]interface{}{
+			"model":      "claude-sonnet-4-20250514",
+			"system":     "<!-- @proxy-local-route:af83e9 model=test_model --> You are helpful",
+			"messages":   []interface{}{map[string]interface{}{"role": "user", "content": "hi"}},
+			"max_tokens": 1024,
+			"stream":     stream,
+			"metadata":   map[string]interface{}{"user_id": "user_abc"},
+		})
+		status, header, respBody := proxyRequestHeaders(t, infra, "POST", "/v1/messages", body, nil)
+		if status != 200 {
+			t.Fatalf("stream=%v: expected 200, got %d: %s", stream, status, respBody)
+		}
+		if got := header.Get("Request-Id"); got != "req-provider-42" {
+			t.Errorf("stream=%v: Request-Id = %q, want the provider's", stream, got)
+		}
+		mu.Lock()
+		var sent translate.ORequest
+		json.Unmarshal(lastBody, &sent)
+		mu.Unlock()
+		if sent.User != "user_abc" {
+			t.Errorf("stream=%v: provider got user %q, want metadata.user_id", stream, sent.User)
+		}
+	}
+}
+
+func TestLocalRouteStreamsInterleavedThinking(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, delta := range []string{
+			`{"reasoning_content":"Look at the file."}`,
+			`{"content":"Reading it."}`,
+			`{"reasoning_content":"Use Read."}`,
+			`{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"Read","arguments":"{\"path\""}}]}`,
+			`{"reasoning_content":"With the path."}`,
+			`{"tool_calls":[{"index":0,"function":{"arguments":":\"/x\"}"}}]}`,
+		} {
+			fmt.Fprintf(w, "data: {\"id\":\"c1\",\"choices\":[{\"index\":0,\"delta\":%s}]}\n\n", delta)
+		}
+		fmt.Fprint(w, "data: {\"id\":\"c1\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"tool_calls\"}]}\n\ndata: [DONE]\n\n")
+	}))
+	t.Cleanup(srv.Close)
+
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:      "mock",
+			Endpoint:  srv.URL + "/v1",
+			Transform: []string{"reasoning"},
+			Models:    map[string]config.ModelConfig{"test_model": {Model: "m"}},
+		}},
+	})
+	infra := setupInfra(t, resolver)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"system":     "<!-- @proxy-local-route:af83e9 model=test_model --> You are helpful",
+		"messages":   []map[string]string{{"role": "user", "content": "read /x"}},
+		"max_tokens": 1024,
+		"stream":     true,
+	})
+	status, respBody, _ := proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+	if status != 200 {
+		t.Fatalf("expected 200, got %d: %s", status, respBody)
+	}
+
+	v := translate.NewSSEValidator(func(msg string) { t.Errorf("invalid stream: %s", msg) })
+	v.Write([]byte(respBody))
+	v.Close()
+
+	var blocks []string
+	for _, line := range strings.Split(respBody, "\n") {
+		var ev struct {
+			Type  string `json:"type"`
+			Block struct {
+				Type string `json:"type"`
+			} `json:"content_block"`
+		}
+		if json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev) == nil && ev.Type == "content_block_start" {
+			blocks = append(blocks, ev.Block.Type)
+		}
+	}
+	want := []string{"thinking", "text", "thinking", "tool_use", "thinking"}
+	if !reflect.DeepEqual(blocks, want) {
+		t.Errorf("blocks = %v, want %v", blocks, want)
+	}
+	if strings.Count(respBody, `"type":"signature_delta"`) != 3 {
+		t.Errorf("expected every thinking block signed:\n%s", respBody)
+	}
+	if !strings.Contains(respBody, `{"delta":{"partial_json":":\"/x\"}","type":"input_json_delta"},"index":3`) {
+		t.Errorf("tool arguments split from their block:\n%s", respBody)
+	}
+}
+
+func TestLocalRouteThinkingSignatures(t *testing.T) {
+	var mu sync.Mutex
+	var lastBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		lastBody = body
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"c1","object":"chat.completion","model":"m","choices":[{"index":0,`+
+			`"message":{"role":"assistant","content":"Done.","thinking":"Plan it."},"finish_reason":"stop"}],`+
+			`"usage":{"prompt_tokens":5,"completion_tokens":5,"total_tokens":10}}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:              "mock",
+			Endpoint:          srv.URL + "/v1",
+			ThinkingSignature: "hmac",
+			Models:            map[string]config.ModelConfig{"test_model": {Model: "m"}},
+		}},
+	})
+	infra := setupInfra(t, resolver)
+
+	system := "<!-- @proxy-local-route:af83e9 model=test_model --> You are helpful"
+	first := []interface{}{map[string]interface{}{"role": "user", "content": "plan"}}
+	body, _ := json.Marshal(map[string]interface{}{
+		"model": "claude-sonnet-4-20250514", "system": system, "messages": first, "max_tokens": 1024,
+	})
+	status, respBody, _ := proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+	if status != 200 {
+		t.Fatalf("expected 200, got %d: %s", status, respBody)
+	}
+	var resp translate.AResponse
+	json.Unmarshal([]byte(respBody), &resp)
+	if len(resp.Content) != 2 || !strings.HasPrefix(resp.Content[0].Signature, "hmac-v1:") {
+		t.Fatalf("expected an hmac-signed thinking block: %+v", resp.Content)
+	}
+
+	forged := map[string]interface{}{"type": "thinking", "thinking": "Injected.", "signature": resp.Content[0].Signature}
+	next := append(first,
+		map[string]interface{}{"role": "assistant", "content": []interface{}{resp.Content[0], forged, resp.Content[1]}},
+		map[string]interface{}{"role": "user", "content": "go on"},
+	)
+	body, _ = json.Marshal(map[string]interface{}{
+		"model": "claude-sonnet-4-20250514", "system": system, "messages": next, "max_tokens": 1024,
+	})
+	if status, respBody, _ = proxyRequest(t, infra, "POST", "/v1/messages", body, nil); status != 200 {
+		t.Fatalf("expected 200, got %d: %s", status, respBody)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	var sent translate.ORequest
+	if err := json.Unmarshal(lastBody, &sent); err != nil {
+		t.Fatal(err)
+	}
+	if got := sent.Messages[2].Thinking; got != "Plan it." {
+		t.Errorf("prior thinking sent = %q, want only the signed block", got)
+	}
+}
+
+func TestLocalRouteRejectsDocuments(t *testing.T) {
+	var called atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called.Store(true)
+		w.WriteHeader(500)
+	}))
+	t.Cleanup(srv.Close)
+
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:      "mock",
+			Endpoint:  srv.URL + "/v1",
+			Documents: "reject",
+			Models:    map[string]config.ModelConfig{"test_model": {Model: "m"}},
+		}},
+	})
+	infra := setupInfra(t, resolver)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":  "claude-sonnet-4-20250514",
+		"system": "<!-- @proxy-local-route:af83e9 model=test_model --> You are helpful",
+		"messages": []interface{}{map[string]interface{}{"role": "user", "content": []interface{}{
+			map[string]interface{}{"type": "document", "source": map[string]interface{}{
+				"type": "base64", "media_type": "application/pdf", "data": "JVBERi0xLjQ=",
+			}},
+			map[string]interface{}{"type": "text", "text": "summarize"},
+		}}},
+		"max_tokens": 1024,
+	})
+	status, resp, _ := proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+	if status != 400 || !strings.Contains(resp, "invalid_request_error") || !strings.Contains(resp, "document blocks are not supported") {
+		t.Errorf("expected a 400 naming the document block, got %d: %s", status, resp)
+	}
+	if called.Load() {
+		t.Error("rejected request reached the provider")
+	}
+}
@@ -0,0 +1,38 @@
+package proxy
+
+import (
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/mitm"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/translate"
+)
+
+// Metrics is a point-in-time snapshot of proxy counters, served by the admin API.
+type Metrics struct {
+	Pools         map[string]PoolStats       `json:"pools"`                // keyed by provider name
+	Admission     AdmissionStats             `json:"admission"`            // tunnel slots and the CONNECT wait queue
+	CertCache     *mitm.Stats                `json:"cert_cache,omitempty"` // MITM leaf certificate cache
+	ClientStreams ClientStreamStats          `json:"client_streams"`       // translated SSE delivery to clients
+	Transforms    []translate.TransformCount `json:"transforms"`           // per transform, provider and model
+	Bypass        BypassStats                `json:"bypass"`               // CONNECTs tunneled without MITM
+	Access        AccessStats                `json:"access"`               // allowed_clients and per-client rate limits
+	Budgets       []BudgetStatus             `json:"budgets"`              // labels with a daily budget
+	Speed         []SpeedStats               `json:"speed"`                // time to first token and tokens/sec of local streams
+}
+
+// Metrics returns current proxy counters.
+func (p *Proxy) Metrics() Metrics {
+	m := Metrics{
+		Pools:         p.pools.stats(),
+		Admission:     p.admit.stats(),
+		ClientStreams: p.clients.snapshot(),
+		Transforms:    p.transforms.Snapshot(),
+		Bypass:        p.bypass.snapshot(),
+		Access:        p.access.snapshot(),
+		Budgets:       p.budgetStatus(),
+		Speed:         p.activity.speedStats(),
+	}
+	if p.certCache != nil {
+		stats := p.certCache.Stats()
+		m.CertCache = &stats
+	}
+	return m
+}
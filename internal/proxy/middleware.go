@@ -0,0 +1,205 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/tracing"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/translate"
+)
+
+// Middleware hooks into every request the proxy answers inside an
+// intercepted tunnel, for audit, policy or rewriting without changing
+// forwardLocal or forwardUpstream. Hooks run on the tunnel's goroutine, so
+// a slow hook only delays its own request, but one Middleware serves every
+// tunnel and must be safe for concurrent use. Embed NopMiddleware to
+// implement only some hooks.
+type Middleware interface {
+	// OnRequest runs before the request is routed. Changes to ex.Header
+	// and ex.Body are what gets routed and forwarded. A non-nil error
+	// refuses the request with a 403 carrying its message.
+	OnRequest(ex *Exchange) error
+
+	// OnStreamEvent runs for each server-sent event of a streamed
+	// response before the client gets it: the translated events of a
+	// local route, and upstream event streams that aren't compressed.
+	// Changes to ev are sent; set ev.Drop to leave the event out.
+	OnStreamEvent(ex *Exchange, ev *StreamEvent)
+
+	// OnResponse runs once the response has been sent, streamed or not.
+	OnResponse(ex *Exchange, resp *ExchangeResponse)
+}
+
+// NopMiddleware implements every Middleware hook as a no-op.
+type NopMiddleware struct{}
+
+func (NopMiddleware) OnRequest(*Exchange) error               { return nil }
+func (NopMiddleware) OnStreamEvent(*Exchange, *StreamEvent)   {}
+func (NopMiddleware) OnResponse(*Exchange, *ExchangeResponse) {}
+
+// Exchange is one request inside a tunnel, shared by its hooks.
+type Exchange struct {
+	Method string
+	URL    string      // https://host[:port]/path?query
+	Header http.Header // the client's request headers
+	// Body is the request body, for the requests the proxy buffers
+	// (Messages, count_tokens and embeddings calls). It is nil for those
+	// relayed as they arrive, whose body can't be rewritten.
+	Body []byte
+	// Route is "local", "count_tokens", "embeddings" or "upstream", and
+	// Label the marker label of a local route. Both are set by the time
+	// OnStreamEvent and OnResponse run.
+	Route string
+	Label string
+}
+
+func (ex *Exchange) setRoute(route, label string) {
+	if ex != nil {
+		ex.Route, ex.Label = route, label
+	}
+}
+
+// StreamEvent is one server-sent event. Fields other than event and data
+// (id, retry, comments) are kept only when the event is left unchanged.
+type StreamEvent struct {
+	Event string
+	Data  string
+	Drop  bool
+}
+
+// ExchangeResponse is the response an Exchange got, as the client saw it.
+// Body is decoded from chunked framing, gzip, deflate, brotli and zstd,
+// and holds at most max_body_bytes; Truncated reports a longer one.
+type ExchangeResponse struct {
+	Status    int // 0 when no response was sent
+	Header    http.Header
+	Body      []byte
+	Truncated bool
+}
+
+// WithMiddleware adds hooks; each runs in the order added.
+func WithMiddleware(m ...Middleware) Option {
+	return func(p *Proxy) { p.middleware = append(p.middleware, m...) }
+}
+
+// onRequest runs the OnRequest hooks, stopping at the first refusal.
+func (p *Proxy) onRequest(ex *Exchange) error {
+	for _, m := range p.middleware {
+		if err := m.OnRequest(ex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refuseByMiddleware answers req with the error an OnRequest hook returned.
+func (p *Proxy) refuseByMiddleware(w io.Writer, req *http.Request, host, port string, span *tracing.Span, err error) {
+	span.SetError("refused by middleware")
+	span.End()
+	log.Printf("[MIDDLEWARE] %s https://%s%s refused: %v", req.Method, net.JoinHostPort(host, port), req.URL.RequestURI(), err)
+	sendAnthropicError(w, 403, translate.FormatError("permission_error", fmt.Sprintf("[MIDDLEWARE] %v", err)))
+}
+
+// onResponse runs the OnResponse hooks.
+func (p *Proxy) onResponse(ex *Exchange, resp *ExchangeResponse) {
+	for _, m := range p.middleware {
+		m.OnResponse(ex, resp)
+	}
+}
+
+// streamHooks wraps w so the SSE events written through it pass the
+// OnStreamEvent hooks. Writes go straight to w when ex is nil. Call flush
+// once the stream is done.
+func (p *Proxy) streamHooks(ex *Exchange, w io.Writer) *eventHookWriter {
+	return &eventHookWriter{dst: w, ex: ex, hooks: p.middleware}
+}
+
+// eventHookWriter splits an SSE stream into events and passes each through
+// the hooks on its way to dst.
+type eventHookWriter struct {
+	dst   io.Writer
+	ex    *Exchange
+	hooks []Middleware
+	buf   []byte
+}
+
+func (h *eventHookWriter) Write(b []byte) (int, error) {
+	if h == nil || len(h.hooks) == 0 || h.ex == nil {
+		return h.dst.Write(b)
+	}
+	h.buf = append(h.buf, b...)
+	for {
+		i := bytes.Index(h.buf, []byte("\n\n"))
+		if i < 0 {
+			return len(b), nil
+		}
+		out := h.apply(h.buf[:i+2])
+		h.buf = h.buf[i+2:]
+		if len(out) == 0 {
+			continue // dropped
+		}
+		if _, err := h.dst.Write(out); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// isEventStream reports whether h describes a server-sent event stream.
+func isEventStream(h http.Header) bool {
+	mt, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	return mt == "text/event-stream"
+}
+
+// flush writes out a trailing partial event unchanged.
+func (h *eventHookWriter) flush() {
+	if len(h.buf) > 0 {
+		h.dst.Write(h.buf)
+		h.buf = nil
+	}
+}
+
+// apply runs the hooks over one raw event and returns the bytes to send.
+func (h *eventHookWriter) apply(raw []byte) []byte {
+	var ev StreamEvent
+	var data []string
+	fields := false
+	for _, line := range strings.Split(strings.TrimRight(string(raw), "\n"), "\n") {
+		name, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch name {
+		case "event":
+			ev.Event, fields = value, true
+		case "data":
+			data, fields = append(data, value), true
+		}
+	}
+	if !fields {
+		return raw // a comment or keep-alive
+	}
+	ev.Data = strings.Join(data, "\n")
+	orig := ev
+	for _, m := range h.hooks {
+		m.OnStreamEvent(h.ex, &ev)
+	}
+	switch {
+	case ev.Drop:
+		return nil
+	case ev == orig:
+		return raw
+	}
+	var out strings.Builder
+	if ev.Event != "" {
+		out.WriteString("event: " + ev.Event + "\n")
+	}
+	for _, line := range strings.Split(ev.Data, "\n") {
+		out.WriteString("data: " + line + "\n")
+	}
+	out.WriteString("\n")
+	return []byte(out.String())
+}
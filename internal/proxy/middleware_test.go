@@ -0,0 +1,147 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/testutil"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+// testMiddleware refuses /v1/blocked and bodies that mention "forbidden",
+// tags and rewrites requests, edits
+// the local stream and reports each finished exchange on done.
+type testMiddleware struct {
+	NopMiddleware
+	done chan Exchange
+	resp chan ExchangeResponse
+}
+
+func newTestMiddleware() *testMiddleware {
+	return &testMiddleware{done: make(chan Exchange, 8), resp: make(chan ExchangeResponse, 8)}
+}
+
+func (m *testMiddleware) OnRequest(ex *Exchange) error {
+	if strings.HasSuffix(ex.URL, "/v1/blocked") || strings.Contains(string(ex.Body), "forbidden") {
+		return errors.New("blocked by policy")
+	}
+	ex.Header.Set("X-Audited", "yes")
+	if ex.Body != nil {
+		ex.Body = []byte(strings.ReplaceAll(string(ex.Body), "original", "rewritten"))
+	}
+	return nil
+}
+
+func (m *testMiddleware) OnStreamEvent(ex *Exchange, ev *StreamEvent) {
+	if ev.Event != "content_block_delta" {
+		return
+	}
+	if strings.Contains(ev.Data, "streaming ") {
+		ev.Drop = true
+	}
+	ev.Data = strings.ReplaceAll(ev.Data, "Mock ", "Fake ")
+}
+
+func (m *testMiddleware) OnResponse(ex *Exchange, resp *ExchangeResponse) {
+	m.done <- *ex
+	m.resp <- *resp
+}
+
+func (m *testMiddleware) wait(t *testing.T) (Exchange, ExchangeResponse) {
+	t.Helper()
+	select {
+	case ex := <-m.done:
+		return ex, <-m.resp
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnResponse not called")
+	}
+	return Exchange{}, ExchangeResponse{}
+}
+
+func TestMiddlewareRewritesUpstreamRequest(t *testing.T) {
+	m := newTestMiddleware()
+	infra := setupInfraWithOptions(t, nil, WithMiddleware(m))
+
+	status, body, _ := proxyRequest(t, infra, "POST", "/v1/messages",
+		[]byte(`{"model":"claude-sonnet-4-20250514","system":"original"}`), nil)
+	if status != 200 {
+		t.Fatalf("status %d: %s", status, body)
+	}
+	if !strings.Contains(body, `rewritten`) || strings.Contains(body, `original`) {
+		t.Errorf("upstream saw the body before OnRequest: %s", body)
+	}
+	if !strings.Contains(body, `"X-Audited"`) {
+		t.Errorf("header set in OnRequest not forwarded: %s", body)
+	}
+
+	ex, resp := m.wait(t)
+	wantURL := fmt.Sprintf("https://localhost:%d/v1/messages", infra.upstreamPort)
+	if ex.Method != "POST" || ex.URL != wantURL || ex.Route != "upstream" {
+		t.Errorf("exchange = %s %s route %q", ex.Method, ex.URL, ex.Route)
+	}
+	if resp.Status != 200 || !strings.Contains(string(resp.Body), "rewritten") || resp.Truncated {
+		t.Errorf("OnResponse got %d %q truncated=%v", resp.Status, resp.Body, resp.Truncated)
+	}
+}
+
+func TestMiddlewareRefusesRequest(t *testing.T) {
+	m := newTestMiddleware()
+	infra := setupInfraWithOptions(t, nil, WithMiddleware(m))
+
+	status, body, _ := proxyRequest(t, infra, "GET", "/v1/blocked", nil, nil)
+	if status != 403 || !strings.Contains(body, "[MIDDLEWARE] blocked by policy") {
+		t.Errorf("got %d: %s", status, body)
+	}
+	if _, resp := m.wait(t); resp.Status != 403 {
+		t.Errorf("OnResponse status = %d, want 403", resp.Status)
+	}
+}
+
+func TestMiddlewareRefusalClosesTunnel(t *testing.T) {
+	m := newTestMiddleware()
+	infra := setupInfraWithOptions(t, nil, WithMiddleware(m))
+
+	conn := dialTunnel(t, infra)
+	defer conn.Close()
+	writeKeepAlive(conn, "/v1/messages", `{"system":"forbidden"}`)
+	if resp, body := readClosingResponse(t, conn); resp.StatusCode != 403 || !strings.Contains(body, "[MIDDLEWARE] blocked by policy") {
+		t.Errorf("got %d: %s", resp.StatusCode, body)
+	}
+}
+
+func TestMiddlewareStreamEvents(t *testing.T) {
+	oaiSrv, oaiPort, _ := testutil.MockOpenAIServer()
+	t.Cleanup(func() { oaiSrv.Close() })
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "mock",
+			Endpoint: fmt.Sprintf("http://127.0.0.1:%d/v1", oaiPort),
+			Models:   map[string]config.ModelConfig{"test_model": {Model: "mock-model-v1"}},
+		}},
+	})
+	m := newTestMiddleware()
+	infra := setupInfraWithOptions(t, resolver, WithMiddleware(m))
+
+	status, body, _ := proxyRequest(t, infra, "POST", "/v1/messages", streamBody(), nil)
+	if status != 200 {
+		t.Fatalf("status %d: %s", status, body)
+	}
+	if !strings.Contains(body, "Fake ") || strings.Contains(body, "Mock ") {
+		t.Errorf("event not rewritten: %s", body)
+	}
+	if strings.Contains(body, "streaming ") {
+		t.Errorf("dropped event sent: %s", body)
+	}
+	assertSSELifecycle(t, body)
+
+	ex, resp := m.wait(t)
+	if ex.Route != "local" || ex.Label != "test_model" {
+		t.Errorf("route = %q %q", ex.Route, ex.Label)
+	}
+	if resp.Header.Get("Content-Type") != "text/event-stream" || !strings.Contains(string(resp.Body), "Fake ") {
+		t.Errorf("OnResponse got %v %q", resp.Header, resp.Body)
+	}
+}
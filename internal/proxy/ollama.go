@@ -0,0 +1,52 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// UnloadModel asks the Ollama server behind label to evict the model from
+// memory by sending a native /api/generate request with keep_alive: 0.
+// This frees VRAM for another label without a manual `ollama stop`.
+func (p *Proxy) UnloadModel(label string) error {
+	if p.modelResolver == nil {
+		return fmt.Errorf("no provider config loaded")
+	}
+	m, err := p.modelResolver.Resolve(label)
+	if err != nil {
+		return err
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":      m.Model,
+		"keep_alive": 0,
+	})
+	req, err := http.NewRequest("POST", ollamaBaseURL(m.Endpoint)+"/api/generate", strings.NewReader(string(body)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := setProviderHeaders(req, m); err != nil {
+		return err
+	}
+
+	resp, err := p.localClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("provider returned %d: %s", resp.StatusCode, sanitizeForLog(string(msg)))
+	}
+	return nil
+}
+
+// ollamaBaseURL strips the OpenAI-compatible /v1 suffix from an Ollama
+// endpoint, yielding the root used by Ollama's native API.
+func ollamaBaseURL(endpoint string) string {
+	return strings.TrimSuffix(endpoint, "/v1")
+}
@@ -0,0 +1,360 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/translate"
+)
+
+// anthropicVersion is sent to Anthropic-API backends.
+const anthropicVersion = "2023-06-01"
+
+// OpenAIHandler returns an OpenAI-compatible API (chat completions,
+// embeddings and model listing) over the configured labels, for tools that only speak OpenAI.
+// Labels on OpenAI providers are relayed with the model name swapped in;
+// labels on api: anthropic providers are translated to the Messages API and
+// back, so the proxy works as a bridge in both directions. Clients are held
+// to allowed_clients and the per-client rate limit, and with a proxy token
+// set must send it as their API key.
+func (p *Proxy) OpenAIHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/chat/completions", p.handleOpenAIChat)
+	mux.HandleFunc("POST /v1/embeddings", p.handleOpenAIEmbeddings)
+	mux.HandleFunc("GET /v1/models", p.handleOpenAIModels)
+	return p.limitClients(p.requireToken(mux))
+}
+
+func (p *Proxy) handleOpenAIModels(w http.ResponseWriter, r *http.Request) {
+	data := []map[string]interface{}{}
+	if p.modelResolver != nil {
+		for _, m := range p.modelResolver.Models() {
+			data = append(data, map[string]interface{}{"id": m.Label, "object": "model", "owned_by": m.Provider})
+		}
+		for _, e := range p.modelResolver.Embeddings() {
+			data = append(data, map[string]interface{}{"id": e.Label, "object": "model", "owned_by": e.Provider})
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"object": "list", "data": data})
+}
+
+func (p *Proxy) handleOpenAIChat(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, p.limits.MaxBodyBytes+1))
+	if err != nil {
+		sendOpenAIError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("read body: %v", err))
+		return
+	}
+	if int64(len(body)) > p.limits.MaxBodyBytes {
+		sendOpenAIError(w, http.StatusRequestEntityTooLarge, "invalid_request_error", "request body too large")
+		return
+	}
+
+	var meta struct {
+		Model         string `json:"model"`
+		Stream        bool   `json:"stream"`
+		StreamOptions struct {
+			IncludeUsage bool `json:"include_usage"`
+		} `json:"stream_options"`
+	}
+	if err := json.Unmarshal(body, &meta); err != nil {
+		sendOpenAIError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("parse request: %v", err))
+		return
+	}
+	if p.modelResolver == nil {
+		sendOpenAIError(w, http.StatusServiceUnavailable, "api_error", "no providers configured — create ~/.claude-hybrid/config.yaml")
+		return
+	}
+	candidates, err := p.modelResolver.Candidates(p.listenerTarget(meta.Model, body))
+	if err != nil {
+		sendOpenAIError(w, http.StatusNotFound, "invalid_request_error", fmt.Sprintf("unknown model label %q", meta.Model))
+		return
+	}
+	resolved := candidates[0]
+	var release func()
+	if len(candidates) > 1 {
+		resolved, release = p.pickMember(meta.Model, candidates, true)
+	}
+
+	use, ok, spent := p.applyBudget(resolved)
+	if !ok {
+		log.Printf("[LOCAL_ERR:BUDGET] %s refused: $%.2f of its $%.2f daily budget spent", use.Label, spent, use.Budget.DailyUSD)
+		sendOpenAIError(w, http.StatusTooManyRequests, "insufficient_quota",
+			fmt.Sprintf("[BUDGET] Model '%s' has spent $%.2f of its $%.2f daily budget; it resets at local midnight. Raise budget.daily_usd in ~/.claude-hybrid/config.yaml to continue today",
+				use.Label, spent, use.Budget.DailyUSD))
+		return
+	}
+	resolved = use
+
+	if release == nil {
+		release, err = p.hosts.acquire(resolved)
+		if err != nil {
+			log.Printf("[LOCAL_ERR:CAPACITY] %s refused: %v", resolved.Label, err)
+			sendOpenAIError(w, http.StatusServiceUnavailable, "server_error", fmt.Sprintf("model %q unavailable: %v", resolved.Label, err))
+			return
+		}
+	}
+	defer release()
+
+	streamMode := "non-streaming"
+	if meta.Stream {
+		streamMode = "streaming"
+	}
+	log.Printf("OPENAI_ROUTE %s → %s/%s (%s api, %s)", resolved.Label, resolved.Provider, resolved.Model, resolved.API, streamMode)
+	start := time.Now()
+
+	var usage translate.OUsage
+	if resolved.API == config.APIAnthropic {
+		ok = p.bridgeToAnthropic(w, resolved, body, meta.Stream, meta.StreamOptions.IncludeUsage, &usage)
+	} else {
+		ok = p.relayToOpenAI(w, resolved, body, &usage)
+	}
+	p.chargeBudget(resolved, usage.PromptTokens, usage.CompletionTokens)
+	if ok {
+		log.Printf("OPENAI_OK %s → %s/%s (%s, %dms, in=%d out=%d tokens)", resolved.Label, resolved.Provider, resolved.Model,
+			streamMode, time.Since(start).Milliseconds(), usage.PromptTokens, usage.CompletionTokens)
+	}
+}
+
+// listenerTarget is routeTarget for an OpenAI chat request: auto: and
+// judge: labels classify its Messages translation, or the body as sent when
+// it doesn't translate.
+func (p *Proxy) listenerTarget(label string, body []byte) string {
+	if !strings.HasPrefix(label, config.AutoTierPrefix) && !strings.HasPrefix(label, config.JudgePrefix) {
+		return label
+	}
+	if aBody, err := translate.OpenAIToAnthropic(body, label, 0); err == nil {
+		body = aBody
+	}
+	return p.routeTarget(label, body)
+}
+
+// relayToOpenAI forwards an OpenAI request to an OpenAI provider with the
+// label replaced by the backend model name, streaming the response back
+// as-is and recording the provider's token usage in usage.
+func (p *Proxy) relayToOpenAI(w http.ResponseWriter, resolved config.ResolvedModel, body []byte, usage *translate.OUsage) bool {
+	var req map[string]interface{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		sendOpenAIError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("parse request: %v", err))
+		return false
+	}
+	req["model"] = resolved.Model
+	stream, _ := req["stream"].(bool)
+	clientUsage := false
+	if stream {
+		// Usage is always asked for so the request can be charged; the
+		// usage chunk is dropped again for clients that didn't ask.
+		opts, _ := req["stream_options"].(map[string]interface{})
+		if opts == nil {
+			opts = map[string]interface{}{}
+		}
+		clientUsage, _ = opts["include_usage"].(bool)
+		opts["include_usage"] = true
+		req["stream_options"] = opts
+	}
+	out, _ := json.Marshal(req)
+
+	resp, ok := p.doBackend(w, resolved, resolved.Endpoint+"/chat/completions", out, nil)
+	if !ok {
+		return false
+	}
+	defer resp.Body.Close()
+
+	w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+	w.WriteHeader(resp.StatusCode)
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(flushWriter{w}, resp.Body)
+		return false
+	}
+	if stream {
+		return relayOpenAIStream(resp.Body, flushWriter{w}, clientUsage, usage) == nil
+	}
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, p.limits.MaxBodyBytes))
+	w.Write(respBody)
+	if err == nil {
+		_, err = io.Copy(w, resp.Body)
+	}
+	var oResp struct {
+		Usage translate.OUsage `json:"usage"`
+	}
+	if json.Unmarshal(respBody, &oResp) == nil {
+		*usage = oResp.Usage
+	}
+	return err == nil
+}
+
+// relayOpenAIStream copies an OpenAI SSE stream to w line by line, recording
+// the usage chunk's counts in usage. The usage-only chunk is passed on only
+// when keepUsage is set.
+func relayOpenAIStream(r io.Reader, w io.Writer, keepUsage bool, usage *translate.OUsage) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if data, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:")); ok && bytes.Contains(data, []byte(`"usage"`)) {
+			var chunk struct {
+				Choices []json.RawMessage `json:"choices"`
+				Usage   *translate.OUsage `json:"usage"`
+			}
+			if json.Unmarshal(data, &chunk) == nil && chunk.Usage != nil {
+				*usage = *chunk.Usage
+				if !keepUsage && len(chunk.Choices) == 0 {
+					line = nil
+				}
+			}
+		}
+		if len(line) > 0 {
+			if _, werr := w.Write(line); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// bridgeToAnthropic translates an OpenAI request to the Messages API, sends
+// it to an api: anthropic provider, and translates the response back,
+// recording the provider's token usage in usage.
+func (p *Proxy) bridgeToAnthropic(w http.ResponseWriter, resolved config.ResolvedModel, body []byte, stream, includeUsage bool, usage *translate.OUsage) bool {
+	aBody, err := translate.OpenAIToAnthropic(body, resolved.Model, resolved.MaxTokens)
+	if err != nil {
+		log.Printf("[LOCAL_ERR:TRANSLATE] reverse request translation failed for %s: %v", resolved.Label, err)
+		sendOpenAIError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("request translation failed: %v", err))
+		return false
+	}
+
+	headers := map[string]string{"anthropic-version": anthropicVersion}
+	resp, ok := p.doBackend(w, resolved, resolved.Endpoint+"/messages", aBody, headers)
+	if !ok {
+		return false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		sanitized := sanitizeForLog(string(respBody))
+		log.Printf("[LOCAL_ERR:HTTP_%d] %s returned %d: %s", resp.StatusCode, resolved.Label, resp.StatusCode, sanitized)
+		// The error type follows the Messages path's mapping; the status
+		// stays the provider's for client errors, since OpenAI clients
+		// don't know Anthropic's 529.
+		_, errType := providerErrorStatus(resp.StatusCode)
+		code := http.StatusBadGateway
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+			code = resp.StatusCode
+		}
+		if retryAfter := resp.Header.Get("Retry-After"); resp.StatusCode == http.StatusTooManyRequests && retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		sendOpenAIError(w, code, errType, fmt.Sprintf("provider '%s' returned %d: %s", resolved.Label, resp.StatusCode, sanitized))
+		return false
+	}
+
+	if stream {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		rt := translate.NewReverseStreamTranslator(resolved.Label, includeUsage)
+		err := rt.TranslateStream(resp.Body, flushWriter{w})
+		usage.PromptTokens, usage.CompletionTokens = rt.Usage()
+		if err != nil {
+			cat := translate.ClassifyError(err)
+			log.Printf("[LOCAL_ERR:%s] reverse stream translation error for %s: %v", cat, resolved.Label, err)
+			errData := translate.FormatOpenAIError("api_error", fmt.Sprintf("[%s] stream interrupted: %v", cat, err))
+			fmt.Fprintf(flushWriter{w}, "data: %s\n\ndata: [DONE]\n\n", errData)
+			return false
+		}
+		return true
+	}
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, p.limits.MaxBodyBytes+1))
+	if err != nil {
+		cat := translate.ClassifyError(err)
+		log.Printf("[LOCAL_ERR:%s] response read error for %s: %v", cat, resolved.Label, err)
+		sendOpenAIError(w, http.StatusBadGateway, "api_error", fmt.Sprintf("[%s] failed to read response: %v", cat, err))
+		return false
+	}
+	var aResp translate.AResponse
+	if json.Unmarshal(respBody, &aResp) == nil {
+		usage.PromptTokens, usage.CompletionTokens = aResp.Usage.InputTokens, aResp.Usage.OutputTokens
+	}
+	oBody, err := translate.ResponseToOpenAI(respBody, resolved.Label)
+	if err != nil {
+		log.Printf("[LOCAL_ERR:TRANSLATE] reverse response translation failed for %s: %v", resolved.Label, err)
+		sendOpenAIError(w, http.StatusBadGateway, "api_error", fmt.Sprintf("response translation failed: %v", err))
+		return false
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(oBody)
+	return true
+}
+
+// doBackend POSTs body to a provider through its connection pool, writing an
+// OpenAI-format error to w when the provider is unreachable or its key can't
+// be read. The provider's key and configured headers are sent first; headers
+// supplies per-API extras.
+func (p *Proxy) doBackend(w http.ResponseWriter, resolved config.ResolvedModel, url string, body []byte, headers map[string]string) (*http.Response, bool) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		sendOpenAIError(w, http.StatusInternalServerError, "api_error", fmt.Sprintf("create request: %v", err))
+		return nil, false
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if resolved.API == config.APIAnthropic {
+		var key string
+		if key, err = resolved.Key(); key != "" {
+			req.Header.Set("x-api-key", key)
+		}
+		for k, v := range resolved.Headers {
+			req.Header.Set(k, v)
+		}
+	} else {
+		err = setProviderHeaders(req, resolved)
+	}
+	if err != nil {
+		log.Printf("[LOCAL_ERR:SECRET] %s: %v", resolved.Label, err)
+		sendOpenAIError(w, http.StatusBadGateway, "api_error", fmt.Sprintf("[SECRET] no API key for model '%s': %v", resolved.Label, err))
+		return nil, false
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := p.pools.get(resolved).do(req)
+	if err != nil {
+		cat := translate.ClassifyError(err)
+		log.Printf("[LOCAL_ERR:%s] %s unreachable: %v (%s)", cat, resolved.Label, err, url)
+		sendOpenAIError(w, http.StatusBadGateway, "api_error", fmt.Sprintf("[%s] model '%s' unreachable: %v", cat, resolved.Label, err))
+		return nil, false
+	}
+	return resp, true
+}
+
+func sendOpenAIError(w http.ResponseWriter, status int, errType, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(translate.FormatOpenAIError(errType, msg))
+}
+
+// flushWriter flushes after every write so SSE chunks reach the client promptly.
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (f flushWriter) Write(b []byte) (int, error) {
+	n, err := f.w.Write(b)
+	if fl, ok := f.w.(http.Flusher); ok {
+		fl.Flush()
+	}
+	return n, err
+}
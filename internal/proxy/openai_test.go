@@ -0,0 +1,307 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/translate"
+)
+
+// mockAnthropicBackend serves /v1/messages, recording the last request.
+func mockAnthropicBackend(t *testing.T) (url string, last func() (body []byte, header http.Header)) {
+	t.Helper()
+	var lastBody []byte
+	var lastHeader http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			http.NotFound(w, r)
+			return
+		}
+		lastBody, _ = io.ReadAll(r.Body)
+		lastHeader = r.Header.Clone()
+		var req translate.AnthropicRequest
+		json.Unmarshal(lastBody, &req)
+		if req.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, ev := range []string{
+				`{"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":3,"output_tokens":0}}}`,
+				`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"pong"}}`,
+				`{"type":"content_block_stop","index":0}`,
+				`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":1}}`,
+				`{"type":"message_stop"}`,
+			} {
+				fmt.Fprintf(w, "data: %s\n\n", ev)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"msg_1","type":"message","role":"assistant","model":"claude-local",`+
+			`"content":[{"type":"text","text":"pong"}],"stop_reason":"end_turn","usage":{"input_tokens":3,"output_tokens":1}}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, func() ([]byte, http.Header) { return lastBody, lastHeader }
+}
+
+func newOpenAIListener(t *testing.T, providers ...config.ProviderConfig) *httptest.Server {
+	t.Helper()
+	resolver, err := config.NewModelResolver(&config.ProvidersConfig{Providers: providers})
+	if err != nil {
+		t.Fatalf("resolver: %v", err)
+	}
+	p := New(nil, WithModelResolver(resolver))
+	srv := httptest.NewServer(p.OpenAIHandler())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOpenAIListenerBridgesToAnthropic(t *testing.T) {
+	backendURL, last := mockAnthropicBackend(t)
+	srv := newOpenAIListener(t, config.ProviderConfig{
+		Name: "native", Endpoint: backendURL + "/v1", API: "anthropic", APIKey: "sk-local",
+		Models: map[string]config.ModelConfig{"local": {Model: "claude-local"}},
+	})
+
+	resp, err := http.Post(srv.URL+"/v1/chat/completions", "application/json", strings.NewReader(
+		`{"model":"local","messages":[{"role":"system","content":"terse"},{"role":"user","content":"ping"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		b, _ := io.ReadAll(resp.Body)
+		t.Fatalf("status %d: %s", resp.StatusCode, b)
+	}
+	var out translate.OResponse
+	json.NewDecoder(resp.Body).Decode(&out)
+	if len(out.Choices) != 1 || out.Choices[0].Message.Content != "pong" || out.Model != "local" {
+		t.Errorf("unexpected response: %+v", out)
+	}
+
+	body, header := last()
+	var sent translate.AnthropicRequest
+	json.Unmarshal(body, &sent)
+	if sent.Model != "claude-local" || string(sent.System) != `"terse"` {
+		t.Errorf("backend got model=%q system=%s", sent.Model, sent.System)
+	}
+	if header.Get("x-api-key") != "sk-local" || header.Get("anthropic-version") == "" {
+		t.Errorf("missing Anthropic auth headers: %v", header)
+	}
+}
+
+func TestOpenAIListenerStreamsFromAnthropic(t *testing.T) {
+	backendURL, _ := mockAnthropicBackend(t)
+	srv := newOpenAIListener(t, config.ProviderConfig{
+		Name: "native", Endpoint: backendURL + "/v1", API: "anthropic",
+		Models: map[string]config.ModelConfig{"local": {Model: "claude-local"}},
+	})
+
+	resp, err := http.Post(srv.URL+"/v1/chat/completions", "application/json", strings.NewReader(
+		`{"model":"local","stream":true,"messages":[{"role":"user","content":"ping"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	out := string(b)
+	if !strings.Contains(resp.Header.Get("Content-Type"), "text/event-stream") {
+		t.Errorf("content type %q", resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(out, `"content":"pong"`) || !strings.HasSuffix(out, "data: [DONE]\n\n") {
+		t.Errorf("unexpected stream:\n%s", out)
+	}
+}
+
+func TestOpenAIListenerRelaysOpenAIProvider(t *testing.T) {
+	port, getLastBody, _ := capturingMockOpenAI(t)
+	srv := newOpenAIListener(t, config.ProviderConfig{
+		Name: "mock", Endpoint: fmt.Sprintf("http://127.0.0.1:%d/v1", port),
+		Models: map[string]config.ModelConfig{"fast": {Model: "qwen3:32b"}},
+	})
+
+	resp, err := http.Post(srv.URL+"/v1/chat/completions", "application/json", strings.NewReader(
+		`{"model":"fast","messages":[{"role":"user","content":"hi"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	var sent map[string]interface{}
+	json.Unmarshal(getLastBody(), &sent)
+	if sent["model"] != "qwen3:32b" {
+		t.Errorf("label not replaced by backend model: %v", sent["model"])
+	}
+}
+
+func TestOpenAIListenerUnknownModel(t *testing.T) {
+	srv := newOpenAIListener(t)
+	resp, err := http.Post(srv.URL+"/v1/chat/completions", "application/json",
+		strings.NewReader(`{"model":"nope","messages":[]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var e struct {
+		Error struct{ Message string } `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&e)
+	if resp.StatusCode != 404 || !strings.Contains(e.Error.Message, "nope") {
+		t.Errorf("status %d, error %q", resp.StatusCode, e.Error.Message)
+	}
+}
+
+func TestOpenAIListenerProviderErrorTypes(t *testing.T) {
+	tests := []struct {
+		providerStatus, status int
+		errType                string
+	}{
+		{400, 400, "invalid_request_error"},
+		{401, 401, "authentication_error"},
+		{404, 404, "not_found_error"},
+		{429, 429, "rate_limit_error"},
+		{500, 502, "api_error"},
+		{503, 502, "overloaded_error"},
+	}
+	for _, tc := range tests {
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(tc.providerStatus)
+			fmt.Fprint(w, `{"type":"error","error":{"type":"x","message":"no"}}`)
+		}))
+		srv := newOpenAIListener(t, config.ProviderConfig{
+			Name: "native", Endpoint: backend.URL + "/v1", API: "anthropic",
+			Models: map[string]config.ModelConfig{"local": {Model: "claude-local"}},
+		})
+		resp, err := http.Post(srv.URL+"/v1/chat/completions", "application/json",
+			strings.NewReader(`{"model":"local","messages":[{"role":"user","content":"ping"}]}`))
+		backend.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out struct {
+			Error struct {
+				Type string `json:"type"`
+			} `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&out)
+		resp.Body.Close()
+		if resp.StatusCode != tc.status || out.Error.Type != tc.errType {
+			t.Errorf("provider %d: got %d %s, want %d %s", tc.providerStatus, resp.StatusCode, out.Error.Type, tc.status, tc.errType)
+		}
+		if got := resp.Header.Get("Retry-After"); (tc.status == 429) != (got == "7") {
+			t.Errorf("provider %d: Retry-After = %q", tc.providerStatus, got)
+		}
+	}
+}
+
+func TestOpenAIListenerRejectsUnsupportedContent(t *testing.T) {
+	backendURL, last := mockAnthropicBackend(t)
+	srv := newOpenAIListener(t, config.ProviderConfig{
+		Name: "native", Endpoint: backendURL + "/v1", API: "anthropic",
+		Models: map[string]config.ModelConfig{"local": {Model: "claude-local"}},
+	})
+	resp, err := http.Post(srv.URL+"/v1/chat/completions", "application/json", strings.NewReader(
+		`{"model":"local","messages":[{"role":"user","content":[{"type":"input_audio","input_audio":{"data":"AA==","format":"wav"}}]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 400 || !strings.Contains(string(b), "invalid_request_error") || !strings.Contains(string(b), "input_audio") {
+		t.Errorf("got %d: %s", resp.StatusCode, b)
+	}
+	if body, _ := last(); body != nil {
+		t.Errorf("rejected request reached the backend: %s", body)
+	}
+}
+
+func TestOpenAIListenerBudget(t *testing.T) {
+	port, _, _ := capturingMockOpenAI(t)
+	resolver, err := config.NewModelResolver(&config.ProvidersConfig{Providers: []config.ProviderConfig{{
+		Name: "mock", Endpoint: fmt.Sprintf("http://127.0.0.1:%d/v1", port),
+		Models: map[string]config.ModelConfig{
+			"paid": {Model: "m1", Price: &config.Price{Input: 100_000}, Budget: &config.Budget{DailyUSD: 1, Action: config.BudgetBlock}},
+		},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := New(nil, WithModelResolver(resolver))
+	srv := httptest.NewServer(p.OpenAIHandler())
+	t.Cleanup(srv.Close)
+
+	send := func() (*http.Response, string) {
+		resp, err := http.Post(srv.URL+"/v1/chat/completions", "application/json",
+			strings.NewReader(`{"model":"paid","messages":[{"role":"user","content":"hi"}]}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp, string(b)
+	}
+	if resp, body := send(); resp.StatusCode != 200 {
+		t.Fatalf("request within budget: %d %s", resp.StatusCode, body)
+	}
+	if b := p.Metrics().Budgets; len(b) != 1 || b[0].SpentUSD != 1 {
+		t.Fatalf("listener request not charged: %+v", b)
+	}
+
+	resp, body := send()
+	var e struct {
+		Error struct{ Type, Message string } `json:"error"`
+	}
+	json.Unmarshal([]byte(body), &e)
+	if resp.StatusCode != 429 || e.Error.Type != "insufficient_quota" || !strings.Contains(e.Error.Message, "$1.00 of its $1.00 daily budget") {
+		t.Errorf("over budget: %d %s, want an OpenAI 429 insufficient_quota naming the spend", resp.StatusCode, body)
+	}
+}
+
+func TestOpenAIListenerChargesRelayedStream(t *testing.T) {
+	var sent map[string]interface{}
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&sent)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"choices":[{"index":0,"delta":{"content":"ok"}}]}`+"\n\n")
+		fmt.Fprint(w, `data: {"choices":[],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(backend.Close)
+	resolver, err := config.NewModelResolver(&config.ProvidersConfig{Providers: []config.ProviderConfig{{
+		Name: "mock", Endpoint: backend.URL + "/v1",
+		Models: map[string]config.ModelConfig{
+			"paid": {Model: "m1", Price: &config.Price{Input: 100_000}, Budget: &config.Budget{DailyUSD: 5, Action: config.BudgetBlock}},
+		},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := New(nil, WithModelResolver(resolver))
+	srv := httptest.NewServer(p.OpenAIHandler())
+	t.Cleanup(srv.Close)
+
+	resp, err := http.Post(srv.URL+"/v1/chat/completions", "application/json",
+		strings.NewReader(`{"model":"paid","stream":true,"messages":[{"role":"user","content":"hi"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if out := string(b); !strings.Contains(out, `"content":"ok"`) || strings.Contains(out, "usage") || !strings.HasSuffix(out, "data: [DONE]\n\n") {
+		t.Errorf("stream should pass content through and drop the unrequested usage chunk:\n%s", out)
+	}
+	if opts, _ := sent["stream_options"].(map[string]interface{}); opts["include_usage"] != true {
+		t.Errorf("provider not asked for usage: %v", sent["stream_options"])
+	}
+	if b := p.Metrics().Budgets; len(b) != 1 || b[0].SpentUSD != 1 {
+		t.Errorf("streamed request not charged: %+v", b)
+	}
+}
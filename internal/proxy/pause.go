@@ -0,0 +1,168 @@
+package proxy
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pauseTimeout is how long a paused request waits for a decision before it
+// is sent on unchanged, so a forgotten breakpoint can't hang a session.
+const pauseTimeout = 5 * time.Minute
+
+// ErrNotPaused is returned for an ID that names no paused request, such as
+// one already resumed or timed out.
+var ErrNotPaused = errors.New("no paused request with that ID")
+
+// PausedRequest is a request held at a breakpoint, as listed by the admin
+// API. Credentials in Headers are masked.
+type PausedRequest struct {
+	ID      string              `json:"id"`
+	Method  string              `json:"method"`
+	URL     string              `json:"url"`
+	Headers map[string][]string `json:"headers"`
+	Body    string              `json:"body"`
+	Since   time.Time           `json:"since"`
+}
+
+// pauseDecision is what the admin API chose for a paused request.
+type pauseDecision struct {
+	drop bool
+	body []byte // replacement body; nil keeps the original
+}
+
+type pausedEntry struct {
+	info   PausedRequest
+	decide chan pauseDecision // buffered; receives at most one decision
+}
+
+// breakpoints holds requests matching the pause filter until they are
+// resumed, edited or dropped through the admin API.
+type breakpoints struct {
+	filter  *regexp.Regexp
+	timeout time.Duration
+
+	mu     sync.Mutex
+	nextID int
+	held   map[string]*pausedEntry
+}
+
+// WithPause holds every tunnel request whose "METHOD URL" matches filter
+// until it is resumed or dropped through the admin API, for inspecting and
+// editing what the client sends. Only requests the proxy buffers (Messages
+// and embeddings calls) can be held; their body may be replaced on resume.
+func WithPause(filter *regexp.Regexp) Option {
+	return func(p *Proxy) {
+		p.pause = &breakpoints{filter: filter, timeout: pauseTimeout, held: map[string]*pausedEntry{}}
+	}
+}
+
+// hold blocks until req is resumed or dropped, or the timeout passes, if it
+// matches the filter. It returns the body to use and whether to go on.
+func (b *breakpoints) hold(req *http.Request, url string, body []byte) ([]byte, bool) {
+	if b == nil || !b.filter.MatchString(req.Method+" "+url) {
+		return body, true
+	}
+	headers := make(map[string][]string, len(req.Header))
+	for name, vals := range req.Header {
+		if harMasked[strings.ToLower(name)] {
+			vals = []string{"[REDACTED]"}
+		}
+		headers[name] = vals
+	}
+	b.mu.Lock()
+	b.nextID++
+	e := &pausedEntry{
+		info: PausedRequest{
+			ID:      strconv.Itoa(b.nextID),
+			Method:  req.Method,
+			URL:     url,
+			Headers: headers,
+			Body:    string(body),
+			Since:   time.Now(),
+		},
+		decide: make(chan pauseDecision, 1),
+	}
+	b.held[e.info.ID] = e
+	b.mu.Unlock()
+	log.Printf("PAUSED #%s %s %s (resume or drop it on the admin API)", e.info.ID, req.Method, url)
+
+	timer := time.NewTimer(b.timeout)
+	defer timer.Stop()
+	var d pauseDecision
+	select {
+	case d = <-e.decide:
+	case <-timer.C:
+		log.Printf("[PAUSED] #%s timed out after %s; sending it on unchanged", e.info.ID, b.timeout)
+	}
+	b.mu.Lock()
+	delete(b.held, e.info.ID)
+	b.mu.Unlock()
+	if d.drop {
+		return nil, false
+	}
+	if d.body != nil {
+		return d.body, true
+	}
+	return body, true
+}
+
+// decide hands d to the paused request id.
+func (b *breakpoints) decide(id string, d pauseDecision) error {
+	if b == nil {
+		return ErrNotPaused
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	e, ok := b.held[id]
+	if !ok {
+		return ErrNotPaused
+	}
+	delete(b.held, id)
+	e.decide <- d
+	return nil
+}
+
+// Paused lists the requests held at breakpoints, oldest first.
+func (p *Proxy) Paused() []PausedRequest {
+	out := []PausedRequest{}
+	if p.pause == nil {
+		return out
+	}
+	p.pause.mu.Lock()
+	for _, e := range p.pause.held {
+		out = append(out, e.info)
+	}
+	p.pause.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Since.Before(out[j].Since) })
+	return out
+}
+
+// ResumePaused sends a paused request on, with body in place of the one
+// the client sent when body is non-nil.
+func (p *Proxy) ResumePaused(id string, body []byte) error {
+	err := p.pause.decide(id, pauseDecision{body: body})
+	if err == nil {
+		edited := ""
+		if body != nil {
+			edited = " with an edited body"
+		}
+		log.Printf("PAUSED #%s resumed%s", id, edited)
+	}
+	return err
+}
+
+// DropPaused answers a paused request with an error instead of sending it.
+func (p *Proxy) DropPaused(id string) error {
+	err := p.pause.decide(id, pauseDecision{drop: true})
+	if err == nil {
+		log.Printf("PAUSED #%s dropped", id)
+	}
+	return err
+}
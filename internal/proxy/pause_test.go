@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitPaused polls until n requests are held and returns them.
+func waitPaused(t *testing.T, p *Proxy, n int) []PausedRequest {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if held := p.Paused(); len(held) == n {
+			return held
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("expected %d paused requests, have %d", n, len(p.Paused()))
+	return nil
+}
+
+type pausedResult struct {
+	status int
+	body   string
+}
+
+// sendPaused sends a Messages request in the background.
+func sendPaused(t *testing.T, infra *testInfra, body string, headers map[string]string) <-chan pausedResult {
+	done := make(chan pausedResult, 1)
+	go func() {
+		status, respBody, _ := proxyRequest(t, infra, "POST", "/v1/messages", []byte(body), headers)
+		done <- pausedResult{status, respBody}
+	}()
+	return done
+}
+
+func TestPauseResumeWithEdit(t *testing.T) {
+	infra := setupInfraWithOptions(t, nil, WithPause(regexp.MustCompile(`^POST https://[^/]+/v1/messages`)))
+
+	done := sendPaused(t, infra, `{"prompt":"original"}`, map[string]string{"X-Api-Key": "sk-ant-secret"})
+	held := waitPaused(t, infra.proxy, 1)[0]
+	if held.Method != "POST" || !strings.HasSuffix(held.URL, "/v1/messages") || held.Body != `{"prompt":"original"}` {
+		t.Errorf("paused request = %+v", held)
+	}
+	if got := held.Headers["X-Api-Key"]; len(got) != 1 || got[0] != "[REDACTED]" {
+		t.Errorf("credential listed as %v", got)
+	}
+	select {
+	case <-done:
+		t.Fatal("request went through while paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := infra.proxy.ResumePaused(held.ID, []byte(`{"prompt":"edited"}`)); err != nil {
+		t.Fatal(err)
+	}
+	res := <-done
+	if res.status != 200 || !strings.Contains(res.body, `\"prompt\":\"edited\"`) {
+		t.Errorf("upstream should get the edited body: %d %s", res.status, res.body)
+	}
+	if err := infra.proxy.ResumePaused(held.ID, nil); err != ErrNotPaused {
+		t.Errorf("second resume: err = %v, want ErrNotPaused", err)
+	}
+}
+
+func TestPauseDrop(t *testing.T) {
+	infra := setupInfraWithOptions(t, nil, WithPause(regexp.MustCompile(`/v1/messages`)))
+
+	done := sendPaused(t, infra, `{"prompt":"x"}`, nil)
+	held := waitPaused(t, infra.proxy, 1)[0]
+	if err := infra.proxy.DropPaused(held.ID); err != nil {
+		t.Fatal(err)
+	}
+	res := <-done
+	if res.status != 403 || !strings.Contains(res.body, "[PAUSED]") {
+		t.Errorf("dropped request got %d %s", res.status, res.body)
+	}
+}
+
+func TestPauseDropClosesTunnel(t *testing.T) {
+	infra := setupInfraWithOptions(t, nil, WithPause(regexp.MustCompile(`/v1/messages`)))
+
+	conn := dialTunnel(t, infra)
+	defer conn.Close()
+	writeKeepAlive(conn, "/v1/messages", `{"prompt":"x"}`)
+	held := waitPaused(t, infra.proxy, 1)[0]
+	if err := infra.proxy.DropPaused(held.ID); err != nil {
+		t.Fatal(err)
+	}
+	if resp, body := readClosingResponse(t, conn); resp.StatusCode != 403 || !strings.Contains(body, "[PAUSED]") {
+		t.Errorf("dropped request got %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestPauseFilterAndTimeout(t *testing.T) {
+	infra := setupInfraWithOptions(t, nil, WithPause(regexp.MustCompile(`count_tokens`)))
+
+	// Not matching: sent at once.
+	if status, _, _ := proxyRequest(t, infra, "POST", "/v1/messages", []byte(`{}`), nil); status != 200 {
+		t.Fatalf("unmatched request: status %d", status)
+	}
+
+	infra.proxy.pause.timeout = 20 * time.Millisecond
+	status, body, _ := proxyRequest(t, infra, "POST", "/v1/messages/count_tokens", []byte(`{"a":1}`), nil)
+	if status != 200 || !strings.Contains(body, `\"a\":1`) {
+		t.Errorf("timed-out pause should send the original: %d %s", status, body)
+	}
+	if n := len(infra.proxy.Paused()); n != 0 {
+		t.Errorf("%d requests still listed after the timeout", n)
+	}
+}
@@ -0,0 +1,138 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/defaults"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+const (
+	defaultPoolMaxIdle     = 16
+	defaultPoolIdleTimeout = 90 * time.Second
+)
+
+// providerPool is a keep-alive HTTP client dedicated to one provider, so
+// Claude Code's bursts of parallel tool calls reuse warm TCP/TLS connections
+// instead of dialing per request.
+type providerPool struct {
+	client   *http.Client
+	stream   *http.Client // same transport, no total timeout (first_token_timeout streams)
+	newConns atomic.Int64
+	reused   atomic.Int64
+}
+
+// PoolStats reports connection reuse for one provider.
+type PoolStats struct {
+	NewConns    int64 `json:"new_conns"`
+	ReusedConns int64 `json:"reused_conns"`
+}
+
+func newProviderPool(cfg *config.PoolConfig, timeout time.Duration) *providerPool {
+	if timeout <= 0 {
+		timeout = defaults.UpstreamTimeout
+	}
+	maxIdle := defaultPoolMaxIdle
+	idleTimeout := defaultPoolIdleTimeout
+	if cfg != nil {
+		if cfg.MaxIdleConns > 0 {
+			maxIdle = cfg.MaxIdleConns
+		}
+		if cfg.IdleTimeout > 0 {
+			idleTimeout = cfg.IdleTimeout
+		}
+	}
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        maxIdle,
+		MaxIdleConnsPerHost: maxIdle,
+		IdleConnTimeout:     idleTimeout,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+	return &providerPool{
+		client: &http.Client{Transport: transport, Timeout: timeout},
+		stream: &http.Client{Transport: transport},
+	}
+}
+
+// do sends req, recording whether the connection was reused.
+func (pp *providerPool) do(req *http.Request) (*http.Response, error) {
+	return pp.send(pp.client, req)
+}
+
+// doStream is do without the pool's total timeout. The caller bounds the
+// request through its context instead.
+func (pp *providerPool) doStream(req *http.Request) (*http.Response, error) {
+	return pp.send(pp.stream, req)
+}
+
+func (pp *providerPool) send(client *http.Client, req *http.Request) (*http.Response, error) {
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				pp.reused.Add(1)
+			} else {
+				pp.newConns.Add(1)
+			}
+		},
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	// Set explicitly, Accept-Encoding stops the transport decoding gzip on
+	// its own, so decodeBody handles every encoding in one place.
+	req.Header = req.Header.Clone()
+	req.Header.Set("Accept-Encoding", providerAcceptEncoding)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := decodeBody(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// providerPools holds one providerPool per provider name, created lazily.
+type providerPools struct {
+	mu      sync.Mutex
+	pools   map[string]*providerPool
+	timeout time.Duration // used by providers without their own timeout
+}
+
+func (ps *providerPools) get(m config.ResolvedModel) *providerPool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.pools == nil {
+		ps.pools = make(map[string]*providerPool)
+	}
+	pp, ok := ps.pools[m.Provider]
+	if !ok {
+		timeout := m.Timeout
+		if timeout <= 0 {
+			timeout = ps.timeout
+		}
+		pp = newProviderPool(m.Pool, timeout)
+		ps.pools[m.Provider] = pp
+	}
+	return pp
+}
+
+func (ps *providerPools) stats() map[string]PoolStats {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	out := make(map[string]PoolStats, len(ps.pools))
+	for name, pp := range ps.pools {
+		out[name] = PoolStats{NewConns: pp.newConns.Load(), ReusedConns: pp.reused.Load()}
+	}
+	return out
+}
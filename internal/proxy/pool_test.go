@@ -0,0 +1,47 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+func TestProviderPoolReusesConnections(t *testing.T) {
+	port, calls := countingMockOpenAI(t, 0)
+	pools := &providerPools{}
+	m := config.ResolvedModel{Provider: "mock", Pool: &config.PoolConfig{MaxIdleConns: 2, IdleTimeout: time.Minute}}
+
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("POST", fmt.Sprintf("http://127.0.0.1:%d/v1/chat/completions", port), nil)
+		resp, err := pools.get(m).do(req)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	if *calls != 3 {
+		t.Fatalf("expected 3 upstream calls, got %d", *calls)
+	}
+	stats := pools.stats()["mock"]
+	if stats.NewConns != 1 || stats.ReusedConns != 2 {
+		t.Errorf("expected 1 new / 2 reused, got %+v", stats)
+	}
+}
+
+func TestProviderPoolPerProvider(t *testing.T) {
+	pools := &providerPools{}
+	a := pools.get(config.ResolvedModel{Provider: "a"})
+	b := pools.get(config.ResolvedModel{Provider: "b"})
+	if a == b {
+		t.Fatal("expected distinct pools per provider")
+	}
+	if pools.get(config.ResolvedModel{Provider: "a"}) != a {
+		t.Error("expected pool to be reused for the same provider")
+	}
+}
@@ -0,0 +1,91 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/defaults"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+// PreloadModels sends a one-token warm-up request to every model configured
+// with preload: true, so Ollama loads it into memory before Claude Code's first
+// routed request instead of stalling mid-session. Requests run concurrently;
+// PreloadModels returns once all of them have finished.
+func (p *Proxy) PreloadModels() {
+	if p.modelResolver == nil {
+		return
+	}
+	client := &http.Client{
+		Transport: p.localClient.Transport,
+		Timeout:   defaults.PreloadTimeout,
+	}
+
+	var wg sync.WaitGroup
+	for _, m := range p.modelResolver.Models() {
+		if !m.Preload {
+			continue
+		}
+		wg.Add(1)
+		go func(m config.ResolvedModel) {
+			defer wg.Done()
+			start := time.Now()
+			if err := preloadModel(client, m); err != nil {
+				log.Printf("PRELOAD_ERR %s → %s/%s: %v", m.Label, m.Provider, m.Model, err)
+				return
+			}
+			log.Printf("PRELOAD_OK %s → %s/%s (%dms)",
+				m.Label, m.Provider, m.Model, time.Since(start).Milliseconds())
+		}(m)
+	}
+	wg.Wait()
+}
+
+func preloadModel(client *http.Client, m config.ResolvedModel) error {
+	req := map[string]interface{}{
+		"model":      m.Model,
+		"messages":   []map[string]string{{"role": "user", "content": "hi"}},
+		"max_tokens": 1,
+	}
+	if m.KeepAlive != "" {
+		req["keep_alive"] = keepAliveValue(m.KeepAlive)
+	}
+	body, _ := json.Marshal(req)
+
+	httpReq, err := http.NewRequest("POST", m.Endpoint+"/chat/completions", strings.NewReader(string(body)))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if err := setProviderHeaders(httpReq, m); err != nil {
+		return err
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, defaults.MaxBodyBytes))
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("provider returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// keepAliveValue converts a configured keep_alive into the JSON value Ollama
+// expects: bare integers ("-1", "0", "300") are seconds and must be sent as
+// numbers, anything else ("5m", "1h") is a duration string.
+func keepAliveValue(s string) interface{} {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n
+	}
+	return s
+}
@@ -0,0 +1,96 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+func TestPreloadModels(t *testing.T) {
+	port, getLastBody, _ := capturingMockOpenAI(t)
+
+	resolver, err := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:      "ollama",
+			Endpoint:  fmt.Sprintf("http://127.0.0.1:%d/v1", port),
+			KeepAlive: "-1",
+			Models: map[string]config.ModelConfig{
+				"coder": {Model: "qwen3:32b", Preload: true},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("resolver: %v", err)
+	}
+
+	p := New(nil, WithModelResolver(resolver))
+	p.PreloadModels()
+
+	var req map[string]interface{}
+	if err := json.Unmarshal(getLastBody(), &req); err != nil {
+		t.Fatalf("no preload request captured: %v", err)
+	}
+	if req["model"] != "qwen3:32b" {
+		t.Errorf("expected model qwen3:32b, got %v", req["model"])
+	}
+	if req["max_tokens"] != float64(1) {
+		t.Errorf("expected max_tokens 1, got %v", req["max_tokens"])
+	}
+	// Integer keep_alive values must be sent as JSON numbers.
+	if req["keep_alive"] != float64(-1) {
+		t.Errorf("expected keep_alive -1, got %v (%T)", req["keep_alive"], req["keep_alive"])
+	}
+}
+
+func TestPreloadSkipsModelsWithoutFlag(t *testing.T) {
+	port, getLastBody, _ := capturingMockOpenAI(t)
+
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "ollama",
+			Endpoint: fmt.Sprintf("http://127.0.0.1:%d/v1", port),
+			Models:   map[string]config.ModelConfig{"coder": {Model: "qwen3:32b"}},
+		}},
+	})
+
+	p := New(nil, WithModelResolver(resolver))
+	p.PreloadModels()
+
+	if body := getLastBody(); body != nil {
+		t.Errorf("expected no preload request, got %s", body)
+	}
+}
+
+func TestLocalRouteForwardsKeepAlive(t *testing.T) {
+	port, getLastBody, _ := capturingMockOpenAI(t)
+
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "ollama",
+			Endpoint: fmt.Sprintf("http://127.0.0.1:%d/v1", port),
+			Models: map[string]config.ModelConfig{
+				"coder": {Model: "qwen3:32b", KeepAlive: "15m"},
+			},
+		}},
+	})
+	infra := setupInfra(t, resolver)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"system":     "<!-- @proxy-local-route:af83e9 model=coder -->",
+		"messages":   []map[string]string{{"role": "user", "content": "hello"}},
+		"max_tokens": 100,
+	})
+	status, respBody, _ := proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+	if status != 200 {
+		t.Fatalf("expected 200, got %d: %s", status, respBody)
+	}
+
+	var req map[string]interface{}
+	json.Unmarshal(getLastBody(), &req)
+	if req["keep_alive"] != "15m" {
+		t.Errorf("expected keep_alive 15m, got %v", req["keep_alive"])
+	}
+}
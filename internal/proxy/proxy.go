@@ -6,18 +6,23 @@ import (
 	"bytes"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
 	"net/http"
+	"net/http/httputil"
+	"net/netip"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/peter-wagstaff/claude-hybrid-router/internal/config"
 	"github.com/peter-wagstaff/claude-hybrid-router/internal/mitm"
-	"github.com/peter-wagstaff/claude-hybrid-router/internal/translate"
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/tracing"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/translate"
 )
 
 // Proxy is an HTTP handler that handles CONNECT requests with MITM TLS.
@@ -26,8 +31,39 @@ type Proxy struct {
 	httpClient    *http.Client
 	localClient   *http.Client
 	modelResolver *config.ModelResolver
-	sem           chan struct{}
+	admit         *admission
 	verbose       bool
+	validateSSE   bool // check the order of translated SSE events (see WithValidateSSE)
+	hosts         *hostMonitor
+	dedupe        *dedupeCache
+	pools         providerPools
+	clients       clientStats
+	transforms    *translate.TransformStats
+	tokenizers    sync.Map // label + tokenizer spec → tokenizer.Tokenizer
+	limits        config.Limits
+	upstream      *translate.TransformChain
+	upstreamCfg   *config.UpstreamConfig
+	intercept     interceptList // CONNECT targets to MITM; the rest are tunneled blind
+	bypass        bypassStats
+	tlsProfiles   []tlsProfileRule  // upstream ClientHello profile per host, most specific first
+	dnsOverrides  map[string]string // host → address dialed instead (see WithDNSOverrides)
+	har           *HARLog           // tunnel traffic capture, nil when off
+	pause         *breakpoints      // requests held for the admin API, nil when off
+	middleware    []Middleware      // hooks run for every tunnel request
+	apiHosts      interceptList     // hosts that receive Anthropic credentials
+	apiKey        string            // replaces the client's Anthropic credential when set
+	proxyToken    string            // required from clients when set (see WithProxyToken)
+	access        accessControl     // allowed_clients and per-client rate limits
+	activity      activityLog       // recent local routes, for the admin API
+	events        eventBus          // live routing events, for the admin API
+	slowThreshold time.Duration     // local routes slower than this log a phase breakdown
+	history       routeHistory      // per-conversation routing decisions, for the admin API
+	tracer        *tracing.Tracer   // nil when tracing is off (see WithTracer)
+	budgets       budgetLedger      // per-label spend today
+	judgePicks    judgePicks        // model=judge:NAME decision per conversation
+	toolIDs       toolIDTables      // provider tool call IDs behind rewritten ones, per conversation
+	thinkingKey   []byte            // signs thinking blocks for thinking_signature: hmac
+	annotations   config.AnnotationsConfig
 }
 
 // Option configures a Proxy.
@@ -38,6 +74,13 @@ func WithVerbose(v bool) Option {
 	return func(p *Proxy) { p.verbose = v }
 }
 
+// WithValidateSSE checks every translated stream against the Anthropic SSE
+// event order and logs each violation as [SSE_INVALID]. It is a debugging
+// aid for transform chains.
+func WithValidateSSE(v bool) Option {
+	return func(p *Proxy) { p.validateSSE = v }
+}
+
 // WithHTTPClient sets a custom HTTP client for upstream requests.
 func WithHTTPClient(c *http.Client) Option {
 	return func(p *Proxy) { p.httpClient = c }
@@ -48,56 +91,163 @@ func WithModelResolver(r *config.ModelResolver) Option {
 	return func(p *Proxy) { p.modelResolver = r }
 }
 
+// WithDedupe enables reuse of identical background-class local responses,
+// stored under dir so concurrent instances share them.
+func WithDedupe(dir string, cfg *config.DedupeConfig) Option {
+	return func(p *Proxy) { p.dedupe = newDedupeCache(dir, cfg) }
+}
+
+// WithUpstreamTransforms runs chain over every Messages request passed through
+// to Anthropic, with cfg.Params as the transforms' settings.
+func WithUpstreamTransforms(chain *translate.TransformChain, cfg *config.UpstreamConfig) Option {
+	return func(p *Proxy) {
+		p.upstream = chain
+		p.upstreamCfg = cfg
+	}
+}
+
+// WithIntercept sets the hosts the proxy decrypts (see
+// config.DefaultIntercept for the pattern syntax). CONNECTs to any other host
+// are tunneled without MITM. An empty list keeps the default.
+func WithIntercept(patterns []string) Option {
+	return func(p *Proxy) {
+		if len(patterns) > 0 {
+			p.intercept = newInterceptList(patterns)
+		}
+	}
+}
+
+// WithLimits overrides the default resource limits; zero fields keep their
+// defaults.
+func WithLimits(l config.Limits) Option {
+	return func(p *Proxy) { p.limits = p.limits.Merge(l) }
+}
+
 // New creates a new Proxy.
 func New(cache *mitm.CertCache, opts ...Option) *Proxy {
 	p := &Proxy{
-		certCache: cache,
-		sem:       make(chan struct{}, config.MaxProxyGoroutines),
+		certCache:  cache,
+		hosts:      newHostMonitor(),
+		transforms: translate.NewTransformStats(),
+		limits:     config.DefaultLimits(),
+
+		thinkingKey: newThinkingKey(),
 	}
 	for _, o := range opts {
 		o(p)
 	}
+	if p.intercept == nil {
+		p.intercept = newInterceptList(config.DefaultIntercept)
+	}
+	if p.apiHosts == nil {
+		p.apiHosts = newInterceptList(defaultAnthropicHosts)
+	}
+	p.admit = newAdmission(p.limits.MaxConcurrent, p.limits.MaxQueued, p.limits.QueueTimeout)
+	p.pools.timeout = p.limits.UpstreamTimeout
 	if p.httpClient == nil {
 		p.httpClient = &http.Client{
 			Transport: &http.Transport{
 				ForceAttemptHTTP2: true,
-				TLSClientConfig:  &tls.Config{},
+				TLSClientConfig:   &tls.Config{},
 			},
 			CheckRedirect: func(*http.Request, []*http.Request) error {
 				return http.ErrUseLastResponse
 			},
-			Timeout: config.UpstreamTimeout,
+			Timeout: p.limits.UpstreamTimeout,
 		}
 	}
+	if len(p.dnsOverrides) > 0 {
+		// Before the profiles, whose transports are cloned from this one.
+		c := *p.httpClient
+		c.Transport = p.overrideDial(c.Transport)
+		p.httpClient = &c
+	}
+	if len(p.tlsProfiles) > 0 {
+		c := *p.httpClient
+		if c.Transport == nil {
+			c.Transport = http.DefaultTransport
+		}
+		c.Transport = newProfileTransport(c.Transport, p.tlsProfiles)
+		p.httpClient = &c
+	}
 	if p.localClient == nil {
 		p.localClient = &http.Client{
-			Timeout: config.UpstreamTimeout,
+			Timeout: p.limits.UpstreamTimeout,
 		}
 	}
 	return p
 }
 
+// ModelResolver returns the resolver used for local routing, or nil if no
+// provider config is loaded.
+func (p *Proxy) ModelResolver() *config.ModelResolver {
+	return p.modelResolver
+}
+
 // ServeHTTP handles CONNECT requests.
 func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodConnect {
 		http.Error(w, "only CONNECT supported", http.StatusMethodNotAllowed)
 		return
 	}
+	client := clientAddr(r.RemoteAddr)
+
+	// The span covers setting up the tunnel; requests inside it get their
+	// own spans.
+	span := p.tracer.Start(traceParent(r.Header), "CONNECT", tracing.KindServer)
+	defer span.End()
+	span.SetAttr("server.address", r.Host)
+	span.SetAttr("client.address", client.String())
+
+	if !p.access.permits(client) {
+		span.SetError("client not allowed")
+		log.Printf("[PROXY_DENIED] CONNECT %s from %s refused: not in allowed_clients", r.Host, r.RemoteAddr)
+		http.Error(w, "client not allowed", http.StatusForbidden)
+		return
+	}
+	if ok, retryAfter := p.access.take(client); !ok {
+		span.SetError("rate limited")
+		p.logVerbose("[PROXY_RATE] CONNECT %s from %s refused: rate limit", r.Host, r.RemoteAddr)
+		w.Header().Set("Retry-After", retryAfter)
+		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+		return
+	}
+	if !p.authorizeConnect(w, r) {
+		span.SetError("proxy authentication required")
+		return
+	}
 
 	host, port, err := net.SplitHostPort(r.Host)
 	if err != nil {
+		span.SetError("bad CONNECT target")
 		http.Error(w, "bad CONNECT target", http.StatusBadRequest)
 		return
 	}
 
-	// Acquire semaphore (non-blocking)
-	select {
-	case p.sem <- struct{}{}:
-	default:
-		http.Error(w, "proxy overloaded", http.StatusServiceUnavailable)
+	intercepted := p.intercept.match(host)
+	span.SetAttr("hybrid.intercepted", intercepted)
+	if !intercepted {
+		span.End() // don't time the blind tunnel's lifetime
+		p.blindTunnel(w, r)
+		return
+	}
+
+	// Wait for a tunnel slot
+	queued := time.Now()
+	release, err := p.admit.acquire(r.Context())
+	wait := time.Since(queued)
+	span.SetAttr("hybrid.admission_wait_ms", wait.Milliseconds())
+	if err != nil {
+		span.SetError(err.Error())
+		if r.Context().Err() != nil {
+			return // client went away while queued
+		}
+		log.Printf("[PROXY_BUSY] CONNECT %s refused: %v", r.Host, err)
+		w.Header().Set("Retry-After", overloadRetryAfter)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
 		return
 	}
-	defer func() { <-p.sem }()
+	defer release()
 
 	// Hijack the connection
 	hj, ok := w.(http.Hijacker)
@@ -123,16 +273,20 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	tlsConn := tls.Server(conn, tlsCfg)
 	if err := tlsConn.Handshake(); err != nil {
+		span.SetError("TLS handshake failed")
 		p.logVerbose("MITM TLS handshake failed for %s: %v", host, err)
 		return
 	}
 	defer tlsConn.Close()
+	span.End()
 
-	p.handleTunnel(tlsConn, host, port)
+	p.handleTunnel(tlsConn, client, host, port, wait)
 }
 
-func (p *Proxy) handleTunnel(tlsConn net.Conn, host, port string) {
-	tlsConn.SetDeadline(deadlineFromNow(config.ClientRecvTimeout))
+// handleTunnel serves the requests on an intercepted tunnel. queued is how
+// long the CONNECT waited for a slot, which delayed the first request.
+func (p *Proxy) handleTunnel(tlsConn net.Conn, client netip.Addr, host, port string, queued time.Duration) {
+	tlsConn.SetDeadline(deadlineFromNow(p.limits.ClientRecvTimeout))
 	br := bufio.NewReader(tlsConn)
 
 	for {
@@ -140,42 +294,244 @@ func (p *Proxy) handleTunnel(tlsConn net.Conn, host, port string) {
 		if err != nil {
 			return // Connection closed or read error
 		}
-
-		body, err := io.ReadAll(io.LimitReader(req.Body, config.MaxBodyBytes+1))
-		req.Body.Close()
-		if err != nil {
-			sendError(tlsConn, 400, "Bad Request")
-			return
+		conn := tlsConn
+		var capture *exchangeCapture
+		if p.har != nil || len(p.middleware) > 0 {
+			capture = newExchangeCapture(req, "https://"+targetAuthority(host, port), p.limits.MaxBodyBytes)
+			conn = capture.wrap(tlsConn)
+		}
+		var ex *Exchange
+		if len(p.middleware) > 0 {
+			ex = &Exchange{Method: req.Method, URL: capture.url, Header: req.Header}
 		}
-		if int64(len(body)) > config.MaxBodyBytes {
-			sendError(tlsConn, 413, "Content Too Large")
+		keep := p.serveTunnelRequest(conn, client, host, port, req, ex, queued)
+		queued = 0
+		if capture != nil {
+			cr := capture.response()
+			if p.har != nil {
+				p.har.record(capture, cr)
+			}
+			if ex != nil {
+				resp := &ExchangeResponse{Body: cr.body, Truncated: cr.truncated}
+				if cr.resp != nil {
+					resp.Status, resp.Header = cr.resp.StatusCode, cr.resp.Header
+				}
+				p.onResponse(ex, resp)
+			}
+		}
+		if !keep {
 			return
 		}
+	}
+}
+
+// serveTunnelRequest answers one request read from a tunnel and reports
+// whether the connection can carry another. ex is the request as the
+// middleware sees it, nil when there is none. queued is the tunnel's
+// admission wait when req is its first request.
+func (p *Proxy) serveTunnelRequest(tlsConn net.Conn, client netip.Addr, host, port string, req *http.Request, ex *Exchange, queued time.Duration) bool {
+	span := p.tracer.Start(traceParent(req.Header), req.Method+" "+req.URL.Path, tracing.KindServer)
+	span.SetAttr("http.request.method", req.Method)
+	span.SetAttr("url.path", req.URL.Path)
+	span.SetAttr("server.address", host)
+	if id := req.Header.Get("X-Client-Request-Id"); id != "" {
+		span.SetAttr("hybrid.client_request_id", id) // correlates with the client's own telemetry
+	}
+	if ok, retryAfter := p.access.take(client); !ok {
+		span.SetError("rate limited")
+		span.End()
+		p.logVerbose("[PROXY_RATE] %s %s%s from %s refused: rate limit", req.Method, host, req.URL.Path, client)
+		sendRateLimited(tlsConn, retryAfter)
+		return false
+	}
+	if mayCarryMarker(req) && req.ContentLength > p.limits.MaxBodyBytes {
+		// Refused on its declared size, before a client waiting on
+		// Expect: 100-continue is asked for the body.
+		span.SetError("request too large")
+		span.End()
+		sendError(tlsConn, 413, "Content Too Large")
+		return false
+	}
+	if !answerExpect(tlsConn, req) {
+		span.SetError("unsupported expectation")
+		span.End()
+		return false
+	}
 
-		// Reset deadline for each request
-		tlsConn.SetDeadline(deadlineFromNow(config.ClientRecvTimeout))
+	// Only Messages API calls can carry a routing marker. Everything else
+	// (uploads, telemetry, file APIs) streams straight through without
+	// being buffered in memory.
+	if !mayCarryMarker(req) {
+		tlsConn.SetDeadline(deadlineFromNow(p.limits.ClientRecvTimeout))
+		if err := p.onRequest(ex); err != nil {
+			// The body is left unread, so the connection can't be reused.
+			p.refuseByMiddleware(tlsConn, req, host, port, span, err)
+			return false
+		}
+		span.SetAttr("hybrid.route", "upstream")
+		p.decideRoute(ex, req, host, port, "upstream", "")
+		ok := p.forwardUpstream(tlsConn, host, port, req, req.Body, req.ContentLength, span, ex)
+		span.End()
+		io.Copy(io.Discard, req.Body)
+		req.Body.Close()
+		return ok && !req.Close
+	}
 
-		routeModel, strippedBody := detectLocalRoute(body)
-		if routeModel != "" {
-			streamMode := "non-streaming"
-			var reqMeta struct{ Stream bool `json:"stream"` }
-			if json.Unmarshal(body, &reqMeta) == nil && reqMeta.Stream {
-				streamMode = "streaming"
-			}
-			log.Printf("LOCAL_ROUTE %s https://%s:%s%s → model=%s (%s)",
-				req.Method, host, port, req.URL.RequestURI(), routeModel, streamMode)
+	body, err := io.ReadAll(io.LimitReader(req.Body, p.limits.MaxBodyBytes+1))
+	req.Body.Close()
+	if err != nil {
+		span.SetError("request read failed")
+		span.End()
+		sendError(tlsConn, 400, "Bad Request")
+		return false
+	}
+	if int64(len(body)) > p.limits.MaxBodyBytes {
+		span.SetError("request too large")
+		span.End()
+		sendError(tlsConn, 413, "Content Too Large")
+		return false
+	}
 
-			p.forwardLocal(tlsConn, routeModel, strippedBody)
+	var resume bool
+	if body, resume = p.pause.hold(req, "https://"+targetAuthority(host, port)+req.URL.RequestURI(), body); !resume {
+		span.SetError("dropped at a breakpoint")
+		span.End()
+		sendAnthropicError(tlsConn, 403, translate.FormatError("permission_error",
+			"[PAUSED] Request dropped at a breakpoint from the admin API"))
+		return false
+	}
+	if ex != nil {
+		ex.Body = body
+		if err := p.onRequest(ex); err != nil {
+			p.refuseByMiddleware(tlsConn, req, host, port, span, err)
+			return false
+		}
+		body = ex.Body
+	}
+
+	// Reset deadline for each request
+	tlsConn.SetDeadline(deadlineFromNow(p.limits.ClientRecvTimeout))
+
+	if isEmbeddings(req.URL.Path) {
+		ok := true
+		if label, local := p.embeddingLabel(body); local {
+			log.Printf("LOCAL_ROUTE %s https://%s%s → embedding model=%s", req.Method, net.JoinHostPort(host, port), req.URL.RequestURI(), label)
+			span.SetAttr("hybrid.route", "embeddings")
+			span.SetAttr("hybrid.label", label)
+			p.decideRoute(ex, req, host, port, "embeddings", label)
+			p.embeddingsLocal(tlsConn, body)
 		} else {
-			if !p.forwardUpstream(tlsConn, host, port, req, body) {
-				return
-			}
+			span.SetAttr("hybrid.route", "upstream")
+			p.decideRoute(ex, req, host, port, "upstream", "")
+			ok = p.forwardUpstream(tlsConn, host, port, req, bytes.NewReader(body), int64(len(body)), span, ex)
 		}
+		span.End()
+		return ok && !req.Close
+	}
 
-		if req.Close {
-			return
+	rr := parseRouteRequest(body)
+	rr.Header = req.Header
+	rr.Span = span
+	rr.Exchange = ex
+	rr.QueueWait = queued
+	if rr.MarkerErr != nil {
+		// Never forward a request that was meant to stay local.
+		span.SetAttr("hybrid.route", "local")
+		p.decideRoute(ex, req, host, port, "local", "")
+		span.SetError("malformed routing marker")
+		span.End()
+		log.Printf("[LOCAL_ERR:MARKER] %s https://%s%s: malformed routing marker: %v",
+			req.Method, net.JoinHostPort(host, port), req.URL.RequestURI(), rr.MarkerErr)
+		sendAnthropicError(tlsConn, 400, translate.FormatError("invalid_request_error",
+			fmt.Sprintf("[MARKER] Malformed routing marker: %v", rr.MarkerErr)))
+	} else if rr.Route.Model != "" {
+		streamMode := "non-streaming"
+		if rr.Stream {
+			streamMode = "streaming"
+		}
+		opts := ""
+		if len(rr.Route.Opts) > 0 {
+			opts = " " + strings.Join(rr.Route.Opts, " ")
+		}
+		log.Printf("LOCAL_ROUTE %s https://%s%s → model=%s%s (%s)",
+			req.Method, net.JoinHostPort(host, port), req.URL.RequestURI(), rr.Route.Model, opts, streamMode)
+
+		span.SetAttr("hybrid.label", rr.Route.Model)
+		span.SetAttr("hybrid.stream", rr.Stream)
+		if isCountTokens(req.URL.Path) {
+			span.SetAttr("hybrid.route", "count_tokens")
+			p.decideRoute(ex, req, host, port, "count_tokens", rr.Route.Model)
+			p.countTokensLocal(tlsConn, rr)
+		} else {
+			span.SetAttr("hybrid.route", "local")
+			p.decideRoute(ex, req, host, port, "local", rr.Route.Model)
+			p.forwardLocal(tlsConn, rr)
+		}
+		span.End()
+	} else {
+		span.SetAttr("hybrid.route", "upstream")
+		p.decideRoute(ex, req, host, port, "upstream", "")
+		if p.upstream != nil {
+			if body, err = p.transformUpstream(body); err != nil {
+				span.SetError("upstream transform failed")
+				span.End()
+				log.Printf("[UPSTREAM_ERR:TRANSFORM] %s%s: %v", host, req.URL.Path, err)
+				sendAnthropicError(tlsConn, 500, translate.FormatError("api_error",
+					fmt.Sprintf("upstream transform failed: %v", err)))
+				return false
+			}
+		}
+		if !isCountTokens(req.URL.Path) {
+			ref := conversationOf(body)
+			p.history.record(ref, HistoryEntry{Route: "upstream", Model: ref.model})
+		}
+		ok := p.forwardUpstream(tlsConn, host, port, req, bytes.NewReader(body), int64(len(body)), span, ex)
+		span.End()
+		if !ok {
+			return false
 		}
 	}
+
+	return !req.Close
+}
+
+// transformUpstream applies the upstream transform chain to an Anthropic
+// Messages body. Errors fail the request rather than sending the original,
+// since a redaction transform must never be skipped silently.
+func (p *Proxy) transformUpstream(body []byte) ([]byte, error) {
+	var req map[string]interface{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("parse request: %w", err)
+	}
+	model, _ := req["model"].(string)
+	ctx := translate.NewTransformContext(model, "anthropic")
+	ctx.Params = p.upstreamCfg.Params
+	ctx.Stats = p.transforms
+	if err := p.upstream.RunRequest(req, ctx); err != nil {
+		return nil, err
+	}
+	return json.Marshal(req)
+}
+
+// answerExpect handles a request's Expect header, which is addressed to the
+// proxy and never forwarded. A client sending Expect: 100-continue holds its
+// body back until told to go on, and every accepted request has its body
+// read, so the interim response goes out at once. Any other expectation is
+// refused with 417, and answerExpect returns false.
+func answerExpect(w io.Writer, req *http.Request) bool {
+	expect := req.Header.Get("Expect")
+	if expect == "" {
+		return true
+	}
+	req.Header.Del("Expect")
+	if !strings.EqualFold(strings.TrimSpace(expect), "100-continue") {
+		sendError(w, 417, "Expectation Failed")
+		return false
+	}
+	if req.ProtoAtLeast(1, 1) && req.ContentLength != 0 {
+		io.WriteString(w, "HTTP/1.1 100 Continue\r\n\r\n")
+	}
+	return true
 }
 
 var hopByHop = map[string]bool{
@@ -185,94 +541,162 @@ var hopByHop = map[string]bool{
 	"te":                true,
 	"trailers":          true,
 	"upgrade":           true,
+	// Trailers are read off chunked bodies and dropped in both directions,
+	// so the header announcing them is dropped too.
+	"trailer": true,
 }
 
-func (p *Proxy) forwardUpstream(tlsConn net.Conn, host, port string, req *http.Request, body []byte) bool {
-	var url string
-	if port == "443" {
-		url = "https://" + host + req.URL.RequestURI()
-	} else {
-		url = "https://" + net.JoinHostPort(host, port) + req.URL.RequestURI()
+// mayCarryMarker reports whether req could contain a routing marker, or
+// name an embedding label, and so must be buffered for inspection: a JSON
+// POST to a Messages or embeddings API path.
+func mayCarryMarker(req *http.Request) bool {
+	if req.Method != http.MethodPost || !strings.Contains(req.URL.Path, "/messages") && !isEmbeddings(req.URL.Path) {
+		return false
+	}
+	ct := req.Header.Get("Content-Type")
+	return ct == "" || strings.Contains(ct, "json")
+}
+
+// forwardUpstream relays req to the real host. body is read once; contentLength
+// is its size, or -1 when unknown (the upstream request is then sent chunked).
+// The relay is traced as a child of span, and an event stream passes through
+// ex's OnStreamEvent hooks.
+func (p *Proxy) forwardUpstream(tlsConn net.Conn, host, port string, req *http.Request, body io.Reader, contentLength int64, span *tracing.Span, ex *Exchange) bool {
+	up := p.tracer.Start(span.Context(), "upstream "+host, tracing.KindClient)
+	defer up.End()
+	up.SetAttr("server.address", host)
+
+	authority := targetAuthority(host, port)
+	url := "https://" + authority + req.URL.RequestURI()
+	if addr, plain := p.overrideAddr(net.JoinHostPort(host, port)); plain {
+		url = "http://" + addr + req.URL.RequestURI()
 	}
 
 	var bodyReader io.Reader
-	if len(body) > 0 {
-		bodyReader = strings.NewReader(string(body))
+	if contentLength != 0 {
+		bodyReader = body
 	}
 
 	upReq, err := http.NewRequest(req.Method, url, bodyReader)
 	if err != nil {
-		sendError(tlsConn, 502, "Bad Gateway")
+		up.SetError("bad request URL")
+		span.SetError("bad request URL")
+		p.sendUpstreamError(tlsConn, host, req.URL.Path, err)
 		return false
 	}
 
-	// Copy headers, skip hop-by-hop
-	for k, vals := range req.Header {
-		if hopByHop[strings.ToLower(k)] {
-			continue
-		}
-		for _, v := range vals {
-			upReq.Header.Add(k, v)
-		}
-	}
-	if len(body) > 0 {
-		upReq.ContentLength = int64(len(body))
+	upReq.Host = authority
+	p.forwardHeaders(upReq.Header, req.Header, p.classifyHost(host))
+	if bodyReader != nil {
+		upReq.ContentLength = contentLength
 	}
 
 	resp, err := p.httpClient.Do(upReq)
+	if err != nil && retryUpstream(upReq, err) {
+		// A blip on the upstream leg. Pooled connections may share the
+		// fault, so drop them and try once more on a fresh one.
+		if p.verbose || isAPIHost(host) {
+			log.Printf("upstream error for %s %s%s: %v; retrying on a fresh connection", req.Method, host, req.URL.Path, err)
+		}
+		up.SetAttr("hybrid.upstream_retry", true)
+		p.httpClient.CloseIdleConnections()
+		resp, err = p.httpClient.Do(upReq.Clone(upReq.Context()))
+	}
 	if err != nil {
+		up.SetError(translate.ClassifyError(err))
+		span.SetError(translate.ClassifyError(err))
 		if p.verbose || isAPIHost(host) {
 			log.Printf("upstream error for %s: %v", host, err)
 		}
-		sendError(tlsConn, 502, "Bad Gateway")
+		p.sendUpstreamError(tlsConn, host, req.URL.Path, err)
 		return false
 	}
 	defer resp.Body.Close()
+	up.SetAttr("http.response.status_code", resp.StatusCode)
+	span.SetAttr("http.response.status_code", resp.StatusCode)
 
-	// Build HTTP/1.1 response headers, stripping hop-by-hop
-	hasCL := resp.ContentLength >= 0
-
-	if hasCL {
-		// Stream directly with known Content-Length
+	// Build HTTP/1.1 response headers, stripping hop-by-hop. The body is
+	// relayed as the server encoded it, never decoded or recompressed: the
+	// client's Accept-Encoding was forwarded, so it can read any encoding the
+	// server chose. When the client sent none, the transport asked for gzip
+	// itself and hands back the decoded body with Content-Encoding and
+	// Content-Length removed, so the headers still describe the bytes sent.
+	switch {
+	case resp.ContentLength >= 0 || !bodyAllowed(req.Method, resp.StatusCode):
+		// Stream directly with known Content-Length (or no body at all)
 		writeResponseHeaders(tlsConn, resp)
 		if _, err := io.Copy(tlsConn, resp.Body); err != nil {
 			p.logVerbose("response streaming error for %s: %v", host, err)
 			return false
 		}
-	} else {
-		// Buffer body and add Content-Length
-		respBody, err := io.ReadAll(io.LimitReader(resp.Body, config.MaxBodyBytes+1))
-		if err != nil {
-			p.logVerbose("response read error for %s: %v", host, err)
-			return false
+	case req.ProtoAtLeast(1, 1):
+		// Unknown length, as for an SSE stream: relay each read as one
+		// chunk the moment it arrives, so events reach the client live.
+		writeResponseHeaders(tlsConn, resp, "Transfer-Encoding: chunked")
+		cw := httputil.NewChunkedWriter(tlsConn)
+		var out io.Writer = cw
+		hw := p.streamHooks(ex, cw)
+		if isEventStream(resp.Header) && resp.Header.Get("Content-Encoding") == "" {
+			out = hw
 		}
-		if int64(len(respBody)) > config.MaxBodyBytes {
-			p.logVerbose("response from %s exceeded size limit", host)
-			sendError(tlsConn, 502, "Bad Gateway")
+		if _, err := io.Copy(out, resp.Body); err != nil {
+			// Without the last chunk the client sees the body as cut off.
+			p.logVerbose("response streaming error for %s: %v", host, err)
 			return false
 		}
-		writeResponseHeadersWithCL(tlsConn, resp, len(respBody))
-		tlsConn.Write(respBody)
+		hw.flush()
+		cw.Close()
+		io.WriteString(tlsConn, "\r\n")
+	default:
+		// An HTTP/1.0 client can't read chunks: send the body raw and end
+		// it by closing the connection.
+		writeResponseHeaders(tlsConn, resp, "Connection: close")
+		if _, err := io.Copy(tlsConn, resp.Body); err != nil {
+			p.logVerbose("response streaming error for %s: %v", host, err)
+		}
+		return false
 	}
 
 	return true
 }
 
-func writeResponseHeaders(w io.Writer, resp *http.Response) {
-	fmt.Fprintf(w, "HTTP/1.1 %s\r\n", resp.Status) // "200 OK"
-	for k, vals := range resp.Header {
-		if hopByHop[strings.ToLower(k)] {
-			continue
-		}
-		for _, v := range vals {
-			fmt.Fprintf(w, "%s: %s\r\n", k, v)
-		}
+// targetAuthority is the host[:port] of a CONNECT target as written in a
+// URL: the default port 443 is left off and an IPv6 literal is bracketed.
+// host is as net.SplitHostPort returns it, without brackets.
+func targetAuthority(host, port string) string {
+	if port != "443" {
+		return net.JoinHostPort(host, port)
 	}
-	fmt.Fprint(w, "\r\n")
+	if strings.Contains(host, ":") {
+		return "[" + host + "]"
+	}
+	return host
+}
+
+// retryUpstream reports whether a failed upstream request may be sent again:
+// a GET or HEAD without a body, which is safe to repeat, that failed on the
+// connection. A timeout isn't retried, since a second wait as long as the
+// first is worse than the error.
+func retryUpstream(req *http.Request, err error) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		return false
+	}
+	return translate.ClassifyError(err) != "TIMEOUT"
+}
+
+// bodyAllowed reports whether a response to method with status may carry a
+// body (RFC 9110 section 6.4.1).
+func bodyAllowed(method string, status int) bool {
+	return method != http.MethodHead && status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
 }
 
-func writeResponseHeadersWithCL(w io.Writer, resp *http.Response, bodyLen int) {
-	fmt.Fprintf(w, "HTTP/1.1 %s\r\n", resp.Status)
+// writeResponseHeaders writes resp's status line and end-to-end headers,
+// then any extra header lines.
+func writeResponseHeaders(w io.Writer, resp *http.Response, extra ...string) {
+	fmt.Fprintf(w, "HTTP/1.1 %s\r\n", resp.Status) // "200 OK"
 	for k, vals := range resp.Header {
 		if hopByHop[strings.ToLower(k)] {
 			continue
@@ -281,36 +705,101 @@ func writeResponseHeadersWithCL(w io.Writer, resp *http.Response, bodyLen int) {
 			fmt.Fprintf(w, "%s: %s\r\n", k, v)
 		}
 	}
-	fmt.Fprintf(w, "Content-Length: %d\r\n", bodyLen)
+	for _, line := range extra {
+		fmt.Fprintf(w, "%s\r\n", line)
+	}
 	fmt.Fprint(w, "\r\n")
 }
 
-func (p *Proxy) forwardLocal(w io.Writer, modelLabel string, body []byte) {
+func (p *Proxy) forwardLocal(w io.Writer, rr routeRequest) {
+	route, body, isStreaming := rr.Route, rr.Body, rr.Stream
+	modelLabel := route.Model
+	ann := p.annotate(w, "local", modelLabel)
+	w = ann
 	if p.modelResolver == nil {
 		// No config — fall back to stub response
-		isStreaming := false
-		var data map[string]interface{}
-		if json.Unmarshal(body, &data) == nil {
-			if s, ok := data["stream"].(bool); ok {
-				isStreaming = s
-			}
-		}
+		ann.route = "stub"
 		sendLocalStub(w, modelLabel, isStreaming)
 		return
 	}
 
 	start := time.Now()
+	ev := p.activity.begin(modelLabel, isStreaming)
+	ev.conv = conversationOf(body)
+	defer p.finishRoute(ev)
+	trace := &localTrace{tracer: p.tracer, req: rr.Span, slow: p.slowThreshold, queued: rr.QueueWait}
+	defer trace.end(ev)
 
-	resolved, err := p.modelResolver.Resolve(modelLabel)
+	trace.begin("route", tracing.KindInternal)
+	candidates, err := p.modelResolver.Candidates(p.routeTarget(modelLabel, body))
 	if err != nil {
+		ev.Status = "CONFIG"
 		log.Printf("model resolution failed: %v", err)
 		errBody := translate.FormatError("invalid_request_error",
 			fmt.Sprintf("Unknown model label %q — check ~/.claude-hybrid/config.yaml", modelLabel))
 		sendAnthropicError(w, 400, errBody)
 		return
 	}
+	resolved := candidates[0]
+	var release func()
+	if len(candidates) > 1 {
+		resolved, release = p.pickMember(modelLabel, candidates, false)
+	}
+
+	use, ok, spent := p.applyBudget(resolved)
+	if !ok {
+		ev.Status = "BUDGET"
+		log.Printf("[LOCAL_ERR:BUDGET] %s refused: $%.2f of its $%.2f daily budget spent", use.Label, spent, use.Budget.DailyUSD)
+		msg := fmt.Sprintf("[BUDGET] Local model '%s' has spent $%.2f of its $%.2f daily budget; it resets at local midnight. Raise budget.daily_usd in ~/.claude-hybrid/config.yaml to continue today",
+			use.Label, spent, use.Budget.DailyUSD)
+		sendAnthropicError(w, 402, translate.FormatError("billing_error", msg))
+		return
+	}
+	resolved = route.apply(use)
+	p.activity.setTarget(ev, resolved.Provider, resolved.Model)
+	ann.setTarget(resolved)
+
+	if resolved.API == config.APIAnthropic {
+		ev.Status = "CONFIG"
+		errBody := translate.FormatError("invalid_request_error",
+			fmt.Sprintf("Model label %q is on an api: anthropic provider, which is only served by the OpenAI-compatible listener (--openai-addr)", modelLabel))
+		sendAnthropicError(w, 400, errBody)
+		return
+	}
+
+	dedupeKey := ""
+	if p.dedupe != nil {
+		dedupeKey = p.dedupe.key(route.dedupeLabel(), body)
+		if dedupeKey != "" {
+			e, age, ok, done := p.dedupe.lookup(dedupeKey)
+			if ok {
+				ev.Status = "dedupe"
+				ann.route = "dedupe"
+				log.Printf("LOCAL_DEDUPE %s → reused response from %dms ago", modelLabel, age.Milliseconds())
+				writeDedupeHit(w, e)
+				return
+			}
+			defer done()
+		}
+	}
+
+	if release == nil {
+		release, err = p.hosts.acquire(resolved)
+		if err != nil {
+			ev.Status = "CAPACITY"
+			log.Printf("[LOCAL_ERR:CAPACITY] %s refused: %v", modelLabel, err)
+			msg := fmt.Sprintf("[CAPACITY] Local model '%s' unavailable: %v", modelLabel, err)
+			if resolved.Fallback != "" {
+				msg += fmt.Sprintf(" — try the fallback label '%s'", resolved.Fallback)
+			}
+			sendAnthropicError(w, 529, translate.FormatError("overloaded_error", msg))
+			return
+		}
+	}
+	defer release()
 
 	// Build transform chain
+	trace.begin("translate request", tracing.KindInternal)
 	chain, err := translate.BuildChain(resolved.Transform)
 	if err != nil {
 		log.Printf("transform chain build failed for %v: %v — falling back to no transforms", resolved.Transform, err)
@@ -318,10 +807,58 @@ func (p *Proxy) forwardLocal(w io.Writer, modelLabel string, body []byte) {
 	}
 	ctx := translate.NewTransformContext(resolved.Model, resolved.Provider)
 	ctx.Params = resolved.Params
+	ctx.Stats = p.transforms
+	ctx.ToolIDs = p.toolIDs.get(ev.conv.id)
+	ctx.FinishReasons = resolved.FinishReasons
+	ctx.Citations = resolved.Citations
+	if resolved.ThinkingSignature == "hmac" {
+		ctx.Signer = p.thinkingSigner(ev.conv.id)
+	}
+	if resolved.HideReasoning || resolved.ReasoningSummary > 0 {
+		ctx.ReasoningDisplay = translate.ReasoningDisplay{
+			Hidden:        resolved.HideReasoning,
+			SummaryTokens: resolved.ReasoningSummary,
+			Count:         p.tokenizerFor(resolved).Count,
+		}
+	}
 
-	// Translate request body
-	oaiBody, err := translate.RequestToOpenAI(body, resolved.Model, resolved.MaxTokens)
+	// Translate request body. raw=openai routes skip translation and request
+	// transforms: the caller has already written the prompt for the backend.
+	// Response transforms still run.
+	var oaiBody []byte
+	reqChain := chain
+	if route.Raw == "openai" {
+		oaiBody, err = translate.PassthroughToOpenAIOptions(body, translate.RequestOptions{
+			Model:        resolved.Model,
+			MaxTokensCap: resolved.MaxTokens,
+			TopK:         resolved.SendTopK,
+		})
+		reqChain = translate.NewTransformChain()
+	} else {
+		opts := translate.RequestOptions{
+			Model:           resolved.Model,
+			MaxTokensCap:    resolved.MaxTokens,
+			ToolErrorPrefix: translate.DefaultToolErrorPrefix,
+			EmptyContent:    resolved.EmptyContent,
+			TopK:            resolved.SendTopK,
+			Signer:          ctx.Signer,
+			Documents:       resolved.Documents,
+		}
+		if resolved.ToolErrorPrefix != nil {
+			opts.ToolErrorPrefix = *resolved.ToolErrorPrefix
+		}
+		oaiBody, err = translate.RequestToOpenAIOptions(body, opts)
+	}
+	var unsupported *translate.UnsupportedContentError
+	if errors.As(err, &unsupported) {
+		ev.Status = "TRANSLATE"
+		log.Printf("[LOCAL_ERR:TRANSLATE] unsupported content for %s: %v", modelLabel, err)
+		sendAnthropicError(w, 400, translate.FormatError("invalid_request_error",
+			fmt.Sprintf("[TRANSLATE] Model '%s': %v", modelLabel, err)))
+		return
+	}
 	if err != nil {
+		ev.Status = "TRANSLATE"
 		log.Printf("request translation failed: %v", err)
 		errBody := translate.FormatError("api_error", fmt.Sprintf("Request translation failed: %v", err))
 		sendAnthropicError(w, 500, errBody)
@@ -331,26 +868,30 @@ func (p *Proxy) forwardLocal(w io.Writer, modelLabel string, body []byte) {
 	// Run request transforms
 	var oaiReq map[string]interface{}
 	if err := json.Unmarshal(oaiBody, &oaiReq); err == nil {
-		if err := chain.RunRequest(oaiReq, ctx); err != nil {
+		route.setSampling(oaiReq)
+		if route.Raw == "" {
+			ctx.ToolIDs.RestoreRequest(oaiReq)
+		}
+		if err := reqChain.RunRequest(oaiReq, ctx); err != nil {
+			ev.Status = "TRANSLATE"
 			log.Printf("[LOCAL_ERR:TRANSLATE] request transform failed for %s: %v", modelLabel, err)
 			errBody := translate.FormatError("api_error",
 				fmt.Sprintf("[TRANSLATE] Request transform failed for '%s': %v", modelLabel, err))
 			sendAnthropicError(w, 500, errBody)
 			return
 		}
-		oaiBody, _ = json.Marshal(oaiReq)
-	}
-
-	// Determine if streaming
-	isStreaming := false
-	var data map[string]interface{}
-	if json.Unmarshal(body, &data) == nil {
-		if s, ok := data["stream"].(bool); ok {
-			isStreaming = s
+		if resolved.KeepAlive != "" {
+			if _, exists := oaiReq["keep_alive"]; !exists {
+				oaiReq["keep_alive"] = keepAliveValue(resolved.KeepAlive)
+			}
 		}
+		oaiBody, _ = json.Marshal(oaiReq)
 	}
 
 	// Build request to local provider
+	call := trace.begin("provider "+resolved.Provider, tracing.KindClient)
+	call.SetAttr("server.address", resolved.Endpoint)
+	call.SetAttr("gen_ai.request.model", resolved.Model)
 	endpoint := resolved.Endpoint + "/chat/completions"
 	localReq, err := http.NewRequest("POST", endpoint, strings.NewReader(string(oaiBody)))
 	if err != nil {
@@ -359,14 +900,43 @@ func (p *Proxy) forwardLocal(w io.Writer, modelLabel string, body []byte) {
 		sendAnthropicError(w, 500, errBody)
 		return
 	}
+	p.forwardHeaders(localReq.Header, rr.Header, destLocal)
 	localReq.Header.Set("Content-Type", "application/json")
-	if resolved.APIKey != "" {
-		localReq.Header.Set("Authorization", "Bearer "+resolved.APIKey)
+	if err := setProviderHeaders(localReq, resolved); err != nil {
+		ev.Status = "SECRET"
+		log.Printf("[LOCAL_ERR:SECRET] %s: %v", modelLabel, err)
+		errBody := translate.FormatError("api_error",
+			fmt.Sprintf("[SECRET] No API key for local model '%s': %v", modelLabel, err))
+		sendAnthropicError(w, 502, errBody)
+		return
+	}
+	if call != nil {
+		localReq.Header.Set("traceparent", call.Context().Traceparent())
 	}
 
-	resp, err := p.localClient.Do(localReq)
+	p.events.publish(RoutingEvent{Type: EventProviderCalled, Label: modelLabel,
+		Provider: resolved.Provider, Model: resolved.Model, Stream: isStreaming})
+
+	// A first-token deadline replaces the pool's total timeout for streams.
+	pool := p.pools.get(resolved)
+	var ft *firstTokenDeadline
+	var resp *http.Response
+	if isStreaming && resolved.FirstTokenTimeout > 0 {
+		reqCtx, deadline, cancel := armFirstToken(resolved.FirstTokenTimeout)
+		defer cancel()
+		ft = deadline
+		resp, err = pool.doStream(localReq.WithContext(reqCtx))
+	} else {
+		resp, err = pool.do(localReq)
+	}
 	if err != nil {
+		if ft.expired() {
+			ev.Status = "FIRST_TOKEN"
+			sendFirstTokenTimeout(w, resolved)
+			return
+		}
 		cat := translate.ClassifyError(err)
+		ev.Status = cat
 		log.Printf("[LOCAL_ERR:%s] %s unreachable: %v (%s)", cat, modelLabel, err, endpoint)
 		errBody := translate.FormatError("api_error",
 			fmt.Sprintf("[%s] Local model '%s' unreachable: %v (%s)", cat, modelLabel, err, endpoint))
@@ -374,56 +944,150 @@ func (p *Proxy) forwardLocal(w io.Writer, modelLabel string, body []byte) {
 		return
 	}
 	defer resp.Body.Close()
+	call.SetAttr("http.response.status_code", resp.StatusCode)
+	// The provider's request ID goes back to Claude Code as request-id, the
+	// header it reports Anthropic's under, so its logs point at the
+	// provider's.
+	providerID := providerRequestID(resp.Header)
+	if providerID != "" {
+		call.SetAttr("hybrid.provider_request_id", providerID)
+	}
 
 	if resp.StatusCode != 200 {
 		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
 		sanitized := sanitizeForLog(string(respBody))
+		ev.Status = fmt.Sprintf("HTTP_%d", resp.StatusCode)
+		if resp.StatusCode == http.StatusUnauthorized && resolved.Secret != nil {
+			// The key may have been rotated; read it again next time.
+			resolved.Secret.Invalidate()
+		}
+		if providerID != "" {
+			sanitized += " (request " + providerID + ")"
+		}
 		log.Printf("[LOCAL_ERR:HTTP_%d] %s returned %d: %s", resp.StatusCode, modelLabel, resp.StatusCode, sanitized)
-		errBody := translate.FormatError("api_error",
+		code, errType := providerErrorStatus(resp.StatusCode)
+		errBody := translate.FormatError(errType,
 			fmt.Sprintf("[HTTP_%d] Local provider '%s' returned %d: %s", resp.StatusCode, modelLabel, resp.StatusCode, sanitized))
-		// Map provider client errors (4xx) to 400 so the caller treats them
-		// as non-retryable.  We can't forward the raw code (e.g. 401) because
-		// the client thinks it's talking to Anthropic and may retry auth
-		// errors.  Server errors (5xx) become 502 to indicate upstream failure.
-		code := 502
-		if resp.StatusCode >= 400 && resp.StatusCode < 500 {
-			code = 400
+		if retryAfter := resp.Header.Get("Retry-After"); code == http.StatusTooManyRequests && retryAfter != "" {
+			sendAnthropicErrorRetry(w, code, errBody, retryAfter)
+			return
 		}
 		sendAnthropicError(w, code, errBody)
 		return
 	}
 
 	if isStreaming {
-		// Stream: translate OpenAI SSE → Anthropic SSE
-		var sseBuf bytes.Buffer
+		// Stream: translate OpenAI SSE → Anthropic SSE, relaying events as
+		// they are produced. Closing the provider body on abort stops the
+		// translator when the client falls too far behind.
+		trace.begin("stream", tracing.KindInternal)
+		body := &liveBody{rc: resp.Body}
+		sw := newSSEStreamWriter(w, p.limits.StreamBufferBytes, p.limits.ClientWriteTimeout, &p.clients,
+			func() { body.Close() })
+		if providerID != "" {
+			sw.AddHeader("Request-Id", providerID)
+		}
+		hw := p.streamHooks(rr.Exchange, sw)
+		var out io.Writer = hw
+		var captured bytes.Buffer
+		if dedupeKey != "" {
+			out = io.MultiWriter(hw, &captured)
+		}
+		out = io.MultiWriter(out, &previewWriter{log: &p.activity, ev: ev})
+		var validator *translate.SSEValidator
+		if p.validateSSE {
+			validator = translate.NewSSEValidator(func(v string) {
+				log.Printf("[SSE_INVALID] %s (%s): %s", modelLabel, strings.Join(resolved.Transform, ","), v)
+			})
+			out = io.MultiWriter(out, validator)
+		}
 		st := translate.NewStreamTranslator(modelLabel)
 		st.SetVerbose(p.verbose)
+		st.SetMaxLineBytes(resolved.SSEMaxLine)
 		st.SetTransformChain(chain, ctx)
-		streamErr := st.TranslateStream(resp.Body, &sseBuf)
-		sseBody := sseBuf.Bytes()
-		if streamErr != nil {
+		st.SetTokenCounter(p.tokenizerFor(resolved).Count)
+		var gate *firstTokenGate
+		if ft != nil {
+			gate = &firstTokenGate{dst: out, onToken: ft.disarm}
+			out = gate
+		}
+		var streamErr error
+		if resolved.StreamResume > 0 && oaiReq != nil {
+			streamErr = p.translateResumed(st, body, out, resolved, oaiReq, localReq.Header)
+			defer body.Close()
+		} else {
+			streamErr = st.TranslateStream(resp.Body, out)
+		}
+		if ft.expired() && !gate.open {
+			sw.Close()
+			ev.Status = "FIRST_TOKEN"
+			sendFirstTokenTimeout(w, resolved)
+			return
+		}
+		if gate != nil {
+			gate.flush()
+		}
+		hw.flush()
+		if streamErr != nil && !sw.Started() {
+			sw.Close()
 			cat := translate.ClassifyError(streamErr)
+			ev.Status = cat
 			log.Printf("[LOCAL_ERR:%s] stream translation error for %s: %v", cat, modelLabel, streamErr)
-			if len(sseBody) == 0 {
-				errBody := translate.FormatError("api_error",
-					fmt.Sprintf("[%s] Stream translation failed for '%s': %v", cat, modelLabel, streamErr))
-				sendAnthropicError(w, 502, errBody)
-				return
-			}
-			sseBody = append(sseBody, translate.FormatStreamError("api_error",
-				fmt.Sprintf("[%s] Stream interrupted for '%s': %v", cat, modelLabel, streamErr))...)
+			errBody := translate.FormatError("api_error",
+				fmt.Sprintf("[%s] Stream translation failed for '%s': %v", cat, modelLabel, streamErr))
+			sendAnthropicError(w, 502, errBody)
+			return
+		}
+		var cat string
+		if streamErr != nil {
+			cat = translate.ClassifyError(streamErr)
+			sw.Write(translate.FormatStreamError("api_error",
+				fmt.Sprintf("[%s] Stream interrupted for '%s': %v", cat, modelLabel, streamErr)))
+		}
+		ev.InputTokens, ev.OutputTokens = st.Usage()
+		p.chargeBudget(resolved, ev.InputTokens, ev.OutputTokens)
+		if streamErr == nil && p.annotations.SSEComment {
+			sw.Write(ann.sseComment(ev.InputTokens, ev.OutputTokens))
 		}
-		fmt.Fprintf(w, "HTTP/1.1 200 OK\r\nContent-Type: text/event-stream\r\nContent-Length: %d\r\n\r\n", len(sseBody))
-		w.Write(sseBody)
-		if streamErr == nil {
-			log.Printf("LOCAL_OK %s → %s/%s (streaming, %dms)",
-				modelLabel, resolved.Provider, resolved.Model, time.Since(start).Milliseconds())
+		if clientErr := sw.Close(); clientErr != nil {
+			// The abort closed the provider body, so streamErr is a consequence.
+			ev.Status = "CLIENT"
+			log.Printf("[LOCAL_ERR:CLIENT] %s stream aborted: %v", modelLabel, clientErr)
+			return
+		}
+		if streamErr != nil {
+			ev.Status = cat
+			log.Printf("[LOCAL_ERR:%s] stream translation error for %s: %v", cat, modelLabel, streamErr)
+		} else {
+			ev.Status = "ok"
+			if dedupeKey != "" {
+				p.dedupe.put(dedupeKey, "text/event-stream", captured.Bytes())
+			}
+			if st.StopReason() == "max_tokens" {
+				logLengthStop(modelLabel, resolved, oaiReq)
+			}
+			if validator != nil {
+				validator.Close()
+			}
+			speed := ""
+			if ttft, _, tps, ok := streamSpeed(ev, time.Now()); ok {
+				speed = fmt.Sprintf(", ttft=%dms, %.1f tok/s", ttft.Milliseconds(), tps)
+			}
+			est := ""
+			if st.UsageEstimated() {
+				est = ", output estimated"
+			}
+			log.Printf("LOCAL_OK %s → %s/%s (streaming, %dms%s, in=%d out=%d tokens%s)",
+				modelLabel, resolved.Provider, resolved.Model, time.Since(start).Milliseconds(), speed,
+				ev.InputTokens, ev.OutputTokens, est)
 		}
 	} else {
 		// Non-streaming: translate response
-		respBody, err := io.ReadAll(io.LimitReader(resp.Body, config.MaxBodyBytes+1))
+		trace.begin("translate response", tracing.KindInternal)
+		respBody, err := io.ReadAll(io.LimitReader(resp.Body, p.limits.MaxBodyBytes+1))
 		if err != nil {
 			cat := translate.ClassifyError(err)
+			ev.Status = cat
 			log.Printf("[LOCAL_ERR:%s] response read error for %s: %v", cat, modelLabel, err)
 			errBody := translate.FormatError("api_error",
 				fmt.Sprintf("[%s] Failed to read response from '%s': %v", cat, modelLabel, err))
@@ -431,36 +1095,144 @@ func (p *Proxy) forwardLocal(w io.Writer, modelLabel string, body []byte) {
 			return
 		}
 		respBody, _ = chain.RunResponse(respBody, ctx)
-		aBody, err := translate.ResponseToAnthropic(respBody, modelLabel)
+		aBody, err := translate.ResponseToAnthropicContext(respBody, modelLabel, ctx)
 		if err != nil {
+			ev.Status = "TRANSLATE"
 			log.Printf("[LOCAL_ERR:TRANSLATE] response translation failed for %s: %v", modelLabel, err)
 			errBody := translate.FormatError("api_error",
 				fmt.Sprintf("[TRANSLATE] Response translation failed for '%s': %v", modelLabel, err))
 			sendAnthropicError(w, 502, errBody)
 			return
 		}
-		fmt.Fprintf(w, "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: %d\r\n\r\n", len(aBody))
+		var idHeader string
+		if providerID != "" {
+			idHeader = "Request-Id: " + providerID + "\r\n"
+		}
+		fmt.Fprintf(w, "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: %d\r\n%s\r\n", len(aBody), idHeader)
 		w.Write(aBody)
+		if dedupeKey != "" {
+			p.dedupe.put(dedupeKey, "application/json", aBody)
+		}
 		// Extract token usage from translated response
 		var aResp struct {
-			Usage struct {
+			StopReason string `json:"stop_reason"`
+			Usage      struct {
 				InputTokens  int `json:"input_tokens"`
 				OutputTokens int `json:"output_tokens"`
 			} `json:"usage"`
 		}
 		json.Unmarshal(aBody, &aResp)
+		if aResp.StopReason == "max_tokens" {
+			logLengthStop(modelLabel, resolved, oaiReq)
+		}
+		ev.Status = "ok"
+		ev.InputTokens, ev.OutputTokens = aResp.Usage.InputTokens, aResp.Usage.OutputTokens
+		p.chargeBudget(resolved, ev.InputTokens, ev.OutputTokens)
 		log.Printf("LOCAL_OK %s → %s/%s (%dms, in=%d out=%d tokens)",
 			modelLabel, resolved.Provider, resolved.Model, time.Since(start).Milliseconds(),
 			aResp.Usage.InputTokens, aResp.Usage.OutputTokens)
 	}
 }
 
+// logLengthStop explains a reply that ended at the token limit. Claude Code
+// sees stop_reason max_tokens and asks for a continuation; when the
+// provider's max_tokens capped the request, raising it avoids the round trip.
+func logLengthStop(label string, m config.ResolvedModel, oaiReq map[string]interface{}) {
+	limit := -1
+	for _, k := range []string{"max_completion_tokens", "max_tokens"} {
+		if n, ok := oaiReq[k].(float64); ok {
+			limit = int(n)
+			break
+		}
+	}
+	switch {
+	case m.MaxTokens > 0 && limit == m.MaxTokens:
+		log.Printf("[LOCAL_LENGTH] %s stopped at max_tokens (%d, capped by provider %s's max_tokens)", label, limit, m.Provider)
+	case limit >= 0:
+		log.Printf("[LOCAL_LENGTH] %s stopped at max_tokens (%d)", label, limit)
+	default:
+		log.Printf("[LOCAL_LENGTH] %s stopped at the backend's token limit", label)
+	}
+}
+
+// sendFirstTokenTimeout answers a stream whose provider produced no token
+// within first_token_timeout. Like a capacity refusal it is an overloaded
+// error, which Claude Code retries, and it names the fallback label if any.
+func sendFirstTokenTimeout(w io.Writer, m config.ResolvedModel) {
+	log.Printf("[LOCAL_ERR:FIRST_TOKEN] %s produced no token within %s (%s)", m.Label, m.FirstTokenTimeout, m.Endpoint)
+	msg := fmt.Sprintf("[FIRST_TOKEN] Local model '%s' produced no token within %s", m.Label, m.FirstTokenTimeout)
+	if m.Fallback != "" {
+		msg += fmt.Sprintf(" — try the fallback label '%s'", m.Fallback)
+	}
+	sendAnthropicError(w, 529, translate.FormatError("overloaded_error", msg))
+}
+
 func sendAnthropicError(w io.Writer, httpStatus int, body []byte) {
 	fmt.Fprintf(w, "HTTP/1.1 %d Error\r\nContent-Type: application/json\r\nContent-Length: %d\r\nConnection: close\r\n\r\n",
 		httpStatus, len(body))
 	w.Write(body)
 }
 
+// providerErrorStatus maps a provider's error status to the status and
+// Anthropic error type Claude Code gets, so its retry logic treats the
+// failure as it would the same failure from Anthropic: rate limits and
+// overload are retried with backoff, bad requests and auth failures are
+// not. Statuses without an Anthropic counterpart become 400
+// invalid_request_error (other 4xx) or 502 api_error (5xx).
+func providerErrorStatus(status int) (int, string) {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return status, "authentication_error"
+	case status == http.StatusNotFound:
+		return status, "not_found_error"
+	case status == http.StatusRequestEntityTooLarge:
+		return status, "request_too_large"
+	case status == http.StatusTooManyRequests:
+		return status, "rate_limit_error"
+	case status == http.StatusServiceUnavailable:
+		return 529, "overloaded_error"
+	case status >= 400 && status < 500:
+		return http.StatusBadRequest, "invalid_request_error"
+	}
+	return http.StatusBadGateway, "api_error"
+}
+
+// sendAnthropicErrorRetry is sendAnthropicError with a Retry-After header,
+// passed on from a rate-limited provider.
+func sendAnthropicErrorRetry(w io.Writer, httpStatus int, body []byte, retryAfter string) {
+	fmt.Fprintf(w, "HTTP/1.1 %d Error\r\nContent-Type: application/json\r\nRetry-After: %s\r\nContent-Length: %d\r\nConnection: close\r\n\r\n",
+		httpStatus, retryAfter, len(body))
+	w.Write(body)
+}
+
+// sendRateLimited answers a request over a client's rate limit with an
+// Anthropic rate_limit_error, which Claude Code retries after Retry-After.
+func sendRateLimited(w io.Writer, retryAfter string) {
+	body := translate.FormatError("rate_limit_error", "claude-hybrid: per-client rate limit exceeded")
+	fmt.Fprintf(w, "HTTP/1.1 429 Too Many Requests\r\nContent-Type: application/json\r\nRetry-After: %s\r\nContent-Length: %d\r\nConnection: close\r\n\r\n",
+		retryAfter, len(body))
+	w.Write(body)
+}
+
+// sendUpstreamError answers a request that couldn't be relayed to host.
+// Claude Code can't parse a bare-text error, so API paths (/v1/...) on
+// Anthropic hosts get Anthropic error JSON with the cause: overloaded_error
+// (529) for a timeout, which it retries with backoff, and api_error (502)
+// otherwise. Other requests get a plain 502.
+func (p *Proxy) sendUpstreamError(w io.Writer, host, path string, err error) {
+	if !p.apiHosts.match(host) || !strings.HasPrefix(path, "/v1/") {
+		sendError(w, 502, "Bad Gateway")
+		return
+	}
+	cat := translate.ClassifyError(err)
+	msg := fmt.Sprintf("[UPSTREAM:%s] claude-hybrid could not reach %s: %s", cat, host, sanitizeForLog(err.Error()))
+	if cat == "TIMEOUT" {
+		sendAnthropicError(w, 529, translate.FormatError("overloaded_error", msg))
+		return
+	}
+	sendAnthropicError(w, 502, translate.FormatError("api_error", msg))
+}
+
 func sendError(w io.Writer, code int, status string) {
 	body := status
 	fmt.Fprintf(w, "HTTP/1.1 %d %s\r\nContent-Length: %d\r\nConnection: close\r\n\r\n%s",
@@ -485,6 +1257,24 @@ func isAPIHost(host string) bool {
 		strings.Contains(host, "127.0.0.1")
 }
 
+// setProviderHeaders adds bearer auth and the provider's configured extra
+// headers to an OpenAI-format request. Extra headers win over Authorization so
+// a provider can supply its own auth scheme. It fails only when the key comes
+// from api_key_file or api_key_cmd and can't be read.
+func setProviderHeaders(req *http.Request, m config.ResolvedModel) error {
+	key, err := m.Key()
+	if err != nil {
+		return err
+	}
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	for k, v := range m.Headers {
+		req.Header.Set(k, v)
+	}
+	return nil
+}
+
 var bearerRE = regexp.MustCompile(`(?i)bearer\s+\S+`)
 var apiKeyRE = regexp.MustCompile(`(?i)(sk-|key-)[a-zA-Z0-9]{8,}`)
 
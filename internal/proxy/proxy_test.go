@@ -1,16 +1,22 @@
 package proxy
 
 import (
+	"bufio"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net"
+	"net/http"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/peter-wagstaff/claude-hybrid-router/internal/testutil"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/translate"
 )
 
 func TestCleanRequestForwarded(t *testing.T) {
@@ -310,6 +316,167 @@ func TestUpstreamUnreachable(t *testing.T) {
 	}
 }
 
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestUpstreamFailureAnthropicError(t *testing.T) {
+	tests := []struct {
+		path   string
+		err    error
+		status int
+		want   string
+	}{
+		{"/v1/messages", fmt.Errorf("dial tcp 1.2.3.4:443: connect: connection refused"), 502, `"type":"api_error"`},
+		{"/v1/messages", fmt.Errorf("context deadline exceeded"), 529, `"type":"overloaded_error"`},
+		{"/v1/models", fmt.Errorf("dial tcp: no such host"), 502, "[UPSTREAM:CONNECTION]"},
+		{"/api/event_logging/batch", fmt.Errorf("dial tcp: connection refused"), 502, "Bad Gateway"},
+	}
+	for _, tt := range tests {
+		failing := &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) { return nil, tt.err })}
+		infra := setupInfraWithOptions(t, nil, WithHTTPClient(failing), WithAnthropicHosts([]string{"localhost"}))
+		status, body, _ := proxyRequest(t, infra, "POST", tt.path, []byte(`{"messages":[]}`),
+			map[string]string{"Content-Type": "application/json"})
+		if status != tt.status || !strings.Contains(body, tt.want) {
+			t.Errorf("%s (%v): %d %s, want %d with %s", tt.path, tt.err, status, body, tt.status, tt.want)
+		}
+	}
+}
+
+func TestUpstreamStreamRelayedLive(t *testing.T) {
+	// The upstream holds its second event until the client has read the
+	// first, so a relay that buffers the whole body would never finish.
+	release := make(chan struct{})
+	client := upstreamHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: ping\ndata: {}\n\n")
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-time.After(5 * time.Second):
+		}
+		fmt.Fprint(w, "event: message_stop\ndata: {}\n\n")
+	})
+	infra := setupInfraWithOptions(t, nil, WithHTTPClient(client))
+
+	conn := dialTunnel(t, infra)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(3 * time.Second))
+	fmt.Fprint(conn, "GET /v1/stream HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	if len(resp.TransferEncoding) == 0 || resp.TransferEncoding[0] != "chunked" {
+		t.Fatalf("expected a chunked relay, got %v (Content-Length %d)", resp.TransferEncoding, resp.ContentLength)
+	}
+	first := make([]byte, len("event: ping\ndata: {}\n\n"))
+	if _, err := io.ReadFull(resp.Body, first); err != nil {
+		t.Fatalf("first event not relayed before the stream ended: %v", err)
+	}
+	close(release)
+	rest, err := io.ReadAll(resp.Body)
+	if err != nil || !strings.Contains(string(rest), "message_stop") {
+		t.Fatalf("rest of stream: %q, %v", rest, err)
+	}
+
+	// The chunked body is properly terminated, so the tunnel stays usable.
+	fmt.Fprint(conn, "GET /v1/stream HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n")
+	resp, err = http.ReadResponse(br, nil)
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatalf("second request on the tunnel: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestUpstreamStreamHTTP10Client(t *testing.T) {
+	client := upstreamHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		fmt.Fprint(w, "event: message_stop\ndata: {}\n\n")
+	})
+	infra := setupInfraWithOptions(t, nil, WithHTTPClient(client))
+
+	conn := dialTunnel(t, infra)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(3 * time.Second))
+	fmt.Fprint(conn, "GET /v1/stream HTTP/1.0\r\nHost: localhost\r\n\r\n")
+	data, _ := io.ReadAll(conn)
+	resp := string(data)
+	if strings.Contains(strings.ToLower(resp), "transfer-encoding") {
+		t.Errorf("HTTP/1.0 client sent chunks: %q", resp)
+	}
+	if !strings.Contains(resp, "Connection: close") || !strings.HasSuffix(resp, "event: message_stop\ndata: {}\n\n") {
+		t.Errorf("expected a raw body ended by close: %q", resp)
+	}
+}
+
+func TestUpstreamIdempotentRetry(t *testing.T) {
+	tests := []struct {
+		method string
+		err    error
+		status int
+		calls  int
+	}{
+		{"GET", fmt.Errorf("read tcp: connection reset by peer"), 200, 2},
+		{"HEAD", fmt.Errorf("dial tcp: connection refused"), 200, 2},
+		{"GET", fmt.Errorf("context deadline exceeded"), 502, 1},
+		{"POST", fmt.Errorf("read tcp: connection reset by peer"), 502, 1},
+	}
+	for _, tt := range tests {
+		var calls atomic.Int32
+		flaky := &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+			if calls.Add(1) == 1 {
+				return nil, tt.err
+			}
+			return &http.Response{
+				StatusCode: 200, Status: "200 OK", ContentLength: 2,
+				Header: http.Header{}, Body: io.NopCloser(strings.NewReader("ok")),
+			}, nil
+		})}
+		infra := setupInfraWithOptions(t, nil, WithHTTPClient(flaky))
+		var body []byte
+		if tt.method == "POST" {
+			body = []byte("{}")
+		}
+		status, _, _ := proxyRequest(t, infra, tt.method, "/v1/files/f1", body, nil)
+		if status != tt.status || int(calls.Load()) != tt.calls {
+			t.Errorf("%s after %v: status %d after %d calls, want %d after %d",
+				tt.method, tt.err, status, calls.Load(), tt.status, tt.calls)
+		}
+	}
+}
+
+func TestIPv6ConnectTarget(t *testing.T) {
+	for port, want := range map[string]string{
+		"443":  "https://[::1]/v1/models",
+		"8443": "https://[::1]:8443/v1/models",
+	} {
+		var got string
+		capture := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			got = r.URL.String()
+			return &http.Response{
+				StatusCode: 200, Status: "200 OK", ContentLength: 2,
+				Header: http.Header{}, Body: io.NopCloser(strings.NewReader("ok")),
+			}, nil
+		})}
+		// The intercept entry is written as in a URL, with brackets.
+		infra := setupInfraWithOptions(t, nil, WithHTTPClient(capture), WithIntercept([]string{"[::1]"}))
+		conn := dialTunnelTo(t, infra, "::1", port)
+		conn.SetDeadline(time.Now().Add(3 * time.Second))
+		fmt.Fprint(conn, "GET /v1/models HTTP/1.1\r\nHost: [::1]\r\nConnection: close\r\n\r\n")
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		conn.Close()
+		if err != nil || resp.StatusCode != 200 {
+			t.Fatalf("port %s: %v, %v", port, resp, err)
+		}
+		if got != want {
+			t.Errorf("port %s: upstream URL %q, want %q", port, got, want)
+		}
+	}
+}
+
 func TestNonConnectMethodRejected(t *testing.T) {
 	infra := setupInfra(t, nil)
 
@@ -330,3 +497,190 @@ func TestNonConnectMethodRejected(t *testing.T) {
 		t.Errorf("expected 405 Method Not Allowed, got: %s", resp)
 	}
 }
+
+func TestNonMessagesUploadStreamedThrough(t *testing.T) {
+	infra := setupInfra(t, nil)
+
+	payload := strings.Repeat("x", 256*1024)
+	status, respBody, _ := proxyRequest(t, infra, "PUT", "/v1/files/upload", []byte(payload),
+		map[string]string{"Content-Type": "application/octet-stream"})
+	if status != 200 {
+		t.Fatalf("expected 200, got %d", status)
+	}
+
+	var echo testutil.EchoResponse
+	if err := json.Unmarshal([]byte(respBody), &echo); err != nil {
+		t.Fatalf("parse echo response: %v", err)
+	}
+	if echo.Body != payload {
+		t.Errorf("upload body corrupted: got %d bytes, want %d", len(echo.Body), len(payload))
+	}
+}
+
+func TestExpectContinue(t *testing.T) {
+	// The client holds its body until the interim 100 arrives, as Node does.
+	for _, path := range []string{"/v1/messages", "/v1/files"} {
+		t.Run(path, func(t *testing.T) {
+			infra := setupInfra(t, nil)
+			conn := dialTunnel(t, infra)
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(3 * time.Second))
+
+			body := `{"messages":[{"role":"user","content":"big upload"}]}`
+			fmt.Fprintf(conn, "POST %s HTTP/1.1\r\nHost: localhost\r\nContent-Type: application/json\r\n"+
+				"Content-Length: %d\r\nExpect: 100-continue\r\n\r\n", path, len(body))
+			br := bufio.NewReader(conn)
+			interim, err := http.ReadResponse(br, nil)
+			if err != nil || interim.StatusCode != http.StatusContinue {
+				t.Fatalf("expected 100 Continue before the body, got %v, %v", interim, err)
+			}
+			io.WriteString(conn, body)
+			resp, err := http.ReadResponse(br, nil)
+			if err != nil || resp.StatusCode != 200 {
+				t.Fatalf("final response: %v, %v", resp, err)
+			}
+			var echo testutil.EchoResponse
+			json.NewDecoder(resp.Body).Decode(&echo)
+			if echo.Body != body {
+				t.Errorf("upstream got body %q", echo.Body)
+			}
+			if _, ok := echo.Headers["Expect"]; ok {
+				t.Error("Expect was forwarded upstream")
+			}
+		})
+	}
+}
+
+func TestExpectRefusals(t *testing.T) {
+	tests := []struct {
+		name, headers string
+		status        int
+	}{
+		{"unknown expectation", "Content-Length: 2\r\nExpect: 200-ok\r\n", 417},
+		{"declared too large", "Content-Length: 100\r\nExpect: 100-continue\r\n", 413},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			infra := setupInfraWithOptions(t, nil, WithLimits(config.Limits{MaxBodyBytes: 64}))
+			conn := dialTunnel(t, infra)
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(3 * time.Second))
+
+			fmt.Fprintf(conn, "POST /v1/messages HTTP/1.1\r\nHost: localhost\r\nContent-Type: application/json\r\n%s\r\n", tt.headers)
+			resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+			if err != nil || resp.StatusCode != tt.status {
+				t.Fatalf("got %v, %v; want %d without sending the body", resp, err, tt.status)
+			}
+		})
+	}
+}
+
+func TestRequestTrailersDropped(t *testing.T) {
+	infra := setupInfra(t, nil)
+	conn := dialTunnel(t, infra)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(3 * time.Second))
+	br := bufio.NewReader(conn)
+
+	for _, path := range []string{"/v1/files", "/v1/messages"} {
+		fmt.Fprintf(conn, "POST %s HTTP/1.1\r\nHost: localhost\r\nContent-Type: application/json\r\n"+
+			"Transfer-Encoding: chunked\r\nTrailer: X-Checksum\r\n\r\n"+
+			"5\r\n{\"a\":\r\n2\r\n1}\r\n0\r\nX-Checksum: abc\r\n\r\n", path)
+		resp, err := http.ReadResponse(br, nil)
+		if err != nil || resp.StatusCode != 200 {
+			t.Fatalf("%s: %v, %v", path, resp, err)
+		}
+		var echo testutil.EchoResponse
+		json.NewDecoder(resp.Body).Decode(&echo)
+		resp.Body.Close()
+		if echo.Body != `{"a":1}` {
+			t.Errorf("%s: upstream got body %q", path, echo.Body)
+		}
+		for _, h := range []string{"Trailer", "X-Checksum"} {
+			if _, ok := echo.Headers[h]; ok {
+				t.Errorf("%s: %s reached the upstream", path, h)
+			}
+		}
+	}
+}
+
+func TestMayCarryMarker(t *testing.T) {
+	tests := []struct {
+		method, path, contentType string
+		want                      bool
+	}{
+		{"POST", "/v1/messages", "application/json", true},
+		{"POST", "/v1/messages?beta=true", "", true},
+		{"POST", "/v1/messages/count_tokens", "application/json", true},
+		{"GET", "/v1/messages", "", false},
+		{"POST", "/v1/files", "multipart/form-data; boundary=x", false},
+		{"POST", "/api/event_logging/batch", "application/json", false},
+		{"PUT", "/v1/messages", "application/json", false},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, "https://api.anthropic.com"+tt.path, nil)
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		if got := mayCarryMarker(req); got != tt.want {
+			t.Errorf("mayCarryMarker(%s %s %q) = %v, want %v", tt.method, tt.path, tt.contentType, got, tt.want)
+		}
+	}
+}
+
+func TestUpstreamTransformsApplied(t *testing.T) {
+	chain, err := translate.BuildChain([]string{"upstream:striptools", "upstream:redact"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	infra := setupInfraWithOptions(t, nil, WithUpstreamTransforms(chain, &config.UpstreamConfig{
+		Params: map[string]interface{}{
+			"strip_tools": []interface{}{"WebFetch"},
+			"redact":      []interface{}{`secret-\d+`},
+		},
+	}))
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"tools":    []map[string]string{{"name": "Bash"}, {"name": "WebFetch"}},
+		"messages": []map[string]string{{"role": "user", "content": "token secret-1234"}},
+	})
+	status, respBody, _ := proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+	if status != 200 {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	var echo testutil.EchoResponse
+	if err := json.Unmarshal([]byte(respBody), &echo); err != nil {
+		t.Fatalf("parse echo response: %v", err)
+	}
+	if strings.Contains(echo.Body, "WebFetch") || strings.Contains(echo.Body, "secret-1234") {
+		t.Errorf("upstream body not transformed: %s", echo.Body)
+	}
+	if !strings.Contains(echo.Body, "[REDACTED]") || !strings.Contains(echo.Body, "Bash") {
+		t.Errorf("unexpected upstream body: %s", echo.Body)
+	}
+}
+
+func TestUpstreamTransformErrorFailsClosed(t *testing.T) {
+	chain, _ := translate.BuildChain([]string{"upstream:redact"})
+	infra := setupInfraWithOptions(t, nil, WithUpstreamTransforms(chain, &config.UpstreamConfig{
+		Params: map[string]interface{}{"redact": []interface{}{"("}},
+	}))
+	body := []byte(`{"messages":[{"role":"user","content":"hello"}]}`)
+	status, respBody, _ := proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+	if status != 500 || !strings.Contains(respBody, "upstream transform failed") {
+		t.Errorf("expected 500 transform error, got %d: %s", status, respBody)
+	}
+}
+
+func TestLimitsMaxBodyBytes(t *testing.T) {
+	infra := setupInfraWithOptions(t, nil, WithLimits(config.Limits{MaxBodyBytes: 64}))
+
+	small := []byte(`{"messages":[]}`)
+	if status, _, _ := proxyRequest(t, infra, "POST", "/v1/messages", small, nil); status != 200 {
+		t.Errorf("small body: expected 200, got %d", status)
+	}
+	large := []byte(`{"messages":[{"role":"user","content":"` + strings.Repeat("x", 100) + `"}]}`)
+	if status, _, _ := proxyRequest(t, infra, "POST", "/v1/messages", large, nil); status != 413 {
+		t.Errorf("large body: expected 413, got %d", status)
+	}
+}
@@ -0,0 +1,120 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/translate"
+)
+
+// resumePrompt follows the cut-off text in a continuation request.
+const resumePrompt = "Your previous reply was cut off. Continue it exactly where it stopped, without repeating any of it or commenting on the interruption."
+
+// liveBody is the provider body a stream is reading. A resumed stream swaps
+// in each continuation's body, so a client abort closes whichever is live.
+type liveBody struct {
+	mu     sync.Mutex
+	rc     io.ReadCloser
+	closed bool
+}
+
+func (b *liveBody) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return b.rc.Close()
+}
+
+// swap makes rc the live body, or closes it if the stream was aborted.
+func (b *liveBody) swap(rc io.ReadCloser) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		rc.Close()
+		return false
+	}
+	b.rc = rc
+	return true
+}
+
+// translateResumed translates a provider stream like TranslateStream, but
+// when the stream is cut off after text it re-issues the request with that
+// text as an assistant turn and a prompt to continue, up to
+// m.StreamResume times, and streams the continuation into the same
+// message. The client sees one uninterrupted reply. A stream that can't be
+// resumed ends as TranslateStream would leave it.
+func (p *Proxy) translateResumed(st *translate.StreamTranslator, body *liveBody, w io.Writer,
+	m config.ResolvedModel, oaiReq map[string]interface{}, header http.Header) error {
+	for attempt := 1; ; attempt++ {
+		err := st.TranslatePartial(body.rc, w)
+		if err == nil || !errors.Is(err, translate.ErrStreamCut) {
+			return err
+		}
+		text, ok := st.Resumable()
+		if !ok || attempt > m.StreamResume {
+			st.Finish(w)
+			return err
+		}
+		log.Printf("LOCAL_RESUME %s: %v after %d chars; continuing (%d/%d)", m.Label, err, len(text), attempt, m.StreamResume)
+		resp, rerr := p.resumeStream(m, oaiReq, header, text)
+		if rerr != nil {
+			log.Printf("[LOCAL_RESUME] %s: continuation failed: %v", m.Label, rerr)
+			st.Finish(w)
+			return err
+		}
+		body.rc.Close()
+		if !body.swap(resp.Body) {
+			st.Finish(w)
+			return err
+		}
+		st.Resume()
+	}
+}
+
+// resumeStream sends the continuation request for a stream cut off after
+// text. With no text yet, the original request is sent again.
+func (p *Proxy) resumeStream(m config.ResolvedModel, oaiReq map[string]interface{}, header http.Header, text string) (*http.Response, error) {
+	req := make(map[string]interface{}, len(oaiReq))
+	for k, v := range oaiReq {
+		req[k] = v
+	}
+	if text != "" {
+		msgs, _ := oaiReq["messages"].([]interface{})
+		req["messages"] = append(msgs[:len(msgs):len(msgs)],
+			map[string]interface{}{"role": "assistant", "content": text},
+			map[string]interface{}{"role": "user", "content": resumePrompt})
+	}
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequest("POST", m.Endpoint+"/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header = header.Clone()
+	pool := p.pools.get(m)
+	var resp *http.Response
+	if m.FirstTokenTimeout > 0 {
+		// Like the first stream, a continuation has no total time limit.
+		resp, err = pool.doStream(httpReq)
+	} else {
+		resp, err = pool.do(httpReq)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, sanitizeForLog(clip(string(data), 200)))
+	}
+	return resp, nil
+}
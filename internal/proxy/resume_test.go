@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+// flakyStreamServer cuts its first stream off after "Hello, wor" and
+// answers every later request with "ld!", recording each request body.
+func flakyStreamServer(t *testing.T) (*httptest.Server, func() []map[string]interface{}) {
+	t.Helper()
+	var mu sync.Mutex
+	var bodies []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var req map[string]interface{}
+		json.Unmarshal(data, &req)
+		mu.Lock()
+		bodies = append(bodies, req)
+		n := len(bodies)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/event-stream")
+		if n == 1 {
+			fmt.Fprint(w, "data: {\"id\":\"c1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hello, wor\"}}]}\n\n")
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		fmt.Fprint(w, "data: {\"id\":\"c2\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"ld!\"}}]}\n\n"+
+			"data: {\"id\":\"c2\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n")
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []map[string]interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		return bodies
+	}
+}
+
+func TestLocalRouteStreamResume(t *testing.T) {
+	srv, bodies := flakyStreamServer(t)
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:         "mock",
+			Endpoint:     srv.URL + "/v1",
+			StreamResume: 2,
+			Models:       map[string]config.ModelConfig{"test_model": {Model: "m"}},
+		}},
+	})
+	infra := setupInfra(t, resolver)
+
+	status, respBody, _ := proxyRequest(t, infra, "POST", "/v1/messages", streamBody(), nil)
+	if status != 200 {
+		t.Fatalf("expected 200, got %d: %s", status, respBody)
+	}
+	assertSSELifecycle(t, respBody)
+	if strings.Contains(respBody, "event: error") {
+		t.Errorf("resumed stream should not carry an error: %s", respBody)
+	}
+	if !strings.Contains(respBody, `"text":"Hello, wor"`) || !strings.Contains(respBody, `"text":"ld!"`) {
+		t.Errorf("expected both halves of the reply: %s", respBody)
+	}
+	if n := strings.Count(respBody, "event: message_start"); n != 1 {
+		t.Errorf("expected one message_start, got %d", n)
+	}
+
+	reqs := bodies()
+	if len(reqs) != 2 {
+		t.Fatalf("expected 2 provider requests, got %d", len(reqs))
+	}
+	first := reqs[0]["messages"].([]interface{})
+	msgs := reqs[1]["messages"].([]interface{})
+	if len(msgs) != len(first)+2 {
+		t.Fatalf("expected the original messages plus two, got %v", msgs)
+	}
+	prefix := msgs[len(first)].(map[string]interface{})
+	if prefix["role"] != "assistant" || prefix["content"] != "Hello, wor" {
+		t.Errorf("assistant prefix = %v", prefix)
+	}
+	if prompt := msgs[len(first)+1].(map[string]interface{}); prompt["role"] != "user" || prompt["content"] != resumePrompt {
+		t.Errorf("continuation prompt = %v", prompt)
+	}
+	if got := infra.proxy.Activity().Recent[0].Status; got != "ok" {
+		t.Errorf("activity status = %q, want ok", got)
+	}
+}
+
+func TestLocalRouteStreamResumeOff(t *testing.T) {
+	srv, bodies := flakyStreamServer(t)
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "mock",
+			Endpoint: srv.URL + "/v1",
+			Models:   map[string]config.ModelConfig{"test_model": {Model: "m"}},
+		}},
+	})
+	infra := setupInfra(t, resolver)
+
+	status, respBody, _ := proxyRequest(t, infra, "POST", "/v1/messages", streamBody(), nil)
+	if status != 200 {
+		t.Fatalf("expected 200, got %d: %s", status, respBody)
+	}
+	if !strings.Contains(respBody, "event: error") || !strings.Contains(respBody, "Stream interrupted") {
+		t.Errorf("expected a stream error without stream_resume: %s", respBody)
+	}
+	if n := len(bodies()); n != 1 {
+		t.Errorf("expected 1 provider request, got %d", n)
+	}
+}
@@ -1,40 +1,293 @@
 package proxy
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/defaults"
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/tracing"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/translate"
 )
 
-var routeMarkerRE = regexp.MustCompile(`<!-- @proxy-local-route:af83e9 model=(\S+) -->`)
+// routeMarkerRE matches a routing marker. The hash must end at whitespace
+// or the closing -->, so a marker with a longer hash isn't taken as ours.
+var routeMarkerRE = regexp.MustCompile(regexp.QuoteMeta(defaults.RouteMarker) + `((?:\s.*?)?)-->`)
+
+// localRoute is a parsed routing marker.
+type localRoute struct {
+	Model string // model label
+	Raw   string // "openai" = system/messages already target the backend; skip request translation
+
+	// Overrides for this request, applied on top of the label's config.
+	Temperature *float64
+	TopP        *float64
+	MaxTokens   int      // lowers the label's max_tokens cap (0 = keep it)
+	Transform   []string // "+name" adds, "-name" removes, or bare names replace the chain
+
+	Opts []string // the options after model=, as written, for logs and dedupe keys
+}
+
+// FormatMarker returns the routing marker for label with options such as
+// "temp=0.2", checked as the proxy will check them.
+func FormatMarker(label string, opts ...string) (string, error) {
+	body := strings.Join(append([]string{"model=" + label}, opts...), " ")
+	if _, err := parseRouteMarker(body); err != nil {
+		return "", err
+	}
+	return defaults.RouteMarker + " " + body + " -->", nil
+}
+
+// maxMarkerTokens bounds a marker's max_tokens so a typo can't ask for an
+// unbounded completion.
+const maxMarkerTokens = 1_000_000
+
+// parseRouteMarker parses the options of a routing marker (the text between
+// the marker's tag and its closing "-->"). Parsing is strict: a marker that
+// names no model, repeats or misspells an option, or gives a value out of
+// range is an error rather than being forwarded to Anthropic unnoticed.
+func parseRouteMarker(opts string) (localRoute, error) {
+	var route localRoute
+	seen := make(map[string]bool)
+	for _, opt := range strings.Fields(opts) {
+		k, v, ok := strings.Cut(opt, "=")
+		if !ok || k == "" || v == "" {
+			return localRoute{}, fmt.Errorf("option %q is not key=value", opt)
+		}
+		if k == "temperature" {
+			k = "temp"
+		}
+		if seen[k] {
+			return localRoute{}, fmt.Errorf("option %q is given twice", k)
+		}
+		seen[k] = true
+		if k != "model" {
+			route.Opts = append(route.Opts, opt)
+		}
+		switch k {
+		case "model":
+			route.Model = v
+		case "raw":
+			if v != "openai" {
+				return localRoute{}, fmt.Errorf("raw=%s: only raw=openai is supported", v)
+			}
+			route.Raw = v
+		case "temp":
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f < 0 || f > 2 {
+				return localRoute{}, fmt.Errorf("temp=%s: want a number from 0 to 2", v)
+			}
+			route.Temperature = &f
+		case "top_p":
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f <= 0 || f > 1 {
+				return localRoute{}, fmt.Errorf("top_p=%s: want a number above 0, at most 1", v)
+			}
+			route.TopP = &f
+		case "max_tokens":
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxMarkerTokens {
+				return localRoute{}, fmt.Errorf("max_tokens=%s: want a whole number from 1 to %d", v, maxMarkerTokens)
+			}
+			route.MaxTokens = n
+		case "transform":
+			t, err := parseTransformEdit(v)
+			if err != nil {
+				return localRoute{}, fmt.Errorf("transform=%s: %v", v, err)
+			}
+			route.Transform = t
+		default:
+			return localRoute{}, fmt.Errorf("unknown option %q", k)
+		}
+	}
+	if route.Model == "" {
+		return localRoute{}, fmt.Errorf("no model= label")
+	}
+	return route, nil
+}
+
+// parseTransformEdit checks a marker's comma-separated transform list.
+// Either every entry is "+name" or "-name", editing the label's chain, or
+// none is, replacing it.
+func parseTransformEdit(v string) ([]string, error) {
+	items := strings.Split(v, ",")
+	edits := 0
+	for _, item := range items {
+		name := item
+		if strings.HasPrefix(item, "+") || strings.HasPrefix(item, "-") {
+			name = item[1:]
+			edits++
+		}
+		if !translate.HasTransform(name) {
+			return nil, fmt.Errorf("unknown transform %q", name)
+		}
+	}
+	if edits != 0 && edits != len(items) {
+		return nil, fmt.Errorf("mix of +/- edits and a replacement list")
+	}
+	return items, nil
+}
+
+// apply returns m with the route's overrides. Temperature and top_p aren't
+// part of the model; forwardLocal sets them on the translated request.
+func (r localRoute) apply(m config.ResolvedModel) config.ResolvedModel {
+	if r.MaxTokens > 0 && (m.MaxTokens == 0 || r.MaxTokens < m.MaxTokens) {
+		m.MaxTokens = r.MaxTokens
+	}
+	if len(r.Transform) == 0 {
+		return m
+	}
+	edit := r.Transform[0][0] == '+' || r.Transform[0][0] == '-'
+	if !edit {
+		m.Transform = append([]string(nil), r.Transform...)
+		return m
+	}
+	chain := append([]string(nil), m.Transform...)
+	for _, item := range r.Transform {
+		name := item[1:]
+		if item[0] == '-' {
+			chain = slices.DeleteFunc(chain, func(s string) bool { return s == name })
+		} else if !slices.Contains(chain, name) {
+			chain = append(chain, name)
+		}
+	}
+	m.Transform = chain
+	return m
+}
+
+// setSampling writes the route's sampling overrides into a translated
+// request, replacing the client's values.
+func (r localRoute) setSampling(req map[string]interface{}) {
+	if r.Temperature != nil {
+		req["temperature"] = *r.Temperature
+	}
+	if r.TopP != nil {
+		req["top_p"] = *r.TopP
+	}
+}
+
+// dedupeLabel is the label dedupe keys are built from. Overrides are part of
+// it, since the stripped body no longer shows them.
+func (r localRoute) dedupeLabel() string {
+	if len(r.Opts) == 0 {
+		return r.Model
+	}
+	return r.Model + " " + strings.Join(r.Opts, " ")
+}
+
+// routeRequest is what the tunnel needs from a Messages request body,
+// gathered in a single pass by parseRouteRequest.
+type routeRequest struct {
+	Route     localRoute  // zero when no marker was found
+	MarkerErr error       // set when the system field has a marker that doesn't parse
+	Stream    bool        // top-level "stream" flag
+	Body      []byte      // body with the marker stripped (original body when no marker)
+	Header    http.Header // the client's request headers, filtered per destination before forwarding
+
+	Span      *tracing.Span // the request's span; nil when tracing is off
+	Exchange  *Exchange     // the request as the middleware sees it; nil when there is none
+	QueueWait time.Duration // admission wait of the tunnel, for its first request
+}
+
+// skipValue validates a JSON value without allocating for it. Used for the
+// (often megabyte-sized) messages and tools arrays the router never inspects.
+type skipValue struct{}
 
-// detectLocalRoute checks the system field of a JSON body for a routing marker.
-// Returns the model name and the body with the marker stripped, or "" and the original body.
-func detectLocalRoute(body []byte) (model string, stripped []byte) {
+func (*skipValue) UnmarshalJSON([]byte) error { return nil }
+
+// parseRouteRequest walks the top level of a JSON body once, picking out the
+// system and stream fields. Only the system field is checked for a routing
+// marker; when one is found, just that value is rewritten and spliced back so
+// the rest of the body is forwarded byte-for-byte.
+func parseRouteRequest(body []byte) routeRequest {
+	rr := routeRequest{Body: body}
 	if len(body) == 0 {
-		return "", body
+		return rr
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return rr
 	}
+	var system json.RawMessage
+	var sysStart, sysEnd int64
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return routeRequest{Body: body}
+		}
+		switch tok.(string) {
+		case "system":
+			if err := dec.Decode(&system); err != nil {
+				return routeRequest{Body: body}
+			}
+			sysEnd = dec.InputOffset()
+			sysStart = sysEnd - int64(len(system))
+		case "stream":
+			var stream interface{}
+			if err := dec.Decode(&stream); err != nil {
+				return routeRequest{Body: body}
+			}
+			rr.Stream, _ = stream.(bool)
+		default:
+			if err := dec.Decode(&skipValue{}); err != nil {
+				return routeRequest{Body: body}
+			}
+		}
+	}
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('}') {
+		return routeRequest{Body: body}
+	}
+	if len(bytes.TrimSpace(body[dec.InputOffset():])) != 0 {
+		return routeRequest{Body: body}
+	}
+
+	if len(system) == 0 {
+		return rr
+	}
+	route, cleaned, err := stripSystemMarker(system)
+	if cleaned == nil {
+		return rr
+	}
+	out := make([]byte, 0, len(body)-len(system)+len(cleaned))
+	out = append(out, body[:sysStart]...)
+	out = append(out, cleaned...)
+	out = append(out, body[sysEnd:]...)
+	rr.Route, rr.MarkerErr = route, err
+	rr.Body = out
+	return rr
+}
 
-	var data map[string]interface{}
-	if err := json.Unmarshal(body, &data); err != nil {
-		return "", body
+// stripSystemMarker looks for a routing marker in a raw system value (a string
+// or a list of text blocks). Returns the parsed route and the re-encoded system
+// value with the marker removed, or nil when there is no marker. A marker
+// that doesn't parse is still removed, and its error returned.
+func stripSystemMarker(system json.RawMessage) (localRoute, []byte, error) {
+	if !bytes.Contains(system, []byte("@proxy-local-route:")) {
+		return localRoute{}, nil, nil
 	}
 
-	system, ok := data["system"]
-	if !ok || system == nil {
-		return "", body
+	var parsed interface{}
+	if err := json.Unmarshal(system, &parsed); err != nil {
+		return localRoute{}, nil, nil
 	}
 
-	switch s := system.(type) {
+	switch s := parsed.(type) {
 	case string:
 		m := routeMarkerRE.FindStringSubmatch(s)
 		if m != nil {
-			cleaned := routeMarkerRE.ReplaceAllString(s, "")
 			// Trim leading/trailing whitespace left by marker removal
-			data["system"] = trimSpace(cleaned)
-			out, _ := json.Marshal(data)
-			return m[1], out
+			out, _ := json.Marshal(trimSpace(routeMarkerRE.ReplaceAllString(s, "")))
+			route, err := parseRouteMarker(m[1])
+			return route, out, err
 		}
 	case []interface{}:
 		for _, block := range s {
@@ -49,13 +302,14 @@ func detectLocalRoute(body []byte) (model string, stripped []byte) {
 			m := routeMarkerRE.FindStringSubmatch(text)
 			if m != nil {
 				bm["text"] = trimSpace(routeMarkerRE.ReplaceAllString(text, ""))
-				out, _ := json.Marshal(data)
-				return m[1], out
+				out, _ := json.Marshal(s)
+				route, err := parseRouteMarker(m[1])
+				return route, out, err
 			}
 		}
 	}
 
-	return "", body
+	return localRoute{}, nil, nil
 }
 
 // trimSpace trims whitespace but preserves non-empty content.
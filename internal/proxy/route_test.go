@@ -3,19 +3,23 @@ package proxy
 import (
 	"bytes"
 	"encoding/json"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
 )
 
-func TestDetectLocalRoute_StringSystem(t *testing.T) {
+func TestParseRouteRequest_StringSystem(t *testing.T) {
 	body, _ := json.Marshal(map[string]interface{}{
 		"system":   "<!-- @proxy-local-route:af83e9 model=my_model --> You are helpful",
 		"messages": []map[string]string{{"role": "user", "content": "hi"}},
 	})
 
-	model, stripped := detectLocalRoute(body)
-	if model != "my_model" {
-		t.Fatalf("expected my_model, got %q", model)
+	rr := parseRouteRequest(body)
+	route, stripped := rr.Route, rr.Body
+	if route.Model != "my_model" {
+		t.Fatalf("expected my_model, got %q", route.Model)
 	}
 
 	var data map[string]interface{}
@@ -26,7 +30,7 @@ func TestDetectLocalRoute_StringSystem(t *testing.T) {
 	}
 }
 
-func TestDetectLocalRoute_ListSystem(t *testing.T) {
+func TestParseRouteRequest_ListSystem(t *testing.T) {
 	body, _ := json.Marshal(map[string]interface{}{
 		"system": []map[string]string{
 			{"type": "text", "text": "<!-- @proxy-local-route:af83e9 model=list_model --> Instructions"},
@@ -34,9 +38,10 @@ func TestDetectLocalRoute_ListSystem(t *testing.T) {
 		"messages": []map[string]string{{"role": "user", "content": "hi"}},
 	})
 
-	model, stripped := detectLocalRoute(body)
-	if model != "list_model" {
-		t.Fatalf("expected list_model, got %q", model)
+	rr := parseRouteRequest(body)
+	route, stripped := rr.Route, rr.Body
+	if route.Model != "list_model" {
+		t.Fatalf("expected list_model, got %q", route.Model)
 	}
 
 	var data map[string]interface{}
@@ -48,22 +53,45 @@ func TestDetectLocalRoute_ListSystem(t *testing.T) {
 	}
 }
 
-func TestDetectLocalRoute_NoMarker(t *testing.T) {
+func TestParseRouteRequest_NoMarker(t *testing.T) {
 	body, _ := json.Marshal(map[string]interface{}{
 		"system":   "You are helpful",
 		"messages": []map[string]string{{"role": "user", "content": "hi"}},
 	})
 
-	model, stripped := detectLocalRoute(body)
-	if model != "" {
-		t.Fatalf("expected no model, got %q", model)
+	rr := parseRouteRequest(body)
+	route, stripped := rr.Route, rr.Body
+	if route.Model != "" {
+		t.Fatalf("expected no model, got %q", route.Model)
 	}
 	if !bytes.Equal(stripped, body) {
 		t.Error("body should be unchanged")
 	}
 }
 
-func TestDetectLocalRoute_MarkerInMessages(t *testing.T) {
+func TestParseRouteRequest_OtherHash(t *testing.T) {
+	for _, system := range []string{
+		"<!-- @proxy-local-route:af83e9X model=m --> You are helpful",
+		"<!-- @proxy-local-route:af83e91 model=m --> You are helpful",
+		"<!-- @proxy-local-route:af83e9--model=m --> You are helpful",
+	} {
+		body, _ := json.Marshal(map[string]interface{}{"system": system, "messages": []string{}})
+		rr := parseRouteRequest(body)
+		if rr.Route.Model != "" || rr.MarkerErr != nil {
+			t.Errorf("%q: route %+v, err %v; want no marker", system, rr.Route, rr.MarkerErr)
+		}
+		if !bytes.Equal(rr.Body, body) {
+			t.Errorf("%q: body changed: %s", system, rr.Body)
+		}
+	}
+	// Without options the marker is still ours, and still incomplete.
+	body, _ := json.Marshal(map[string]interface{}{"system": "<!-- @proxy-local-route:af83e9--> hi", "messages": []string{}})
+	if rr := parseRouteRequest(body); rr.MarkerErr == nil || !strings.Contains(rr.MarkerErr.Error(), "no model=") {
+		t.Errorf("bare marker: err = %v, want no model=", rr.MarkerErr)
+	}
+}
+
+func TestParseRouteRequest_MarkerInMessages(t *testing.T) {
 	body, _ := json.Marshal(map[string]interface{}{
 		"messages": []map[string]string{{
 			"role":    "user",
@@ -71,29 +99,32 @@ func TestDetectLocalRoute_MarkerInMessages(t *testing.T) {
 		}},
 	})
 
-	model, stripped := detectLocalRoute(body)
-	if model != "" {
-		t.Fatalf("should not detect marker in messages, got %q", model)
+	rr := parseRouteRequest(body)
+	route, stripped := rr.Route, rr.Body
+	if route.Model != "" {
+		t.Fatalf("should not detect marker in messages, got %q", route.Model)
 	}
 	if !bytes.Equal(stripped, body) {
 		t.Error("body should be unchanged")
 	}
 }
 
-func TestDetectLocalRoute_NonJSON(t *testing.T) {
+func TestParseRouteRequest_NonJSON(t *testing.T) {
 	body := []byte("not json at all")
-	model, stripped := detectLocalRoute(body)
-	if model != "" {
-		t.Fatalf("expected no model, got %q", model)
+	rr := parseRouteRequest(body)
+	route, stripped := rr.Route, rr.Body
+	if route.Model != "" {
+		t.Fatalf("expected no model, got %q", route.Model)
 	}
 	if !bytes.Equal(stripped, body) {
 		t.Error("body should be unchanged")
 	}
 }
 
-func TestDetectLocalRoute_EmptyBody(t *testing.T) {
-	model, stripped := detectLocalRoute(nil)
-	if model != "" || stripped != nil {
+func TestParseRouteRequest_EmptyBody(t *testing.T) {
+	rr := parseRouteRequest(nil)
+	route, stripped := rr.Route, rr.Body
+	if route.Model != "" || stripped != nil {
 		t.Error("expected nil passthrough")
 	}
 }
@@ -151,3 +182,197 @@ func TestSendLocalStub_Streaming(t *testing.T) {
 		t.Error("missing stub text in SSE output")
 	}
 }
+
+func TestParseRouteRequest_Options(t *testing.T) {
+	temp, topP := 0.2, 0.9
+	tests := []struct {
+		system string
+		want   localRoute
+	}{
+		{"<!-- @proxy-local-route:af83e9 model=m raw=openai --> hi", localRoute{Model: "m", Raw: "openai", Opts: []string{"raw=openai"}}},
+		{"<!-- @proxy-local-route:af83e9 model=m -->", localRoute{Model: "m"}},
+		{"<!-- @proxy-local-route:af83e9 model=x temp=0.2 max_tokens=2048 transform=+reasoning -->", localRoute{
+			Model: "x", Temperature: &temp, MaxTokens: 2048, Transform: []string{"+reasoning"},
+			Opts: []string{"temp=0.2", "max_tokens=2048", "transform=+reasoning"},
+		}},
+		{"<!--  @proxy-local-route:af83e9 top_p=0.9  model=x   transform=tooluse,enhancetool-->", localRoute{}},
+		{"<!-- @proxy-local-route:af83e9   top_p=0.9  model=x   transform=tooluse,enhancetool-->", localRoute{
+			Model: "x", TopP: &topP, Transform: []string{"tooluse", "enhancetool"},
+			Opts: []string{"top_p=0.9", "transform=tooluse,enhancetool"},
+		}},
+	}
+	for _, tt := range tests {
+		body, _ := json.Marshal(map[string]interface{}{"system": tt.system})
+		rr := parseRouteRequest(body)
+		if rr.MarkerErr != nil {
+			t.Errorf("parseRouteRequest(%q): %v", tt.system, rr.MarkerErr)
+			continue
+		}
+		if !reflect.DeepEqual(rr.Route, tt.want) {
+			t.Errorf("parseRouteRequest(%q) = %+v, want %+v", tt.system, rr.Route, tt.want)
+		}
+		if tt.want.Model != "" && strings.Contains(string(rr.Body), "proxy-local-route") {
+			t.Errorf("marker with options not stripped: %s", rr.Body)
+		}
+	}
+}
+
+func TestParseRouteRequest_BadMarker(t *testing.T) {
+	for _, tt := range []struct{ opts, err string }{
+		{"", "no model="},
+		{"temp=0.2", "no model="},
+		{"model=", "not key=value"},
+		{"model=m model=n", "given twice"},
+		{"model=m temp=0.2 temperature=0.3", "given twice"},
+		{"model=m future_opt=1", `unknown option "future_opt"`},
+		{"model=m temp", "not key=value"},
+		{"model=m temp=hot", "from 0 to 2"},
+		{"model=m temp=2.5", "from 0 to 2"},
+		{"model=m top_p=0", "above 0"},
+		{"model=m max_tokens=0", "whole number"},
+		{"model=m max_tokens=1.5", "whole number"},
+		{"model=m raw=anthropic", "only raw=openai"},
+		{"model=m transform=+nope", `unknown transform "nope"`},
+		{"model=m transform=+reasoning,tooluse", "mix of"},
+		{"xmodel=m", `unknown option "xmodel"`},
+	} {
+		system := "<!-- @proxy-local-route:af83e9 " + tt.opts + " --> You are a coder"
+		body, _ := json.Marshal(map[string]interface{}{"system": system, "messages": []string{}})
+		rr := parseRouteRequest(body)
+		if rr.MarkerErr == nil || !strings.Contains(rr.MarkerErr.Error(), tt.err) {
+			t.Errorf("marker %q: err = %v, want %q", tt.opts, rr.MarkerErr, tt.err)
+		}
+		if rr.Route.Model != "" {
+			t.Errorf("marker %q: route %+v, want none", tt.opts, rr.Route)
+		}
+		if strings.Contains(string(rr.Body), "proxy-local-route") {
+			t.Errorf("marker %q not stripped: %s", tt.opts, rr.Body)
+		}
+	}
+}
+
+func TestLocalRouteApply(t *testing.T) {
+	m := config.ResolvedModel{Label: "x", MaxTokens: 4096, Transform: []string{"tooluse", "enhancetool"}}
+	for _, tt := range []struct {
+		route     localRoute
+		maxTokens int
+		transform []string
+	}{
+		{localRoute{}, 4096, []string{"tooluse", "enhancetool"}},
+		{localRoute{MaxTokens: 2048}, 2048, []string{"tooluse", "enhancetool"}},
+		{localRoute{MaxTokens: 8192}, 4096, []string{"tooluse", "enhancetool"}},
+		{localRoute{Transform: []string{"+reasoning", "-tooluse", "+enhancetool"}}, 4096, []string{"enhancetool", "reasoning"}},
+		{localRoute{Transform: []string{"deepseek"}}, 4096, []string{"deepseek"}},
+	} {
+		got := tt.route.apply(m)
+		if got.MaxTokens != tt.maxTokens || !reflect.DeepEqual(got.Transform, tt.transform) {
+			t.Errorf("%+v.apply: max_tokens %d transform %v, want %d %v",
+				tt.route, got.MaxTokens, got.Transform, tt.maxTokens, tt.transform)
+		}
+	}
+	if len(m.Transform) != 2 || m.Transform[0] != "tooluse" {
+		t.Errorf("apply changed the resolved model's chain: %v", m.Transform)
+	}
+}
+
+func TestParseRouteRequest_StreamFlagAndSplice(t *testing.T) {
+	body := []byte(`{"model":"claude","messages":[{"role":"user","content":"a <b> & c"}],` +
+		`"system":"<!-- @proxy-local-route:af83e9 model=m --> sys","stream":true,"max_tokens":10}`)
+
+	rr := parseRouteRequest(body)
+	if rr.Route.Model != "m" || !rr.Stream {
+		t.Fatalf("got route %+v stream %v", rr.Route, rr.Stream)
+	}
+	want := `{"model":"claude","messages":[{"role":"user","content":"a <b> & c"}],` +
+		`"system":"sys","stream":true,"max_tokens":10}`
+	if string(rr.Body) != want {
+		t.Errorf("only the system value should change:\n got %s\nwant %s", rr.Body, want)
+	}
+}
+
+func TestParseRouteRequest_Malformed(t *testing.T) {
+	for _, body := range []string{
+		`{"system":"<!-- @proxy-local-route:af83e9 model=m -->"`,
+		`{"system":"<!-- @proxy-local-route:af83e9 model=m -->"} trailing`,
+		`["system"]`,
+	} {
+		rr := parseRouteRequest([]byte(body))
+		if rr.Route.Model != "" || string(rr.Body) != body {
+			t.Errorf("malformed body %q should pass through, got %+v", body, rr.Route)
+		}
+	}
+}
+
+// largeHistoryBody builds a Messages request with roughly n bytes of history.
+func largeHistoryBody(n int) []byte {
+	var msgs []map[string]interface{}
+	chunk := strings.Repeat("func main() { fmt.Println(\"hello\") }\n", 50)
+	for size := 0; size < n; size += len(chunk) {
+		msgs = append(msgs,
+			map[string]interface{}{"role": "user", "content": []map[string]string{{"type": "text", "text": chunk}}},
+			map[string]interface{}{"role": "assistant", "content": "ok"})
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"system":     []map[string]string{{"type": "text", "text": "<!-- @proxy-local-route:af83e9 model=fast --> You are a coder"}},
+		"messages":   msgs,
+		"max_tokens": 8192,
+		"stream":     true,
+	})
+	return body
+}
+
+// mapDecodeRoute is the previous approach, kept as the benchmark baseline:
+// decode the whole body into a map, re-encode it, then decode again for stream.
+func mapDecodeRoute(body []byte) ([]byte, bool) {
+	var data map[string]interface{}
+	json.Unmarshal(body, &data)
+	blocks := data["system"].([]interface{})
+	bm := blocks[0].(map[string]interface{})
+	bm["text"] = routeMarkerRE.ReplaceAllString(bm["text"].(string), "")
+	out, _ := json.Marshal(data)
+	var meta struct {
+		Stream bool `json:"stream"`
+	}
+	json.Unmarshal(body, &meta)
+	var again map[string]interface{}
+	json.Unmarshal(out, &again)
+	return out, meta.Stream
+}
+
+func BenchmarkRouteParse(b *testing.B) {
+	body := largeHistoryBody(1 << 20)
+	b.Run("single_pass", func(b *testing.B) {
+		b.SetBytes(int64(len(body)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if rr := parseRouteRequest(body); rr.Route.Model != "fast" {
+				b.Fatal("marker not found")
+			}
+		}
+	})
+	b.Run("map_decode", func(b *testing.B) {
+		b.SetBytes(int64(len(body)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			mapDecodeRoute(body)
+		}
+	})
+}
+
+func TestFormatMarker(t *testing.T) {
+	m, err := FormatMarker("fast_coder", "temp=0.2", "transform=+reasoning")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := json.Marshal(map[string]interface{}{"system": m + " You are a coder"})
+	rr := parseRouteRequest(body)
+	if rr.Route.Model != "fast_coder" || *rr.Route.Temperature != 0.2 || rr.MarkerErr != nil {
+		t.Errorf("FormatMarker output %q parsed as %+v, %v", m, rr.Route, rr.MarkerErr)
+	}
+	for _, bad := range [][]string{{"a b"}, {"x", "temp=9"}, {""}} {
+		if m, err := FormatMarker(bad[0], bad[1:]...); err == nil {
+			t.Errorf("FormatMarker(%q) = %q, want an error", bad, m)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// errClientTooSlow aborts a stream whose client stopped reading.
+var errClientTooSlow = errors.New("client not reading: write deadline exceeded")
+
+// deadlineWriter is implemented by net.Conn (and tls.Conn).
+type deadlineWriter interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// sseStreamWriter relays translated SSE to the client as a chunked HTTP
+// response while the translator keeps reading from the provider. Output
+// waits in a buffer of at most maxBuffered bytes; when it is full, Write
+// blocks until the client drains it, pushing backpressure onto the provider
+// connection instead of growing memory. Every write to the client carries a
+// deadline, and a client that misses it aborts the stream: the buffered
+// events are dropped, onAbort runs (closing the provider response), and all
+// further writes fail.
+//
+// Response headers are sent with the first batch, so a stream that fails
+// before producing output can still be answered with a plain error.
+type sseStreamWriter struct {
+	dst          io.Writer
+	writeTimeout time.Duration
+	maxBuffered  int
+	onAbort      func()
+	stats        *clientStats
+
+	mu      sync.Mutex
+	buf     []byte
+	spare   []byte
+	err     error
+	closing bool
+	wrote   bool   // Write was called at least once
+	header  string // extra response header lines, each ending in CRLF
+
+	wake  chan struct{} // data or close pending for the sender
+	space chan struct{} // the sender drained the buffer
+	done  chan struct{} // the sender exited
+}
+
+func newSSEStreamWriter(dst io.Writer, maxBuffered int, writeTimeout time.Duration, stats *clientStats, onAbort func()) *sseStreamWriter {
+	sw := &sseStreamWriter{
+		dst:          dst,
+		writeTimeout: writeTimeout,
+		maxBuffered:  maxBuffered,
+		onAbort:      onAbort,
+		stats:        stats,
+		wake:         make(chan struct{}, 1),
+		space:        make(chan struct{}, 1),
+		done:         make(chan struct{}),
+	}
+	stats.streams.Add(1)
+	go sw.send()
+	return sw
+}
+
+// Write queues p for the client. It blocks while the buffer is full and
+// fails once the stream has been aborted.
+func (sw *sseStreamWriter) Write(p []byte) (int, error) {
+	stalled := false
+	for {
+		sw.mu.Lock()
+		if sw.err != nil {
+			err := sw.err
+			sw.mu.Unlock()
+			return 0, err
+		}
+		sw.wrote = true
+		// A single write larger than the buffer is accepted into an empty one.
+		if len(sw.buf) == 0 || len(sw.buf)+len(p) <= sw.maxBuffered {
+			sw.buf = append(sw.buf, p...)
+			sw.stats.observeBuffered(len(sw.buf))
+			sw.mu.Unlock()
+			signal(sw.wake)
+			return len(p), nil
+		}
+		sw.mu.Unlock()
+
+		if !stalled {
+			stalled = true
+			sw.stats.stalls.Add(1)
+		}
+		start := time.Now()
+		select {
+		case <-sw.space:
+		case <-sw.done:
+		}
+		sw.stats.stallNanos.Add(int64(time.Since(start)))
+	}
+}
+
+// AddHeader adds a response header; it has no effect once output has been
+// sent.
+func (sw *sseStreamWriter) AddHeader(name, value string) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	sw.header += name + ": " + value + "\r\n"
+}
+
+// Started reports whether any output was queued for the client.
+func (sw *sseStreamWriter) Started() bool {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.wrote
+}
+
+// Close flushes buffered output, ends the chunked body and waits for the
+// sender. It returns errClientTooSlow or the write error if the stream was
+// aborted.
+func (sw *sseStreamWriter) Close() error {
+	sw.mu.Lock()
+	sw.closing = true
+	sw.mu.Unlock()
+	signal(sw.wake)
+	<-sw.done
+
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.err
+}
+
+// send drains the buffer to the client, one HTTP chunk per batch.
+func (sw *sseStreamWriter) send() {
+	defer close(sw.done)
+	headerSent := false
+	for {
+		<-sw.wake
+		for {
+			sw.mu.Lock()
+			batch := sw.buf
+			sw.buf, sw.spare = sw.spare[:0], nil
+			closing := sw.closing
+			header := sw.header
+			sw.mu.Unlock()
+			signal(sw.space)
+
+			if len(batch) > 0 {
+				if !headerSent {
+					headerSent = true
+					if err := sw.write([]byte("HTTP/1.1 200 OK\r\nContent-Type: text/event-stream\r\n" +
+						"Cache-Control: no-cache\r\nTransfer-Encoding: chunked\r\n" + header + "\r\n")); err != nil {
+						sw.abort(err)
+						return
+					}
+				}
+				chunk := fmt.Appendf(nil, "%x\r\n", len(batch))
+				chunk = append(chunk, batch...)
+				chunk = append(chunk, "\r\n"...)
+				if err := sw.write(chunk); err != nil {
+					sw.abort(err)
+					return
+				}
+				sw.stats.bytes.Add(int64(len(batch)))
+			}
+
+			sw.mu.Lock()
+			sw.spare = batch[:0]
+			more := len(sw.buf) > 0
+			sw.mu.Unlock()
+			if more {
+				continue
+			}
+			if closing {
+				if headerSent {
+					if err := sw.write([]byte("0\r\n\r\n")); err != nil {
+						sw.abort(err)
+					}
+				}
+				sw.clearDeadline()
+				return
+			}
+			break
+		}
+	}
+}
+
+// write sends b to the client under the write deadline.
+func (sw *sseStreamWriter) write(b []byte) error {
+	if dw, ok := sw.dst.(deadlineWriter); ok {
+		dw.SetWriteDeadline(time.Now().Add(sw.writeTimeout))
+	}
+	_, err := sw.dst.Write(b)
+	if isTimeout(err) {
+		return errClientTooSlow
+	}
+	return err
+}
+
+func (sw *sseStreamWriter) clearDeadline() {
+	if dw, ok := sw.dst.(deadlineWriter); ok {
+		dw.SetWriteDeadline(time.Time{})
+	}
+}
+
+func (sw *sseStreamWriter) abort(err error) {
+	sw.mu.Lock()
+	sw.err = err
+	sw.buf = nil
+	sw.mu.Unlock()
+	if errors.Is(err, errClientTooSlow) {
+		sw.stats.slowAborts.Add(1)
+	} else {
+		sw.stats.writeErrors.Add(1)
+	}
+	if sw.onAbort != nil {
+		sw.onAbort()
+	}
+}
+
+func isTimeout(err error) bool {
+	var te interface{ Timeout() bool }
+	return errors.As(err, &te) && te.Timeout()
+}
+
+// signal does a non-blocking send on a 1-buffered notification channel.
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// clientStats counts how translated streams fared against their clients.
+type clientStats struct {
+	streams     atomic.Int64
+	bytes       atomic.Int64
+	stalls      atomic.Int64 // writes that found the buffer full
+	stallNanos  atomic.Int64 // time the translator spent blocked on a full buffer
+	slowAborts  atomic.Int64 // streams aborted because the client missed a write deadline
+	writeErrors atomic.Int64 // streams aborted by other client write errors
+	maxBuffered atomic.Int64 // high-water mark of buffered bytes
+}
+
+func (s *clientStats) observeBuffered(n int) {
+	for {
+		cur := s.maxBuffered.Load()
+		if int64(n) <= cur || s.maxBuffered.CompareAndSwap(cur, int64(n)) {
+			return
+		}
+	}
+}
+
+// ClientStreamStats reports how clients kept up with translated streams.
+type ClientStreamStats struct {
+	Streams      int64 `json:"streams"`
+	BytesWritten int64 `json:"bytes_written"`
+	Stalls       int64 `json:"stalls"`
+	StallMs      int64 `json:"stall_ms"`
+	SlowAborts   int64 `json:"slow_aborts"`
+	WriteErrors  int64 `json:"write_errors"`
+	MaxBuffered  int64 `json:"max_buffered_bytes"`
+}
+
+func (s *clientStats) snapshot() ClientStreamStats {
+	return ClientStreamStats{
+		Streams:      s.streams.Load(),
+		BytesWritten: s.bytes.Load(),
+		Stalls:       s.stalls.Load(),
+		StallMs:      time.Duration(s.stallNanos.Load()).Milliseconds(),
+		SlowAborts:   s.slowAborts.Load(),
+		WriteErrors:  s.writeErrors.Load(),
+		MaxBuffered:  s.maxBuffered.Load(),
+	}
+}
@@ -0,0 +1,122 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSSEStreamWriterChunked(t *testing.T) {
+	var out bytes.Buffer
+	var stats clientStats
+	sw := newSSEStreamWriter(&out, 1024, time.Second, &stats, nil)
+	events := []string{"event: a\ndata: {}\n\n", "event: b\ndata: {}\n\n"}
+	for _, e := range events {
+		sw.Write([]byte(e))
+	}
+	if err := sw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(&out), nil)
+	if err != nil {
+		t.Fatalf("parse response: %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read chunked body: %v", err)
+	}
+	if resp.Header.Get("Content-Type") != "text/event-stream" || string(body) != strings.Join(events, "") {
+		t.Errorf("got %q %q", resp.Header.Get("Content-Type"), body)
+	}
+	if s := stats.snapshot(); s.Streams != 1 || s.BytesWritten != int64(len(body)) {
+		t.Errorf("stats: %+v", s)
+	}
+}
+
+func TestSSEStreamWriterNoOutputSendsNothing(t *testing.T) {
+	var out bytes.Buffer
+	sw := newSSEStreamWriter(&out, 1024, time.Second, &clientStats{}, nil)
+	if err := sw.Close(); err != nil || out.Len() != 0 || sw.Started() {
+		t.Errorf("expected no output, got %q (err %v)", out.String(), err)
+	}
+}
+
+func TestSSEStreamWriterAbortsStalledClient(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	var stats clientStats
+	var aborted atomic.Bool
+	sw := newSSEStreamWriter(server, 1024, 50*time.Millisecond, &stats, func() { aborted.Store(true) })
+
+	// The client never reads: once the buffer fills, Write blocks until the
+	// sender's write deadline fires, then fails.
+	event := bytes.Repeat([]byte("x"), 400)
+	var err error
+	deadline := time.Now().Add(5 * time.Second)
+	for err == nil && time.Now().Before(deadline) {
+		_, err = sw.Write(event)
+	}
+	if !errors.Is(err, errClientTooSlow) {
+		t.Fatalf("expected errClientTooSlow, got %v", err)
+	}
+	if closeErr := sw.Close(); !errors.Is(closeErr, errClientTooSlow) {
+		t.Errorf("Close: %v", closeErr)
+	}
+	if !aborted.Load() {
+		t.Error("onAbort not called")
+	}
+	s := stats.snapshot()
+	if s.SlowAborts != 1 || s.Stalls == 0 || s.MaxBuffered > 1024 {
+		t.Errorf("stats: %+v", s)
+	}
+}
+
+func TestSSEStreamWriterBackpressureBounded(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+
+	// A slow but live client: reads in small bites with pauses.
+	received := make(chan int)
+	go func() {
+		n := 0
+		buf := make([]byte, 256)
+		for {
+			time.Sleep(time.Millisecond)
+			k, err := client.Read(buf)
+			n += k
+			if err != nil {
+				received <- n
+				return
+			}
+		}
+	}()
+
+	var stats clientStats
+	sw := newSSEStreamWriter(server, 2048, time.Second, &stats, nil)
+	event := bytes.Repeat([]byte("y"), 500)
+	for range 40 {
+		if _, err := sw.Write(event); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := sw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	server.Close()
+	if n := <-received; n < 40*500 {
+		t.Errorf("client received %d bytes, want at least %d", n, 40*500)
+	}
+	if s := stats.snapshot(); s.MaxBuffered > 2048 || s.Stalls == 0 || s.SlowAborts != 0 {
+		t.Errorf("stats: %+v", s)
+	}
+}
@@ -0,0 +1,129 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+const (
+	defaultTelemetryInterval = 10 * time.Second
+	telemetryFetchTimeout    = 2 * time.Second
+)
+
+// ollamaPS is the subset of Ollama's /api/ps response used for capacity checks.
+type ollamaPS struct {
+	Models []struct {
+		Name     string `json:"name"`
+		Model    string `json:"model"`
+		SizeVRAM int64  `json:"size_vram"`
+	} `json:"models"`
+}
+
+type hostSnapshot struct {
+	fetched time.Time
+	ps      ollamaPS
+}
+
+// hostMonitor tracks per-provider load: in-flight request counts and cached
+// /api/ps snapshots. Snapshots are refreshed lazily when stale, so no
+// background goroutine is needed.
+type hostMonitor struct {
+	client *http.Client
+
+	mu        sync.Mutex
+	inFlight  map[string]int
+	snapshots map[string]hostSnapshot
+}
+
+func newHostMonitor() *hostMonitor {
+	return &hostMonitor{
+		client:    &http.Client{Timeout: telemetryFetchTimeout},
+		inFlight:  make(map[string]int),
+		snapshots: make(map[string]hostSnapshot),
+	}
+}
+
+// acquire checks whether m's backend has capacity and, if so, counts the
+// request as in flight. The returned release func must be called when the
+// request completes. A non-nil error describes why the backend is refused.
+func (h *hostMonitor) acquire(m config.ResolvedModel) (release func(), err error) {
+	if err := h.checkVRAM(m); err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if m.MaxConcurrent > 0 && h.inFlight[m.Provider] >= m.MaxConcurrent {
+		return nil, fmt.Errorf("provider %q is saturated (%d/%d requests in flight)",
+			m.Provider, h.inFlight[m.Provider], m.MaxConcurrent)
+	}
+	h.inFlight[m.Provider]++
+	return func() {
+		h.mu.Lock()
+		h.inFlight[m.Provider]--
+		h.mu.Unlock()
+	}, nil
+}
+
+// checkVRAM refuses the route when loading m would exceed the host's VRAM
+// budget. Models that are already loaded always pass. Telemetry failures
+// fail open: an unreachable /api/ps never blocks routing on its own.
+func (h *hostMonitor) checkVRAM(m config.ResolvedModel) error {
+	t := m.Telemetry
+	if t == nil || !t.OllamaPS || t.VRAMBudgetMB <= 0 {
+		return nil
+	}
+	ps, ok := h.snapshot(m)
+	if !ok {
+		return nil
+	}
+
+	var usedBytes int64
+	for _, loaded := range ps.Models {
+		if loaded.Name == m.Model || loaded.Model == m.Model {
+			return nil
+		}
+		usedBytes += loaded.SizeVRAM
+	}
+	usedMB := int(usedBytes >> 20)
+	if usedMB+m.VRAMMB > t.VRAMBudgetMB {
+		return fmt.Errorf("host for provider %q is out of VRAM (%d MB loaded, %d MB needed, %d MB budget)",
+			m.Provider, usedMB, m.VRAMMB, t.VRAMBudgetMB)
+	}
+	return nil
+}
+
+// snapshot returns a fresh-enough /api/ps result for m's provider.
+func (h *hostMonitor) snapshot(m config.ResolvedModel) (ollamaPS, bool) {
+	interval := m.Telemetry.Interval
+	if interval <= 0 {
+		interval = defaultTelemetryInterval
+	}
+
+	h.mu.Lock()
+	snap, ok := h.snapshots[m.Provider]
+	h.mu.Unlock()
+	if ok && time.Since(snap.fetched) < interval {
+		return snap.ps, true
+	}
+
+	resp, err := h.client.Get(ollamaBaseURL(m.Endpoint) + "/api/ps")
+	if err != nil {
+		return ollamaPS{}, false
+	}
+	defer resp.Body.Close()
+	var ps ollamaPS
+	if resp.StatusCode != 200 || json.NewDecoder(resp.Body).Decode(&ps) != nil {
+		return ollamaPS{}, false
+	}
+
+	h.mu.Lock()
+	h.snapshots[m.Provider] = hostSnapshot{fetched: time.Now(), ps: ps}
+	h.mu.Unlock()
+	return ps, true
+}
@@ -0,0 +1,142 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+// mockOllamaPS serves a fixed /api/ps response reporting one loaded model.
+func mockOllamaPS(t *testing.T, loaded string, sizeVRAM int64) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/ps" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"models": []map[string]interface{}{{"name": loaded, "model": loaded, "size_vram": sizeVRAM}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHostMonitorVRAMBudget(t *testing.T) {
+	ollama := mockOllamaPS(t, "deepseek-r1:14b", 20<<30)
+	base := config.ResolvedModel{
+		Endpoint: ollama.URL + "/v1",
+		Provider: "ollama",
+		Telemetry: &config.TelemetryConfig{
+			OllamaPS:     true,
+			VRAMBudgetMB: 24 << 10,
+		},
+	}
+
+	tests := []struct {
+		name    string
+		model   string
+		vramMB  int
+		wantErr bool
+	}{
+		{"target already loaded", "deepseek-r1:14b", 8 << 10, false},
+		{"fits alongside loaded model", "qwen3:4b", 3 << 10, false},
+		{"exceeds budget", "qwen3:32b", 20 << 10, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHostMonitor()
+			m := base
+			m.Model = tt.model
+			m.VRAMMB = tt.vramMB
+			release, err := h.acquire(m)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("acquire err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if release != nil {
+				release()
+			}
+		})
+	}
+}
+
+func TestHostMonitorTelemetryFailsOpen(t *testing.T) {
+	h := newHostMonitor()
+	m := config.ResolvedModel{
+		Endpoint:  "http://127.0.0.1:1/v1",
+		Provider:  "ollama",
+		Model:     "qwen3:32b",
+		VRAMMB:    1 << 20,
+		Telemetry: &config.TelemetryConfig{OllamaPS: true, VRAMBudgetMB: 1},
+	}
+	release, err := h.acquire(m)
+	if err != nil {
+		t.Fatalf("unreachable /api/ps should not block routing: %v", err)
+	}
+	release()
+}
+
+func TestHostMonitorMaxConcurrent(t *testing.T) {
+	h := newHostMonitor()
+	m := config.ResolvedModel{Provider: "vllm", MaxConcurrent: 2}
+
+	r1, err := h.acquire(m)
+	if err != nil {
+		t.Fatalf("first acquire: %v", err)
+	}
+	r2, err := h.acquire(m)
+	if err != nil {
+		t.Fatalf("second acquire: %v", err)
+	}
+	if _, err := h.acquire(m); err == nil {
+		t.Fatal("expected third acquire to be refused")
+	}
+	r1()
+	r3, err := h.acquire(m)
+	if err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+	r2()
+	r3()
+}
+
+func TestLocalRouteSaturatedSuggestsFallback(t *testing.T) {
+	ollama := mockOllamaPS(t, "deepseek-r1:14b", 20<<30)
+
+	resolver, err := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:      "ollama",
+			Endpoint:  ollama.URL + "/v1",
+			Telemetry: &config.TelemetryConfig{OllamaPS: true, VRAMBudgetMB: 24 << 10},
+			Models: map[string]config.ModelConfig{
+				"coder":     {Model: "qwen3:32b", VRAMMB: 20 << 10, Fallback: "reasoning"},
+				"reasoning": {Model: "deepseek-r1:14b"},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("resolver: %v", err)
+	}
+	infra := setupInfra(t, resolver)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"system":     "<!-- @proxy-local-route:af83e9 model=coder -->",
+		"messages":   []map[string]string{{"role": "user", "content": "hello"}},
+		"max_tokens": 100,
+	})
+	status, respBody, _ := proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+	if status != 529 {
+		t.Fatalf("expected 529, got %d: %s", status, respBody)
+	}
+	if !strings.Contains(respBody, "overloaded_error") {
+		t.Errorf("expected overloaded_error, got %s", respBody)
+	}
+	if !strings.Contains(respBody, "'reasoning'") {
+		t.Errorf("expected fallback label suggestion, got %s", respBody)
+	}
+}
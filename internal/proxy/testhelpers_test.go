@@ -1,30 +1,43 @@
 package proxy
 
 import (
+	"bufio"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
-	"github.com/peter-wagstaff/claude-hybrid-router/internal/config"
 	"github.com/peter-wagstaff/claude-hybrid-router/internal/mitm"
 	"github.com/peter-wagstaff/claude-hybrid-router/internal/testutil"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
 )
 
 type testInfra struct {
 	proxyAddr    string
 	upstreamPort int
 	mitmCACert   []byte
+	proxy        *Proxy
 }
 
 // setupInfra creates a full proxy test stack: upstream echo server, MITM cert cache,
 // and proxy. When resolver is non-nil, the proxy is configured with WithModelResolver.
 func setupInfra(t *testing.T, resolver *config.ModelResolver) *testInfra {
 	t.Helper()
+	return setupInfraWithOptions(t, resolver)
+}
+
+// setupInfraWithOptions is setupInfra with extra proxy options appended.
+func setupInfraWithOptions(t *testing.T, resolver *config.ModelResolver, extra ...Option) *testInfra {
+	t.Helper()
 
 	// Generate CAs
 	upstreamCACert, upstreamCAKey, err := testutil.GenerateTestCA()
@@ -71,10 +84,12 @@ func setupInfra(t *testing.T, resolver *config.ModelResolver) *testInfra {
 	}
 
 	// Build proxy options
-	opts := []Option{WithHTTPClient(httpClient)}
+	// The mock upstream is on localhost, which the default list would bypass.
+	opts := []Option{WithHTTPClient(httpClient), WithIntercept([]string{"localhost"})}
 	if resolver != nil {
 		opts = append(opts, WithModelResolver(resolver))
 	}
+	opts = append(opts, extra...)
 
 	// Start proxy
 	proxy := New(certCache, opts...)
@@ -90,30 +105,36 @@ func setupInfra(t *testing.T, resolver *config.ModelResolver) *testInfra {
 		proxyAddr:    ln.Addr().String(),
 		upstreamPort: echoPort,
 		mitmCACert:   mitmCACert,
+		proxy:        proxy,
 	}
 }
 
-// proxyRequest sends a request through the CONNECT proxy and returns status, body, and content-type.
-func proxyRequest(t *testing.T, infra *testInfra, method, path string, body []byte, headers map[string]string) (int, string, string) {
+// dialTunnel opens a CONNECT tunnel to the mock upstream through the proxy
+// and completes the TLS handshake with the MITM certificate.
+func dialTunnel(t *testing.T, infra *testInfra) *tls.Conn {
 	t.Helper()
+	return dialTunnelTo(t, infra, "localhost", strconv.Itoa(infra.upstreamPort))
+}
 
-	targetHost := "localhost"
-	targetPort := infra.upstreamPort
+// dialTunnelTo is dialTunnel for any CONNECT target, which need not be
+// reachable: only the proxy's side of the TLS handshake is checked.
+func dialTunnelTo(t *testing.T, infra *testInfra, targetHost, targetPort string) *tls.Conn {
+	t.Helper()
 
 	conn, err := net.Dial("tcp", infra.proxyAddr)
 	if err != nil {
 		t.Fatalf("connect to proxy: %v", err)
 	}
-	defer conn.Close()
 
 	// Send CONNECT
-	fmt.Fprintf(conn, "CONNECT %s:%d HTTP/1.1\r\nHost: %s\r\n\r\n",
-		targetHost, targetPort, targetHost)
+	target := net.JoinHostPort(targetHost, targetPort)
+	fmt.Fprintf(conn, "CONNECT %s HTTP/1.1\r\nHost: %s\r\n\r\n", target, target)
 
 	// Read CONNECT response
 	buf := make([]byte, 4096)
 	n, _ := conn.Read(buf)
 	if !strings.Contains(string(buf[:n]), "200") {
+		conn.Close()
 		t.Fatalf("CONNECT failed: %s", buf[:n])
 	}
 
@@ -125,8 +146,41 @@ func proxyRequest(t *testing.T, infra *testInfra, method, path string, body []by
 		ServerName: targetHost,
 	})
 	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
 		t.Fatalf("TLS handshake: %v", err)
 	}
+	return tlsConn
+}
+
+// upstreamHandler returns an upstream client whose requests are all
+// answered by h through a real transport, so its compression and framing
+// behavior applies.
+func upstreamHandler(t *testing.T, h http.HandlerFunc) *http.Client {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	target, _ := url.Parse(srv.URL)
+	tr := &http.Transport{}
+	t.Cleanup(tr.CloseIdleConnections)
+	return &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		r.URL.Scheme, r.URL.Host = "http", target.Host
+		return tr.RoundTrip(r)
+	})}
+}
+
+// proxyRequest sends a request through the CONNECT proxy and returns status, body, and content-type.
+func proxyRequest(t *testing.T, infra *testInfra, method, path string, body []byte, headers map[string]string) (int, string, string) {
+	t.Helper()
+	status, header, respBody := proxyRequestHeaders(t, infra, method, path, body, headers)
+	return status, respBody, header.Get("Content-Type")
+}
+
+// proxyRequestHeaders is proxyRequest returning all response headers.
+func proxyRequestHeaders(t *testing.T, infra *testInfra, method, path string, body []byte, headers map[string]string) (int, http.Header, string) {
+	t.Helper()
+	targetHost := "localhost"
+	tlsConn := dialTunnel(t, infra)
+	defer tlsConn.Close()
 
 	// Build HTTP request
 	var headerLines string
@@ -161,15 +215,54 @@ func proxyRequest(t *testing.T, infra *testInfra, method, path string, body []by
 	var statusCode int
 	fmt.Sscanf(parts[1], "%d", &statusCode)
 
-	// Extract content-type
-	contentType := ""
-	for _, line := range strings.Split(resp[:headerEnd], "\r\n") {
-		if strings.HasPrefix(strings.ToLower(line), "content-type:") {
-			contentType = strings.TrimSpace(strings.SplitN(line, ":", 2)[1])
+	// Extract headers
+	header := http.Header{}
+	chunked := false
+	for _, line := range strings.Split(resp[:headerEnd], "\r\n")[1:] {
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			header.Add(k, strings.TrimSpace(v))
+		}
+		if strings.ToLower(line) == "transfer-encoding: chunked" {
+			chunked = true
+		}
+	}
+
+	respBody := resp[headerEnd+4:]
+	if chunked {
+		decoded, err := io.ReadAll(httputil.NewChunkedReader(strings.NewReader(respBody)))
+		if err != nil {
+			t.Fatalf("decode chunked body: %v\n%q", err, respBody)
 		}
+		respBody = string(decoded)
 	}
+	return statusCode, header, respBody
+}
+
+// writeKeepAlive writes a POST on an open tunnel without Connection: close.
+func writeKeepAlive(conn net.Conn, path, body string) {
+	fmt.Fprintf(conn, "POST %s HTTP/1.1\r\nHost: localhost\r\nContent-Type: application/json\r\nContent-Length: %d\r\n\r\n%s",
+		path, len(body), body)
+}
 
-	return statusCode, resp[headerEnd+4:], contentType
+// readClosingResponse reads one response from a tunnel and checks that it
+// says Connection: close and that the proxy then closes the tunnel.
+func readClosingResponse(t *testing.T, conn net.Conn) (*http.Response, string) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !resp.Close {
+		t.Errorf("response doesn't say Connection: close: %v", resp.Header)
+	}
+	if n, err := br.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("tunnel still open after Connection: close: read %d, %v", n, err)
+	}
+	return resp, string(body)
 }
 
 // assertSSELifecycle checks that all 6 Anthropic SSE lifecycle events are present.
@@ -0,0 +1,27 @@
+package proxy
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/translate"
+)
+
+// newThinkingKey returns the secret behind thinking_signature: hmac. It
+// lives only as long as the process, so thinking signed before a restart
+// no longer verifies and is not sent back to the model.
+func newThinkingKey() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}
+
+// thinkingSigner returns the signer for conv's thinking blocks. Each
+// conversation has its own key, so thinking pasted from another session
+// does not verify either.
+func (p *Proxy) thinkingSigner(conv string) *translate.ThinkingSigner {
+	h := hmac.New(sha256.New, p.thinkingKey)
+	h.Write([]byte(conv))
+	return translate.NewThinkingSigner(h.Sum(nil))
+}
@@ -0,0 +1,154 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/tokenizer"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+// diffRE matches a unified diff header or hunk line.
+var diffRE = regexp.MustCompile(`(?m)^(diff --git |@@ -[0-9]+(,[0-9]+)? \+[0-9]+(,[0-9]+)? @@)`)
+
+// tierDecision is the label picked for a model=auto:NAME request and why.
+type tierDecision struct {
+	label   string
+	large   bool
+	reasons []string // the limits the request exceeded, or its size when none
+}
+
+func (d tierDecision) String() string {
+	size := "small"
+	if d.large {
+		size = "large"
+	}
+	return fmt.Sprintf("%s (%s: %s)", d.label, size, strings.Join(d.reasons, ", "))
+}
+
+// classifyTier picks t's small or large label for an Anthropic Messages
+// body. Any one signal sends the request large: a long prompt, many tools,
+// a code diff anywhere in the conversation, a keyword such as "plan" in the
+// latest user instruction, or extended thinking. A body that can't be
+// parsed stays small.
+func classifyTier(t config.TierConfig, body []byte) tierDecision {
+	var req struct {
+		Messages []struct {
+			Role    string          `json:"role"`
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+		Tools    []json.RawMessage `json:"tools"`
+		Thinking *struct {
+			Type string `json:"type"`
+		} `json:"thinking"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return tierDecision{label: t.Small, reasons: []string{"unparsed request"}}
+	}
+	tokens, _ := tokenizer.EstimateMessages(tokenizer.Heuristic{}, body)
+
+	var reasons []string
+	if tokens > t.MaxTokens {
+		reasons = append(reasons, fmt.Sprintf("%d tokens > %d", tokens, t.MaxTokens))
+	}
+	if len(req.Tools) > t.MaxTools {
+		reasons = append(reasons, fmt.Sprintf("%d tools > %d", len(req.Tools), t.MaxTools))
+	}
+	if req.Thinking != nil && req.Thinking.Type == "enabled" {
+		reasons = append(reasons, "thinking")
+	}
+	instruction := ""
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		m := req.Messages[i]
+		if m.Role != "user" {
+			continue
+		}
+		if text := contentText(m.Content, false); strings.TrimSpace(text) != "" {
+			instruction = strings.ToLower(text)
+			break
+		}
+	}
+	for _, kw := range t.Keywords {
+		if strings.Contains(instruction, strings.ToLower(kw)) {
+			reasons = append(reasons, fmt.Sprintf("keyword %q", kw))
+			break
+		}
+	}
+	for _, m := range req.Messages {
+		if diffRE.MatchString(contentText(m.Content, true)) {
+			reasons = append(reasons, "diff")
+			break
+		}
+	}
+
+	if len(reasons) > 0 {
+		return tierDecision{label: t.Large, large: true, reasons: reasons}
+	}
+	return tierDecision{label: t.Small, reasons: []string{fmt.Sprintf("%d tokens, %d tools", tokens, len(req.Tools))}}
+}
+
+// contentText returns the text of a string-or-blocks content value, with
+// tool results and tool inputs when withTools is set.
+func contentText(raw json.RawMessage, withTools bool) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var blocks []struct {
+		Type    string          `json:"type"`
+		Text    string          `json:"text"`
+		Input   json.RawMessage `json:"input"`
+		Content json.RawMessage `json:"content"`
+	}
+	if json.Unmarshal(raw, &blocks) != nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, blk := range blocks {
+		switch {
+		case blk.Type == "text":
+			b.WriteString(blk.Text)
+		case blk.Type == "tool_result" && withTools:
+			b.WriteString(contentText(blk.Content, true))
+		case blk.Type == "tool_use" && withTools:
+			var input map[string]interface{}
+			json.Unmarshal(blk.Input, &input)
+			for _, v := range input {
+				if s, ok := v.(string); ok {
+					b.WriteString(s)
+					b.WriteByte('\n')
+				}
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// routeTarget returns the name to resolve for a marker label: the label
+// itself, for model=auto:NAME the tier's small or large label for this
+// request, or for model=judge:NAME the judge's pick, logging the decision.
+// An unknown tier or judge is left for the resolver to reject.
+func (p *Proxy) routeTarget(label string, body []byte) string {
+	if name, ok := strings.CutPrefix(label, config.JudgePrefix); ok {
+		j, ok := p.modelResolver.Judge(name)
+		if !ok {
+			return label
+		}
+		return p.judgeTarget(label, j, body)
+	}
+	name, ok := strings.CutPrefix(label, config.AutoTierPrefix)
+	if !ok {
+		return label
+	}
+	t, ok := p.modelResolver.Tier(name)
+	if !ok {
+		return label
+	}
+	d := classifyTier(t, body)
+	log.Printf("LOCAL_TIER %s → %s", label, d)
+	return d.label
+}
@@ -0,0 +1,136 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+func TestClassifyTier(t *testing.T) {
+	tier := config.TierConfig{Small: "s", Large: "l", MaxTokens: 1000, MaxTools: 3, Keywords: []string{"plan"}}
+	msg := func(role string, content interface{}) map[string]interface{} {
+		return map[string]interface{}{"role": role, "content": content}
+	}
+	toolResult := func(text string) []map[string]interface{} {
+		return []map[string]interface{}{{"type": "tool_result", "tool_use_id": "t1", "content": text}}
+	}
+	tools := func(n int) []map[string]interface{} {
+		out := make([]map[string]interface{}, n)
+		for i := range out {
+			out[i] = map[string]interface{}{"name": fmt.Sprintf("t%d", i), "input_schema": map[string]string{"type": "object"}}
+		}
+		return out
+	}
+	tests := []struct {
+		name   string
+		req    map[string]interface{}
+		large  bool
+		reason string
+	}{
+		{"short", map[string]interface{}{"messages": []interface{}{msg("user", "rename foo to bar")}}, false, "tokens, 0 tools"},
+		{"long", map[string]interface{}{"messages": []interface{}{msg("user", strings.Repeat("word ", 2000))}}, true, "tokens > 1000"},
+		{"tools", map[string]interface{}{"messages": []interface{}{msg("user", "hi")}, "tools": tools(4)}, true, "4 tools > 3"},
+		{"keyword", map[string]interface{}{"messages": []interface{}{msg("user", "Plan the migration")}}, true, `keyword "plan"`},
+		{"keyword behind tool results", map[string]interface{}{"messages": []interface{}{
+			msg("user", "please plan this"), msg("assistant", "ok"), msg("user", toolResult("file contents")),
+		}}, true, `keyword "plan"`},
+		{"keyword only in a tool result", map[string]interface{}{"messages": []interface{}{
+			msg("user", "fix it"), msg("assistant", "ok"), msg("user", toolResult("# plan: none")),
+		}}, false, "tokens"},
+		{"diff in a tool result", map[string]interface{}{"messages": []interface{}{
+			msg("user", "review"), msg("assistant", "ok"),
+			msg("user", toolResult("diff --git a/x.go b/x.go\n@@ -1,2 +1,3 @@\n+x")),
+		}}, true, "diff"},
+		{"thinking", map[string]interface{}{"messages": []interface{}{msg("user", "hi")}, "thinking": map[string]interface{}{"type": "enabled", "budget_tokens": 1024}}, true, "thinking"},
+	}
+	for _, tt := range tests {
+		body, _ := json.Marshal(tt.req)
+		d := classifyTier(tier, body)
+		if d.large != tt.large || !strings.Contains(d.String(), tt.reason) {
+			t.Errorf("%s: %s, want large=%v with %q", tt.name, d, tt.large, tt.reason)
+		}
+		if want := map[bool]string{false: "s", true: "l"}[tt.large]; d.label != want {
+			t.Errorf("%s: label %s, want %s", tt.name, d.label, want)
+		}
+	}
+}
+
+func TestLocalRouteAutoTier(t *testing.T) {
+	oaiPort, getLastReq, _ := capturingMockOpenAI(t)
+	resolver, err := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "mock",
+			Endpoint: fmt.Sprintf("http://127.0.0.1:%d/v1", oaiPort),
+			Models:   map[string]config.ModelConfig{"coder_7b": {Model: "small-m"}, "coder_32b": {Model: "large-m"}},
+		}},
+		Tiers: map[string]config.TierConfig{"coder": {Small: "coder_7b", Large: "coder_32b"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	infra := setupInfra(t, resolver)
+	for prompt, want := range map[string]string{
+		"rename foo to bar":              "small-m",
+		"think hard about the data race": "large-m",
+	} {
+		body, _ := json.Marshal(map[string]interface{}{
+			"model":      "claude-sonnet-4-20250514",
+			"system":     "<!-- @proxy-local-route:af83e9 model=auto:coder -->",
+			"messages":   []map[string]string{{"role": "user", "content": prompt}},
+			"max_tokens": 64,
+		})
+		status, resp, _ := proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+		if status != 200 {
+			t.Fatalf("%q: %d %s", prompt, status, resp)
+		}
+		var oaiReq map[string]interface{}
+		json.Unmarshal(getLastReq(), &oaiReq)
+		if oaiReq["model"] != want {
+			t.Errorf("%q went to %v, want %s", prompt, oaiReq["model"], want)
+		}
+	}
+}
+
+func TestOpenAIListenerAutoTier(t *testing.T) {
+	oaiPort, getLastReq, _ := capturingMockOpenAI(t)
+	resolver, err := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "mock",
+			Endpoint: fmt.Sprintf("http://127.0.0.1:%d/v1", oaiPort),
+			Models:   map[string]config.ModelConfig{"coder_7b": {Model: "small-m"}, "coder_32b": {Model: "large-m"}},
+		}},
+		Tiers: map[string]config.TierConfig{"coder": {Small: "coder_7b", Large: "coder_32b"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(New(nil, WithModelResolver(resolver)).OpenAIHandler())
+	t.Cleanup(srv.Close)
+	for prompt, want := range map[string]string{
+		"rename foo to bar":              "small-m",
+		"think hard about the data race": "large-m",
+	} {
+		body, _ := json.Marshal(map[string]interface{}{
+			"model":    "auto:coder",
+			"messages": []map[string]string{{"role": "user", "content": prompt}},
+		})
+		resp, err := http.Post(srv.URL+"/v1/chat/completions", "application/json", strings.NewReader(string(body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != 200 {
+			t.Fatalf("%q: status %d", prompt, resp.StatusCode)
+		}
+		var oaiReq map[string]interface{}
+		json.Unmarshal(getLastReq(), &oaiReq)
+		if oaiReq["model"] != want {
+			t.Errorf("%q went to %v, want %s", prompt, oaiReq["model"], want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ClientHello profiles for the upstream leg. Some gateways fingerprint TLS
+// (JA3/JA4) and block clients whose handshake doesn't look like Claude
+// Code's own Node runtime.
+//
+// crypto/tls fixes extension order and GREASE, so a profile can only shape
+// what it exposes: versions, offered cipher suites, curves and ALPN. That
+// is enough to drop the most Go-specific traits (h2 in ALPN, the post-quantum
+// key share) but not to match Node byte for byte.
+const (
+	TLSProfileGo   = "go"   // crypto/tls defaults, HTTP/2
+	TLSProfileNode = "node" // Node.js https defaults (OpenSSL 3): HTTP/1.1 only, classic curves
+)
+
+// nodeCipherSuites are the TLS 1.2 suites in Node's default list that
+// crypto/tls implements. TLS 1.3 suites aren't configurable and match Node's.
+var nodeCipherSuites = []uint16{
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+}
+
+// ValidateTLSProfiles checks a host pattern → profile map from config.
+// Patterns use the intercept list syntax.
+func ValidateTLSProfiles(profiles map[string]string) error {
+	for pattern, name := range profiles {
+		if name != TLSProfileGo && name != TLSProfileNode {
+			return fmt.Errorf("tls_profiles: %s: unknown profile %q (want %s or %s)", pattern, name, TLSProfileGo, TLSProfileNode)
+		}
+	}
+	return nil
+}
+
+// WithTLSProfiles selects a ClientHello profile per upstream host. Hosts
+// matching no pattern keep the client's own TLS settings. The most
+// specific pattern wins: exact hosts, then longer wildcards, then "*".
+func WithTLSProfiles(profiles map[string]string) Option {
+	return func(p *Proxy) {
+		p.tlsProfiles = nil
+		for pattern, name := range profiles {
+			p.tlsProfiles = append(p.tlsProfiles, tlsProfileRule{
+				pattern: newInterceptList([]string{pattern}),
+				profile: name,
+			})
+		}
+		sort.Slice(p.tlsProfiles, func(i, j int) bool {
+			return patternRank(p.tlsProfiles[i].pattern) > patternRank(p.tlsProfiles[j].pattern)
+		})
+	}
+}
+
+type tlsProfileRule struct {
+	pattern interceptList
+	profile string
+}
+
+// patternRank orders patterns by specificity.
+func patternRank(l interceptList) int {
+	if len(l) == 0 || l[0] == "*" {
+		return 0
+	}
+	if strings.HasPrefix(l[0], "*.") {
+		return len(l[0])
+	}
+	return 1 << 20
+}
+
+// profileTransport sends each request through the transport of the profile
+// its host matches.
+type profileTransport struct {
+	base       http.RoundTripper
+	rules      []tlsProfileRule
+	transports map[string]http.RoundTripper
+}
+
+// newProfileTransport builds one transport per profile in rules from base,
+// keeping base's roots and proxy settings.
+func newProfileTransport(base http.RoundTripper, rules []tlsProfileRule) *profileTransport {
+	t := &profileTransport{base: base, rules: rules, transports: map[string]http.RoundTripper{}}
+	for _, r := range rules {
+		if _, ok := t.transports[r.profile]; !ok {
+			t.transports[r.profile] = profiledTransport(base, r.profile)
+		}
+	}
+	return t
+}
+
+func (t *profileTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Hostname()
+	for _, r := range t.rules {
+		if r.pattern.match(host) {
+			return t.transports[r.profile].RoundTrip(req)
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// profiledTransport clones base (or the default transport) with profile's
+// TLS settings applied.
+func profiledTransport(base http.RoundTripper, profile string) *http.Transport {
+	ht, ok := base.(*http.Transport)
+	if !ok {
+		ht = http.DefaultTransport.(*http.Transport)
+	}
+	ht = ht.Clone()
+	if ht.TLSClientConfig == nil {
+		ht.TLSClientConfig = &tls.Config{}
+	}
+	switch profile {
+	case TLSProfileNode:
+		cfg := ht.TLSClientConfig
+		cfg.MinVersion = tls.VersionTLS12
+		cfg.CipherSuites = nodeCipherSuites
+		cfg.CurvePreferences = []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384}
+		cfg.NextProtos = []string{"http/1.1"}
+		// Node's https module never offers h2; a non-nil empty map keeps
+		// the transport from adding it back.
+		ht.ForceAttemptHTTP2 = false
+		ht.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	case TLSProfileGo:
+		ht.TLSClientConfig.NextProtos = nil
+		ht.ForceAttemptHTTP2 = true
+	}
+	return ht
+}
@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// helloRecorder is a TLS server that records the last ClientHello it saw.
+type helloRecorder struct {
+	*httptest.Server
+	mu    sync.Mutex
+	hello *tls.ClientHelloInfo
+}
+
+func newHelloRecorder(t *testing.T) *helloRecorder {
+	t.Helper()
+	h := &helloRecorder{}
+	h.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	h.Server.EnableHTTP2 = true
+	h.Server.TLS = &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			h.mu.Lock()
+			h.hello = hello
+			h.mu.Unlock()
+			return nil, nil
+		},
+	}
+	h.Server.StartTLS()
+	t.Cleanup(h.Close)
+	return h
+}
+
+func (h *helloRecorder) last() *tls.ClientHelloInfo {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.hello
+}
+
+func TestTLSProfileNode(t *testing.T) {
+	srv := newHelloRecorder(t)
+	base := srv.Client()
+	base.Transport.(*http.Transport).ForceAttemptHTTP2 = true
+
+	p := New(nil, WithHTTPClient(base), WithTLSProfiles(map[string]string{"127.0.0.1": TLSProfileNode}))
+	resp, err := p.httpClient.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+
+	hello := srv.last()
+	if !slices.Equal(hello.SupportedProtos, []string{"http/1.1"}) {
+		t.Errorf("ALPN = %v, want [http/1.1]", hello.SupportedProtos)
+	}
+	if !slices.Equal(hello.SupportedCurves, []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384}) {
+		t.Errorf("curves = %v", hello.SupportedCurves)
+	}
+	if resp.ProtoMajor != 1 {
+		t.Errorf("node profile negotiated %s", resp.Proto)
+	}
+}
+
+func TestTLSProfileUnmatchedHostKeepsClient(t *testing.T) {
+	srv := newHelloRecorder(t)
+	base := srv.Client()
+	base.Transport.(*http.Transport).ForceAttemptHTTP2 = true
+
+	p := New(nil, WithHTTPClient(base), WithTLSProfiles(map[string]string{"*.example.com": TLSProfileNode}))
+	resp, err := p.httpClient.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+	if !slices.Contains(srv.last().SupportedProtos, "h2") {
+		t.Errorf("unmatched host should keep HTTP/2 in ALPN: %v", srv.last().SupportedProtos)
+	}
+}
+
+func TestTLSProfileRulesMostSpecificFirst(t *testing.T) {
+	p := New(nil, WithTLSProfiles(map[string]string{
+		"*":                 TLSProfileNode,
+		"*.anthropic.com":   TLSProfileNode,
+		"api.anthropic.com": TLSProfileGo,
+	}))
+	var order []string
+	for _, r := range p.tlsProfiles {
+		order = append(order, r.pattern[0])
+	}
+	if !slices.Equal(order, []string{"api.anthropic.com", "*.anthropic.com", "*"}) {
+		t.Errorf("rule order = %v", order)
+	}
+
+	if err := ValidateTLSProfiles(map[string]string{"*": "chrome"}); err == nil {
+		t.Error("expected error for unknown profile")
+	}
+}
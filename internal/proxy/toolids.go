@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"sync"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/translate"
+)
+
+// maxToolIDConversations bounds the per-conversation tool ID maps; the
+// conversation used least recently is forgotten first.
+const maxToolIDConversations = 256
+
+// toolIDTables keeps each conversation's translate.ToolIDMap, so a tool call
+// ID rewritten for Claude Code in one turn goes back to the provider as the
+// ID it issued when the tool_result arrives in the next.
+type toolIDTables struct {
+	mu   sync.Mutex
+	maps map[string]*toolIDTable
+}
+
+type toolIDTable struct {
+	ids  *translate.ToolIDMap
+	used time.Time
+}
+
+// get returns conv's map, creating it; nil for a request without a
+// conversation.
+func (t *toolIDTables) get(conv string) *translate.ToolIDMap {
+	if conv == "" {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.maps == nil {
+		t.maps = make(map[string]*toolIDTable)
+	}
+	now := time.Now()
+	if e, ok := t.maps[conv]; ok {
+		e.used = now
+		return e.ids
+	}
+	if len(t.maps) >= maxToolIDConversations {
+		oldest := ""
+		for k, e := range t.maps {
+			if oldest == "" || e.used.Before(t.maps[oldest].used) {
+				oldest = k
+			}
+		}
+		delete(t.maps, oldest)
+	}
+	e := &toolIDTable{ids: translate.NewToolIDMap(), used: now}
+	t.maps[conv] = e
+	return e.ids
+}
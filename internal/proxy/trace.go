@@ -0,0 +1,120 @@
+package proxy
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/tracing"
+)
+
+// WithTracer records spans for CONNECTs and the requests inside tunnels:
+// the route decision, request translation, the provider call and the
+// response stream. A nil tracer records nothing.
+func WithTracer(t *tracing.Tracer) Option {
+	return func(p *Proxy) { p.tracer = t }
+}
+
+// traceParent returns the span context a client sent in its traceparent
+// header, or the zero context, which starts a new trace.
+func traceParent(h http.Header) tracing.SpanContext {
+	sc, _ := tracing.ParseTraceparent(h.Get("traceparent"))
+	return sc
+}
+
+// WithSlowRequestThreshold logs a phase breakdown, as [SLOW], for every
+// local route that takes longer than d. Zero turns it off.
+func WithSlowRequestThreshold(d time.Duration) Option {
+	return func(p *Proxy) { p.slowThreshold = d }
+}
+
+// localTrace times the phases of a local route as consecutive child spans
+// of the request span. The phase boundaries are also kept, without a
+// tracer, for the slow-request log.
+type localTrace struct {
+	tracer *tracing.Tracer
+	req    *tracing.Span
+	phase  *tracing.Span
+	marks  []phaseMark
+	slow   time.Duration // log a breakdown above this; 0 = never
+	queued time.Duration // the tunnel's admission wait, for its first request
+}
+
+type phaseMark struct {
+	name  string
+	start time.Time
+}
+
+// begin ends the current phase and starts the next.
+func (lt *localTrace) begin(name string, kind tracing.Kind) *tracing.Span {
+	lt.phase.End()
+	lt.phase = lt.tracer.Start(lt.req.Context(), name, kind)
+	lt.marks = append(lt.marks, phaseMark{name, time.Now()})
+	return lt.phase
+}
+
+// end closes the last phase and copies ev's outcome onto the request span.
+// A failure is also marked on the phase it happened in.
+func (lt *localTrace) end(ev *RouteEvent) {
+	lt.req.SetAttr("hybrid.status", ev.Status)
+	if ev.Provider != "" {
+		lt.req.SetAttr("hybrid.provider", ev.Provider)
+		lt.req.SetAttr("gen_ai.request.model", ev.Model)
+	}
+	if ev.InputTokens > 0 || ev.OutputTokens > 0 {
+		lt.req.SetAttr("gen_ai.usage.input_tokens", ev.InputTokens)
+		lt.req.SetAttr("gen_ai.usage.output_tokens", ev.OutputTokens)
+	}
+	if ev.Status != "ok" && ev.Status != "dedupe" {
+		status := ev.Status
+		if status == "" {
+			status = "ERROR"
+		}
+		lt.phase.SetError(status)
+		lt.req.SetError(status)
+	}
+	lt.phase.End()
+	if lt.slow > 0 {
+		lt.logSlow(ev, time.Now())
+	}
+}
+
+// logSlow logs where a route that ended at end spent its time, if it took
+// longer than the threshold: queueing and routing point at the proxy, the
+// provider phase (until response headers) at the network or a loading
+// model, and the first token and generation at the GPU.
+func (lt *localTrace) logSlow(ev *RouteEvent, end time.Time) {
+	total := end.Sub(ev.Start) + lt.queued
+	if total < lt.slow {
+		return
+	}
+	parts := []string{fmt.Sprintf("queue %dms", lt.queued.Milliseconds())}
+	for i, m := range lt.marks {
+		stop := end
+		if i+1 < len(lt.marks) {
+			stop = lt.marks[i+1].start
+		}
+		if m.name == "stream" && ev.firstToken.After(m.start) {
+			parts = append(parts,
+				fmt.Sprintf("stream first token %dms", ev.firstToken.Sub(m.start).Milliseconds()),
+				fmt.Sprintf("generation %dms", stop.Sub(ev.firstToken).Milliseconds()))
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s %dms", m.name, stop.Sub(m.start).Milliseconds()))
+	}
+	if !ev.firstToken.IsZero() {
+		parts = append(parts, fmt.Sprintf("ttft %dms", ev.firstToken.Sub(ev.Start).Milliseconds()))
+	}
+	target := ev.Label
+	if ev.Provider != "" {
+		target += " → " + ev.Provider + "/" + ev.Model
+	}
+	status := ev.Status
+	if status == "" {
+		status = "ERROR"
+	}
+	log.Printf("[SLOW] %s %s after %dms (threshold %s): %s",
+		target, status, total.Milliseconds(), lt.slow, strings.Join(parts, ", "))
+}
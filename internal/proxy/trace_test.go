@@ -0,0 +1,212 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/tracing"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+// exportedSpan is the part of an OTLP/JSON span the tests check.
+type exportedSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Status       struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"status"`
+}
+
+// collectSpans starts a tracer exporting to a fake collector. The returned
+// func shuts the tracer down and returns every span it exported by name.
+func collectSpans(t *testing.T) (*tracing.Tracer, func() map[string]exportedSpan) {
+	t.Helper()
+	var mu sync.Mutex
+	spans := map[string]exportedSpan{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []exportedSpan `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("bad OTLP body: %v", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, s := range ss.Spans {
+					spans[s.Name] = s
+				}
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	tr := tracing.New(tracing.Config{Endpoint: srv.URL + "/v1/traces", Interval: time.Hour})
+	return tr, func() map[string]exportedSpan {
+		tr.Shutdown(context.Background())
+		mu.Lock()
+		defer mu.Unlock()
+		return spans
+	}
+}
+
+func TestTracingLocalRoute(t *testing.T) {
+	port, _, headers := capturingMockOpenAI(t)
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "mock",
+			Endpoint: fmt.Sprintf("http://127.0.0.1:%d/v1", port),
+			Models:   map[string]config.ModelConfig{"fast": {Model: "m1"}},
+		}},
+	})
+	tr, spans := collectSpans(t)
+	infra := setupInfraWithOptions(t, resolver, WithTracer(tr))
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"system":     "<!-- @proxy-local-route:af83e9 model=fast -->",
+		"messages":   []map[string]string{{"role": "user", "content": "hi"}},
+		"max_tokens": 64,
+	})
+	const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	status, respBody, _ := proxyRequest(t, infra, "POST", "/v1/messages", body, map[string]string{"traceparent": parent})
+	if status != 200 {
+		t.Fatalf("status %d: %s", status, respBody)
+	}
+
+	got := spans()
+	req, ok := got["POST /v1/messages"]
+	if !ok {
+		t.Fatalf("no request span in %v", got)
+	}
+	if req.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || req.ParentSpanID != "00f067aa0ba902b7" {
+		t.Errorf("request span %+v does not continue the client's trace", req)
+	}
+	for _, name := range []string{"route", "translate request", "provider mock", "translate response"} {
+		s, ok := got[name]
+		if !ok {
+			t.Errorf("missing %q span", name)
+			continue
+		}
+		if s.TraceID != req.TraceID || s.ParentSpanID != req.SpanID || s.Status.Code != 0 {
+			t.Errorf("%q span = %+v, want an ok child of the request span", name, s)
+		}
+	}
+	if _, ok := got["CONNECT"]; !ok {
+		t.Error("missing CONNECT span")
+	}
+
+	call := got["provider mock"]
+	if tp := headers().Get("traceparent"); !strings.Contains(tp, call.TraceID+"-"+call.SpanID) {
+		t.Errorf("provider got traceparent %q, want the provider span %s/%s", tp, call.TraceID, call.SpanID)
+	}
+}
+
+func TestTracingMarksFailedPhase(t *testing.T) {
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "dead",
+			Endpoint: "http://127.0.0.1:1/v1",
+			Models:   map[string]config.ModelConfig{"down": {Model: "x"}},
+		}},
+	})
+	tr, spans := collectSpans(t)
+	infra := setupInfraWithOptions(t, resolver, WithTracer(tr))
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"system":     "<!-- @proxy-local-route:af83e9 model=down -->",
+		"messages":   []map[string]string{{"role": "user", "content": "hi"}},
+		"max_tokens": 64,
+	})
+	proxyRequest(t, infra, "POST", "/v1/messages", body, nil)
+
+	got := spans()
+	if s := got["provider dead"]; s.Status.Code != 2 || s.Status.Message != "CONNECTION" {
+		t.Errorf("provider span = %+v, want CONNECTION error", s)
+	}
+	if s := got["POST /v1/messages"]; s.Status.Code != 2 || s.ParentSpanID != "" {
+		t.Errorf("request span = %+v, want a failed root span", s)
+	}
+	if s := got["route"]; s.Status.Code != 0 {
+		t.Errorf("route span = %+v, want ok", s)
+	}
+}
+
+// logBuffer collects log output for tests that check log lines.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf strings.Builder
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func captureLog(t *testing.T) *logBuffer {
+	t.Helper()
+	b := &logBuffer{}
+	log.SetOutput(b)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return b
+}
+
+func TestSlowRequestBreakdown(t *testing.T) {
+	srv := slowStreamServer(t, 60*time.Millisecond, 0)
+	resolver, _ := config.NewModelResolver(&config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "slow",
+			Endpoint: srv.URL + "/v1",
+			Models:   map[string]config.ModelConfig{"test_model": {Model: "m"}},
+		}},
+	})
+	infra := setupInfraWithOptions(t, resolver, WithSlowRequestThreshold(40*time.Millisecond))
+	logs := captureLog(t)
+
+	proxyRequest(t, infra, "POST", "/v1/messages", streamBody(), nil)
+	line := ""
+	for _, l := range strings.Split(logs.String(), "\n") {
+		if strings.Contains(l, "[SLOW]") {
+			line = l
+		}
+	}
+	for _, want := range []string{"test_model → slow/m ok", "route ", "translate request ", "provider slow ", "stream first token ", "generation ", "ttft "} {
+		if !strings.Contains(line, want) {
+			t.Errorf("slow log %q lacks %q", line, want)
+		}
+	}
+
+	// A fast route stays quiet.
+	var lt localTrace
+	lt.slow = time.Minute
+	lt.logSlow(&RouteEvent{Start: time.Now(), Label: "fast", Status: "ok"}, time.Now())
+	if strings.Count(logs.String(), "[SLOW]") != 1 {
+		t.Errorf("fast route logged as slow:\n%s", logs)
+	}
+}
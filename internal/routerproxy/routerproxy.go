@@ -0,0 +1,18 @@
+// Package routerproxy gives the claude-hybrid binary the proxy inside a
+// pkg/router Router. pkg/router keeps *proxy.Proxy out of its exported API,
+// since internal/proxy may change in any release.
+package routerproxy
+
+import "github.com/peter-wagstaff/claude-hybrid-router/internal/proxy"
+
+var of func(rt any) *proxy.Proxy
+
+// Register installs the accessor; pkg/router calls it from init.
+func Register(f func(rt any) *proxy.Proxy) {
+	of = f
+}
+
+// Of returns the proxy inside rt, a *router.Router.
+func Of(rt any) *proxy.Proxy {
+	return of(rt)
+}
@@ -1,4 +1,4 @@
-// Package testutil provides test infrastructure: cert generation and echo server.
+// Package testutil provides test infrastructure: cert generation, an echo server and a mock OpenAI server.
 package testutil
 
 import (
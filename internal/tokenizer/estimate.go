@@ -0,0 +1,101 @@
+package tokenizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Structural overheads added on top of the counted text. Chat templates wrap
+// each message and tool definition in a few special tokens; images are
+// counted at Anthropic's ceiling for a full-size image since their
+// dimensions aren't decoded.
+const (
+	requestOverhead = 3
+	messageOverhead = 4
+	toolOverhead    = 8
+	imageTokens     = 1600
+)
+
+// EstimateMessages estimates the input tokens of an Anthropic Messages
+// request body: system prompt, messages and tool definitions. All text is
+// counted in a single Count call so server-backed tokenizers make one round
+// trip.
+func EstimateMessages(t Tokenizer, body []byte) (int, error) {
+	var req struct {
+		System   json.RawMessage `json:"system"`
+		Messages []struct {
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+		Tools []struct {
+			Name        string          `json:"name"`
+			Description string          `json:"description"`
+			InputSchema json.RawMessage `json:"input_schema"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return 0, fmt.Errorf("parse request: %w", err)
+	}
+
+	var text strings.Builder
+	fixed := requestOverhead
+	fixed += collectContent(&text, req.System)
+	for _, m := range req.Messages {
+		fixed += messageOverhead
+		fixed += collectContent(&text, m.Content)
+	}
+	for _, tool := range req.Tools {
+		fixed += toolOverhead
+		text.WriteString(tool.Name)
+		text.WriteByte('\n')
+		text.WriteString(tool.Description)
+		text.WriteByte('\n')
+		text.Write(tool.InputSchema)
+		text.WriteByte('\n')
+	}
+	return fixed + t.Count(text.String()), nil
+}
+
+// collectContent appends the text in a string-or-blocks content value to b
+// and returns the fixed token cost of blocks that aren't text.
+func collectContent(b *strings.Builder, raw json.RawMessage) int {
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		b.WriteString(s)
+		b.WriteByte('\n')
+		return 0
+	}
+	var blocks []struct {
+		Type     string          `json:"type"`
+		Text     string          `json:"text"`
+		Thinking string          `json:"thinking"`
+		Name     string          `json:"name"`
+		Input    json.RawMessage `json:"input"`
+		Content  json.RawMessage `json:"content"`
+	}
+	if json.Unmarshal(raw, &blocks) != nil {
+		return 0
+	}
+	fixed := 0
+	for _, blk := range blocks {
+		switch blk.Type {
+		case "text":
+			b.WriteString(blk.Text)
+		case "thinking":
+			b.WriteString(blk.Thinking)
+		case "tool_use":
+			b.WriteString(blk.Name)
+			b.WriteByte(' ')
+			b.Write(blk.Input)
+		case "tool_result":
+			fixed += collectContent(b, blk.Content)
+		case "image", "document":
+			fixed += imageTokens
+		}
+		b.WriteByte('\n')
+	}
+	return fixed
+}
@@ -0,0 +1,71 @@
+package tokenizer
+
+import "unicode"
+
+// Heuristic estimates tokens from the shape of the text, tuned against
+// BPE vocabularies of the cl100k/o200k/Llama-3 family: short words are one
+// token, long words split, digits group in threes, punctuation pairs up,
+// and CJK characters are a token each. It is typically within 10-15% on
+// English prose and code.
+type Heuristic struct{}
+
+func (Heuristic) Name() string { return SpecHeuristic }
+
+func (Heuristic) Count(text string) int {
+	n := 0
+	rs := []rune(text)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		j := i + 1
+		switch {
+		case isCJK(r):
+			n++
+		case unicode.IsLetter(r):
+			ascii := r < 0x80
+			for j < len(rs) && unicode.IsLetter(rs[j]) && !isCJK(rs[j]) {
+				ascii = ascii && rs[j] < 0x80
+				j++
+			}
+			l := j - i
+			if ascii {
+				n += 1 + (l-1)/8
+			} else {
+				n += 1 + (l-1)/3
+			}
+		case unicode.IsDigit(r):
+			for j < len(rs) && unicode.IsDigit(rs[j]) {
+				j++
+			}
+			n += (j - i + 2) / 3
+		case r == '\n' || r == '\r':
+			for j < len(rs) && (rs[j] == '\n' || rs[j] == '\r') {
+				j++
+			}
+			n++
+		case unicode.IsSpace(r):
+			// A single space joins the next word; runs (indentation) are
+			// tokens of their own.
+			for j < len(rs) && unicode.IsSpace(rs[j]) && rs[j] != '\n' && rs[j] != '\r' {
+				j++
+			}
+			if j-i > 1 {
+				n += 1 + (j-i)/8
+			}
+		default:
+			for j < len(rs) && isSymbol(rs[j]) {
+				j++
+			}
+			n += (j - i + 1) / 2
+		}
+		i = j
+	}
+	return n
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+func isSymbol(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r)
+}
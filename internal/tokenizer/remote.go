@@ -0,0 +1,74 @@
+package tokenizer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync/atomic"
+)
+
+// remote asks the model server to tokenize. llama.cpp answers with the
+// token list; vLLM also returns a count.
+type remote struct {
+	kind     string // SpecLlamaCpp or SpecVLLM
+	url      string
+	apiKey   string
+	model    string
+	client   *http.Client
+	fallback Tokenizer
+
+	warned atomic.Bool
+}
+
+func (r *remote) Name() string { return r.kind }
+
+func (r *remote) Count(text string) int {
+	n, err := r.tokenize(text)
+	if err != nil {
+		// Log once per tokenizer; the estimate is still useful.
+		if r.warned.CompareAndSwap(false, true) {
+			log.Printf("[TOKENIZER] %s at %s failed, using heuristic: %v", r.kind, r.url, err)
+		}
+		return r.fallback.Count(text)
+	}
+	return n
+}
+
+func (r *remote) tokenize(text string) (int, error) {
+	var payload interface{}
+	if r.kind == SpecVLLM {
+		payload = map[string]interface{}{"model": r.model, "prompt": text, "add_special_tokens": false}
+	} else {
+		payload = map[string]interface{}{"content": text}
+	}
+	body, _ := json.Marshal(payload)
+	req, err := http.NewRequest("POST", r.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if r.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+r.apiKey)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return 0, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	var out struct {
+		Count  *int              `json:"count"`
+		Tokens []json.RawMessage `json:"tokens"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return 0, fmt.Errorf("decode /tokenize response: %w", err)
+	}
+	if out.Count != nil {
+		return *out.Count, nil
+	}
+	return len(out.Tokens), nil
+}
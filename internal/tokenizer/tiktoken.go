@@ -0,0 +1,102 @@
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+// tiktokenSplit approximates the cl100k_base pre-tokenizer. RE2 has no
+// lookahead, so trailing whitespace before a word stays with the whitespace
+// run instead of the word; counts differ by at most a token per such run.
+var tiktokenSplit = regexp.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`)
+
+// tiktoken is a byte-level BPE tokenizer read from a .tiktoken vocabulary
+// (one "base64(token) rank" pair per line, as published for cl100k_base
+// and o200k_base).
+type tiktoken struct {
+	name  string
+	ranks map[string]int
+}
+
+var tiktokenCache sync.Map // path → *tiktoken
+
+func loadTiktoken(path string) (Tokenizer, error) {
+	if t, ok := tiktokenCache.Load(path); ok {
+		return t.(*tiktoken), nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("tiktoken vocabulary: %w", err)
+	}
+	defer f.Close()
+
+	ranks := make(map[string]int, 100_000)
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		tok, rank, ok := bytes.Cut(sc.Bytes(), []byte(" "))
+		if !ok {
+			if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("tiktoken vocabulary %s:%d: want \"token rank\"", path, line)
+		}
+		b, err := base64.StdEncoding.DecodeString(string(tok))
+		if err != nil {
+			return nil, fmt.Errorf("tiktoken vocabulary %s:%d: %w", path, line, err)
+		}
+		r, err := strconv.Atoi(string(rank))
+		if err != nil {
+			return nil, fmt.Errorf("tiktoken vocabulary %s:%d: %w", path, line, err)
+		}
+		ranks[string(b)] = r
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("tiktoken vocabulary: %w", err)
+	}
+	t := &tiktoken{name: "tiktoken:" + filepath.Base(path), ranks: ranks}
+	actual, _ := tiktokenCache.LoadOrStore(path, t)
+	return actual.(*tiktoken), nil
+}
+
+func (t *tiktoken) Name() string { return t.name }
+
+func (t *tiktoken) Count(text string) int {
+	n := 0
+	for _, piece := range tiktokenSplit.FindAllString(text, -1) {
+		n += t.countPiece(piece)
+	}
+	return n
+}
+
+// countPiece runs byte pair merging over one pre-token, always merging the
+// adjacent pair with the lowest rank, and returns the resulting token count.
+func (t *tiktoken) countPiece(piece string) int {
+	if _, ok := t.ranks[piece]; ok {
+		return 1
+	}
+	// bounds[i] is where part i starts; the last entry is len(piece).
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+	for len(bounds) > 2 {
+		best, bestRank := -1, 0
+		for i := 0; i+2 < len(bounds); i++ {
+			if r, ok := t.ranks[piece[bounds[i]:bounds[i+2]]]; ok && (best < 0 || r < bestRank) {
+				best, bestRank = i, r
+			}
+		}
+		if best < 0 {
+			break
+		}
+		bounds = append(bounds[:best+1], bounds[best+2:]...)
+	}
+	return len(bounds) - 1
+}
@@ -0,0 +1,70 @@
+// Package tokenizer estimates token counts for locally routed models.
+//
+// Claude Code sizes its requests from Anthropic token counts, which mean
+// little for a local model. A Tokenizer gives every feature that needs a
+// count (count_tokens, usage estimates) the same per-model answer.
+package tokenizer
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Tokenizer counts the tokens a model would see for text. Count never fails:
+// implementations that depend on a file or a server fall back to the
+// heuristic and say so in their log output.
+type Tokenizer interface {
+	Name() string
+	Count(text string) int
+}
+
+// Spec names, as written in config (tokenizer: ...).
+const (
+	SpecHeuristic = "heuristic" // built-in estimate, no setup
+	SpecLlamaCpp  = "llamacpp"  // llama.cpp server POST /tokenize (exact for GGUF models)
+	SpecVLLM      = "vllm"      // vLLM POST /tokenize
+	specTiktoken  = "tiktoken:" // tiktoken:/path/to/cl100k_base.tiktoken
+)
+
+// remoteTimeout bounds a /tokenize call; past it the heuristic answers.
+const remoteTimeout = 5 * time.Second
+
+// Validate checks that spec names a known tokenizer. It does not load files
+// or contact servers.
+func Validate(spec string) error {
+	switch {
+	case spec == "", spec == SpecHeuristic, spec == SpecLlamaCpp, spec == SpecVLLM:
+		return nil
+	case strings.HasPrefix(spec, specTiktoken):
+		if strings.TrimPrefix(spec, specTiktoken) == "" {
+			return fmt.Errorf("tokenizer %q: missing vocabulary path", spec)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown tokenizer %q (want %s, %s, %s or tiktoken:<path>)", spec, SpecHeuristic, SpecLlamaCpp, SpecVLLM)
+}
+
+// New builds the tokenizer named by spec. endpoint, apiKey and model are the
+// provider's, used by the server-backed tokenizers. An empty spec means the
+// heuristic.
+func New(spec, endpoint, apiKey, model string) (Tokenizer, error) {
+	if err := Validate(spec); err != nil {
+		return nil, err
+	}
+	switch {
+	case spec == "" || spec == SpecHeuristic:
+		return Heuristic{}, nil
+	case spec == SpecLlamaCpp, spec == SpecVLLM:
+		return &remote{
+			kind:     spec,
+			url:      strings.TrimSuffix(endpoint, "/v1") + "/tokenize",
+			apiKey:   apiKey,
+			model:    model,
+			client:   &http.Client{Timeout: remoteTimeout},
+			fallback: Heuristic{},
+		}, nil
+	}
+	return loadTiktoken(strings.TrimPrefix(spec, specTiktoken))
+}
@@ -0,0 +1,186 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHeuristic(t *testing.T) {
+	h := Heuristic{}
+	cases := []struct {
+		text     string
+		min, max int
+	}{
+		{"", 0, 0},
+		{"hello world", 2, 2},
+		{"The quick brown fox jumps over the lazy dog.", 9, 11},
+		{"internationalization", 2, 4},
+		{"1234567", 3, 3},
+		{"func main() {\n\tfmt.Println(\"hi\")\n}", 10, 16},
+		{"你好世界", 4, 4},
+	}
+	for _, c := range cases {
+		if n := h.Count(c.text); n < c.min || n > c.max {
+			t.Errorf("Count(%q) = %d, want %d..%d", c.text, n, c.min, c.max)
+		}
+	}
+}
+
+func writeVocab(t *testing.T, tokens ...string) string {
+	t.Helper()
+	var b strings.Builder
+	rank := 0
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), rank)
+		rank++
+	}
+	for _, tok := range tokens {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(tok)), rank)
+		rank++
+	}
+	path := filepath.Join(t.TempDir(), "test.tiktoken")
+	os.WriteFile(path, []byte(b.String()), 0644)
+	return path
+}
+
+func TestTiktoken(t *testing.T) {
+	path := writeVocab(t, "he", "ll", "hell", "hello", " w", " wor", " world")
+	tok, err := New("tiktoken:"+path, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.Name() != "tiktoken:test.tiktoken" {
+		t.Errorf("Name = %q", tok.Name())
+	}
+	cases := map[string]int{
+		"hello":       1,
+		"hello world": 2,
+		"helloo":      2, // "hello" + "o"
+		"hex":         2, // "he" + "x"
+		"xyz":         3, // bytes only
+	}
+	for text, want := range cases {
+		if got := tok.Count(text); got != want {
+			t.Errorf("Count(%q) = %d, want %d", text, got, want)
+		}
+	}
+
+	again, _ := New("tiktoken:"+path, "", "", "")
+	if again != tok {
+		t.Error("vocabulary should be loaded once per path")
+	}
+	if _, err := New("tiktoken:"+filepath.Join(t.TempDir(), "missing"), "", "", ""); err == nil {
+		t.Error("expected error for missing vocabulary")
+	}
+}
+
+func TestRemoteTokenizers(t *testing.T) {
+	var gotBody map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tokenize" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		if _, ok := gotBody["model"]; ok {
+			fmt.Fprint(w, `{"count":7,"max_model_len":32768,"tokens":[1,2,3,4,5,6,7]}`)
+			return
+		}
+		fmt.Fprint(w, `{"tokens":[1,2,3]}`)
+	}))
+	defer srv.Close()
+
+	llama, _ := New(SpecLlamaCpp, srv.URL+"/v1", "", "")
+	if n := llama.Count("anything"); n != 3 {
+		t.Errorf("llamacpp count = %d, want 3", n)
+	}
+	if gotBody["content"] != "anything" {
+		t.Errorf("llamacpp body = %v", gotBody)
+	}
+
+	vllm, _ := New(SpecVLLM, srv.URL+"/v1", "", "qwen")
+	if n := vllm.Count("anything"); n != 7 {
+		t.Errorf("vllm count = %d, want 7", n)
+	}
+	if gotBody["model"] != "qwen" || gotBody["prompt"] != "anything" {
+		t.Errorf("vllm body = %v", gotBody)
+	}
+
+	down, _ := New(SpecLlamaCpp, "http://127.0.0.1:1/v1", "", "")
+	if n := down.Count("hello world"); n != (Heuristic{}).Count("hello world") {
+		t.Errorf("unreachable server should fall back to heuristic, got %d", n)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, ok := range []string{"", "heuristic", "llamacpp", "vllm", "tiktoken:/x.tiktoken"} {
+		if err := Validate(ok); err != nil {
+			t.Errorf("Validate(%q): %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"tiktoken:", "sentencepiece", "Heuristic"} {
+		if err := Validate(bad); err == nil {
+			t.Errorf("Validate(%q) should fail", bad)
+		}
+	}
+}
+
+func TestEstimateMessages(t *testing.T) {
+	body := []byte(`{
+		"system": [{"type":"text","text":"You are helpful"}],
+		"messages": [
+			{"role":"user","content":"read the file"},
+			{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Read","input":{"path":"/tmp/x"}}]},
+			{"role":"user","content":[
+				{"type":"tool_result","tool_use_id":"t1","content":[{"type":"text","text":"file body"}]},
+				{"type":"image","source":{"type":"base64","media_type":"image/png","data":"AAAA"}}
+			]}
+		],
+		"tools": [{"name":"Read","description":"Read a file","input_schema":{"type":"object"}}]
+	}`)
+	counter := &recordingTokenizer{}
+	n, err := EstimateMessages(counter, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counter.calls != 1 {
+		t.Errorf("Count called %d times, want 1", counter.calls)
+	}
+	for _, want := range []string{"You are helpful", "read the file", `Read {"path":"/tmp/x"}`, "file body", "Read a file", `{"type":"object"}`} {
+		if !strings.Contains(counter.text, want) {
+			t.Errorf("counted text missing %q:\n%s", want, counter.text)
+		}
+	}
+	if strings.Contains(counter.text, "AAAA") {
+		t.Error("image data should not be counted as text")
+	}
+	wantFixed := requestOverhead + 3*messageOverhead + toolOverhead + imageTokens
+	if n != wantFixed+len(counter.text) {
+		t.Errorf("estimate = %d, want %d", n, wantFixed+len(counter.text))
+	}
+
+	if _, err := EstimateMessages(Heuristic{}, []byte("not json")); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+// recordingTokenizer counts one token per byte and records its input.
+type recordingTokenizer struct {
+	calls int
+	text  string
+}
+
+func (r *recordingTokenizer) Name() string { return "recording" }
+
+func (r *recordingTokenizer) Count(text string) int {
+	r.calls++
+	r.text += text
+	return len(text)
+}
@@ -0,0 +1,343 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Exporter defaults.
+const (
+	DefaultServiceName = "claude-hybrid"
+	DefaultInterval    = 5 * time.Second
+
+	maxQueued     = 2048 // spans waiting for export; more are dropped
+	maxBatch      = 512  // spans per export request
+	exportTimeout = 10 * time.Second
+)
+
+// Config selects where spans are exported.
+type Config struct {
+	Endpoint    string            // OTLP/HTTP traces URL, e.g. http://localhost:4318/v1/traces; empty disables tracing
+	Headers     map[string]string // sent with every export, e.g. an API key for a hosted collector
+	ServiceName string            // service.name resource attribute (default DefaultServiceName)
+	Interval    time.Duration     // how often queued spans are sent (default DefaultInterval)
+}
+
+// TracesURL turns a collector base URL (the OTEL_EXPORTER_OTLP_ENDPOINT
+// form) into its traces URL by appending /v1/traces, unless base already
+// ends with it.
+func TracesURL(base string) string {
+	base = strings.TrimRight(base, "/")
+	if base == "" || strings.HasSuffix(base, "/v1/traces") {
+		return base
+	}
+	return base + "/v1/traces"
+}
+
+// WithEnv overlays the standard OpenTelemetry environment variables on c:
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT, OTEL_EXPORTER_OTLP_ENDPOINT,
+// OTEL_EXPORTER_OTLP_HEADERS (and the _TRACES_ variant) and
+// OTEL_SERVICE_NAME. OTEL_SDK_DISABLED=true or OTEL_TRACES_EXPORTER=none
+// clear the endpoint, disabling tracing.
+func (c Config) WithEnv(getenv func(string) string) Config {
+	if v := getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); v != "" {
+		c.Endpoint = v
+	} else if v := getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); v != "" {
+		c.Endpoint = TracesURL(v)
+	}
+	for _, name := range []string{"OTEL_EXPORTER_OTLP_HEADERS", "OTEL_EXPORTER_OTLP_TRACES_HEADERS"} {
+		for k, v := range parseHeaders(getenv(name)) {
+			if c.Headers == nil {
+				c.Headers = make(map[string]string)
+			}
+			c.Headers[k] = v
+		}
+	}
+	if v := getenv("OTEL_SERVICE_NAME"); v != "" {
+		c.ServiceName = v
+	}
+	if strings.EqualFold(getenv("OTEL_SDK_DISABLED"), "true") || getenv("OTEL_TRACES_EXPORTER") == "none" {
+		c.Endpoint = ""
+	}
+	return c
+}
+
+// parseHeaders parses the OTEL_EXPORTER_OTLP_HEADERS format:
+// comma-separated key=value pairs with URL-encoded values.
+func parseHeaders(s string) map[string]string {
+	if s == "" {
+		return nil
+	}
+	h := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			continue
+		}
+		if dv, err := url.QueryUnescape(strings.TrimSpace(v)); err == nil {
+			v = dv
+		}
+		h[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return h
+}
+
+// Tracer creates spans and exports them in the background.
+type Tracer struct {
+	exp *exporter
+}
+
+// New starts a tracer exporting to cfg.Endpoint. It returns nil, a tracer
+// that records nothing, when the endpoint is empty.
+func New(cfg Config) *Tracer {
+	if cfg.Endpoint == "" {
+		return nil
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = DefaultServiceName
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultInterval
+	}
+	e := &exporter{
+		cfg:    cfg,
+		client: &http.Client{Timeout: exportTimeout},
+		kick:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go e.run()
+	return &Tracer{exp: e}
+}
+
+// Shutdown exports the spans still queued and stops the tracer. Spans ended
+// afterwards are dropped.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	t.exp.once.Do(func() { close(t.exp.stop) })
+	select {
+	case <-t.exp.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats reports spans exported and dropped (queue full or export failed).
+func (t *Tracer) Stats() (exported, dropped int64) {
+	if t == nil {
+		return 0, 0
+	}
+	t.exp.mu.Lock()
+	defer t.exp.mu.Unlock()
+	return t.exp.exported, t.exp.dropped
+}
+
+type exporter struct {
+	cfg    Config
+	client *http.Client
+
+	mu       sync.Mutex
+	queue    []*Span
+	stopped  bool
+	exported int64
+	dropped  int64
+	failing  bool // the last export failed; logged once per failure streak
+
+	kick chan struct{} // a full batch is waiting
+	stop chan struct{}
+	once sync.Once
+	done chan struct{}
+}
+
+func (e *exporter) enqueue(s *Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stopped || len(e.queue) >= maxQueued {
+		e.dropped++
+		return
+	}
+	e.queue = append(e.queue, s)
+	if len(e.queue) >= maxBatch {
+		select {
+		case e.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (e *exporter) run() {
+	defer close(e.done)
+	tick := time.NewTicker(e.cfg.Interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+		case <-e.kick:
+		case <-e.stop:
+			e.mu.Lock()
+			e.stopped = true
+			e.mu.Unlock()
+			for e.flush() {
+			}
+			return
+		}
+		for e.flush() {
+		}
+	}
+}
+
+// flush exports up to one batch and reports whether more are queued.
+func (e *exporter) flush() bool {
+	e.mu.Lock()
+	n := min(len(e.queue), maxBatch)
+	batch := e.queue[:n:n]
+	e.queue = e.queue[n:]
+	more := len(e.queue) > 0
+	e.mu.Unlock()
+	if n == 0 {
+		return false
+	}
+
+	err := e.post(batch)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err != nil {
+		e.dropped += int64(n)
+		if !e.failing {
+			log.Printf("[TRACING] export to %s failed: %v (dropping spans until it recovers)", e.cfg.Endpoint, err)
+		}
+		e.failing = true
+		return more
+	}
+	if e.failing {
+		log.Printf("[TRACING] export to %s recovered", e.cfg.Endpoint)
+	}
+	e.failing = false
+	e.exported += int64(n)
+	return more
+}
+
+func (e *exporter) post(batch []*Span) error {
+	body, err := json.Marshal(e.encode(batch))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", e.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+// OTLP/JSON request shapes (opentelemetry-proto, JSON mapping). IDs are
+// hex strings and 64-bit integers decimal strings.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID      string         `json:"traceId"`
+		SpanID       string         `json:"spanId"`
+		ParentSpanID string         `json:"parentSpanId,omitempty"`
+		Name         string         `json:"name"`
+		Kind         Kind           `json:"kind"`
+		Start        string         `json:"startTimeUnixNano"`
+		End          string         `json:"endTimeUnixNano"`
+		Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+		Status       otlpStatus     `json:"status"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"` // 0 unset, 2 error
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string                 `json:"key"`
+		Value map[string]interface{} `json:"value"`
+	}
+)
+
+func (e *exporter) encode(batch []*Span) otlpRequest {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, s := range batch {
+		o := otlpSpan{
+			TraceID: hex.EncodeToString(s.ctx.TraceID[:]),
+			SpanID:  hex.EncodeToString(s.ctx.SpanID[:]),
+			Name:    s.name,
+			Kind:    s.kind,
+			Start:   strconv.FormatInt(s.start.UnixNano(), 10),
+			End:     strconv.FormatInt(s.end.UnixNano(), 10),
+		}
+		if s.parent != (SpanID{}) {
+			o.ParentSpanID = hex.EncodeToString(s.parent[:])
+		}
+		for _, a := range s.attrs {
+			o.Attributes = append(o.Attributes, keyValue(a.key, a.value))
+		}
+		if s.failed {
+			o.Status = otlpStatus{Code: 2, Message: s.errMsg}
+		}
+		spans = append(spans, o)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpKeyValue{keyValue("service.name", e.cfg.ServiceName)}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "github.com/peter-wagstaff/claude-hybrid-router"}, Spans: spans}},
+	}}}
+}
+
+func keyValue(key string, v interface{}) otlpKeyValue {
+	var value map[string]interface{}
+	switch v := v.(type) {
+	case string:
+		value = map[string]interface{}{"stringValue": v}
+	case bool:
+		value = map[string]interface{}{"boolValue": v}
+	case int64:
+		value = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		value = map[string]interface{}{"doubleValue": v}
+	default:
+		value = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+	}
+	return otlpKeyValue{Key: key, Value: value}
+}
@@ -0,0 +1,154 @@
+// Package tracing records spans and exports them to an OpenTelemetry
+// collector with OTLP over HTTP, JSON encoded. It implements the small part
+// of the OpenTelemetry SDK the proxy needs: W3C trace context, spans with
+// attributes and status, and a batching exporter.
+//
+// A nil *Tracer and a nil *Span are valid and record nothing, so callers
+// don't check whether tracing is enabled.
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// TraceID and SpanID are W3C trace context identifiers.
+type (
+	TraceID [16]byte
+	SpanID  [8]byte
+)
+
+// SpanContext identifies a span for propagation. The zero value is invalid
+// and starts a new trace.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+}
+
+// IsValid reports whether sc has non-zero IDs.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Traceparent formats sc as a W3C traceparent header value, sampled.
+func (sc SpanContext) Traceparent() string {
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-01"
+}
+
+// ParseTraceparent parses a W3C traceparent header value. Unknown future
+// versions are accepted if their first four fields parse, as the
+// specification asks.
+func ParseTraceparent(h string) (SpanContext, bool) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(h), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		(parts[0] == "00" && len(parts) != 4) ||
+		len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return SpanContext{}, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return SpanContext{}, false
+	}
+	return sc, sc.IsValid()
+}
+
+// Kind is the OTLP span kind.
+type Kind int
+
+// Span kinds, numbered as in OTLP.
+const (
+	KindInternal Kind = 1
+	KindServer   Kind = 2
+	KindClient   Kind = 3
+)
+
+// Span is one timed operation. Spans are not safe for concurrent use; each
+// is owned by the goroutine handling its request.
+type Span struct {
+	tracer *Tracer
+	name   string
+	kind   Kind
+	ctx    SpanContext
+	parent SpanID
+	start  time.Time
+	end    time.Time
+	attrs  []attr
+	errMsg string
+	failed bool
+	ended  bool
+}
+
+type attr struct {
+	key   string
+	value interface{} // string, bool, int64 or float64
+}
+
+// Start begins a span named name. A valid parent makes it a child of that
+// span, in the parent's trace; otherwise it starts a new trace.
+func (t *Tracer) Start(parent SpanContext, name string, kind Kind) *Span {
+	if t == nil {
+		return nil
+	}
+	s := &Span{tracer: t, name: name, kind: kind, start: time.Now()}
+	if parent.IsValid() {
+		s.ctx.TraceID, s.parent = parent.TraceID, parent.SpanID
+	} else {
+		rand.Read(s.ctx.TraceID[:])
+	}
+	rand.Read(s.ctx.SpanID[:])
+	return s
+}
+
+// Context returns the span's identity for child spans and propagation. It
+// is the zero SpanContext for a nil span.
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.ctx
+}
+
+// SetAttr sets an attribute. value is a string, bool, integer or float;
+// other types are formatted as strings by the exporter. Spans can't change
+// once ended.
+func (s *Span) SetAttr(key string, value interface{}) {
+	if s == nil || s.ended {
+		return
+	}
+	switch v := value.(type) {
+	case int:
+		value = int64(v)
+	case float32:
+		value = float64(v)
+	}
+	for i := range s.attrs {
+		if s.attrs[i].key == key {
+			s.attrs[i].value = value
+			return
+		}
+	}
+	s.attrs = append(s.attrs, attr{key, value})
+}
+
+// SetError marks the span failed with a short description.
+func (s *Span) SetError(msg string) {
+	if s == nil || s.ended {
+		return
+	}
+	s.failed, s.errMsg = true, msg
+}
+
+// End finishes the span and queues it for export. Later calls do nothing.
+func (s *Span) End() {
+	if s == nil || s.ended {
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.tracer.exp.enqueue(s)
+}
@@ -0,0 +1,146 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTraceparent(t *testing.T) {
+	const h = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, ok := ParseTraceparent(h)
+	if !ok {
+		t.Fatal("valid traceparent rejected")
+	}
+	if got := sc.Traceparent(); got != h {
+		t.Errorf("round trip = %q", got)
+	}
+	for _, bad := range []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-4bf92f3577b34da6a3ce929d0e0e473g-00f067aa0ba902b7-01",
+	} {
+		if _, ok := ParseTraceparent(bad); ok {
+			t.Errorf("accepted %q", bad)
+		}
+	}
+	if _, ok := ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"); !ok {
+		t.Error("future version with extra fields rejected")
+	}
+}
+
+func TestWithEnv(t *testing.T) {
+	env := map[string]string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318/",
+		"OTEL_EXPORTER_OTLP_HEADERS":  "x-api-key=a%20b, x-team = infra",
+		"OTEL_SERVICE_NAME":           "router",
+	}
+	c := Config{Endpoint: "http://file:4318/v1/traces", ServiceName: "from-file"}.WithEnv(func(k string) string { return env[k] })
+	if c.Endpoint != "http://collector:4318/v1/traces" || c.ServiceName != "router" {
+		t.Errorf("config = %+v", c)
+	}
+	if c.Headers["x-api-key"] != "a b" || c.Headers["x-team"] != "infra" {
+		t.Errorf("headers = %v", c.Headers)
+	}
+
+	env["OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"] = "http://traces:4318/custom"
+	if c := (Config{}).WithEnv(func(k string) string { return env[k] }); c.Endpoint != "http://traces:4318/custom" {
+		t.Errorf("traces endpoint = %q, want it used as is", c.Endpoint)
+	}
+	env["OTEL_TRACES_EXPORTER"] = "none"
+	if c := (Config{}).WithEnv(func(k string) string { return env[k] }); c.Endpoint != "" {
+		t.Errorf("OTEL_TRACES_EXPORTER=none left endpoint %q", c.Endpoint)
+	}
+}
+
+func TestNilTracerRecordsNothing(t *testing.T) {
+	var tr *Tracer
+	s := tr.Start(SpanContext{}, "x", KindServer)
+	s.SetAttr("k", 1)
+	s.SetError("boom")
+	s.End()
+	if s.Context().IsValid() {
+		t.Error("nil span has a valid context")
+	}
+	if New(Config{}) != nil {
+		t.Error("New without an endpoint should return nil")
+	}
+	if err := tr.Shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestExportOTLPJSON(t *testing.T) {
+	var mu sync.Mutex
+	var bodies [][]byte
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, b)
+		auth = r.Header.Get("Authorization")
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	tr := New(Config{Endpoint: srv.URL + "/v1/traces", Headers: map[string]string{"Authorization": "Bearer k"}, Interval: time.Hour})
+	parent, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req := tr.Start(parent, "POST /v1/messages", KindServer)
+	req.SetAttr("hybrid.label", "fast")
+	req.SetAttr("gen_ai.usage.output_tokens", 5)
+	req.SetAttr("hybrid.stream", true)
+	call := tr.Start(req.Context(), "provider ollama", KindClient)
+	call.SetError("CONNECTION")
+	call.End()
+	req.End()
+	req.End() // ignored
+	if err := tr.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(bodies) != 1 || auth != "Bearer k" {
+		t.Fatalf("%d exports, Authorization %q", len(bodies), auth)
+	}
+	var got otlpRequest
+	if err := json.Unmarshal(bodies[0], &got); err != nil {
+		t.Fatal(err)
+	}
+	rs := got.ResourceSpans[0]
+	if v := rs.Resource.Attributes[0]; v.Key != "service.name" || v.Value["stringValue"] != DefaultServiceName {
+		t.Errorf("resource = %+v", rs.Resource)
+	}
+	spans := rs.ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("spans = %+v", spans)
+	}
+	c, r := spans[0], spans[1]
+	if r.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || r.ParentSpanID != "00f067aa0ba902b7" || r.Kind != KindServer {
+		t.Errorf("request span = %+v", r)
+	}
+	if c.TraceID != r.TraceID || c.ParentSpanID != r.SpanID || c.Status.Code != 2 || c.Status.Message != "CONNECTION" {
+		t.Errorf("child span = %+v", c)
+	}
+	want := map[string]interface{}{"stringValue": "fast"}
+	if a := r.Attributes[0]; a.Key != "hybrid.label" || a.Value["stringValue"] != want["stringValue"] {
+		t.Errorf("attribute = %+v", a)
+	}
+	if a := r.Attributes[1]; a.Value["intValue"] != "5" {
+		t.Errorf("int attribute = %+v, want a decimal string", a)
+	}
+	if a := r.Attributes[2]; a.Value["boolValue"] != true {
+		t.Errorf("bool attribute = %+v", a)
+	}
+	if exported, dropped := tr.Stats(); exported != 2 || dropped != 0 {
+		t.Errorf("stats = %d exported, %d dropped", exported, dropped)
+	}
+}
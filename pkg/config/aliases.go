@@ -0,0 +1,156 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Aliases and label groups are marker labels that name other labels, so
+// prompts and agent files keep working while the backends behind them
+// change. An alias points at one label or label group; a label group lists
+// labels (or aliases of labels) to try in order.
+
+// checkAliases validates cfg's aliases and label groups against the
+// resolved models.
+func checkAliases(cfg *ProvidersConfig, models map[string]ResolvedModel, patterns []labelPattern) error {
+	isLabel := labelChecker(models, patterns)
+	for _, name := range sortedKeys(cfg.Aliases) {
+		target := cfg.Aliases[name]
+		if m, ok := models[name]; ok && m.Pattern == "" {
+			return fmt.Errorf("alias %q is also a model label", name)
+		}
+		if _, ok := cfg.LabelGroups[name]; ok {
+			return fmt.Errorf("%q is both an alias and a label group", name)
+		}
+		if _, ok := cfg.Aliases[target]; ok {
+			return fmt.Errorf("alias %q points at alias %q; point it at a label", name, target)
+		}
+		if _, ok := cfg.Schedules[target]; ok {
+			return fmt.Errorf("alias %q points at schedule %q; use the schedule's name", name, target)
+		}
+		if _, ok := cfg.LabelGroups[target]; !ok && !isLabel(target) {
+			return fmt.Errorf("alias %q: label %q not defined", name, target)
+		}
+	}
+	for _, name := range sortedKeys(cfg.LabelGroups) {
+		members := cfg.LabelGroups[name]
+		if m, ok := models[name]; ok && m.Pattern == "" {
+			return fmt.Errorf("label group %q is also a model label", name)
+		}
+		if len(members) == 0 {
+			return fmt.Errorf("label group %q is empty", name)
+		}
+		for _, member := range members {
+			if target, ok := cfg.Aliases[member]; ok {
+				member = target
+			}
+			if _, ok := cfg.LabelGroups[member]; ok {
+				return fmt.Errorf("label group %q: %q is a label group; groups can't nest", name, member)
+			}
+			if !isLabel(member) {
+				return fmt.Errorf("label group %q: label %q not defined", name, member)
+			}
+		}
+	}
+	return nil
+}
+
+// labelChecker reports whether a name is a configured or wildcard label.
+func labelChecker(models map[string]ResolvedModel, patterns []labelPattern) func(string) bool {
+	return func(name string) bool {
+		if m, ok := models[name]; ok && m.Pattern == "" {
+			return true
+		}
+		_, ok := matchPattern(patterns, name)
+		return ok
+	}
+}
+
+// routableChecker reports whether a name is something a marker can route
+// to: a label, alias, label group or schedule.
+func routableChecker(cfg *ProvidersConfig, isLabel func(string) bool) func(string) bool {
+	return func(name string) bool {
+		_, alias := cfg.Aliases[name]
+		_, group := cfg.LabelGroups[name]
+		_, sched := cfg.Schedules[name]
+		return alias || group || sched || isLabel(name)
+	}
+}
+
+// Candidates returns the models a marker label routes to, in the order to
+// try them: one for a label or an alias of one, or each member of a label
+// group. A tier (auto:NAME) is first replaced by its small label, a judge
+// (judge:NAME) by its default, and a schedule by its current target. Each carries
+// its own label, so budgets and metrics follow the backend rather than the
+// name in the marker.
+func (r *ModelResolver) Candidates(label string) ([]ResolvedModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name := label
+	if tier, ok := strings.CutPrefix(name, AutoTierPrefix); ok {
+		// Without a request to classify, a tier is its small label.
+		if t, ok := r.cfg.Tiers[tier]; ok {
+			name = t.Small
+		}
+	} else if judge, ok := strings.CutPrefix(name, JudgePrefix); ok {
+		// Likewise, a judge is its default.
+		if j, ok := r.cfg.Judges[judge]; ok {
+			name = j.Default
+		}
+	}
+	if s, ok := r.schedules[name]; ok {
+		now := r.now()
+		if name, ok = s.pick(now); !ok {
+			return nil, fmt.Errorf("schedule %q has no rule for %s", label, now.Format("Mon 15:04"))
+		}
+	}
+	if target, ok := r.cfg.Aliases[name]; ok {
+		name = target
+	}
+	names := []string{name}
+	if members, ok := r.cfg.LabelGroups[name]; ok {
+		names = members
+	}
+	out := make([]ResolvedModel, 0, len(names))
+	for _, n := range names {
+		if target, ok := r.cfg.Aliases[n]; ok {
+			n = target
+		}
+		m, ok := r.lookup(n)
+		if !ok {
+			return nil, fmt.Errorf("unknown model label %q", label)
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// Aliases returns every alias, label group and schedule with what it
+// names; a schedule lists each rule's target.
+func (r *ModelResolver) Aliases() map[string][]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make(map[string][]string, len(r.cfg.Aliases)+len(r.cfg.LabelGroups))
+	for name, target := range r.cfg.Aliases {
+		out[name] = []string{target}
+	}
+	for name, members := range r.cfg.LabelGroups {
+		out[name] = members
+	}
+	for name, rules := range r.cfg.Schedules {
+		for _, rule := range rules {
+			out[name] = append(out[name], rule.Label)
+		}
+	}
+	return out
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
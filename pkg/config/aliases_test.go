@@ -0,0 +1,89 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAliasesAndLabelGroups(t *testing.T) {
+	cfg, r := loadTestConfig(t, `
+providers:
+  - name: ollama
+    endpoint: http://localhost:11434/v1
+    models:
+      qwen_coder_32b: qwen2.5-coder:32b
+      "ollama/*": {model: "{1}"}
+  - name: groq
+    endpoint: https://api.groq.com/openai/v1
+    models:
+      groq_llama: llama-3.3-70b-versatile
+aliases:
+  coder: qwen_coder_32b
+  fast: cheap
+  gemma: ollama/gemma3:27b
+label_groups:
+  cheap: [groq_llama, coder, ollama/llama3]
+`)
+	if m, err := r.Resolve("coder"); err != nil || m.Label != "qwen_coder_32b" || m.Model != "qwen2.5-coder:32b" {
+		t.Errorf("Resolve(coder) = %+v, %v", m, err)
+	}
+	if m, _ := r.Resolve("gemma"); m.Model != "gemma3:27b" {
+		t.Errorf("alias of a pattern label: model %q", m.Model)
+	}
+	for _, label := range []string{"cheap", "fast"} {
+		c, err := r.Candidates(label)
+		if err != nil {
+			t.Fatalf("Candidates(%s): %v", label, err)
+		}
+		var got []string
+		for _, m := range c {
+			got = append(got, m.Label+"="+m.Model)
+		}
+		if s := strings.Join(got, " "); s != "groq_llama=llama-3.3-70b-versatile qwen_coder_32b=qwen2.5-coder:32b ollama/llama3=llama3" {
+			t.Errorf("Candidates(%s) = %s", label, s)
+		}
+	}
+	if m, _ := r.Resolve("cheap"); m.Label != "groq_llama" {
+		t.Errorf("Resolve(cheap) = %s, want the first member", m.Label)
+	}
+	if c, _ := r.Candidates("groq_llama"); len(c) != 1 {
+		t.Errorf("a plain label has %d candidates", len(c))
+	}
+	if a := r.Aliases(); len(a) != 4 || a["cheap"][1] != "coder" {
+		t.Errorf("Aliases() = %v", a)
+	}
+
+	// A profile re-points one alias and keeps the rest.
+	p, err := NewModelResolver(cfg.WithProfile(&ProvidersConfig{Aliases: map[string]string{"coder": "groq_llama"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := p.Resolve("coder"); m.Label != "groq_llama" {
+		t.Errorf("profile alias: coder → %s", m.Label)
+	}
+	if m, _ := p.Resolve("gemma"); m.Model != "gemma3:27b" {
+		t.Errorf("profile should keep other aliases: gemma → %q", m.Model)
+	}
+}
+
+func TestAliasErrors(t *testing.T) {
+	tests := []struct{ extra, want string }{
+		{"aliases: {c: nope}", `alias "c": label "nope" not defined`},
+		{"aliases: {a: b}", `alias "a" is also a model label`},
+		{"aliases: {x: b, y: x}", `alias "y" points at alias "x"`},
+		{"aliases: {x: b}\nlabel_groups: {x: [a]}", `"x" is both an alias and a label group`},
+		{"label_groups: {g: []}", `label group "g" is empty`},
+		{"label_groups: {g: [a, nope]}", `label group "g": label "nope" not defined`},
+		{"label_groups: {g: [a], h: [g]}", `label group "h": "g" is a label group`},
+		{"label_groups: {a: [b]}", `label group "a" is also a model label`},
+	}
+	for _, tt := range tests {
+		cfg, err := parseConfig([]byte("providers:\n  - name: p\n    endpoint: http://p/v1\n    models: {a: a, b: b}\n"+tt.extra+"\n"), "config.yaml")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.extra, err)
+		}
+		if _, err := NewModelResolver(cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.extra, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Package config loads claude-hybrid's config.yaml and resolves marker
+// labels to provider models the way the proxy does.
+//
+// LoadConfig (or LoadConfigWithProfile, which also applies a named
+// profile) parses and validates a config; NewModelResolver
+// turns it into a ModelResolver, whose Resolve and Candidates follow
+// aliases, label groups, schedules and pattern labels to ResolvedModels
+// carrying each label's endpoint, key source, transforms, price and budget.
+// Import converts other routers' configs, Schema describes the file for
+// editors, and Limits, ParseClients and ParseDNSOverrides cover the
+// listener settings.
+//
+// It is a public package. Its exported identifiers follow semantic
+// versioning with the module: they are not removed or changed
+// incompatibly within a major version, and new config keys arrive as new
+// fields whose zero value keeps the old behavior. The YAML format is held
+// to the same rule. The proxy's built-in limits and timeouts are internal
+// and may change in any release; DefaultLimits reports the current ones.
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/defaults"
+)
+
+// DefaultIntercept lists the CONNECT targets the proxy decrypts when the
+// config sets no intercept list. Every other host (telemetry, Sentry,
+// statsig, plugin marketplaces) is tunneled without MITM. Patterns are exact
+// hosts, "*.example.com" for any subdomain, or "*" for everything.
+var DefaultIntercept = []string{"api.anthropic.com"}
+
+// LANClients is the client allowlist used when the proxy listens beyond
+// loopback and no allowed_clients are configured: private and link-local
+// ranges, so a proxy shared on a LAN isn't open to the internet.
+var LANClients = []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7", "fe80::/10"}
+
+// ParseClients parses allowed_clients entries, each a CIDR prefix or a
+// single address.
+func ParseClients(list []string) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("allowed_clients: %q is not an address or CIDR", s)
+			}
+			out = append(out, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		pre, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("allowed_clients: %q is not an address or CIDR", s)
+		}
+		out = append(out, pre.Masked())
+	}
+	return out, nil
+}
+
+// ParseDNSOverrides checks and normalizes dns_overrides: host → address.
+// Keys are lowercased host names or IP literals, without brackets. Values
+// become "host:port", a bare host that keeps the CONNECT port, or
+// "http://host:port" for a plain-HTTP gateway.
+func ParseDNSOverrides(m map[string]string) (map[string]string, error) {
+	out := make(map[string]string, len(m))
+	for host, addr := range m {
+		key := strings.ToLower(strings.Trim(strings.TrimSpace(host), "[]"))
+		if key == "" || strings.ContainsAny(key, "/ ") || (strings.Contains(key, ":") && !isIPv6(key)) {
+			return nil, fmt.Errorf("dns_overrides: %q is not a host name (give the port in the address)", host)
+		}
+		addr = strings.TrimSpace(addr)
+		plain := strings.HasPrefix(addr, "http://")
+		rest := strings.TrimPrefix(addr, "http://")
+		h, port, err := net.SplitHostPort(rest)
+		switch {
+		case err == nil:
+			if n, perr := strconv.Atoi(port); perr != nil || n < 1 || n > 65535 {
+				return nil, fmt.Errorf("dns_overrides: %s: bad port in %q", host, addr)
+			}
+		case plain:
+			return nil, fmt.Errorf("dns_overrides: %s: %q needs a port", host, addr)
+		case strings.Contains(rest, ":") && !isIPv6(strings.Trim(rest, "[]")):
+			return nil, fmt.Errorf("dns_overrides: %s: %q is not host or host:port", host, addr)
+		default:
+			h = strings.Trim(rest, "[]")
+		}
+		if h == "" || strings.ContainsAny(h, "/ ") {
+			return nil, fmt.Errorf("dns_overrides: %s: %q is not host or host:port", host, addr)
+		}
+		switch {
+		case plain:
+			out[key] = "http://" + net.JoinHostPort(h, port)
+		case port != "":
+			out[key] = net.JoinHostPort(h, port)
+		default:
+			out[key] = h
+		}
+	}
+	return out, nil
+}
+
+func isIPv6(s string) bool {
+	a, err := netip.ParseAddr(s)
+	return err == nil && a.Is6()
+}
+
+// ClientRateLimit caps how fast each client IP may use the proxy. CONNECTs
+// and requests inside tunnels both count.
+type ClientRateLimit struct {
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+	Burst             int `yaml:"burst,omitempty"` // default requests_per_minute
+}
+
+// Limits holds the tunable resource limits. In config.yaml they live under
+// limits:, and each can be overridden by a command-line flag. Zero fields
+// take the DefaultLimits value.
+type Limits struct {
+	MaxBodyBytes       int64         `yaml:"max_body_bytes,omitempty"`       // largest request or buffered response body
+	UpstreamTimeout    time.Duration `yaml:"upstream_timeout,omitempty"`     // per-request timeout for Anthropic and providers
+	ClientRecvTimeout  time.Duration `yaml:"client_recv_timeout,omitempty"`  // idle time allowed on a client tunnel
+	MaxConcurrent      int           `yaml:"max_concurrent,omitempty"`       // tunnels handled at once
+	MaxQueued          int           `yaml:"max_queued,omitempty"`           // tunnels waiting for a slot (-1 = refuse at once)
+	QueueTimeout       time.Duration `yaml:"queue_timeout,omitempty"`        // longest a tunnel waits for a slot
+	StreamBufferBytes  int           `yaml:"stream_buffer_bytes,omitempty"`  // translated SSE buffered per slow client
+	ClientWriteTimeout time.Duration `yaml:"client_write_timeout,omitempty"` // drop a stream whose client can't take a write this long
+}
+
+// DefaultLimits returns the built-in limits.
+func DefaultLimits() Limits {
+	return Limits{
+		MaxBodyBytes:       defaults.MaxBodyBytes,
+		UpstreamTimeout:    defaults.UpstreamTimeout,
+		ClientRecvTimeout:  defaults.ClientRecvTimeout,
+		MaxConcurrent:      defaults.MaxProxyGoroutines,
+		MaxQueued:          defaults.MaxQueuedTunnels,
+		QueueTimeout:       defaults.QueueTimeout,
+		StreamBufferBytes:  defaults.StreamBufferBytes,
+		ClientWriteTimeout: defaults.ClientWriteTimeout,
+	}
+}
+
+// Merge returns l with every non-zero field of o applied on top.
+func (l Limits) Merge(o Limits) Limits {
+	if o.MaxBodyBytes > 0 {
+		l.MaxBodyBytes = o.MaxBodyBytes
+	}
+	if o.UpstreamTimeout > 0 {
+		l.UpstreamTimeout = o.UpstreamTimeout
+	}
+	if o.ClientRecvTimeout > 0 {
+		l.ClientRecvTimeout = o.ClientRecvTimeout
+	}
+	if o.MaxConcurrent > 0 {
+		l.MaxConcurrent = o.MaxConcurrent
+	}
+	if o.MaxQueued != 0 {
+		l.MaxQueued = o.MaxQueued
+	}
+	if o.QueueTimeout > 0 {
+		l.QueueTimeout = o.QueueTimeout
+	}
+	if o.StreamBufferBytes > 0 {
+		l.StreamBufferBytes = o.StreamBufferBytes
+	}
+	if o.ClientWriteTimeout > 0 {
+		l.ClientWriteTimeout = o.ClientWriteTimeout
+	}
+	return l
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Embedding server wire formats.
+const (
+	EmbedOpenAI = "openai" // POST {endpoint}/embeddings, also served by Ollama, vLLM and TEI
+	EmbedOllama = "ollama" // Ollama's native POST /api/embed
+	EmbedTEI    = "tei"    // Hugging Face text-embeddings-inference's native POST /embed
+)
+
+// DefaultEmbeddingBatch is how many inputs go to the backend per request
+// when batch_size isn't set. It matches TEI's default max_client_batch_size.
+const DefaultEmbeddingBatch = 32
+
+// EmbeddingConfig is one label under a provider's embedding_models. Like a
+// model, it can be written as just the backend model name.
+type EmbeddingConfig struct {
+	Model     string `yaml:"model"`
+	Format    string `yaml:"format,omitempty"`     // EmbedOpenAI (default), EmbedOllama or EmbedTEI
+	BatchSize int    `yaml:"batch_size,omitempty"` // inputs per backend request (default 32)
+}
+
+// UnmarshalYAML allows EmbeddingConfig to be a plain string or a map.
+func (ec *EmbeddingConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		ec.Model = value.Value
+		return nil
+	}
+	type raw EmbeddingConfig
+	return value.Decode((*raw)(ec))
+}
+
+// MarshalYAML writes an EmbeddingConfig with no settings as a plain string.
+func (ec EmbeddingConfig) MarshalYAML() (interface{}, error) {
+	type raw EmbeddingConfig
+	if reflect.DeepEqual(ec, EmbeddingConfig{Model: ec.Model}) {
+		return ec.Model, nil
+	}
+	return raw(ec), nil
+}
+
+// ResolvedEmbedding is an embedding label with its provider's settings.
+// Chat-only fields of the embedded ResolvedModel are left empty.
+type ResolvedEmbedding struct {
+	ResolvedModel
+	Format    string // EmbedOpenAI, EmbedOllama or EmbedTEI
+	BatchSize int    // inputs per backend request
+}
+
+// resolveEmbeddings resolves every provider's embedding_models. Their
+// labels can't repeat chat labels, so a label names one kind of model.
+func resolveEmbeddings(cfg *ProvidersConfig, models map[string]ResolvedModel) (map[string]ResolvedEmbedding, error) {
+	out := make(map[string]ResolvedEmbedding)
+	for _, p := range cfg.Providers {
+		if len(p.EmbeddingModels) == 0 {
+			continue
+		}
+		base, p, err := resolveProvider(cfg, p)
+		if err != nil {
+			return nil, err
+		}
+		for _, label := range sortedKeys(p.EmbeddingModels) {
+			ec := p.EmbeddingModels[label]
+			if _, ok := models[label]; ok {
+				return nil, fmt.Errorf("duplicate model label %q", label)
+			}
+			if _, ok := out[label]; ok {
+				return nil, fmt.Errorf("duplicate model label %q", label)
+			}
+			if isPattern(label) {
+				return nil, fmt.Errorf("embedding model %q: wildcard labels are for chat models only", label)
+			}
+			if ec.Model == "" {
+				return nil, fmt.Errorf("embedding model %q: missing model", label)
+			}
+			format := ec.Format
+			switch format {
+			case "":
+				format = EmbedOpenAI
+			case EmbedOpenAI, EmbedOllama, EmbedTEI:
+			default:
+				return nil, fmt.Errorf("embedding model %q: unknown format %q (want %s, %s or %s)", label, ec.Format, EmbedOpenAI, EmbedOllama, EmbedTEI)
+			}
+			if base.API == APIAnthropic && format == EmbedOpenAI {
+				return nil, fmt.Errorf("embedding model %q: api: anthropic providers have no embeddings endpoint; set format", label)
+			}
+			if ec.BatchSize < 0 {
+				return nil, fmt.Errorf("embedding model %q: batch_size must not be negative", label)
+			}
+			batch := ec.BatchSize
+			if batch == 0 {
+				batch = DefaultEmbeddingBatch
+			}
+			m := base
+			m.Label, m.Model, m.KeepAlive = label, ec.Model, p.KeepAlive
+			out[label] = ResolvedEmbedding{ResolvedModel: m, Format: format, BatchSize: batch}
+		}
+	}
+	return out, nil
+}
+
+// Embedding looks up an embedding label.
+func (r *ModelResolver) Embedding(label string) (ResolvedEmbedding, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	e, ok := r.embeddings[label]
+	if !ok {
+		return ResolvedEmbedding{}, fmt.Errorf("unknown embedding model label %q", label)
+	}
+	return e, nil
+}
+
+// Embeddings returns every embedding label, sorted.
+func (r *ModelResolver) Embeddings() []ResolvedEmbedding {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]ResolvedEmbedding, 0, len(r.embeddings))
+	for _, e := range r.embeddings {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Label < out[j].Label })
+	return out
+}
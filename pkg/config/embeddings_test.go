@@ -0,0 +1,59 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEmbeddingModels(t *testing.T) {
+	_, r := loadTestConfig(t, `
+providers:
+  - name: ollama
+    endpoint: http://localhost:11434/v1
+    keep_alive: 10m
+    models:
+      fast: qwen2.5-coder:7b
+    embedding_models:
+      embed: nomic-embed-text
+  - name: tei
+    endpoint: http://localhost:8080
+    models: {}
+    embedding_models:
+      bge: {model: BAAI/bge-large-en-v1.5, format: tei, batch_size: 8}
+`)
+	e, err := r.Embedding("embed")
+	if err != nil || e.Model != "nomic-embed-text" || e.Format != EmbedOpenAI || e.BatchSize != DefaultEmbeddingBatch || e.KeepAlive != "10m" {
+		t.Errorf("Embedding(embed) = %+v, %v", e, err)
+	}
+	if e, err := r.Embedding("bge"); err != nil || e.Format != EmbedTEI || e.BatchSize != 8 || e.Endpoint != "http://localhost:8080" {
+		t.Errorf("Embedding(bge) = %+v, %v", e, err)
+	}
+	if _, err := r.Resolve("embed"); err == nil {
+		t.Error("embedding labels should not resolve as chat labels")
+	}
+	if _, err := r.Embedding("fast"); err == nil {
+		t.Error("chat labels should not resolve as embedding labels")
+	}
+	if n := len(r.Embeddings()); n != 2 {
+		t.Errorf("Embeddings() has %d labels, want 2", n)
+	}
+}
+
+func TestEmbeddingModelErrors(t *testing.T) {
+	tests := []struct{ extra, want string }{
+		{"    embedding_models: {a: e}", `duplicate model label "a"`},
+		{"    embedding_models: {e: {model: e, format: grpc}}", `unknown format "grpc"`},
+		{"    embedding_models: {e: {model: e, batch_size: -1}}", "must not be negative"},
+		{"    embedding_models: {'e*': e}", "wildcard labels are for chat models only"},
+		{"    embedding_models: {e: {format: tei}}", "missing model"},
+	}
+	for _, tt := range tests {
+		cfg, err := parseConfig([]byte("providers:\n  - name: p\n    endpoint: http://p/v1\n    models: {a: a, b: b}\n"+tt.extra+"\n"), "config.yaml")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.extra, err)
+		}
+		if _, err := NewModelResolver(cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.extra, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,372 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/defaults"
+	"gopkg.in/yaml.v3"
+)
+
+// Import sources accepted by Import.
+const (
+	ImportClaudeCodeRouter = "claude-code-router"
+	ImportYRouter          = "y-router"
+)
+
+// Import converts another router's config into a ProvidersConfig. The
+// report lists, one line each, what was mapped, changed or dropped, so the
+// user can review the result before using it. The returned config resolves
+// cleanly.
+func Import(from string, data []byte) (*ProvidersConfig, []string, error) {
+	var (
+		cfg    *ProvidersConfig
+		report []string
+		err    error
+	)
+	switch from {
+	case ImportClaudeCodeRouter:
+		cfg, report, err = importClaudeCodeRouter(data)
+	case ImportYRouter:
+		cfg, report, err = importYRouter(data)
+	default:
+		return nil, nil, fmt.Errorf("unknown import source %q (want %s or %s)", from, ImportClaudeCodeRouter, ImportYRouter)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := resolveModels(cfg); err != nil {
+		return nil, nil, fmt.Errorf("imported config is invalid: %w", err)
+	}
+	return cfg, report, nil
+}
+
+// MarshalConfig renders cfg as config.yaml content.
+func MarshalConfig(cfg *ProvidersConfig) ([]byte, error) {
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// ccrConfig is the subset of claude-code-router's config.json that maps
+// onto this router.
+type ccrConfig struct {
+	Providers []struct {
+		Name        string                     `json:"name"`
+		APIBaseURL  string                     `json:"api_base_url"`
+		APIKey      string                     `json:"api_key"`
+		Models      []string                   `json:"models"`
+		Transformer map[string]json.RawMessage `json:"transformer"`
+	} `json:"Providers"`
+	Router       map[string]json.RawMessage `json:"Router"`
+	APITimeoutMS json.Number                `json:"API_TIMEOUT_MS"`
+	ProxyURL     string                     `json:"PROXY_URL"`
+	CustomRouter string                     `json:"CUSTOM_ROUTER_PATH"`
+	Transformers []struct {
+		Path string `json:"path"`
+	} `json:"transformers"`
+}
+
+// ccrTransforms maps claude-code-router transformer names onto transforms
+// in this package. Unlisted names have no equivalent and are dropped.
+var ccrTransforms = map[string]string{
+	"openrouter":     "openrouter",
+	"deepseek":       "deepseek",
+	"groq":           "groq",
+	"tooluse":        "tooluse",
+	"enhancetool":    "enhancetool",
+	"cleancache":     "cleancache",
+	"reasoning":      "reasoning",
+	"forcereasoning": "forcereasoning",
+	"extrathinktag":  "extrathinktag",
+	"gemini":         "schema:gemini",
+	"customparams":   "customparams",
+}
+
+// ccrRouteLabels names the label created for each claude-code-router route.
+var ccrRouteLabels = []struct{ key, label string }{
+	{"default", "default"},
+	{"background", "background"},
+	{"think", "think"},
+	{"longContext", "long-context"},
+	{"webSearch", "web-search"},
+	{"image", "image"},
+}
+
+func importClaudeCodeRouter(data []byte) (*ProvidersConfig, []string, error) {
+	var src ccrConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&src); err != nil {
+		return nil, nil, fmt.Errorf("parse claude-code-router config: %w", err)
+	}
+	if len(src.Providers) == 0 {
+		return nil, nil, fmt.Errorf("claude-code-router config has no Providers")
+	}
+
+	cfg := &ProvidersConfig{}
+	var report []string
+	note := func(format string, args ...interface{}) {
+		report = append(report, fmt.Sprintf(format, args...))
+	}
+
+	// labelOf finds the label created for provider,model, for routes.
+	labelOf := map[string]string{}
+	taken := map[string]bool{}
+	for _, p := range src.Providers {
+		pc := ProviderConfig{
+			Name:     p.Name,
+			Endpoint: ccrEndpoint(p.APIBaseURL),
+			APIKey:   ccrEnvRef(p.APIKey),
+			Models:   map[string]ModelConfig{},
+		}
+		if pc.Endpoint != p.APIBaseURL {
+			note("provider %s: endpoint %s → %s", p.Name, p.APIBaseURL, pc.Endpoint)
+		}
+		if pc.APIKey != "" && !strings.Contains(pc.APIKey, "${") {
+			note("provider %s: api_key copied as plain text; consider ${ENV_VAR}", p.Name)
+		}
+
+		var base []string
+		if raw, ok := p.Transformer["use"]; ok {
+			base, pc.MaxTokens = ccrTransformList(raw, p.Name, note)
+		}
+		if len(base) > 0 {
+			pc.Transform = withSchema(base, p.Name)
+			note("provider %s: transform %v", p.Name, pc.Transform)
+		}
+
+		for _, model := range p.Models {
+			mc := ModelConfig{Model: model}
+			if raw, ok := p.Transformer[model]; ok {
+				var override struct {
+					Use json.RawMessage `json:"use"`
+				}
+				if json.Unmarshal(raw, &override) == nil && len(override.Use) > 0 {
+					extra, maxTokens := ccrTransformList(override.Use, p.Name+"/"+model, note)
+					// claude-code-router applies model transformers on top of
+					// the provider's; here a model list replaces it, so fold
+					// the provider's in.
+					mc.Transform = withSchema(appendMissing(base, extra), p.Name)
+					mc.MaxTokens = maxTokens
+				}
+			}
+			label := labelName(model[strings.LastIndex(model, "/")+1:])
+			if taken[label] {
+				label = labelName(p.Name + "-" + model)
+			}
+			taken[label] = true
+			pc.Models[label] = mc
+			labelOf[p.Name+","+model] = label
+			note("label %s → %s/%s", label, p.Name, model)
+		}
+		cfg.Providers = append(cfg.Providers, pc)
+	}
+
+	routed := false
+	for _, r := range ccrRouteLabels {
+		raw, ok := src.Router[r.key]
+		if !ok {
+			continue
+		}
+		var target string
+		if json.Unmarshal(raw, &target) != nil || target == "" {
+			continue
+		}
+		provider, model, _ := strings.Cut(target, ",")
+		i := providerIndex(cfg, provider)
+		if i < 0 {
+			note("Router.%s: provider %q not found, dropped", r.key, provider)
+			continue
+		}
+		if taken[r.label] {
+			note("Router.%s: label %s already used by a model, use %s instead", r.key, r.label, labelOf[target])
+			continue
+		}
+		mc, ok := cfg.Providers[i].Models[labelOf[target]]
+		if !ok {
+			mc = ModelConfig{Model: model}
+		}
+		taken[r.label] = true
+		cfg.Providers[i].Models[r.label] = mc
+		note("Router.%s → label %s (%s/%s)", r.key, r.label, provider, model)
+		routed = true
+	}
+	if routed {
+		note("routes are picked by marker, not by request class: put %s model=<label> --> in an agent's system prompt; unmarked requests still go to Anthropic", defaults.RouteMarker)
+	}
+	if _, ok := src.Router["longContextThreshold"]; ok {
+		note("Router.longContextThreshold dropped: there is no automatic long-context routing")
+	}
+
+	if ms, err := src.APITimeoutMS.Int64(); err == nil && ms > 0 {
+		cfg.Limits = &Limits{UpstreamTimeout: time.Duration(ms) * time.Millisecond}
+		note("API_TIMEOUT_MS → limits.upstream_timeout %s", cfg.Limits.UpstreamTimeout)
+	}
+	if src.ProxyURL != "" {
+		note("PROXY_URL dropped: set HTTPS_PROXY in the environment instead")
+	}
+	if src.CustomRouter != "" {
+		note("CUSTOM_ROUTER_PATH %s dropped: custom JavaScript routers are not supported", src.CustomRouter)
+	}
+	for _, t := range src.Transformers {
+		note("custom transformer %s dropped: JavaScript plugins are not supported", t.Path)
+	}
+	return cfg, report, nil
+}
+
+// ccrTransformList maps a claude-code-router "use" list. Entries are a name
+// or a [name, options] pair; maxtoken's max_tokens option becomes the
+// returned max_tokens.
+func ccrTransformList(raw json.RawMessage, owner string, note func(string, ...interface{})) ([]string, int) {
+	var entries []json.RawMessage
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		note("%s: unreadable transformer list dropped", owner)
+		return nil, 0
+	}
+	var out []string
+	maxTokens := 0
+	for _, e := range entries {
+		var name string
+		var opts map[string]interface{}
+		if json.Unmarshal(e, &name) != nil {
+			var pair []json.RawMessage
+			if json.Unmarshal(e, &pair) != nil || len(pair) == 0 || json.Unmarshal(pair[0], &name) != nil {
+				note("%s: unreadable transformer entry %s dropped", owner, e)
+				continue
+			}
+			if len(pair) > 1 {
+				json.Unmarshal(pair[1], &opts)
+			}
+		}
+		if strings.EqualFold(name, "maxtoken") {
+			if n, ok := opts["max_tokens"].(float64); ok {
+				maxTokens = int(n)
+				note("%s: maxtoken → max_tokens %d", owner, maxTokens)
+			}
+			continue
+		}
+		mapped, ok := ccrTransforms[strings.ToLower(name)]
+		if !ok {
+			note("%s: transformer %s has no equivalent, dropped", owner, name)
+			continue
+		}
+		if len(opts) > 0 {
+			note("%s: options for transformer %s dropped", owner, name)
+		}
+		out = appendMissing(out, []string{mapped})
+	}
+	return out, maxTokens
+}
+
+// ccrEndpoint turns a full chat completions URL into a provider endpoint.
+func ccrEndpoint(url string) string {
+	url = strings.TrimRight(url, "/")
+	return strings.TrimSuffix(url, "/chat/completions")
+}
+
+// ccrEnvRE matches claude-code-router's $VAR and ${VAR} references.
+var ccrEnvRE = regexp.MustCompile(`^\$\{?([A-Za-z_][A-Za-z0-9_]*)\}?$`)
+
+// ccrEnvRef rewrites an environment reference into ${VAR} form.
+func ccrEnvRef(s string) string {
+	if m := ccrEnvRE.FindStringSubmatch(s); m != nil {
+		return "${" + m[1] + "}"
+	}
+	return s
+}
+
+// withSchema appends the schema transform auto-detection would have picked,
+// unless the list already has one: an explicit list turns detection off.
+func withSchema(transforms []string, provider string) []string {
+	for _, t := range transforms {
+		if strings.HasPrefix(t, "schema:") {
+			return transforms
+		}
+	}
+	return append(transforms, detectTransform(nil, provider)...)
+}
+
+// appendMissing returns a with the entries of b it lacks appended.
+func appendMissing(a, b []string) []string {
+	out := append([]string(nil), a...)
+	for _, s := range b {
+		found := false
+		for _, t := range out {
+			found = found || t == s
+		}
+		if !found {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+var labelUnsafeRE = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// labelName derives a marker-friendly label from a model name.
+func labelName(model string) string {
+	return strings.Trim(labelUnsafeRE.ReplaceAllString(strings.ToLower(model), "-"), "-")
+}
+
+func providerIndex(cfg *ProvidersConfig, name string) int {
+	for i, p := range cfg.Providers {
+		if p.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// yRouterModels mirrors y-router's own mapping of Claude model names to
+// OpenRouter models.
+var yRouterModels = []struct{ label, model string }{
+	{"haiku", "anthropic/claude-3.5-haiku"},
+	{"sonnet", "anthropic/claude-sonnet-4"},
+	{"opus", "anthropic/claude-opus-4"},
+}
+
+// yRouterVarRE matches KEY = "value" in wrangler.toml and KEY=value in
+// .dev.vars.
+var yRouterVarRE = regexp.MustCompile(`(?m)^\s*([A-Z_]+)\s*=\s*"?([^"\n]*)"?\s*$`)
+
+// importYRouter reads y-router's wrangler.toml or .dev.vars. y-router has a
+// single OpenRouter upstream and passes the client's key through, so the
+// import is one provider.
+func importYRouter(data []byte) (*ProvidersConfig, []string, error) {
+	vars := map[string]string{}
+	for _, m := range yRouterVarRE.FindAllStringSubmatch(string(data), -1) {
+		vars[m[1]] = m[2]
+	}
+	var report []string
+	endpoint := vars["OPENROUTER_BASE_URL"]
+	if endpoint == "" {
+		endpoint = "https://openrouter.ai/api/v1"
+		report = append(report, "OPENROUTER_BASE_URL not set, using "+endpoint)
+	}
+	pc := ProviderConfig{
+		Name:      "openrouter",
+		Endpoint:  strings.TrimRight(endpoint, "/"),
+		APIKey:    "${OPENROUTER_API_KEY}",
+		Transform: []string{"cleancache", "openrouter", "enhancetool", "schema:generic"},
+		Models:    map[string]ModelConfig{},
+	}
+	report = append(report, "provider openrouter: api_key read from $OPENROUTER_API_KEY (y-router took it from each request)")
+	for _, m := range yRouterModels {
+		pc.Models[m.label] = ModelConfig{Model: m.model}
+		report = append(report, fmt.Sprintf("label %s → openrouter/%s", m.label, m.model))
+	}
+	report = append(report, "y-router passed other model names through unchanged: add a label for each OpenRouter model you use")
+	return &ProvidersConfig{Providers: []ProviderConfig{pc}}, report, nil
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const ccrSample = `{
+  "API_TIMEOUT_MS": 600000,
+  "PROXY_URL": "http://127.0.0.1:7890",
+  "transformers": [{"path": "/home/me/.claude-code-router/plugins/gemini-cli.js"}],
+  "Providers": [
+    {
+      "name": "openrouter",
+      "api_base_url": "https://openrouter.ai/api/v1/chat/completions",
+      "api_key": "$OPENROUTER_API_KEY",
+      "models": ["google/gemini-2.5-pro-preview", "anthropic/claude-sonnet-4"],
+      "transformer": {"use": ["openrouter"]}
+    },
+    {
+      "name": "deepseek",
+      "api_base_url": "https://api.deepseek.com/chat/completions",
+      "api_key": "sk-plain",
+      "models": ["deepseek-chat", "deepseek-reasoner"],
+      "transformer": {
+        "use": ["deepseek"],
+        "deepseek-chat": {"use": ["tooluse"]}
+      }
+    },
+    {
+      "name": "ollama",
+      "api_base_url": "http://localhost:11434/v1/chat/completions",
+      "api_key": "ollama",
+      "models": ["qwen2.5-coder:latest"],
+      "transformer": {"use": [["maxtoken", {"max_tokens": 16384}], "vertex-gemini"]}
+    }
+  ],
+  "Router": {
+    "default": "deepseek,deepseek-chat",
+    "background": "ollama,qwen2.5-coder:latest",
+    "think": "deepseek,deepseek-reasoner",
+    "longContext": "openrouter,google/gemini-2.5-pro-preview",
+    "longContextThreshold": 60000,
+    "webSearch": "missing,model"
+  }
+}`
+
+func TestImportClaudeCodeRouter(t *testing.T) {
+	cfg, report, err := Import(ImportClaudeCodeRouter, []byte(ccrSample))
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	r, err := NewModelResolver(cfg)
+	if err != nil {
+		t.Fatalf("resolver: %v", err)
+	}
+
+	or, _ := r.Resolve("gemini-2.5-pro-preview")
+	if or.Endpoint != "https://openrouter.ai/api/v1" || strings.Join(or.Transform, ",") != "openrouter,schema:generic" {
+		t.Errorf("openrouter label = %+v", or)
+	}
+	if cfg.Providers[0].APIKey != "${OPENROUTER_API_KEY}" {
+		t.Errorf("api_key = %q, want env reference", cfg.Providers[0].APIKey)
+	}
+
+	chat, _ := r.Resolve("deepseek-chat")
+	if strings.Join(chat.Transform, ",") != "deepseek,tooluse,schema:generic" {
+		t.Errorf("model transforms should extend the provider's: %v", chat.Transform)
+	}
+	coder, _ := r.Resolve("qwen2.5-coder-latest")
+	if coder.MaxTokens != 16384 || coder.Model != "qwen2.5-coder:latest" {
+		t.Errorf("ollama label = %+v", coder)
+	}
+
+	for label, model := range map[string]string{
+		"default":      "deepseek-chat",
+		"background":   "qwen2.5-coder:latest",
+		"think":        "deepseek-reasoner",
+		"long-context": "google/gemini-2.5-pro-preview",
+	} {
+		m, err := r.Resolve(label)
+		if err != nil || m.Model != model {
+			t.Errorf("route label %s = %+v, %v; want model %s", label, m, err, model)
+		}
+	}
+	if _, err := r.Resolve("web-search"); err == nil {
+		t.Error("route to an unknown provider should be dropped")
+	}
+	if cfg.Limits == nil || cfg.Limits.UpstreamTimeout != 10*time.Minute {
+		t.Errorf("limits = %+v", cfg.Limits)
+	}
+
+	joined := strings.Join(report, "\n")
+	for _, want := range []string{
+		"provider deepseek: api_key copied as plain text",
+		"transformer vertex-gemini has no equivalent",
+		"Router.webSearch: provider \"missing\" not found",
+		"longContextThreshold dropped",
+		"PROXY_URL dropped",
+		"gemini-cli.js dropped",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("report missing %q:\n%s", want, joined)
+		}
+	}
+}
+
+func TestImportLabelCollision(t *testing.T) {
+	cfg, _, err := Import(ImportClaudeCodeRouter, []byte(`{"Providers": [
+		{"name": "a", "api_base_url": "http://a/v1/chat/completions", "models": ["org/m"]},
+		{"name": "b", "api_base_url": "http://b/v1/chat/completions", "models": ["other/m"]}
+	]}`))
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if _, ok := cfg.Providers[1].Models["b-other-m"]; !ok {
+		t.Errorf("colliding label not prefixed: %v", cfg.Providers[1].Models)
+	}
+}
+
+func TestImportYRouter(t *testing.T) {
+	cfg, _, err := Import(ImportYRouter, []byte("name = \"y-router\"\n[vars]\nOPENROUTER_BASE_URL = \"https://gateway.example/api/v1/\"\n"))
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	p := cfg.Providers[0]
+	if p.Endpoint != "https://gateway.example/api/v1" || p.Models["sonnet"].Model == "" {
+		t.Errorf("provider = %+v", p)
+	}
+}
+
+func TestImportUnknownSource(t *testing.T) {
+	if _, _, err := Import("litellm", nil); err == nil {
+		t.Error("expected error for unknown source")
+	}
+}
+
+func TestMarshalConfigRoundTrip(t *testing.T) {
+	cfg, _, err := Import(ImportClaudeCodeRouter, []byte(ccrSample))
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	out, err := MarshalConfig(cfg)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(out), "deepseek-reasoner: deepseek-reasoner\n") {
+		t.Errorf("plain models should be written as strings:\n%s", out)
+	}
+	if !strings.Contains(string(out), "upstream_timeout: 10m0s") {
+		t.Errorf("durations should be written as strings:\n%s", out)
+	}
+
+	path := t.TempDir() + "/config.yaml"
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		t.Fatal(err)
+	}
+	back, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if _, err := NewModelResolver(back); err != nil {
+		t.Fatalf("reloaded config invalid: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// JudgePrefix starts a marker label that asks a judge model which label
+// to use: model=judge:pick.
+const JudgePrefix = "judge:"
+
+// Judge defaults.
+const (
+	DefaultJudgeTimeout  = 5 * time.Second
+	DefaultJudgeCacheTTL = time.Hour
+)
+
+// JudgeConfig routes model=judge:NAME by asking a small, fast model to pick
+// one of Choices for a condensed copy of the request. The pick is cached
+// per conversation, so the judge runs once per conversation rather than
+// once per turn.
+type JudgeConfig struct {
+	Judge    string            `yaml:"judge"`               // label of the model that decides
+	Choices  map[string]string `yaml:"choices"`             // label, alias, label group or schedule → when to pick it
+	Default  string            `yaml:"default"`             // a choice, used when the judge fails or answers off the list
+	Timeout  time.Duration     `yaml:"timeout,omitempty"`   // for the judge's answer (default 5s)
+	CacheTTL time.Duration     `yaml:"cache_ttl,omitempty"` // how long a conversation keeps its pick (default 1h)
+}
+
+func (j JudgeConfig) withDefaults() JudgeConfig {
+	if j.Timeout == 0 {
+		j.Timeout = DefaultJudgeTimeout
+	}
+	if j.CacheTTL == 0 {
+		j.CacheTTL = DefaultJudgeCacheTTL
+	}
+	return j
+}
+
+// checkJudges validates cfg's judges. The judge itself must be a label, as
+// it is called directly rather than routed.
+func checkJudges(cfg *ProvidersConfig, isLabel func(string) bool) error {
+	routable := routableChecker(cfg, isLabel)
+	for _, name := range sortedKeys(cfg.Judges) {
+		j := cfg.Judges[name]
+		if name == "" || strings.ContainsAny(name, " :") {
+			return fmt.Errorf("judge %q: names can't contain spaces or colons", name)
+		}
+		if !isLabel(j.Judge) {
+			return fmt.Errorf("judge %q: judge label %q not defined", name, j.Judge)
+		}
+		if len(j.Choices) < 2 {
+			return fmt.Errorf("judge %q needs at least two choices", name)
+		}
+		for _, choice := range sortedKeys(j.Choices) {
+			if !routable(choice) {
+				return fmt.Errorf("judge %q: label %q not defined", name, choice)
+			}
+		}
+		if _, ok := j.Choices[j.Default]; !ok {
+			return fmt.Errorf("judge %q: default %q is not one of its choices", name, j.Default)
+		}
+		if j.Timeout < 0 || j.CacheTTL < 0 {
+			return fmt.Errorf("judge %q: timeout and cache_ttl must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// Judge returns the judge for model=judge:NAME, with defaults filled in.
+func (r *ModelResolver) Judge(name string) (JudgeConfig, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	j, ok := r.cfg.Judges[name]
+	if !ok {
+		return JudgeConfig{}, false
+	}
+	return j.withDefaults(), true
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJudges(t *testing.T) {
+	_, r := loadTestConfig(t, `
+providers:
+  - name: ollama
+    endpoint: http://localhost:11434/v1
+    models:
+      router: qwen2.5:1.5b
+      coder_7b: qwen2.5-coder:7b
+      coder_32b: qwen2.5-coder:32b
+judges:
+  pick:
+    judge: router
+    choices:
+      coder_7b: small edits and questions
+      coder_32b: design, debugging and multi-file changes
+    default: coder_7b
+    timeout: 2s
+`)
+	j, ok := r.Judge("pick")
+	if !ok || j.Timeout.Seconds() != 2 || j.CacheTTL != DefaultJudgeCacheTTL {
+		t.Errorf("Judge(pick) = %+v, %v; want defaults filled in", j, ok)
+	}
+	if m, err := r.Resolve("judge:pick"); err != nil || m.Label != "coder_7b" {
+		t.Errorf("Resolve(judge:pick) = %s, %v; want the default", m.Label, err)
+	}
+	if _, err := r.Resolve("judge:nope"); err == nil {
+		t.Error("unknown judge should not resolve")
+	}
+}
+
+func TestJudgeErrors(t *testing.T) {
+	tests := []struct{ extra, want string }{
+		{"judges: {j: {judge: nope, choices: {a: x, b: y}, default: a}}", `judge label "nope" not defined`},
+		{"judges: {j: {judge: a, choices: {a: x}, default: a}}", "at least two choices"},
+		{"judges: {j: {judge: a, choices: {a: x, nope: y}, default: a}}", `label "nope" not defined`},
+		{"judges: {j: {judge: a, choices: {a: x, b: y}, default: c}}", "not one of its choices"},
+		{"judges: {j: {judge: a, choices: {a: x, b: y}, default: a, timeout: -1s}}", "must not be negative"},
+		{"judges: {'a b': {judge: a, choices: {a: x, b: y}, default: a}}", "can't contain spaces or colons"},
+	}
+	for _, tt := range tests {
+		cfg, err := parseConfig([]byte("providers:\n  - name: p\n    endpoint: http://p/v1\n    models: {a: a, b: b}\n"+tt.extra+"\n"), "config.yaml")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.extra, err)
+		}
+		if _, err := NewModelResolver(cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.extra, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LabelSpec describes a label registered at runtime. A label on an existing
+// provider inherits all of its settings; naming a new provider creates it,
+// which needs an endpoint (or a group that supplies one).
+type LabelSpec struct {
+	Label     string   `json:"label"`
+	Provider  string   `json:"provider"`
+	Model     string   `json:"model"`
+	MaxTokens int      `json:"max_tokens,omitempty"`
+	Transform []string `json:"transform,omitempty"`
+
+	// New-provider settings; rejected for a provider that already exists.
+	Endpoint string `json:"endpoint,omitempty"`
+	APIKey   string `json:"api_key,omitempty"` // ${VAR} references are expanded
+	API      string `json:"api,omitempty"`
+	Group    string `json:"group,omitempty"`
+}
+
+func (s LabelSpec) modelConfig() ModelConfig {
+	return ModelConfig{Model: s.Model, MaxTokens: s.MaxTokens, Transform: s.Transform}
+}
+
+func (s LabelSpec) providerConfig() ProviderConfig {
+	return ProviderConfig{
+		Name:     s.Provider,
+		Group:    s.Group,
+		Endpoint: s.Endpoint,
+		APIKey:   s.APIKey,
+		API:      s.API,
+		Models:   map[string]ModelConfig{s.Label: s.modelConfig()},
+	}
+}
+
+// AddLabel registers a new label, validating it exactly as if it had been in
+// the config file. On error the resolver is unchanged.
+func (r *ModelResolver) AddLabel(spec LabelSpec) (ResolvedModel, error) {
+	if spec.Label == "" || spec.Provider == "" || spec.Model == "" {
+		return ResolvedModel{}, fmt.Errorf("label, provider and model are required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.models[spec.Label]; exists {
+		return ResolvedModel{}, fmt.Errorf("duplicate model label %q", spec.Label)
+	}
+
+	// Copy-on-write: the providers slice and the touched Models map are
+	// cloned so a failed validation leaves r.cfg intact.
+	cfg := *r.cfg
+	cfg.Providers = append([]ProviderConfig(nil), r.cfg.Providers...)
+	found := false
+	for i, p := range cfg.Providers {
+		if p.Name != spec.Provider {
+			continue
+		}
+		if spec.Endpoint != "" || spec.APIKey != "" || spec.API != "" || spec.Group != "" {
+			return ResolvedModel{}, fmt.Errorf("provider %q already exists; omit endpoint, api_key, api and group", spec.Provider)
+		}
+		models := make(map[string]ModelConfig, len(p.Models)+1)
+		for k, v := range p.Models {
+			models[k] = v
+		}
+		models[spec.Label] = spec.modelConfig()
+		cfg.Providers[i].Models = models
+		found = true
+		break
+	}
+	if !found {
+		cfg.Providers = append(cfg.Providers, spec.providerConfig())
+	}
+
+	models, err := resolveModels(&cfg)
+	if err != nil {
+		return ResolvedModel{}, err
+	}
+	r.set(&cfg, models)
+	return models[spec.Label], nil
+}
+
+// PersistLabel appends spec to the config file at path. It edits the YAML
+// tree rather than re-encoding the config, so existing comments survive; the
+// file is replaced atomically.
+func PersistLabel(path string, spec LabelSpec) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parse config: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("parse config: top level is not a mapping")
+	}
+	root := doc.Content[0]
+
+	providers := mappingValue(root, "providers")
+	if providers == nil || providers.Kind != yaml.SequenceNode {
+		providers = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		setMappingValue(root, "providers", providers)
+	}
+
+	var model yaml.Node
+	if err := model.Encode(spec.modelConfig()); err != nil {
+		return err
+	}
+	if spec.MaxTokens == 0 && len(spec.Transform) == 0 {
+		model = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: spec.Model}
+	}
+
+	for _, p := range providers.Content {
+		if name := mappingValue(p, "name"); name != nil && name.Value == spec.Provider {
+			models := mappingValue(p, "models")
+			if models == nil || models.Kind != yaml.MappingNode {
+				models = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+				setMappingValue(p, "models", models)
+			}
+			setMappingValue(models, spec.Label, &model)
+			return writeYAML(path, &doc)
+		}
+	}
+
+	var provider yaml.Node
+	if err := provider.Encode(spec.providerConfig()); err != nil {
+		return err
+	}
+	if models := mappingValue(&provider, "models"); models != nil {
+		setMappingValue(models, spec.Label, &model)
+	}
+	providers.Content = append(providers.Content, &provider)
+	return writeYAML(path, &doc)
+}
+
+// mappingValue returns the value node for key in a mapping node, or nil.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	if m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setMappingValue(m *yaml.Node, key string, v *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = v
+			return
+		}
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, v)
+}
+
+func writeYAML(path string, doc *yaml.Node) error {
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".config-*.yaml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(out.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if info, err := os.Stat(path); err == nil {
+		os.Chmod(tmp.Name(), info.Mode().Perm())
+	}
+	return os.Rename(tmp.Name(), path)
+}
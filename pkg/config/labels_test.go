@@ -0,0 +1,91 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAddLabel(t *testing.T) {
+	_, r := loadTestConfig(t, `
+providers:
+  - name: vllm
+    endpoint: http://gpu-1:8000/v1
+    api_key: tok
+    models:
+      coder: Qwen/Qwen2.5-Coder-32B-Instruct
+`)
+
+	m, err := r.AddLabel(LabelSpec{Label: "coder-small", Provider: "vllm", Model: "Qwen/Qwen2.5-Coder-7B-Instruct"})
+	if err != nil {
+		t.Fatalf("AddLabel existing provider: %v", err)
+	}
+	if m.Endpoint != "http://gpu-1:8000/v1" || m.APIKey != "tok" {
+		t.Errorf("label did not inherit provider settings: %+v", m)
+	}
+
+	if _, err := r.AddLabel(LabelSpec{Label: "fresh", Provider: "vllm-2", Model: "m", Endpoint: "http://gpu-2:8000/v1/"}); err != nil {
+		t.Fatalf("AddLabel new provider: %v", err)
+	}
+	if m, err := r.Resolve("fresh"); err != nil || m.Endpoint != "http://gpu-2:8000/v1" || m.Provider != "vllm-2" {
+		t.Errorf("new provider label: %+v, %v", m, err)
+	}
+
+	for name, spec := range map[string]LabelSpec{
+		"duplicate":        {Label: "coder", Provider: "vllm", Model: "x"},
+		"missing endpoint": {Label: "a", Provider: "nowhere", Model: "x"},
+		"redefine":         {Label: "b", Provider: "vllm", Model: "x", Endpoint: "http://other/v1"},
+		"missing model":    {Label: "c", Provider: "vllm"},
+	} {
+		if _, err := r.AddLabel(spec); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if got := len(r.Models()); got != 3 {
+		t.Errorf("failed adds changed the resolver: %d labels", got)
+	}
+}
+
+func TestPersistLabel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte(`# my providers
+providers:
+  # local GPU box
+  - name: vllm
+    endpoint: http://gpu-1:8000/v1
+    models:
+      coder: big-model
+`), 0600)
+
+	if err := PersistLabel(path, LabelSpec{Label: "small", Provider: "vllm", Model: "small-model"}); err != nil {
+		t.Fatalf("persist to existing provider: %v", err)
+	}
+	if err := PersistLabel(path, LabelSpec{
+		Label: "remote", Provider: "vllm-2", Model: "m", Endpoint: "http://gpu-2:8000/v1", MaxTokens: 8192,
+	}); err != nil {
+		t.Fatalf("persist new provider: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "# local GPU box") {
+		t.Errorf("comments lost:\n%s", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("file mode changed to %v", info.Mode().Perm())
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewModelResolver(cfg)
+	if err != nil {
+		t.Fatalf("persisted config invalid: %v\n%s", err, data)
+	}
+	if m, _ := r.Resolve("small"); m.Model != "small-model" || m.Provider != "vllm" {
+		t.Errorf("small: %+v", m)
+	}
+	if m, _ := r.Resolve("remote"); m.Endpoint != "http://gpu-2:8000/v1" || m.MaxTokens != 8192 {
+		t.Errorf("remote: %+v", m)
+	}
+}
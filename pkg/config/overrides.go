@@ -0,0 +1,201 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvOverridePrefix starts the environment variables that set config keys.
+// Path segments are separated by a double underscore, since keys contain
+// single ones: CLAUDE_HYBRID_LIMITS__MAX_CONCURRENT=64.
+const EnvOverridePrefix = "CLAUDE_HYBRID_"
+
+// Override sets one config key from outside the config file.
+type Override struct {
+	Path   []string // keys, sequence indexes or provider names
+	Value  string   // YAML: a scalar, or a flow list or map such as [a, b]
+	Source string   // where it came from, for errors and logs
+}
+
+// String returns the override's dotted path and source, leaving out the
+// value, which may be a secret.
+func (o Override) String() string {
+	return strings.Join(o.Path, ".") + " (" + o.Source + ")"
+}
+
+// ParseSet parses a --set argument, path=value. The path is dotted keys:
+// providers.0.endpoint, or providers.ollama.endpoint to pick a provider by
+// name.
+func ParseSet(s string) (Override, error) {
+	path, value, ok := strings.Cut(s, "=")
+	if !ok || path == "" {
+		return Override{}, fmt.Errorf("--set %q: want path=value", s)
+	}
+	segs := strings.Split(path, ".")
+	for _, seg := range segs {
+		if seg == "" {
+			return Override{}, fmt.Errorf("--set %q: empty path segment", s)
+		}
+	}
+	return Override{Path: segs, Value: value, Source: "--set " + path}, nil
+}
+
+// EnvOverrides returns the overrides in environ, a list of KEY=value
+// strings. Only variables whose first segment names a top-level config key
+// are overrides, so other CLAUDE_HYBRID_* variables are left alone. They
+// are sorted by name so the result doesn't depend on the environment's
+// order.
+func EnvOverrides(environ []string) []Override {
+	top := topLevelKeys()
+	var out []Override
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		rest, found := strings.CutPrefix(name, EnvOverridePrefix)
+		if !ok || !found {
+			continue
+		}
+		segs := strings.Split(strings.ToLower(rest), "__")
+		if !top[segs[0]] {
+			continue
+		}
+		out = append(out, Override{Path: segs, Value: value, Source: name})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Source < out[j].Source })
+	return out
+}
+
+// topLevelKeys returns the YAML keys of ProvidersConfig that overrides can
+// set. Profiles are applied before overrides, so they aren't among them.
+func topLevelKeys() map[string]bool {
+	keys := make(map[string]bool)
+	t := reflect.TypeOf(ProvidersConfig{})
+	for i := 0; i < t.NumField(); i++ {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if key != "" && key != "-" && key != "profiles" {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+// WithOverrides returns a copy of cfg with the overrides applied in order,
+// so a later one wins. Each is checked as if it had been written in the
+// file: unknown keys and values of the wrong type are errors naming the
+// override's source.
+func (cfg *ProvidersConfig) WithOverrides(overrides []Override) (*ProvidersConfig, error) {
+	var doc yaml.Node
+	if err := doc.Encode(cfg); err != nil {
+		return nil, err
+	}
+	top := topLevelKeys()
+	out := cfg
+	for _, o := range overrides {
+		if !top[o.Path[0]] {
+			return nil, fmt.Errorf("%s: unknown config key %q", o.Source, o.Path[0])
+		}
+		var value yaml.Node
+		if err := yaml.Unmarshal([]byte(o.Value), &value); err != nil {
+			return nil, fmt.Errorf("%s: %v", o.Source, err)
+		}
+		v := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str"}
+		if len(value.Content) > 0 {
+			v = value.Content[0]
+		}
+		if err := setPath(&doc, o.Path, v); err != nil {
+			return nil, fmt.Errorf("%s: %v", o.Source, err)
+		}
+		data, err := yaml.Marshal(&doc)
+		if err != nil {
+			return nil, err
+		}
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		next := new(ProvidersConfig)
+		if err := dec.Decode(next); err != nil {
+			return nil, fmt.Errorf("%s: %v", o.Source, err)
+		}
+		out = next
+	}
+	return out, nil
+}
+
+// setPath replaces the value at path in node, creating mapping keys as
+// needed. A sequence segment is an index (the length appends an item) or the
+// name of a mapping item. A model or embedding model given as a plain string
+// is expanded to {model: ...} so its other fields can be set.
+func setPath(node *yaml.Node, path []string, value *yaml.Node) error {
+	for i, seg := range path {
+		last := i == len(path)-1
+		switch node.Kind {
+		case yaml.MappingNode:
+			next := mappingValue(node, seg)
+			if next == nil {
+				next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+				if !last && isIndex(path[i+1]) {
+					next = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+				}
+				setMappingValue(node, seg, next)
+			}
+			if last {
+				setMappingValue(node, seg, value)
+				return nil
+			}
+			if next.Kind == yaml.ScalarNode && i > 0 && (path[i-1] == "models" || path[i-1] == "embedding_models") {
+				*next = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{
+					{Kind: yaml.ScalarNode, Tag: "!!str", Value: "model"},
+					{Kind: yaml.ScalarNode, Tag: "!!str", Value: next.Value},
+				}}
+			}
+			node = next
+		case yaml.SequenceNode:
+			idx, err := sequenceIndex(node, seg)
+			if err != nil {
+				return fmt.Errorf("%s: %v", strings.Join(path[:i], "."), err)
+			}
+			if idx == len(node.Content) {
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"})
+			}
+			if last {
+				node.Content[idx] = value
+				return nil
+			}
+			node = node.Content[idx]
+		case yaml.DocumentNode:
+			if len(node.Content) == 0 {
+				node.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+			}
+			return setPath(node.Content[0], path, value)
+		default:
+			return fmt.Errorf("%s is a value, not a list or map", strings.Join(path[:i], "."))
+		}
+	}
+	return nil
+}
+
+func isIndex(seg string) bool {
+	_, err := strconv.Atoi(seg)
+	return err == nil
+}
+
+// sequenceIndex resolves seg within a sequence: an index up to its length,
+// or the name: of one of its items.
+func sequenceIndex(seq *yaml.Node, seg string) (int, error) {
+	if n, err := strconv.Atoi(seg); err == nil {
+		if n < 0 || n > len(seq.Content) {
+			return 0, fmt.Errorf("index %d out of range (%d items)", n, len(seq.Content))
+		}
+		return n, nil
+	}
+	for i, item := range seq.Content {
+		if name := mappingValue(item, "name"); name != nil && strings.EqualFold(name.Value, seg) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("no item named %q", seg)
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustSet(t *testing.T, s string) Override {
+	t.Helper()
+	o, err := ParseSet(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func TestWithOverrides(t *testing.T) {
+	cfg, _ := loadTestConfig(t, `
+limits:
+  upstream_timeout: 1m
+providers:
+  - name: ollama
+    endpoint: http://localhost:11434/v1
+    models:
+      fast: qwen3:8b
+      big:
+        model: qwen3:32b
+        max_tokens: 8192
+`)
+	env := EnvOverrides([]string{
+		"CLAUDE_HYBRID_LIMITS__MAX_CONCURRENT=64",
+		"CLAUDE_HYBRID_PROVIDERS__0__ENDPOINT=http://env:11434/v1",
+		"CLAUDE_HYBRID_ADMIN_TOKEN=secret", // not a config key
+		"CLAUDE_HYBRID_CA_PASSPHRASE=pw",
+		"PATH=/bin",
+	})
+	if len(env) != 2 {
+		t.Fatalf("env overrides = %v, want the two config keys", env)
+	}
+	got, err := cfg.WithOverrides(append(env,
+		mustSet(t, "providers.ollama.endpoint=http://gpu:11434/v1"), // wins over the env
+		mustSet(t, "providers.ollama.models.fast.max_tokens=2048"),
+		mustSet(t, "providers.1.name=groq"),
+		mustSet(t, "providers.groq.endpoint=https://api.groq.com/openai/v1"),
+		mustSet(t, "providers.groq.models.llama=llama-3.3-70b"),
+		mustSet(t, "intercept=[api.anthropic.com, '*.corp.example']"),
+		mustSet(t, "dedupe.ttl=1m"),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Limits.MaxConcurrent != 64 || got.Limits.UpstreamTimeout != time.Minute {
+		t.Errorf("limits = %+v, want the file's timeout and the env's max_concurrent", got.Limits)
+	}
+	if len(got.Intercept) != 2 || got.Dedupe == nil || got.Dedupe.TTL != time.Minute {
+		t.Errorf("intercept %v dedupe %+v", got.Intercept, got.Dedupe)
+	}
+	r, err := NewModelResolver(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for label, want := range map[string]ResolvedModel{
+		"fast":  {Endpoint: "http://gpu:11434/v1", Model: "qwen3:8b", MaxTokens: 2048},
+		"big":   {Endpoint: "http://gpu:11434/v1", Model: "qwen3:32b", MaxTokens: 8192},
+		"llama": {Endpoint: "https://api.groq.com/openai/v1", Model: "llama-3.3-70b"},
+	} {
+		m, err := r.Resolve(label)
+		if err != nil || m.Endpoint != want.Endpoint || m.Model != want.Model || m.MaxTokens != want.MaxTokens {
+			t.Errorf("%s = %+v, %v; want %+v", label, m, err, want)
+		}
+	}
+	if cfg.Providers[0].Endpoint != "http://localhost:11434/v1" || cfg.Limits.MaxConcurrent != 0 {
+		t.Error("WithOverrides changed the original config")
+	}
+}
+
+func TestWithOverridesErrors(t *testing.T) {
+	cfg, _ := loadTestConfig(t, `
+providers:
+  - name: ollama
+    endpoint: http://localhost:11434/v1
+    models:
+      fast: qwen3:8b
+`)
+	for set, want := range map[string]string{
+		"limits.max_concurent=4":     "field max_concurent not found",
+		"limits.max_concurrent=lots": "cannot unmarshal",
+		"nope=1":                     `unknown config key "nope"`,
+		"profiles.x.dedupe.ttl=1m":   `unknown config key "profiles"`,
+		"providers.5.endpoint=x":     "index 5 out of range",
+		"providers.vllm.endpoint=x":  `no item named "vllm"`,
+		"providers.0.name.first=x":   "providers.0.name is a value",
+		"intercept=[unclosed":        "did not find expected",
+	} {
+		_, err := cfg.WithOverrides([]Override{mustSet(t, set)})
+		if err == nil || !strings.Contains(err.Error(), want) || !strings.HasPrefix(err.Error(), "--set ") {
+			t.Errorf("--set %s: err = %v, want %q", set, err, want)
+		}
+	}
+	for _, bad := range []string{"novalue", "=x", "a..b=1"} {
+		if _, err := ParseSet(bad); err == nil {
+			t.Errorf("ParseSet(%q) accepted", bad)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A model label containing * is a pattern: any marker label it matches
+// resolves to that entry, with the backend model derived from the label.
+// In the model name, {label} is the whole label and {1}, {2}... are the
+// text each * matched, so
+//
+//	"ollama/*": {model: "{1}"}
+//
+// routes model=ollama/qwen3:32b to qwen3:32b without a config change for
+// every newly pulled tag.
+
+// modelPlaceholderRE matches {label} and {N} in a pattern's model name.
+var modelPlaceholderRE = regexp.MustCompile(`\{(label|[0-9]+)\}`)
+
+// labelPattern is a compiled wildcard label.
+type labelPattern struct {
+	re      *regexp.Regexp
+	literal int           // non-wildcard characters; more is more specific
+	model   ResolvedModel // Label and Pattern are the pattern itself
+}
+
+func isPattern(label string) bool {
+	return strings.Contains(label, "*")
+}
+
+// compilePattern turns a wildcard label into an anchored regexp in which
+// each * matches at least one character.
+func compilePattern(label string) *regexp.Regexp {
+	parts := strings.Split(label, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, "(.+?)") + "$")
+}
+
+// checkPattern validates a pattern label's model config.
+func checkPattern(label string, mc ModelConfig) error {
+	if strings.Contains(label, "**") {
+		return fmt.Errorf("adjacent * in pattern")
+	}
+	stars := strings.Count(label, "*")
+	for _, m := range modelPlaceholderRE.FindAllStringSubmatch(mc.Model, -1) {
+		if n, err := strconv.Atoi(m[1]); err == nil && (n < 1 || n > stars) {
+			return fmt.Errorf("model %q: {%d} but the pattern has %d *", mc.Model, n, stars)
+		}
+	}
+	if mc.Preload {
+		return fmt.Errorf("preload needs a concrete label, not a pattern")
+	}
+	if mc.Budget != nil {
+		return fmt.Errorf("budget needs a concrete label, not a pattern")
+	}
+	return nil
+}
+
+// newPatterns collects the pattern entries of models, most specific first:
+// the pattern with the most literal characters wins, then the one that
+// sorts first.
+func newPatterns(models map[string]ResolvedModel) []labelPattern {
+	var out []labelPattern
+	for label, m := range models {
+		if m.Pattern == "" {
+			continue
+		}
+		out = append(out, labelPattern{
+			re:      compilePattern(label),
+			literal: len(label) - strings.Count(label, "*"),
+			model:   m,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].literal != out[j].literal {
+			return out[i].literal > out[j].literal
+		}
+		return out[i].model.Label < out[j].model.Label
+	})
+	return out
+}
+
+// matchPattern resolves label against patterns, filling the model name.
+func matchPattern(patterns []labelPattern, label string) (ResolvedModel, bool) {
+	for _, p := range patterns {
+		groups := p.re.FindStringSubmatch(label)
+		if groups == nil {
+			continue
+		}
+		m := p.model
+		m.Label = label
+		m.Model = modelPlaceholderRE.ReplaceAllStringFunc(m.Model, func(ph string) string {
+			name := ph[1 : len(ph)-1]
+			if name == "label" {
+				return label
+			}
+			n, _ := strconv.Atoi(name)
+			return groups[n]
+		})
+		return m, true
+	}
+	return ResolvedModel{}, false
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPatternLabels(t *testing.T) {
+	_, r := loadTestConfig(t, `
+providers:
+  - name: ollama
+    endpoint: http://localhost:11434/v1
+    max_tokens: 8192
+    models:
+      "ollama/*": {model: "{1}"}
+      "qwen*": {model: "{label}"}
+      qwen3-coder: qwen3-coder:30b-a3b-q4_K_M
+  - name: deepseek
+    endpoint: https://api.deepseek.com/v1
+    models:
+      "ds-*-*": {model: "deepseek-{1}", fallback: ds-chat}
+      "ds-*": {model: "deepseek-{1}"}
+`)
+	tests := []struct{ label, provider, model string }{
+		{"ollama/qwen3:32b", "ollama", "qwen3:32b"},
+		{"qwen2.5:7b", "ollama", "qwen2.5:7b"},
+		{"qwen3-coder", "ollama", "qwen3-coder:30b-a3b-q4_K_M"}, // configured labels win
+		{"ds-chat", "deepseek", "deepseek-chat"},
+		{"ds-reasoner-fast", "deepseek", "deepseek-reasoner"}, // more literal text wins
+	}
+	for _, tt := range tests {
+		m, err := r.Resolve(tt.label)
+		if err != nil {
+			t.Errorf("Resolve(%q): %v", tt.label, err)
+			continue
+		}
+		if m.Provider != tt.provider || m.Model != tt.model || m.Label != tt.label {
+			t.Errorf("Resolve(%q) = %s/%s label %q, want %s/%s", tt.label, m.Provider, m.Model, m.Label, tt.provider, tt.model)
+		}
+	}
+	if m, _ := r.Resolve("ollama/llama3"); m.MaxTokens != 8192 || m.Pattern != "ollama/*" {
+		t.Errorf("pattern match should carry the provider's settings: %+v", m)
+	}
+	for _, label := range []string{"ollama/", "ds-", "mistral"} {
+		if _, err := r.Resolve(label); err == nil {
+			t.Errorf("Resolve(%q) should fail", label)
+		}
+	}
+
+	if n := len(r.Models()); n != 1 {
+		t.Errorf("Models() has %d entries, want only the configured label", n)
+	}
+	var order []string
+	for _, p := range r.Patterns() {
+		order = append(order, p.Label)
+	}
+	if got := strings.Join(order, " "); got != "ollama/* ds-*-* qwen* ds-*" {
+		t.Errorf("Patterns() order = %s", got)
+	}
+}
+
+func TestPatternLabelErrors(t *testing.T) {
+	tests := []struct{ models, want string }{
+		{`"ds-*": {model: "deepseek-{2}"}`, "{2} but the pattern has 1 *"},
+		{`"ds-**": {model: "{label}"}`, "adjacent *"},
+		{`"ds-*": {model: "{1}", preload: true}`, "preload needs a concrete label"},
+		{`"ds-*": {model: "{1}", price: {input: 1, output: 1}, budget: {daily_usd: 1}}`, "budget needs a concrete label"},
+		{`"a": {model: "a", fallback: "ds-x"}`, `fallback label "ds-x" not defined`},
+	}
+	for _, tt := range tests {
+		cfg, err := parseConfig([]byte("providers:\n  - name: p\n    endpoint: http://p/v1\n    models:\n      "+tt.models+"\n"), "config.yaml")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.models, err)
+		}
+		if _, err := NewModelResolver(cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.models, err, tt.want)
+		}
+	}
+}
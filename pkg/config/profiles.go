@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var profileNameRE = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// LoadConfigWithProfile loads the config file at path and applies the named
+// profile, which is taken from the file's profiles: section or, failing
+// that, from dir/<name>.yaml. With no profile it is LoadConfig, except that
+// a missing file returns a nil config rather than an error. A profile file
+// works without a config file.
+//
+// persistPath is where labels added at runtime should be saved so that the
+// same invocation sees them again: the file that supplies the providers
+// list, or "" when that is a profiles: section, which isn't edited.
+func LoadConfigWithProfile(path, dir, name string) (cfg *ProvidersConfig, persistPath string, err error) {
+	base, err := LoadConfig(path)
+	switch {
+	case os.IsNotExist(err):
+		base = nil
+	case err != nil:
+		return nil, "", err
+	}
+	if name == "" {
+		if base == nil {
+			return nil, "", nil
+		}
+		return base, path, nil
+	}
+	if !profileNameRE.MatchString(name) {
+		return nil, "", fmt.Errorf("invalid profile name %q", name)
+	}
+
+	profilePath := filepath.Join(dir, name+".yaml")
+	var profile *ProvidersConfig
+	fromFile := false
+	if base != nil {
+		if p, ok := base.Profiles[name]; ok {
+			profile = &p
+		}
+	}
+	if data, err := os.ReadFile(profilePath); err == nil {
+		if profile != nil {
+			return nil, "", fmt.Errorf("profile %q is defined in both %s and %s", name, path, profilePath)
+		}
+		if profile, err = parseConfig(data, profilePath); err != nil {
+			return nil, "", err
+		}
+		fromFile = true
+	} else if !os.IsNotExist(err) {
+		return nil, "", err
+	}
+	if profile == nil {
+		return nil, "", fmt.Errorf("unknown profile %q (profiles: %s)", name, strings.Join(ListProfiles(base, dir), ", "))
+	}
+	if len(profile.Profiles) > 0 {
+		return nil, "", fmt.Errorf("profile %q: profiles can't define profiles", name)
+	}
+
+	switch {
+	case profile.Providers == nil && base != nil:
+		persistPath = path
+	case profile.Providers != nil && fromFile:
+		persistPath = profilePath
+	}
+	if base == nil {
+		base = &ProvidersConfig{}
+	}
+	return base.WithProfile(profile), persistPath, nil
+}
+
+// WithProfile returns a copy of cfg with every setting the profile sets
+// replacing cfg's. Maps (groups, vars, aliases, label groups) are merged by
+// name, with the profile's winning, so a profile's providers can use the
+// base config's groups. The copy has no profiles.
+func (cfg *ProvidersConfig) WithProfile(profile *ProvidersConfig) *ProvidersConfig {
+	out := *cfg
+	// Field by field, so settings added to ProvidersConfig later are
+	// covered without touching this.
+	dst := reflect.ValueOf(&out).Elem()
+	src := reflect.ValueOf(profile).Elem()
+	for i := 0; i < src.NumField(); i++ {
+		f := src.Field(i)
+		if f.IsZero() {
+			continue
+		}
+		if f.Kind() != reflect.Map || dst.Field(i).Len() == 0 {
+			dst.Field(i).Set(f)
+			continue
+		}
+		merged := reflect.MakeMapWithSize(f.Type(), dst.Field(i).Len()+f.Len())
+		for _, m := range []reflect.Value{dst.Field(i), f} {
+			for it := m.MapRange(); it.Next(); {
+				merged.SetMapIndex(it.Key(), it.Value())
+			}
+		}
+		dst.Field(i).Set(merged)
+	}
+	out.Profiles = nil
+	return &out
+}
+
+// ListProfiles returns the names of the profiles in cfg (which may be nil)
+// and in dir, sorted.
+func ListProfiles(cfg *ProvidersConfig, dir string) []string {
+	seen := make(map[string]bool)
+	if cfg != nil {
+		for name := range cfg.Profiles {
+			seen[name] = true
+		}
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".yaml"); ok && !e.IsDir() && profileNameRE.MatchString(name) {
+			seen[name] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
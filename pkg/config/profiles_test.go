@@ -0,0 +1,125 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const profileBase = `
+groups:
+  local:
+    endpoint: http://localhost:11434/v1
+intercept: [api.anthropic.com]
+providers:
+  - name: ollama
+    group: local
+    models:
+      fast: qwen3:8b
+profiles:
+  work:
+    groups:
+      cloud:
+        endpoint: https://api.example.com/v1
+    providers:
+      - name: corp
+        group: cloud
+        models:
+          fast: corp-model
+      - name: ollama
+        group: local
+        models:
+          offline: qwen3:8b
+    dedupe:
+      ttl: 1m
+`
+
+func writeProfileFiles(t *testing.T, base string, profiles map[string]string) (path, dir string) {
+	t.Helper()
+	root := t.TempDir()
+	path, dir = filepath.Join(root, "config.yaml"), filepath.Join(root, "profiles")
+	if base != "" {
+		if err := os.WriteFile(path, []byte(base), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.MkdirAll(dir, 0700)
+	for name, data := range profiles {
+		if err := os.WriteFile(filepath.Join(dir, name+".yaml"), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path, dir
+}
+
+func TestLoadConfigWithProfile(t *testing.T) {
+	path, dir := writeProfileFiles(t, profileBase, map[string]string{
+		"home": "dedupe:\n  ttl: 5m\n",
+		"lab":  "providers:\n  - name: lab\n    endpoint: http://lab:8000/v1\n    models:\n      fast: big\n",
+	})
+
+	cfg, persist, err := LoadConfigWithProfile(path, dir, "")
+	if err != nil || persist != path || len(cfg.Profiles) != 1 {
+		t.Fatalf("no profile: %v, persist %q", err, persist)
+	}
+
+	cfg, persist, err = LoadConfigWithProfile(path, dir, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewModelResolver(cfg)
+	if err != nil {
+		t.Fatalf("work profile doesn't resolve: %v", err)
+	}
+	if m, _ := r.Resolve("fast"); m.Provider != "corp" || m.Endpoint != "https://api.example.com/v1" {
+		t.Errorf("fast under work = %s at %s, want corp at the profile's group", m.Provider, m.Endpoint)
+	}
+	if m, err := r.Resolve("offline"); err != nil || m.Endpoint != "http://localhost:11434/v1" {
+		t.Errorf("offline uses the base config's group: %+v, %v", m, err)
+	}
+	if cfg.Dedupe == nil || len(cfg.Intercept) != 1 || cfg.Profiles != nil {
+		t.Errorf("work: dedupe %v intercept %v profiles %v; want the profile's dedupe, the base intercept, no profiles",
+			cfg.Dedupe, cfg.Intercept, cfg.Profiles)
+	}
+	if persist != "" {
+		t.Errorf("inline profile with providers: persist %q, want none", persist)
+	}
+
+	cfg, persist, err = LoadConfigWithProfile(path, dir, "home")
+	if err != nil || cfg.Dedupe == nil || len(cfg.Providers) != 1 || cfg.Providers[0].Name != "ollama" || persist != path {
+		t.Errorf("home keeps the base providers and persists to config.yaml: %+v, %q, %v", cfg, persist, err)
+	}
+	_, persist, err = LoadConfigWithProfile(path, dir, "lab")
+	if err != nil || persist != filepath.Join(dir, "lab.yaml") {
+		t.Errorf("lab persists to its own file: %q, %v", persist, err)
+	}
+}
+
+func TestLoadConfigWithProfileErrors(t *testing.T) {
+	path, dir := writeProfileFiles(t, profileBase, map[string]string{
+		"work":   "dedupe:\n  ttl: 5m\n",
+		"nested": "profiles:\n  x: {}\n",
+		"broken": "providers: [",
+	})
+	for name, want := range map[string]string{
+		"work":    "defined in both",
+		"nested":  "can't define profiles",
+		"broken":  "broken.yaml: yaml: line 1",
+		"missing": "unknown profile \"missing\" (profiles: broken, nested, work)",
+		"../x":    "invalid profile name",
+	} {
+		if _, _, err := LoadConfigWithProfile(path, dir, name); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("profile %q: err = %v, want %q", name, err, want)
+		}
+	}
+
+	// Without config.yaml, a profile file stands alone.
+	path, dir = writeProfileFiles(t, "", map[string]string{"solo": "providers:\n  - name: p\n    endpoint: http://p/v1\n    models:\n      a: b\n"})
+	if cfg, _, err := LoadConfigWithProfile(path, dir, ""); cfg != nil || err != nil {
+		t.Errorf("no config, no profile: %v, %v", cfg, err)
+	}
+	if cfg, _, err := LoadConfigWithProfile(path, dir, "solo"); err != nil || len(cfg.Providers) != 1 {
+		t.Errorf("profile without config.yaml: %v, %v", cfg, err)
+	}
+}
@@ -0,0 +1,870 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/tokenizer"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/redact"
+	"gopkg.in/yaml.v3"
+)
+
+// ModelConfig supports both simple string ("qwen3:32b") and expanded form with per-model overrides.
+type ModelConfig struct {
+	Model     string                 `yaml:"model"`
+	MaxTokens int                    `yaml:"max_tokens,omitempty"`
+	Transform []string               `yaml:"transform,omitempty"`  // per-model override (replaces provider-level)
+	Params    map[string]interface{} `yaml:"params,omitempty"`     // custom params injected into request body
+	Preload   bool                   `yaml:"preload,omitempty"`    // send a warm-up request at proxy start
+	KeepAlive string                 `yaml:"keep_alive,omitempty"` // per-model override of provider keep_alive
+	Fallback  string                 `yaml:"fallback,omitempty"`   // label suggested when this backend is saturated
+	VRAMMB    int                    `yaml:"vram_mb,omitempty"`    // approximate VRAM needed to load this model
+	Tokenizer string                 `yaml:"tokenizer,omitempty"`  // per-model override of provider tokenizer
+	Price     *Price                 `yaml:"price,omitempty"`      // token prices, for budgets
+	Budget    *Budget                `yaml:"budget,omitempty"`     // daily spend limit
+
+	// How much thinking Claude Code sees: full (default), hidden, or
+	// summary:N (about N tokens of each thinking block).
+	ReasoningDisplay string `yaml:"reasoning_display,omitempty"`
+}
+
+// Price is what a model costs, in USD per million tokens.
+type Price struct {
+	Input  float64 `yaml:"input"`
+	Output float64 `yaml:"output"`
+}
+
+// Cost returns the USD cost of a request's tokens.
+func (p Price) Cost(inputTokens, outputTokens int) float64 {
+	return (float64(inputTokens)*p.Input + float64(outputTokens)*p.Output) / 1e6
+}
+
+// Budget actions.
+const (
+	BudgetBlock    = "block"    // refuse requests (the default)
+	BudgetWarn     = "warn"     // log once a day and keep routing
+	BudgetFallback = "fallback" // route to the label's fallback instead
+)
+
+// Budget limits a label's spend per local calendar day, priced with its
+// Price and shared by every claude-hybrid instance.
+type Budget struct {
+	DailyUSD float64 `yaml:"daily_usd"`
+	Action   string  `yaml:"action,omitempty"` // BudgetBlock (default), BudgetWarn or BudgetFallback
+}
+
+// UnmarshalYAML allows ModelConfig to be a plain string or a map.
+func (mc *ModelConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		mc.Model = value.Value
+		return nil
+	}
+	type raw ModelConfig
+	return value.Decode((*raw)(mc))
+}
+
+// MarshalYAML writes a ModelConfig with no overrides as a plain string.
+func (mc ModelConfig) MarshalYAML() (interface{}, error) {
+	type raw ModelConfig
+	if reflect.DeepEqual(mc, ModelConfig{Model: mc.Model}) {
+		return mc.Model, nil
+	}
+	return raw(mc), nil
+}
+
+// ProviderConfig represents a single OpenAI-compatible provider.
+type ProviderConfig struct {
+	Name          string                 `yaml:"name"`
+	Group         string                 `yaml:"group,omitempty"` // inherit unset fields from this provider group
+	Endpoint      string                 `yaml:"endpoint"`
+	APIKey        string                 `yaml:"api_key"`
+	API           string                 `yaml:"api,omitempty"`                // backend wire format: "openai" (default) or "anthropic"
+	MaxTokens     int                    `yaml:"max_tokens,omitempty"`         // cap max_tokens for this provider
+	Transform     []string               `yaml:"transform,omitempty"`          // transform chain (auto-detected from name if empty)
+	Params        map[string]interface{} `yaml:"params,omitempty"`             // custom params injected into request body
+	KeepAlive     string                 `yaml:"keep_alive,omitempty"`         // Ollama keep_alive forwarded with each request (e.g. "30m", "-1")
+	MaxConcurrent int                    `yaml:"max_concurrent,omitempty"`     // refuse routes beyond this many in-flight requests (0 = unlimited)
+	Telemetry     *TelemetryConfig       `yaml:"telemetry,omitempty"`          // optional backend host capacity checks
+	Pool          *PoolConfig            `yaml:"pool,omitempty"`               // connection pool tuning
+	SSEMaxLine    int                    `yaml:"sse_max_line_bytes,omitempty"` // longest accepted SSE line from this provider (default 16MB)
+	Headers       map[string]string      `yaml:"headers,omitempty"`            // extra HTTP headers sent with every request
+	Timeout       time.Duration          `yaml:"timeout,omitempty"`            // per-request timeout (default limits.upstream_timeout)
+	Tokenizer     string                 `yaml:"tokenizer,omitempty"`          // token counting: heuristic (default), llamacpp, vllm, tiktoken:<path>
+	Models        map[string]ModelConfig `yaml:"models"`                       // label → backend model name or config
+
+	// Labels for the embeddings endpoint, kept apart from chat labels.
+	EmbeddingModels map[string]EmbeddingConfig `yaml:"embedding_models,omitempty"`
+
+	FirstTokenTimeout time.Duration `yaml:"first_token_timeout,omitempty"` // streams: fail if no token arrives this soon, then no total limit
+	StreamResume      int           `yaml:"stream_resume,omitempty"`       // streams: continue a cut-off stream with a new request, up to this many times
+
+	// Marker prepended to tool results Claude Code flagged is_error (nil =
+	// translate.DefaultToolErrorPrefix, "" = send errors unmarked).
+	ToolErrorPrefix *string `yaml:"tool_error_prefix,omitempty"`
+	// Content of an assistant turn that only calls tools: "omit" (default),
+	// "null" or "empty" (""), for providers that reject the others.
+	EmptyContent string `yaml:"empty_content,omitempty"`
+	// Whether the backend accepts top_k, so Claude Code's is passed on
+	// (nil = by name: Ollama, vLLM, llama.cpp and LM Studio do).
+	SendTopK *bool `yaml:"send_top_k,omitempty"`
+	// Provider finish reasons mapped to Anthropic stop reasons, ahead of
+	// the built-in table (e.g. {abort: max_tokens}).
+	FinishReasons map[string]string `yaml:"finish_reasons,omitempty"`
+	// How thinking blocks are signed: "timestamp" (default) or "hmac", keyed
+	// per conversation so prior thinking is checked before it is sent back.
+	ThinkingSignature string `yaml:"thinking_signature,omitempty"`
+	// What becomes of sources the provider cites (annotations, Perplexity
+	// citations): "references" (default, a list after the text), "blocks"
+	// (Anthropic citations on the text block) or "drop".
+	Citations string `yaml:"citations,omitempty"`
+	// How document (PDF) blocks are sent: "text" (default, extracted
+	// locally), "file" (base64 file parts, for backends that read PDFs) or
+	// "reject" (an invalid_request_error naming the block type).
+	Documents string `yaml:"documents,omitempty"`
+
+	// Instead of api_key: a file holding the key, or a command printing it
+	// (e.g. "op read op://dev/deepseek/key"), read on first use.
+	APIKeyFile string        `yaml:"api_key_file,omitempty"`
+	APIKeyCmd  string        `yaml:"api_key_cmd,omitempty"`
+	APIKeyTTL  time.Duration `yaml:"api_key_ttl,omitempty"` // how long api_key_cmd output is reused (default 15m)
+}
+
+// GroupConfig holds defaults shared by the providers that name it in their
+// group field. A provider's own settings win; headers are merged key by key.
+type GroupConfig struct {
+	Endpoint  string                 `yaml:"endpoint,omitempty"`
+	APIKey    string                 `yaml:"api_key,omitempty"`
+	API       string                 `yaml:"api,omitempty"`
+	MaxTokens int                    `yaml:"max_tokens,omitempty"`
+	Transform []string               `yaml:"transform,omitempty"`
+	Params    map[string]interface{} `yaml:"params,omitempty"`
+	Headers   map[string]string      `yaml:"headers,omitempty"`
+	Timeout   time.Duration          `yaml:"timeout,omitempty"`
+	Tokenizer string                 `yaml:"tokenizer,omitempty"`
+
+	FirstTokenTimeout time.Duration     `yaml:"first_token_timeout,omitempty"`
+	StreamResume      int               `yaml:"stream_resume,omitempty"`
+	ToolErrorPrefix   *string           `yaml:"tool_error_prefix,omitempty"`
+	EmptyContent      string            `yaml:"empty_content,omitempty"`
+	SendTopK          *bool             `yaml:"send_top_k,omitempty"`
+	FinishReasons     map[string]string `yaml:"finish_reasons,omitempty"`
+	ThinkingSignature string            `yaml:"thinking_signature,omitempty"`
+	Citations         string            `yaml:"citations,omitempty"`
+	Documents         string            `yaml:"documents,omitempty"`
+
+	APIKeyFile string        `yaml:"api_key_file,omitempty"`
+	APIKeyCmd  string        `yaml:"api_key_cmd,omitempty"`
+	APIKeyTTL  time.Duration `yaml:"api_key_ttl,omitempty"`
+}
+
+// applyTo returns p with unset fields filled from the group.
+func (g GroupConfig) applyTo(p ProviderConfig) ProviderConfig {
+	if p.Endpoint == "" {
+		p.Endpoint = g.Endpoint
+	}
+	// The key's source is inherited whole: a provider with its own key,
+	// key file or key command ignores the group's.
+	if p.APIKey == "" && p.APIKeyFile == "" && p.APIKeyCmd == "" {
+		p.APIKey, p.APIKeyFile, p.APIKeyCmd = g.APIKey, g.APIKeyFile, g.APIKeyCmd
+	}
+	if p.APIKeyTTL == 0 {
+		p.APIKeyTTL = g.APIKeyTTL
+	}
+	if p.API == "" {
+		p.API = g.API
+	}
+	if p.MaxTokens == 0 {
+		p.MaxTokens = g.MaxTokens
+	}
+	if len(p.Transform) == 0 {
+		p.Transform = g.Transform
+	}
+	if len(p.Params) == 0 {
+		p.Params = g.Params
+	}
+	if p.Timeout == 0 {
+		p.Timeout = g.Timeout
+	}
+	if p.Tokenizer == "" {
+		p.Tokenizer = g.Tokenizer
+	}
+	if p.FirstTokenTimeout == 0 {
+		p.FirstTokenTimeout = g.FirstTokenTimeout
+	}
+	if p.StreamResume == 0 {
+		p.StreamResume = g.StreamResume
+	}
+	if p.ToolErrorPrefix == nil {
+		p.ToolErrorPrefix = g.ToolErrorPrefix
+	}
+	if p.EmptyContent == "" {
+		p.EmptyContent = g.EmptyContent
+	}
+	if p.SendTopK == nil {
+		p.SendTopK = g.SendTopK
+	}
+	if len(p.FinishReasons) == 0 {
+		p.FinishReasons = g.FinishReasons
+	}
+	if p.ThinkingSignature == "" {
+		p.ThinkingSignature = g.ThinkingSignature
+	}
+	if p.Citations == "" {
+		p.Citations = g.Citations
+	}
+	if p.Documents == "" {
+		p.Documents = g.Documents
+	}
+	if len(g.Headers) > 0 {
+		headers := make(map[string]string, len(g.Headers)+len(p.Headers))
+		for k, v := range g.Headers {
+			headers[k] = v
+		}
+		for k, v := range p.Headers {
+			headers[k] = v
+		}
+		p.Headers = headers
+	}
+	return p
+}
+
+// TelemetryConfig enables capacity checks against a provider's host before routing.
+type TelemetryConfig struct {
+	OllamaPS     bool          `yaml:"ollama_ps"`                // poll Ollama's /api/ps for loaded models
+	Interval     time.Duration `yaml:"interval,omitempty"`       // how long a snapshot stays fresh (default 10s)
+	VRAMBudgetMB int           `yaml:"vram_budget_mb,omitempty"` // total VRAM available on the host
+}
+
+// PoolConfig tunes the pooled HTTP transport used for a provider.
+type PoolConfig struct {
+	MaxIdleConns int           `yaml:"max_idle_conns,omitempty"` // idle keep-alive connections kept (default 16)
+	IdleTimeout  time.Duration `yaml:"idle_timeout,omitempty"`   // close idle connections after this long (default 90s)
+}
+
+// DedupeConfig enables reuse of identical background-class local responses
+// across concurrent sessions.
+type DedupeConfig struct {
+	TTL       time.Duration `yaml:"ttl,omitempty"`        // how long a response stays reusable (default 30s)
+	MaxTokens int           `yaml:"max_tokens,omitempty"` // requests above this max_tokens are never deduped (default 512)
+}
+
+// HistoryConfig controls the per-conversation routing history served by
+// the admin API. It is always kept in memory; Persist also writes it to
+// disk so it survives restarts.
+type HistoryConfig struct {
+	Persist   bool          `yaml:"persist,omitempty"`   // append decisions to ~/.claude-hybrid/history/
+	Retention time.Duration `yaml:"retention,omitempty"` // days older than this are deleted at startup (default 720h)
+}
+
+// UpstreamConfig tunes requests passed through to Anthropic. Transforms see
+// Anthropic Messages bodies, so only upstream:* transforms apply.
+type UpstreamConfig struct {
+	Transform   []string               `yaml:"transform"`              // applied in order to every upstream Messages request
+	Params      map[string]interface{} `yaml:"params,omitempty"`       // settings read by the transforms
+	TLSProfiles map[string]string      `yaml:"tls_profiles,omitempty"` // host pattern → ClientHello profile for the upstream leg
+}
+
+// AdminConfig restricts the admin API, for when it is reachable beyond
+// loopback. Tokens may use ${VAR} expansion.
+type AdminConfig struct {
+	ReadOnly   bool   `yaml:"read_only,omitempty"`   // refuse every mutating endpoint
+	ReadToken  string `yaml:"read_token,omitempty"`  // bearer token for GET endpoints
+	WriteToken string `yaml:"write_token,omitempty"` // bearer token for mutating endpoints (also grants read)
+}
+
+// Tokens returns the read and write tokens with environment variables
+// expanded.
+func (a AdminConfig) Tokens() (read, write string) {
+	return expandEnvVars(a.ReadToken), expandEnvVars(a.WriteToken)
+}
+
+// LogConfig sets proxy.log rotation; --log-max-size and --log-retention
+// override it.
+type LogConfig struct {
+	MaxSize   string `yaml:"max_size,omitempty"`  // e.g. "50MB" (default 20MB)
+	Retention int    `yaml:"retention,omitempty"` // compressed logs kept, -1 for none (default 5)
+}
+
+// ProxyAuthConfig requires clients of the proxy to authenticate. Token may
+// use ${VAR} expansion; "auto" generates a fresh token per run, which a
+// launched claude is given automatically.
+type ProxyAuthConfig struct {
+	Token string `yaml:"token,omitempty"`
+}
+
+// ResolvedToken returns Token with environment variables expanded.
+func (a ProxyAuthConfig) ResolvedToken() string {
+	return expandEnvVars(a.Token)
+}
+
+// TracingConfig exports OpenTelemetry spans to a collector over OTLP/HTTP
+// (JSON). The standard OTEL_EXPORTER_OTLP_* and OTEL_SERVICE_NAME
+// environment variables override it.
+type TracingConfig struct {
+	Endpoint    string            `yaml:"endpoint,omitempty"`     // collector base URL, e.g. http://localhost:4318 (/v1/traces is appended)
+	Headers     map[string]string `yaml:"headers,omitempty"`      // sent with each export; values may use ${VAR}
+	ServiceName string            `yaml:"service_name,omitempty"` // default "claude-hybrid"
+}
+
+// ResolvedHeaders returns Headers with environment variables expanded.
+func (t TracingConfig) ResolvedHeaders() map[string]string {
+	if len(t.Headers) == 0 {
+		return nil
+	}
+	h := make(map[string]string, len(t.Headers))
+	for k, v := range t.Headers {
+		h[k] = expandEnvVars(v)
+	}
+	return h
+}
+
+// AnnotationsConfig controls how locally answered responses report their
+// route: X-Hybrid-* response headers (on unless Off) and a summary comment
+// at the end of each stream.
+type AnnotationsConfig struct {
+	Off        bool `yaml:"off,omitempty"`         // send no X-Hybrid-* headers
+	SSEComment bool `yaml:"sse_comment,omitempty"` // end streams with ": hybrid route=local ..."
+}
+
+// AnthropicConfig controls the credentials sent to Anthropic.
+type AnthropicConfig struct {
+	Hosts      []string       `yaml:"hosts,omitempty"`      // hosts that receive Anthropic credentials (default anthropic.com and subdomains)
+	Workspaces []WorkspaceKey `yaml:"workspaces,omitempty"` // per-directory API keys
+}
+
+// WorkspaceKey sends APIKey instead of the client's own credential when
+// claude-hybrid is started in Dir or below it.
+type WorkspaceKey struct {
+	Dir    string `yaml:"dir"`     // ~ is the home directory
+	APIKey string `yaml:"api_key"` // may use ${VAR}
+}
+
+// KeyFor returns the expanded API key of the most specific workspace
+// containing dir, or "" when none does.
+func (a AnthropicConfig) KeyFor(dir string) string {
+	home, _ := os.UserHomeDir()
+	best, key := -1, ""
+	for _, w := range a.Workspaces {
+		wd := w.Dir
+		if strings.HasPrefix(wd, "~/") && home != "" {
+			wd = filepath.Join(home, wd[2:])
+		}
+		wd = filepath.Clean(wd)
+		if dir != wd && !strings.HasPrefix(dir, wd+string(filepath.Separator)) {
+			continue
+		}
+		if len(wd) > best {
+			best, key = len(wd), expandEnvVars(w.APIKey)
+		}
+	}
+	return key
+}
+
+// ProvidersConfig is the top-level config file structure.
+type ProvidersConfig struct {
+	Vars         map[string]string      `yaml:"vars,omitempty"` // values for {NAME} in endpoints; see expandEndpoint
+	Groups       map[string]GroupConfig `yaml:"groups,omitempty"`
+	Providers    []ProviderConfig       `yaml:"providers"`
+	Dedupe       *DedupeConfig          `yaml:"dedupe,omitempty"`
+	History      *HistoryConfig         `yaml:"history,omitempty"`
+	Upstream     *UpstreamConfig        `yaml:"upstream,omitempty"`
+	Limits       *Limits                `yaml:"limits,omitempty"`
+	Intercept    []string               `yaml:"intercept,omitempty"`     // hosts to MITM (default DefaultIntercept); others are tunneled untouched
+	DNSOverrides map[string]string      `yaml:"dns_overrides,omitempty"` // host → address dialed instead; see ParseDNSOverrides
+	Admin        *AdminConfig           `yaml:"admin,omitempty"`
+	LogRedact    *redact.Config         `yaml:"log_redact,omitempty"` // extra secrets scrubbed from proxy.log
+	Log          *LogConfig             `yaml:"log,omitempty"`        // proxy.log rotation
+	Anthropic    *AnthropicConfig       `yaml:"anthropic,omitempty"`
+	ProxyAuth    *ProxyAuthConfig       `yaml:"proxy_auth,omitempty"` // shared secret required on CONNECT and the OpenAI listener
+	Tracing      *TracingConfig         `yaml:"tracing,omitempty"`    // OpenTelemetry span export
+
+	SlowRequestThreshold time.Duration `yaml:"slow_request_threshold,omitempty"` // local routes slower than this log a [SLOW] phase breakdown
+
+	// Marker labels that name other labels; see Candidates.
+	Aliases     map[string]string         `yaml:"aliases,omitempty"`      // marker label → label or label group
+	LabelGroups map[string][]string       `yaml:"label_groups,omitempty"` // marker label → labels tried in order
+	Schedules   map[string][]ScheduleRule `yaml:"schedules,omitempty"`    // marker label → target by time of day
+	Tiers       map[string]TierConfig     `yaml:"tiers,omitempty"`        // model=auto:NAME → small or large label per request
+	Judges      map[string]JudgeConfig    `yaml:"judges,omitempty"`       // model=judge:NAME → label picked by a judge model
+
+	Annotations *AnnotationsConfig `yaml:"annotations,omitempty"` // X-Hybrid-* headers on local responses
+
+	AllowedClients  []string         `yaml:"allowed_clients,omitempty"`   // CIDRs or addresses allowed to CONNECT (loopback always is)
+	ClientRateLimit *ClientRateLimit `yaml:"client_rate_limit,omitempty"` // per client IP
+
+	// Profiles are named overlays picked with --profile; see WithProfile.
+	Profiles map[string]ProvidersConfig `yaml:"profiles,omitempty"`
+}
+
+// Provider wire formats.
+const (
+	APIOpenAI    = "openai"    // OpenAI Chat Completions
+	APIAnthropic = "anthropic" // Anthropic Messages; reachable only through the OpenAI listener
+)
+
+// ResolvedModel holds the result of resolving a model label.
+type ResolvedModel struct {
+	Endpoint  string                 // e.g. "http://localhost:11434/v1"
+	Model     string                 // backend model name, e.g. "qwen3:32b"
+	APIKey    string                 // resolved API key (empty if none); see Key
+	Secret    *SecretKey             // api_key_file or api_key_cmd source (nil = APIKey)
+	Label     string                 // original label, e.g. "fast_coder"
+	Pattern   string                 // the wildcard label this one matched ("" if configured as is)
+	Provider  string                 // provider name, e.g. "ollama"
+	API       string                 // backend wire format: APIOpenAI or APIAnthropic
+	MaxTokens int                    // cap max_tokens (0 = no cap)
+	Transform []string               // transform chain
+	Params    map[string]interface{} // custom params injected into request body
+	Preload   bool                   // warm the model up at proxy start
+	KeepAlive string                 // Ollama keep_alive value ("" = provider default)
+	Fallback  string                 // label suggested when the backend is saturated
+	VRAMMB    int                    // approximate VRAM needed to load the model (0 = unknown)
+	Price     *Price                 // nil = free (no spend is recorded)
+	Budget    *Budget                // nil = unlimited; Action is always set
+
+	MaxConcurrent int               // provider-wide in-flight cap (0 = unlimited)
+	Telemetry     *TelemetryConfig  // provider host capacity checks (nil = disabled)
+	Pool          *PoolConfig       // connection pool tuning (nil = defaults)
+	SSEMaxLine    int               // longest accepted SSE line (0 = 16 MB)
+	Headers       map[string]string // extra HTTP headers for provider requests
+	Timeout       time.Duration     // per-request timeout (0 = limits.UpstreamTimeout)
+	Tokenizer     string            // tokenizer spec for token estimates ("" = heuristic)
+
+	// FirstTokenTimeout, when set, replaces Timeout for streams: the stream
+	// fails if no token arrives within it, and has no total limit after.
+	FirstTokenTimeout time.Duration
+	// StreamResume is how many times a stream cut off mid-text is continued
+	// by a new request carrying the text so far (0 = never).
+	StreamResume int
+	// ToolErrorPrefix marks errored tool results sent to an OpenAI backend
+	// (nil = the translate default, "" = unmarked).
+	ToolErrorPrefix *string
+	// EmptyContent is how an assistant turn that only calls tools carries
+	// its content: "omit" (or ""), "null" or "empty".
+	EmptyContent string
+	// SendTopK passes the request's top_k on to the backend.
+	SendTopK bool
+	// FinishReasons maps provider finish reasons to Anthropic stop reasons
+	// ahead of the built-in table.
+	FinishReasons map[string]string
+	// ThinkingSignature is how thinking blocks are signed: "timestamp" (or
+	// "") or "hmac".
+	ThinkingSignature string
+	// HideReasoning drops thinking blocks from responses; ReasoningSummary,
+	// when set, keeps about this many tokens of each (reasoning_display).
+	HideReasoning    bool
+	ReasoningSummary int
+	// Citations is what becomes of cited sources: "references" (or ""),
+	// "blocks" or "drop".
+	Citations string
+	// Documents is how document blocks are sent: "text" (or ""), "file" or
+	// "reject".
+	Documents string
+}
+
+// ModelResolver resolves model labels to provider details. It is safe for
+// concurrent use; labels can be added at runtime with AddLabel.
+type ModelResolver struct {
+	mu         sync.RWMutex
+	cfg        *ProvidersConfig // source of models, rebuilt by AddLabel
+	models     map[string]ResolvedModel
+	patterns   []labelPattern // wildcard labels, most specific first
+	schedules  map[string]compiledSchedule
+	embeddings map[string]ResolvedEmbedding
+	now        func() time.Time // clock for schedules
+}
+
+var envVarRE = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// expandEnvVars replaces ${VAR} references with environment variable values.
+func expandEnvVars(s string) string {
+	return envVarRE.ReplaceAllStringFunc(s, func(match string) string {
+		varName := envVarRE.FindStringSubmatch(match)[1]
+		return os.Getenv(varName)
+	})
+}
+
+// expandHeaders applies ${VAR} expansion to header values.
+func expandHeaders(h map[string]string) map[string]string {
+	if len(h) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(h))
+	for k, v := range h {
+		out[k] = expandEnvVars(v)
+	}
+	return out
+}
+
+// LoadConfig reads and parses a config file.
+func LoadConfig(path string) (*ProvidersConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseConfig(data, path)
+}
+
+// NewModelResolver builds a resolver from config.
+func NewModelResolver(cfg *ProvidersConfig) (*ModelResolver, error) {
+	models, err := resolveModels(cfg)
+	if err != nil {
+		return nil, err
+	}
+	r := &ModelResolver{now: time.Now}
+	r.set(cfg, models)
+	return r, nil
+}
+
+// resolveModels validates cfg and resolves every label in it.
+func resolveModels(cfg *ProvidersConfig) (map[string]ResolvedModel, error) {
+	models := make(map[string]ResolvedModel)
+	for _, p := range cfg.Providers {
+		base, p, err := resolveProvider(cfg, p)
+		if err != nil {
+			return nil, err
+		}
+		providerTransform := detectTransform(p.Transform, p.Name)
+
+		for label, mc := range p.Models {
+			if _, exists := models[label]; exists {
+				return nil, fmt.Errorf("duplicate model label %q", label)
+			}
+			// Per-model transform overrides provider-level
+			transform := providerTransform
+			if len(mc.Transform) > 0 {
+				transform = mc.Transform
+			}
+			// Per-model max_tokens overrides provider-level (if set)
+			maxTokens := p.MaxTokens
+			if mc.MaxTokens > 0 {
+				maxTokens = mc.MaxTokens
+			}
+			// Per-model params override provider-level
+			params := p.Params
+			if len(mc.Params) > 0 {
+				params = mc.Params
+			}
+			keepAlive := p.KeepAlive
+			if mc.KeepAlive != "" {
+				keepAlive = mc.KeepAlive
+			}
+			tok := p.Tokenizer
+			if mc.Tokenizer != "" {
+				tok = mc.Tokenizer
+			}
+			if err := tokenizer.Validate(tok); err != nil {
+				return nil, fmt.Errorf("model %q: %w", label, err)
+			}
+			hide, summary, err := parseReasoningDisplay(mc.ReasoningDisplay)
+			if err != nil {
+				return nil, fmt.Errorf("model %q: %w", label, err)
+			}
+			budget, err := resolveBudget(mc)
+			if err != nil {
+				return nil, fmt.Errorf("model %q: %w", label, err)
+			}
+			var pattern string
+			if isPattern(label) {
+				if err := checkPattern(label, mc); err != nil {
+					return nil, fmt.Errorf("model %q: %w", label, err)
+				}
+				pattern = label
+			}
+			m := base
+			m.Model = mc.Model
+			m.Label = label
+			m.Pattern = pattern
+			m.MaxTokens = maxTokens
+			m.Transform = transform
+			m.Params = params
+			m.Preload = mc.Preload
+			m.KeepAlive = keepAlive
+			m.Fallback = mc.Fallback
+			m.VRAMMB = mc.VRAMMB
+			m.Price = mc.Price
+			m.Budget = budget
+			m.Tokenizer = tok
+			m.HideReasoning = hide
+			m.ReasoningSummary = summary
+			models[label] = m
+		}
+	}
+	patterns := newPatterns(models)
+	for label, m := range models {
+		if m.Fallback == "" {
+			continue
+		}
+		if f, ok := models[m.Fallback]; ok && f.Pattern == "" {
+			continue
+		}
+		if _, ok := matchPattern(patterns, m.Fallback); !ok {
+			return nil, fmt.Errorf("model %q: fallback label %q not defined", label, m.Fallback)
+		}
+	}
+	if err := checkAliases(cfg, models, patterns); err != nil {
+		return nil, err
+	}
+	if _, err := compileSchedules(cfg, labelChecker(models, patterns)); err != nil {
+		return nil, err
+	}
+	if err := checkTiers(cfg, labelChecker(models, patterns)); err != nil {
+		return nil, err
+	}
+	if err := checkJudges(cfg, labelChecker(models, patterns)); err != nil {
+		return nil, err
+	}
+	if _, err := resolveEmbeddings(cfg, models); err != nil {
+		return nil, err
+	}
+	return models, nil
+}
+
+// resolveProvider applies p's group and returns the settings every label
+// on p shares, with p as the group left it.
+func resolveProvider(cfg *ProvidersConfig, p ProviderConfig) (ResolvedModel, ProviderConfig, error) {
+	if p.Name == "" {
+		return ResolvedModel{}, p, fmt.Errorf("provider missing name")
+	}
+	if p.Group != "" {
+		g, ok := cfg.Groups[p.Group]
+		if !ok {
+			return ResolvedModel{}, p, fmt.Errorf("provider %q: group %q not defined", p.Name, p.Group)
+		}
+		p = g.applyTo(p)
+	}
+	if p.Endpoint == "" {
+		return ResolvedModel{}, p, fmt.Errorf("provider %q missing endpoint", p.Name)
+	}
+	endpoint, err := expandEndpoint(p.Endpoint, cfg.Vars)
+	if err != nil {
+		return ResolvedModel{}, p, fmt.Errorf("provider %q: %w", p.Name, err)
+	}
+	endpoint = strings.TrimRight(endpoint, "/")
+	apiKey := expandEnvVars(p.APIKey)
+	sources := 0
+	for _, v := range []string{p.APIKey, p.APIKeyFile, p.APIKeyCmd} {
+		if v != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return ResolvedModel{}, p, fmt.Errorf("provider %q: set only one of api_key, api_key_file and api_key_cmd", p.Name)
+	}
+	secret := newSecretKey(p)
+	api := p.API
+	switch api {
+	case "":
+		api = APIOpenAI
+	case APIOpenAI, APIAnthropic:
+	default:
+		return ResolvedModel{}, p, fmt.Errorf("provider %q: unknown api %q (want %q or %q)", p.Name, p.API, APIOpenAI, APIAnthropic)
+	}
+	if p.StreamResume < 0 {
+		return ResolvedModel{}, p, fmt.Errorf("provider %q: stream_resume must not be negative", p.Name)
+	}
+	for fr, sr := range p.FinishReasons {
+		if !slices.Contains(stopReasons, sr) {
+			return ResolvedModel{}, p, fmt.Errorf("provider %q: finish_reasons: %q maps to unknown stop reason %q (want one of %s)",
+				p.Name, fr, sr, strings.Join(stopReasons, ", "))
+		}
+	}
+	switch p.EmptyContent {
+	case "", "omit", "null", "empty":
+	default:
+		return ResolvedModel{}, p, fmt.Errorf("provider %q: unknown empty_content %q (want omit, null or empty)", p.Name, p.EmptyContent)
+	}
+	switch p.ThinkingSignature {
+	case "", "timestamp", "hmac":
+	default:
+		return ResolvedModel{}, p, fmt.Errorf("provider %q: unknown thinking_signature %q (want timestamp or hmac)", p.Name, p.ThinkingSignature)
+	}
+	switch p.Citations {
+	case "", "references", "blocks", "drop":
+	default:
+		return ResolvedModel{}, p, fmt.Errorf("provider %q: unknown citations %q (want references, blocks or drop)", p.Name, p.Citations)
+	}
+	switch p.Documents {
+	case "", "text", "file", "reject":
+	default:
+		return ResolvedModel{}, p, fmt.Errorf("provider %q: unknown documents %q (want text, file or reject)", p.Name, p.Documents)
+	}
+	return ResolvedModel{
+		Endpoint: endpoint,
+		APIKey:   apiKey,
+		Secret:   secret,
+		Provider: p.Name,
+		API:      api,
+
+		MaxConcurrent: p.MaxConcurrent,
+		Telemetry:     p.Telemetry,
+		Pool:          p.Pool,
+		SSEMaxLine:    p.SSEMaxLine,
+		Headers:       expandHeaders(p.Headers),
+		Timeout:       p.Timeout,
+
+		FirstTokenTimeout: p.FirstTokenTimeout,
+		StreamResume:      p.StreamResume,
+		ToolErrorPrefix:   p.ToolErrorPrefix,
+		EmptyContent:      p.EmptyContent,
+		SendTopK:          detectTopK(p.SendTopK, p.Name),
+		FinishReasons:     p.FinishReasons,
+		ThinkingSignature: p.ThinkingSignature,
+		Citations:         p.Citations,
+		Documents:         p.Documents,
+	}, p, nil
+}
+
+// defaultReasoningSummary is the summary length of reasoning_display: summary.
+const defaultReasoningSummary = 200
+
+// parseReasoningDisplay parses a model's reasoning_display: full (or ""),
+// hidden, summary or summary:N.
+func parseReasoningDisplay(s string) (hide bool, summary int, err error) {
+	switch s {
+	case "", "full":
+		return false, 0, nil
+	case "hidden":
+		return true, 0, nil
+	case "summary":
+		return false, defaultReasoningSummary, nil
+	}
+	if n, ok := strings.CutPrefix(s, "summary:"); ok {
+		if summary, err := strconv.Atoi(n); err == nil && summary > 0 {
+			return false, summary, nil
+		}
+	}
+	return false, 0, fmt.Errorf("unknown reasoning_display %q (want full, hidden, summary or summary:N tokens)", s)
+}
+
+// resolveBudget validates a model's budget and fills in the default action.
+func resolveBudget(mc ModelConfig) (*Budget, error) {
+	if mc.Price != nil && (mc.Price.Input < 0 || mc.Price.Output < 0) {
+		return nil, fmt.Errorf("price must not be negative")
+	}
+	if mc.Budget == nil {
+		return nil, nil
+	}
+	b := *mc.Budget
+	if b.DailyUSD <= 0 {
+		return nil, fmt.Errorf("budget.daily_usd must be positive")
+	}
+	if mc.Price == nil {
+		return nil, fmt.Errorf("budget needs a price to measure spend against")
+	}
+	switch b.Action {
+	case "":
+		b.Action = BudgetBlock
+	case BudgetBlock, BudgetWarn:
+	case BudgetFallback:
+		if mc.Fallback == "" {
+			return nil, fmt.Errorf("budget action %q needs a fallback label", BudgetFallback)
+		}
+	default:
+		return nil, fmt.Errorf("unknown budget action %q (want %s, %s or %s)", b.Action, BudgetBlock, BudgetWarn, BudgetFallback)
+	}
+	return &b, nil
+}
+
+// detectTransform returns the transform chain to use.
+// If explicit is set, use it. Otherwise auto-detect from provider name with "schema:" prefix.
+func detectTransform(explicit []string, providerName string) []string {
+	if len(explicit) > 0 {
+		return explicit
+	}
+	name := strings.ToLower(providerName)
+	for _, known := range []string{"openai", "gemini", "ollama"} {
+		if strings.Contains(name, known) {
+			return []string{"schema:" + known}
+		}
+	}
+	return []string{"schema:generic"}
+}
+
+// stopReasons are the Anthropic stop reasons finish_reasons may map to.
+var stopReasons = []string{"end_turn", "max_tokens", "stop_sequence", "tool_use", "pause_turn", "refusal"}
+
+// topKBackends are provider name fragments of servers known to accept top_k.
+// OpenAI's API rejects it, so it is only sent where it is known to work.
+var topKBackends = []string{"ollama", "vllm", "llamacpp", "llama.cpp", "llama-cpp", "lmstudio", "lm-studio"}
+
+// detectTopK returns whether to send top_k: explicit if set, otherwise by
+// provider name.
+func detectTopK(explicit *bool, providerName string) bool {
+	if explicit != nil {
+		return *explicit
+	}
+	name := strings.ToLower(providerName)
+	for _, known := range topKBackends {
+		if strings.Contains(name, known) {
+			return true
+		}
+	}
+	return false
+}
+
+// Models returns every resolved model, sorted by label. Wildcard labels
+// are left out; see Patterns.
+func (r *ModelResolver) Models() []ResolvedModel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]ResolvedModel, 0, len(r.models))
+	for _, m := range r.models {
+		if m.Pattern == "" {
+			out = append(out, m)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Label < out[j].Label })
+	return out
+}
+
+// Patterns returns the wildcard labels in the order they are tried, with
+// their model names unexpanded.
+func (r *ModelResolver) Patterns() []ResolvedModel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]ResolvedModel, len(r.patterns))
+	for i, p := range r.patterns {
+		out[i] = p.model
+	}
+	return out
+}
+
+// Resolve looks up a model label and returns its provider details. A label
+// not configured as is may match a wildcard label. An alias resolves to its
+// target, and a label group to its first member; see Candidates.
+func (r *ModelResolver) Resolve(label string) (ResolvedModel, error) {
+	c, err := r.Candidates(label)
+	if err != nil {
+		return ResolvedModel{}, err
+	}
+	return c[0], nil
+}
+
+// set installs a validated config and its resolved models. r.mu must be
+// held, or r not yet shared.
+func (r *ModelResolver) set(cfg *ProvidersConfig, models map[string]ResolvedModel) {
+	r.cfg = cfg
+	r.models = models
+	r.patterns = newPatterns(models)
+	// Validated by resolveModels, so this can't fail.
+	r.schedules, _ = compileSchedules(cfg, labelChecker(models, r.patterns))
+	r.embeddings, _ = resolveEmbeddings(cfg, models)
+}
+
+// lookup finds a configured or wildcard label. r.mu must be held.
+func (r *ModelResolver) lookup(label string) (ResolvedModel, bool) {
+	if m, ok := r.models[label]; ok && m.Pattern == "" {
+		return m, true
+	}
+	return matchPattern(r.patterns, label)
+}
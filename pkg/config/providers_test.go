@@ -0,0 +1,741 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadTestConfig writes yaml to a temp file, loads and resolves it.
+func loadTestConfig(t *testing.T, yaml string) (*ProvidersConfig, *ModelResolver) {
+	t.Helper()
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte(yaml), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	cfg, err := LoadConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	r, err := NewModelResolver(cfg)
+	if err != nil {
+		t.Fatalf("NewModelResolver: %v", err)
+	}
+	return cfg, r
+}
+
+func TestLoadConfigAndResolve(t *testing.T) {
+	cfg, r := loadTestConfig(t, `
+providers:
+  - name: ollama
+    endpoint: http://localhost:11434/v1
+    models:
+      fast_coder: qwen3:32b
+      reasoning: deepseek-r1:14b
+  - name: together
+    endpoint: https://api.together.xyz/v1/
+    api_key: tok_123
+    models:
+      big_coder: deepseek-coder-v2-236b
+`)
+
+	if len(cfg.Providers) != 2 {
+		t.Fatalf("expected 2 providers, got %d", len(cfg.Providers))
+	}
+
+	m, err := r.Resolve("fast_coder")
+	if err != nil {
+		t.Fatalf("Resolve fast_coder: %v", err)
+	}
+	if m.Endpoint != "http://localhost:11434/v1" {
+		t.Errorf("unexpected endpoint: %s", m.Endpoint)
+	}
+	if m.Model != "qwen3:32b" {
+		t.Errorf("unexpected model: %s", m.Model)
+	}
+	if m.Provider != "ollama" {
+		t.Errorf("unexpected provider: %s", m.Provider)
+	}
+
+	m, err = r.Resolve("big_coder")
+	if err != nil {
+		t.Fatalf("Resolve big_coder: %v", err)
+	}
+	if m.APIKey != "tok_123" {
+		t.Errorf("unexpected api key: %s", m.APIKey)
+	}
+	// Trailing slash should be trimmed
+	if m.Endpoint != "https://api.together.xyz/v1" {
+		t.Errorf("unexpected endpoint: %s", m.Endpoint)
+	}
+
+	_, err = r.Resolve("nonexistent")
+	if err == nil {
+		t.Error("expected error for unknown label")
+	}
+
+	// Auto-detect transform from provider name
+	if !reflect.DeepEqual(m.Transform, []string{"schema:generic"}) {
+		t.Errorf("expected [schema:generic] transform for 'together', got %v", m.Transform)
+	}
+	ollamaModel, _ := r.Resolve("fast_coder")
+	if !reflect.DeepEqual(ollamaModel.Transform, []string{"schema:ollama"}) {
+		t.Errorf("expected [schema:ollama] transform, got %v", ollamaModel.Transform)
+	}
+}
+
+func TestExplicitTransform(t *testing.T) {
+	_, r := loadTestConfig(t, `
+providers:
+  - name: my-google-provider
+    endpoint: https://generativelanguage.googleapis.com/v1beta/openai
+    transform: ["gemini"]
+    models:
+      flash: gemini-2.0-flash
+`)
+
+	m, _ := r.Resolve("flash")
+	if !reflect.DeepEqual(m.Transform, []string{"gemini"}) {
+		t.Errorf("expected [gemini] transform, got %v", m.Transform)
+	}
+}
+
+func TestEnvVarExpansion(t *testing.T) {
+	t.Setenv("TEST_API_KEY", "secret_key_value")
+
+	_, r := loadTestConfig(t, `
+providers:
+  - name: remote
+    endpoint: https://api.example.com/v1
+    api_key: ${TEST_API_KEY}
+    models:
+      test_model: gpt-4
+`)
+
+	m, _ := r.Resolve("test_model")
+	if m.APIKey != "secret_key_value" {
+		t.Errorf("expected expanded key, got %q", m.APIKey)
+	}
+}
+
+func TestDuplicateModelLabel(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte(`
+providers:
+  - name: a
+    endpoint: http://localhost:1/v1
+    models:
+      dupe: model-a
+  - name: b
+    endpoint: http://localhost:2/v1
+    models:
+      dupe: model-b
+`), 0644)
+
+	cfg, _ := LoadConfig(cfgPath)
+	_, err := NewModelResolver(cfg)
+	if err == nil {
+		t.Error("expected error for duplicate label")
+	}
+}
+
+func TestMissingEndpoint(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte(`
+providers:
+  - name: bad
+    models:
+      x: y
+`), 0644)
+
+	cfg, _ := LoadConfig(cfgPath)
+	_, err := NewModelResolver(cfg)
+	if err == nil {
+		t.Error("expected error for missing endpoint")
+	}
+}
+
+func TestTransformArray(t *testing.T) {
+	_, r := loadTestConfig(t, `
+providers:
+  - name: local
+    endpoint: http://localhost:11434/v1
+    transform: ["reasoning", "enhancetool"]
+    models:
+      smart: qwen3:32b
+`)
+
+	m, _ := r.Resolve("smart")
+	want := []string{"reasoning", "enhancetool"}
+	if !reflect.DeepEqual(m.Transform, want) {
+		t.Errorf("expected %v, got %v", want, m.Transform)
+	}
+}
+
+func TestTransformPerModel(t *testing.T) {
+	_, r := loadTestConfig(t, `
+providers:
+  - name: local
+    endpoint: http://localhost:11434/v1
+    transform: ["reasoning"]
+    models:
+      default_model: qwen3:32b
+      tool_model:
+        model: qwen3:32b
+        transform: ["tooluse", "enhancetool"]
+`)
+
+	// default_model should inherit provider-level transform
+	dm, _ := r.Resolve("default_model")
+	if !reflect.DeepEqual(dm.Transform, []string{"reasoning"}) {
+		t.Errorf("expected provider-level [reasoning], got %v", dm.Transform)
+	}
+
+	// tool_model should use per-model override
+	tm, _ := r.Resolve("tool_model")
+	want := []string{"tooluse", "enhancetool"}
+	if !reflect.DeepEqual(tm.Transform, want) {
+		t.Errorf("expected per-model %v, got %v", want, tm.Transform)
+	}
+}
+
+func TestTransformAutoDetect(t *testing.T) {
+	_, r := loadTestConfig(t, `
+providers:
+  - name: ollama
+    endpoint: http://localhost:11434/v1
+    models:
+      local: qwen3:32b
+  - name: some-provider
+    endpoint: http://localhost:8080/v1
+    models:
+      remote: some-model
+`)
+
+	m, _ := r.Resolve("local")
+	if !reflect.DeepEqual(m.Transform, []string{"schema:ollama"}) {
+		t.Errorf("expected [schema:ollama], got %v", m.Transform)
+	}
+
+	m, _ = r.Resolve("remote")
+	if !reflect.DeepEqual(m.Transform, []string{"schema:generic"}) {
+		t.Errorf("expected [schema:generic], got %v", m.Transform)
+	}
+}
+
+func TestModelConfigMaxTokens(t *testing.T) {
+	_, r := loadTestConfig(t, `
+providers:
+  - name: local
+    endpoint: http://localhost:11434/v1
+    max_tokens: 4096
+    models:
+      default_cap: qwen3:32b
+      custom_cap:
+        model: qwen3:32b
+        max_tokens: 8192
+`)
+
+	dm, _ := r.Resolve("default_cap")
+	if dm.MaxTokens != 4096 {
+		t.Errorf("expected provider-level 4096, got %d", dm.MaxTokens)
+	}
+
+	cm, _ := r.Resolve("custom_cap")
+	if cm.MaxTokens != 8192 {
+		t.Errorf("expected per-model 8192, got %d", cm.MaxTokens)
+	}
+}
+
+func TestPreloadAndKeepAlive(t *testing.T) {
+	_, r := loadTestConfig(t, `
+providers:
+  - name: ollama
+    endpoint: http://localhost:11434/v1
+    keep_alive: 30m
+    models:
+      coder:
+        model: qwen3:32b
+        preload: true
+      reasoning:
+        model: deepseek-r1:14b
+        keep_alive: "-1"
+      plain: llama3
+`)
+
+	m, _ := r.Resolve("coder")
+	if !m.Preload {
+		t.Error("expected preload for coder")
+	}
+	if m.KeepAlive != "30m" {
+		t.Errorf("expected provider-level keep_alive 30m, got %q", m.KeepAlive)
+	}
+
+	m, _ = r.Resolve("reasoning")
+	if m.Preload {
+		t.Error("reasoning should not preload")
+	}
+	if m.KeepAlive != "-1" {
+		t.Errorf("expected per-model keep_alive -1, got %q", m.KeepAlive)
+	}
+
+	models := r.Models()
+	if len(models) != 3 || models[0].Label != "coder" || models[2].Label != "reasoning" {
+		t.Errorf("expected models sorted by label, got %+v", models)
+	}
+}
+
+func TestFallbackLabelMustExist(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte(`
+providers:
+  - name: ollama
+    endpoint: http://localhost:11434/v1
+    telemetry:
+      ollama_ps: true
+      interval: 5s
+      vram_budget_mb: 24576
+    models:
+      coder:
+        model: qwen3:32b
+        fallback: missing
+`), 0644)
+
+	cfg, err := LoadConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Providers[0].Telemetry.Interval.Seconds() != 5 {
+		t.Errorf("expected 5s interval, got %v", cfg.Providers[0].Telemetry.Interval)
+	}
+	if _, err := NewModelResolver(cfg); err == nil {
+		t.Error("expected error for undefined fallback label")
+	}
+}
+
+func TestProviderAPI(t *testing.T) {
+	_, r := loadTestConfig(t, `
+providers:
+  - name: local-claude
+    endpoint: http://localhost:8080/v1
+    api: anthropic
+    models:
+      native: claude-local
+  - name: ollama
+    endpoint: http://localhost:11434/v1
+    models:
+      fast: qwen3:32b
+`)
+	if m, _ := r.Resolve("native"); m.API != APIAnthropic {
+		t.Errorf("expected anthropic api, got %q", m.API)
+	}
+	if m, _ := r.Resolve("fast"); m.API != APIOpenAI {
+		t.Errorf("expected default openai api, got %q", m.API)
+	}
+
+	_, err := NewModelResolver(&ProvidersConfig{Providers: []ProviderConfig{{
+		Name: "x", Endpoint: "http://x", API: "grpc", Models: map[string]ModelConfig{"a": {Model: "a"}},
+	}}})
+	if err == nil {
+		t.Error("expected error for unknown api")
+	}
+}
+
+func TestResolverFinishReasons(t *testing.T) {
+	r, err := NewModelResolver(&ProvidersConfig{Providers: []ProviderConfig{{
+		Name: "x", Endpoint: "http://x", FinishReasons: map[string]string{"abort": "max_tokens"},
+		Models: map[string]ModelConfig{"a": {Model: "a"}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := r.Resolve("a"); m.FinishReasons["abort"] != "max_tokens" {
+		t.Errorf("FinishReasons = %v", m.FinishReasons)
+	}
+	_, err = NewModelResolver(&ProvidersConfig{Providers: []ProviderConfig{{
+		Name: "x", Endpoint: "http://x", FinishReasons: map[string]string{"abort": "truncated"},
+		Models: map[string]ModelConfig{"a": {Model: "a"}},
+	}}})
+	if err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("expected error naming the unknown stop reason, got %v", err)
+	}
+}
+
+func TestDetectTopK(t *testing.T) {
+	no := false
+	tests := []struct {
+		name     string
+		explicit *bool
+		want     bool
+	}{
+		{"ollama", nil, true},
+		{"vllm-box", nil, true},
+		{"LMStudio", nil, true},
+		{"deepseek", nil, false},
+		{"ollama", &no, false},
+	}
+	for _, tc := range tests {
+		if got := detectTopK(tc.explicit, tc.name); got != tc.want {
+			t.Errorf("detectTopK(%v, %q) = %v, want %v", tc.explicit, tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestResolverEmptyContent(t *testing.T) {
+	r, err := NewModelResolver(&ProvidersConfig{Providers: []ProviderConfig{{
+		Name: "mistral", Endpoint: "http://x", EmptyContent: "null", Models: map[string]ModelConfig{"a": {Model: "a"}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := r.Resolve("a"); m.EmptyContent != "null" {
+		t.Errorf("EmptyContent = %q, want null", m.EmptyContent)
+	}
+	_, err = NewModelResolver(&ProvidersConfig{Providers: []ProviderConfig{{
+		Name: "x", Endpoint: "http://x", EmptyContent: "none", Models: map[string]ModelConfig{"a": {Model: "a"}},
+	}}})
+	if err == nil {
+		t.Error("expected error for unknown empty_content")
+	}
+}
+
+func TestResolverThinkingSignature(t *testing.T) {
+	r, err := NewModelResolver(&ProvidersConfig{Providers: []ProviderConfig{{
+		Name: "local", Endpoint: "http://x", ThinkingSignature: "hmac", Models: map[string]ModelConfig{"a": {Model: "a"}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := r.Resolve("a"); m.ThinkingSignature != "hmac" {
+		t.Errorf("ThinkingSignature = %q, want hmac", m.ThinkingSignature)
+	}
+	_, err = NewModelResolver(&ProvidersConfig{Providers: []ProviderConfig{{
+		Name: "x", Endpoint: "http://x", ThinkingSignature: "rsa", Models: map[string]ModelConfig{"a": {Model: "a"}},
+	}}})
+	if err == nil {
+		t.Error("expected error for unknown thinking_signature")
+	}
+}
+
+func TestResolverCitations(t *testing.T) {
+	r, err := NewModelResolver(&ProvidersConfig{Providers: []ProviderConfig{{
+		Name: "perplexity", Endpoint: "http://x", Citations: "blocks", Models: map[string]ModelConfig{"a": {Model: "a"}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := r.Resolve("a"); m.Citations != "blocks" {
+		t.Errorf("Citations = %q, want blocks", m.Citations)
+	}
+	_, err = NewModelResolver(&ProvidersConfig{Providers: []ProviderConfig{{
+		Name: "x", Endpoint: "http://x", Citations: "footnotes", Models: map[string]ModelConfig{"a": {Model: "a"}},
+	}}})
+	if err == nil {
+		t.Error("expected error for unknown citations")
+	}
+}
+
+func TestResolverDocuments(t *testing.T) {
+	r, err := NewModelResolver(&ProvidersConfig{
+		Groups: map[string]GroupConfig{"pdf": {Documents: "file"}},
+		Providers: []ProviderConfig{{
+			Name: "x", Endpoint: "http://x", Group: "pdf", Models: map[string]ModelConfig{"a": {Model: "a"}},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := r.Resolve("a"); m.Documents != "file" {
+		t.Errorf("Documents = %q, want file (from group)", m.Documents)
+	}
+	_, err = NewModelResolver(&ProvidersConfig{Providers: []ProviderConfig{{
+		Name: "x", Endpoint: "http://x", Documents: "ocr", Models: map[string]ModelConfig{"a": {Model: "a"}},
+	}}})
+	if err == nil {
+		t.Error("expected error for unknown documents")
+	}
+}
+
+func TestParseReasoningDisplay(t *testing.T) {
+	tests := []struct {
+		in      string
+		hide    bool
+		summary int
+		ok      bool
+	}{
+		{"", false, 0, true},
+		{"full", false, 0, true},
+		{"hidden", true, 0, true},
+		{"summary", false, defaultReasoningSummary, true},
+		{"summary:50", false, 50, true},
+		{"summary:0", false, 0, false},
+		{"summary:x", false, 0, false},
+		{"brief", false, 0, false},
+	}
+	for _, tc := range tests {
+		hide, summary, err := parseReasoningDisplay(tc.in)
+		if (err == nil) != tc.ok || hide != tc.hide || summary != tc.summary {
+			t.Errorf("parseReasoningDisplay(%q) = %v, %d, %v", tc.in, hide, summary, err)
+		}
+	}
+}
+
+func TestProviderGroups(t *testing.T) {
+	t.Setenv("TEST_GROUP_KEY", "sk-shared")
+	_, r := loadTestConfig(t, `
+groups:
+  openrouter:
+    endpoint: https://openrouter.ai/api/v1
+    api_key: ${TEST_GROUP_KEY}
+    transform: [openrouter]
+    timeout: 2m
+    first_token_timeout: 20s
+    tool_error_prefix: "ERROR: "
+    headers:
+      HTTP-Referer: https://example.com
+      X-Title: shared
+providers:
+  - name: or-fast
+    group: openrouter
+    models:
+      fast: qwen/qwen3-32b
+  - name: or-custom
+    group: openrouter
+    api_key: sk-own
+    timeout: 10s
+    first_token_timeout: 5s
+    tool_error_prefix: ""
+    headers:
+      X-Title: custom
+    models:
+      custom: meta/llama-3
+`)
+	fast, _ := r.Resolve("fast")
+	if fast.Endpoint != "https://openrouter.ai/api/v1" || fast.APIKey != "sk-shared" || fast.Timeout != 2*time.Minute {
+		t.Errorf("group defaults not inherited: %+v", fast)
+	}
+	if !reflect.DeepEqual(fast.Transform, []string{"openrouter"}) {
+		t.Errorf("transform not inherited: %v", fast.Transform)
+	}
+	if fast.FirstTokenTimeout != 20*time.Second {
+		t.Errorf("first_token_timeout not inherited: %s", fast.FirstTokenTimeout)
+	}
+	custom, _ := r.Resolve("custom")
+	if custom.APIKey != "sk-own" || custom.Timeout != 10*time.Second || custom.FirstTokenTimeout != 5*time.Second {
+		t.Errorf("provider overrides lost: %+v", custom)
+	}
+	if fast.ToolErrorPrefix == nil || *fast.ToolErrorPrefix != "ERROR: " {
+		t.Errorf("tool_error_prefix not inherited: %v", fast.ToolErrorPrefix)
+	}
+	if custom.ToolErrorPrefix == nil || *custom.ToolErrorPrefix != "" {
+		t.Errorf("empty tool_error_prefix did not override the group: %v", custom.ToolErrorPrefix)
+	}
+	want := map[string]string{"HTTP-Referer": "https://example.com", "X-Title": "custom"}
+	if !reflect.DeepEqual(custom.Headers, want) {
+		t.Errorf("headers = %v, want %v", custom.Headers, want)
+	}
+	if fast.Headers["X-Title"] != "shared" {
+		t.Errorf("override leaked into sibling provider: %v", fast.Headers)
+	}
+
+	_, err := NewModelResolver(&ProvidersConfig{Providers: []ProviderConfig{{
+		Name: "x", Group: "missing", Endpoint: "http://x", Models: map[string]ModelConfig{"a": {Model: "a"}},
+	}}})
+	if err == nil {
+		t.Error("expected error for undefined group")
+	}
+}
+
+func TestLimitsConfig(t *testing.T) {
+	cfg, _ := loadTestConfig(t, `
+limits:
+  max_body_bytes: 52428800
+  upstream_timeout: 5m
+  max_concurrent: 512
+  max_queued: -1
+providers: []
+`)
+	got := DefaultLimits().Merge(*cfg.Limits)
+	want := DefaultLimits()
+	want.MaxBodyBytes = 50 << 20
+	want.UpstreamTimeout = 5 * time.Minute
+	want.MaxConcurrent = 512
+	want.MaxQueued = -1
+	if got != want {
+		t.Errorf("limits = %+v, want %+v", got, want)
+	}
+
+	// Later layers (flags) override only what they set.
+	got = got.Merge(Limits{UpstreamTimeout: time.Minute})
+	if got.UpstreamTimeout != time.Minute || got.MaxConcurrent != 512 {
+		t.Errorf("flag overlay: %+v", got)
+	}
+}
+
+func TestTokenizer(t *testing.T) {
+	_, r := loadTestConfig(t, `
+providers:
+  - name: local
+    endpoint: http://localhost:8080/v1
+    tokenizer: llamacpp
+    models:
+      default_tok: qwen3
+      custom_tok:
+        model: qwen3
+        tokenizer: tiktoken:/opt/cl100k_base.tiktoken
+`)
+	if m, _ := r.Resolve("default_tok"); m.Tokenizer != "llamacpp" {
+		t.Errorf("expected provider-level llamacpp, got %q", m.Tokenizer)
+	}
+	if m, _ := r.Resolve("custom_tok"); m.Tokenizer != "tiktoken:/opt/cl100k_base.tiktoken" {
+		t.Errorf("expected per-model tiktoken, got %q", m.Tokenizer)
+	}
+
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	os.WriteFile(cfgPath, []byte(`
+providers:
+  - name: local
+    endpoint: http://localhost:8080/v1
+    models:
+      bad:
+        model: qwen3
+        tokenizer: sentencepiece
+`), 0644)
+	cfg, err := LoadConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if _, err := NewModelResolver(cfg); err == nil || !strings.Contains(err.Error(), "sentencepiece") {
+		t.Errorf("expected unknown tokenizer error, got %v", err)
+	}
+}
+
+func TestAnthropicWorkspaceKey(t *testing.T) {
+	t.Setenv("CLIENT_A_KEY", "sk-ant-client-a")
+	a := AnthropicConfig{Workspaces: []WorkspaceKey{
+		{Dir: "/work", APIKey: "sk-ant-work"},
+		{Dir: "/work/client-a/", APIKey: "${CLIENT_A_KEY}"},
+	}}
+	for dir, want := range map[string]string{
+		"/work/client-a/api": "sk-ant-client-a",
+		"/work/client-a":     "sk-ant-client-a",
+		"/work/client-ab":    "sk-ant-work",
+		"/home/me":           "",
+	} {
+		if got := a.KeyFor(dir); got != want {
+			t.Errorf("KeyFor(%q) = %q, want %q", dir, got, want)
+		}
+	}
+}
+
+func TestAllowedClients(t *testing.T) {
+	cfg, _ := loadTestConfig(t, `
+providers: []
+allowed_clients: [192.168.1.0/24, 10.0.0.5, "fd00::/8"]
+client_rate_limit:
+  requests_per_minute: 120
+`)
+	got, err := ParseClients(cfg.AllowedClients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s []string
+	for _, p := range got {
+		s = append(s, p.String())
+	}
+	if want := []string{"192.168.1.0/24", "10.0.0.5/32", "fd00::/8"}; !reflect.DeepEqual(s, want) {
+		t.Errorf("prefixes = %v, want %v", s, want)
+	}
+	if cfg.ClientRateLimit.RequestsPerMinute != 120 {
+		t.Errorf("rate limit = %+v", cfg.ClientRateLimit)
+	}
+	if _, err := ParseClients([]string{"192.168.1.0/33"}); err == nil {
+		t.Error("expected error for bad CIDR")
+	}
+	if _, err := ParseClients([]string{"lan"}); err == nil {
+		t.Error("expected error for hostname")
+	}
+}
+
+func TestDNSOverrides(t *testing.T) {
+	cfg, _ := loadTestConfig(t, `
+providers: []
+dns_overrides:
+  API.Anthropic.com: 127.0.0.1:8080
+  statsig.anthropic.com: 10.0.0.2
+  "[fd00::1]": "::1"
+  gw.example.com: http://localhost:4000
+`)
+	got, err := ParseDNSOverrides(cfg.DNSOverrides)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"api.anthropic.com":     "127.0.0.1:8080",
+		"statsig.anthropic.com": "10.0.0.2",
+		"fd00::1":               "::1",
+		"gw.example.com":        "http://localhost:4000",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("overrides = %v, want %v", got, want)
+	}
+	for _, bad := range []map[string]string{
+		{"api.anthropic.com:443": "127.0.0.1"},
+		{"api.anthropic.com": "127.0.0.1:99999"},
+		{"api.anthropic.com": "http://localhost"},
+		{"api.anthropic.com": "localhost:80:80"},
+		{"api.anthropic.com": ""},
+	} {
+		if _, err := ParseDNSOverrides(bad); err == nil {
+			t.Errorf("expected error for %v", bad)
+		}
+	}
+}
+
+func TestBudget(t *testing.T) {
+	_, r := loadTestConfig(t, `
+providers:
+  - name: openrouter
+    endpoint: https://openrouter.ai/api/v1
+    models:
+      coder:
+        model: deepseek/deepseek-chat
+        price: {input: 0.27, output: 1.10}
+        budget: {daily_usd: 5}
+        fallback: local
+      local: qwen3
+`)
+	m, _ := r.Resolve("coder")
+	if m.Budget == nil || m.Budget.DailyUSD != 5 || m.Budget.Action != BudgetBlock {
+		t.Errorf("budget = %+v, want $5 with the default block action", m.Budget)
+	}
+	if got := m.Price.Cost(1_000_000, 500_000); got < 0.819 || got > 0.821 {
+		t.Errorf("cost = %v, want 0.82", got)
+	}
+
+	for model, want := range map[string]string{
+		"{model: m, budget: {daily_usd: 5}}":                                      "needs a price",
+		"{model: m, price: {input: 1}, budget: {daily_usd: 0}}":                   "must be positive",
+		"{model: m, price: {input: 1}, budget: {daily_usd: 5, action: fallback}}": "needs a fallback",
+		"{model: m, price: {input: 1}, budget: {daily_usd: 5, action: shutdown}}": "unknown budget action",
+		"{model: m, price: {input: -1}}":                                          "negative",
+	} {
+		cfg := &ProvidersConfig{}
+		if err := yaml.Unmarshal([]byte("providers: [{name: p, endpoint: http://x/v1, models: {l: "+model+"}}]"), cfg); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewModelResolver(cfg); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: error %v, want %q", model, err, want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScheduleRule is one entry of a schedule: the label to route to while the
+// local time matches When. A rule without When always matches, so it is the
+// schedule's default when it comes last.
+type ScheduleRule struct {
+	When  string `yaml:"when,omitempty"` // cron-style: minute hour day-of-month month day-of-week
+	Label string `yaml:"label"`          // a label, alias or label group
+}
+
+// A schedule is a marker label whose target depends on the time of day:
+//
+//	schedules:
+//	  background:
+//	    - when: "* 9-17 * * mon-fri"  # work hours: the GPU is busy
+//	      label: groq_llama
+//	    - label: local_gpu
+//
+// Rules are tried in order and the first whose When matches the current
+// local time wins.
+
+// cronField bounds and names, in field order.
+var cronFields = []struct {
+	name     string
+	min, max int
+	names    []string // names for min, min+1, ...
+}{
+	{"minute", 0, 59, nil},
+	{"hour", 0, 23, nil},
+	{"day of month", 1, 31, nil},
+	{"month", 1, 12, []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{"day of week", 0, 7, []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// cronSpec is a parsed When: one bit per allowed value of each field.
+type cronSpec struct {
+	fields  [5]uint64
+	domStar bool // day of month was *
+	dowStar bool // day of week was *
+}
+
+// parseCron parses a five-field cron expression. Each field is *, a value,
+// a range a-b, or a list of these, each optionally with a /step. Months and
+// weekdays may be written as three-letter names; Sunday is 0 or 7.
+func parseCron(s string) (*cronSpec, error) {
+	parts := strings.Fields(s)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("when %q: want 5 fields (minute hour day-of-month month day-of-week), got %d", s, len(parts))
+	}
+	var c cronSpec
+	for i, part := range parts {
+		bits, err := parseCronField(part, i)
+		if err != nil {
+			return nil, fmt.Errorf("when %q: %s: %v", s, cronFields[i].name, err)
+		}
+		c.fields[i] = bits
+	}
+	if c.fields[4]&(1<<7) != 0 {
+		c.fields[4] |= 1 // 7 is Sunday too
+	}
+	c.domStar = parts[2] == "*"
+	c.dowStar = parts[4] == "*"
+	return &c, nil
+}
+
+func parseCronField(s string, field int) (uint64, error) {
+	f := cronFields[field]
+	var bits uint64
+	for _, item := range strings.Split(s, ",") {
+		rng, stepStr, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("bad step %q", stepStr)
+			}
+			step = n
+		}
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = cronValue(a, field); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = cronValue(b, field); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				hi = f.max
+			}
+			if hi < lo {
+				return 0, fmt.Errorf("range %q runs backwards", rng)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func cronValue(s string, field int) (int, error) {
+	f := cronFields[field]
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("%q is not from %d to %d", s, f.min, f.max)
+	}
+	return n, nil
+}
+
+// match reports whether t falls in the spec. As in cron, when both day
+// fields are restricted a day matching either one matches.
+func (c *cronSpec) match(t time.Time) bool {
+	has := func(field, v int) bool { return c.fields[field]&(1<<v) != 0 }
+	if !has(0, t.Minute()) || !has(1, t.Hour()) || !has(3, int(t.Month())) {
+		return false
+	}
+	dom, dow := has(2, t.Day()), has(4, int(t.Weekday()))
+	switch {
+	case c.domStar && c.dowStar:
+		return true
+	case c.domStar:
+		return dow
+	case c.dowStar:
+		return dom
+	}
+	return dom || dow
+}
+
+// scheduleRule is a ScheduleRule with When parsed; a nil spec always
+// matches.
+type scheduleRule struct {
+	spec  *cronSpec
+	label string
+}
+
+type compiledSchedule []scheduleRule
+
+// compileSchedules parses and validates cfg's schedules. Targets may be
+// labels, aliases or label groups.
+func compileSchedules(cfg *ProvidersConfig, isLabel func(string) bool) (map[string]compiledSchedule, error) {
+	out := make(map[string]compiledSchedule, len(cfg.Schedules))
+	for _, name := range sortedKeys(cfg.Schedules) {
+		rules := cfg.Schedules[name]
+		if isLabel(name) {
+			return nil, fmt.Errorf("schedule %q is also a model label", name)
+		}
+		if _, ok := cfg.Aliases[name]; ok {
+			return nil, fmt.Errorf("%q is both a schedule and an alias", name)
+		}
+		if _, ok := cfg.LabelGroups[name]; ok {
+			return nil, fmt.Errorf("%q is both a schedule and a label group", name)
+		}
+		if len(rules) == 0 {
+			return nil, fmt.Errorf("schedule %q has no rules", name)
+		}
+		var cs compiledSchedule
+		for i, rule := range rules {
+			_, isAlias := cfg.Aliases[rule.Label]
+			_, isGroup := cfg.LabelGroups[rule.Label]
+			if _, ok := cfg.Schedules[rule.Label]; ok {
+				return nil, fmt.Errorf("schedule %q: %q is a schedule; schedules can't nest", name, rule.Label)
+			}
+			if !isAlias && !isGroup && !isLabel(rule.Label) {
+				return nil, fmt.Errorf("schedule %q: label %q not defined", name, rule.Label)
+			}
+			var spec *cronSpec
+			if rule.When != "" {
+				var err error
+				if spec, err = parseCron(rule.When); err != nil {
+					return nil, fmt.Errorf("schedule %q: %w", name, err)
+				}
+			} else if i < len(rules)-1 {
+				return nil, fmt.Errorf("schedule %q: only the last rule can leave out when", name)
+			}
+			cs = append(cs, scheduleRule{spec, rule.Label})
+		}
+		out[name] = cs
+	}
+	return out, nil
+}
+
+// pick returns the label of the first rule matching t.
+func (s compiledSchedule) pick(t time.Time) (string, bool) {
+	for _, r := range s {
+		if r.spec == nil || r.spec.match(t) {
+			return r.label, true
+		}
+	}
+	return "", false
+}
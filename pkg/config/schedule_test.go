@@ -0,0 +1,114 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	// 2026-03-02 is a Monday.
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 3, day, hour, minute, 0, 0, time.Local)
+	}
+	tests := []struct {
+		when string
+		t    time.Time
+		want bool
+	}{
+		{"* * * * *", at(2, 3, 4), true},
+		{"* 9-17 * * mon-fri", at(2, 9, 0), true},
+		{"* 9-17 * * mon-fri", at(2, 17, 59), true},
+		{"* 9-17 * * mon-fri", at(2, 18, 0), false},
+		{"* 9-17 * * mon-fri", at(7, 12, 0), false}, // Saturday
+		{"* 22-23,0-6 * * *", at(3, 23, 30), true},
+		{"* 22-23,0-6 * * *", at(3, 7, 0), false},
+		{"*/15 * * * *", at(2, 1, 45), true},
+		{"*/15 * * * *", at(2, 1, 46), false},
+		{"30/10 * * * *", at(2, 1, 50), true},
+		{"* * * * 7", at(8, 12, 0), true}, // Sunday as 7
+		{"* * * mar *", at(2, 0, 0), true},
+		{"* * 1 * mon", at(2, 0, 0), true}, // either day field matches
+		{"* * 1 * tue", at(2, 0, 0), false},
+	}
+	for _, tt := range tests {
+		c, err := parseCron(tt.when)
+		if err != nil {
+			t.Errorf("parseCron(%q): %v", tt.when, err)
+			continue
+		}
+		if got := c.match(tt.t); got != tt.want {
+			t.Errorf("%q matches %s = %v, want %v", tt.when, tt.t.Format("Mon 15:04"), got, tt.want)
+		}
+	}
+
+	for when, want := range map[string]string{
+		"* * * *":        "want 5 fields",
+		"60 * * * *":     `minute: "60" is not from 0 to 59`,
+		"* 17-9 * * *":   `hour: range "17-9" runs backwards`,
+		"* * * * funday": `day of week: "funday"`,
+		"*/0 * * * *":    `bad step "0"`,
+	} {
+		if _, err := parseCron(when); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("parseCron(%q) err = %v, want %q", when, err, want)
+		}
+	}
+}
+
+func TestSchedules(t *testing.T) {
+	_, r := loadTestConfig(t, `
+providers:
+  - name: ollama
+    endpoint: http://localhost:11434/v1
+    models:
+      local_gpu: qwen3:32b
+  - name: groq
+    endpoint: https://api.groq.com/openai/v1
+    models:
+      groq_llama: llama-3.3-70b-versatile
+label_groups:
+  cloud: [groq_llama, local_gpu]
+schedules:
+  background:
+    - when: "* 9-17 * * mon-fri"
+      label: cloud
+    - label: local_gpu
+  weekend_only:
+    - when: "* * * * sat,sun"
+      label: local_gpu
+`)
+	r.now = func() time.Time { return time.Date(2026, 3, 2, 10, 0, 0, 0, time.Local) } // Monday
+	c, err := r.Candidates("background")
+	if err != nil || len(c) != 2 || c[0].Label != "groq_llama" {
+		t.Errorf("work hours: %v, %v; want the cloud group", c, err)
+	}
+	if _, err := r.Resolve("weekend_only"); err == nil || !strings.Contains(err.Error(), `schedule "weekend_only" has no rule for Mon 10:00`) {
+		t.Errorf("no matching rule: err = %v", err)
+	}
+
+	r.now = func() time.Time { return time.Date(2026, 3, 2, 22, 0, 0, 0, time.Local) }
+	if m, _ := r.Resolve("background"); m.Label != "local_gpu" {
+		t.Errorf("night: routed to %s, want local_gpu", m.Label)
+	}
+}
+
+func TestScheduleErrors(t *testing.T) {
+	tests := []struct{ extra, want string }{
+		{"schedules: {s: []}", `schedule "s" has no rules`},
+		{"schedules: {a: [{label: b}]}", `schedule "a" is also a model label`},
+		{"schedules: {s: [{label: nope}]}", `schedule "s": label "nope" not defined`},
+		{"schedules: {s: [{label: a}, {when: '* * * * *', label: b}]}", "only the last rule can leave out when"},
+		{"schedules: {s: [{when: '* 25 * * *', label: a}]}", `hour: "25" is not from 0 to 23`},
+		{"schedules: {s: [{label: a}], t: [{label: s}]}", `schedule "t": "s" is a schedule`},
+		{"schedules: {s: [{label: a}]}\naliases: {x: s}", `alias "x" points at schedule "s"`},
+	}
+	for _, tt := range tests {
+		cfg, err := parseConfig([]byte("providers:\n  - name: p\n    endpoint: http://p/v1\n    models: {a: a, b: b}\n"+tt.extra+"\n"), "config.yaml")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.extra, err)
+		}
+		if _, err := NewModelResolver(cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.extra, err, tt.want)
+		}
+	}
+}
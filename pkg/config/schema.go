@@ -0,0 +1,368 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The schema and the load-time check are both derived from the Go types'
+// yaml tags, so a field added to the config is covered by each without
+// further changes.
+
+var (
+	durationType    = reflect.TypeOf(time.Duration(0))
+	modelConfigType = reflect.TypeOf(ModelConfig{})
+)
+
+// shorthandTypes can be written as a plain string, the backend model name.
+var shorthandTypes = map[reflect.Type]bool{
+	modelConfigType:                   true,
+	reflect.TypeOf(EmbeddingConfig{}): true,
+}
+
+// schemaEnums lists the values allowed for string fields with a fixed set,
+// keyed by Go type and yaml key.
+var schemaEnums = map[string][]string{
+	"ProviderConfig.api": {APIOpenAI, APIAnthropic},
+	"GroupConfig.api":    {APIOpenAI, APIAnthropic},
+	"Budget.action":      {BudgetBlock, BudgetWarn, BudgetFallback},
+}
+
+// Schema returns a JSON Schema (draft 2020-12) for config.yaml, for editor
+// completion and external validation.
+func Schema() map[string]interface{} {
+	g := &schemaGen{defs: make(map[string]interface{})}
+	root := g.typeSchema(reflect.TypeOf(ProvidersConfig{}))
+	return map[string]interface{}{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$id":     "https://github.com/peter-wagstaff/claude-hybrid-router/config.schema.json",
+		"title":   "claude-hybrid config.yaml",
+		"$ref":    root["$ref"],
+		"$defs":   g.defs,
+	}
+}
+
+type schemaGen struct {
+	defs map[string]interface{}
+}
+
+// typeSchema returns the schema for t. Named structs are added to $defs
+// and referenced, which also covers the recursive profiles.
+func (g *schemaGen) typeSchema(t reflect.Type) map[string]interface{} {
+	switch {
+	case t == durationType:
+		return map[string]interface{}{"type": "string", "pattern": `^-?([0-9]+(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+$|^0$`}
+	case t.Kind() == reflect.Ptr:
+		return g.typeSchema(t.Elem())
+	case t.Kind() == reflect.Struct:
+		name := t.Name()
+		if pkg := t.PkgPath(); pkg != modelConfigType.PkgPath() {
+			// Other packages' types, e.g. redact.Config as RedactConfig.
+			base := pkg[strings.LastIndex(pkg, "/")+1:]
+			name = strings.ToUpper(base[:1]) + base[1:] + name
+		}
+		ref := map[string]interface{}{"$ref": "#/$defs/" + name}
+		if _, ok := g.defs[name]; ok {
+			return ref
+		}
+		g.defs[name] = nil // placeholder while the fields are generated
+		props := make(map[string]interface{})
+		for _, f := range yamlFields(t) {
+			s := g.typeSchema(f.typ)
+			if enum, ok := schemaEnums[name+"."+f.key]; ok {
+				s = map[string]interface{}{"type": "string", "enum": enum}
+			}
+			props[f.key] = s
+		}
+		obj := map[string]interface{}{"type": "object", "properties": props, "additionalProperties": false}
+		if shorthandTypes[t] {
+			// A model is a backend model name or a map of its settings.
+			obj = map[string]interface{}{"oneOf": []interface{}{map[string]interface{}{"type": "string"}, obj}}
+		}
+		g.defs[name] = obj
+		return ref
+	case t.Kind() == reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": g.typeSchema(t.Elem())}
+	case t.Kind() == reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.typeSchema(t.Elem())}
+	case t.Kind() == reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+	return map[string]interface{}{} // interface{}: any value
+}
+
+type yamlField struct {
+	key string
+	typ reflect.Type
+}
+
+// yamlFields returns the fields of struct t by yaml key, in order.
+func yamlFields(t reflect.Type) []yamlField {
+	var out []yamlField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("yaml")
+		key, _, _ := strings.Cut(tag, ",")
+		if !f.IsExported() || key == "-" {
+			continue
+		}
+		if key == "" {
+			key = strings.ToLower(f.Name)
+		}
+		out = append(out, yamlField{key: key, typ: f.Type})
+	}
+	return out
+}
+
+// ConfigError is a problem at a position in a config file.
+type ConfigError struct {
+	File         string
+	Line, Column int
+	Path         string // dotted location, e.g. providers[0].models.fast
+	Msg          string
+}
+
+func (e ConfigError) Error() string {
+	msg := e.Msg
+	if e.Path != "" {
+		msg = e.Path + ": " + msg
+	}
+	if e.File == "" {
+		return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, msg)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, msg)
+}
+
+// ConfigErrors is every problem found in a config file, one per line.
+type ConfigErrors []ConfigError
+
+func (e ConfigErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseConfig decodes a config file after checking it against the config
+// types, so unknown keys and mistyped values are reported with their
+// positions instead of being ignored or reported by line alone.
+func parseConfig(data []byte, file string) (*ProvidersConfig, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", file, err)
+	}
+	var cfg ProvidersConfig
+	if len(doc.Content) == 0 {
+		return &cfg, nil
+	}
+	if errs := checkNode(&doc); len(errs) > 0 {
+		for i := range errs {
+			errs[i].File = file
+		}
+		return nil, errs
+	}
+	if err := doc.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", file, err)
+	}
+	return &cfg, nil
+}
+
+// maxConfigErrors bounds how many problems are reported at once.
+const maxConfigErrors = 10
+
+// checkNode validates a parsed config document against ProvidersConfig,
+// reporting unknown keys and values of the wrong type with their
+// positions.
+func checkNode(doc *yaml.Node) ConfigErrors {
+	var errs ConfigErrors
+	if doc.Kind == yaml.DocumentNode {
+		if len(doc.Content) == 0 {
+			return nil
+		}
+		doc = doc.Content[0]
+	}
+	checkValue(doc, reflect.TypeOf(ProvidersConfig{}), "", &errs)
+	if len(errs) > maxConfigErrors {
+		errs = errs[:maxConfigErrors]
+	}
+	return errs
+}
+
+func checkValue(n *yaml.Node, t reflect.Type, path string, errs *ConfigErrors) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!null" {
+		return
+	}
+	bad := func(format string, args ...interface{}) {
+		*errs = append(*errs, ConfigError{Line: n.Line, Column: n.Column, Path: path, Msg: fmt.Sprintf(format, args...)})
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == durationType:
+		if n.Kind != yaml.ScalarNode {
+			bad("want a duration such as 30s, got %s", nodeKind(n))
+		} else if _, err := time.ParseDuration(n.Value); err != nil {
+			bad("want a duration such as 30s or 2m, got %q", n.Value)
+		}
+	case t.Kind() == reflect.Struct:
+		if shorthandTypes[t] && n.Kind == yaml.ScalarNode {
+			return
+		}
+		if n.Kind != yaml.MappingNode {
+			bad("want a map, got %s", nodeKind(n))
+			return
+		}
+		fields := make(map[string]yamlField)
+		var keys []string
+		for _, f := range yamlFields(t) {
+			fields[f.key] = f
+			keys = append(keys, f.key)
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			if k.Value == "<<" {
+				continue
+			}
+			f, ok := fields[k.Value]
+			if !ok {
+				msg := fmt.Sprintf("unknown key %q", k.Value)
+				if s := closestKey(k.Value, keys); s != "" {
+					msg += fmt.Sprintf(" (did you mean %q?)", s)
+				}
+				*errs = append(*errs, ConfigError{Line: k.Line, Column: k.Column, Path: path, Msg: msg})
+				continue
+			}
+			checkValue(v, f.typ, joinPath(path, f.key), errs)
+			if enum, ok := schemaEnums[t.Name()+"."+f.key]; ok && v.Kind == yaml.ScalarNode && v.Value != "" {
+				if !containsString(enum, v.Value) {
+					*errs = append(*errs, ConfigError{Line: v.Line, Column: v.Column, Path: joinPath(path, f.key),
+						Msg: fmt.Sprintf("%q is not one of %s", v.Value, strings.Join(enum, ", "))})
+				}
+			}
+		}
+	case t.Kind() == reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			bad("want a list, got %s", nodeKind(n))
+			return
+		}
+		for i, item := range n.Content {
+			checkValue(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case t.Kind() == reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			bad("want a map, got %s", nodeKind(n))
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			checkValue(n.Content[i+1], t.Elem(), joinPath(path, n.Content[i].Value), errs)
+		}
+	case t.Kind() == reflect.Interface:
+	default:
+		if n.Kind != yaml.ScalarNode {
+			bad("want a %s, got %s", scalarName(t), nodeKind(n))
+			return
+		}
+		switch t.Kind() {
+		case reflect.Bool:
+			if n.ShortTag() != "!!bool" {
+				bad("want true or false, got %q", n.Value)
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if n.ShortTag() != "!!int" {
+				bad("want a whole number, got %q", n.Value)
+			}
+		case reflect.Float32, reflect.Float64:
+			if tag := n.ShortTag(); tag != "!!int" && tag != "!!float" {
+				bad("want a number, got %q", n.Value)
+			}
+		}
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func nodeKind(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "a map"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	return fmt.Sprintf("%q", n.Value)
+}
+
+func scalarName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	}
+	return "whole number"
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// closestKey returns the key within two edits of s, if there is one.
+func closestKey(s string, keys []string) string {
+	sort.Strings(keys)
+	best, bestDist := "", 3
+	for _, k := range keys {
+		if d := editDistance(s, k); d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
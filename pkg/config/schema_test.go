@@ -0,0 +1,96 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	data, err := json.Marshal(Schema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s struct {
+		Ref  string `json:"$ref"`
+		Defs map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+			OneOf      []json.RawMessage          `json:"oneOf"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		t.Fatal(err)
+	}
+	root, ok := s.Defs["ProvidersConfig"]
+	if s.Ref != "#/$defs/ProvidersConfig" || !ok {
+		t.Fatalf("root = %q, defs %v", s.Ref, s.Defs)
+	}
+	for key, want := range map[string]string{
+		"providers": `{"items":{"$ref":"#/$defs/ProviderConfig"},"type":"array"}`,
+		"profiles":  `{"additionalProperties":{"$ref":"#/$defs/ProvidersConfig"},"type":"object"}`,
+		"intercept": `{"items":{"type":"string"},"type":"array"}`,
+	} {
+		if got := string(root.Properties[key]); got != want {
+			t.Errorf("%s = %s, want %s", key, got, want)
+		}
+	}
+	if got := string(s.Defs["ProviderConfig"].Properties["api"]); got != `{"enum":["openai","anthropic"],"type":"string"}` {
+		t.Errorf("api = %s", got)
+	}
+	if !strings.Contains(string(s.Defs["Limits"].Properties["upstream_timeout"]), `"pattern"`) {
+		t.Errorf("durations should be patterned strings: %s", s.Defs["Limits"].Properties["upstream_timeout"])
+	}
+	if len(s.Defs["ModelConfig"].OneOf) != 2 {
+		t.Errorf("a model is a string or a map: %+v", s.Defs["ModelConfig"])
+	}
+}
+
+func TestLoadConfigStrict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte(`limits:
+  max_concurent: 4
+  upstream_timeout: soon
+providers:
+  - name: ollama
+    endpont: http://localhost:11434/v1
+    api: openia
+    max_tokens: lots
+    models:
+      fast: qwen3:8b
+      big:
+        model: qwen3:32b
+        preload: maybe
+intercept: api.anthropic.com
+profiles:
+  work:
+    dedupe: {ttl: 1m, max: 3}
+`), 0600)
+
+	_, err := LoadConfig(path)
+	var errs ConfigErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("err = %v, want ConfigErrors", err)
+	}
+	want := []string{
+		path + `:2:3: limits: unknown key "max_concurent" (did you mean "max_concurrent"?)`,
+		path + `:3:21: limits.upstream_timeout: want a duration such as 30s or 2m, got "soon"`,
+		path + `:6:5: providers[0]: unknown key "endpont" (did you mean "endpoint"?)`,
+		path + `:7:10: providers[0].api: "openia" is not one of openai, anthropic`,
+		path + `:8:17: providers[0].max_tokens: want a whole number, got "lots"`,
+		path + `:13:18: providers[0].models.big.preload: want true or false, got "maybe"`,
+		path + `:14:12: intercept: want a list, got "api.anthropic.com"`,
+		path + `:17:23: profiles.work.dedupe: unknown key "max"`,
+	}
+	if got := strings.Split(err.Error(), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("errors:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestExampleConfigIsValid(t *testing.T) {
+	if _, err := LoadConfig(filepath.Join("..", "..", "config.example.yaml")); err != nil {
+		t.Fatal(err)
+	}
+}
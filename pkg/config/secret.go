@@ -0,0 +1,158 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSecretTTL is how long a key read by api_key_cmd is reused
+	// before the command runs again.
+	DefaultSecretTTL = 15 * time.Minute
+	// secretCmdTimeout bounds an api_key_cmd run, which may wait on an
+	// unlock prompt from a password manager.
+	secretCmdTimeout = 60 * time.Second
+)
+
+// SecretKey is a provider API key kept outside config.yaml: read from a
+// file or printed by a command, on first use rather than at startup. A file
+// is re-read when it changes; a command's output is reused for its TTL. All
+// labels on a provider share one SecretKey, so the command runs once for
+// them. Safe for concurrent use.
+type SecretKey struct {
+	file string        // path, with ~ and ${VAR} expanded
+	cmd  string        // shell command line
+	ttl  time.Duration // command output lifetime
+
+	mu      sync.Mutex
+	value   string
+	fetched time.Time // when value was read
+	modTime time.Time // file's modification time when read
+}
+
+// newSecretKey returns the SecretKey for a provider's api_key_file or
+// api_key_cmd, or nil when it uses neither.
+func newSecretKey(p ProviderConfig) *SecretKey {
+	switch {
+	case p.APIKeyFile != "":
+		return &SecretKey{file: expandHome(expandEnvVars(p.APIKeyFile))}
+	case p.APIKeyCmd != "":
+		ttl := p.APIKeyTTL
+		if ttl == 0 {
+			ttl = DefaultSecretTTL
+		}
+		return &SecretKey{cmd: p.APIKeyCmd, ttl: ttl}
+	}
+	return nil
+}
+
+// String describes where the key comes from, never the key.
+func (s *SecretKey) String() string {
+	if s.file != "" {
+		return "api_key_file " + s.file
+	}
+	name, _, _ := strings.Cut(s.cmd, " ")
+	return "api_key_cmd " + name
+}
+
+// Get returns the key, reading the file or running the command when the
+// cached value is missing or stale. Failures aren't cached.
+func (s *SecretKey) Get() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file != "" {
+		info, err := os.Stat(s.file)
+		if err != nil {
+			return "", fmt.Errorf("api_key_file: %w", err)
+		}
+		if s.value != "" && info.ModTime().Equal(s.modTime) {
+			return s.value, nil
+		}
+		data, err := os.ReadFile(s.file)
+		if err != nil {
+			return "", fmt.Errorf("api_key_file: %w", err)
+		}
+		key := strings.TrimSpace(string(data))
+		if key == "" {
+			return "", fmt.Errorf("api_key_file %s is empty", s.file)
+		}
+		s.value, s.modTime = key, info.ModTime()
+		return key, nil
+	}
+
+	if s.value != "" && time.Since(s.fetched) < s.ttl {
+		return s.value, nil
+	}
+	key, err := runSecretCmd(s.cmd)
+	if err != nil {
+		return "", err
+	}
+	s.value, s.fetched = key, time.Now()
+	return key, nil
+}
+
+// Invalidate drops the cached key, so the next Get reads it again. Called
+// when the provider rejects the key, which may have been rotated.
+func (s *SecretKey) Invalidate() {
+	s.mu.Lock()
+	s.value = ""
+	s.mu.Unlock()
+}
+
+// runSecretCmd runs cmd through the shell and returns its trimmed output.
+func runSecretCmd(cmd string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretCmdTimeout)
+	defer cancel()
+	var c *exec.Cmd
+	if runtime.GOOS == "windows" {
+		c = exec.CommandContext(ctx, "cmd", "/C", cmd)
+	} else {
+		c = exec.CommandContext(ctx, "sh", "-c", cmd)
+	}
+	var stderr bytes.Buffer
+	c.Stderr = &stderr
+	out, err := c.Output()
+	name, _, _ := strings.Cut(cmd, " ")
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > 200 {
+			msg = msg[:200] + "…"
+		}
+		if msg != "" {
+			return "", fmt.Errorf("api_key_cmd %s: %v: %s", name, err, msg)
+		}
+		return "", fmt.Errorf("api_key_cmd %s: %v", name, err)
+	}
+	key := strings.TrimSpace(string(out))
+	if key == "" {
+		return "", fmt.Errorf("api_key_cmd %s printed nothing", name)
+	}
+	return key, nil
+}
+
+// expandHome replaces a leading ~/ with the home directory.
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
+
+// Key returns the model's provider API key, from config or from its
+// SecretKey. It is empty when the provider has none.
+func (m ResolvedModel) Key() (string, error) {
+	if m.Secret != nil {
+		return m.Secret.Get()
+	}
+	return m.APIKey, nil
+}
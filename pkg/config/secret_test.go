@@ -0,0 +1,121 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSecretKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(path, []byte("sk-one\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, r := loadTestConfig(t, `
+providers:
+  - name: deepseek
+    endpoint: https://api.deepseek.com/v1
+    api_key_file: `+path+`
+    models:
+      ds: deepseek-chat
+`)
+	m, _ := r.Resolve("ds")
+	if m.APIKey != "" || m.Secret == nil {
+		t.Fatalf("APIKey %q, Secret %v: want the key read lazily", m.APIKey, m.Secret)
+	}
+	if key, err := m.Key(); err != nil || key != "sk-one" {
+		t.Fatalf("Key() = %q, %v; want sk-one", key, err)
+	}
+
+	// A rewritten file is picked up without a restart.
+	if err := os.WriteFile(path, []byte("sk-two"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Second)
+	os.Chtimes(path, later, later)
+	if key, _ := m.Key(); key != "sk-two" {
+		t.Errorf("after rewrite Key() = %q, want sk-two", key)
+	}
+
+	os.Remove(path)
+	if _, err := m.Key(); err == nil || !strings.Contains(err.Error(), "api_key_file") {
+		t.Errorf("missing file: err = %v", err)
+	}
+}
+
+func TestSecretKeyCmd(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("no /bin/sh")
+	}
+	count := filepath.Join(t.TempDir(), "count")
+	_, r := loadTestConfig(t, `
+providers:
+  - name: deepseek
+    endpoint: https://api.deepseek.com/v1
+    api_key_cmd: echo run >> `+count+`; echo sk-from-cmd
+    models:
+      ds: deepseek-chat
+      ds-r1: deepseek-reasoner
+`)
+	for _, label := range []string{"ds", "ds-r1", "ds"} {
+		m, _ := r.Resolve(label)
+		if key, err := m.Key(); err != nil || key != "sk-from-cmd" {
+			t.Fatalf("%s: Key() = %q, %v", label, key, err)
+		}
+	}
+	data, _ := os.ReadFile(count)
+	if runs := strings.Count(string(data), "run"); runs != 1 {
+		t.Errorf("command ran %d times, want 1 (shared and cached)", runs)
+	}
+
+	m, _ := r.Resolve("ds")
+	m.Secret.Invalidate()
+	m.Key()
+	data, _ = os.ReadFile(count)
+	if runs := strings.Count(string(data), "run"); runs != 2 {
+		t.Errorf("after Invalidate command ran %d times, want 2", runs)
+	}
+}
+
+func TestSecretKeyCmdErrors(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("no /bin/sh")
+	}
+	for _, tt := range []struct{ cmd, want string }{
+		{"echo locked >&2; exit 1", "locked"},
+		{"true", "printed nothing"},
+	} {
+		s := newSecretKey(ProviderConfig{APIKeyCmd: tt.cmd})
+		_, err := s.Get()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: err = %v, want %q", tt.cmd, err, tt.want)
+		}
+	}
+}
+
+func TestSecretKeySources(t *testing.T) {
+	cfg := &ProvidersConfig{
+		Groups: map[string]GroupConfig{"cloud": {APIKeyCmd: "pass show cloud"}},
+		Providers: []ProviderConfig{
+			{Name: "a", Group: "cloud", Endpoint: "http://a/v1", Models: map[string]ModelConfig{"a": {Model: "a"}}},
+			{Name: "b", Group: "cloud", Endpoint: "http://b/v1", APIKey: "sk-b", Models: map[string]ModelConfig{"b": {Model: "b"}}},
+		},
+	}
+	r, err := NewModelResolver(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, _ := r.Resolve("a"); a.Secret == nil || a.Secret.String() != "api_key_cmd pass" {
+		t.Errorf("a: Secret = %v, want the group's api_key_cmd", a.Secret)
+	}
+	if b, _ := r.Resolve("b"); b.Secret != nil || b.APIKey != "sk-b" {
+		t.Errorf("b: Secret = %v, APIKey %q; its own api_key should replace the group's command", b.Secret, b.APIKey)
+	}
+
+	cfg.Providers[1].APIKeyFile = "/tmp/key"
+	if _, err := NewModelResolver(cfg); err == nil || !strings.Contains(err.Error(), "only one of") {
+		t.Errorf("api_key with api_key_file: err = %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AutoTierPrefix starts a marker label that names a tier instead of a
+// label: model=auto:coder lets the proxy pick the tier's small or large
+// label per request.
+const AutoTierPrefix = "auto:"
+
+// Tier classifier defaults.
+const (
+	DefaultTierMaxTokens = 8000
+	DefaultTierMaxTools  = 20
+)
+
+// DefaultTierKeywords send a request to the large label when its last user
+// message contains one of them.
+var DefaultTierKeywords = []string{"think", "plan", "design", "architect", "step by step", "root cause"}
+
+// TierConfig is a pair of labels for model=auto:NAME and the limits of what
+// the small one handles. A request over any limit goes to the large one.
+type TierConfig struct {
+	Small     string   `yaml:"small"`                // label, alias, label group or schedule
+	Large     string   `yaml:"large"`                // likewise
+	MaxTokens int      `yaml:"max_tokens,omitempty"` // estimated prompt tokens (default 8000)
+	MaxTools  int      `yaml:"max_tools,omitempty"`  // tool definitions (default 20)
+	Keywords  []string `yaml:"keywords,omitempty"`   // in the last user message (default DefaultTierKeywords)
+}
+
+// withDefaults fills unset limits.
+func (t TierConfig) withDefaults() TierConfig {
+	if t.MaxTokens == 0 {
+		t.MaxTokens = DefaultTierMaxTokens
+	}
+	if t.MaxTools == 0 {
+		t.MaxTools = DefaultTierMaxTools
+	}
+	if len(t.Keywords) == 0 {
+		t.Keywords = DefaultTierKeywords
+	}
+	return t
+}
+
+// checkTiers validates cfg's tiers: each needs a small and a large target
+// that a marker could name directly.
+func checkTiers(cfg *ProvidersConfig, isLabel func(string) bool) error {
+	routable := routableChecker(cfg, isLabel)
+	for _, name := range sortedKeys(cfg.Tiers) {
+		t := cfg.Tiers[name]
+		if name == "" || strings.ContainsAny(name, " :") {
+			return fmt.Errorf("tier %q: names can't contain spaces or colons", name)
+		}
+		if t.Small == "" || t.Large == "" {
+			return fmt.Errorf("tier %q needs both small and large", name)
+		}
+		for _, target := range []string{t.Small, t.Large} {
+			if !routable(target) {
+				return fmt.Errorf("tier %q: label %q not defined", name, target)
+			}
+		}
+		if t.MaxTokens < 0 || t.MaxTools < 0 {
+			return fmt.Errorf("tier %q: max_tokens and max_tools must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// Tier returns the tier for model=auto:NAME, with defaults filled in.
+func (r *ModelResolver) Tier(name string) (TierConfig, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.cfg.Tiers[name]
+	if !ok {
+		return TierConfig{}, false
+	}
+	return t.withDefaults(), true
+}
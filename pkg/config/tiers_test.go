@@ -0,0 +1,52 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTiers(t *testing.T) {
+	_, r := loadTestConfig(t, `
+providers:
+  - name: ollama
+    endpoint: http://localhost:11434/v1
+    models:
+      coder_7b: qwen2.5-coder:7b
+      coder_32b: qwen2.5-coder:32b
+aliases:
+  big: coder_32b
+tiers:
+  coder:
+    small: coder_7b
+    large: big
+    max_tokens: 4000
+`)
+	tier, ok := r.Tier("coder")
+	if !ok || tier.MaxTokens != 4000 || tier.MaxTools != DefaultTierMaxTools || len(tier.Keywords) == 0 {
+		t.Errorf("Tier(coder) = %+v, %v; want defaults filled in", tier, ok)
+	}
+	if m, err := r.Resolve("auto:coder"); err != nil || m.Label != "coder_7b" {
+		t.Errorf("Resolve(auto:coder) = %s, %v; want the small label", m.Label, err)
+	}
+	if _, err := r.Resolve("auto:nope"); err == nil {
+		t.Error("unknown tier should not resolve")
+	}
+}
+
+func TestTierErrors(t *testing.T) {
+	tests := []struct{ extra, want string }{
+		{"tiers: {t: {small: a}}", `tier "t" needs both small and large`},
+		{"tiers: {t: {small: a, large: nope}}", `tier "t": label "nope" not defined`},
+		{"tiers: {'a:b': {small: a, large: b}}", "can't contain spaces or colons"},
+		{"tiers: {t: {small: a, large: b, max_tools: -1}}", "must not be negative"},
+	}
+	for _, tt := range tests {
+		cfg, err := parseConfig([]byte("providers:\n  - name: p\n    endpoint: http://p/v1\n    models: {a: a, b: b}\n"+tt.extra+"\n"), "config.yaml")
+		if err != nil {
+			t.Fatalf("%s: %v", tt.extra, err)
+		}
+		if _, err := NewModelResolver(cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.extra, err, tt.want)
+		}
+	}
+}
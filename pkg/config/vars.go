@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// endpointVarRE matches {NAME} and {NAME:-default} in an endpoint.
+var endpointVarRE = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// expandEndpoint fills an endpoint's {NAME} references so one config can
+// name different hosts on different machines. NAME is looked up in the
+// environment first, so a machine can override the shared value, then in
+// vars (case-insensitively, since CLAUDE_HYBRID_VARS__* overrides arrive
+// lowercased), then falls back to the :-default. A reference with none of
+// these is an error. ${VAR} is expanded first, as in other fields. An IPv6
+// address filled in as the host is bracketed.
+func expandEndpoint(s string, vars map[string]string) (string, error) {
+	s = expandEnvVars(s)
+	var missing []string
+	var out strings.Builder
+	last := 0
+	for _, loc := range endpointVarRE.FindAllStringSubmatchIndex(s, -1) {
+		out.WriteString(s[last:loc[0]])
+		last = loc[1]
+		name := s[loc[2]:loc[3]]
+		var v string
+		if env, ok := os.LookupEnv(name); ok && env != "" {
+			v = env
+		} else if val, ok := lookupVar(vars, name); ok {
+			v = expandEnvVars(val)
+		} else if loc[4] >= 0 {
+			v = s[loc[4]:loc[5]]
+		} else {
+			missing = append(missing, "{"+name+"}")
+			out.WriteString(s[loc[0]:loc[1]])
+			continue
+		}
+		if strings.HasSuffix(s[:loc[0]], "://") {
+			v = bracketIPv6(v)
+		}
+		out.WriteString(v)
+	}
+	out.WriteString(s[last:])
+	if len(missing) > 0 {
+		return "", fmt.Errorf("endpoint %s: %s not set in the environment or vars, and no :-default", s, strings.Join(missing, ", "))
+	}
+	return out.String(), nil
+}
+
+// bracketIPv6 returns an IPv6 literal as a URL host, "[::1]", so a host
+// reference can hold one (OLLAMA_HOST=::1). A zone's % is escaped as URLs
+// require. Anything else is returned as is.
+func bracketIPv6(host string) string {
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !addr.Is6() {
+		return host
+	}
+	return "[" + strings.Replace(host, "%", "%25", 1) + "]"
+}
+
+func lookupVar(vars map[string]string, name string) (string, bool) {
+	if v, ok := vars[name]; ok {
+		return v, true
+	}
+	for k, v := range vars {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandEndpoint(t *testing.T) {
+	t.Setenv("HYBRID_TEST_HOST", "gpu-box")
+	t.Setenv("HYBRID_TEST_PORT", "")
+	vars := map[string]string{"gpu_host": "10.0.0.5", "scheme": "https", "HYBRID_TEST_HOST": "shadowed", "v6": "fe80::1%eth0"}
+	tests := []struct{ in, want string }{
+		{"http://localhost:11434/v1", "http://localhost:11434/v1"},
+		{"http://{HYBRID_TEST_HOST:-localhost}:11434/v1", "http://gpu-box:11434/v1"},
+		{"http://{HYBRID_TEST_UNSET:-localhost}:11434/v1", "http://localhost:11434/v1"},
+		{"http://localhost:{HYBRID_TEST_PORT:-11434}/v1", "http://localhost:11434/v1"}, // empty counts as unset
+		{"{scheme}://{GPU_HOST}:8000/v1", "https://10.0.0.5:8000/v1"},                  // vars match case-insensitively
+		{"http://{HYBRID_TEST_HOST}/v1", "http://gpu-box/v1"},                          // environment wins over vars
+		{"http://{gpu_host:-}/v1", "http://10.0.0.5/v1"},
+		{"http://${HYBRID_TEST_HOST}/v1", "http://gpu-box/v1"},
+		{"http://{HYBRID_TEST_UNSET:-::1}:11434/v1", "http://[::1]:11434/v1"}, // IPv6 hosts are bracketed
+		{"http://{v6}:8000/v1", "http://[fe80::1%25eth0]:8000/v1"},
+		{"http://{v6}/v1?via={gpu_host}", "http://[fe80::1%25eth0]/v1?via=10.0.0.5"},
+		{"http://gpu:{HYBRID_TEST_UNSET:-8000}/v1", "http://gpu:8000/v1"},
+	}
+	for _, tt := range tests {
+		got, err := expandEndpoint(tt.in, vars)
+		if err != nil || got != tt.want {
+			t.Errorf("expandEndpoint(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+
+	_, err := expandEndpoint("http://{HYBRID_TEST_UNSET}:{ALSO_UNSET}/v1", vars)
+	if err == nil || !strings.Contains(err.Error(), "{HYBRID_TEST_UNSET}, {ALSO_UNSET} not set") {
+		t.Errorf("unset reference: err = %v", err)
+	}
+}
+
+func TestEndpointVarsInConfig(t *testing.T) {
+	t.Setenv("HYBRID_TEST_OLLAMA", "")
+	cfg, r := loadTestConfig(t, `
+vars:
+  gpu: 192.168.1.20
+groups:
+  lan:
+    endpoint: http://{gpu}:8000/v1
+providers:
+  - name: ollama
+    endpoint: http://{HYBRID_TEST_OLLAMA:-localhost}:11434/v1/
+    models:
+      qwen: qwen3:32b
+  - name: vllm
+    group: lan
+    models:
+      big: llama-70b
+profiles:
+  away:
+    vars:
+      gpu: gpu.example.net
+`)
+	if m, _ := r.Resolve("qwen"); m.Endpoint != "http://localhost:11434/v1" {
+		t.Errorf("qwen endpoint = %q", m.Endpoint)
+	}
+	if m, _ := r.Resolve("big"); m.Endpoint != "http://192.168.1.20:8000/v1" {
+		t.Errorf("big endpoint = %q", m.Endpoint)
+	}
+
+	away, err := NewModelResolver(cfg.WithProfile(&ProvidersConfig{Vars: cfg.Profiles["away"].Vars}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := away.Resolve("big"); m.Endpoint != "http://gpu.example.net:8000/v1" {
+		t.Errorf("with profile, big endpoint = %q", m.Endpoint)
+	}
+
+	_, err = NewModelResolver(&ProvidersConfig{Providers: []ProviderConfig{{
+		Name: "lan", Endpoint: "http://{nope}/v1", Models: map[string]ModelConfig{"x": {Model: "x"}},
+	}}})
+	if err == nil || !strings.Contains(err.Error(), `provider "lan": endpoint http://{nope}/v1: {nope} not set`) {
+		t.Errorf("undefined var: err = %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+// Package redact scrubs secrets from text before it is written to the proxy
+// log. Error bodies and unparseable chunks can carry tool results, which
+// can carry file contents and credentials.
+//
+// It is public because config.ProvidersConfig carries its Config; the API
+// follows semantic versioning with the module.
+package redact
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Placeholders written in place of redacted text.
+const (
+	Mask     = "[REDACTED]"
+	PathMask = "[REDACTED_PATH]"
+)
+
+// minEnvValue is the shortest environment value redacted; shorter values
+// (ports, flags) would mask ordinary text.
+const minEnvValue = 8
+
+// builtinPatterns match credentials in common formats. They always apply.
+var builtinPatterns = []string{
+	`(?i)bearer\s+[A-Za-z0-9._~+/=-]{8,}`, // Authorization headers
+	`sk-ant-[A-Za-z0-9_-]{16,}`,           // Anthropic keys and OAuth tokens
+	`sk-[A-Za-z0-9_-]{16,}`,               // OpenAI-style keys
+	`AKIA[0-9A-Z]{16}`,                    // AWS access key IDs
+	`gh[pousr]_[A-Za-z0-9]{36,}`,          // GitHub tokens
+	`github_pat_[A-Za-z0-9_]{22,}`,        // GitHub fine-grained tokens
+	`xox[abpr]-[A-Za-z0-9-]{10,}`,         // Slack tokens
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----`,  // PEM private keys
+}
+
+// secretEnvSuffixes mark environment variables whose values are always
+// redacted.
+var secretEnvSuffixes = []string{"_KEY", "_TOKEN", "_SECRET", "_PASSWORD", "_PASSPHRASE"}
+
+// Config lists redactions added to the built-in ones.
+type Config struct {
+	Patterns []string `yaml:"patterns,omitempty"` // extra regexes
+	Env      []string `yaml:"env,omitempty"`      // environment variables whose values are redacted
+	Paths    []string `yaml:"paths,omitempty"`    // globs; matching file paths are replaced, ~ is the home dir
+}
+
+// Redactor replaces secrets in text. The zero value redacts nothing; use New.
+type Redactor struct {
+	patterns []*regexp.Regexp
+	values   []string // literal secrets, longest first
+	paths    []string
+}
+
+// pathTokenRE finds path-like tokens: absolute, home-relative or
+// relative paths with at least one separator.
+var pathTokenRE = regexp.MustCompile(`(?:~|\.{0,2})/[^\s"'<>|,;:)\]}]+`)
+
+// New builds a Redactor from the built-in rules plus cfg (which may be nil)
+// and environ, the process environment as os.Environ returns it.
+func New(cfg *Config, environ []string) (*Redactor, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	r := &Redactor{}
+	for _, p := range append(append([]string(nil), builtinPatterns...), cfg.Patterns...) {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("log_redact: pattern %q: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	named := map[string]bool{}
+	for _, name := range cfg.Env {
+		named[name] = true
+	}
+	seen := map[string]bool{}
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || len(value) < minEnvValue || seen[value] {
+			continue
+		}
+		if named[name] || hasSecretSuffix(name) {
+			r.values = append(r.values, value)
+			seen[value] = true
+		}
+	}
+	sort.Slice(r.values, func(i, j int) bool { return len(r.values[i]) > len(r.values[j]) })
+
+	home, _ := os.UserHomeDir()
+	for _, g := range cfg.Paths {
+		if strings.HasPrefix(g, "~/") && home != "" {
+			g = filepath.Join(home, g[2:])
+		}
+		if _, err := filepath.Match(g, ""); err != nil {
+			return nil, fmt.Errorf("log_redact: path glob %q: %w", g, err)
+		}
+		r.paths = append(r.paths, g)
+	}
+	return r, nil
+}
+
+func hasSecretSuffix(name string) bool {
+	name = strings.ToUpper(name)
+	for _, s := range secretEnvSuffixes {
+		if strings.HasSuffix(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns s with every secret replaced.
+func (r *Redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, v := range r.values {
+		s = strings.ReplaceAll(s, v, Mask)
+	}
+	for _, re := range r.patterns {
+		s = re.ReplaceAllString(s, Mask)
+	}
+	if len(r.paths) > 0 {
+		s = pathTokenRE.ReplaceAllStringFunc(s, func(tok string) string {
+			if r.matchPath(tok) {
+				return PathMask
+			}
+			return tok
+		})
+	}
+	return s
+}
+
+// matchPath reports whether a path token matches a glob, either whole or by
+// its base name (so "*.pem" catches /etc/ssl/private/site.pem).
+func (r *Redactor) matchPath(tok string) bool {
+	for _, g := range r.paths {
+		target := tok
+		if !strings.Contains(g, "/") {
+			target = filepath.Base(tok)
+		}
+		if ok, _ := filepath.Match(g, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Writer redacts each write before passing it to an underlying writer. The
+// standard logger writes one entry per call, so secrets are never split
+// across writes. The redactor can be swapped while logging is live, which
+// lets the log open before config.yaml is read.
+type Writer struct {
+	w io.Writer
+	r atomic.Pointer[Redactor]
+}
+
+// NewWriter wraps w with redactor r.
+func NewWriter(w io.Writer, r *Redactor) *Writer {
+	rw := &Writer{w: w}
+	rw.r.Store(r)
+	return rw
+}
+
+// SetRedactor replaces the redactor used for later writes.
+func (rw *Writer) SetRedactor(r *Redactor) { rw.r.Store(r) }
+
+// Write redacts p and writes it. It reports len(p) on success so callers
+// aren't confused by the changed length.
+func (rw *Writer) Write(p []byte) (int, error) {
+	out := rw.r.Load().String(string(p))
+	if out == string(p) {
+		return rw.w.Write(p)
+	}
+	if _, err := io.WriteString(rw.w, out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,29 @@
+package router
+
+import "github.com/peter-wagstaff/claude-hybrid-router/internal/proxy"
+
+// Middleware hooks into every request the router answers inside an
+// intercepted tunnel, for audit, policy or rewriting. Hooks run on the
+// tunnel's goroutine, and one Middleware serves every tunnel, so it must be
+// safe for concurrent use. Embed NopMiddleware to implement only some hooks.
+//
+//   - OnRequest runs before the request is routed. Changes to ex.Header and
+//     ex.Body are what gets routed and forwarded; a non-nil error refuses
+//     the request with a 403 carrying its message.
+//   - OnStreamEvent runs for each server-sent event of a streamed response
+//     before the client gets it. Changes to ev are sent; set ev.Drop to
+//     leave the event out.
+//   - OnResponse runs once the response has been sent.
+type Middleware = proxy.Middleware
+
+// NopMiddleware implements every Middleware hook as a no-op.
+type NopMiddleware = proxy.NopMiddleware
+
+// Exchange is one request inside a tunnel, shared by its hooks.
+type Exchange = proxy.Exchange
+
+// StreamEvent is one server-sent event seen by OnStreamEvent.
+type StreamEvent = proxy.StreamEvent
+
+// ExchangeResponse is the response an Exchange got, as the client saw it.
+type ExchangeResponse = proxy.ExchangeResponse
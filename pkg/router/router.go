@@ -0,0 +1,463 @@
+// Package router runs claude-hybrid in-process: New wires the MITM proxy,
+// model resolver, provider clients, transforms, access control and tracing
+// from a config the way the claude-hybrid binary does, and returns an
+// http.Handler to serve as an HTTPS proxy. Daemons, supervisors and tests
+// can embed it without exec'ing the binary.
+//
+// It is a public package; its API follows semantic versioning with the
+// module.
+package router
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/admin"
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/defaults"
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/mitm"
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/proxy"
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/routerproxy"
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/tracing"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/redact"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/translate"
+)
+
+// ErrCA is wrapped by New's error when the CA certificate or key can't be
+// used.
+var ErrCA = errors.New("CA certificate")
+
+// ErrHAR is wrapped by New's error when the WithHAR file can't be created.
+var ErrHAR = errors.New("HAR file")
+
+// Router is a fully wired claude-hybrid proxy. Serve it as an HTTP proxy
+// (it answers CONNECT only); clients must trust CACert.
+type Router struct {
+	proxy     *proxy.Proxy
+	tracer    *tracing.Tracer
+	har       *proxy.HARLog
+	caPEM     []byte
+	token     string
+	adminOpts []admin.Option
+}
+
+func init() {
+	routerproxy.Register(func(rt any) *proxy.Proxy { return rt.(*Router).proxy })
+}
+
+type settings struct {
+	certPEM, keyPEM []byte
+	certsDir        string
+	certCacheSize   int
+	stateDir        string
+	session         string
+	configPath      string
+	workDir         string
+	limits          config.Limits
+	intercept       []string
+	proxyToken      string
+	allowed         []string
+	ratePerMinute   int
+	verbose         bool
+	validateSSE     bool
+	logOutput       io.Writer
+	pause           *regexp.Regexp
+	harPath         string
+	middleware      []Middleware
+}
+
+// Option configures New. Options win over the matching config settings,
+// as the binary's flags win over config.yaml.
+type Option func(*settings)
+
+// WithCA signs intercepted hosts' certificates with the given CA.
+func WithCA(certPEM, keyPEM []byte) Option {
+	return func(s *settings) { s.certPEM, s.keyPEM = certPEM, keyPEM }
+}
+
+// WithCertsDir uses dir/ca.crt and dir/ca.key, generating them if dir has
+// none. The key must be a plain PEM file; for a protected key, unlock it
+// and use WithCA. Without WithCA or WithCertsDir, New generates a CA that
+// lives as long as the Router.
+func WithCertsDir(dir string) Option {
+	return func(s *settings) { s.certsDir = dir }
+}
+
+// WithCertCacheSize caps the leaf certificates kept in memory.
+func WithCertCacheSize(n int) Option {
+	return func(s *settings) { s.certCacheSize = n }
+}
+
+// WithStateDir keeps the daily budget ledger (shared with other instances
+// using the same dir), the dedupe cache and persisted conversation history
+// under dir. Without it, budgets count this Router's spend only, dedupe is
+// off and history is kept in memory.
+func WithStateDir(dir string) Option {
+	return func(s *settings) { s.stateDir = dir }
+}
+
+// WithSessionID names this instance in the budget ledger (default
+// "s<pid>").
+func WithSessionID(id string) Option {
+	return func(s *settings) { s.session = id }
+}
+
+// WithConfigPath is the file that labels registered through the admin API
+// are saved to.
+func WithConfigPath(path string) Option {
+	return func(s *settings) { s.configPath = path }
+}
+
+// WithWorkDir picks the anthropic workspace key for dir (default the
+// current directory).
+func WithWorkDir(dir string) Option {
+	return func(s *settings) { s.workDir = dir }
+}
+
+// WithLimits sets limits; nonzero fields win over the config's limits.
+func WithLimits(l config.Limits) Option {
+	return func(s *settings) { s.limits = l }
+}
+
+// WithIntercept sets the hosts to decrypt, in place of the config's list.
+func WithIntercept(hosts []string) Option {
+	return func(s *settings) { s.intercept = hosts }
+}
+
+// WithProxyToken requires token from proxy clients, in place of the
+// config's proxy_auth.token. "auto" generates one; ProxyToken returns it.
+func WithProxyToken(token string) Option {
+	return func(s *settings) { s.proxyToken = token }
+}
+
+// WithAllowedClients sets the CIDRs or addresses allowed to connect, in
+// place of the config's allowed_clients. Loopback always is.
+func WithAllowedClients(clients []string) Option {
+	return func(s *settings) { s.allowed = clients }
+}
+
+// WithClientRateLimit allows perMinute CONNECTs plus requests per client IP.
+func WithClientRateLimit(perMinute int) Option {
+	return func(s *settings) { s.ratePerMinute = perMinute }
+}
+
+// WithVerbose logs every tunnel and dropped stream chunk.
+func WithVerbose(v bool) Option {
+	return func(s *settings) { s.verbose = v }
+}
+
+// WithValidateSSE checks the order of every translated stream's events and
+// logs violations, for debugging transform chains.
+func WithValidateSSE(v bool) Option {
+	return func(s *settings) { s.validateSSE = v }
+}
+
+// WithLogOutput sends the router's log lines to w, scrubbed of secrets
+// (plus the config's log_redact rules). The proxy logs through the standard
+// log package, so this sets its output for the whole process.
+func WithLogOutput(w io.Writer) Option {
+	return func(s *settings) { s.logOutput = w }
+}
+
+// WithPause holds every intercepted request whose "METHOD URL" matches
+// filter until it is resumed or dropped through the admin API (or five
+// minutes pass), as --pause does. Only Messages and embeddings calls can be
+// held.
+func WithPause(filter *regexp.Regexp) Option {
+	return func(s *settings) { s.pause = filter }
+}
+
+// WithHAR records every request answered inside an intercepted tunnel, with
+// its response, to a HAR 1.2 file at path, as --har does. The file is
+// created or truncated by New and closed by Close. Credentials in request
+// headers are masked; bodies are not.
+func WithHAR(path string) Option {
+	return func(s *settings) { s.harPath = path }
+}
+
+// WithMiddleware adds hooks run on every request answered inside an
+// intercepted tunnel, in the order added.
+func WithMiddleware(m ...Middleware) Option {
+	return func(s *settings) { s.middleware = append(s.middleware, m...) }
+}
+
+// New builds a Router from cfg, which may be nil: every local route then
+// gets a stub response.
+func New(cfg *config.ProvidersConfig, opts ...Option) (*Router, error) {
+	s := settings{certCacheSize: defaults.MitmCacheMaxSize, session: fmt.Sprintf("s%d", os.Getpid())}
+	for _, o := range opts {
+		o(&s)
+	}
+	hasConfig := cfg != nil
+	if !hasConfig {
+		cfg = &config.ProvidersConfig{}
+	}
+
+	if s.logOutput != nil {
+		r, err := redact.New(cfg.LogRedact, os.Environ())
+		if err != nil {
+			return nil, err
+		}
+		log.SetOutput(redact.NewWriter(s.logOutput, r))
+	}
+
+	certPEM, keyPEM, err := s.ca()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCA, err)
+	}
+	certCache, err := mitm.NewCertCache(certPEM, keyPEM, mitm.WithMaxEntries(s.certCacheSize))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCA, err)
+	}
+
+	rt := &Router{caPEM: certPEM}
+	popts := []proxy.Option{proxy.WithVerbose(s.verbose), proxy.WithValidateSSE(s.validateSSE)}
+	if hasConfig {
+		resolver, err := config.NewModelResolver(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("build model resolver: %w", err)
+		}
+		popts = append(popts, proxy.WithModelResolver(resolver))
+	}
+	if len(cfg.DNSOverrides) > 0 {
+		dns, err := config.ParseDNSOverrides(cfg.DNSOverrides)
+		if err != nil {
+			return nil, err
+		}
+		popts = append(popts, proxy.WithDNSOverrides(dns))
+		hosts := make([]string, 0, len(dns))
+		for host := range dns {
+			hosts = append(hosts, host)
+		}
+		sort.Strings(hosts)
+		for _, host := range hosts {
+			log.Printf("DNS override: %s → %s", host, dns[host])
+		}
+	}
+	if cfg.Dedupe != nil && s.stateDir != "" {
+		popts = append(popts, proxy.WithDedupe(filepath.Join(s.stateDir, "cache"), cfg.Dedupe))
+	}
+	if cfg.SlowRequestThreshold > 0 {
+		popts = append(popts, proxy.WithSlowRequestThreshold(cfg.SlowRequestThreshold))
+	}
+	if h := cfg.History; h != nil && h.Persist && s.stateDir != "" {
+		popts = append(popts, proxy.WithHistoryDir(filepath.Join(s.stateDir, "history"), h.Retention))
+	}
+	if cfg.Upstream != nil && len(cfg.Upstream.Transform) > 0 {
+		chain, err := translate.BuildChain(cfg.Upstream.Transform)
+		if err != nil {
+			return nil, fmt.Errorf("upstream transforms: %w", err)
+		}
+		popts = append(popts, proxy.WithUpstreamTransforms(chain, cfg.Upstream))
+		log.Printf("Upstream transforms enabled: %s", strings.Join(cfg.Upstream.Transform, ", "))
+	}
+	if cfg.Upstream != nil && len(cfg.Upstream.TLSProfiles) > 0 {
+		if err := proxy.ValidateTLSProfiles(cfg.Upstream.TLSProfiles); err != nil {
+			return nil, fmt.Errorf("upstream: %w", err)
+		}
+		popts = append(popts, proxy.WithTLSProfiles(cfg.Upstream.TLSProfiles))
+	}
+	if cfg.Anthropic != nil {
+		popts = append(popts, proxy.WithAnthropicHosts(cfg.Anthropic.Hosts))
+		dir := s.workDir
+		if dir == "" {
+			dir, _ = os.Getwd()
+		}
+		if key := cfg.Anthropic.KeyFor(dir); key != "" {
+			popts = append(popts, proxy.WithAnthropicKey(key))
+			log.Printf("Using the workspace Anthropic API key for %s", dir)
+		}
+	}
+	if cfg.Annotations != nil {
+		popts = append(popts, proxy.WithAnnotations(*cfg.Annotations))
+	}
+
+	limits := config.DefaultLimits()
+	if cfg.Limits != nil {
+		limits = limits.Merge(*cfg.Limits)
+	}
+	limits = limits.Merge(s.limits)
+	if limits != config.DefaultLimits() {
+		log.Printf("Limits: max_body_bytes=%d upstream_timeout=%s client_recv_timeout=%s max_concurrent=%d max_queued=%d queue_timeout=%s",
+			limits.MaxBodyBytes, limits.UpstreamTimeout, limits.ClientRecvTimeout, limits.MaxConcurrent,
+			limits.MaxQueued, limits.QueueTimeout)
+	}
+	popts = append(popts, proxy.WithLimits(limits))
+
+	intercept := config.DefaultIntercept
+	if len(s.intercept) > 0 {
+		intercept = s.intercept
+	} else if len(cfg.Intercept) > 0 {
+		intercept = cfg.Intercept
+	}
+	popts = append(popts, proxy.WithIntercept(intercept))
+	log.Printf("Intercepting: %s (other hosts are tunneled without MITM)", strings.Join(intercept, ", "))
+
+	rt.token = s.proxyToken
+	if rt.token == "" && cfg.ProxyAuth != nil {
+		rt.token = cfg.ProxyAuth.ResolvedToken()
+	}
+	if rt.token == "auto" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return nil, fmt.Errorf("generate proxy token: %w", err)
+		}
+		rt.token = hex.EncodeToString(b)
+	}
+	if rt.token != "" {
+		popts = append(popts, proxy.WithProxyToken(rt.token))
+		log.Printf("Proxy authentication required")
+	}
+
+	allowed := s.allowed
+	if allowed == nil {
+		allowed = cfg.AllowedClients
+	}
+	prefixes, err := config.ParseClients(allowed)
+	if err != nil {
+		return nil, err
+	}
+	popts = append(popts, proxy.WithAllowedClients(prefixes))
+	rate := s.ratePerMinute
+	burst := 0
+	if rate == 0 && cfg.ClientRateLimit != nil {
+		rate, burst = cfg.ClientRateLimit.RequestsPerMinute, cfg.ClientRateLimit.Burst
+	}
+	if rate > 0 {
+		popts = append(popts, proxy.WithClientRateLimit(rate, burst))
+	}
+
+	if s.stateDir != "" {
+		popts = append(popts, proxy.WithBudgetLedger(filepath.Join(s.stateDir, "budget"), s.session))
+	}
+
+	// OTEL_* environment variables win over the config's tracing section
+	var traceCfg tracing.Config
+	if cfg.Tracing != nil {
+		traceCfg = tracing.Config{
+			Endpoint:    tracing.TracesURL(cfg.Tracing.Endpoint),
+			Headers:     cfg.Tracing.ResolvedHeaders(),
+			ServiceName: cfg.Tracing.ServiceName,
+		}
+	}
+	traceCfg = traceCfg.WithEnv(os.Getenv)
+	if rt.tracer = tracing.New(traceCfg); rt.tracer != nil {
+		popts = append(popts, proxy.WithTracer(rt.tracer))
+		log.Printf("Exporting OpenTelemetry spans to %s", traceCfg.Endpoint)
+	}
+
+	if s.configPath != "" {
+		rt.adminOpts = append(rt.adminOpts, admin.WithConfigPath(s.configPath))
+	}
+	if cfg.Admin != nil {
+		read, write := cfg.Admin.Tokens()
+		rt.adminOpts = append(rt.adminOpts, admin.WithReadOnly(cfg.Admin.ReadOnly), admin.WithTokens(read, write))
+	}
+
+	if s.pause != nil {
+		popts = append(popts, proxy.WithPause(s.pause))
+	}
+	if len(s.middleware) > 0 {
+		popts = append(popts, proxy.WithMiddleware(s.middleware...))
+	}
+	if s.harPath != "" {
+		if rt.har, err = proxy.OpenHAR(s.harPath); err != nil {
+			rt.tracer.Shutdown(context.Background())
+			return nil, fmt.Errorf("%w: %v", ErrHAR, err)
+		}
+		popts = append(popts, proxy.WithHAR(rt.har))
+	}
+
+	rt.proxy = proxy.New(certCache, popts...)
+	return rt, nil
+}
+
+// ca returns the CA to sign with: the one given, the one in the certs
+// directory (generated there when missing), or a new one.
+func (s *settings) ca() (certPEM, keyPEM []byte, err error) {
+	switch {
+	case s.certPEM != nil:
+		return s.certPEM, s.keyPEM, nil
+	case s.certsDir == "":
+		return mitm.GenerateCA()
+	}
+	certPath := filepath.Join(s.certsDir, "ca.crt")
+	keyPath := filepath.Join(s.certsDir, "ca.key")
+	if certPEM, err = os.ReadFile(certPath); err == nil {
+		keyPEM, err = os.ReadFile(keyPath)
+		return certPEM, keyPEM, err
+	}
+	if !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+	if err := os.MkdirAll(s.certsDir, 0700); err != nil {
+		return nil, nil, err
+	}
+	if certPEM, keyPEM, err = mitm.GenerateCA(); err != nil {
+		return nil, nil, err
+	}
+	if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		return nil, nil, err
+	}
+	if err := os.WriteFile(certPath, certPEM, 0644); err != nil {
+		return nil, nil, err
+	}
+	log.Printf("CA certificate written to %s", certPath)
+	return certPEM, keyPEM, nil
+}
+
+// ServeHTTP answers CONNECT requests as the HTTPS proxy.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.proxy.ServeHTTP(w, r)
+}
+
+// OpenAIHandler serves the OpenAI-compatible API for configured labels.
+func (rt *Router) OpenAIHandler() http.Handler {
+	return rt.proxy.OpenAIHandler()
+}
+
+// AdminHandler serves the admin API and dashboard, with the config's admin
+// tokens and read-only setting.
+func (rt *Router) AdminHandler() http.Handler {
+	return admin.New(rt.proxy, rt.adminOpts...)
+}
+
+// CACert returns the PEM certificate clients must trust.
+func (rt *Router) CACert() []byte {
+	return rt.caPEM
+}
+
+// ProxyToken returns the secret clients must send, or "" when none is
+// required. It goes in the proxy URL's password.
+func (rt *Router) ProxyToken() string {
+	return rt.token
+}
+
+// Preload warms up the models marked preload: true. It blocks until they
+// answer, so run it in its own goroutine.
+func (rt *Router) Preload() {
+	rt.proxy.PreloadModels()
+}
+
+// Close flushes spans still waiting to be exported and closes the HAR
+// file.
+func (rt *Router) Close(ctx context.Context) error {
+	err := rt.tracer.Shutdown(ctx)
+	if rt.har != nil {
+		if herr := rt.har.Close(); err == nil {
+			err = herr
+		}
+	}
+	return err
+}
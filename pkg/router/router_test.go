@@ -0,0 +1,187 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/testutil"
+	"github.com/peter-wagstaff/claude-hybrid-router/pkg/config"
+)
+
+func TestRouterServesLocalRoute(t *testing.T) {
+	cfg := mockConfig(t)
+	rt, err := New(cfg, WithIntercept([]string{"api.example.test"}), WithProxyToken("auto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rt.ProxyToken()) != 32 {
+		t.Fatalf("auto proxy token = %q", rt.ProxyToken())
+	}
+
+	// A local route is answered without dialing the intercepted host.
+	status, got := postLocal(t, rt, "hi")
+	if status != 200 || !strings.Contains(got, "Mock response from mock-model-v1") {
+		t.Errorf("got %d: %s", status, got)
+	}
+}
+
+// mockConfig returns a config with one label, fast, on a mock provider.
+func mockConfig(t *testing.T) *config.ProvidersConfig {
+	t.Helper()
+	oaiSrv, oaiPort, _ := testutil.MockOpenAIServer()
+	t.Cleanup(func() { oaiSrv.Close() })
+	return &config.ProvidersConfig{
+		Providers: []config.ProviderConfig{{
+			Name:     "mock",
+			Endpoint: fmt.Sprintf("http://127.0.0.1:%d/v1", oaiPort),
+			Models:   map[string]config.ModelConfig{"fast": {Model: "mock-model-v1"}},
+		}},
+	}
+}
+
+// postLocal serves rt and sends it a Messages request for the fast label,
+// through the proxy, to api.example.test (which rt must intercept).
+func postLocal(t *testing.T, rt *Router, content string) (int, string) {
+	t.Helper()
+	srv := httptest.NewServer(rt)
+	t.Cleanup(srv.Close)
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(rt.CACert()) {
+		t.Fatal("CACert is not a PEM certificate")
+	}
+	proxyURL, _ := url.Parse(srv.URL)
+	if rt.ProxyToken() != "" {
+		proxyURL.User = url.UserPassword("claude", rt.ProxyToken())
+	}
+	client := &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyURL(proxyURL),
+		TLSClientConfig: &tls.Config{RootCAs: pool},
+	}}
+	body := `{"model":"claude-sonnet-4-20250514","max_tokens":64,` +
+		`"system":"<!-- @proxy-local-route:af83e9 model=fast --> Be brief",` +
+		`"messages":[{"role":"user","content":"` + content + `"}]}`
+	resp, err := client.Post("https://api.example.test/v1/messages", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	got, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(got)
+}
+
+// policy refuses requests mentioning "secret" and counts responses.
+type policy struct {
+	NopMiddleware
+	responses atomic.Int32
+}
+
+func (*policy) OnRequest(ex *Exchange) error {
+	if bytes.Contains(ex.Body, []byte("secret")) {
+		return errors.New("blocked by policy")
+	}
+	return nil
+}
+
+func (p *policy) OnResponse(ex *Exchange, resp *ExchangeResponse) {
+	if ex.Route == "local" && ex.Label == "fast" {
+		p.responses.Add(1)
+	}
+}
+
+func TestRouterMiddlewareAndHAR(t *testing.T) {
+	mw := &policy{}
+	harPath := filepath.Join(t.TempDir(), "capture.har")
+	rt, err := New(mockConfig(t), WithIntercept([]string{"api.example.test"}), WithMiddleware(mw), WithHAR(harPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status, got := postLocal(t, rt, "hi"); status != 200 {
+		t.Errorf("allowed request: got %d: %s", status, got)
+	}
+	if status, got := postLocal(t, rt, "the secret"); status != 403 || !strings.Contains(got, "blocked by policy") {
+		t.Errorf("refused request: got %d: %s", status, got)
+	}
+	// Hooks and the HAR entry run after the client has its response.
+	var har struct {
+		Log struct {
+			Entries []struct {
+				Response struct {
+					Status int `json:"status"`
+				} `json:"response"`
+			} `json:"entries"`
+		} `json:"log"`
+	}
+	readHAR := func() error {
+		data, err := os.ReadFile(harPath)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(data, &har)
+	}
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if readHAR() == nil && len(har.Log.Entries) == 2 && mw.responses.Load() == 1 {
+			break
+		}
+	}
+	if err := rt.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := mw.responses.Load(); n != 1 {
+		t.Errorf("OnResponse saw %d local responses, want 1", n)
+	}
+	if err := readHAR(); err != nil {
+		t.Fatalf("HAR file: %v", err)
+	}
+	if len(har.Log.Entries) != 2 || har.Log.Entries[0].Response.Status != 200 || har.Log.Entries[1].Response.Status != 403 {
+		t.Errorf("HAR entries = %+v", har.Log.Entries)
+	}
+}
+
+func TestRouterHARError(t *testing.T) {
+	_, err := New(nil, WithHAR(filepath.Join(t.TempDir(), "missing", "capture.har")))
+	if !errors.Is(err, ErrHAR) {
+		t.Errorf("err = %v, want ErrHAR", err)
+	}
+}
+
+func TestRouterConfigErrors(t *testing.T) {
+	_, err := New(&config.ProvidersConfig{AllowedClients: []string{"not-an-address"}})
+	if err == nil || errors.Is(err, ErrCA) {
+		t.Errorf("bad allowed_clients: err = %v", err)
+	}
+	_, err = New(nil, WithCA([]byte("not a cert"), []byte("not a key")))
+	if !errors.Is(err, ErrCA) {
+		t.Errorf("bad CA: err = %v, want ErrCA", err)
+	}
+}
+
+func TestRouterCertsDir(t *testing.T) {
+	dir := t.TempDir()
+	first, err := New(nil, WithCertsDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := New(nil, WithCertsDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.CACert(), second.CACert()) {
+		t.Error("CA generated in the certs dir was not reused")
+	}
+}
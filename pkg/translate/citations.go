@@ -0,0 +1,125 @@
+package translate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Citation modes: what becomes of the sources a provider cites.
+const (
+	// CitationsReferences appends a numbered source list to the answer's
+	// text (the default).
+	CitationsReferences = "references"
+	// CitationsBlocks attaches the sources to the text block as Anthropic
+	// web_search_result_location citations.
+	CitationsBlocks = "blocks"
+	// CitationsDrop discards them.
+	CitationsDrop = "drop"
+)
+
+// OAnnotation is an annotation on an OpenAI message or delta; OpenAI and
+// OpenRouter's web search report url_citation entries.
+type OAnnotation struct {
+	Type        string        `json:"type"`
+	URLCitation *OURLCitation `json:"url_citation,omitempty"`
+}
+
+// OURLCitation is a web page the answer cites. StartIndex and EndIndex
+// locate the citing text in the message content.
+type OURLCitation struct {
+	URL        string `json:"url"`
+	Title      string `json:"title,omitempty"`
+	Content    string `json:"content,omitempty"`
+	StartIndex int    `json:"start_index,omitempty"`
+	EndIndex   int    `json:"end_index,omitempty"`
+}
+
+// OSearchResult is a Perplexity search result, sent alongside citations.
+type OSearchResult struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// ACitation is a citation on an Anthropic text block.
+type ACitation struct {
+	Type           string `json:"type"` // web_search_result_location
+	URL            string `json:"url"`
+	Title          string `json:"title"`
+	CitedText      string `json:"cited_text"`
+	EncryptedIndex string `json:"encrypted_index"`
+}
+
+// citationSources collects a response's cited sources in order, one per
+// URL. It reads OpenAI/OpenRouter annotations and Perplexity's top-level
+// citations and search_results, which may repeat in every stream chunk.
+type citationSources struct {
+	list []ACitation
+	seen map[string]int
+}
+
+func (c *citationSources) add(url, title, cited string) {
+	if url == "" {
+		return
+	}
+	if c.seen == nil {
+		c.seen = make(map[string]int)
+	}
+	if i, ok := c.seen[url]; ok {
+		if c.list[i].Title == "" {
+			c.list[i].Title = title
+		}
+		if c.list[i].CitedText == "" {
+			c.list[i].CitedText = cited
+		}
+		return
+	}
+	c.seen[url] = len(c.list)
+	c.list = append(c.list, ACitation{Type: "web_search_result_location", URL: url, Title: title, CitedText: cited})
+}
+
+// addAnnotations adds url_citation annotations on content.
+func (c *citationSources) addAnnotations(anns []OAnnotation, content string) {
+	for _, a := range anns {
+		if a.Type != "url_citation" || a.URLCitation == nil {
+			continue
+		}
+		u := a.URLCitation
+		cited := u.Content
+		if r := []rune(content); cited == "" && u.StartIndex >= 0 && u.StartIndex < u.EndIndex && u.EndIndex <= len(r) {
+			cited = string(r[u.StartIndex:u.EndIndex])
+		}
+		c.add(u.URL, u.Title, cited)
+	}
+}
+
+// addPerplexity adds Perplexity's citations, titled from search_results.
+func (c *citationSources) addPerplexity(urls []string, results []OSearchResult) {
+	titles := make(map[string]string, len(results))
+	for _, r := range results {
+		titles[r.URL] = r.Title
+	}
+	for _, u := range urls {
+		c.add(u, titles[u], "")
+	}
+	for _, r := range results {
+		c.add(r.URL, r.Title, "")
+	}
+}
+
+// references returns the numbered source list appended in
+// CitationsReferences mode, or "" when there are no sources.
+func (c *citationSources) references() string {
+	if len(c.list) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("\n\nSources:")
+	for i, s := range c.list {
+		if s.Title != "" {
+			fmt.Fprintf(&sb, "\n[%d] %s - %s", i+1, s.Title, s.URL)
+		} else {
+			fmt.Fprintf(&sb, "\n[%d] %s", i+1, s.URL)
+		}
+	}
+	return sb.String()
+}
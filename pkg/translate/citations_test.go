@@ -0,0 +1,102 @@
+package translate
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const annotatedResponse = `{"id":"r1","choices":[{"index":0,"message":{"role":"assistant",
+	"content":"Go 1.24 is out.",
+	"annotations":[
+		{"type":"url_citation","url_citation":{"url":"https://go.dev/blog","title":"Go Blog","start_index":0,"end_index":7}},
+		{"type":"url_citation","url_citation":{"url":"https://go.dev/doc","content":"release notes"}},
+		{"type":"file_citation"}]},
+	"finish_reason":"stop"}]}`
+
+const perplexityResponse = `{"id":"r1","choices":[{"index":0,"message":{"role":"assistant","content":"Go 1.24 is out [1]."},
+	"finish_reason":"stop"}],
+	"citations":["https://go.dev/blog","https://example.com"],
+	"search_results":[{"title":"Go Blog","url":"https://go.dev/blog"}]}`
+
+func citedText(t *testing.T, body, mode string) AResponseBlock {
+	t.Helper()
+	out, err := ResponseToAnthropicContext([]byte(body), "m", &TransformContext{Citations: mode})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp AResponse
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Content) != 1 {
+		t.Fatalf("expected one text block, got %+v", resp.Content)
+	}
+	return resp.Content[0]
+}
+
+func TestResponseCitations(t *testing.T) {
+	tests := []struct {
+		name, body, mode string
+		text             string
+	}{
+		{"annotations", annotatedResponse, "",
+			"Go 1.24 is out.\n\nSources:\n[1] Go Blog - https://go.dev/blog\n[2] https://go.dev/doc"},
+		{"perplexity", perplexityResponse, CitationsReferences,
+			"Go 1.24 is out [1].\n\nSources:\n[1] Go Blog - https://go.dev/blog\n[2] https://example.com"},
+		{"drop", annotatedResponse, CitationsDrop, "Go 1.24 is out."},
+	}
+	for _, tc := range tests {
+		if got := citedText(t, tc.body, tc.mode); got.Text != tc.text || got.Citations != nil {
+			t.Errorf("%s: got %q %+v, want %q", tc.name, got.Text, got.Citations, tc.text)
+		}
+	}
+
+	block := citedText(t, annotatedResponse, CitationsBlocks)
+	want := []ACitation{
+		{Type: "web_search_result_location", URL: "https://go.dev/blog", Title: "Go Blog", CitedText: "Go 1.24"},
+		{Type: "web_search_result_location", URL: "https://go.dev/doc", CitedText: "release notes"},
+	}
+	if block.Text != "Go 1.24 is out." || !reflect.DeepEqual(block.Citations, want) {
+		t.Errorf("blocks: got %q %+v", block.Text, block.Citations)
+	}
+}
+
+func TestStreamCitations(t *testing.T) {
+	input := makeSSE(
+		`{"id":"r1","choices":[{"index":0,"delta":{"content":"Go 1.24 "}}],"citations":["https://go.dev/blog"]}`,
+		`{"id":"r1","choices":[{"index":0,"delta":{"content":"is out."}}],"citations":["https://go.dev/blog"]}`,
+		`{"id":"r1","choices":[{"index":0,"delta":{"annotations":[{"type":"url_citation","url_citation":{"url":"https://go.dev/doc","title":"Docs"}}]}}]}`,
+		`{"id":"r1","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+	)
+	for _, tc := range []struct {
+		mode string
+		want []string
+	}{
+		{"", []string{`{"delta":{"text":"\n\nSources:\n[1] https://go.dev/blog\n[2] Docs - https://go.dev/doc","type":"text_delta"},"index":0`}},
+		{CitationsBlocks, []string{
+			`{"delta":{"citation":{"type":"web_search_result_location","url":"https://go.dev/blog","title":"","cited_text":"","encrypted_index":""},"type":"citations_delta"},"index":0`,
+			`"url":"https://go.dev/doc","title":"Docs"`,
+		}},
+	} {
+		ctx := NewTransformContext("m", "perplexity")
+		ctx.Citations = tc.mode
+		var buf bytes.Buffer
+		st := NewStreamTranslator("m")
+		st.SetTransformChain(NewTransformChain(), ctx)
+		if err := st.TranslateStream(strings.NewReader(input), &buf); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		v := NewSSEValidator(func(msg string) { t.Errorf("%q: %s", tc.mode, msg) })
+		v.Write(buf.Bytes())
+		v.Close()
+		for _, w := range tc.want {
+			if !strings.Contains(out, w) {
+				t.Errorf("%q: missing %s\n%s", tc.mode, w, out)
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package translate
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Document modes: how document blocks reach a provider.
+const (
+	// DocumentsText extracts the document's text locally and sends it as
+	// message text (the default).
+	DocumentsText = "text"
+	// DocumentsFile sends base64 PDFs as OpenAI file content parts, for
+	// providers that read them; other documents go as text.
+	DocumentsFile = "file"
+	// DocumentsReject fails the request with UnsupportedContentError.
+	DocumentsReject = "reject"
+)
+
+// UnsupportedContentError reports a content block a request can't carry to
+// its provider. The proxy answers it with an invalid_request_error.
+type UnsupportedContentError struct {
+	BlockType string // e.g. "document"
+	Reason    string
+}
+
+func (e *UnsupportedContentError) Error() string {
+	return fmt.Sprintf("%s blocks are not supported here: %s", e.BlockType, e.Reason)
+}
+
+// ADocumentSource is the source of an Anthropic document or image block.
+type ADocumentSource struct {
+	Type      string          `json:"type"` // base64, text, url or content
+	MediaType string          `json:"media_type,omitempty"`
+	Data      string          `json:"data,omitempty"`
+	URL       string          `json:"url,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"` // content: string or []ContentBlock
+}
+
+// OContentPart is a part of an OpenAI message's array content.
+type OContentPart struct {
+	Type string `json:"type"` // text or file
+	Text string `json:"text,omitempty"`
+	File *OFile `json:"file,omitempty"`
+}
+
+// OFile is an OpenAI file content part: a data URL with its file name.
+type OFile struct {
+	Filename string `json:"filename,omitempty"`
+	FileData string `json:"file_data"`
+}
+
+// documentText returns a document block's text, headed by its title.
+func documentText(b ContentBlock) (string, error) {
+	unsupported := func(reason string) error {
+		return &UnsupportedContentError{BlockType: "document", Reason: reason}
+	}
+	if b.Source == nil {
+		return "", unsupported("document has no source")
+	}
+	var text string
+	switch src := b.Source; {
+	case src.Type == "text":
+		text = src.Data
+	case src.Type == "content":
+		// Custom content documents hold text (and images, dropped here).
+		text, _ = extractToolResultContent(ContentBlock{Content: src.Content}, RequestOptions{})
+	case src.Type == "url":
+		return "", unsupported("URL documents can't be fetched for this provider")
+	case src.Type == "base64":
+		data, err := base64.StdEncoding.DecodeString(src.Data)
+		if err != nil {
+			return "", unsupported(fmt.Sprintf("invalid base64 data: %v", err))
+		}
+		switch {
+		case src.MediaType == "application/pdf":
+			if text, err = pdfText(data); err != nil {
+				return "", err
+			}
+			if text == "" {
+				return "", unsupported("no text could be extracted from the PDF")
+			}
+		case strings.HasPrefix(src.MediaType, "text/"):
+			text = string(data)
+		default:
+			return "", unsupported(fmt.Sprintf("media type %q can't be read as text", src.MediaType))
+		}
+	default:
+		return "", unsupported(fmt.Sprintf("unknown source type %q", src.Type))
+	}
+	if b.Title != "" {
+		return "Document: " + b.Title + "\n\n" + text, nil
+	}
+	return text, nil
+}
+
+// documentPart returns a base64 PDF document block as a file part.
+func documentPart(b ContentBlock) (OContentPart, bool) {
+	src := b.Source
+	if src == nil || src.Type != "base64" || src.MediaType != "application/pdf" {
+		return OContentPart{}, false
+	}
+	name := b.Title
+	if name == "" {
+		name = "document"
+	}
+	if !strings.HasSuffix(strings.ToLower(name), ".pdf") {
+		name += ".pdf"
+	}
+	return OContentPart{Type: "file", File: &OFile{
+		Filename: name,
+		FileData: "data:application/pdf;base64," + src.Data,
+	}}, true
+}
+
+// translateDocument turns a document block into content parts as
+// opts.Documents asks.
+func translateDocument(b ContentBlock, opts RequestOptions) (OContentPart, error) {
+	switch opts.Documents {
+	case DocumentsReject:
+		return OContentPart{}, &UnsupportedContentError{BlockType: "document", Reason: "this provider is configured to reject documents"}
+	case DocumentsFile:
+		if part, ok := documentPart(b); ok {
+			return part, nil
+		}
+	}
+	text, err := documentText(b)
+	if err != nil {
+		return OContentPart{}, err
+	}
+	return OContentPart{Type: "text", Text: text}, nil
+}
@@ -0,0 +1,120 @@
+package translate
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func documentRequest(t *testing.T, blocks string) []byte {
+	t.Helper()
+	return []byte(`{"model":"x","max_tokens":10,"messages":[{"role":"user","content":` + blocks + `}]}`)
+}
+
+func TestRequestDocuments(t *testing.T) {
+	pdf := base64.StdEncoding.EncodeToString(testPDF("BT (Quarterly revenue grew.) Tj ET", true))
+	body := documentRequest(t, `[
+		{"type":"document","title":"report.pdf","source":{"type":"base64","media_type":"application/pdf","data":"`+pdf+`"}},
+		{"type":"text","text":"Summarize it."}]`)
+
+	out, err := RequestToOpenAIOptions(body, RequestOptions{Model: "m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req ORequest
+	json.Unmarshal(out, &req)
+	if got := req.Messages[0].Content; got != "Document: report.pdf\n\nQuarterly revenue grew.\nSummarize it." {
+		t.Errorf("text mode content = %q", got)
+	}
+
+	out, err = RequestToOpenAIOptions(body, RequestOptions{Model: "m", Documents: DocumentsFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw struct {
+		Messages []struct {
+			Content []OContentPart `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(out, &raw); err != nil {
+		t.Fatalf("file mode content is not an array: %v\n%s", err, out)
+	}
+	parts := raw.Messages[0].Content
+	if len(parts) != 2 || parts[0].Type != "file" || parts[0].File.Filename != "report.pdf" ||
+		parts[0].File.FileData != "data:application/pdf;base64,"+pdf || parts[1].Text != "Summarize it." {
+		t.Errorf("file mode parts = %+v", parts)
+	}
+
+	_, err = RequestToOpenAIOptions(body, RequestOptions{Model: "m", Documents: DocumentsReject})
+	var unsupported *UnsupportedContentError
+	if !errors.As(err, &unsupported) || unsupported.BlockType != "document" {
+		t.Errorf("reject mode error = %v", err)
+	}
+}
+
+func TestRequestDocumentSources(t *testing.T) {
+	tests := []struct {
+		name, block, want, errPart string
+	}{
+		{"plain text", `{"type":"document","source":{"type":"text","media_type":"text/plain","data":"notes"}}`, "notes", ""},
+		{"base64 text", `{"type":"document","source":{"type":"base64","media_type":"text/csv","data":"` +
+			base64.StdEncoding.EncodeToString([]byte("a,b")) + `"}}`, "a,b", ""},
+		{"content", `{"type":"document","source":{"type":"content","content":[{"type":"text","text":"one"},{"type":"text","text":"two"}]}}`, "one\ntwo", ""},
+		{"url", `{"type":"document","source":{"type":"url","url":"https://example.com/a.pdf"}}`, "", "URL documents"},
+		{"scanned pdf", `{"type":"document","source":{"type":"base64","media_type":"application/pdf","data":"` +
+			base64.StdEncoding.EncodeToString(testPDF("q 100 0 0 100 0 0 cm /Im1 Do Q", false)) + `"}}`, "", "no text could be extracted"},
+	}
+	for _, tc := range tests {
+		out, err := RequestToOpenAIOptions(documentRequest(t, "["+tc.block+"]"), RequestOptions{Model: "m"})
+		if tc.errPart != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.errPart) {
+				t.Errorf("%s: error = %v, want %q", tc.name, err, tc.errPart)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		var req ORequest
+		json.Unmarshal(out, &req)
+		if got := req.Messages[0].Content; got != tc.want {
+			t.Errorf("%s: content = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestRequestDocumentInToolResult(t *testing.T) {
+	pdf := base64.StdEncoding.EncodeToString(testPDF("BT (Page one.) Tj ET", false))
+	body := []byte(`{"model":"x","max_tokens":10,"messages":[
+		{"role":"user","content":"read it"},
+		{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"a.pdf"}}]},
+		{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":[
+			{"type":"document","source":{"type":"base64","media_type":"application/pdf","data":"` + pdf + `"}}]}]}]}`)
+	// Tool messages carry no files: file mode falls back to text.
+	out, err := RequestToOpenAIOptions(body, RequestOptions{Model: "m", Documents: DocumentsFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req ORequest
+	json.Unmarshal(out, &req)
+	if got := req.Messages[2].Content; got != "Page one." {
+		t.Errorf("tool result content = %q", got)
+	}
+	if _, err := RequestToOpenAIOptions(body, RequestOptions{Model: "m", Documents: DocumentsReject}); err == nil {
+		t.Error("reject mode accepted a document in a tool result")
+	}
+}
+
+func TestOMessageArrayContent(t *testing.T) {
+	var m OMessage
+	err := json.Unmarshal([]byte(`{"role":"user","content":[{"type":"text","text":"a"},`+
+		`{"type":"file","file":{"filename":"x.pdf","file_data":"data:application/pdf;base64,AA=="}},{"type":"text","text":"b"}]}`), &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Content != "a\nb" || len(m.Parts) != 1 || m.Parts[0].File.Filename != "x.pdf" {
+		t.Errorf("got %+v", m)
+	}
+}
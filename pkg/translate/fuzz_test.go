@@ -0,0 +1,147 @@
+package translate
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"testing"
+)
+
+// Fuzz targets for the translators. The seeds run with go test; to fuzz:
+//
+//	go test ./pkg/translate -run '^$' -fuzz FuzzStreamTranslator -fuzztime 1m
+
+// allTransforms chains every registered transform, so the fuzzers reach
+// each one's handling of unexpected JSON shapes.
+func allTransforms(t testing.TB) (*TransformChain, *TransformContext) {
+	var names []string
+	for name := range transformRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	chain, err := BuildChain(names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := NewTransformContext("m", "p")
+	ctx.Params = map[string]interface{}{"temperature": 0.2}
+	return chain, ctx
+}
+
+func FuzzRequestToOpenAI(f *testing.F) {
+	f.Add([]byte(`{"model":"claude","max_tokens":100,"system":"be brief","messages":[{"role":"user","content":"hi"}]}`))
+	f.Add([]byte(`{"max_tokens":10,"stream":true,"system":[{"type":"text","text":"s","cache_control":{"type":"ephemeral"}}],
+		"messages":[{"role":"user","content":[{"type":"text","text":"a"},{"type":"image","source":{"type":"base64","media_type":"image/png","data":"AA=="}}]},
+		{"role":"assistant","content":[{"type":"thinking","thinking":"t","signature":"s"},{"type":"tool_use","id":"t1","name":"Read","input":{"p":1}}]},
+		{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":[{"type":"text","text":"ok"}],"is_error":true}]}],
+		"tools":[{"name":"Read","input_schema":{"type":"object","additionalProperties":false,"properties":{"p":{"type":"integer"}}}}],
+		"tool_choice":{"type":"tool","name":"Read"},"thinking":{"type":"enabled","budget_tokens":1024}}`))
+	f.Add([]byte(`{"messages":[{"role":"user","content":null}],"tool_choice":"auto","tools":[{"name":"x","input_schema":[]}]}`))
+	f.Fuzz(func(t *testing.T, body []byte) {
+		out, err := RequestToOpenAI(body, "backend", 4096)
+		if err != nil {
+			return
+		}
+		var req map[string]interface{}
+		if err := json.Unmarshal(out, &req); err != nil {
+			t.Fatalf("translated request is not JSON: %v\n%s", err, out)
+		}
+		chain, ctx := allTransforms(t)
+		chain.RunRequest(req, ctx)
+		if _, err := json.Marshal(req); err != nil {
+			t.Fatalf("transformed request does not marshal: %v", err)
+		}
+		PassthroughToOpenAI(body, "backend", 4096)
+	})
+}
+
+func FuzzResponseToAnthropic(f *testing.F) {
+	f.Add([]byte(`{"id":"c1","choices":[{"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":1}}`))
+	f.Add([]byte(`{"choices":[{"message":{"content":"<think>x</think>y","reasoning_content":"r",
+		"tool_calls":[{"id":"call 1","type":"function","function":{"name":"Read","arguments":"{\"p\":1,}"}}]},"finish_reason":"tool_calls"}]}`))
+	f.Add([]byte(`{"choices":[{"message":{"content":[{"type":"text","text":"a"}],"reasoning_details":[{"text":1}]}}],"usage":null}`))
+	f.Add([]byte(`{"choices":[]}`))
+	f.Fuzz(func(t *testing.T, body []byte) {
+		chain, ctx := allTransforms(t)
+		if transformed, err := chain.RunResponse(body, ctx); err == nil {
+			body = transformed
+		}
+		out, err := ResponseToAnthropic(body, "label")
+		if err != nil {
+			return
+		}
+		if !json.Valid(out) {
+			t.Fatalf("translated response is not JSON:\n%s", out)
+		}
+	})
+}
+
+func FuzzStreamTranslator(f *testing.F) {
+	f.Add([]byte("data: {\"choices\":[{\"delta\":{\"role\":\"assistant\",\"content\":\"hi\"}}]}\n\n" +
+		"data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":1}}\n\ndata: [DONE]\n\n"))
+	f.Add([]byte("data: {\"choices\":[{\"delta\":{\"reasoning_content\":\"r\"}}]}\n\n" +
+		"data: {\"choices\":[{\"delta\":{\"content\":\"<think>a</think>b\"}}]}\n\n" +
+		"data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"id\":\"c1\",\"function\":{\"name\":\"Read\",\"arguments\":\"{\\\"p\\\"\"}}]}}]}\n\n" +
+		"data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"function\":{\"arguments\":\":1,}\"}}]}}]}\n\n" +
+		"data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"tool_calls\"}]}\n\n"))
+	f.Add([]byte(": keepalive\r\nevent: error\r\ndata: {\"error\":{\"message\":\"boom\"}}\r\n\r\n"))
+	f.Add([]byte("data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":-1}]}}]}\n\ndata: {\"choices\":null}\n\n"))
+	f.Fuzz(func(t *testing.T, stream []byte) {
+		st := NewStreamTranslator("label")
+		st.SetMaxLineBytes(1 << 16)
+		chain, ctx := allTransforms(t)
+		st.SetTransformChain(chain, ctx)
+		st.TranslateStream(bytes.NewReader(stream), io.Discard)
+
+		rt := NewReverseStreamTranslator("label", true)
+		rt.TranslateStream(bytes.NewReader(stream), io.Discard)
+	})
+}
+
+func FuzzSSEEventReader(f *testing.F) {
+	f.Add([]byte("event: message_start\ndata: {}\n\n"))
+	f.Add([]byte("\xef\xbb\xbfdata: a\r\ndata: b\r\nid: 1\r\nretry: 10\r\n\r\n: comment\n"))
+	f.Add([]byte("data:no-space\ndata\n\nevent: x\n\ndata: unterminated"))
+	f.Fuzz(func(t *testing.T, stream []byte) {
+		er := newSSEEventReader(bytes.NewReader(stream), 1<<12)
+		for i := 0; ; i++ {
+			ev, err := er.next()
+			if err != nil {
+				return
+			}
+			if len(ev.Data) > len(stream) {
+				t.Fatalf("event %d has %d bytes of data from a %d-byte stream", i, len(ev.Data), len(stream))
+			}
+		}
+	})
+}
+
+// FuzzTransforms hands arbitrary JSON to each transform on its own, since a
+// chain stops at the first error and would shield the transforms after it.
+func FuzzTransforms(f *testing.F) {
+	f.Add([]byte(`{"messages":[{"role":"system","content":[{"type":"text","text":"s","cache_control":{}}]}],"tools":[{"function":{"name":"f","parameters":{"type":"object","properties":{"a":{"type":["string","null"],"format":"uri"}}}}}],"max_completion_tokens":5}`))
+	f.Add([]byte(`{"choices":[{"message":{"content":"<reasoning_content>r</reasoning_content>x","tool_calls":[{"function":{"name":"ExitTool","arguments":"{\"response\":\"y\"}"}}]}}]}`))
+	f.Add([]byte(`{"choices":[{"index":0,"delta":{"content":"<think>","tool_calls":[{"index":0,"function":{"arguments":"{"}}]},"finish_reason":null}]}`))
+	f.Add([]byte(`{"choices":[{"delta":{"tool_calls":[{"index":"0","function":null}]},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens_details":{"cached_tokens":"x"}}}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var names []string
+		for name := range transformRegistry {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			tr := transformRegistry[name]()
+			ctx := NewTransformContext("m", "p")
+			ctx.Params = map[string]interface{}{"temperature": 0.2}
+			var req map[string]interface{}
+			if json.Unmarshal(data, &req) == nil && req != nil {
+				tr.TransformRequest(req, ctx)
+			}
+			tr.TransformResponse(data, ctx)
+			// Twice, so stateful stream transforms see a chunk mid-stream.
+			tr.TransformStreamChunk(data, ctx)
+			tr.TransformStreamChunk(data, ctx)
+		}
+	})
+}
@@ -31,10 +31,9 @@ func FixJSON(s string) string {
 		return fixed
 	}
 
-	// Tier 3: close unclosed brackets
-	fixed = closeBrackets(fixed)
-	if json.Valid([]byte(fixed)) {
-		return fixed
+	// Tier 3: close an unterminated string and unclosed brackets
+	if suffix, ok := RepairSuffix(fixed); ok {
+		return fixed + suffix
 	}
 
 	return "{}"
@@ -121,3 +120,64 @@ func closeBrackets(s string) string {
 	}
 	return b.String()
 }
+
+// jsonScan follows streamed JSON fragment by fragment and reports when the
+// top-level object or array has closed, so a stream can tell complete tool
+// arguments from partial ones without re-reading them. It checks brackets
+// and strings only; the value is validated once, when its block closes.
+type jsonScan struct {
+	depth    int
+	opened   bool
+	inString bool
+	escaped  bool
+}
+
+func (s *jsonScan) write(frag string) {
+	for i := 0; i < len(frag); i++ {
+		c := frag[i]
+		if s.inString {
+			switch {
+			case s.escaped:
+				s.escaped = false
+			case c == '\\':
+				s.escaped = true
+			case c == '"':
+				s.inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			s.inString = true
+		case '{', '[':
+			s.depth++
+			s.opened = true
+		case '}', ']':
+			if s.depth > 0 {
+				s.depth--
+			}
+		}
+	}
+}
+
+// complete reports whether a top-level value was opened and has closed.
+func (s *jsonScan) complete() bool {
+	return s.opened && s.depth == 0 && !s.inString
+}
+
+// RepairSuffix returns the text to append to s to make it valid JSON, for
+// cases where s was already streamed to the client and cannot be rewritten:
+// only unterminated strings and unclosed brackets are repairable this way.
+// ok is false when s is empty, already valid, or needs a non-append fix.
+func RepairSuffix(s string) (suffix string, ok bool) {
+	if strings.TrimSpace(s) == "" || json.Valid([]byte(s)) {
+		return "", false
+	}
+	for _, candidate := range []string{s, s + `"`} {
+		fixed := closeBrackets(candidate)
+		if json.Valid([]byte(fixed)) {
+			return fixed[len(s):], true
+		}
+	}
+	return "", false
+}
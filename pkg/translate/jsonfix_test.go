@@ -47,6 +47,21 @@ func TestFixJSON_TruncatedNested(t *testing.T) {
 	assertValidJSON(t, result)
 }
 
+func TestFixJSON_UnterminatedString(t *testing.T) {
+	// A model cut off mid-value: keep what it wrote instead of falling
+	// back to {}.
+	tests := map[string]string{
+		`{"path": "/tmp/fi`:                 `{"path": "/tmp/fi"}`,
+		`{"cmd": "ls", "args": ["-l", "/ho`: `{"cmd": "ls", "args": ["-l", "/ho"]}`,
+		`{"a": {"note": "say \"hi`:          `{"a": {"note": "say \"hi"}}`,
+	}
+	for in, want := range tests {
+		if got := FixJSON(in); got != want {
+			t.Errorf("FixJSON(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
 func TestFixJSON_EmptyFallback(t *testing.T) {
 	result := FixJSON(`not json at all!!!`)
 	if result != "{}" {
@@ -83,3 +98,50 @@ func assertContains(t *testing.T, s, substr string) {
 		t.Errorf("expected %q to contain %q", s, substr)
 	}
 }
+
+func TestRepairSuffix(t *testing.T) {
+	tests := []struct {
+		in     string
+		suffix string
+		ok     bool
+	}{
+		{`{"a":1}`, "", false},
+		{``, "", false},
+		{`{"a":1`, "}", true},
+		{`{"a":"b`, `"}`, true},
+		{`{"a":[{"b":1}`, "]}", true},
+		{`{"a":1,}`, "", false}, // trailing comma needs a rewrite, not an append
+	}
+	for _, tt := range tests {
+		suffix, ok := RepairSuffix(tt.in)
+		if ok != tt.ok || suffix != tt.suffix {
+			t.Errorf("RepairSuffix(%q) = %q, %v; want %q, %v", tt.in, suffix, ok, tt.suffix, tt.ok)
+		}
+	}
+}
+
+func TestJSONScan(t *testing.T) {
+	tests := []struct {
+		frags    []string
+		complete bool
+	}{
+		{nil, false},
+		{[]string{`{"a":1}`}, true},
+		{[]string{`{"a":`, `1`}, false},
+		{[]string{`{"a":`, `1}`}, true},
+		{[]string{`{"cmd":"echo }"`}, false},        // brace inside a string
+		{[]string{`{"cmd":"say \"}`, `\""}`}, true}, // escaped quote split across fragments
+		{[]string{`{"p":"C:\\`, `"}`}, true},        // escaped backslash at a fragment end
+		{[]string{`{"a":[1,`, `2]`}, false},
+		{[]string{`{"a":[1,`, `2]}`}, true},
+	}
+	for _, tt := range tests {
+		var s jsonScan
+		for _, f := range tt.frags {
+			s.write(f)
+		}
+		if s.complete() != tt.complete {
+			t.Errorf("%q: complete = %v, want %v", tt.frags, s.complete(), tt.complete)
+		}
+	}
+}
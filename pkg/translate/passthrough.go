@@ -0,0 +1,97 @@
+package translate
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// anthropicOnlyFields are request fields with no OpenAI equivalent that are
+// dropped in passthrough mode.
+var anthropicOnlyFields = []string{"metadata", "thinking", "top_k", "anthropic_version", "container", "mcp_servers"}
+
+// PassthroughToOpenAI builds an OpenAI Chat Completions request from an
+// Anthropic-shaped body whose system prompt and messages already target the
+// backend model directly (marker option raw=openai). Messages are forwarded
+// verbatim; only the envelope is adapted: the model is replaced, system
+// becomes a leading system message, max_tokens and stop_sequences are renamed,
+// metadata.user_id becomes user, and Anthropic-format tools are converted.
+// Fields that are already OpenAI-shaped pass through untouched.
+func PassthroughToOpenAI(body []byte, backendModel string, maxTokensCap int) ([]byte, error) {
+	return PassthroughToOpenAIOptions(body, RequestOptions{Model: backendModel, MaxTokensCap: maxTokensCap})
+}
+
+// PassthroughToOpenAIOptions is PassthroughToOpenAI with options. Only
+// Model, MaxTokensCap and TopK apply, since messages are not translated.
+func PassthroughToOpenAIOptions(body []byte, opts RequestOptions) ([]byte, error) {
+	var req map[string]interface{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("parse request: %w", err)
+	}
+
+	req["model"] = opts.Model
+
+	msgs, _ := req["messages"].([]interface{})
+	if raw, ok := req["system"]; ok {
+		sysJSON, _ := json.Marshal(raw)
+		if text := extractSystemText(sysJSON); text != "" {
+			msgs = append([]interface{}{map[string]interface{}{"role": "system", "content": text}}, msgs...)
+		}
+		delete(req, "system")
+	}
+	req["messages"] = msgs
+
+	if mt, ok := req["max_tokens"].(float64); ok {
+		if opts.MaxTokensCap > 0 && int(mt) > opts.MaxTokensCap {
+			mt = float64(opts.MaxTokensCap)
+		}
+		req["max_completion_tokens"] = mt
+		delete(req, "max_tokens")
+	}
+	if stop, ok := req["stop_sequences"]; ok {
+		req["stop"] = stop
+		delete(req, "stop_sequences")
+	}
+	if md, ok := req["metadata"].(map[string]interface{}); ok {
+		if id, ok := md["user_id"].(string); ok && id != "" {
+			if _, set := req["user"]; !set {
+				req["user"] = id
+			}
+		}
+	}
+	for _, f := range anthropicOnlyFields {
+		if f == "top_k" && opts.TopK {
+			continue
+		}
+		delete(req, f)
+	}
+
+	if tools, ok := req["tools"].([]interface{}); ok {
+		for i, t := range tools {
+			tool, ok := t.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			schema, isAnthropic := tool["input_schema"]
+			if !isAnthropic {
+				continue
+			}
+			fn := map[string]interface{}{"name": tool["name"], "parameters": schema}
+			if desc, ok := tool["description"]; ok {
+				fn["description"] = desc
+			}
+			tools[i] = map[string]interface{}{"type": "function", "function": fn}
+		}
+	}
+	if tc, ok := req["tool_choice"].(map[string]interface{}); ok {
+		raw, _ := json.Marshal(tc)
+		req["tool_choice"] = translateToolChoice(raw)
+	}
+
+	if stream, _ := req["stream"].(bool); stream {
+		if _, ok := req["stream_options"]; !ok {
+			req["stream_options"] = map[string]bool{"include_usage": true}
+		}
+	}
+
+	return json.Marshal(req)
+}
@@ -0,0 +1,92 @@
+package translate
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestPassthroughToOpenAI(t *testing.T) {
+	body := []byte(`{
+		"model": "claude-sonnet",
+		"system": [{"type": "text", "text": "<|im_start|>system tuned"}],
+		"messages": [{"role": "user", "content": "exact prompt"}],
+		"max_tokens": 32000,
+		"stop_sequences": ["<|im_end|>"],
+		"metadata": {"user_id": "u1"},
+		"top_k": 20,
+		"stream": true,
+		"tools": [{"name": "Read", "description": "read", "input_schema": {"type": "object"}}],
+		"tool_choice": {"type": "any"},
+		"repeat_penalty": 1.1
+	}`)
+
+	out, err := PassthroughToOpenAI(body, "qwen3:32b", 8192)
+	if err != nil {
+		t.Fatalf("PassthroughToOpenAI: %v", err)
+	}
+	var req map[string]interface{}
+	json.Unmarshal(out, &req)
+
+	if req["model"] != "qwen3:32b" {
+		t.Errorf("model = %v", req["model"])
+	}
+	wantMsgs := []interface{}{
+		map[string]interface{}{"role": "system", "content": "<|im_start|>system tuned"},
+		map[string]interface{}{"role": "user", "content": "exact prompt"},
+	}
+	if !reflect.DeepEqual(req["messages"], wantMsgs) {
+		t.Errorf("messages = %v", req["messages"])
+	}
+	if req["max_completion_tokens"] != float64(8192) {
+		t.Errorf("expected capped max_completion_tokens, got %v", req["max_completion_tokens"])
+	}
+	for _, k := range []string{"system", "max_tokens", "stop_sequences", "metadata"} {
+		if _, ok := req[k]; ok {
+			t.Errorf("%s should be removed", k)
+		}
+	}
+	if !reflect.DeepEqual(req["stop"], []interface{}{"<|im_end|>"}) {
+		t.Errorf("stop = %v", req["stop"])
+	}
+	if req["user"] != "u1" {
+		t.Errorf("user = %v, want metadata.user_id", req["user"])
+	}
+	if _, ok := req["top_k"]; ok {
+		t.Error("top_k should be removed")
+	}
+	if req["repeat_penalty"] != 1.1 {
+		t.Errorf("backend-specific field should pass through, got %v", req["repeat_penalty"])
+	}
+	if req["tool_choice"] != "required" {
+		t.Errorf("tool_choice = %v", req["tool_choice"])
+	}
+	tool := req["tools"].([]interface{})[0].(map[string]interface{})
+	if tool["type"] != "function" || tool["function"].(map[string]interface{})["name"] != "Read" {
+		t.Errorf("tool not converted: %v", tool)
+	}
+	if _, ok := req["stream_options"]; !ok {
+		t.Error("expected stream_options for streaming request")
+	}
+}
+
+func TestPassthroughKeepsOpenAIShapedMessages(t *testing.T) {
+	body := []byte(`{
+		"messages": [
+			{"role": "assistant", "content": null, "tool_calls": [{"id": "c1", "type": "function", "function": {"name": "f", "arguments": "{}"}}]},
+			{"role": "tool", "tool_call_id": "c1", "content": "done"}
+		],
+		"max_tokens": 10
+	}`)
+	out, err := PassthroughToOpenAI(body, "m", 0)
+	if err != nil {
+		t.Fatalf("PassthroughToOpenAI: %v", err)
+	}
+	var req struct {
+		Messages []map[string]interface{} `json:"messages"`
+	}
+	json.Unmarshal(out, &req)
+	if len(req.Messages) != 2 || req.Messages[1]["role"] != "tool" || req.Messages[0]["tool_calls"] == nil {
+		t.Errorf("messages should be forwarded verbatim, got %v", req.Messages)
+	}
+}
@@ -0,0 +1,268 @@
+package translate
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits on what one PDF may expand to. A small PDF of repeated Flate
+// streams can otherwise decompress, and extract, to gigabytes.
+const (
+	maxPDFDecompressed = 64 << 20 // all content streams together
+	maxPDFText         = 4 << 20  // extracted text
+)
+
+// pdfStream matches a stream's dictionary and the start of its data.
+var pdfStream = regexp.MustCompile(`(?s)<<((?:[^<>]|<[^<]|>[^>]|<<(?:[^<>]|<[^<]|>[^>])*>>)*)>>\s*stream\r?\n`)
+
+// pdfText extracts the text shown by a PDF's content streams. It reads
+// uncompressed and FlateDecode streams and the text-showing operators
+// (Tj, TJ, ' and "), breaking lines where the text moves down. Fonts with
+// custom encodings (Identity-H CID fonts, which most PDF producers embed
+// for non-Latin text) come out as unreadable bytes and are dropped, so the
+// result may be empty; it is no substitute for a real PDF library. A PDF
+// exceeding maxPDFDecompressed or maxPDFText fails with an
+// UnsupportedContentError.
+func pdfText(data []byte) (string, error) {
+	var sb strings.Builder
+	budget := maxPDFDecompressed
+	for _, loc := range pdfStream.FindAllSubmatchIndex(data, -1) {
+		dict := data[loc[2]:loc[3]]
+		start := loc[1]
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		raw := data[start : start+end]
+		switch {
+		case bytes.Contains(dict, []byte("/FlateDecode")):
+			r, err := zlib.NewReader(bytes.NewReader(raw))
+			if err != nil {
+				continue
+			}
+			// A truncated stream still yields its leading text.
+			raw, _ = io.ReadAll(io.LimitReader(r, int64(budget)+1))
+		case bytes.Contains(dict, []byte("/Filter")):
+			continue // images and other encodings carry no text
+		}
+		if budget -= len(raw); budget < 0 {
+			return "", pdfTooLarge(fmt.Sprintf("content streams decompress to more than %d MB", maxPDFDecompressed>>20))
+		}
+		if bytes.Contains(raw, []byte("BT")) {
+			pdfContentText(raw, &sb)
+		}
+		if sb.Len() > maxPDFText {
+			return "", pdfTooLarge(fmt.Sprintf("extracted text exceeds %d MB", maxPDFText>>20))
+		}
+	}
+	return cleanPDFText(sb.String()), nil
+}
+
+func pdfTooLarge(reason string) error {
+	return &UnsupportedContentError{BlockType: "document", Reason: "PDF is too large: " + reason}
+}
+
+// pdfContentText appends the text a content stream shows to sb.
+func pdfContentText(s []byte, sb *strings.Builder) {
+	var operands []string // strings and numbers since the last operator
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '(':
+			str, n := pdfLiteral(s[i:])
+			operands = append(operands, str)
+			i += n
+		case c == '<' && i+1 < len(s) && s[i+1] != '<':
+			j := bytes.IndexByte(s[i:], '>')
+			if j < 0 {
+				return
+			}
+			operands = append(operands, pdfHex(s[i+1:i+j]))
+			i += j + 1
+		case c == '[' || c == ']':
+			i++
+		case c == '%':
+			for i < len(s) && s[i] != '\n' && s[i] != '\r' {
+				i++
+			}
+		case c == '-' || c == '.' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(s) && (s[j] == '.' || (s[j] >= '0' && s[j] <= '9')) {
+				j++
+			}
+			// A large negative TJ adjustment is a word gap.
+			if n, err := strconv.ParseFloat(string(s[i:j]), 64); err == nil && n < -200 {
+				operands = append(operands, " ")
+			} else {
+				operands = append(operands, "\x00"+string(s[i:j]))
+			}
+			i = j
+		case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '\'' || c == '"' || c == '*':
+			j := i + 1
+			for j < len(s) && ((s[j] >= 'a' && s[j] <= 'z') || (s[j] >= 'A' && s[j] <= 'Z') || s[j] == '*') {
+				j++
+			}
+			pdfOperator(string(s[i:j]), operands, sb)
+			operands = operands[:0]
+			i = j
+		default:
+			i++
+		}
+	}
+}
+
+// pdfOperator applies a content stream operator to sb.
+func pdfOperator(op string, operands []string, sb *strings.Builder) {
+	switch op {
+	case "Tj", "TJ", "'", "\"":
+		if op != "Tj" && op != "TJ" {
+			sb.WriteByte('\n')
+		}
+		for _, o := range operands {
+			if !strings.HasPrefix(o, "\x00") {
+				sb.WriteString(o)
+			}
+		}
+	case "Td", "TD":
+		// Moving down starts a new line; moving right, a new word.
+		if len(operands) == 2 && strings.TrimPrefix(operands[1], "\x00") != "0" {
+			sb.WriteByte('\n')
+		} else {
+			sb.WriteByte(' ')
+		}
+	case "T*", "ET":
+		sb.WriteByte('\n')
+	}
+}
+
+// pdfLiteral decodes the literal string at the start of s, returning it and
+// the bytes it spans.
+func pdfLiteral(s []byte) (string, int) {
+	var out []byte
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '(':
+			if depth > 0 {
+				out = append(out, c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return pdfBytes(out), i + 1
+			}
+			out = append(out, c)
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch e := s[i]; e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					j := i
+					for j < len(s) && j < i+3 && s[j] >= '0' && s[j] <= '7' {
+						j++
+					}
+					// Overflow past \377 is ignored: keep the low 8 bits.
+					n, _ := strconv.ParseUint(string(s[i:j]), 8, 16)
+					out = append(out, byte(n))
+					i = j - 1
+				} else {
+					out = append(out, e)
+				}
+			}
+		default:
+			out = append(out, c)
+		}
+	}
+	return pdfBytes(out), len(s)
+}
+
+// pdfHex decodes a hex string's digits.
+func pdfHex(s []byte) string {
+	var out []byte
+	var hi byte
+	half := false
+	for _, c := range s {
+		var v byte
+		switch {
+		case c >= '0' && c <= '9':
+			v = c - '0'
+		case c >= 'a' && c <= 'f':
+			v = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			v = c - 'A' + 10
+		default:
+			continue
+		}
+		if half {
+			out = append(out, hi<<4|v)
+		} else {
+			hi = v
+		}
+		half = !half
+	}
+	if half {
+		out = append(out, hi<<4)
+	}
+	return pdfBytes(out)
+}
+
+// pdfBytes converts a string's bytes to text: UTF-16BE with a byte order
+// mark, else Latin-1 (close enough to the standard encodings).
+func pdfBytes(b []byte) string {
+	if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+		var sb strings.Builder
+		for i := 2; i+1 < len(b); i += 2 {
+			sb.WriteRune(rune(b[i])<<8 | rune(b[i+1]))
+		}
+		return sb.String()
+	}
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	rs := make([]rune, len(b))
+	for i, c := range b {
+		rs[i] = rune(c)
+	}
+	return string(rs)
+}
+
+// cleanPDFText drops unreadable lines and collapses blank space. A line
+// counts as unreadable when fewer than half its characters are letters,
+// digits, spaces or common punctuation.
+func cleanPDFText(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			continue
+		}
+		readable := 0
+		for _, r := range line {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) || strings.ContainsRune(".,;:!?'\"()-%/&", r) {
+				readable++
+			}
+		}
+		if readable*2 >= utf8.RuneCountInString(line) {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
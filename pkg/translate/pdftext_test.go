@@ -0,0 +1,91 @@
+package translate
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// testPDF returns a minimal PDF showing content, Flate-compressed when
+// compress is set. Real PDFs have more objects; pdfText only needs streams.
+func testPDF(content string, compress bool) []byte {
+	return testPDFStreams(compress, content)
+}
+
+// testPDFStreams returns a minimal PDF with one content stream per entry.
+func testPDFStreams(compress bool, contents ...string) []byte {
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\n")
+	for i, content := range contents {
+		stream, dict := []byte(content), ""
+		if compress {
+			var z bytes.Buffer
+			w := zlib.NewWriter(&z)
+			w.Write(stream)
+			w.Close()
+			stream, dict = z.Bytes(), " /Filter /FlateDecode"
+		}
+		fmt.Fprintf(&pdf, "%d 0 obj\n<< /Length %d%s >>\nstream\n%s\nendstream\nendobj\n", i+2, len(stream), dict, stream)
+	}
+	pdf.WriteString("%%EOF\n")
+	return pdf.Bytes()
+}
+
+func TestPDFText(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{"lines", "BT /F1 12 Tf 72 720 Td (Hello PDF) Tj 0 -14 Td (second line) Tj ET", "Hello PDF\nsecond line"},
+		{"TJ spacing", "BT [(wor) -20 (ld) -300 (again)] TJ ET", "world again"},
+		{"escapes", `BT (a \(b\) c\\d \101) Tj T* (next) ' ET`, "a (b) c\\d A\nnext"},
+		{"octal overflow", `BT (\501\502\503) Tj ET`, "ABC"},
+		{"hex", "BT <48656C6C6F> Tj ET", "Hello"},
+		{"utf16", "BT <FEFF00E9007400E9> Tj ET", "été"},
+		{"glyph ids", "BT <0001000200030004000500060007> Tj ET", ""},
+		{"no text", "0 0 m 100 100 l S", ""},
+	}
+	for _, tc := range tests {
+		for _, compress := range []bool{false, true} {
+			got, err := pdfText(testPDF(tc.content, compress))
+			if err != nil || got != tc.want {
+				t.Errorf("%s (compressed %v): got %q, %v, want %q", tc.name, compress, got, err, tc.want)
+			}
+		}
+	}
+}
+
+func TestPDFTextSkipsImages(t *testing.T) {
+	pdf := []byte("%PDF-1.4\n1 0 obj\n<< /Length 6 /Filter /DCTDecode >>\nstream\nBT ET \nendstream\nendobj\n")
+	if got, _ := pdfText(pdf); got != "" {
+		t.Errorf("got %q from an image stream", got)
+	}
+}
+
+func TestPDFTextLimits(t *testing.T) {
+	// Each stream is a few KB compressed but megabytes of text.
+	text := "BT (" + strings.Repeat("all work and no play ", 100000) + ") Tj ET" // 2 MB
+	noText := strings.Repeat("0 0 m 1 1 l S\n", 1<<20)                           // 14 MB
+	tests := []struct {
+		name    string
+		streams []string
+		reason  string
+	}{
+		{"text", []string{text, text, text}, "extracted text"},
+		{"decompressed", []string{noText, noText, noText, noText, noText}, "decompress"},
+	}
+	for _, tc := range tests {
+		pdf := testPDFStreams(true, tc.streams...)
+		_, err := pdfText(pdf)
+		var unsupported *UnsupportedContentError
+		if !errors.As(err, &unsupported) || !strings.Contains(unsupported.Reason, tc.reason) {
+			t.Errorf("%s: %d-byte PDF: error = %v, want an UnsupportedContentError about %s", tc.name, len(pdf), err, tc.reason)
+		}
+	}
+	// One stream under both limits still extracts.
+	if got, err := pdfText(testPDFStreams(true, text)); err != nil || len(got) < 2<<20-100 {
+		t.Errorf("2 MB of text: got %d bytes, %v", len(got), err)
+	}
+}
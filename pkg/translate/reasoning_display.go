@@ -0,0 +1,71 @@
+package translate
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ThinkingTruncatedMarker ends a thinking block cut short by
+// ReasoningDisplay.SummaryTokens.
+const ThinkingTruncatedMarker = "\n[thinking truncated]"
+
+// ReasoningDisplay limits how much of a model's thinking reaches the client.
+// The zero value shows all of it.
+type ReasoningDisplay struct {
+	Hidden bool // drop thinking blocks entirely
+	// SummaryTokens keeps about this many tokens of each thinking block,
+	// cut at a word boundary and followed by ThinkingTruncatedMarker
+	// (0 = all).
+	SummaryTokens int
+	// Count measures tokens for SummaryTokens (nil = about four bytes a
+	// token).
+	Count func(text string) int
+}
+
+// count returns the token count of text.
+func (d ReasoningDisplay) count(text string) int {
+	if d.Count != nil {
+		return d.Count(text)
+	}
+	return (len(text) + 3) / 4
+}
+
+// truncate returns the longest prefix of text, ending at a word boundary,
+// of at most SummaryTokens tokens, and whether text was cut.
+func (d ReasoningDisplay) truncate(text string) (string, bool) {
+	if d.SummaryTokens <= 0 || d.count(text) <= d.SummaryTokens {
+		return text, false
+	}
+	// Binary search over rune boundaries for the longest prefix that fits.
+	var bounds []int
+	for i := range text {
+		bounds = append(bounds, i)
+	}
+	lo, hi := 0, len(bounds)-1
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if d.count(text[:bounds[mid]]) <= d.SummaryTokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	cut := text[:bounds[lo]]
+	next, _ := utf8.DecodeRuneInString(text[len(cut):])
+	if i := strings.LastIndexFunc(cut, unicode.IsSpace); i > 0 && !unicode.IsSpace(next) {
+		cut = cut[:i]
+	}
+	return strings.TrimRightFunc(cut, unicode.IsSpace), true
+}
+
+// apply returns thinking as the client should see it: "" when hidden.
+func (d ReasoningDisplay) apply(thinking string) string {
+	if d.Hidden {
+		return ""
+	}
+	if cut, ok := d.truncate(thinking); ok {
+		return cut + ThinkingTruncatedMarker
+	}
+	return thinking
+}
@@ -0,0 +1,121 @@
+package translate
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func words(s string) int { return len(strings.Fields(s)) }
+
+func TestReasoningDisplayTruncate(t *testing.T) {
+	tests := []struct {
+		text   string
+		tokens int
+		want   string
+		cut    bool
+	}{
+		{"one two three", 0, "one two three", false},
+		{"one two three", 3, "one two three", false},
+		{"one two three four", 2, "one two", true},
+		{"one two three four", 1, "one", true},
+		{"ünï cödé wörds hëre", 2, "ünï cödé", true},
+	}
+	for _, tc := range tests {
+		d := ReasoningDisplay{SummaryTokens: tc.tokens, Count: words}
+		got, cut := d.truncate(tc.text)
+		if got != tc.want || cut != tc.cut {
+			t.Errorf("truncate(%q, %d) = %q, %v; want %q, %v", tc.text, tc.tokens, got, cut, tc.want, tc.cut)
+		}
+	}
+}
+
+func translateWithDisplay(t *testing.T, d ReasoningDisplay) string {
+	t.Helper()
+	input := makeSSE(
+		`{"id":"r1","choices":[{"index":0,"delta":{"reasoning_content":"First I read "}}]}`,
+		`{"id":"r1","choices":[{"index":0,"delta":{"reasoning_content":"the file, then I "}}]}`,
+		`{"id":"r1","choices":[{"index":0,"delta":{"reasoning_content":"edit it."}}]}`,
+		`{"id":"r1","choices":[{"index":0,"delta":{"content":"Done."}}]}`,
+		`{"id":"r1","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+	)
+	ctx := NewTransformContext("m", "deepseek")
+	ctx.ReasoningDisplay = d
+	var buf bytes.Buffer
+	st := NewStreamTranslator("m")
+	st.SetTransformChain(NewTransformChain(newReasoningTransform()), ctx)
+	if err := st.TranslateStream(strings.NewReader(input), &buf); err != nil {
+		t.Fatal(err)
+	}
+	v := NewSSEValidator(func(msg string) { t.Error(msg) })
+	v.Write(buf.Bytes())
+	v.Close()
+	return buf.String()
+}
+
+func TestStreamReasoningHidden(t *testing.T) {
+	out := translateWithDisplay(t, ReasoningDisplay{Hidden: true})
+	if strings.Contains(out, "thinking") || strings.Contains(out, "signature") {
+		t.Errorf("hidden reasoning reached the client:\n%s", out)
+	}
+	if !strings.Contains(out, `{"delta":{"text":"Done.","type":"text_delta"},"index":0`) {
+		t.Errorf("text should be block 0:\n%s", out)
+	}
+}
+
+func TestStreamReasoningSummary(t *testing.T) {
+	out := translateWithDisplay(t, ReasoningDisplay{SummaryTokens: 5, Count: words})
+	var thinking strings.Builder
+	for _, line := range strings.Split(out, "\n") {
+		var ev struct {
+			Delta struct {
+				Type     string `json:"type"`
+				Thinking string `json:"thinking"`
+			} `json:"delta"`
+		}
+		json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev)
+		if ev.Delta.Type == "thinking_delta" {
+			thinking.WriteString(ev.Delta.Thinking)
+		}
+	}
+	if want := "First I read the file," + ThinkingTruncatedMarker; thinking.String() != want {
+		t.Errorf("thinking = %q, want %q", thinking.String(), want)
+	}
+	if !strings.Contains(out, `"type":"signature_delta"},"index":0`) || !strings.Contains(out, `"text":"Done."`) {
+		t.Errorf("expected a signed summary block, then the text:\n%s", out)
+	}
+}
+
+func TestResponseReasoningDisplay(t *testing.T) {
+	body := `{"id":"r1","choices":[{"index":0,"message":{"role":"assistant","content":"Done.",` +
+		`"thinking":"First I read the file, then I edit it."},"finish_reason":"stop"}]}`
+	tests := []struct {
+		display  ReasoningDisplay
+		thinking string
+	}{
+		{ReasoningDisplay{}, "First I read the file, then I edit it."},
+		{ReasoningDisplay{Hidden: true}, ""},
+		{ReasoningDisplay{SummaryTokens: 3, Count: words}, "First I read" + ThinkingTruncatedMarker},
+	}
+	for _, tc := range tests {
+		out, err := ResponseToAnthropicContext([]byte(body), "m", &TransformContext{ReasoningDisplay: tc.display})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var resp AResponse
+		json.Unmarshal(out, &resp)
+		var got string
+		for _, b := range resp.Content {
+			if b.Type == "thinking" {
+				got = b.Thinking
+			}
+		}
+		if got != tc.thinking {
+			t.Errorf("%+v: thinking = %q, want %q", tc.display, got, tc.thinking)
+		}
+		if last := resp.Content[len(resp.Content)-1]; last.Text != "Done." {
+			t.Errorf("%+v: text block lost: %+v", tc.display, resp.Content)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package translate
+
+import "strings"
+
+// Providers return reasoning under different fields. extractReasoning reads
+// every known variant from an OpenAI message or delta, removes them, and
+// returns the text so the reasoning transform can emit one thinking
+// representation:
+//
+//	reasoning_content                      DeepSeek, Qwen, vLLM, llama.cpp
+//	reasoning (string)                     OpenRouter, Groq, Ollama
+//	reasoning_details[].text / .summary    OpenRouter (reasoning.text, reasoning.summary)
+//	reasoning_summary (string or parts)    OpenAI o-series via compatible gateways
+//	content + extra_content.google.thought Gemini thought parts
+//
+// OpenRouter sends reasoning and reasoning_details side by side with the
+// same text, so the first variant found wins and the rest are dropped.
+// Encrypted details carry no text and are dropped.
+func extractReasoning(m map[string]interface{}) (string, bool) {
+	text, found := "", false
+	take := func(s string, ok bool) {
+		if ok && !found {
+			text, found = s, true
+		}
+	}
+
+	take(stringField(m["reasoning_content"]))
+	take(stringField(m["reasoning"]))
+	take(reasoningDetailsText(m["reasoning_details"]))
+	take(summaryText(m["reasoning_summary"]))
+	if isGeminiThought(m) {
+		take(stringField(m["content"]))
+		delete(m, "content")
+		delete(m, "extra_content")
+	}
+
+	for _, k := range []string{"reasoning_content", "reasoning", "reasoning_details", "reasoning_summary"} {
+		delete(m, k)
+	}
+	return text, found
+}
+
+func stringField(v interface{}) (string, bool) {
+	s, ok := v.(string)
+	return s, ok
+}
+
+// reasoningDetailsText joins the readable entries of an OpenRouter
+// reasoning_details array.
+func reasoningDetailsText(v interface{}) (string, bool) {
+	details, ok := v.([]interface{})
+	if !ok {
+		return "", false
+	}
+	var b strings.Builder
+	found := false
+	for _, d := range details {
+		entry, ok := d.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch entry["type"] {
+		case "reasoning.text":
+			if s, ok := entry["text"].(string); ok {
+				b.WriteString(s)
+				found = true
+			}
+		case "reasoning.summary":
+			if s, ok := entry["summary"].(string); ok {
+				b.WriteString(s)
+				found = true
+			}
+		}
+	}
+	return b.String(), found
+}
+
+// summaryText reads a reasoning summary given as a string or as a list of
+// {"type": "summary_text", "text": ...} parts.
+func summaryText(v interface{}) (string, bool) {
+	if s, ok := v.(string); ok {
+		return s, true
+	}
+	parts, ok := v.([]interface{})
+	if !ok {
+		return "", false
+	}
+	var b strings.Builder
+	found := false
+	for _, p := range parts {
+		part, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if s, ok := part["text"].(string); ok {
+			if found {
+				b.WriteString("\n\n")
+			}
+			b.WriteString(s)
+			found = true
+		}
+	}
+	return b.String(), found
+}
+
+// isGeminiThought reports whether m's content is a Gemini thought part
+// (extra_content.google.thought: true) rather than answer text.
+func isGeminiThought(m map[string]interface{}) bool {
+	extra, _ := m["extra_content"].(map[string]interface{})
+	google, _ := extra["google"].(map[string]interface{})
+	thought, _ := google["thought"].(bool)
+	return thought
+}
+
+// takePriorThinking calls fn with each assistant message of an OpenAI
+// request that carries thinking from an earlier Anthropic turn (see
+// OMessage.Thinking), after removing the field. Transforms use it to hand
+// that reasoning back in the form their provider reads: reasoning_content,
+// OpenRouter's reasoning, or inline tags.
+func takePriorThinking(req map[string]interface{}, fn func(msg map[string]interface{}, thinking string)) {
+	msgs, _ := req["messages"].([]interface{})
+	for _, m := range msgs {
+		msg, ok := m.(map[string]interface{})
+		if !ok || msg["role"] != "assistant" {
+			continue
+		}
+		thinking, ok := msg["thinking"].(string)
+		if !ok || thinking == "" {
+			continue
+		}
+		delete(msg, "thinking")
+		fn(msg, thinking)
+	}
+}
@@ -0,0 +1,545 @@
+// Package translate converts between Anthropic Messages API and OpenAI Chat Completions API formats.
+//
+// It is a public package with an API that follows semantic versioning with
+// the module: RequestToOpenAI, ResponseToAnthropic and StreamTranslator for
+// the two directions, and BuildChain (or NewTransformChain with custom
+// Transformers, registered by RegisterTransform) for provider quirks.
+package translate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// AnthropicRequest represents the relevant fields of an Anthropic Messages API request.
+type AnthropicRequest struct {
+	Model         string          `json:"model"`
+	System        json.RawMessage `json:"system,omitempty"` // string or []ContentBlock
+	Messages      []AMessage      `json:"messages"`
+	MaxTokens     int             `json:"max_tokens,omitempty"`
+	Temperature   *float64        `json:"temperature,omitempty"`
+	TopP          *float64        `json:"top_p,omitempty"`
+	TopK          *int            `json:"top_k,omitempty"`
+	StopSequences []string        `json:"stop_sequences,omitempty"`
+	Stream        bool            `json:"stream,omitempty"`
+	Tools         []ATool         `json:"tools,omitempty"`
+	ToolChoice    json.RawMessage `json:"tool_choice,omitempty"`
+	Metadata      *AMetadata      `json:"metadata,omitempty"`
+}
+
+// AMetadata is an Anthropic request's metadata.
+type AMetadata struct {
+	UserID string `json:"user_id,omitempty"` // opaque end-user ID; OpenAI's user
+}
+
+// AMessage is an Anthropic message.
+type AMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"` // string or []ContentBlock
+}
+
+// ContentBlock is an Anthropic content block (text, tool_use, tool_result, thinking).
+type ContentBlock struct {
+	Type      string           `json:"type"`
+	Text      string           `json:"text,omitempty"`
+	ID        string           `json:"id,omitempty"`          // tool_use
+	Name      string           `json:"name,omitempty"`        // tool_use
+	Input     json.RawMessage  `json:"input,omitempty"`       // tool_use
+	ToolUseID string           `json:"tool_use_id,omitempty"` // tool_result
+	Content   json.RawMessage  `json:"content,omitempty"`     // tool_result (string or []ContentBlock)
+	IsError   bool             `json:"is_error,omitempty"`    // tool_result
+	Thinking  string           `json:"thinking,omitempty"`    // thinking block content
+	Signature string           `json:"signature,omitempty"`   // thinking
+	Title     string           `json:"title,omitempty"`       // document
+	Source    *ADocumentSource `json:"source,omitempty"`      // document, image
+}
+
+// ATool is an Anthropic tool definition.
+type ATool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+// OpenAI types
+
+// ORequest is an OpenAI Chat Completions request.
+type ORequest struct {
+	Model       string      `json:"model"`
+	Messages    []OMessage  `json:"messages"`
+	MaxTokens   int         `json:"max_completion_tokens,omitempty"`
+	Temperature *float64    `json:"temperature,omitempty"`
+	TopP        *float64    `json:"top_p,omitempty"`
+	TopK        *int        `json:"top_k,omitempty"` // not OpenAI; see RequestOptions.TopK
+	Stop        []string    `json:"stop,omitempty"`
+	Stream      bool        `json:"stream,omitempty"`
+	Tools       []OTool     `json:"tools,omitempty"`
+	ToolChoice  interface{} `json:"tool_choice,omitempty"`
+	User        string      `json:"user,omitempty"` // from metadata.user_id
+}
+
+// OMessage is an OpenAI message.
+type OMessage struct {
+	Role        string        `json:"role"`
+	Content     string        `json:"content,omitempty"`
+	ToolCalls   []OToolCall   `json:"tool_calls,omitempty"`   // assistant
+	ToolCallID  string        `json:"tool_call_id,omitempty"` // tool
+	Thinking    string        `json:"thinking,omitempty"`     // preserved from Anthropic thinking blocks; see takePriorThinking
+	Annotations []OAnnotation `json:"annotations,omitempty"`  // response only: cited sources
+
+	// Parts, when set, are sent as array content after Content's text, for
+	// parts that aren't text (files); see MarshalJSON.
+	Parts []OContentPart `json:"-"`
+
+	emptyContent string // how MarshalJSON writes empty Content; see RequestOptions.EmptyContent
+	signature    string // thinking signature set by a transform, for ResponseToAnthropic
+}
+
+// MarshalJSON writes Parts as array content, and an empty Content as null
+// or "" when the message asks for it, leaving it out otherwise.
+func (m OMessage) MarshalJSON() ([]byte, error) {
+	type plain OMessage
+	if len(m.Parts) > 0 {
+		parts := m.Parts
+		if m.Content != "" {
+			parts = append([]OContentPart{{Type: "text", Text: m.Content}}, parts...)
+		}
+		return json.Marshal(struct {
+			plain
+			Content []OContentPart `json:"content"`
+		}{plain(m), parts})
+	}
+	if m.Content != "" || (m.emptyContent != EmptyContentNull && m.emptyContent != EmptyContentString) {
+		return json.Marshal(plain(m))
+	}
+	aux := struct {
+		plain
+		Content *string `json:"content"`
+	}{plain: plain(m)}
+	if m.emptyContent == EmptyContentString {
+		aux.Content = new(string)
+	}
+	return json.Marshal(aux)
+}
+
+// UnmarshalJSON accepts thinking either as a string or as the
+// {"content": ..., "signature": ...} object the reasoning transforms put on
+// response messages. The signature is kept for the response's thinking block.
+func (m *OMessage) UnmarshalJSON(b []byte) error {
+	type plain OMessage
+	aux := struct {
+		*plain
+		Content  json.RawMessage `json:"content"`
+		Thinking json.RawMessage `json:"thinking"`
+	}{plain: (*plain)(m)}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	if err := m.unmarshalContent(aux.Content); err != nil {
+		return err
+	}
+	m.Thinking, m.signature = "", ""
+	if len(aux.Thinking) == 0 || string(aux.Thinking) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(aux.Thinking, &m.Thinking); err == nil {
+		return nil
+	}
+	var obj struct {
+		Content   string `json:"content"`
+		Signature string `json:"signature"`
+	}
+	if err := json.Unmarshal(aux.Thinking, &obj); err != nil {
+		return fmt.Errorf("message thinking: %w", err)
+	}
+	m.Thinking, m.signature = obj.Content, obj.Signature
+	return nil
+}
+
+// unmarshalContent reads content given as a string or as parts: text parts
+// are joined into Content and the rest kept in Parts.
+func (m *OMessage) unmarshalContent(raw json.RawMessage) error {
+	m.Content, m.Parts = "", nil
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(raw, &m.Content); err == nil {
+		return nil
+	}
+	var parts []OContentPart
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return fmt.Errorf("message content: %w", err)
+	}
+	var texts []string
+	for _, p := range parts {
+		if p.Type == "text" {
+			texts = append(texts, p.Text)
+		} else {
+			m.Parts = append(m.Parts, p)
+		}
+	}
+	m.Content = strings.Join(texts, "\n")
+	return nil
+}
+
+// OToolCall is an OpenAI tool call in an assistant message.
+type OToolCall struct {
+	ID       string        `json:"id"`
+	Type     string        `json:"type"`
+	Function OFunctionCall `json:"function"`
+}
+
+// OFunctionCall holds function name and arguments (JSON string).
+type OFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// OTool is an OpenAI tool definition.
+type OTool struct {
+	Type     string    `json:"type"`
+	Function OFunction `json:"function"`
+}
+
+// OFunction is an OpenAI function definition.
+type OFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+// DefaultToolErrorPrefix starts the content of a tool result the client
+// marked is_error. OpenAI tool messages have no error flag, so without it a
+// model can't tell a failed command from a successful one.
+const DefaultToolErrorPrefix = "[tool error] "
+
+// How an assistant message that only calls tools carries its content.
+// Providers disagree: Mistral and some vLLM chat templates reject "", others
+// reject a missing field or null.
+const (
+	EmptyContentOmit   = "omit"  // no content field
+	EmptyContentNull   = "null"  // "content": null
+	EmptyContentString = "empty" // "content": ""
+)
+
+// RequestOptions controls RequestToOpenAIOptions.
+type RequestOptions struct {
+	Model        string // backend model name
+	MaxTokensCap int    // cap on max_tokens (0 = none)
+	// ToolErrorPrefix starts the content of each tool_result marked
+	// is_error ("" = none).
+	ToolErrorPrefix string
+	// EmptyContent is one of the EmptyContent constants ("" = omit).
+	EmptyContent string
+	// TopK passes top_k on. OpenAI rejects it, but Ollama, vLLM and
+	// llama.cpp accept it; it is dropped when false.
+	TopK bool
+	// Signer, when set, checks the signatures of earlier turns' thinking:
+	// blocks it did not sign are not sent to the model.
+	Signer *ThinkingSigner
+	// Documents is one of the Documents constants ("" = DocumentsText).
+	Documents string
+}
+
+// RequestToOpenAI translates an Anthropic Messages request body to OpenAI Chat Completions format.
+// Schema cleaning is handled separately by the transform chain.
+func RequestToOpenAI(body []byte, backendModel string, maxTokensCap int) ([]byte, error) {
+	return RequestToOpenAIOptions(body, RequestOptions{
+		Model:           backendModel,
+		MaxTokensCap:    maxTokensCap,
+		ToolErrorPrefix: DefaultToolErrorPrefix,
+	})
+}
+
+// RequestToOpenAIOptions is RequestToOpenAI with every option spelled out.
+func RequestToOpenAIOptions(body []byte, opts RequestOptions) ([]byte, error) {
+	var req AnthropicRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("parse anthropic request: %w", err)
+	}
+
+	maxTokens := req.MaxTokens
+	if opts.MaxTokensCap > 0 && maxTokens > opts.MaxTokensCap {
+		maxTokens = opts.MaxTokensCap
+	}
+
+	oReq := ORequest{
+		Model:       opts.Model,
+		MaxTokens:   maxTokens,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		Stop:        req.StopSequences,
+		Stream:      req.Stream,
+	}
+	if req.Metadata != nil {
+		oReq.User = req.Metadata.UserID
+	}
+	if opts.TopK {
+		oReq.TopK = req.TopK
+	}
+
+	// System prompt
+	systemText := extractSystemText(req.System)
+	if systemText != "" {
+		oReq.Messages = append(oReq.Messages, OMessage{Role: "system", Content: systemText})
+	}
+
+	// Messages
+	for _, msg := range req.Messages {
+		oMsgs, err := translateMessage(msg, opts)
+		if err != nil {
+			return nil, err
+		}
+		oReq.Messages = append(oReq.Messages, oMsgs...)
+	}
+
+	// Tools
+	for _, tool := range req.Tools {
+		oReq.Tools = append(oReq.Tools, OTool{
+			Type: "function",
+			Function: OFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.InputSchema,
+			},
+		})
+	}
+
+	// Tool choice
+	if len(req.ToolChoice) > 0 {
+		oReq.ToolChoice = translateToolChoice(req.ToolChoice)
+	}
+
+	if oReq.Stream {
+		// Request stream options to get usage in streaming
+		return marshalWithStreamOptions(oReq)
+	}
+
+	return json.Marshal(oReq)
+}
+
+// marshalWithStreamOptions adds stream_options to get usage data in streaming responses.
+func marshalWithStreamOptions(req ORequest) ([]byte, error) {
+	// Marshal normally then add stream_options
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	json.Unmarshal(data, &m)
+	m["stream_options"] = map[string]bool{"include_usage": true}
+	return json.Marshal(m)
+}
+
+func extractSystemText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+
+	// Try string first
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+
+	// Try array of content blocks
+	var blocks []ContentBlock
+	if json.Unmarshal(raw, &blocks) == nil {
+		var parts []string
+		for _, b := range blocks {
+			if b.Type == "text" && b.Text != "" {
+				parts = append(parts, b.Text)
+			}
+		}
+		return strings.Join(parts, "\n")
+	}
+
+	return ""
+}
+
+func translateMessage(msg AMessage, opts RequestOptions) ([]OMessage, error) {
+	// Content can be a string or array of content blocks
+	var contentStr string
+	if json.Unmarshal(msg.Content, &contentStr) == nil {
+		return []OMessage{{Role: msg.Role, Content: contentStr}}, nil
+	}
+
+	var blocks []ContentBlock
+	if err := json.Unmarshal(msg.Content, &blocks); err != nil {
+		return nil, fmt.Errorf("parse message content: %w", err)
+	}
+
+	if msg.Role == "assistant" {
+		return translateAssistantBlocks(blocks, opts)
+	}
+
+	// User message: may contain text + tool_result blocks
+	return translateUserBlocks(blocks, opts)
+}
+
+func translateAssistantBlocks(blocks []ContentBlock, opts RequestOptions) ([]OMessage, error) {
+	msg := OMessage{Role: "assistant"}
+	var textParts, thinkingParts []string
+
+	for _, b := range blocks {
+		switch b.Type {
+		case "text":
+			if b.Text != "" {
+				textParts = append(textParts, b.Text)
+			}
+		case "thinking":
+			// Interleaved thinking gives one block per step; keep them all,
+			// unless opts.Signer did not sign them.
+			// redacted_thinking is encrypted for Anthropic and is dropped.
+			if b.Thinking != "" && opts.Signer.Verify(b.Thinking, b.Signature) {
+				thinkingParts = append(thinkingParts, b.Thinking)
+			}
+		case "tool_use":
+			args := string(b.Input)
+			if args == "" || args == "null" {
+				args = "{}"
+			}
+			msg.ToolCalls = append(msg.ToolCalls, OToolCall{
+				ID:   b.ID,
+				Type: "function",
+				Function: OFunctionCall{
+					Name:      b.Name,
+					Arguments: args,
+				},
+			})
+		}
+	}
+
+	msg.Content = strings.Join(textParts, "\n")
+	msg.Thinking = strings.Join(thinkingParts, "\n")
+	if len(msg.ToolCalls) > 0 {
+		msg.emptyContent = opts.EmptyContent
+	}
+	return []OMessage{msg}, nil
+}
+
+// translateUserBlocks emits one tool message per tool_result, then the
+// user's text. Tool messages go first whatever the block order, since OpenAI
+// providers reject a user message between an assistant's tool calls and
+// their results.
+func translateUserBlocks(blocks []ContentBlock, opts RequestOptions) ([]OMessage, error) {
+	var msgs []OMessage
+	var parts []OContentPart // text and documents, in order
+	files := false
+
+	for _, b := range blocks {
+		switch b.Type {
+		case "text":
+			parts = append(parts, OContentPart{Type: "text", Text: b.Text})
+		case "document":
+			part, err := translateDocument(b, opts)
+			if err != nil {
+				return nil, err
+			}
+			files = files || part.Type != "text"
+			parts = append(parts, part)
+		case "tool_result":
+			content, err := extractToolResultContent(b, opts)
+			if err != nil {
+				return nil, err
+			}
+			if b.IsError {
+				content = markToolError(content, opts.ToolErrorPrefix)
+			}
+			msgs = append(msgs, OMessage{
+				Role:       "tool",
+				ToolCallID: b.ToolUseID,
+				Content:    content,
+			})
+		}
+	}
+
+	switch {
+	case files:
+		msgs = append(msgs, OMessage{Role: "user", Parts: parts})
+	case len(parts) > 0:
+		texts := make([]string, len(parts))
+		for i, p := range parts {
+			texts[i] = p.Text
+		}
+		msgs = append(msgs, OMessage{Role: "user", Content: strings.Join(texts, "\n")})
+	}
+
+	return msgs, nil
+}
+
+// markToolError starts an errored tool result's content with prefix, unless
+// it already does.
+func markToolError(content, prefix string) string {
+	switch {
+	case prefix == "" || strings.HasPrefix(content, prefix):
+		return content
+	case content == "":
+		return strings.TrimSpace(prefix)
+	}
+	return prefix + content
+}
+
+// extractToolResultContent returns a tool result's text. Documents in it
+// (Claude Code's Read tool returns PDFs as document blocks) are sent as
+// their text, since tool messages carry no files, unless opts rejects them.
+func extractToolResultContent(b ContentBlock, opts RequestOptions) (string, error) {
+	if len(b.Content) == 0 {
+		return "", nil
+	}
+
+	// Try string
+	var s string
+	if json.Unmarshal(b.Content, &s) == nil {
+		return s, nil
+	}
+
+	// Try array of content blocks
+	var blocks []ContentBlock
+	if json.Unmarshal(b.Content, &blocks) == nil {
+		var parts []string
+		for _, cb := range blocks {
+			switch cb.Type {
+			case "text":
+				parts = append(parts, cb.Text)
+			case "document":
+				if opts.Documents == DocumentsFile {
+					opts.Documents = DocumentsText
+				}
+				part, err := translateDocument(cb, opts)
+				if err != nil {
+					return "", err
+				}
+				parts = append(parts, part.Text)
+			}
+		}
+		return strings.Join(parts, "\n"), nil
+	}
+
+	return string(b.Content), nil
+}
+
+func translateToolChoice(raw json.RawMessage) interface{} {
+	var tc struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+	}
+	if json.Unmarshal(raw, &tc) != nil {
+		return nil
+	}
+
+	switch tc.Type {
+	case "auto":
+		return "auto"
+	case "any":
+		return "required"
+	case "tool":
+		return map[string]interface{}{
+			"type":     "function",
+			"function": map[string]string{"name": tc.Name},
+		}
+	default:
+		return "auto"
+	}
+}
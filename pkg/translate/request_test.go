@@ -2,6 +2,7 @@ package translate
 
 import (
 	"encoding/json"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -275,6 +276,134 @@ func TestRequestMultipleToolResults(t *testing.T) {
 	}
 }
 
+func TestRequestMetadataUser(t *testing.T) {
+	out, err := RequestToOpenAI([]byte(`{"model":"x","messages":[{"role":"user","content":"hi"}],"metadata":{"user_id":"user_abc_session_1"}}`), "m", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req ORequest
+	json.Unmarshal(out, &req)
+	if req.User != "user_abc_session_1" {
+		t.Errorf("user = %q, want metadata.user_id", req.User)
+	}
+
+	out, _ = RequestToOpenAI([]byte(`{"model":"x","messages":[{"role":"user","content":"hi"}]}`), "m", 0)
+	if strings.Contains(string(out), `"user":`) {
+		t.Errorf("user sent without metadata: %s", out)
+	}
+}
+
+func TestRequestTopK(t *testing.T) {
+	input := []byte(`{"model":"x","messages":[{"role":"user","content":"hi"}],"top_k":40}`)
+	out, err := RequestToOpenAI(input, "m", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "top_k") {
+		t.Errorf("top_k sent by default: %s", out)
+	}
+	out, err = RequestToOpenAIOptions(input, RequestOptions{Model: "m", TopK: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req ORequest
+	json.Unmarshal(out, &req)
+	if req.TopK == nil || *req.TopK != 40 {
+		t.Errorf("top_k = %v, want 40", req.TopK)
+	}
+}
+
+func TestRequestToolResultErrors(t *testing.T) {
+	input := `{
+		"model": "x",
+		"messages": [
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "t1", "content": "exit status 1", "is_error": true},
+				{"type": "tool_result", "tool_use_id": "t2", "content": "ok"},
+				{"type": "tool_result", "tool_use_id": "t3", "is_error": true},
+				{"type": "tool_result", "tool_use_id": "t4", "content": "[tool error] already marked", "is_error": true}
+			]}
+		]
+	}`
+	contents := func(out []byte) []string {
+		var req ORequest
+		if err := json.Unmarshal(out, &req); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, m := range req.Messages {
+			got = append(got, m.Content)
+		}
+		return got
+	}
+
+	out, err := RequestToOpenAI([]byte(input), "model", 0)
+	if err != nil {
+		t.Fatalf("RequestToOpenAI: %v", err)
+	}
+	want := []string{"[tool error] exit status 1", "ok", "[tool error]", "[tool error] already marked"}
+	if got := contents(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("default prefix: got %q, want %q", got, want)
+	}
+
+	out, err = RequestToOpenAIOptions([]byte(input), RequestOptions{Model: "model"})
+	if err != nil {
+		t.Fatalf("RequestToOpenAIOptions: %v", err)
+	}
+	want = []string{"exit status 1", "ok", "", "[tool error] already marked"}
+	if got := contents(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("no prefix: got %q, want %q", got, want)
+	}
+}
+
+func TestRequestEmptyAssistantContent(t *testing.T) {
+	input := `{
+		"model": "x",
+		"messages": [
+			{"role": "user", "content": "list files"},
+			{"role": "assistant", "content": [
+				{"type": "tool_use", "id": "t1", "name": "ls", "input": {}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "t1", "content": "a.go"}
+			]},
+			{"role": "assistant", "content": [
+				{"type": "text", "text": "Running it."},
+				{"type": "tool_use", "id": "t2", "name": "ls", "input": {}}
+			]}
+		]
+	}`
+	for _, tc := range []struct {
+		mode string
+		want string // raw content of the tool-only assistant message
+	}{
+		{"", ""},
+		{EmptyContentOmit, ""},
+		{EmptyContentNull, "null"},
+		{EmptyContentString, `""`},
+	} {
+		out, err := RequestToOpenAIOptions([]byte(input), RequestOptions{Model: "m", EmptyContent: tc.mode})
+		if err != nil {
+			t.Fatalf("%q: %v", tc.mode, err)
+		}
+		var req struct {
+			Messages []map[string]json.RawMessage `json:"messages"`
+		}
+		if err := json.Unmarshal(out, &req); err != nil {
+			t.Fatal(err)
+		}
+		if got := string(req.Messages[1]["content"]); got != tc.want {
+			t.Errorf("%q: tool-only content = %s, want %s", tc.mode, got, tc.want)
+		}
+		if got := string(req.Messages[3]["content"]); got != `"Running it."` {
+			t.Errorf("%q: text content = %s", tc.mode, got)
+		}
+		if _, ok := req.Messages[1]["tool_calls"]; !ok {
+			t.Errorf("%q: tool_calls lost: %s", tc.mode, out)
+		}
+	}
+}
+
 func TestRequestToolSchemaStripping(t *testing.T) {
 	// Schema stripping is now handled by the transform chain, not RequestToOpenAI.
 	// This test verifies that RequestToOpenAI + schema:generic chain strips schemas correctly.
@@ -382,3 +511,33 @@ func TestRequestToolSchemaArrayItems(t *testing.T) {
 		t.Error("additionalProperties not stripped from nested array items")
 	}
 }
+
+func TestRequestThinkingBlocks(t *testing.T) {
+	input := `{
+		"model": "x",
+		"messages": [
+			{"role": "user", "content": "List files"},
+			{"role": "assistant", "content": [
+				{"type": "thinking", "thinking": "Need ls.", "signature": "sig1"},
+				{"type": "redacted_thinking", "data": "EqQBCkgIARABGAIiQL..."},
+				{"type": "tool_use", "id": "t1", "name": "Bash", "input": {"command": "ls"}},
+				{"type": "thinking", "thinking": "Then summarize.", "signature": "sig2"}
+			]}
+		]
+	}`
+
+	out, err := RequestToOpenAI([]byte(input), "model", 0)
+	if err != nil {
+		t.Fatalf("RequestToOpenAI: %v", err)
+	}
+	var req ORequest
+	json.Unmarshal(out, &req)
+
+	asst := req.Messages[1]
+	if asst.Thinking != "Need ls.\nThen summarize." {
+		t.Errorf("thinking = %q", asst.Thinking)
+	}
+	if strings.Contains(string(out), "EqQB") {
+		t.Errorf("redacted thinking leaked into request: %s", out)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 )
 
 // OpenAI response types
@@ -13,13 +14,20 @@ import (
 // OResponse is an OpenAI Chat Completion response.
 type OResponse struct {
 	ID      string    `json:"id"`
+	Object  string    `json:"object,omitempty"`
+	Created int64     `json:"created,omitempty"`
 	Choices []OChoice `json:"choices"`
 	Usage   *OUsage   `json:"usage,omitempty"`
 	Model   string    `json:"model"`
+
+	// Perplexity's sources: cited URLs, and search results with titles
+	Citations     []string        `json:"citations,omitempty"`
+	SearchResults []OSearchResult `json:"search_results,omitempty"`
 }
 
 // OChoice is a choice in an OpenAI response.
 type OChoice struct {
+	Index        int      `json:"index"`
 	Message      OMessage `json:"message"`
 	FinishReason string   `json:"finish_reason"`
 }
@@ -47,11 +55,14 @@ type AResponse struct {
 
 // AResponseBlock is a content block in an Anthropic response.
 type AResponseBlock struct {
-	Type  string          `json:"type"`
-	Text  string          `json:"text,omitempty"`
-	ID    string          `json:"id,omitempty"`
-	Name  string          `json:"name,omitempty"`
-	Input json.RawMessage `json:"input,omitempty"`
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	Thinking  string          `json:"thinking,omitempty"`
+	Signature string          `json:"signature,omitempty"`
+	Citations []ACitation     `json:"citations,omitempty"`
 }
 
 // AUsage is token usage in Anthropic format.
@@ -75,13 +86,37 @@ type AError struct {
 // toolIDClean removes characters not allowed in Anthropic tool IDs.
 var toolIDClean = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
 
-func sanitizeToolID(id string) string {
-	return toolIDClean.ReplaceAllString(id, "_")
+// sanitizeToolID cleans id for Anthropic, recording a changed ID in ids.
+func sanitizeToolID(id string, ids *ToolIDMap) string {
+	clean := toolIDClean.ReplaceAllString(id, "_")
+	ids.Record(clean, id)
+	return clean
 }
 
 // ResponseToAnthropic translates an OpenAI Chat Completion response to Anthropic Messages format.
 // modelLabel is the user-facing label (not the backend model name).
 func ResponseToAnthropic(body []byte, modelLabel string) ([]byte, error) {
+	return ResponseToAnthropicIDs(body, modelLabel, nil)
+}
+
+// ResponseToAnthropicIDs is ResponseToAnthropic, recording in ids (if not
+// nil) the provider's ID behind each tool_use ID it had to clean.
+func ResponseToAnthropicIDs(body []byte, modelLabel string, ids *ToolIDMap) ([]byte, error) {
+	return ResponseToAnthropicContext(body, modelLabel, &TransformContext{ToolIDs: ids})
+}
+
+// ResponseToAnthropicContext is ResponseToAnthropic using ctx's ToolIDs,
+// FinishReasons, Signer, ReasoningDisplay and Citations. ctx may be nil.
+func ResponseToAnthropicContext(body []byte, modelLabel string, ctx *TransformContext) ([]byte, error) {
+	var ids *ToolIDMap
+	var finishReasons map[string]string
+	var signer *ThinkingSigner
+	var display ReasoningDisplay
+	var citations string
+	if ctx != nil {
+		ids, finishReasons, signer = ctx.ToolIDs, ctx.FinishReasons, ctx.Signer
+		display, citations = ctx.ReasoningDisplay, ctx.Citations
+	}
 	var oResp OResponse
 	if err := json.Unmarshal(body, &oResp); err != nil {
 		return nil, fmt.Errorf("parse openai response: %w", err)
@@ -101,12 +136,34 @@ func ResponseToAnthropic(body []byte, modelLabel string) ([]byte, error) {
 		Model: modelLabel,
 	}
 
-	// Build content blocks
-	if msg.Content != "" {
+	// Build content blocks: thinking first, signed as the stream signs it
+	if thinking := display.apply(msg.Thinking); thinking != "" {
+		sig := msg.signature
+		if signer != nil || sig == "" || thinking != msg.Thinking {
+			sig = signer.Sign(thinking)
+		}
 		aResp.Content = append(aResp.Content, AResponseBlock{
+			Type:      "thinking",
+			Thinking:  thinking,
+			Signature: sig,
+		})
+	}
+	if msg.Content != "" {
+		block := AResponseBlock{
 			Type: "text",
 			Text: msg.Content,
-		})
+		}
+		var sources citationSources
+		sources.addAnnotations(msg.Annotations, msg.Content)
+		sources.addPerplexity(oResp.Citations, oResp.SearchResults)
+		switch citations {
+		case CitationsDrop:
+		case CitationsBlocks:
+			block.Citations = sources.list
+		default:
+			block.Text += sources.references()
+		}
+		aResp.Content = append(aResp.Content, block)
 	}
 
 	for _, tc := range msg.ToolCalls {
@@ -124,14 +181,14 @@ func ResponseToAnthropic(body []byte, modelLabel string) ([]byte, error) {
 
 		aResp.Content = append(aResp.Content, AResponseBlock{
 			Type:  "tool_use",
-			ID:    sanitizeToolID(tc.ID),
+			ID:    sanitizeToolID(tc.ID, ids),
 			Name:  tc.Function.Name,
 			Input: input,
 		})
 	}
 
 	// Stop reason
-	stopReason := mapFinishReason(choice.FinishReason)
+	stopReason := mapFinishReason(choice.FinishReason, finishReasons)
 	aResp.StopReason = &stopReason
 
 	// Usage
@@ -145,17 +202,44 @@ func ResponseToAnthropic(body []byte, modelLabel string) ([]byte, error) {
 	return json.Marshal(aResp)
 }
 
-func mapFinishReason(fr string) string {
-	switch fr {
-	case "stop":
-		return "end_turn"
-	case "tool_calls":
-		return "tool_use"
-	case "length":
-		return "max_tokens"
-	default:
-		return "end_turn"
+// thinkingSignature returns a placeholder signature for a thinking block.
+// Local models don't sign their reasoning, but Claude Code expects the
+// field; the timestamp form matches the reasoning transforms' close chunks.
+func thinkingSignature() string {
+	return fmt.Sprintf("<%d>", time.Now().UnixMilli())
+}
+
+// finishReasons maps OpenAI finish reasons, and the variants providers send
+// instead, to Anthropic stop reasons.
+var finishReasons = map[string]string{
+	"stop":           "end_turn",
+	"eos":            "end_turn", // llama.cpp, TGI
+	"eos_token":      "end_turn", // TGI
+	"end_turn":       "end_turn",
+	"tool_calls":     "tool_use",
+	"function_call":  "tool_use", // legacy OpenAI functions
+	"tool_use":       "tool_use",
+	"length":         "max_tokens",
+	"max_tokens":     "max_tokens", // Gemini-style, some gateways
+	"model_length":   "max_tokens", // Mistral: context window full
+	"content_filter": "refusal",
+	"safety":         "refusal", // Gemini
+	"recitation":     "refusal", // Gemini
+	"stop_sequence":  "stop_sequence",
+}
+
+// mapFinishReason returns the Anthropic stop reason for a provider's
+// finish reason. overrides (from the provider's finish_reasons config) are
+// consulted first; finish reasons are matched case-insensitively, and
+// unknown ones end the turn.
+func mapFinishReason(fr string, overrides map[string]string) string {
+	if sr, ok := overrides[fr]; ok {
+		return sr
+	}
+	if sr, ok := finishReasons[strings.ToLower(fr)]; ok {
+		return sr
 	}
+	return "end_turn"
 }
 
 // ClassifyError categorizes an error for logging and user-facing messages.
@@ -1,6 +1,7 @@
 package translate
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -146,15 +147,53 @@ func TestResponseFinishReasonMapping(t *testing.T) {
 		{"stop", "end_turn"},
 		{"tool_calls", "tool_use"},
 		{"length", "max_tokens"},
+		{"content_filter", "refusal"},
+		{"function_call", "tool_use"},
+		{"eos", "end_turn"},
+		{"MAX_TOKENS", "max_tokens"},
+		{"model_length", "max_tokens"},
 		{"unknown", "end_turn"},
+		{"", "end_turn"},
 	}
 
 	for _, tt := range tests {
-		got := mapFinishReason(tt.openai)
+		got := mapFinishReason(tt.openai, nil)
 		if got != tt.expected {
 			t.Errorf("mapFinishReason(%q) = %q, want %q", tt.openai, got, tt.expected)
 		}
 	}
+
+	overrides := map[string]string{"abort": "max_tokens", "stop": "stop_sequence"}
+	if got := mapFinishReason("abort", overrides); got != "max_tokens" {
+		t.Errorf("override for abort: got %q", got)
+	}
+	if got := mapFinishReason("stop", overrides); got != "stop_sequence" {
+		t.Errorf("override for stop: got %q", got)
+	}
+}
+
+func TestResponseFinishReasonOverrides(t *testing.T) {
+	body := []byte(`{"id":"r","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"abort"}]}`)
+	ctx := NewTransformContext("m", "p")
+	ctx.FinishReasons = map[string]string{"abort": "max_tokens"}
+	out, err := ResponseToAnthropicContext(body, "m", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp AResponse
+	json.Unmarshal(out, &resp)
+	if resp.StopReason == nil || *resp.StopReason != "max_tokens" {
+		t.Errorf("stop_reason = %v, want max_tokens", resp.StopReason)
+	}
+
+	input := makeSSE(chunk("r", strPtr("hi"), strPtr("abort")))
+	var buf bytes.Buffer
+	st := NewStreamTranslator("m")
+	st.SetTransformChain(NewTransformChain(), ctx)
+	st.TranslateStream(strings.NewReader(input), &buf)
+	if st.StopReason() != "max_tokens" || !strings.Contains(buf.String(), `"stop_reason":"max_tokens"`) {
+		t.Errorf("stream stop reason = %q:\n%s", st.StopReason(), buf.String())
+	}
 }
 
 func TestResponseNoChoices(t *testing.T) {
@@ -214,3 +253,84 @@ func TestFormatError(t *testing.T) {
 		t.Errorf("unexpected message: %s", resp.Error.Message)
 	}
 }
+
+func TestResponseThinkingBlock(t *testing.T) {
+	body := `{"id":"r1","choices":[{"index":0,"message":{"role":"assistant","content":"Answer","thinking":{"content":"Reasoning"}},"finish_reason":"stop"}]}`
+	out, err := ResponseToAnthropic([]byte(body), "m")
+	if err != nil {
+		t.Fatalf("ResponseToAnthropic: %v", err)
+	}
+	var resp AResponse
+	json.Unmarshal(out, &resp)
+	if len(resp.Content) != 2 {
+		t.Fatalf("expected thinking + text, got %+v", resp.Content)
+	}
+	if resp.Content[0].Type != "thinking" || resp.Content[0].Thinking != "Reasoning" || resp.Content[0].Signature == "" {
+		t.Errorf("unexpected thinking block: %+v", resp.Content[0])
+	}
+	if resp.Content[1].Type != "text" || resp.Content[1].Text != "Answer" {
+		t.Errorf("unexpected text block: %+v", resp.Content[1])
+	}
+}
+
+func TestResponseThinkingFromTransforms(t *testing.T) {
+	tests := []struct {
+		name  string
+		chain []string
+		msg   string
+	}{
+		{"reasoning", []string{"reasoning"}, `{"role":"assistant","reasoning_content":"Plan it.","content":"Done."}`},
+		{"openrouter", []string{"openrouter", "reasoning"}, `{"role":"assistant","reasoning":"Plan it.","content":"Done."}`},
+		{"extrathinktag", []string{"extrathinktag"}, `{"role":"assistant","content":"<think>Plan it.</think>Done."}`},
+		{"forcereasoning", []string{"forcereasoning"}, `{"role":"assistant","content":"<reasoning_content>Plan it.</reasoning_content>Done."}`},
+		{"enhancetool", []string{"deepseek", "reasoning", "enhancetool"}, `{"role":"assistant","reasoning_content":"Plan it.","content":"Done."}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			chain, err := BuildChain(tc.chain)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body := `{"id":"r1","choices":[{"index":0,"message":` + tc.msg + `,"finish_reason":"stop"}]}`
+			ctx := NewTransformContext("m", "p")
+			transformed, err := chain.RunResponse([]byte(body), ctx)
+			if err != nil {
+				t.Fatalf("RunResponse: %v", err)
+			}
+			out, err := ResponseToAnthropicContext(transformed, "m", ctx)
+			if err != nil {
+				t.Fatalf("ResponseToAnthropicContext: %v", err)
+			}
+			var resp AResponse
+			json.Unmarshal(out, &resp)
+			if len(resp.Content) != 2 {
+				t.Fatalf("expected thinking + text, got %+v", resp.Content)
+			}
+			th := resp.Content[0]
+			if th.Type != "thinking" || th.Thinking != "Plan it." || th.Signature == "" {
+				t.Errorf("unexpected thinking block: %+v", th)
+			}
+			if resp.Content[1].Type != "text" || resp.Content[1].Text != "Done." {
+				t.Errorf("unexpected text block: %+v", resp.Content[1])
+			}
+		})
+	}
+}
+
+func TestResponseThinkingSignature(t *testing.T) {
+	body := `{"id":"r1","choices":[{"index":0,"message":{"role":"assistant","content":null,` +
+		`"thinking":{"content":"Need the file.","signature":"sig-1"},` +
+		`"tool_calls":[{"id":"call_1","type":"function","function":{"name":"Read","arguments":"{}"}}]},"finish_reason":"tool_calls"}]}`
+	out, err := ResponseToAnthropic([]byte(body), "m")
+	if err != nil {
+		t.Fatalf("ResponseToAnthropic: %v", err)
+	}
+	var resp AResponse
+	json.Unmarshal(out, &resp)
+	if len(resp.Content) != 2 || resp.Content[0].Type != "thinking" || resp.Content[1].Type != "tool_use" {
+		t.Fatalf("expected thinking then tool_use, got %+v", resp.Content)
+	}
+	if resp.Content[0].Signature != "sig-1" {
+		t.Errorf("signature = %q, want the transform's", resp.Content[0].Signature)
+	}
+}
@@ -0,0 +1,550 @@
+package translate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Reverse direction: OpenAI Chat Completions clients talking to an
+// Anthropic Messages backend.
+
+// defaultReverseMaxTokens is used when an OpenAI request omits max_tokens,
+// which the Messages API requires.
+const defaultReverseMaxTokens = 4096
+
+// oInRequest is an incoming OpenAI Chat Completions request. Content, stop
+// and tool_choice take several shapes and are decoded lazily.
+type oInRequest struct {
+	Messages            []oInMessage    `json:"messages"`
+	MaxTokens           int             `json:"max_tokens,omitempty"`
+	MaxCompletionTokens int             `json:"max_completion_tokens,omitempty"`
+	Temperature         *float64        `json:"temperature,omitempty"`
+	TopP                *float64        `json:"top_p,omitempty"`
+	TopK                *int            `json:"top_k,omitempty"` // vLLM, Ollama and llama.cpp extension
+	Stop                json.RawMessage `json:"stop,omitempty"`  // string or []string
+	Stream              bool            `json:"stream,omitempty"`
+	Tools               []OTool         `json:"tools,omitempty"`
+	ToolChoice          json.RawMessage `json:"tool_choice,omitempty"`
+	User                string          `json:"user,omitempty"`
+}
+
+type oInMessage struct {
+	Role       string          `json:"role"`
+	Content    json.RawMessage `json:"content"` // string, null, or content parts
+	ToolCalls  []OToolCall     `json:"tool_calls,omitempty"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
+}
+
+// OpenAIToAnthropic translates an OpenAI Chat Completions request body to an
+// Anthropic Messages request for backendModel. System and developer messages
+// become the system prompt; tool results become tool_result blocks; and
+// consecutive messages of the same role are merged, since the Messages API
+// requires user/assistant alternation.
+func OpenAIToAnthropic(body []byte, backendModel string, maxTokensCap int) ([]byte, error) {
+	var req oInRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("parse openai request: %w", err)
+	}
+
+	maxTokens := req.MaxCompletionTokens
+	if maxTokens == 0 {
+		maxTokens = req.MaxTokens
+	}
+	if maxTokens == 0 {
+		maxTokens = defaultReverseMaxTokens
+	}
+	if maxTokensCap > 0 && maxTokens > maxTokensCap {
+		maxTokens = maxTokensCap
+	}
+
+	aReq := AnthropicRequest{
+		Model:         backendModel,
+		MaxTokens:     maxTokens,
+		Temperature:   req.Temperature,
+		TopP:          req.TopP,
+		TopK:          req.TopK,
+		StopSequences: decodeStop(req.Stop),
+		Stream:        req.Stream,
+	}
+	if req.User != "" {
+		aReq.Metadata = &AMetadata{UserID: req.User}
+	}
+
+	var systemParts []string
+	var msgs []AMessage
+	var pending []ContentBlock
+	pendingRole := ""
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		content, err := json.Marshal(pending)
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, AMessage{Role: pendingRole, Content: content})
+		pending = nil
+		return nil
+	}
+	appendBlocks := func(role string, blocks ...ContentBlock) error {
+		if role != pendingRole {
+			if err := flush(); err != nil {
+				return err
+			}
+			pendingRole = role
+		}
+		pending = append(pending, blocks...)
+		return nil
+	}
+
+	for _, m := range req.Messages {
+		var text string
+		var err error
+		if m.Role != "user" {
+			if text, err = openAIContentText(m.Content); err != nil {
+				return nil, fmt.Errorf("%s message: %w", m.Role, err)
+			}
+		}
+		switch m.Role {
+		case "system", "developer":
+			if text != "" {
+				systemParts = append(systemParts, text)
+			}
+		case "user":
+			blocks, berr := openAIContentBlocks(m.Content)
+			if berr != nil {
+				return nil, fmt.Errorf("user message: %w", berr)
+			}
+			if len(blocks) > 0 {
+				err = appendBlocks("user", blocks...)
+			}
+		case "assistant":
+			var blocks []ContentBlock
+			if text != "" {
+				blocks = append(blocks, ContentBlock{Type: "text", Text: text})
+			}
+			for _, tc := range m.ToolCalls {
+				input := json.RawMessage(tc.Function.Arguments)
+				if !json.Valid(input) {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, ContentBlock{Type: "tool_use", ID: tc.ID, Name: tc.Function.Name, Input: input})
+			}
+			if len(blocks) > 0 {
+				err = appendBlocks("assistant", blocks...)
+			}
+		case "tool":
+			content, _ := json.Marshal(text)
+			err = appendBlocks("user", ContentBlock{Type: "tool_result", ToolUseID: m.ToolCallID, Content: content})
+		default:
+			return nil, fmt.Errorf("unsupported message role %q", m.Role)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	aReq.Messages = msgs
+
+	if len(systemParts) > 0 {
+		aReq.System, _ = json.Marshal(strings.Join(systemParts, "\n"))
+	}
+
+	for _, tool := range req.Tools {
+		schema := tool.Function.Parameters
+		if len(schema) == 0 || string(schema) == "null" {
+			schema = json.RawMessage(`{"type":"object","properties":{}}`)
+		}
+		aReq.Tools = append(aReq.Tools, ATool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: schema,
+		})
+	}
+
+	if len(req.ToolChoice) > 0 {
+		tc, drop := reverseToolChoice(req.ToolChoice)
+		if drop {
+			aReq.Tools = nil
+		} else if tc != nil {
+			aReq.ToolChoice, _ = json.Marshal(tc)
+		}
+	}
+
+	return json.Marshal(aReq)
+}
+
+// oInContentPart is a part of an OpenAI message's array content.
+type oInContentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	ImageURL *struct {
+		URL string `json:"url"`
+	} `json:"image_url"`
+}
+
+// decodeOpenAIContent decodes OpenAI message content, a string or a list of
+// content parts, as parts.
+func decodeOpenAIContent(raw json.RawMessage) ([]oInContentPart, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return []oInContentPart{{Type: "text", Text: s}}, nil
+	}
+	var parts []oInContentPart
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return nil, fmt.Errorf("content: want a string or content parts: %w", err)
+	}
+	return parts, nil
+}
+
+// openAIContentText flattens OpenAI message content to text, for the roles
+// whose Anthropic counterparts only take text. Other parts are an error
+// rather than silently lost.
+func openAIContentText(raw json.RawMessage) (string, error) {
+	parts, err := decodeOpenAIContent(raw)
+	if err != nil {
+		return "", err
+	}
+	var texts []string
+	for _, p := range parts {
+		if p.Type != "text" {
+			return "", fmt.Errorf("unsupported content part %q (only text is supported here)", p.Type)
+		}
+		if p.Text != "" {
+			texts = append(texts, p.Text)
+		}
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// openAIContentBlocks translates user message content to Anthropic blocks:
+// runs of text parts join into one text block, and image_url parts become
+// image blocks. Other parts (audio, files) are an error.
+func openAIContentBlocks(raw json.RawMessage) ([]ContentBlock, error) {
+	parts, err := decodeOpenAIContent(raw)
+	if err != nil {
+		return nil, err
+	}
+	var blocks []ContentBlock
+	var texts []string
+	flushText := func() {
+		if len(texts) > 0 {
+			blocks = append(blocks, ContentBlock{Type: "text", Text: strings.Join(texts, "\n")})
+			texts = nil
+		}
+	}
+	for _, p := range parts {
+		switch p.Type {
+		case "text":
+			if p.Text != "" {
+				texts = append(texts, p.Text)
+			}
+		case "image_url":
+			if p.ImageURL == nil {
+				return nil, fmt.Errorf("image_url part has no url")
+			}
+			src, err := imageSource(p.ImageURL.URL)
+			if err != nil {
+				return nil, err
+			}
+			flushText()
+			blocks = append(blocks, ContentBlock{Type: "image", Source: src})
+		default:
+			return nil, fmt.Errorf("unsupported content part %q", p.Type)
+		}
+	}
+	flushText()
+	return blocks, nil
+}
+
+// imageSource turns an image_url URL into an Anthropic image source: a
+// base64 data URL keeps its data, an http(s) URL is passed on for the
+// backend to fetch.
+func imageSource(url string) (*ADocumentSource, error) {
+	if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") {
+		return &ADocumentSource{Type: "url", URL: url}, nil
+	}
+	rest, ok := strings.CutPrefix(url, "data:")
+	if !ok {
+		return nil, fmt.Errorf("image_url: want a data: or http(s) URL")
+	}
+	// data:[<media type>][;base64],<data>
+	meta, data, ok := strings.Cut(rest, ",")
+	mediaType, base64Data := strings.CutSuffix(meta, ";base64")
+	if !ok || !base64Data || !strings.HasPrefix(mediaType, "image/") {
+		return nil, fmt.Errorf("image_url: want a base64 image data URL (data:image/...;base64,...)")
+	}
+	return &ADocumentSource{Type: "base64", MediaType: mediaType, Data: data}, nil
+}
+
+func decodeStop(raw json.RawMessage) []string {
+	if len(raw) == 0 {
+		return nil
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		if s == "" {
+			return nil
+		}
+		return []string{s}
+	}
+	var list []string
+	json.Unmarshal(raw, &list)
+	return list
+}
+
+// reverseToolChoice maps an OpenAI tool_choice to Anthropic's. drop reports
+// "none", which Anthropic expresses by sending no tools.
+func reverseToolChoice(raw json.RawMessage) (tc map[string]string, drop bool) {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		switch s {
+		case "none":
+			return nil, true
+		case "required":
+			return map[string]string{"type": "any"}, false
+		default:
+			return map[string]string{"type": "auto"}, false
+		}
+	}
+	var obj struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if json.Unmarshal(raw, &obj) == nil && obj.Function.Name != "" {
+		return map[string]string{"type": "tool", "name": obj.Function.Name}, false
+	}
+	return nil, false
+}
+
+// ResponseToOpenAI translates an Anthropic Messages response to an OpenAI
+// Chat Completion. modelLabel is the user-facing label.
+func ResponseToOpenAI(body []byte, modelLabel string) ([]byte, error) {
+	var aResp AResponse
+	if err := json.Unmarshal(body, &aResp); err != nil {
+		return nil, fmt.Errorf("parse anthropic response: %w", err)
+	}
+	if aResp.Type != "message" {
+		return nil, fmt.Errorf("unexpected anthropic response type %q", aResp.Type)
+	}
+
+	msg := OMessage{Role: "assistant"}
+	var texts []string
+	for _, b := range aResp.Content {
+		switch b.Type {
+		case "text":
+			texts = append(texts, b.Text)
+		case "tool_use":
+			args := "{}"
+			var compact bytes.Buffer
+			if len(b.Input) > 0 && string(b.Input) != "null" && json.Compact(&compact, b.Input) == nil {
+				args = compact.String()
+			}
+			msg.ToolCalls = append(msg.ToolCalls, OToolCall{
+				ID:       b.ID,
+				Type:     "function",
+				Function: OFunctionCall{Name: b.Name, Arguments: args},
+			})
+		}
+	}
+	msg.Content = strings.Join(texts, "")
+
+	stopReason := ""
+	if aResp.StopReason != nil {
+		stopReason = *aResp.StopReason
+	}
+
+	oResp := OResponse{
+		ID:      "chatcmpl-" + strings.TrimPrefix(aResp.ID, "msg_"),
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   modelLabel,
+		Choices: []OChoice{{Message: msg, FinishReason: mapStopReason(stopReason)}},
+		Usage: &OUsage{
+			PromptTokens:     aResp.Usage.InputTokens,
+			CompletionTokens: aResp.Usage.OutputTokens,
+			TotalTokens:      aResp.Usage.InputTokens + aResp.Usage.OutputTokens,
+		},
+	}
+	return json.Marshal(oResp)
+}
+
+// mapStopReason is the inverse of mapFinishReason.
+func mapStopReason(sr string) string {
+	switch sr {
+	case "tool_use":
+		return "tool_calls"
+	case "max_tokens":
+		return "length"
+	default:
+		return "stop"
+	}
+}
+
+// ReverseStreamTranslator converts an Anthropic SSE stream to OpenAI
+// chat.completion.chunk events.
+type ReverseStreamTranslator struct {
+	modelLabel   string
+	id           string
+	created      int64
+	includeUsage bool
+	// Anthropic content block index → OpenAI tool_calls index
+	toolIndex map[int]int
+	usage     OUsage
+	stop      string
+}
+
+// NewReverseStreamTranslator creates a reverse streaming translator. When
+// includeUsage is set (stream_options.include_usage), a final usage-only
+// chunk is emitted before [DONE].
+func NewReverseStreamTranslator(modelLabel string, includeUsage bool) *ReverseStreamTranslator {
+	return &ReverseStreamTranslator{
+		modelLabel:   modelLabel,
+		id:           "chatcmpl-stream",
+		created:      time.Now().Unix(),
+		includeUsage: includeUsage,
+		toolIndex:    make(map[int]int),
+	}
+}
+
+// aStreamEvent covers the fields used from each Anthropic SSE event type.
+type aStreamEvent struct {
+	Type    string `json:"type"`
+	Index   int    `json:"index"`
+	Message *struct {
+		ID    string `json:"id"`
+		Usage AUsage `json:"usage"`
+	} `json:"message,omitempty"`
+	ContentBlock *AResponseBlock `json:"content_block,omitempty"`
+	Delta        *struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta,omitempty"`
+	Usage *AUsage `json:"usage,omitempty"`
+	Error *AError `json:"error,omitempty"`
+}
+
+// TranslateStream reads an Anthropic SSE stream from r and writes OpenAI
+// SSE chunks to w, ending with "data: [DONE]".
+func (rt *ReverseStreamTranslator) TranslateStream(r io.Reader, w io.Writer) error {
+	er := newSSEEventReader(r, 0)
+
+	for {
+		sse, err := er.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		var ev aStreamEvent
+		if err := json.Unmarshal(sse.Data, &ev); err != nil {
+			continue
+		}
+
+		switch ev.Type {
+		case "message_start":
+			if ev.Message != nil {
+				rt.id = "chatcmpl-" + strings.TrimPrefix(ev.Message.ID, "msg_")
+				rt.usage.PromptTokens = ev.Message.Usage.InputTokens
+			}
+			rt.emit(w, OStreamDelta{Role: "assistant"}, nil)
+		case "content_block_start":
+			if ev.ContentBlock != nil && ev.ContentBlock.Type == "tool_use" {
+				idx := len(rt.toolIndex)
+				rt.toolIndex[ev.Index] = idx
+				rt.emit(w, OStreamDelta{ToolCalls: []OStreamToolCall{{
+					Index:    idx,
+					ID:       ev.ContentBlock.ID,
+					Type:     "function",
+					Function: OStreamFuncDelta{Name: ev.ContentBlock.Name},
+				}}}, nil)
+			}
+		case "content_block_delta":
+			if ev.Delta == nil {
+				continue
+			}
+			switch ev.Delta.Type {
+			case "text_delta":
+				text := ev.Delta.Text
+				rt.emit(w, OStreamDelta{Content: &text}, nil)
+			case "input_json_delta":
+				if idx, ok := rt.toolIndex[ev.Index]; ok && ev.Delta.PartialJSON != "" {
+					rt.emit(w, OStreamDelta{ToolCalls: []OStreamToolCall{{
+						Index:    idx,
+						Function: OStreamFuncDelta{Arguments: ev.Delta.PartialJSON},
+					}}}, nil)
+				}
+			}
+		case "message_delta":
+			if ev.Delta != nil && ev.Delta.StopReason != "" {
+				rt.stop = ev.Delta.StopReason
+			}
+			if ev.Usage != nil {
+				rt.usage.CompletionTokens = ev.Usage.OutputTokens
+			}
+		case "message_stop":
+			rt.finish(w)
+			return nil
+		case "error":
+			msg := "stream error"
+			if ev.Error != nil {
+				msg = ev.Error.Type + ": " + ev.Error.Message
+			}
+			return fmt.Errorf("anthropic stream error: %s", msg)
+		}
+	}
+	return fmt.Errorf("anthropic stream ended without message_stop")
+}
+
+// Usage returns the input and output token counts the Anthropic stream
+// reported, or zeros if it sent none.
+func (rt *ReverseStreamTranslator) Usage() (input, output int) {
+	return rt.usage.PromptTokens, rt.usage.CompletionTokens
+}
+
+func (rt *ReverseStreamTranslator) finish(w io.Writer) {
+	reason := mapStopReason(rt.stop)
+	rt.emit(w, OStreamDelta{}, &reason)
+	if rt.includeUsage {
+		rt.usage.TotalTokens = rt.usage.PromptTokens + rt.usage.CompletionTokens
+		usage := rt.usage
+		rt.write(w, OStreamChunk{
+			ID: rt.id, Object: "chat.completion.chunk", Created: rt.created, Model: rt.modelLabel,
+			Choices: []OStreamChoice{}, Usage: &usage,
+		})
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+func (rt *ReverseStreamTranslator) emit(w io.Writer, delta OStreamDelta, finishReason *string) {
+	rt.write(w, OStreamChunk{
+		ID: rt.id, Object: "chat.completion.chunk", Created: rt.created, Model: rt.modelLabel,
+		Choices: []OStreamChoice{{Delta: delta, FinishReason: finishReason}},
+	})
+}
+
+func (rt *ReverseStreamTranslator) write(w io.Writer, chunk OStreamChunk) {
+	data, _ := json.Marshal(chunk)
+	fmt.Fprintf(w, "data: %s\n\n", data)
+}
+
+// FormatOpenAIError creates an OpenAI-format error response body.
+func FormatOpenAIError(errType, message string) []byte {
+	out, _ := json.Marshal(map[string]interface{}{
+		"error": map[string]interface{}{"type": errType, "message": message},
+	})
+	return out
+}
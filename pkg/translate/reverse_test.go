@@ -0,0 +1,255 @@
+package translate
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOpenAIToAnthropicConversation(t *testing.T) {
+	input := `{
+		"model": "local",
+		"messages": [
+			{"role": "system", "content": "Be brief."},
+			{"role": "user", "content": [{"type": "text", "text": "List files"}]},
+			{"role": "assistant", "content": null, "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "ls", "arguments": "{\"path\":\".\"}"}},
+				{"id": "call_2", "type": "function", "function": {"name": "ls", "arguments": "{\"path\":\"src\"}"}}
+			]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "a.go"},
+			{"role": "tool", "tool_call_id": "call_2", "content": "b.go"},
+			{"role": "user", "content": "Thanks"}
+		],
+		"tools": [{"type": "function", "function": {"name": "ls", "parameters": {"type": "object"}}}],
+		"tool_choice": "required",
+		"stop": "END",
+		"max_tokens": 100000,
+		"user": "u1"
+	}`
+
+	out, err := OpenAIToAnthropic([]byte(input), "claude-local", 8192)
+	if err != nil {
+		t.Fatalf("OpenAIToAnthropic: %v", err)
+	}
+	var req AnthropicRequest
+	if err := json.Unmarshal(out, &req); err != nil {
+		t.Fatal(err)
+	}
+
+	if req.Model != "claude-local" || req.MaxTokens != 8192 {
+		t.Errorf("model/max_tokens: %s %d", req.Model, req.MaxTokens)
+	}
+	if string(req.System) != `"Be brief."` {
+		t.Errorf("system: %s", req.System)
+	}
+	if len(req.StopSequences) != 1 || req.StopSequences[0] != "END" {
+		t.Errorf("stop: %v", req.StopSequences)
+	}
+	if string(req.ToolChoice) != `{"type":"any"}` {
+		t.Errorf("tool_choice: %s", req.ToolChoice)
+	}
+	if len(req.Tools) != 1 || req.Tools[0].Name != "ls" {
+		t.Errorf("tools: %+v", req.Tools)
+	}
+
+	// user, assistant(2 tool_use), user(2 tool_result + text)
+	if len(req.Messages) != 3 {
+		t.Fatalf("expected 3 alternating messages, got %d", len(req.Messages))
+	}
+	var last []ContentBlock
+	json.Unmarshal(req.Messages[2].Content, &last)
+	if req.Messages[2].Role != "user" || len(last) != 3 ||
+		last[0].Type != "tool_result" || last[1].ToolUseID != "call_2" || last[2].Text != "Thanks" {
+		t.Errorf("tool results not merged into one user turn: %s", req.Messages[2].Content)
+	}
+	var asst []ContentBlock
+	json.Unmarshal(req.Messages[1].Content, &asst)
+	if len(asst) != 2 || asst[0].Type != "tool_use" || string(asst[0].Input) != `{"path":"."}` {
+		t.Errorf("assistant tool_use blocks: %s", req.Messages[1].Content)
+	}
+	if req.Metadata == nil || req.Metadata.UserID != "u1" {
+		t.Errorf("metadata = %+v, want user_id from user", req.Metadata)
+	}
+}
+
+func TestOpenAIToAnthropicDefaults(t *testing.T) {
+	out, err := OpenAIToAnthropic([]byte(`{"messages":[{"role":"user","content":"hi"}],"tools":[{"type":"function","function":{"name":"x"}}],"tool_choice":"none"}`), "m", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req AnthropicRequest
+	json.Unmarshal(out, &req)
+	if req.MaxTokens != defaultReverseMaxTokens {
+		t.Errorf("expected default max_tokens, got %d", req.MaxTokens)
+	}
+	if len(req.Tools) != 0 {
+		t.Errorf("tool_choice none should drop tools, got %+v", req.Tools)
+	}
+}
+
+func TestOpenAIToAnthropicImages(t *testing.T) {
+	input := `{"messages":[{"role":"user","content":[
+		{"type":"text","text":"Compare"},
+		{"type":"text","text":"these:"},
+		{"type":"image_url","image_url":{"url":"data:image/png;base64,iVBORw0KGgo=","detail":"high"}},
+		{"type":"image_url","image_url":{"url":"https://example.com/b.jpg"}},
+		{"type":"text","text":"Which is larger?"}]}]}`
+	out, err := OpenAIToAnthropic([]byte(input), "m", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req AnthropicRequest
+	json.Unmarshal(out, &req)
+	var blocks []ContentBlock
+	json.Unmarshal(req.Messages[0].Content, &blocks)
+	want := []ContentBlock{
+		{Type: "text", Text: "Compare\nthese:"},
+		{Type: "image", Source: &ADocumentSource{Type: "base64", MediaType: "image/png", Data: "iVBORw0KGgo="}},
+		{Type: "image", Source: &ADocumentSource{Type: "url", URL: "https://example.com/b.jpg"}},
+		{Type: "text", Text: "Which is larger?"},
+	}
+	if !reflect.DeepEqual(blocks, want) {
+		t.Errorf("blocks = %s", req.Messages[0].Content)
+	}
+}
+
+func TestOpenAIToAnthropicRejectsUnsupportedParts(t *testing.T) {
+	tests := []struct {
+		name, messages, errPart string
+	}{
+		{"audio", `[{"role":"user","content":[{"type":"input_audio","input_audio":{"data":"AA==","format":"wav"}}]}]`, `"input_audio"`},
+		{"image not base64", `[{"role":"user","content":[{"type":"image_url","image_url":{"url":"data:image/png,raw"}}]}]`, "base64 image data URL"},
+		{"not an image", `[{"role":"user","content":[{"type":"image_url","image_url":{"url":"data:text/plain;base64,AA=="}}]}]`, "base64 image data URL"},
+		{"image in system", `[{"role":"system","content":[{"type":"image_url","image_url":{"url":"https://example.com/a.png"}}]}]`, "system message"},
+	}
+	for _, tc := range tests {
+		_, err := OpenAIToAnthropic([]byte(`{"messages":`+tc.messages+`}`), "m", 0)
+		if err == nil || !strings.Contains(err.Error(), tc.errPart) {
+			t.Errorf("%s: err = %v, want one mentioning %s", tc.name, err, tc.errPart)
+		}
+	}
+}
+
+func TestResponseToOpenAI(t *testing.T) {
+	input := `{
+		"id": "msg_123", "type": "message", "role": "assistant", "model": "claude-local",
+		"content": [
+			{"type": "text", "text": "Checking."},
+			{"type": "tool_use", "id": "toolu_1", "name": "ls", "input": {"path": "."}}
+		],
+		"stop_reason": "tool_use",
+		"usage": {"input_tokens": 12, "output_tokens": 7}
+	}`
+	out, err := ResponseToOpenAI([]byte(input), "local")
+	if err != nil {
+		t.Fatalf("ResponseToOpenAI: %v", err)
+	}
+	var resp OResponse
+	json.Unmarshal(out, &resp)
+	if resp.ID != "chatcmpl-123" || resp.Object != "chat.completion" || resp.Model != "local" {
+		t.Errorf("envelope: %+v", resp)
+	}
+	c := resp.Choices[0]
+	if c.FinishReason != "tool_calls" || c.Message.Content != "Checking." {
+		t.Errorf("choice: %+v", c)
+	}
+	if len(c.Message.ToolCalls) != 1 || c.Message.ToolCalls[0].Function.Arguments != `{"path":"."}` {
+		t.Errorf("tool calls: %+v", c.Message.ToolCalls)
+	}
+	if resp.Usage.TotalTokens != 19 {
+		t.Errorf("usage: %+v", resp.Usage)
+	}
+}
+
+func TestResponseToOpenAIRejectsError(t *testing.T) {
+	if _, err := ResponseToOpenAI(FormatError("api_error", "boom"), "local"); err == nil {
+		t.Error("expected error for Anthropic error body")
+	}
+}
+
+func TestReverseStream(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_s1","usage":{"input_tokens":5,"output_tokens":0}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"ls","input":{}}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"path\":"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\".\"}"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":9}}`,
+		`{"type":"message_stop"}`,
+	}
+	var in strings.Builder
+	for _, e := range events {
+		var probe struct{ Type string }
+		json.Unmarshal([]byte(e), &probe)
+		in.WriteString("event: " + probe.Type + "\ndata: " + e + "\n\n")
+	}
+
+	var out bytes.Buffer
+	rt := NewReverseStreamTranslator("local", true)
+	if err := rt.TranslateStream(strings.NewReader(in.String()), &out); err != nil {
+		t.Fatalf("TranslateStream: %v", err)
+	}
+
+	var chunks []OStreamChunk
+	done := false
+	for _, line := range strings.Split(out.String(), "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			continue
+		}
+		var c OStreamChunk
+		if err := json.Unmarshal([]byte(data), &c); err != nil {
+			t.Fatalf("bad chunk %q: %v", data, err)
+		}
+		chunks = append(chunks, c)
+	}
+	if !done {
+		t.Fatal("missing [DONE]")
+	}
+
+	var text, args string
+	var finish string
+	for _, c := range chunks {
+		if c.ID != "chatcmpl-s1" {
+			t.Errorf("chunk id %q", c.ID)
+		}
+		for _, ch := range c.Choices {
+			if ch.Delta.Content != nil {
+				text += *ch.Delta.Content
+			}
+			for _, tc := range ch.Delta.ToolCalls {
+				args += tc.Function.Arguments
+			}
+			if ch.FinishReason != nil {
+				finish = *ch.FinishReason
+			}
+		}
+	}
+	if text != "Hi" || args != `{"path":"."}` || finish != "tool_calls" {
+		t.Errorf("text=%q args=%q finish=%q", text, args, finish)
+	}
+	last := chunks[len(chunks)-1]
+	if last.Usage == nil || last.Usage.PromptTokens != 5 || last.Usage.CompletionTokens != 9 {
+		t.Errorf("usage chunk: %+v", last.Usage)
+	}
+	if in, out := rt.Usage(); in != 5 || out != 9 {
+		t.Errorf("Usage() = %d, %d, want 5, 9", in, out)
+	}
+}
+
+func TestReverseStreamError(t *testing.T) {
+	in := "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"busy\"}}\n\n"
+	err := NewReverseStreamTranslator("local", false).TranslateStream(strings.NewReader(in), &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "busy") {
+		t.Errorf("expected stream error, got %v", err)
+	}
+}
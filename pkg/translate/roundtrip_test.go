@@ -0,0 +1,259 @@
+package translate
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// Property tests: random Anthropic histories are translated to OpenAI with
+// RequestToOpenAI and back with OpenAIToAnthropic, and the invariants a
+// backend on either side relies on are checked. A failing seed is printed so
+// the history can be replayed with genHistory.
+
+const roundTripSeeds = 2000
+
+// genHistory builds a valid Anthropic conversation: user and assistant turns
+// alternate, every tool_use is answered by a tool_result in the next user
+// turn, and blocks within a turn come in random order.
+func genHistory(r *rand.Rand) AnthropicRequest {
+	req := AnthropicRequest{Model: "claude", MaxTokens: 1024}
+	if r.Intn(2) == 0 {
+		req.System, _ = json.Marshal("be brief")
+	}
+	var pending []string // tool_use IDs awaiting results
+	nextID := 0
+	turns := 1 + r.Intn(8)
+	for i := 0; i < turns; i++ {
+		var user []ContentBlock
+		for _, id := range pending {
+			user = append(user, genToolResult(r, id))
+		}
+		pending = nil
+		for n := r.Intn(3); n > 0 || len(user) == 0; n-- {
+			user = append(user, ContentBlock{Type: "text", Text: genText(r)})
+		}
+		r.Shuffle(len(user), func(a, b int) { user[a], user[b] = user[b], user[a] })
+		req.Messages = append(req.Messages, genMessage(r, "user", user))
+
+		if i == turns-1 {
+			break
+		}
+		var asst []ContentBlock
+		if r.Intn(3) == 0 {
+			asst = append(asst, ContentBlock{Type: "thinking", Thinking: genText(r)})
+		}
+		for n := r.Intn(3); n > 0; n-- {
+			asst = append(asst, ContentBlock{Type: "text", Text: genText(r)})
+		}
+		for n := r.Intn(4); n > 0; n-- {
+			id := fmt.Sprintf("toolu_%02d", nextID)
+			nextID++
+			pending = append(pending, id)
+			asst = append(asst, ContentBlock{Type: "tool_use", ID: id, Name: "tool", Input: json.RawMessage(`{"n":1}`)})
+		}
+		if len(asst) == 0 {
+			asst = append(asst, ContentBlock{Type: "text", Text: genText(r)})
+		}
+		r.Shuffle(len(asst), func(a, b int) { asst[a], asst[b] = asst[b], asst[a] })
+		req.Messages = append(req.Messages, genMessage(r, "assistant", asst))
+	}
+	return req
+}
+
+func genToolResult(r *rand.Rand, id string) ContentBlock {
+	b := ContentBlock{Type: "tool_result", ToolUseID: id}
+	switch r.Intn(3) {
+	case 0:
+		b.Content, _ = json.Marshal("result of " + id)
+	case 1:
+		b.Content, _ = json.Marshal([]ContentBlock{{Type: "text", Text: "result of " + id}})
+	}
+	return b
+}
+
+func genText(r *rand.Rand) string {
+	words := []string{"alpha", "beta", "gamma", "delta", "<tag>", "ünï", "line\nbreak"}
+	return words[r.Intn(len(words))] + " " + words[r.Intn(len(words))]
+}
+
+// genMessage encodes blocks as a content array, or as a plain string when the
+// turn is a single text block and the coin says so.
+func genMessage(r *rand.Rand, role string, blocks []ContentBlock) AMessage {
+	if len(blocks) == 1 && blocks[0].Type == "text" && r.Intn(2) == 0 {
+		content, _ := json.Marshal(blocks[0].Text)
+		return AMessage{Role: role, Content: content}
+	}
+	content, _ := json.Marshal(blocks)
+	return AMessage{Role: role, Content: content}
+}
+
+func decodeBlocks(t *testing.T, m AMessage) []ContentBlock {
+	t.Helper()
+	var s string
+	if json.Unmarshal(m.Content, &s) == nil {
+		return []ContentBlock{{Type: "text", Text: s}}
+	}
+	var blocks []ContentBlock
+	if err := json.Unmarshal(m.Content, &blocks); err != nil {
+		t.Fatalf("decode content %s: %v", m.Content, err)
+	}
+	return blocks
+}
+
+// checkOpenAIHistory checks the OpenAI request is one a strict provider
+// accepts: each assistant tool_calls message is followed directly by one tool
+// message per call, with nothing else in between.
+func checkOpenAIHistory(t *testing.T, msgs []OMessage) {
+	t.Helper()
+	for i := 0; i < len(msgs); i++ {
+		m := msgs[i]
+		if m.Role == "tool" {
+			t.Fatalf("message %d: tool message %q does not follow an assistant tool call", i, m.ToolCallID)
+		}
+		if m.Role != "assistant" || len(m.ToolCalls) == 0 {
+			continue
+		}
+		want := map[string]bool{}
+		for _, tc := range m.ToolCalls {
+			want[tc.ID] = true
+		}
+		for len(want) > 0 {
+			i++
+			if i >= len(msgs) || msgs[i].Role != "tool" {
+				t.Fatalf("message %d: tool calls %v not answered before the next turn", i, want)
+			}
+			if !want[msgs[i].ToolCallID] {
+				t.Fatalf("message %d: tool message for unknown call %q", i, msgs[i].ToolCallID)
+			}
+			delete(want, msgs[i].ToolCallID)
+		}
+	}
+}
+
+// checkAnthropicHistory checks roles alternate starting with user, and each
+// assistant tool_use is answered by a tool_result at the start of the next
+// user turn.
+func checkAnthropicHistory(t *testing.T, msgs []AMessage) {
+	t.Helper()
+	if len(msgs) == 0 || msgs[0].Role != "user" {
+		t.Fatalf("history does not start with a user turn")
+	}
+	var pending map[string]bool
+	for i, m := range msgs {
+		wantRole := "user"
+		if i%2 == 1 {
+			wantRole = "assistant"
+		}
+		if m.Role != wantRole {
+			t.Fatalf("message %d: role %q, want %q", i, m.Role, wantRole)
+		}
+		blocks := decodeBlocks(t, m)
+		if m.Role == "user" {
+			seenOther := false
+			for _, b := range blocks {
+				if b.Type != "tool_result" {
+					seenOther = true
+					continue
+				}
+				if seenOther {
+					t.Fatalf("message %d: tool_result %q after other content", i, b.ToolUseID)
+				}
+				if !pending[b.ToolUseID] {
+					t.Fatalf("message %d: tool_result %q has no tool_use in the previous turn", i, b.ToolUseID)
+				}
+				delete(pending, b.ToolUseID)
+			}
+			if len(pending) > 0 {
+				t.Fatalf("message %d: tool_use %v left unanswered", i, pending)
+			}
+			continue
+		}
+		pending = map[string]bool{}
+		for _, b := range blocks {
+			if b.Type == "tool_use" {
+				pending[b.ID] = true
+			}
+		}
+	}
+}
+
+// toolResults maps each tool_result's tool_use_id to its flattened text.
+func toolResults(t *testing.T, msgs []AMessage) map[string]string {
+	t.Helper()
+	out := map[string]string{}
+	for _, m := range msgs {
+		for _, b := range decodeBlocks(t, m) {
+			if b.Type == "tool_result" {
+				out[b.ToolUseID], _ = extractToolResultContent(b, RequestOptions{})
+			}
+		}
+	}
+	return out
+}
+
+// toolUseIDs lists tool_use IDs in history order.
+func toolUseIDs(t *testing.T, msgs []AMessage) []string {
+	t.Helper()
+	var ids []string
+	for _, m := range msgs {
+		for _, b := range decodeBlocks(t, m) {
+			if b.Type == "tool_use" {
+				ids = append(ids, b.ID)
+			}
+		}
+	}
+	return ids
+}
+
+func TestRoundTripProperties(t *testing.T) {
+	for seed := int64(0); seed < roundTripSeeds; seed++ {
+		t.Run(fmt.Sprint(seed), func(t *testing.T) {
+			in := genHistory(rand.New(rand.NewSource(seed)))
+			body, err := json.Marshal(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			oBody, err := RequestToOpenAI(body, "local", 0)
+			if err != nil {
+				t.Fatalf("RequestToOpenAI: %v\n%s", err, body)
+			}
+			var oReq ORequest
+			if err := json.Unmarshal(oBody, &oReq); err != nil {
+				t.Fatal(err)
+			}
+			checkOpenAIHistory(t, oReq.Messages)
+
+			aBody, err := OpenAIToAnthropic(oBody, "claude", 0)
+			if err != nil {
+				t.Fatalf("OpenAIToAnthropic: %v\n%s", err, oBody)
+			}
+			var out AnthropicRequest
+			if err := json.Unmarshal(aBody, &out); err != nil {
+				t.Fatal(err)
+			}
+			checkAnthropicHistory(t, out.Messages)
+
+			if got, want := strings.Join(toolUseIDs(t, out.Messages), ","), strings.Join(toolUseIDs(t, in.Messages), ","); got != want {
+				t.Errorf("tool_use IDs: got %s, want %s", got, want)
+			}
+			got, want := toolResults(t, out.Messages), toolResults(t, in.Messages)
+			if len(got) != len(want) {
+				t.Errorf("tool results: got %d, want %d", len(got), len(want))
+			}
+			for id, text := range want {
+				if got[id] != text {
+					t.Errorf("tool result %s: got %q, want %q", id, got[id], text)
+				}
+			}
+			if extractSystemText(out.System) != extractSystemText(in.System) {
+				t.Errorf("system: got %s, want %s", out.System, in.System)
+			}
+		})
+		if t.Failed() {
+			break
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package translate
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+)
+
+// hmacSignaturePrefix marks signatures made by a ThinkingSigner.
+const hmacSignaturePrefix = "hmac-v1:"
+
+// ThinkingSigner signs thinking blocks with an HMAC of their text. Claude
+// Code sends earlier turns' thinking back with their signatures; Verify
+// tells the blocks this signer produced from those of other models,
+// sessions or edits. A nil signer signs with a timestamp and verifies
+// everything, as the proxy did before signing was configurable.
+type ThinkingSigner struct {
+	key []byte
+}
+
+// NewThinkingSigner returns a signer using key.
+func NewThinkingSigner(key []byte) *ThinkingSigner {
+	return &ThinkingSigner{key: append([]byte(nil), key...)}
+}
+
+// Sign returns the signature for a thinking block holding thinking.
+func (s *ThinkingSigner) Sign(thinking string) string {
+	if s == nil {
+		return thinkingSignature()
+	}
+	return hmacSignaturePrefix + base64.RawURLEncoding.EncodeToString(s.mac(thinking))
+}
+
+// Verify reports whether signature is this signer's for thinking.
+func (s *ThinkingSigner) Verify(thinking, signature string) bool {
+	if s == nil {
+		return true
+	}
+	enc, ok := strings.CutPrefix(signature, hmacSignaturePrefix)
+	if !ok {
+		return false
+	}
+	sum, err := base64.RawURLEncoding.DecodeString(enc)
+	return err == nil && hmac.Equal(sum, s.mac(thinking))
+}
+
+func (s *ThinkingSigner) mac(thinking string) []byte {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte(thinking))
+	return h.Sum(nil)
+}
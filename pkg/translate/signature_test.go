@@ -0,0 +1,101 @@
+package translate
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestThinkingSigner(t *testing.T) {
+	s := NewThinkingSigner([]byte("session one"))
+	sig := s.Sign("Plan it.")
+	if !strings.HasPrefix(sig, hmacSignaturePrefix) {
+		t.Fatalf("signature %q lacks the hmac prefix", sig)
+	}
+	tests := []struct {
+		name     string
+		signer   *ThinkingSigner
+		thinking string
+		sig      string
+		want     bool
+	}{
+		{"own", s, "Plan it.", sig, true},
+		{"edited", s, "Plan it!", sig, false},
+		{"other session", NewThinkingSigner([]byte("session two")), "Plan it.", sig, false},
+		{"timestamp", s, "Plan it.", "<1700000000000>", false},
+		{"anthropic", s, "Plan it.", "EqQBCkgIARABGAIiQL", false},
+		{"garbled", s, "Plan it.", hmacSignaturePrefix + "!!", false},
+		{"nil signer", nil, "Plan it.", "", true},
+	}
+	for _, tc := range tests {
+		if got := tc.signer.Verify(tc.thinking, tc.sig); got != tc.want {
+			t.Errorf("%s: Verify = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+	if sig := (*ThinkingSigner)(nil).Sign("x"); !strings.HasPrefix(sig, "<") {
+		t.Errorf("nil signer should sign with a timestamp, got %q", sig)
+	}
+}
+
+func TestRequestDropsUnverifiedThinking(t *testing.T) {
+	s := NewThinkingSigner([]byte("k"))
+	body := `{"model":"x","max_tokens":10,"messages":[
+		{"role":"user","content":"hi"},
+		{"role":"assistant","content":[
+			{"type":"thinking","thinking":"Mine.","signature":"` + s.Sign("Mine.") + `"},
+			{"type":"thinking","thinking":"Not mine.","signature":"EqQBCkgIARAB"},
+			{"type":"text","text":"Hello"}]},
+		{"role":"user","content":"again"}]}`
+	for _, tc := range []struct {
+		signer *ThinkingSigner
+		want   string
+	}{
+		{nil, "Mine.\nNot mine."},
+		{s, "Mine."},
+	} {
+		out, err := RequestToOpenAIOptions([]byte(body), RequestOptions{Model: "m", Signer: tc.signer})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var req ORequest
+		json.Unmarshal(out, &req)
+		if got := req.Messages[1].Thinking; got != tc.want {
+			t.Errorf("signer %v: thinking = %q, want %q", tc.signer != nil, got, tc.want)
+		}
+	}
+}
+
+func TestSignerSignsResponses(t *testing.T) {
+	s := NewThinkingSigner([]byte("k"))
+	ctx := NewTransformContext("m", "deepseek")
+	ctx.Signer = s
+
+	input := makeSSE(
+		`{"id":"r1","choices":[{"index":0,"delta":{"reasoning_content":"Plan "}}]}`,
+		`{"id":"r1","choices":[{"index":0,"delta":{"reasoning_content":"it."}}]}`,
+		`{"id":"r1","choices":[{"index":0,"delta":{"content":"Done."}}]}`,
+		`{"id":"r1","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+	)
+	var buf bytes.Buffer
+	st := NewStreamTranslator("m")
+	st.SetTransformChain(NewTransformChain(newReasoningTransform()), ctx)
+	if err := st.TranslateStream(strings.NewReader(input), &buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"signature":"` + s.Sign("Plan it.") + `","type":"signature_delta"}`; !strings.Contains(buf.String(), want) {
+		t.Errorf("stream not signed with the signer:\n%s", buf.String())
+	}
+
+	body := `{"id":"r1","choices":[{"index":0,"message":{"role":"assistant","content":"Done.",` +
+		`"thinking":{"content":"Plan it.","signature":"<1>"}},"finish_reason":"stop"}]}`
+	out, err := ResponseToAnthropicContext([]byte(body), "m", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp AResponse
+	json.Unmarshal(out, &resp)
+	if !s.Verify(resp.Content[0].Thinking, resp.Content[0].Signature) {
+		t.Errorf("response thinking not signed with the signer: %+v", resp.Content[0])
+	}
+}
@@ -0,0 +1,96 @@
+package translate
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"testing/iotest"
+)
+
+// Stream snapshots: recorded provider SSE streams in testdata/stream/*.sse
+// are run through a StreamTranslator with the chain the provider's example
+// config uses, and the Anthropic SSE it writes is compared with the checked-in
+// *.golden file. After an intended change to the output, regenerate with
+//
+//	go test ./pkg/translate -run TestStreamSnapshots -update
+//
+// and review the golden diffs.
+
+var updateSnapshots = flag.Bool("update", false, "rewrite testdata/stream/*.golden from the current output")
+
+var streamSnapshots = []struct {
+	name      string // testdata/stream/<name>.sse and .golden
+	transform []string
+}{
+	{"openai_text", []string{"cleancache", "schema:openai"}},
+	{"deepseek_reasoning_tool", []string{"cleancache", "deepseek", "reasoning", "enhancetool", "schema:generic"}},
+	{"qwen_thinktag", []string{"cleancache", "extrathinktag", "enhancetool", "schema:generic"}},
+	{"groq_parallel_tools", []string{"cleancache", "groq", "enhancetool", "schema:generic"}},
+	{"openrouter_reasoning_tool", []string{"cleancache", "openrouter", "reasoning", "enhancetool", "schema:generic"}},
+	{"llamacpp_truncated_tool", []string{"cleancache", "enhancetool", "schema:generic"}},
+}
+
+// Thinking signatures are timestamps and repaired numeric tool IDs are
+// random; both are pinned so snapshots are stable.
+var (
+	snapshotSignature = regexp.MustCompile(`"signature":"\\u003c\d+\\u003e"`)
+	snapshotRandomID  = regexp.MustCompile(`call_[0-9a-f]{24}`)
+)
+
+func translateSnapshot(t *testing.T, transform []string, input []byte, oneByte bool) []byte {
+	t.Helper()
+	chain, err := BuildChain(transform)
+	if err != nil {
+		t.Fatalf("BuildChain: %v", err)
+	}
+	st := NewStreamTranslator("local")
+	st.SetTransformChain(chain, NewTransformContext("local", "snapshot"))
+	r := bytes.NewReader(input)
+	var out bytes.Buffer
+	v := NewSSEValidator(func(violation string) { t.Errorf("SSE order: %s", violation) })
+	w := io.MultiWriter(&out, v)
+	if oneByte {
+		err = st.TranslateStream(iotest.OneByteReader(r), w)
+	} else {
+		err = st.TranslateStream(r, w)
+	}
+	if err != nil {
+		t.Fatalf("TranslateStream: %v", err)
+	}
+	v.Close()
+	b := snapshotSignature.ReplaceAll(out.Bytes(), []byte(`"signature":"\u003c0\u003e"`))
+	return snapshotRandomID.ReplaceAll(b, []byte("call_RANDOM"))
+}
+
+func TestStreamSnapshots(t *testing.T) {
+	for _, tc := range streamSnapshots {
+		t.Run(tc.name, func(t *testing.T) {
+			base := filepath.Join("testdata", "stream", tc.name)
+			input, err := os.ReadFile(base + ".sse")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := translateSnapshot(t, tc.transform, input, false)
+			if *updateSnapshots {
+				if err := os.WriteFile(base+".golden", got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(base + ".golden")
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("output differs from %s.golden (run with -update to accept):\n--- got\n%s\n--- want\n%s", base, got, want)
+			}
+			// Reads that split lines anywhere must not change the output.
+			if split := translateSnapshot(t, tc.transform, input, true); !bytes.Equal(split, got) {
+				t.Errorf("one-byte reads differ from whole reads:\n%s", split)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package translate
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/peter-wagstaff/claude-hybrid-router/internal/defaults"
+)
+
+// sseLineReader reads SSE lines of any length up to a limit. Unlike
+// bufio.Scanner it has no fixed token size, so providers that put a whole
+// tool call in one data: line (llama.cpp does) are not cut off. Lines may end
+// in LF or CRLF; the terminator is stripped.
+type sseLineReader struct {
+	r       *bufio.Reader
+	maxLine int
+	line    []byte // reused across lines that span multiple reads
+}
+
+func newSSELineReader(r io.Reader, maxLine int) *sseLineReader {
+	if maxLine <= 0 {
+		maxLine = defaults.SSEMaxLineBytes
+	}
+	return &sseLineReader{r: bufio.NewReaderSize(r, 64*1024), maxLine: maxLine}
+}
+
+// readLine returns the next line without its terminator. The slice is only
+// valid until the next call. A final unterminated line is returned before
+// io.EOF.
+func (lr *sseLineReader) readLine() ([]byte, error) {
+	frag, err := lr.r.ReadSlice('\n')
+	if err == nil && len(frag) <= lr.maxLine {
+		// Fast path: the whole line fit in the reader's buffer.
+		return trimEOL(frag), nil
+	}
+
+	lr.line = append(lr.line[:0], frag...)
+	for errors.Is(err, bufio.ErrBufferFull) {
+		if len(lr.line) > lr.maxLine+2 { // +2 leaves room for CRLF
+			return nil, lr.tooLong()
+		}
+		frag, err = lr.r.ReadSlice('\n')
+		lr.line = append(lr.line, frag...)
+	}
+	if err != nil && (err != io.EOF || len(lr.line) == 0) {
+		return nil, err
+	}
+	line := trimEOL(lr.line)
+	if len(line) > lr.maxLine {
+		return nil, lr.tooLong()
+	}
+	return line, nil
+}
+
+func (lr *sseLineReader) tooLong() error {
+	return fmt.Errorf("SSE line exceeds %d bytes (raise sse_max_line_bytes for this provider)", lr.maxLine)
+}
+
+func trimEOL(b []byte) []byte {
+	b = bytes.TrimSuffix(b, []byte("\n"))
+	return bytes.TrimSuffix(b, []byte("\r"))
+}
+
+// sseEvent is one dispatched SSE event. Event and Data alias the reader's
+// buffers and are only valid until the next call to next.
+type sseEvent struct {
+	Event []byte // value of the last event: field, empty for the default "message"
+	Data  []byte // data: lines joined with "\n"
+}
+
+// sseEventReader parses an event stream per the WHATWG SSE spec: events end
+// at a blank line, multiple data: lines are joined with newlines, lines
+// starting with ':' are comments (keepalives), a single space after the
+// field colon is optional, and id:/retry: are tracked but never dispatched.
+// Unknown fields are ignored. Unlike the spec, a pending event is still
+// dispatched at EOF, since some providers close the stream without the
+// final blank line.
+type sseEventReader struct {
+	lr      *sseLineReader
+	event   []byte
+	data    []byte
+	hasData bool
+	started bool
+
+	lastID string        // last id: value, for diagnostics
+	retry  time.Duration // last retry: value; the proxy never reconnects
+}
+
+func newSSEEventReader(r io.Reader, maxLine int) *sseEventReader {
+	return &sseEventReader{lr: newSSELineReader(r, maxLine)}
+}
+
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// next returns the next event with a data field, or io.EOF at end of stream.
+func (er *sseEventReader) next() (sseEvent, error) {
+	er.event = er.event[:0]
+	er.data = er.data[:0]
+	er.hasData = false
+
+	for {
+		line, err := er.lr.readLine()
+		if err == io.EOF && er.hasData {
+			return sseEvent{Event: er.event, Data: er.data}, nil
+		}
+		if err != nil {
+			return sseEvent{}, err
+		}
+		if !er.started {
+			er.started = true
+			line = bytes.TrimPrefix(line, utf8BOM)
+		}
+
+		if len(line) == 0 {
+			if er.hasData {
+				return sseEvent{Event: er.event, Data: er.data}, nil
+			}
+			// An event with no data is discarded, event type included.
+			er.event = er.event[:0]
+			continue
+		}
+		if line[0] == ':' {
+			continue
+		}
+
+		field, value := line, []byte(nil)
+		if i := bytes.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], line[i+1:]
+			if len(value) > 0 && value[0] == ' ' {
+				value = value[1:]
+			}
+		}
+		switch string(field) {
+		case "data":
+			if er.hasData {
+				er.data = append(er.data, '\n')
+			}
+			er.data = append(er.data, value...)
+			er.hasData = true
+		case "event":
+			er.event = append(er.event[:0], value...)
+		case "id":
+			if bytes.IndexByte(value, 0) < 0 {
+				er.lastID = string(value)
+			}
+		case "retry":
+			if isDigits(value) {
+				if ms, err := strconv.Atoi(string(value)); err == nil {
+					er.retry = time.Duration(ms) * time.Millisecond
+				}
+			}
+		}
+	}
+}
+
+func isDigits(b []byte) bool {
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return len(b) > 0
+}
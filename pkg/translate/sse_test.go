@@ -0,0 +1,150 @@
+package translate
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readAllLines(t *testing.T, lr *sseLineReader) []string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := lr.readLine()
+		if err == io.EOF {
+			return lines
+		}
+		if err != nil {
+			t.Fatalf("readLine: %v", err)
+		}
+		lines = append(lines, string(line))
+	}
+}
+
+func TestSSELineReaderCRLFAndUnterminated(t *testing.T) {
+	lr := newSSELineReader(strings.NewReader("data: a\r\n\r\ndata: b\n\ndata: c"), 0)
+	got := readAllLines(t, lr)
+	want := []string{"data: a", "", "data: b", "", "data: c"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSSELineReaderLongLine(t *testing.T) {
+	// Well past bufio.Scanner's old 256KB cap and the reader's 64KB buffer.
+	long := "data: " + strings.Repeat("x", 1<<20)
+	lr := newSSELineReader(strings.NewReader(long+"\r\ndata: next\n"), 0)
+	got := readAllLines(t, lr)
+	if len(got) != 2 || got[0] != long || got[1] != "data: next" {
+		t.Fatalf("long line not read intact (got %d lines)", len(got))
+	}
+}
+
+func TestSSELineReaderLimit(t *testing.T) {
+	lr := newSSELineReader(strings.NewReader(strings.Repeat("x", 200*1024)+"\n"), 100*1024)
+	if _, err := lr.readLine(); err == nil || !strings.Contains(err.Error(), "sse_max_line_bytes") {
+		t.Errorf("expected line limit error, got %v", err)
+	}
+	// Exactly at the limit is accepted, CRLF not counted.
+	lr = newSSELineReader(strings.NewReader(strings.Repeat("y", 10)+"\r\n"), 10)
+	if line, err := lr.readLine(); err != nil || len(line) != 10 {
+		t.Errorf("line at limit: %q, %v", line, err)
+	}
+}
+
+func TestStreamGiantSingleLineToolCall(t *testing.T) {
+	// llama.cpp can deliver a full tool call's arguments in one chunk.
+	args := `{"content":"` + strings.Repeat("a", 512*1024) + `"}`
+	input := strings.ReplaceAll(makeSSE(toolChunk("call_1", "Write", args), chunk("resp1", nil, strPtr("tool_calls"))), "\n", "\r\n")
+
+	var buf bytes.Buffer
+	st := NewStreamTranslator("m")
+	if err := st.TranslateStream(strings.NewReader(input), &buf); err != nil {
+		t.Fatalf("TranslateStream: %v", err)
+	}
+	if got := assembledToolInput(t, buf.String()); got != args {
+		t.Errorf("tool input truncated: got %d bytes, want %d", len(got), len(args))
+	}
+
+	st = NewStreamTranslator("m")
+	st.SetMaxLineBytes(64 * 1024)
+	if err := st.TranslateStream(strings.NewReader(input), io.Discard); err == nil {
+		t.Error("expected error when line exceeds configured limit")
+	}
+}
+
+func TestSSEEventReaderSpec(t *testing.T) {
+	input := "\xef\xbb\xbf: keepalive\n" +
+		"retry: 3000\n" +
+		"id: 7\n" +
+		"data:first\n" +
+		"data: second\n\n" +
+		": OPENROUTER PROCESSING\n\n" +
+		"event: ping\n\n" + // no data: discarded, type reset
+		"event: error\n" +
+		"data: {\"x\":1}\r\n\r\n" +
+		"data\n\n" + // field with no colon: empty data
+		"unknown: field\n" +
+		"data: tail" // unterminated final event
+	er := newSSEEventReader(strings.NewReader(input), 0)
+
+	type ev struct{ event, data string }
+	var got []ev
+	for {
+		e, err := er.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("next: %v", err)
+		}
+		got = append(got, ev{string(e.Event), string(e.Data)})
+	}
+	want := []ev{
+		{"", "first\nsecond"},
+		{"error", `{"x":1}`},
+		{"", ""},
+		{"", "tail"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d events %q, want %q", len(got), got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+	if er.retry != 3*time.Second || er.lastID != "7" {
+		t.Errorf("retry=%v id=%q", er.retry, er.lastID)
+	}
+}
+
+func TestStreamMultiLineDataAndComments(t *testing.T) {
+	// A pretty-printed chunk split across data: lines, with keepalives between events.
+	input := ": keepalive\n\n" +
+		"data: {\"id\":\"r1\",\"choices\":[{\"index\":0,\n" +
+		"data:   \"delta\":{\"content\":\"Hello\"}}]}\n\n" +
+		": keepalive\n" +
+		"retry: 1000\n" +
+		"data: {\"id\":\"r1\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n" +
+		"data: [DONE]\n\n"
+
+	var buf bytes.Buffer
+	if err := NewStreamTranslator("m").TranslateStream(strings.NewReader(input), &buf); err != nil {
+		t.Fatalf("TranslateStream: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"text":"Hello"`) || !strings.Contains(buf.String(), `"stop_reason":"end_turn"`) {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestStreamProviderErrorEvent(t *testing.T) {
+	input := "data: " + chunk("r1", strPtr("partial"), nil) + "\n\n" +
+		"event: error\ndata: {\"error\":{\"message\":\"upstream overloaded\"}}\n\n"
+	err := NewStreamTranslator("m").TranslateStream(strings.NewReader(input), io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "upstream overloaded") {
+		t.Errorf("expected provider error, got %v", err)
+	}
+}
@@ -0,0 +1,734 @@
+package translate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// OStreamChunk is an OpenAI streaming chunk.
+type OStreamChunk struct {
+	ID      string          `json:"id"`
+	Object  string          `json:"object,omitempty"`
+	Created int64           `json:"created,omitempty"`
+	Model   string          `json:"model,omitempty"`
+	Choices []OStreamChoice `json:"choices"`
+	Usage   *OUsage         `json:"usage,omitempty"`
+
+	// Perplexity repeats its sources in every chunk
+	Citations     []string        `json:"citations,omitempty"`
+	SearchResults []OSearchResult `json:"search_results,omitempty"`
+}
+
+// OStreamChoice is a choice in a streaming chunk.
+type OStreamChoice struct {
+	Index        int          `json:"index"`
+	Delta        OStreamDelta `json:"delta"`
+	FinishReason *string      `json:"finish_reason"`
+}
+
+// OStreamDelta is the delta content in a streaming chunk.
+type OStreamDelta struct {
+	Role        string            `json:"role,omitempty"`
+	Content     *string           `json:"content,omitempty"`
+	ToolCalls   []OStreamToolCall `json:"tool_calls,omitempty"`
+	Thinking    *OStreamThinking  `json:"thinking,omitempty"`    // set by the reasoning transforms
+	Annotations []OAnnotation     `json:"annotations,omitempty"` // cited sources (OpenAI, OpenRouter)
+}
+
+// OStreamThinking is the thinking delta the reasoning transforms produce:
+// content while reasoning streams, then a signature to close the block.
+type OStreamThinking struct {
+	Content   string `json:"content,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// OStreamToolCall is a tool call delta in streaming.
+type OStreamToolCall struct {
+	Index    int               `json:"index"`
+	ID       string            `json:"id,omitempty"`
+	Type     string            `json:"type,omitempty"`
+	Function OStreamFuncDelta  `json:"function,omitempty"`
+}
+
+// OStreamFuncDelta is the function delta in a streaming tool call.
+type OStreamFuncDelta struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+}
+
+// StreamTranslator converts an OpenAI SSE stream to Anthropic SSE events.
+type StreamTranslator struct {
+	modelLabel   string
+	msgID        string
+	blockIndex   int
+	inTextBlock  bool
+	inToolBlock  bool
+	inThinking   bool
+	started      bool
+	finishReason string
+	usage        *OUsage
+	// Usage of earlier attempts when a cut-off stream is resumed
+	prior OUsage
+	// Text emitted so far, the assistant prefix for a resumed request
+	text strings.Builder
+	// Track tool calls by index to handle multi-chunk tool call streaming
+	toolCalls map[int]*activeToolCall
+	// Transform chain for stream chunk processing
+	chain *TransformChain
+	ctx   *TransformContext
+	// Verbose logging and consecutive drop tracking
+	verbose          bool
+	consecutiveDrops int
+	// Arguments streamed so far for the open tool_use block, validated on close
+	toolArgs strings.Builder
+	argsScan jsonScan // whether toolArgs holds a whole object yet
+	openTool int      // tool call index of the open tool_use block
+	// Thinking and text that arrived while the open tool call's arguments
+	// were incomplete, emitted once they are
+	held []OStreamDelta
+	// Scratch buffer for hand-encoded delta events
+	buf []byte
+	// Longest accepted SSE line (0 = default)
+	maxLine int
+	// Output token estimate for providers that report no usage
+	countTokens func(text string) int
+	otherOut    strings.Builder // thinking and tool calls emitted, for countTokens
+	thinking    strings.Builder // text of the open thinking block, for its signature
+	thinkingCut bool            // the open thinking block reached its summary length
+	sources     citationSources // sources the provider cited, emitted by Finish
+	estimated   bool
+	stopReason  string // set by Finish
+}
+
+type activeToolCall struct {
+	id   string
+	name string
+}
+
+// NewStreamTranslator creates a new streaming translator.
+func NewStreamTranslator(modelLabel string) *StreamTranslator {
+	return &StreamTranslator{
+		modelLabel: modelLabel,
+		msgID:      "msg_stream",
+		toolCalls:  make(map[int]*activeToolCall),
+	}
+}
+
+// SetVerbose enables verbose logging of dropped SSE chunks.
+func (st *StreamTranslator) SetVerbose(v bool) {
+	st.verbose = v
+}
+
+// SetMaxLineBytes caps the length of a single SSE line from the provider
+// (0 = 16 MB).
+func (st *StreamTranslator) SetMaxLineBytes(n int) {
+	st.maxLine = n
+}
+
+// SetTokenCounter sets how output tokens are counted when the provider
+// reports no usage, as many llama.cpp-era servers don't, even with
+// stream_options.include_usage. The text, thinking and tool calls emitted
+// are counted for message_delta's output_tokens and Usage. Without a
+// counter such streams report zero.
+func (st *StreamTranslator) SetTokenCounter(count func(text string) int) {
+	st.countTokens = count
+}
+
+// UsageEstimated reports whether the output tokens were counted by the
+// token counter rather than reported by the provider.
+func (st *StreamTranslator) UsageEstimated() bool {
+	return st.estimated
+}
+
+// StopReason returns the Anthropic stop reason the message ended with, or ""
+// before it has finished.
+func (st *StreamTranslator) StopReason() string {
+	return st.stopReason
+}
+
+// toolIDs is the map rewritten tool call IDs are recorded in, if any.
+func (st *StreamTranslator) toolIDs() *ToolIDMap {
+	if st.ctx == nil {
+		return nil
+	}
+	return st.ctx.ToolIDs
+}
+
+// SetTransformChain sets the transform chain and context for stream chunk processing.
+func (st *StreamTranslator) SetTransformChain(chain *TransformChain, ctx *TransformContext) {
+	st.chain = chain
+	st.ctx = ctx
+}
+
+var sseDone = []byte("[DONE]")
+
+// ErrStreamCut wraps the read error of a provider stream that broke off
+// before it finished.
+var ErrStreamCut = errors.New("provider stream cut off")
+
+// TranslateStream reads an OpenAI SSE stream from r and writes Anthropic SSE events to w.
+func (st *StreamTranslator) TranslateStream(r io.Reader, w io.Writer) error {
+	readErr, err := st.translate(r, w)
+	if err != nil {
+		return err
+	}
+	st.Finish(w)
+	return readErr
+}
+
+// TranslatePartial is TranslateStream, except that when the provider stream
+// is cut off it returns an error wrapping ErrStreamCut and leaves the
+// message open, so the caller can Resume with a new stream or Finish it.
+func (st *StreamTranslator) TranslatePartial(r io.Reader, w io.Writer) error {
+	readErr, err := st.translate(r, w)
+	if err != nil {
+		return err
+	}
+	if readErr != nil {
+		return fmt.Errorf("%w: %v", ErrStreamCut, readErr)
+	}
+	st.Finish(w)
+	return nil
+}
+
+// Finish closes the open block and ends the message.
+func (st *StreamTranslator) Finish(w io.Writer) {
+	// Emit held content and cited sources, then close any open block
+	st.flushHeld(w)
+	st.emitCitations(w)
+	st.closeCurrentBlock(w)
+
+	// Emit message_delta with stop_reason
+	st.emitMessageDelta(w)
+
+	// Emit message_stop
+	st.emitEvent(w, "message_stop", map[string]string{"type": "message_stop"})
+}
+
+// Resumable reports whether a cut-off message can be continued by a new
+// request, and returns the text emitted so far to send as its assistant
+// prefix. Only text can be continued: a message with a tool call or an
+// unfinished thinking block can't.
+func (st *StreamTranslator) Resumable() (string, bool) {
+	if !st.started || st.inThinking || len(st.toolCalls) > 0 {
+		return "", false
+	}
+	return st.text.String(), true
+}
+
+// Resume prepares for the stream of a continuation request. Its text goes
+// on in the open block and its usage adds to the cut-off stream's.
+func (st *StreamTranslator) Resume() {
+	if st.usage != nil {
+		st.prior.PromptTokens += st.usage.PromptTokens
+		st.prior.CompletionTokens += st.usage.CompletionTokens
+	}
+	st.usage = nil
+	st.finishReason = ""
+	st.consecutiveDrops = 0
+}
+
+// translate runs the stream through the state machine. It returns the
+// error that cut the stream off, if any, separately from errors that
+// abandon the message.
+func (st *StreamTranslator) translate(r io.Reader, w io.Writer) (readErr, err error) {
+	er := newSSEEventReader(r, st.maxLine)
+
+	for {
+		// ev aliases the reader's buffers; chunks are fully processed
+		// before the next read, so nothing here needs a copy.
+		ev, err := er.next()
+		if err != nil {
+			if err != io.EOF {
+				readErr = err
+			}
+			break
+		}
+
+		data := ev.Data
+		if string(ev.Event) == "error" {
+			return nil, fmt.Errorf("provider stream error: %.500s", data)
+		}
+		if len(data) == 0 {
+			continue
+		}
+		if bytes.Equal(data, sseDone) {
+			break
+		}
+
+		if st.chain == nil || st.ctx == nil {
+			if err := st.processData(w, data); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// Transforms see the raw chunk (each parses only what it needs), so
+		// only their output is decoded into the state machine.
+		transformedChunks, err := st.chain.RunStreamChunk(data, st.ctx)
+		if err != nil {
+			st.consecutiveDrops++
+			if st.verbose {
+				log.Printf("[LOCAL_ERR:TRANSLATE] stream transform error: %v", err)
+			}
+			if st.consecutiveDrops >= 3 {
+				return nil, fmt.Errorf("too many consecutive stream transform errors (%d)", st.consecutiveDrops)
+			}
+			continue
+		}
+		for _, tc := range transformedChunks {
+			if err := st.processData(w, tc); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Transforms may still hold chunks, e.g. tool calls of a stream that
+	// ended without a finish_reason.
+	if st.chain != nil && st.ctx != nil {
+		held, err := st.chain.FlushStream(st.ctx)
+		if err != nil {
+			return nil, fmt.Errorf("flush stream transforms: %w", err)
+		}
+		for _, tc := range held {
+			if err := st.processData(w, tc); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return readErr, nil
+}
+
+// processData decodes one OpenAI chunk and feeds it to the state machine,
+// counting unparseable chunks toward the consecutive-drop limit.
+func (st *StreamTranslator) processData(w io.Writer, data []byte) error {
+	var chunk OStreamChunk
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		st.consecutiveDrops++
+		if st.verbose {
+			log.Printf("[LOCAL_ERR:PARSE] dropped unparseable SSE chunk: %.200s", data)
+		}
+		if st.consecutiveDrops >= 3 {
+			return fmt.Errorf("too many consecutive unparseable chunks (%d)", st.consecutiveDrops)
+		}
+		return nil
+	}
+	st.consecutiveDrops = 0
+	st.processChunk(w, chunk)
+	return nil
+}
+
+func (st *StreamTranslator) processChunk(w io.Writer, chunk OStreamChunk) {
+	// Capture message ID from first chunk
+	if !st.started && chunk.ID != "" {
+		st.msgID = "msg_" + chunk.ID
+	}
+
+	// Capture usage if present (from stream_options)
+	if chunk.Usage != nil {
+		st.usage = chunk.Usage
+	}
+
+	st.sources.addPerplexity(chunk.Citations, chunk.SearchResults)
+
+	if len(chunk.Choices) == 0 {
+		return
+	}
+
+	choice := chunk.Choices[0]
+	st.sources.addAnnotations(choice.Delta.Annotations, st.text.String())
+
+	// Emit message_start on first chunk
+	if !st.started {
+		st.started = true
+		st.emitMessageStart(w)
+	}
+
+	// Handle finish_reason
+	if choice.FinishReason != nil {
+		st.finishReason = *choice.FinishReason
+	}
+
+	// Thinking or text interleaved with a tool call's argument fragments
+	// would close its block early and strand the rest of its arguments, so
+	// it waits until they are complete.
+	delta := choice.Delta
+	if delta.Thinking != nil || (delta.Content != nil && *delta.Content != "") {
+		if len(st.held) > 0 || (st.inToolBlock && !st.argsScan.complete()) {
+			st.held = append(st.held, OStreamDelta{Content: delta.Content, Thinking: delta.Thinking})
+		} else {
+			st.processContent(w, delta)
+		}
+	}
+
+	// Handle tool calls
+	for _, tc := range delta.ToolCalls {
+		// New tool call (has id and name)
+		if tc.ID != "" {
+			st.closeCurrentBlock(w)
+			st.flushHeld(w)
+			st.closeCurrentBlock(w)
+			st.toolCalls[tc.Index] = &activeToolCall{id: tc.ID, name: tc.Function.Name}
+			st.emitContentBlockStart(w, "tool_use", sanitizeToolID(tc.ID, st.toolIDs()), tc.Function.Name)
+			st.inToolBlock = true
+			st.openTool = tc.Index
+			st.otherOut.WriteString(tc.Function.Name)
+		}
+
+		// Argument fragment, for the open block only
+		if tc.Function.Arguments != "" {
+			if !st.inToolBlock || tc.Index != st.openTool {
+				if st.ctx != nil {
+					st.ctx.recordRepair("stream")
+				}
+				if st.verbose {
+					log.Printf("[LOCAL_ERR:PARSE] dropped arguments for closed tool call %d: %.200s", tc.Index, tc.Function.Arguments)
+				}
+				continue
+			}
+			st.emitInputJSONDelta(w, tc.Function.Arguments)
+		}
+	}
+	if len(st.held) > 0 && st.argsScan.complete() {
+		st.flushHeld(w)
+	}
+}
+
+// processContent emits a delta's thinking and text.
+func (st *StreamTranslator) processContent(w io.Writer, delta OStreamDelta) {
+	// Handle thinking (from the reasoning transforms)
+	if th := delta.Thinking; th != nil {
+		if th.Content != "" {
+			st.otherOut.WriteString(th.Content)
+			display := st.reasoningDisplay()
+			if !st.inThinking && !display.Hidden {
+				st.closeCurrentBlock(w)
+				st.emitContentBlockStart(w, "thinking", "", "")
+				st.inThinking = true
+				st.thinking.Reset()
+				st.thinkingCut = false
+			}
+			if text := st.shownThinking(display, th.Content); text != "" {
+				st.emitDelta(w, `{"thinking":`, text, `,"type":"thinking_delta"}`)
+				st.thinking.WriteString(text)
+			}
+		}
+		if th.Signature != "" && st.inThinking {
+			st.emitDelta(w, `{"signature":`, st.signature(th.Signature), `,"type":"signature_delta"}`)
+			st.inThinking = false
+			st.emitEvent(w, "content_block_stop", map[string]interface{}{
+				"type":  "content_block_stop",
+				"index": st.blockIndex,
+			})
+			st.blockIndex++
+		}
+	}
+
+	// Handle text content
+	if delta.Content != nil && *delta.Content != "" {
+		if !st.inTextBlock {
+			st.closeCurrentBlock(w)
+			st.emitContentBlockStart(w, "text", "", "")
+			st.inTextBlock = true
+		}
+		st.emitTextDelta(w, *delta.Content)
+		st.text.WriteString(*delta.Content)
+	}
+}
+
+// reasoningDisplay returns the context's ReasoningDisplay.
+func (st *StreamTranslator) reasoningDisplay() ReasoningDisplay {
+	if st.ctx == nil {
+		return ReasoningDisplay{}
+	}
+	return st.ctx.ReasoningDisplay
+}
+
+// shownThinking returns the part of a thinking delta the client sees: all
+// of it until the open block reaches display's summary length, then the
+// part that fits and the truncation marker, then nothing.
+func (st *StreamTranslator) shownThinking(display ReasoningDisplay, text string) string {
+	if display.Hidden || st.thinkingCut {
+		return ""
+	}
+	shown := st.thinking.String()
+	cut, ok := display.truncate(shown + text)
+	if !ok {
+		return text
+	}
+	st.thinkingCut = true
+	if len(cut) > len(shown) {
+		return cut[len(shown):] + ThinkingTruncatedMarker
+	}
+	return ThinkingTruncatedMarker
+}
+
+// signature returns the signature closing the open thinking block: the
+// context's Signer's when it has one, else the transform's, else a
+// timestamp.
+func (st *StreamTranslator) signature(fromTransform string) string {
+	if st.ctx != nil && st.ctx.Signer != nil {
+		return st.ctx.Signer.Sign(st.thinking.String())
+	}
+	if fromTransform != "" {
+		return fromTransform
+	}
+	return thinkingSignature()
+}
+
+// emitCitations passes the sources cited during a stream with text on as
+// the context's Citations mode asks: citations_delta events on the open
+// text block, or a source list appended as text.
+func (st *StreamTranslator) emitCitations(w io.Writer) {
+	mode := CitationsReferences
+	if st.ctx != nil && st.ctx.Citations != "" {
+		mode = st.ctx.Citations
+	}
+	if len(st.sources.list) == 0 || st.text.Len() == 0 || mode == CitationsDrop {
+		return
+	}
+	if mode == CitationsBlocks && st.inTextBlock {
+		for _, c := range st.sources.list {
+			st.emitEvent(w, "content_block_delta", map[string]interface{}{
+				"type":  "content_block_delta",
+				"index": st.blockIndex,
+				"delta": map[string]interface{}{"type": "citations_delta", "citation": c},
+			})
+		}
+		return
+	}
+	if !st.inTextBlock {
+		st.closeCurrentBlock(w)
+		st.emitContentBlockStart(w, "text", "", "")
+		st.inTextBlock = true
+	}
+	st.emitTextDelta(w, st.sources.references())
+}
+
+// flushHeld emits the thinking and text held back by an incomplete tool call.
+func (st *StreamTranslator) flushHeld(w io.Writer) {
+	held := st.held
+	st.held = nil
+	for _, d := range held {
+		st.processContent(w, d)
+	}
+}
+
+func (st *StreamTranslator) closeCurrentBlock(w io.Writer) {
+	if st.inToolBlock {
+		st.repairToolArgs(w)
+	}
+	if st.inThinking {
+		// Closed by something other than the transform's signature chunk
+		// (a tool call, the end of the stream): sign it here.
+		st.emitDelta(w, `{"signature":`, st.signature(""), `,"type":"signature_delta"}`)
+	}
+	if st.inTextBlock || st.inToolBlock || st.inThinking {
+		st.emitEvent(w, "content_block_stop", map[string]interface{}{
+			"type":  "content_block_stop",
+			"index": st.blockIndex,
+		})
+		st.blockIndex++
+		st.inTextBlock = false
+		st.inToolBlock = false
+		st.inThinking = false
+	}
+}
+
+// repairToolArgs checks the arguments assembled for the open tool_use block
+// and, when they are truncated JSON (unclosed strings or brackets), emits one
+// last input_json_delta carrying the missing suffix so Claude Code receives a
+// parseable tool input.
+func (st *StreamTranslator) repairToolArgs(w io.Writer) {
+	args := st.toolArgs.String()
+	defer st.toolArgs.Reset()
+	st.argsScan = jsonScan{}
+	suffix, ok := RepairSuffix(args)
+	if !ok {
+		if args != "" && !json.Valid([]byte(args)) && st.verbose {
+			log.Printf("[LOCAL_ERR:PARSE] unrepairable tool arguments for block %d: %.200s", st.blockIndex, args)
+		}
+		return
+	}
+	if st.ctx != nil {
+		st.ctx.recordRepair("stream")
+	}
+	if st.verbose {
+		log.Printf("repaired truncated tool arguments for block %d (appended %q)", st.blockIndex, suffix)
+	}
+	st.emitInputJSONDelta(w, suffix)
+}
+
+func (st *StreamTranslator) emitMessageStart(w io.Writer) {
+	inputTokens := 0
+	if st.usage != nil {
+		inputTokens = st.usage.PromptTokens
+	}
+	st.emitEvent(w, "message_start", map[string]interface{}{
+		"type": "message_start",
+		"message": map[string]interface{}{
+			"id": st.msgID, "type": "message", "role": "assistant",
+			"content": []interface{}{}, "model": st.modelLabel,
+			"stop_reason": nil, "stop_sequence": nil,
+			"usage": map[string]int{"input_tokens": inputTokens, "output_tokens": 0},
+		},
+	})
+}
+
+func (st *StreamTranslator) emitContentBlockStart(w io.Writer, blockType, id, name string) {
+	block := map[string]interface{}{"type": blockType}
+	if blockType == "text" {
+		block["text"] = ""
+	} else if blockType == "thinking" {
+		block["thinking"] = ""
+	} else if blockType == "tool_use" {
+		block["id"] = id
+		block["name"] = name
+		block["input"] = map[string]interface{}{}
+	}
+	st.emitEvent(w, "content_block_start", map[string]interface{}{
+		"type":          "content_block_start",
+		"index":         st.blockIndex,
+		"content_block": block,
+	})
+}
+
+// Text and tool-argument deltas are nearly every event in a stream, so they
+// are encoded by hand into a reused buffer instead of via maps and
+// json.Marshal. The output is byte-identical to emitEvent's.
+
+func (st *StreamTranslator) emitTextDelta(w io.Writer, text string) {
+	st.emitDelta(w, `{"text":`, text, `,"type":"text_delta"}`)
+}
+
+func (st *StreamTranslator) emitInputJSONDelta(w io.Writer, partial string) {
+	st.toolArgs.WriteString(partial)
+	st.argsScan.write(partial)
+	st.otherOut.WriteString(partial)
+	st.emitDelta(w, `{"partial_json":`, partial, `,"type":"input_json_delta"}`)
+}
+
+func (st *StreamTranslator) emitDelta(w io.Writer, open, value, close string) {
+	b := append(st.buf[:0], "event: content_block_delta\ndata: {\"delta\":"...)
+	b = append(b, open...)
+	b = appendJSONString(b, value)
+	b = append(b, close...)
+	b = append(b, `,"index":`...)
+	b = strconv.AppendInt(b, int64(st.blockIndex), 10)
+	b = append(b, ",\"type\":\"content_block_delta\"}\n\n"...)
+	st.buf = b
+	w.Write(b)
+}
+
+func (st *StreamTranslator) emitMessageDelta(w io.Writer) {
+	st.estimateUsage()
+	_, outputTokens := st.Usage()
+	var overrides map[string]string
+	if st.ctx != nil {
+		overrides = st.ctx.FinishReasons
+	}
+	stopReason := mapFinishReason(st.finishReason, overrides)
+	st.stopReason = stopReason
+	st.emitEvent(w, "message_delta", map[string]interface{}{
+		"type":  "message_delta",
+		"delta": map[string]interface{}{"stop_reason": stopReason, "stop_sequence": nil},
+		"usage": map[string]int{"output_tokens": outputTokens},
+	})
+}
+
+func (st *StreamTranslator) emitEvent(w io.Writer, event string, data interface{}) {
+	jsonData, _ := json.Marshal(data)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, jsonData)
+}
+
+// appendJSONString appends s to dst as a JSON string literal, escaping
+// exactly as encoding/json does (including its HTML-safe escapes).
+func appendJSONString(dst []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				dst = append(dst, '\\', c)
+			case '\b':
+				dst = append(dst, '\\', 'b')
+			case '\f':
+				dst = append(dst, '\\', 'f')
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hex[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
+
+// Usage returns the token counts the provider reported in the stream, or
+// zeros if it sent none. A resumed stream's counts include every attempt.
+// When the provider reported no output tokens and a token counter is set,
+// output is the counter's estimate once the message has finished.
+func (st *StreamTranslator) Usage() (input, output int) {
+	input, output = st.prior.PromptTokens, st.prior.CompletionTokens
+	if st.usage != nil {
+		input += st.usage.PromptTokens
+		output += st.usage.CompletionTokens
+	}
+	return input, output
+}
+
+// estimateUsage fills in the output tokens of a stream whose provider
+// reported none, using the token counter.
+func (st *StreamTranslator) estimateUsage() {
+	if _, output := st.Usage(); output > 0 || st.countTokens == nil || st.estimated {
+		return
+	}
+	n := st.countTokens(st.text.String() + st.otherOut.String())
+	if n == 0 {
+		return
+	}
+	if st.usage == nil {
+		st.usage = &OUsage{}
+	}
+	st.usage.CompletionTokens = n
+	st.estimated = true
+}
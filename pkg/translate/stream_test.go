@@ -0,0 +1,703 @@
+package translate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func makeSSE(chunks ...string) string {
+	var sb strings.Builder
+	for _, c := range chunks {
+		sb.WriteString("data: " + c + "\n\n")
+	}
+	sb.WriteString("data: [DONE]\n\n")
+	return sb.String()
+}
+
+func chunk(id string, content *string, finishReason *string) string {
+	c := OStreamChunk{
+		ID: id,
+		Choices: []OStreamChoice{{
+			Delta:        OStreamDelta{Content: content},
+			FinishReason: finishReason,
+		}},
+	}
+	b, _ := json.Marshal(c)
+	return string(b)
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestStreamTextOnly(t *testing.T) {
+	input := makeSSE(
+		chunk("resp1", strPtr("Hello"), nil),
+		chunk("resp1", strPtr(" world"), nil),
+		chunk("resp1", nil, strPtr("stop")),
+	)
+
+	var buf bytes.Buffer
+	st := NewStreamTranslator("my_model")
+	err := st.TranslateStream(strings.NewReader(input), &buf)
+	if err != nil {
+		t.Fatalf("TranslateStream: %v", err)
+	}
+
+	output := buf.String()
+
+	// Check all required events exist
+	for _, event := range []string{
+		"event: message_start",
+		"event: content_block_start",
+		"event: content_block_delta",
+		"event: content_block_stop",
+		"event: message_delta",
+		"event: message_stop",
+	} {
+		if !strings.Contains(output, event) {
+			t.Errorf("missing event: %s", event)
+		}
+	}
+
+	// Check text deltas contain our content
+	if !strings.Contains(output, "Hello") {
+		t.Error("missing 'Hello' in output")
+	}
+	if !strings.Contains(output, " world") {
+		t.Error("missing ' world' in output")
+	}
+
+	// Check model label in message_start
+	if !strings.Contains(output, `"model":"my_model"`) {
+		t.Error("missing model label in message_start")
+	}
+
+	// Check stop_reason is end_turn
+	if !strings.Contains(output, `"stop_reason":"end_turn"`) {
+		t.Error("missing stop_reason end_turn in message_delta")
+	}
+}
+
+func TestStreamToolCall(t *testing.T) {
+	// First chunk: text
+	c1 := chunk("resp1", strPtr("Let me check."), nil)
+
+	// Tool call start
+	tc1 := OStreamChunk{
+		ID: "resp1",
+		Choices: []OStreamChoice{{
+			Delta: OStreamDelta{
+				ToolCalls: []OStreamToolCall{{
+					Index: 0,
+					ID:    "call_abc",
+					Type:  "function",
+					Function: OStreamFuncDelta{
+						Name:      "get_weather",
+						Arguments: "",
+					},
+				}},
+			},
+		}},
+	}
+	b1, _ := json.Marshal(tc1)
+
+	// Tool call argument fragments
+	tc2 := OStreamChunk{
+		ID: "resp1",
+		Choices: []OStreamChoice{{
+			Delta: OStreamDelta{
+				ToolCalls: []OStreamToolCall{{
+					Index:    0,
+					Function: OStreamFuncDelta{Arguments: `{"city":`},
+				}},
+			},
+		}},
+	}
+	b2, _ := json.Marshal(tc2)
+
+	tc3 := OStreamChunk{
+		ID: "resp1",
+		Choices: []OStreamChoice{{
+			Delta: OStreamDelta{
+				ToolCalls: []OStreamToolCall{{
+					Index:    0,
+					Function: OStreamFuncDelta{Arguments: `"SF"}`},
+				}},
+			},
+		}},
+	}
+	b3, _ := json.Marshal(tc3)
+
+	// Finish
+	c5 := chunk("resp1", nil, strPtr("tool_calls"))
+
+	input := makeSSE(c1, string(b1), string(b2), string(b3), c5)
+
+	var buf bytes.Buffer
+	st := NewStreamTranslator("test_model")
+	err := st.TranslateStream(strings.NewReader(input), &buf)
+	if err != nil {
+		t.Fatalf("TranslateStream: %v", err)
+	}
+
+	output := buf.String()
+
+	// Should have text block start, text delta, text block stop
+	if strings.Count(output, "event: content_block_start") != 2 {
+		t.Errorf("expected 2 content_block_start events (text + tool_use), got %d",
+			strings.Count(output, "event: content_block_start"))
+	}
+
+	// Check tool_use block
+	if !strings.Contains(output, `"type":"tool_use"`) {
+		t.Error("missing tool_use content block")
+	}
+	if !strings.Contains(output, `"name":"get_weather"`) {
+		t.Error("missing tool name")
+	}
+	if !strings.Contains(output, `"id":"call_abc"`) {
+		t.Error("missing tool call id")
+	}
+
+	// Check input_json_delta events
+	if !strings.Contains(output, `"type":"input_json_delta"`) {
+		t.Error("missing input_json_delta")
+	}
+
+	// Check stop_reason is tool_use
+	if !strings.Contains(output, `"stop_reason":"tool_use"`) {
+		t.Error("missing stop_reason tool_use")
+	}
+}
+
+func TestStreamToolIDSanitized(t *testing.T) {
+	tc := OStreamChunk{
+		ID: "resp1",
+		Choices: []OStreamChoice{{
+			Delta: OStreamDelta{
+				ToolCalls: []OStreamToolCall{{
+					Index: 0,
+					ID:    "func:call.123",
+					Type:  "function",
+					Function: OStreamFuncDelta{
+						Name:      "test",
+						Arguments: "{}",
+					},
+				}},
+			},
+		}},
+	}
+	b, _ := json.Marshal(tc)
+
+	input := makeSSE(string(b), chunk("resp1", nil, strPtr("tool_calls")))
+
+	var buf bytes.Buffer
+	st := NewStreamTranslator("m")
+	st.TranslateStream(strings.NewReader(input), &buf)
+
+	if !strings.Contains(buf.String(), `"id":"func_call_123"`) {
+		t.Error("tool ID not sanitized in stream")
+	}
+}
+
+func TestStreamEmptyContent(t *testing.T) {
+	// Some backends send empty string content deltas — should be ignored
+	input := makeSSE(
+		chunk("resp1", strPtr(""), nil),
+		chunk("resp1", strPtr("Hi"), nil),
+		chunk("resp1", nil, strPtr("stop")),
+	)
+
+	var buf bytes.Buffer
+	st := NewStreamTranslator("m")
+	st.TranslateStream(strings.NewReader(input), &buf)
+
+	output := buf.String()
+	// Should only have 1 content_block_start (the empty delta shouldn't start a block)
+	if strings.Count(output, "event: content_block_start") != 1 {
+		t.Errorf("expected 1 content_block_start, got %d",
+			strings.Count(output, "event: content_block_start"))
+	}
+}
+
+func TestStreamUsage(t *testing.T) {
+	// Usage chunk (from stream_options include_usage)
+	usageChunk := OStreamChunk{
+		ID:      "resp1",
+		Choices: []OStreamChoice{},
+		Usage:   &OUsage{PromptTokens: 42, CompletionTokens: 10, TotalTokens: 52},
+	}
+	b, _ := json.Marshal(usageChunk)
+
+	input := makeSSE(
+		chunk("resp1", strPtr("Hi"), nil),
+		chunk("resp1", nil, strPtr("stop")),
+		string(b),
+	)
+
+	var buf bytes.Buffer
+	st := NewStreamTranslator("m")
+	st.TranslateStream(strings.NewReader(input), &buf)
+
+	output := buf.String()
+	if !strings.Contains(output, `"output_tokens":10`) {
+		t.Error("missing output_tokens in message_delta")
+	}
+}
+
+func TestStreamUsageEstimate(t *testing.T) {
+	// No usage chunk: the counter's estimate covers text and tool calls.
+	input := makeSSE(
+		chunk("resp1", strPtr("Hello there"), nil),
+		toolChunk("call_1", "Read", `{"path":"a"}`),
+		chunk("resp1", nil, strPtr("tool_calls")),
+	)
+	var counted string
+	var buf bytes.Buffer
+	st := NewStreamTranslator("m")
+	st.SetTokenCounter(func(text string) int { counted = text; return 7 })
+	st.TranslateStream(strings.NewReader(input), &buf)
+
+	if !strings.Contains(buf.String(), `"usage":{"output_tokens":7}`) {
+		t.Errorf("missing estimated output_tokens:\n%s", buf.String())
+	}
+	if counted != `Hello thereRead{"path":"a"}` {
+		t.Errorf("counted %q", counted)
+	}
+	if _, out := st.Usage(); out != 7 || !st.UsageEstimated() {
+		t.Errorf("Usage output = %d, estimated = %v", out, st.UsageEstimated())
+	}
+
+	// A provider's own usage wins.
+	b, _ := json.Marshal(OStreamChunk{ID: "resp1", Choices: []OStreamChoice{}, Usage: &OUsage{PromptTokens: 4, CompletionTokens: 3}})
+	input = makeSSE(chunk("resp1", strPtr("Hi"), strPtr("stop")), string(b))
+	buf.Reset()
+	st = NewStreamTranslator("m")
+	st.SetTokenCounter(func(string) int { return 99 })
+	st.TranslateStream(strings.NewReader(input), &buf)
+	if _, out := st.Usage(); out != 3 || st.UsageEstimated() {
+		t.Errorf("Usage output = %d, estimated = %v, want the provider's 3", out, st.UsageEstimated())
+	}
+}
+
+func TestStreamMessageID(t *testing.T) {
+	input := makeSSE(
+		chunk("chatcmpl-abc", strPtr("Hi"), nil),
+		chunk("chatcmpl-abc", nil, strPtr("stop")),
+	)
+
+	var buf bytes.Buffer
+	st := NewStreamTranslator("m")
+	st.TranslateStream(strings.NewReader(input), &buf)
+
+	if !strings.Contains(buf.String(), `"id":"msg_chatcmpl-abc"`) {
+		t.Error("message ID not prefixed with msg_")
+	}
+}
+
+func TestStreamConsecutiveDropAbort(t *testing.T) {
+	// 3 unparseable SSE lines without [DONE] — should trigger consecutive drop abort
+	input := "data: {bad\n\ndata: {bad\n\ndata: {bad\n\n"
+
+	var buf bytes.Buffer
+	st := NewStreamTranslator("m")
+	err := st.TranslateStream(strings.NewReader(input), &buf)
+
+	if err == nil {
+		t.Fatal("expected error from consecutive unparseable chunks")
+	}
+	if !strings.Contains(err.Error(), "consecutive") {
+		t.Errorf("error = %q, want to contain 'consecutive'", err.Error())
+	}
+}
+
+func TestStreamTransformBadOutputAbort(t *testing.T) {
+	// Valid JSON input chunks, but a transform that returns unparseable output chunks.
+	// This exercises the consecutive drop path at lines 139-143 in stream.go.
+	input := makeSSE(
+		chunk("resp1", strPtr("Hello"), nil),
+	)
+
+	// Transform returns 3 unparseable chunks from a single input chunk
+	badOutputTransform := &mockTransformer{
+		name: "badoutput",
+		streamChunkFn: func(data []byte, ctx *TransformContext) ([][]byte, error) {
+			return [][]byte{
+				[]byte("{bad"),
+				[]byte("{bad"),
+				[]byte("{bad"),
+			}, nil
+		},
+	}
+
+	chain := NewTransformChain(badOutputTransform)
+	ctx := NewTransformContext("m", "p")
+
+	var buf bytes.Buffer
+	st := NewStreamTranslator("m")
+	st.SetTransformChain(chain, ctx)
+	err := st.TranslateStream(strings.NewReader(input), &buf)
+
+	if err == nil {
+		t.Fatal("expected error from consecutive unparseable transformed chunks")
+	}
+	if !strings.Contains(err.Error(), "consecutive") {
+		t.Errorf("error = %q, want to contain 'consecutive'", err.Error())
+	}
+}
+
+// toolChunk builds a streaming chunk carrying one tool call delta.
+func toolChunk(id, name, args string) string {
+	c := OStreamChunk{
+		ID: "resp1",
+		Choices: []OStreamChoice{{
+			Delta: OStreamDelta{
+				ToolCalls: []OStreamToolCall{{
+					Index:    0,
+					ID:       id,
+					Function: OStreamFuncDelta{Name: name, Arguments: args},
+				}},
+			},
+		}},
+	}
+	b, _ := json.Marshal(c)
+	return string(b)
+}
+
+// assembledToolInput concatenates every partial_json fragment in an Anthropic SSE stream.
+func assembledToolInput(t *testing.T, output string) string {
+	t.Helper()
+	var sb strings.Builder
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var ev struct {
+			Delta struct {
+				Type        string `json:"type"`
+				PartialJSON string `json:"partial_json"`
+			} `json:"delta"`
+		}
+		json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev)
+		if ev.Delta.Type == "input_json_delta" {
+			sb.WriteString(ev.Delta.PartialJSON)
+		}
+	}
+	return sb.String()
+}
+
+func TestStreamRepairsTruncatedToolArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"valid args untouched", []string{`{"city":`, `"SF"}`}, `{"city":"SF"}`},
+		{"unclosed object", []string{`{"city":`, `"SF"`}, `{"city":"SF"}`},
+		{"unterminated string", []string{`{"cmd":"ls -la`}, `{"cmd":"ls -la"}`},
+		{"unclosed nested", []string{`{"a":[1,2`}, `{"a":[1,2]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := []string{toolChunk("call_1", "Bash", "")}
+			for _, a := range tt.args {
+				chunks = append(chunks, toolChunk("", "", a))
+			}
+			chunks = append(chunks, chunk("resp1", nil, strPtr("tool_calls")))
+
+			var buf bytes.Buffer
+			st := NewStreamTranslator("test_model")
+			if err := st.TranslateStream(strings.NewReader(makeSSE(chunks...)), &buf); err != nil {
+				t.Fatalf("TranslateStream: %v", err)
+			}
+			if got := assembledToolInput(t, buf.String()); got != tt.want {
+				t.Errorf("assembled input = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamRepairPerToolBlock(t *testing.T) {
+	// Two tool calls: the first truncated, the second valid. The repair for
+	// the first must not leak into the second block's validation.
+	second := OStreamChunk{
+		ID: "resp1",
+		Choices: []OStreamChoice{{
+			Delta: OStreamDelta{
+				ToolCalls: []OStreamToolCall{{
+					Index:    1,
+					ID:       "call_2",
+					Function: OStreamFuncDelta{Name: "Read", Arguments: `{"path":"/tmp"}`},
+				}},
+			},
+		}},
+	}
+	b, _ := json.Marshal(second)
+	input := makeSSE(
+		toolChunk("call_1", "Bash", `{"cmd":"ls"`),
+		string(b),
+		chunk("resp1", nil, strPtr("tool_calls")),
+	)
+
+	var buf bytes.Buffer
+	st := NewStreamTranslator("test_model")
+	if err := st.TranslateStream(strings.NewReader(input), &buf); err != nil {
+		t.Fatalf("TranslateStream: %v", err)
+	}
+	if got := assembledToolInput(t, buf.String()); got != `{"cmd":"ls"}{"path":"/tmp"}` {
+		t.Errorf("unexpected assembled input: %s", got)
+	}
+}
+
+// benchStream builds an OpenAI SSE stream of n chunks: mostly text deltas,
+// ending with a tool call whose arguments arrive in small fragments.
+func benchStream(n int) string {
+	chunks := make([]string, 0, n)
+	text := n * 9 / 10
+	for i := 0; i < text; i++ {
+		chunks = append(chunks, chunk("bench", strPtr("token \"quoted\" <b> "), nil))
+	}
+	chunks = append(chunks, toolChunk("call_1", "Write", `{"path":"a.go","content":"`))
+	for i := text + 1; i < n-1; i++ {
+		chunks = append(chunks, toolChunk("", "", `package main\n`))
+	}
+	chunks = append(chunks, toolChunk("", "", `"}`))
+	return makeSSE(chunks...)
+}
+
+func BenchmarkStreamTranslate10k(b *testing.B) {
+	input := benchStream(10000)
+	b.Run("no_chain", func(b *testing.B) {
+		b.SetBytes(int64(len(input)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			st := NewStreamTranslator("bench")
+			if err := st.TranslateStream(strings.NewReader(input), io.Discard); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("typical_chain", func(b *testing.B) {
+		b.SetBytes(int64(len(input)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			chain, err := BuildChain([]string{"cleancache", "enhancetool", "groq", "schema:generic"})
+			if err != nil {
+				b.Fatal(err)
+			}
+			st := NewStreamTranslator("bench")
+			st.SetTransformChain(chain, NewTransformContext("m", "p"))
+			if err := st.TranslateStream(strings.NewReader(input), io.Discard); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestAppendJSONStringMatchesEncodingJSON(t *testing.T) {
+	for _, s := range []string{
+		"", "plain", `quote " and \ backslash`, "<tag> & amp", "\n\r\t\b\f\x00\x1f",
+		"unicode \u00e9 \u4e16\u754c \U0001F389", "line\u2028sep\u2029", "bad \xff utf8 \xc3", "mixed <\"\u00e9\">\n",
+	} {
+		want, _ := json.Marshal(s)
+		if got := appendJSONString(nil, s); string(got) != string(want) {
+			t.Errorf("appendJSONString(%q) = %s, want %s", s, got, want)
+		}
+	}
+}
+
+func TestHandEncodedDeltasMatchEmitEvent(t *testing.T) {
+	text := "a \"b\" <c>\n"
+	var fast, slow bytes.Buffer
+	st := NewStreamTranslator("m")
+	st.blockIndex = 3
+	st.emitTextDelta(&fast, text)
+	st.emitEvent(&slow, "content_block_delta", map[string]interface{}{
+		"type": "content_block_delta", "index": 3,
+		"delta": map[string]string{"type": "text_delta", "text": text},
+	})
+	st.emitInputJSONDelta(&fast, `{"k":`)
+	st.emitEvent(&slow, "content_block_delta", map[string]interface{}{
+		"type": "content_block_delta", "index": 3,
+		"delta": map[string]string{"type": "input_json_delta", "partial_json": `{"k":`},
+	})
+	if fast.String() != slow.String() {
+		t.Errorf("hand-encoded deltas differ:\n got %s\nwant %s", fast.String(), slow.String())
+	}
+}
+
+func TestStreamThinkingBlocks(t *testing.T) {
+	input := makeSSE(
+		`{"id":"r1","choices":[{"index":0,"delta":{"reasoning":"Plan: "}}]}`,
+		`{"id":"r1","choices":[{"index":0,"delta":{"reasoning":"answer briefly."}}]}`,
+		`{"id":"r1","choices":[{"index":0,"delta":{"content":"Hello"}}]}`,
+		`{"id":"r1","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+	)
+	var buf bytes.Buffer
+	st := NewStreamTranslator("m")
+	st.SetTransformChain(NewTransformChain(newReasoningTransform()), NewTransformContext("m", "openrouter"))
+	if err := st.TranslateStream(strings.NewReader(input), &buf); err != nil {
+		t.Fatalf("TranslateStream: %v", err)
+	}
+	out := buf.String()
+
+	want := []string{
+		`"content_block":{"thinking":"","type":"thinking"},"index":0`,
+		`{"delta":{"thinking":"Plan: ","type":"thinking_delta"},"index":0`,
+		`{"delta":{"thinking":"answer briefly.","type":"thinking_delta"},"index":0`,
+		`"type":"signature_delta"},"index":0`,
+		`{"index":0,"type":"content_block_stop"}`,
+		`"content_block":{"text":"","type":"text"},"index":1`,
+		`{"delta":{"text":"Hello","type":"text_delta"},"index":1`,
+	}
+	pos := 0
+	for _, w := range want {
+		i := strings.Index(out[pos:], w)
+		if i < 0 {
+			t.Fatalf("missing or out of order: %s\n%s", w, out)
+		}
+		pos += i + len(w)
+	}
+}
+
+func TestStreamThinkingClosedByToolCall(t *testing.T) {
+	// Reasoning followed directly by a tool call gets no close chunk from
+	// the transform; the translator signs the block itself.
+	input := makeSSE(
+		`{"id":"r1","choices":[{"index":0,"delta":{"reasoning_content":"Need the file."}}]}`,
+		toolChunk("call_1", "Read", `{"path":"/x"}`),
+		`{"id":"r1","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+	)
+	var buf bytes.Buffer
+	st := NewStreamTranslator("m")
+	st.SetTransformChain(NewTransformChain(newReasoningTransform()), NewTransformContext("m", "deepseek"))
+	if err := st.TranslateStream(strings.NewReader(input), &buf); err != nil {
+		t.Fatalf("TranslateStream: %v", err)
+	}
+	out := buf.String()
+	sig := strings.Index(out, `"type":"signature_delta"},"index":0`)
+	tool := strings.Index(out, `"type":"tool_use"},"index":1`)
+	if sig < 0 || tool < 0 || sig > tool {
+		t.Errorf("expected signed thinking block 0 before tool_use block 1:\n%s", out)
+	}
+}
+
+func TestStreamInterleavedThinking(t *testing.T) {
+	// Reasoning models may think again between text and tool calls, even
+	// in the middle of a tool call's arguments: each thinking run must be
+	// its own signed block, and the arguments must stay in their block.
+	input := makeSSE(
+		`{"id":"r1","choices":[{"index":0,"delta":{"reasoning_content":"think one"}}]}`,
+		`{"id":"r1","choices":[{"index":0,"delta":{"content":"text one"}}]}`,
+		`{"id":"r1","choices":[{"index":0,"delta":{"reasoning_content":"think two"}}]}`,
+		`{"id":"r1","choices":[{"index":0,"delta":{"content":"text two"}}]}`,
+		toolChunk("call_1", "Read", `{"path"`),
+		`{"id":"r1","choices":[{"index":0,"delta":{"reasoning_content":"think three"}}]}`,
+		`{"id":"r1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":":\"/x\"}"}}]}}]}`,
+		`{"id":"r1","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+	)
+	var buf bytes.Buffer
+	st := NewStreamTranslator("m")
+	st.SetTransformChain(NewTransformChain(newReasoningTransform()), NewTransformContext("m", "deepseek"))
+	if err := st.TranslateStream(strings.NewReader(input), &buf); err != nil {
+		t.Fatalf("TranslateStream: %v", err)
+	}
+	out := buf.String()
+
+	v := NewSSEValidator(func(msg string) { t.Error(msg) })
+	v.Write(buf.Bytes())
+	v.Close()
+
+	want := []string{
+		`"content_block":{"thinking":"","type":"thinking"},"index":0`,
+		`{"delta":{"thinking":"think one","type":"thinking_delta"},"index":0`,
+		`"type":"signature_delta"},"index":0`,
+		`{"delta":{"text":"text one","type":"text_delta"},"index":1`,
+		`"content_block":{"thinking":"","type":"thinking"},"index":2`,
+		`"type":"signature_delta"},"index":2`,
+		`{"delta":{"text":"text two","type":"text_delta"},"index":3`,
+		`"type":"tool_use"},"index":4`,
+		`{"index":4,"type":"content_block_stop"}`,
+		`{"delta":{"thinking":"think three","type":"thinking_delta"},"index":5`,
+		`"type":"signature_delta"},"index":5`,
+	}
+	pos := 0
+	for _, w := range want {
+		i := strings.Index(out[pos:], w)
+		if i < 0 {
+			t.Fatalf("missing or out of order: %s\n%s", w, out)
+		}
+		pos += i + len(w)
+	}
+	if got := assembledToolInput(t, out); got != `{"path":"/x"}` {
+		t.Errorf("tool input = %s, want {\"path\":\"/x\"}", got)
+	}
+}
+
+// cutReader yields s, then fails as a dropped connection does.
+func cutReader(s string) io.Reader {
+	return io.MultiReader(strings.NewReader(s), iotest.ErrReader(io.ErrUnexpectedEOF))
+}
+
+func TestStreamPartialResume(t *testing.T) {
+	usage := `{"id":"r","choices":[],"usage":{"prompt_tokens":%d,"completion_tokens":%d}}`
+	first := "data: " + chunk("r1", strPtr("Hello, wor"), nil) + "\n\n" +
+		"data: " + fmt.Sprintf(usage, 10, 3) + "\n\n"
+	var buf bytes.Buffer
+	st := NewStreamTranslator("m")
+	err := st.TranslatePartial(cutReader(first), &buf)
+	if !errors.Is(err, ErrStreamCut) {
+		t.Fatalf("expected ErrStreamCut, got %v", err)
+	}
+	if strings.Contains(buf.String(), "message_stop") {
+		t.Fatal("cut-off message was finished")
+	}
+	text, ok := st.Resumable()
+	if !ok || text != "Hello, wor" {
+		t.Fatalf("Resumable() = %q, %v", text, ok)
+	}
+
+	st.Resume()
+	second := makeSSE(
+		chunk("r2", strPtr("ld!"), nil),
+		chunk("r2", nil, strPtr("stop")),
+		fmt.Sprintf(usage, 14, 2),
+	)
+	if err := st.TranslatePartial(strings.NewReader(second), &buf); err != nil {
+		t.Fatalf("TranslatePartial: %v", err)
+	}
+	out := buf.String()
+	if n := strings.Count(out, "event: message_start"); n != 1 {
+		t.Errorf("expected one message_start, got %d", n)
+	}
+	if n := strings.Count(out, "event: content_block_start"); n != 1 {
+		t.Errorf("continuation should extend the text block, got %d blocks", n)
+	}
+	if !strings.Contains(out, `"stop_reason":"end_turn"`) || !strings.Contains(out, `"output_tokens":5`) {
+		t.Errorf("expected end_turn with summed output tokens:\n%s", out)
+	}
+	if in, outTok := st.Usage(); in != 24 || outTok != 5 {
+		t.Errorf("Usage() = %d, %d; want 24, 5", in, outTok)
+	}
+}
+
+func TestStreamPartialNotResumableAfterToolCall(t *testing.T) {
+	input := "data: " + toolChunk("call_1", "Read", `{"path":`) + "\n\n"
+	var buf bytes.Buffer
+	st := NewStreamTranslator("m")
+	if err := st.TranslatePartial(cutReader(input), &buf); !errors.Is(err, ErrStreamCut) {
+		t.Fatalf("expected ErrStreamCut, got %v", err)
+	}
+	if _, ok := st.Resumable(); ok {
+		t.Error("a message with a tool call must not be resumable")
+	}
+	st.Finish(&buf)
+	if !strings.Contains(buf.String(), "event: message_stop") {
+		t.Error("Finish did not end the message")
+	}
+}
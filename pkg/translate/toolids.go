@@ -0,0 +1,97 @@
+package translate
+
+import "sync"
+
+// maxToolIDs bounds one ToolIDMap; the oldest mappings are forgotten first.
+const maxToolIDs = 4096
+
+// ToolIDMap remembers the provider's tool call ID behind each tool_use ID
+// the client was sent in its place: one cleaned by sanitizeToolID, or a
+// numeric ID the openrouter and groq transforms replaced. Claude Code sends
+// those IDs back with the tool_use and tool_result blocks of the next turn,
+// and RestoreRequest turns them into the provider's IDs again. Keep one map
+// per conversation. It is safe for concurrent use.
+type ToolIDMap struct {
+	mu    sync.Mutex
+	ids   map[string]string // client ID → provider ID
+	order []string          // client IDs, oldest first
+}
+
+// NewToolIDMap returns an empty map.
+func NewToolIDMap() *ToolIDMap {
+	return &ToolIDMap{ids: make(map[string]string)}
+}
+
+// Record notes that the client sees providerID as clientID.
+func (m *ToolIDMap) Record(clientID, providerID string) {
+	if m == nil || clientID == providerID || clientID == "" {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.ids[clientID]; !ok {
+		if len(m.order) >= maxToolIDs {
+			delete(m.ids, m.order[0])
+			m.order = m.order[1:]
+		}
+		m.order = append(m.order, clientID)
+	}
+	m.ids[clientID] = providerID
+}
+
+// Provider returns the provider's ID for clientID, or clientID itself when
+// it was sent unchanged.
+func (m *ToolIDMap) Provider(clientID string) string {
+	if m == nil {
+		return clientID
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if id, ok := m.ids[clientID]; ok {
+		return id
+	}
+	return clientID
+}
+
+// Len returns the number of remembered IDs.
+func (m *ToolIDMap) Len() int {
+	if m == nil {
+		return 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.ids)
+}
+
+// RestoreRequest rewrites the tool call IDs of an OpenAI request (assistant
+// tool_calls and tool messages) back to the provider's.
+func (m *ToolIDMap) RestoreRequest(req map[string]interface{}) {
+	if m.Len() == 0 {
+		return
+	}
+	msgs, _ := req["messages"].([]interface{})
+	for _, item := range msgs {
+		msg, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if id, ok := msg["tool_call_id"].(string); ok {
+			msg["tool_call_id"] = m.Provider(id)
+		}
+		calls, _ := msg["tool_calls"].([]interface{})
+		for _, c := range calls {
+			if call, ok := c.(map[string]interface{}); ok {
+				if id, ok := call["id"].(string); ok {
+					call["id"] = m.Provider(id)
+				}
+			}
+		}
+	}
+}
+
+// recordToolID notes a rewritten tool call ID in ctx.ToolIDs, if set.
+func (ctx *TransformContext) recordToolID(clientID, providerID string) {
+	if ctx != nil {
+		ctx.ToolIDs.Record(clientID, providerID)
+	}
+}
@@ -0,0 +1,101 @@
+package translate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestToolIDMapRecordAndEvict(t *testing.T) {
+	m := NewToolIDMap()
+	m.Record("same", "same")
+	if m.Len() != 0 {
+		t.Errorf("unchanged ID recorded")
+	}
+	m.Record("functions_Read_0", "functions.Read:0")
+	if got := m.Provider("functions_Read_0"); got != "functions.Read:0" {
+		t.Errorf("Provider = %q", got)
+	}
+	if got := m.Provider("toolu_other"); got != "toolu_other" {
+		t.Errorf("unknown ID = %q, want it unchanged", got)
+	}
+	for i := 0; i < maxToolIDs; i++ {
+		m.Record(fmt.Sprintf("call_%d", i), "p")
+	}
+	if m.Len() > maxToolIDs {
+		t.Errorf("Len = %d, want at most %d", m.Len(), maxToolIDs)
+	}
+	if got := m.Provider("functions_Read_0"); got != "functions_Read_0" {
+		t.Errorf("oldest ID not evicted: %q", got)
+	}
+	var nilMap *ToolIDMap
+	nilMap.Record("a", "b")
+	if nilMap.Provider("a") != "a" {
+		t.Error("nil map should return IDs unchanged")
+	}
+}
+
+// A numeric ID replaced by the openrouter transform, and an ID with
+// characters Anthropic rejects, both go back to the provider as issued.
+func TestToolIDMapRoundTrip(t *testing.T) {
+	ids := NewToolIDMap()
+	ctx := NewTransformContext("m", "openrouter")
+	ctx.ToolIDs = ids
+	chain, _ := BuildChain([]string{"openrouter"})
+	st := NewStreamTranslator("local")
+	st.SetTransformChain(chain, ctx)
+	input := "data: " + `{"id":"g1","choices":[{"index":0,"delta":{"tool_calls":[` +
+		`{"index":0,"id":"0","type":"function","function":{"name":"Bash","arguments":"{}"}}]}}]}` + "\n\n" +
+		"data: " + `{"id":"g1","choices":[{"index":0,"delta":{"tool_calls":[` +
+		`{"index":1,"id":"functions.Read:1","type":"function","function":{"name":"Read","arguments":"{}"}}]}}]}` + "\n\n" +
+		"data: [DONE]\n\n"
+	var out strings.Builder
+	if err := st.TranslateStream(strings.NewReader(input), &out); err != nil {
+		t.Fatal(err)
+	}
+	var clientIDs []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		var ev struct {
+			ContentBlock struct{ ID string } `json:"content_block"`
+		}
+		if strings.HasPrefix(line, "data: ") && json.Unmarshal([]byte(line[6:]), &ev) == nil && ev.ContentBlock.ID != "" {
+			clientIDs = append(clientIDs, ev.ContentBlock.ID)
+		}
+	}
+	if len(clientIDs) != 2 || clientIDs[0] == "0" || clientIDs[1] != "functions_Read_1" {
+		t.Fatalf("client IDs = %v", clientIDs)
+	}
+
+	next, _ := json.Marshal(map[string]interface{}{
+		"model": "x",
+		"messages": []interface{}{
+			map[string]interface{}{"role": "user", "content": "go"},
+			map[string]interface{}{"role": "assistant", "content": []interface{}{
+				map[string]interface{}{"type": "tool_use", "id": clientIDs[0], "name": "Bash", "input": map[string]interface{}{}},
+				map[string]interface{}{"type": "tool_use", "id": clientIDs[1], "name": "Read", "input": map[string]interface{}{}},
+			}},
+			map[string]interface{}{"role": "user", "content": []interface{}{
+				map[string]interface{}{"type": "tool_result", "tool_use_id": clientIDs[0], "content": "ok"},
+				map[string]interface{}{"type": "tool_result", "tool_use_id": clientIDs[1], "content": "ok"},
+			}},
+		},
+	})
+	oBody, err := RequestToOpenAI(next, "m", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req map[string]interface{}
+	json.Unmarshal(oBody, &req)
+	ids.RestoreRequest(req)
+	var got ORequest
+	b, _ := json.Marshal(req)
+	json.Unmarshal(b, &got)
+	calls := got.Messages[1].ToolCalls
+	if calls[0].ID != "0" || calls[1].ID != "functions.Read:1" {
+		t.Errorf("tool_calls IDs = %s, %s", calls[0].ID, calls[1].ID)
+	}
+	if got.Messages[2].ToolCallID != "0" || got.Messages[3].ToolCallID != "functions.Read:1" {
+		t.Errorf("tool message IDs = %s, %s", got.Messages[2].ToolCallID, got.Messages[3].ToolCallID)
+	}
+}
@@ -0,0 +1,153 @@
+package translate
+
+import "strings"
+
+// alternateTransform rewrites the message list for providers that enforce
+// strict user/assistant alternation (Mistral and several vLLM chat
+// templates). Claude Code's turns often break it once translated: a user
+// turn with tool results and text becomes tool messages followed by a user
+// message, and compaction or hooks can leave two user turns in a row.
+//
+//   - consecutive user messages are merged into one
+//   - consecutive assistant messages are merged into one, tool calls and all
+//   - a text-only user message right after tool messages is appended to the
+//     last tool message, since these providers reject user after tool
+type alternateTransform struct{}
+
+func (a *alternateTransform) Name() string { return "alternate" }
+
+func (a *alternateTransform) TransformRequest(req map[string]interface{}, _ *TransformContext) error {
+	msgs, ok := req["messages"].([]interface{})
+	if !ok {
+		return nil
+	}
+	out := make([]interface{}, 0, len(msgs))
+	for _, m := range msgs {
+		msg, ok := m.(map[string]interface{})
+		if !ok || len(out) == 0 {
+			out = append(out, m)
+			continue
+		}
+		prev, ok := out[len(out)-1].(map[string]interface{})
+		if !ok {
+			out = append(out, m)
+			continue
+		}
+		role, _ := msg["role"].(string)
+		prevRole, _ := prev["role"].(string)
+		switch {
+		case role == prevRole && role == "user":
+			prev["content"] = joinContent(prev["content"], msg["content"])
+		case role == prevRole && role == "assistant":
+			mergeAssistant(prev, msg)
+		case role == "user" && prevRole == "tool":
+			text, ok := textOnly(msg["content"])
+			if !ok {
+				out = append(out, m)
+				continue
+			}
+			prev["content"] = joinContent(prev["content"], text)
+		default:
+			out = append(out, m)
+		}
+	}
+	req["messages"] = out
+	return nil
+}
+
+// mergeAssistant folds assistant message msg into prev: content is joined,
+// tool calls are appended, and other string fields (reasoning) are joined.
+func mergeAssistant(prev, msg map[string]interface{}) {
+	for k, v := range msg {
+		switch k {
+		case "role":
+		case "content":
+			prev[k] = joinContent(prev[k], v)
+		case "tool_calls":
+			calls, _ := prev[k].([]interface{})
+			more, _ := v.([]interface{})
+			prev[k] = append(calls, more...)
+		default:
+			s, ok := v.(string)
+			old, hasOld := prev[k].(string)
+			switch {
+			case ok && hasOld && old != "" && s != "":
+				prev[k] = old + "\n" + s
+			case !hasOld || old == "":
+				prev[k] = v
+			}
+		}
+	}
+}
+
+// joinContent joins two message contents, each a string, a content part
+// array or missing. Strings stay strings; otherwise the result is an array.
+func joinContent(a, b interface{}) interface{} {
+	as, aStr := a.(string)
+	bs, bStr := b.(string)
+	if a == nil {
+		as, aStr = "", true
+	}
+	if b == nil {
+		bs, bStr = "", true
+	}
+	if aStr && bStr {
+		switch {
+		case as == "":
+			return b
+		case bs == "":
+			return a
+		}
+		return as + "\n\n" + bs
+	}
+	return append(contentParts(a), contentParts(b)...)
+}
+
+// contentParts returns content as a content part array.
+func contentParts(c interface{}) []interface{} {
+	switch c := c.(type) {
+	case []interface{}:
+		return c
+	case string:
+		if c != "" {
+			return []interface{}{map[string]interface{}{"type": "text", "text": c}}
+		}
+	}
+	return nil
+}
+
+// textOnly returns content's text when it holds nothing but text.
+func textOnly(c interface{}) (string, bool) {
+	switch c := c.(type) {
+	case string:
+		return c, true
+	case []interface{}:
+		var texts []string
+		for _, p := range c {
+			part, ok := p.(map[string]interface{})
+			if !ok || part["type"] != "text" {
+				return "", false
+			}
+			text, _ := part["text"].(string)
+			texts = append(texts, text)
+		}
+		return strings.Join(texts, "\n"), true
+	}
+	return "", false
+}
+
+func (a *alternateTransform) TransformResponse(body []byte, _ *TransformContext) ([]byte, error) {
+	return body, nil
+}
+
+func (a *alternateTransform) TransformStreamChunk(data []byte, _ *TransformContext) ([][]byte, error) {
+	return [][]byte{data}, nil
+}
+
+func (a *alternateTransform) streamIdentity() {}
+
+func init() {
+	RegisterTransform("alternate", func() Transformer {
+		return &alternateTransform{}
+	})
+}
@@ -0,0 +1,128 @@
+package translate
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// mistralOrder checks the message order Mistral's API accepts: system
+// messages first, then user and assistant turns alternating, with an
+// assistant's tool calls answered by tool messages that are followed by the
+// next assistant turn.
+func mistralOrder(t *testing.T, msgs []interface{}) {
+	t.Helper()
+	prev := "system"
+	for i, m := range msgs {
+		msg := m.(map[string]interface{})
+		role := msg["role"].(string)
+		calls, _ := msg["tool_calls"].([]interface{})
+		var ok bool
+		switch prev {
+		case "system":
+			ok = role == "system" || role == "user"
+		case "user":
+			ok = role == "assistant"
+		case "assistant":
+			ok = role == "user"
+		case "assistant+tools", "tool":
+			ok = role == "tool" || (prev == "tool" && role == "assistant")
+		}
+		if !ok {
+			t.Fatalf("message %d: role %s after %s", i, role, prev)
+		}
+		prev = role
+		if role == "assistant" && len(calls) > 0 {
+			prev = "assistant+tools"
+		}
+	}
+}
+
+func runAlternate(t *testing.T, anthropic string) []interface{} {
+	t.Helper()
+	body, err := RequestToOpenAI([]byte(anthropic), "m", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req map[string]interface{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&alternateTransform{}).TransformRequest(req, NewTransformContext("m", "mistral")); err != nil {
+		t.Fatal(err)
+	}
+	return req["messages"].([]interface{})
+}
+
+func TestAlternate_ToolResultsWithText(t *testing.T) {
+	msgs := runAlternate(t, `{"model": "x", "system": "sys", "messages": [
+		{"role": "user", "content": "list files"},
+		{"role": "assistant", "content": [{"type": "tool_use", "id": "t1", "name": "ls", "input": {}}]},
+		{"role": "user", "content": [
+			{"type": "tool_result", "tool_use_id": "t1", "content": "a.go"},
+			{"type": "text", "text": "now read it"}
+		]}
+	]}`)
+	mistralOrder(t, msgs)
+	if len(msgs) != 4 {
+		t.Fatalf("got %d messages, want 4: %v", len(msgs), msgs)
+	}
+	last := msgs[3].(map[string]interface{})
+	if last["role"] != "tool" || last["content"] != "a.go\n\nnow read it" {
+		t.Errorf("user text not folded into the tool result: %v", last)
+	}
+}
+
+func TestAlternate_ConsecutiveTurns(t *testing.T) {
+	msgs := runAlternate(t, `{"model": "x", "messages": [
+		{"role": "user", "content": "one"},
+		{"role": "user", "content": [{"type": "text", "text": "two"}]},
+		{"role": "assistant", "content": "thinking aloud"},
+		{"role": "assistant", "content": [{"type": "tool_use", "id": "t1", "name": "ls", "input": {}}]},
+		{"role": "user", "content": [{"type": "tool_result", "tool_use_id": "t1", "content": "ok"}]},
+		{"role": "assistant", "content": "done"},
+		{"role": "user", "content": "thanks"}
+	]}`)
+	mistralOrder(t, msgs)
+	first := msgs[0].(map[string]interface{})
+	if first["content"] != "one\n\ntwo" {
+		t.Errorf("user turns not merged: %v", first)
+	}
+	asst := msgs[1].(map[string]interface{})
+	calls, _ := asst["tool_calls"].([]interface{})
+	if asst["content"] != "thinking aloud" || len(calls) != 1 {
+		t.Errorf("assistant turns not merged: %v", asst)
+	}
+}
+
+func TestAlternate_KeepsImages(t *testing.T) {
+	// A user message with an image can't be folded into a tool result.
+	req := map[string]interface{}{"messages": []interface{}{
+		map[string]interface{}{"role": "tool", "tool_call_id": "t1", "content": "ok"},
+		map[string]interface{}{"role": "user", "content": []interface{}{
+			map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "data:"}},
+		}},
+	}}
+	(&alternateTransform{}).TransformRequest(req, nil)
+	if n := len(req["messages"].([]interface{})); n != 2 {
+		t.Errorf("got %d messages, want 2", n)
+	}
+}
+
+func TestJoinContent(t *testing.T) {
+	part := func(s string) interface{} { return map[string]interface{}{"type": "text", "text": s} }
+	tests := []struct {
+		a, b, want interface{}
+	}{
+		{"a", "b", "a\n\nb"},
+		{nil, "b", "b"},
+		{"a", "", "a"},
+		{"a", []interface{}{part("b")}, []interface{}{part("a"), part("b")}},
+		{[]interface{}{part("a")}, nil, []interface{}{part("a")}},
+	}
+	for _, tc := range tests {
+		if got := joinContent(tc.a, tc.b); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("joinContent(%v, %v) = %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
@@ -36,6 +36,8 @@ func (c *cleanCacheTransform) TransformStreamChunk(data []byte, _ *TransformCont
 	return [][]byte{data}, nil
 }
 
+func (c *cleanCacheTransform) streamIdentity() {}
+
 func init() {
 	RegisterTransform("cleancache", func() Transformer {
 		return &cleanCacheTransform{}
@@ -23,6 +23,8 @@ func (c *customParamsTransform) TransformStreamChunk(data []byte, _ *TransformCo
 	return [][]byte{data}, nil
 }
 
+func (c *customParamsTransform) streamIdentity() {}
+
 func init() {
 	RegisterTransform("customparams", func() Transformer {
 		return &customParamsTransform{}
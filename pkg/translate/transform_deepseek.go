@@ -26,6 +26,8 @@ func (d *deepseekTransform) TransformStreamChunk(data []byte, ctx *TransformCont
 	return [][]byte{data}, nil
 }
 
+func (d *deepseekTransform) streamIdentity() {}
+
 func init() {
 	RegisterTransform("deepseek", func() Transformer {
 		return newDeepseekTransform()
@@ -1,9 +1,11 @@
 package translate
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strings"
 )
 
 const maxToolCallBufferSize = 1 << 20 // 1MB
@@ -64,6 +66,9 @@ func (e *enhancetoolTransform) TransformResponse(body []byte, ctx *TransformCont
 		if fixed != args {
 			fn["arguments"] = fixed
 			changed = true
+			if isMalformedJSON(args) {
+				ctx.recordRepair(e.Name())
+			}
 		}
 	}
 
@@ -78,8 +83,16 @@ func (e *enhancetoolTransform) TransformResponse(body []byte, ctx *TransformCont
 	return out, nil
 }
 
-// TransformStreamChunk buffers tool call arguments and repairs them on finish.
+// TransformStreamChunk buffers tool calls and emits them whole, with repaired
+// arguments, once the provider finishes. A chunk that starts a call passes
+// on without its tool_calls, so the rest of its delta still arrives; one
+// holding only argument fragments is suppressed.
 func (e *enhancetoolTransform) TransformStreamChunk(data []byte, ctx *TransformContext) ([][]byte, error) {
+	// Only tool-call chunks, and the finishing chunk while calls are
+	// buffered, need decoding.
+	if len(ctx.ToolCallBuffers) == 0 && !bytes.Contains(data, toolCallsKey) {
+		return [][]byte{data}, nil
+	}
 	var parsed map[string]interface{}
 	if err := json.Unmarshal(data, &parsed); err != nil {
 		return [][]byte{data}, nil
@@ -93,90 +106,108 @@ func (e *enhancetoolTransform) TransformStreamChunk(data []byte, ctx *TransformC
 	if !ok {
 		return [][]byte{data}, nil
 	}
-	delta, ok := choice["delta"].(map[string]interface{})
-	if !ok {
-		return [][]byte{data}, nil
-	}
+	delta, _ := choice["delta"].(map[string]interface{})
 
-	// Check for finish_reason = "tool_calls" — flush all buffers.
-	if fr, ok := choice["finish_reason"].(string); ok && fr == "tool_calls" {
-		if len(ctx.ToolCallBuffers) == 0 {
-			return [][]byte{data}, nil
+	// Buffer every tool call delta in the chunk.
+	tcArr, _ := delta["tool_calls"].([]interface{})
+	var passthrough []interface{}
+	started := false
+	for _, item := range tcArr {
+		tc, ok := item.(map[string]interface{})
+		if !ok {
+			passthrough = append(passthrough, item)
+			continue
 		}
-
-		// Build repaired tool calls chunk.
-		repairedChunk, err := e.flushBuffers(ctx)
-		if err != nil {
-			return nil, err
+		idx := 0
+		if idxVal, ok := tc["index"].(float64); ok {
+			idx = int(idxVal)
 		}
-		return [][]byte{repairedChunk, data}, nil
-	}
-
-	// Check for tool_calls in delta.
-	tcArr, ok := delta["tool_calls"].([]interface{})
-	if !ok || len(tcArr) == 0 {
-		return [][]byte{data}, nil
-	}
+		fn, _ := tc["function"].(map[string]interface{})
+		args, _ := fn["arguments"].(string)
 
-	tc, ok := tcArr[0].(map[string]interface{})
-	if !ok {
-		return [][]byte{data}, nil
-	}
+		// New tool call start: has "id" field.
+		if id, ok := tc["id"].(string); ok {
+			name, _ := fn["name"].(string)
+			buf := &ToolCallBuffer{ID: id, Name: name}
+			buf.Arguments.WriteString(args)
+			ctx.ToolCallBuffers[idx] = buf
+			started = true
+			continue
+		}
 
-	idx := 0
-	if idxVal, ok := tc["index"].(float64); ok {
-		idx = int(idxVal)
+		// Argument fragment: no id, just function.arguments.
+		buf, exists := ctx.ToolCallBuffers[idx]
+		if !exists {
+			// No buffer for this index — pass through.
+			passthrough = append(passthrough, item)
+			continue
+		}
+		buf.Arguments.WriteString(args)
 	}
 
-	// New tool call start: has "id" field.
-	if id, ok := tc["id"].(string); ok {
-		name := ""
-		if fn, ok := tc["function"].(map[string]interface{}); ok {
-			if n, ok := fn["name"].(string); ok {
-				name = n
+	var out [][]byte
+	// Buffer guard: flush if any call exceeds 1MB.
+	for _, buf := range ctx.ToolCallBuffers {
+		if buf.Arguments.Len() > maxToolCallBufferSize {
+			repairedChunk, err := e.flushBuffers(ctx)
+			if err != nil {
+				return nil, err
 			}
+			out = append(out, repairedChunk)
+			break
 		}
-		ctx.ToolCallBuffers[idx] = &ToolCallBuffer{
-			ID:   id,
-			Name: name,
-		}
+	}
 
-		// Pass through with arguments cleared.
-		if fn, ok := tc["function"].(map[string]interface{}); ok {
-			fn["arguments"] = ""
-		}
-		out, err := json.Marshal(parsed)
+	// Any finish_reason ends the tool calls, whatever the provider calls it:
+	// flush the buffers ahead of the finishing chunk.
+	if fr, ok := choice["finish_reason"].(string); ok && fr != "" && len(ctx.ToolCallBuffers) > 0 {
+		repairedChunk, err := e.flushBuffers(ctx)
 		if err != nil {
-			return [][]byte{data}, nil
+			return nil, err
 		}
-		return [][]byte{out}, nil
+		out = append(out, repairedChunk)
 	}
 
-	// Argument fragment: no id, just function.arguments.
-	if fn, ok := tc["function"].(map[string]interface{}); ok {
-		if args, ok := fn["arguments"].(string); ok {
-			buf, exists := ctx.ToolCallBuffers[idx]
-			if !exists {
-				// No buffer for this index — pass through.
-				return [][]byte{data}, nil
-			}
-			buf.Arguments.WriteString(args)
-
-			// Buffer guard: flush if exceeds 1MB.
-			if buf.Arguments.Len() > maxToolCallBufferSize {
-				repairedChunk, err := e.flushBuffers(ctx)
-				if err != nil {
-					return nil, err
-				}
-				return [][]byte{repairedChunk}, nil
-			}
+	if len(tcArr) == 0 {
+		return append(out, data), nil
+	}
+	if len(passthrough) > 0 {
+		delta["tool_calls"] = passthrough
+	} else {
+		delete(delta, "tool_calls")
+	}
+	if !started && choice["finish_reason"] == nil && !hasDeltaPayload(delta) {
+		// Suppress the chunk.
+		return out, nil
+	}
+	rest, err := json.Marshal(parsed)
+	if err != nil {
+		return [][]byte{data}, nil
+	}
+	return append(out, rest), nil
+}
 
-			// Suppress the chunk.
-			return nil, nil
+// hasDeltaPayload reports whether a delta carries anything besides its role.
+func hasDeltaPayload(delta map[string]interface{}) bool {
+	for k, v := range delta {
+		if k != "role" && v != nil && v != "" {
+			return true
 		}
 	}
+	return false
+}
 
-	return [][]byte{data}, nil
+// flushStream emits the tool calls still buffered when the provider stream
+// ends without a finish_reason.
+func (e *enhancetoolTransform) flushStream(ctx *TransformContext) ([][]byte, error) {
+	if len(ctx.ToolCallBuffers) == 0 {
+		return nil, nil
+	}
+	repairedChunk, err := e.flushBuffers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{repairedChunk}, nil
 }
 
 // flushBuffers builds a single chunk containing all repaired tool calls.
@@ -191,7 +222,11 @@ func (e *enhancetoolTransform) flushBuffers(ctx *TransformContext) ([]byte, erro
 	toolCalls := make([]interface{}, 0, len(indices))
 	for _, idx := range indices {
 		buf := ctx.ToolCallBuffers[idx]
-		repaired := FixJSON(buf.Arguments.String())
+		args := buf.Arguments.String()
+		if isMalformedJSON(args) {
+			ctx.recordRepair(e.Name())
+		}
+		repaired := FixJSON(args)
 		toolCalls = append(toolCalls, map[string]interface{}{
 			"index": idx,
 			"id":    buf.ID,
@@ -224,6 +259,13 @@ func (e *enhancetoolTransform) flushBuffers(ctx *TransformContext) ([]byte, erro
 	return out, nil
 }
 
+// isMalformedJSON reports whether FixJSON has real work to do on s. Empty
+// arguments (no-arg tools) and surrounding whitespace don't count.
+func isMalformedJSON(s string) bool {
+	s = strings.TrimSpace(s)
+	return s != "" && !json.Valid([]byte(s))
+}
+
 func init() {
 	RegisterTransform("enhancetool", func() Transformer {
 		return newEnhancetoolTransform()
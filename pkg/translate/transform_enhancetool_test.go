@@ -1,7 +1,9 @@
 package translate
 
 import (
+	"bytes"
 	"encoding/json"
+	"strings"
 	"testing"
 )
 
@@ -345,3 +347,62 @@ func TestEnhancetoolStream_NoToolCalls(t *testing.T) {
 		t.Errorf("content = %q, want %q", delta["content"], "Hello world")
 	}
 }
+
+// enhancetoolStream runs chunks through a StreamTranslator with enhancetool
+// and returns the Anthropic SSE it writes, checked for event order.
+func enhancetoolStream(t *testing.T, chunks ...string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	st := NewStreamTranslator("m")
+	st.SetTransformChain(NewTransformChain(newEnhancetoolTransform()), NewTransformContext("m", "llamacpp"))
+	if err := st.TranslateStream(strings.NewReader(makeSSE(chunks...)), &buf); err != nil {
+		t.Fatalf("TranslateStream: %v", err)
+	}
+	v := NewSSEValidator(func(msg string) { t.Error(msg) })
+	v.Write(buf.Bytes())
+	v.Close()
+	return buf.String()
+}
+
+func TestEnhancetoolStream_OneToolUseBlock(t *testing.T) {
+	finish := func(reason string) string {
+		return `{"id":"r1","choices":[{"index":0,"delta":{},"finish_reason":"` + reason + `"}]}`
+	}
+	fragment := `{"id":"r1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"/x\"}"}}]}}]}`
+	tests := []struct {
+		name   string
+		chunks []string
+	}{
+		// The start chunk carries the first arguments; they must not be lost.
+		{"tool_calls finish", []string{toolChunk("call_1", "Read", `{"path":`), fragment, finish("tool_calls")}},
+		{"other finish", []string{toolChunk("call_1", "Read", `{"path":`), fragment, finish("stop")}},
+		{"no finish", []string{toolChunk("call_1", "Read", `{"path":`), fragment}},
+		{"all in start", []string{toolChunk("call_1", "Read", `{"path":"/x"}`), finish("tool_calls")}},
+	}
+	for _, tc := range tests {
+		out := enhancetoolStream(t, tc.chunks...)
+		if n := strings.Count(out, `"type":"tool_use"`); n != 1 {
+			t.Errorf("%s: %d tool_use blocks, want 1:\n%s", tc.name, n, out)
+		}
+		if got := assembledToolInput(t, out); got != `{"path":"/x"}` {
+			t.Errorf("%s: tool input = %s, want {\"path\":\"/x\"}", tc.name, got)
+		}
+	}
+}
+
+func TestEnhancetoolStream_ParallelCalls(t *testing.T) {
+	out := enhancetoolStream(t,
+		`{"id":"r1","choices":[{"index":0,"delta":{"tool_calls":[`+
+			`{"index":0,"id":"call_a","function":{"name":"Read","arguments":"{\"path\":\"/a\"}"}},`+
+			`{"index":1,"id":"call_b","function":{"name":"Read","arguments":"{\"path\":"}}]}}]}`,
+		`{"id":"r1","choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"function":{"arguments":"\"/b\""}}]}}]}`,
+		`{"id":"r1","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+	)
+	if n := strings.Count(out, `"type":"tool_use"`); n != 2 {
+		t.Errorf("%d tool_use blocks, want 2:\n%s", n, out)
+	}
+	// The second call's arguments end unterminated and are repaired.
+	if got := assembledToolInput(t, out); got != `{"path":"/a"}{"path":"/b"}` {
+		t.Errorf("tool inputs = %s", got)
+	}
+}
@@ -1,8 +1,8 @@
 package translate
 
 import (
+	"bytes"
 	"encoding/json"
-	"strconv"
 )
 
 // groqTransform strips cache_control, $schema from requests and fixes numeric tool call IDs.
@@ -46,6 +46,10 @@ func (g *groqTransform) TransformResponse(body []byte, ctx *TransformContext) ([
 
 // TransformStreamChunk fixes numeric tool call IDs and adjusts choice index.
 func (g *groqTransform) TransformStreamChunk(data []byte, ctx *TransformContext) ([][]byte, error) {
+	// Only tool-call chunks (deltas or finish_reason) need decoding.
+	if !bytes.Contains(data, toolCallsKey) {
+		return [][]byte{data}, nil
+	}
 	var chunk map[string]interface{}
 	if err := json.Unmarshal(data, &chunk); err != nil {
 		return [][]byte{data}, nil
@@ -70,13 +74,10 @@ func (g *groqTransform) TransformStreamChunk(data []byte, ctx *TransformContext)
 
 	modified := false
 
-	// Fix numeric tool call ID.
-	if tc, ok := toolCalls[0].(map[string]interface{}); ok {
-		if id, ok := tc["id"].(string); ok {
-			if _, err := strconv.Atoi(id); err == nil {
-				tc["id"] = "call_" + randomHex(12)
-				modified = true
-			}
+	// Fix numeric tool call IDs.
+	for _, item := range toolCalls {
+		if tc, ok := item.(map[string]interface{}); ok && fixNumericToolID(tc, ctx) {
+			modified = true
 		}
 	}
 
@@ -20,9 +20,13 @@ func newOpenRouterTransform() *openRouterTransform {
 
 func (o *openRouterTransform) Name() string { return "openrouter" }
 
-// TransformRequest is a no-op. Cache_control stripping for non-Claude models
-// is handled by the cleancache transform in the chain.
-func (o *openRouterTransform) TransformRequest(_ map[string]interface{}, _ *TransformContext) error {
+// TransformRequest sends thinking from earlier turns back in the reasoning
+// field OpenRouter reads on assistant messages. Cache_control stripping for
+// non-Claude models is handled by the cleancache transform in the chain.
+func (o *openRouterTransform) TransformRequest(req map[string]interface{}, _ *TransformContext) error {
+	takePriorThinking(req, func(msg map[string]interface{}, thinking string) {
+		msg["reasoning"] = thinking
+	})
 	return nil
 }
 
@@ -54,7 +58,7 @@ func (o *openRouterTransform) TransformResponse(body []byte, ctx *TransformConte
 			if !ok {
 				continue
 			}
-			if fixNumericToolID(tcMap) {
+			if fixNumericToolID(tcMap, ctx) {
 				changed = true
 			}
 		}
@@ -100,7 +104,7 @@ func (o *openRouterTransform) TransformStreamChunk(data []byte, ctx *TransformCo
 				if !ok {
 					continue
 				}
-				if fixNumericToolID(tcMap) {
+				if fixNumericToolID(tcMap, ctx) {
 					changed = true
 				}
 			}
@@ -141,9 +145,9 @@ func (o *openRouterTransform) TransformStreamChunk(data []byte, ctx *TransformCo
 	return [][]byte{out}, nil
 }
 
-// fixNumericToolID checks if the "id" field is numeric and replaces it with a random call ID.
-// Returns true if a change was made.
-func fixNumericToolID(tc map[string]interface{}) bool {
+// fixNumericToolID checks if the "id" field is numeric and replaces it with a
+// random call ID, recorded in ctx.ToolIDs. Returns true if a change was made.
+func fixNumericToolID(tc map[string]interface{}, ctx *TransformContext) bool {
 	idVal, ok := tc["id"]
 	if !ok {
 		return false
@@ -155,7 +159,9 @@ func fixNumericToolID(tc map[string]interface{}) bool {
 	if _, err := strconv.Atoi(idStr); err != nil {
 		return false
 	}
-	tc["id"] = "call_" + randomHex(12)
+	id := "call_" + randomHex(12)
+	ctx.recordToolID(id, idStr)
+	tc["id"] = id
 	return true
 }
 
@@ -6,8 +6,9 @@ import (
 	"time"
 )
 
-// reasoningTransform converts reasoning_content (DeepSeek R1, Qwen QwQ, etc.)
-// into Anthropic-style thinking blocks.
+// reasoningTransform converts provider reasoning fields (reasoning_content,
+// reasoning, reasoning_details, reasoning_summary, Gemini thought parts; see
+// extractReasoning) into Anthropic-style thinking blocks.
 type reasoningTransform struct{}
 
 func newReasoningTransform() *reasoningTransform {
@@ -16,8 +17,13 @@ func newReasoningTransform() *reasoningTransform {
 
 func (r *reasoningTransform) Name() string { return "reasoning" }
 
-// TransformRequest maps reasoning.max_tokens → thinking.budget_tokens.
+// TransformRequest maps reasoning.max_tokens → thinking.budget_tokens, and
+// sends thinking from earlier turns back as reasoning_content.
 func (r *reasoningTransform) TransformRequest(req map[string]interface{}, ctx *TransformContext) error {
+	takePriorThinking(req, func(msg map[string]interface{}, thinking string) {
+		msg["reasoning_content"] = thinking
+	})
+
 	reasoning, ok := req["reasoning"].(map[string]interface{})
 	if !ok {
 		return nil
@@ -34,7 +40,7 @@ func (r *reasoningTransform) TransformRequest(req map[string]interface{}, ctx *T
 	return nil
 }
 
-// TransformResponse moves reasoning_content from message to thinking in non-streaming responses.
+// TransformResponse moves reasoning from message to thinking in non-streaming responses.
 func (r *reasoningTransform) TransformResponse(body []byte, ctx *TransformContext) ([]byte, error) {
 	var parsed map[string]interface{}
 	if err := json.Unmarshal(body, &parsed); err != nil {
@@ -53,15 +59,15 @@ func (r *reasoningTransform) TransformResponse(body []byte, ctx *TransformContex
 	if !ok {
 		return body, nil
 	}
-	rc, ok := msg["reasoning_content"].(string)
+	rc, ok := extractReasoning(msg)
 	if !ok {
 		return body, nil
 	}
-
-	msg["thinking"] = map[string]interface{}{
-		"content": rc,
+	if rc != "" {
+		msg["thinking"] = map[string]interface{}{
+			"content": rc,
+		}
 	}
-	delete(msg, "reasoning_content")
 
 	out, err := json.Marshal(parsed)
 	if err != nil {
@@ -70,8 +76,8 @@ func (r *reasoningTransform) TransformResponse(body []byte, ctx *TransformContex
 	return out, nil
 }
 
-// TransformStreamChunk rewrites reasoning_content deltas to thinking deltas
-// and emits a thinking-close chunk at the reasoning→content boundary.
+// TransformStreamChunk rewrites reasoning deltas to thinking deltas and emits
+// a thinking-close chunk at the reasoning→content boundary.
 func (r *reasoningTransform) TransformStreamChunk(data []byte, ctx *TransformContext) ([][]byte, error) {
 	var parsed map[string]interface{}
 	if err := json.Unmarshal(data, &parsed); err != nil {
@@ -91,12 +97,12 @@ func (r *reasoningTransform) TransformStreamChunk(data []byte, ctx *TransformCon
 		return [][]byte{data}, nil
 	}
 
-	// Case 1: reasoning_content present — rewrite to thinking delta.
-	if rc, ok := delta["reasoning_content"].(string); ok {
+	// Case 1: reasoning present — rewrite to thinking delta. Empty reasoning
+	// fields (sent alongside content by some providers) are just dropped.
+	if rc, ok := extractReasoning(delta); ok && rc != "" {
 		delta["thinking"] = map[string]interface{}{
 			"content": rc,
 		}
-		delete(delta, "reasoning_content")
 		ctx.ReasoningContent.WriteString(rc)
 
 		out, err := json.Marshal(parsed)
@@ -2,6 +2,8 @@ package translate
 
 import (
 	"encoding/json"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -296,3 +298,113 @@ func TestReasoningRequest_NoReasoning(t *testing.T) {
 		t.Error("thinking should not be set when no reasoning present")
 	}
 }
+
+// reasoningFixtures are message/delta bodies as each provider sends them.
+var reasoningFixtures = []struct {
+	provider string
+	body     string
+	want     string
+}{
+	{"deepseek", `{"role":"assistant","reasoning_content":"Let me think."}`, "Let me think."},
+	{"openrouter", `{"role":"assistant","reasoning":"Let me think.","reasoning_details":[{"type":"reasoning.text","text":"Let me think.","format":"unknown","index":0}]}`, "Let me think."},
+	{"openrouter-details", `{"role":"assistant","reasoning_details":[{"type":"reasoning.summary","summary":"Compared options. "},{"type":"reasoning.encrypted","data":"gAAAA..."},{"type":"reasoning.text","text":"Picked B."}]}`, "Compared options. Picked B."},
+	{"openai", `{"role":"assistant","reasoning_summary":[{"type":"summary_text","text":"Checked inputs."},{"type":"summary_text","text":"Chose the fast path."}]}`, "Checked inputs.\n\nChose the fast path."},
+	{"openai-string", `{"role":"assistant","reasoning_summary":"Checked inputs."}`, "Checked inputs."},
+	{"gemini", `{"role":"assistant","content":"Considering the file layout.","extra_content":{"google":{"thought":true}}}`, "Considering the file layout."},
+}
+
+func TestReasoningProviderVariants(t *testing.T) {
+	for _, f := range reasoningFixtures {
+		t.Run(f.provider+"/stream", func(t *testing.T) {
+			ctx := NewTransformContext("m", f.provider)
+			chunk := []byte(`{"choices":[{"index":0,"delta":` + f.body + `}]}`)
+			out, err := newReasoningTransform().TransformStreamChunk(chunk, ctx)
+			if err != nil || len(out) != 1 {
+				t.Fatalf("got %d chunks, err %v", len(out), err)
+			}
+			var parsed struct {
+				Choices []struct {
+					Delta map[string]interface{} `json:"delta"`
+				} `json:"choices"`
+			}
+			json.Unmarshal(out[0], &parsed)
+			delta := parsed.Choices[0].Delta
+			thinking, _ := delta["thinking"].(map[string]interface{})
+			if thinking["content"] != f.want {
+				t.Errorf("thinking = %v, want %q", delta["thinking"], f.want)
+			}
+			for _, k := range []string{"reasoning", "reasoning_content", "reasoning_details", "reasoning_summary", "content", "extra_content"} {
+				if _, ok := delta[k]; ok {
+					t.Errorf("%s left in delta: %v", k, delta)
+				}
+			}
+		})
+		t.Run(f.provider+"/response", func(t *testing.T) {
+			body := []byte(`{"id":"r1","choices":[{"index":0,"message":` + f.body + `,"finish_reason":"stop"}]}`)
+			out, err := newReasoningTransform().TransformResponse(body, NewTransformContext("m", f.provider))
+			if err != nil {
+				t.Fatal(err)
+			}
+			aBody, err := ResponseToAnthropic(out, "m")
+			if err != nil {
+				t.Fatalf("ResponseToAnthropic: %v", err)
+			}
+			var resp AResponse
+			json.Unmarshal(aBody, &resp)
+			if len(resp.Content) == 0 || resp.Content[0].Type != "thinking" || resp.Content[0].Thinking != f.want {
+				t.Errorf("content = %+v, want one thinking block %q", resp.Content, f.want)
+			}
+		})
+	}
+}
+
+func TestReasoningStreamChunk_EmptyReasoningWithContent(t *testing.T) {
+	// DeepSeek sends reasoning_content: "" alongside the first answer chunk.
+	ctx := NewTransformContext("deepseek-chat", "deepseek")
+	chunk := []byte(`{"choices":[{"index":0,"delta":{"content":"Hi","reasoning_content":""}}]}`)
+	out, err := newReasoningTransform().TransformStreamChunk(chunk, ctx)
+	if err != nil || len(out) != 1 {
+		t.Fatalf("got %d chunks, err %v", len(out), err)
+	}
+	if strings.Contains(string(out[0]), `"thinking"`) {
+		t.Errorf("empty reasoning should not produce thinking: %s", out[0])
+	}
+	if !ctx.HasTextContent {
+		t.Error("content chunk should be recognised as text")
+	}
+}
+
+func TestPriorThinkingRequest(t *testing.T) {
+	tests := []struct {
+		chain []string
+		want  map[string]interface{} // expected assistant message fields
+	}{
+		{[]string{"reasoning"}, map[string]interface{}{"content": "4", "reasoning_content": "basic arithmetic"}},
+		{[]string{"openrouter", "reasoning"}, map[string]interface{}{"content": "4", "reasoning": "basic arithmetic"}},
+		{[]string{"extrathinktag"}, map[string]interface{}{"content": "<think>basic arithmetic</think>\n4"}},
+		{[]string{"cleancache"}, map[string]interface{}{"content": "4", "thinking": "basic arithmetic"}},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.chain, ","), func(t *testing.T) {
+			chain, err := BuildChain(tt.chain)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := map[string]interface{}{
+				"messages": []interface{}{
+					map[string]interface{}{"role": "user", "content": "2+2?"},
+					map[string]interface{}{"role": "assistant", "content": "4", "thinking": "basic arithmetic"},
+					map[string]interface{}{"role": "user", "content": "Why?"},
+				},
+			}
+			if err := chain.RunRequest(req, NewTransformContext("m", "p")); err != nil {
+				t.Fatalf("RunRequest: %v", err)
+			}
+			got := req["messages"].([]interface{})[1].(map[string]interface{})
+			delete(got, "role")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("assistant message = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -10,6 +10,12 @@ func RegisterTransform(name string, ctor func() Transformer) {
 	transformRegistry[name] = ctor
 }
 
+// HasTransform reports whether a transform is registered under name.
+func HasTransform(name string) bool {
+	_, ok := transformRegistry[name]
+	return ok
+}
+
 // BuildChain creates a TransformChain from a list of registered transform names.
 // Returns an error if any name is not found in the registry.
 func BuildChain(names []string) (*TransformChain, error) {
@@ -63,6 +69,8 @@ func (s *schemaTransform) TransformStreamChunk(data []byte, ctx *TransformContex
 	return [][]byte{data}, nil
 }
 
+func (s *schemaTransform) streamIdentity() {}
+
 func init() {
 	RegisterTransform("schema:generic", func() Transformer {
 		return &schemaTransform{
@@ -0,0 +1,91 @@
+package translate
+
+import (
+	"sort"
+	"sync"
+)
+
+// TransformStats counts transform outcomes across requests, keyed by
+// transform, provider and model. A chain records into it when the request's
+// TransformContext has Stats set; it is safe for concurrent use.
+type TransformStats struct {
+	mu     sync.Mutex
+	counts map[transformKey]*TransformCount
+}
+
+type transformKey struct {
+	transform, provider, model string
+}
+
+// TransformCount is one row of a TransformStats snapshot.
+type TransformCount struct {
+	Transform string `json:"transform"`
+	Provider  string `json:"provider"`
+	Model     string `json:"model"`
+	// Errors counts calls that returned an error. Request and response
+	// errors fail the request; stream errors drop the chunk.
+	Errors int64 `json:"errors"`
+	// Suppressed counts stream chunks the transform swallowed. Transforms
+	// that buffer (enhancetool, extrathinktag, tooluse) do this by design;
+	// it matters when the count climbs for one that shouldn't.
+	Suppressed int64 `json:"suppressed"`
+	// Repairs counts outputs the transform had to fix, such as malformed
+	// tool call JSON.
+	Repairs int64 `json:"repairs"`
+}
+
+// NewTransformStats creates an empty TransformStats.
+func NewTransformStats() *TransformStats {
+	return &TransformStats{counts: make(map[transformKey]*TransformCount)}
+}
+
+func (s *TransformStats) add(transform string, ctx *TransformContext, f func(*TransformCount)) {
+	k := transformKey{transform, ctx.ProviderName, ctx.ModelName}
+	s.mu.Lock()
+	c, ok := s.counts[k]
+	if !ok {
+		c = &TransformCount{Transform: transform, Provider: ctx.ProviderName, Model: ctx.ModelName}
+		s.counts[k] = c
+	}
+	f(c)
+	s.mu.Unlock()
+}
+
+// Snapshot returns the current counts sorted by transform, provider, model.
+func (s *TransformStats) Snapshot() []TransformCount {
+	s.mu.Lock()
+	out := make([]TransformCount, 0, len(s.counts))
+	for _, c := range s.counts {
+		out = append(out, *c)
+	}
+	s.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Transform != b.Transform {
+			return a.Transform < b.Transform
+		}
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		return a.Model < b.Model
+	})
+	return out
+}
+
+func (c *TransformContext) recordError(transform string) {
+	if c.Stats != nil {
+		c.Stats.add(transform, c, func(tc *TransformCount) { tc.Errors++ })
+	}
+}
+
+func (c *TransformContext) recordSuppressed(transform string) {
+	if c.Stats != nil {
+		c.Stats.add(transform, c, func(tc *TransformCount) { tc.Suppressed++ })
+	}
+}
+
+func (c *TransformContext) recordRepair(transform string) {
+	if c.Stats != nil {
+		c.Stats.add(transform, c, func(tc *TransformCount) { tc.Repairs++ })
+	}
+}
@@ -0,0 +1,68 @@
+package translate
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTransformStatsCountsChainOutcomes(t *testing.T) {
+	stats := NewTransformStats()
+	failing := &mockTransformer{
+		name:      "failing",
+		requestFn: func(map[string]interface{}, *TransformContext) error { return fmt.Errorf("boom") },
+		streamChunkFn: func(data []byte, _ *TransformContext) ([][]byte, error) {
+			if string(data) == "bad" {
+				return nil, fmt.Errorf("bad chunk")
+			}
+			return nil, nil
+		},
+	}
+	chain := NewTransformChain(&mockTransformer{name: "pass"}, failing)
+	ctx := NewTransformContext("qwen", "vllm")
+	ctx.Stats = stats
+
+	chain.RunRequest(map[string]interface{}{}, ctx)
+	chain.RunStreamChunk([]byte("ok"), ctx)
+	chain.RunStreamChunk([]byte("ok"), ctx)
+	chain.RunStreamChunk([]byte("bad"), ctx)
+
+	got := stats.Snapshot()
+	if len(got) != 1 {
+		t.Fatalf("expected only the failing transform to be counted, got %+v", got)
+	}
+	want := TransformCount{Transform: "failing", Provider: "vllm", Model: "qwen", Errors: 2, Suppressed: 2}
+	if got[0] != want {
+		t.Errorf("got %+v, want %+v", got[0], want)
+	}
+}
+
+func TestTransformStatsEnhancetoolRepairs(t *testing.T) {
+	stats := NewTransformStats()
+	chain := NewTransformChain(newEnhancetoolTransform())
+	ctx := NewTransformContext("m", "p")
+	ctx.Stats = stats
+
+	body := []byte(`{"choices":[{"message":{"tool_calls":[` +
+		`{"function":{"name":"a","arguments":"{\"x\": 1,}"}},` +
+		`{"function":{"name":"b","arguments":"{\"x\": 1}"}},` +
+		`{"function":{"name":"c","arguments":""}}]}}]}`)
+	if _, err := chain.RunResponse(body, ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	got := stats.Snapshot()
+	if len(got) != 1 || got[0].Repairs != 1 {
+		t.Errorf("expected 1 repair (valid and empty arguments don't count), got %+v", got)
+	}
+}
+
+func TestTransformStatsNilIsNoop(t *testing.T) {
+	failing := &mockTransformer{
+		name:      "failing",
+		requestFn: func(map[string]interface{}, *TransformContext) error { return fmt.Errorf("boom") },
+	}
+	ctx := NewTransformContext("m", "p")
+	if err := NewTransformChain(failing).RunRequest(map[string]interface{}{}, ctx); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -30,8 +30,13 @@ func newThinkTagTransform() *thinkTagTransform {
 
 func (t *thinkTagTransform) Name() string { return "extrathinktag" }
 
-// TransformRequest is a no-op.
-func (t *thinkTagTransform) TransformRequest(_ map[string]interface{}, _ *TransformContext) error {
+// TransformRequest puts thinking from earlier turns back into the assistant
+// content as the <think> tags the model wrote it in.
+func (t *thinkTagTransform) TransformRequest(req map[string]interface{}, _ *TransformContext) error {
+	takePriorThinking(req, func(msg map[string]interface{}, thinking string) {
+		content, _ := msg["content"].(string)
+		msg["content"] = "<think>" + thinking + "</think>\n" + content
+	})
 	return nil
 }
 
@@ -235,7 +240,14 @@ func (t *thinkTagTransform) appendThinkingChunks(chunks [][]byte, content string
 		return chunks, nil
 	}
 
-	// No close tag — emit all as thinking
+	// No close tag — hold back a partial one, emit the rest as thinking
+	if partial := partialTag(content, "</think>"); partial != "" {
+		t.tagBuffer = partial
+		content = content[:len(content)-len(partial)]
+		if content == "" {
+			return chunks, nil
+		}
+	}
 	delta["thinking"] = map[string]interface{}{
 		"content": content,
 	}
@@ -248,6 +260,30 @@ func (t *thinkTagTransform) appendThinkingChunks(chunks [][]byte, content string
 	return chunks, nil
 }
 
+// flushStream emits a partial tag still held when the provider stream ends:
+// it was never completed, so it is plain thinking or text.
+func (t *thinkTagTransform) flushStream(ctx *TransformContext) ([][]byte, error) {
+	if t.tagBuffer == "" {
+		return nil, nil
+	}
+	held := t.tagBuffer
+	t.tagBuffer = ""
+	var delta map[string]interface{}
+	if t.state == stateThinking {
+		delta = map[string]interface{}{"thinking": map[string]interface{}{"content": held}}
+	} else {
+		ctx.HasTextContent = true
+		delta = map[string]interface{}{"content": held}
+	}
+	b, err := json.Marshal(map[string]interface{}{
+		"choices": []interface{}{map[string]interface{}{"delta": delta}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal held tag: %w", err)
+	}
+	return [][]byte{b}, nil
+}
+
 func (t *thinkTagTransform) handleThinking(content string, parsed map[string]interface{}, choice, delta map[string]interface{}, ctx *TransformContext) ([][]byte, error) {
 	return t.appendThinkingChunks(nil, content, parsed, choice, delta, ctx)
 }
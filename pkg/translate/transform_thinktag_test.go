@@ -1,7 +1,9 @@
 package translate
 
 import (
+	"bytes"
 	"encoding/json"
+	"strings"
 	"testing"
 )
 
@@ -451,3 +453,78 @@ func TestThinkTagStream_ThinkingOnly(t *testing.T) {
 		t.Error("expected no content chunk when only thinking is present")
 	}
 }
+
+// thinkTagStream runs content deltas, then a bare finish chunk, through a
+// StreamTranslator with extrathinktag and returns the thinking and text the
+// client sees.
+func thinkTagStream(t *testing.T, contents ...string) (thinking, text string) {
+	t.Helper()
+	var chunks []string
+	for _, c := range contents {
+		chunks = append(chunks, chunk("r1", strPtr(c), nil))
+	}
+	chunks = append(chunks, chunk("r1", nil, strPtr("stop")))
+	var buf bytes.Buffer
+	st := NewStreamTranslator("m")
+	st.SetTransformChain(NewTransformChain(newThinkTagTransform()), NewTransformContext("m", "ollama"))
+	if err := st.TranslateStream(strings.NewReader(makeSSE(chunks...)), &buf); err != nil {
+		t.Fatalf("TranslateStream: %v", err)
+	}
+	v := NewSSEValidator(func(msg string) { t.Error(msg) })
+	v.Write(buf.Bytes())
+	v.Close()
+
+	for _, line := range strings.Split(buf.String(), "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		var ev struct {
+			Delta struct {
+				Type     string `json:"type"`
+				Text     string `json:"text"`
+				Thinking string `json:"thinking"`
+			} `json:"delta"`
+		}
+		json.Unmarshal([]byte(data), &ev)
+		switch ev.Delta.Type {
+		case "thinking_delta":
+			thinking += ev.Delta.Thinking
+		case "text_delta":
+			text += ev.Delta.Text
+		}
+	}
+	return thinking, text
+}
+
+func TestThinkTagStream_SplitCloseTag(t *testing.T) {
+	for _, split := range [][]string{
+		{"<think>reason</th", "ink>answer"},
+		{"<think>reason<", "/think>answer"},
+		{"<think>reason", "</", "think", ">", "answer"},
+	} {
+		thinking, text := thinkTagStream(t, split...)
+		if thinking != "reason" || text != "answer" {
+			t.Errorf("%q: thinking %q, text %q; want reason, answer", split, thinking, text)
+		}
+	}
+}
+
+func TestThinkTagStream_FlushesHeldTag(t *testing.T) {
+	// A trailing '<' could start </think> (or <think>); when the stream
+	// ends instead, it is ordinary thinking or text.
+	tests := []struct {
+		contents       []string
+		thinking, text string
+	}{
+		{[]string{"<think>if a <"}, "if a <", ""},
+		{[]string{"<think>x", " </thi"}, "x </thi", ""},
+		{[]string{"compare a <"}, "", "compare a <"},
+	}
+	for _, tc := range tests {
+		thinking, text := thinkTagStream(t, tc.contents...)
+		if thinking != tc.thinking || text != tc.text {
+			t.Errorf("%q: thinking %q, text %q; want %q, %q", tc.contents, thinking, text, tc.thinking, tc.text)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package translate
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// Upstream transforms operate on Anthropic Messages bodies bound for the real
+// API, not on translated OpenAI requests. Only TransformRequest does work;
+// upstream responses are relayed untouched. Each reads its settings from
+// ctx.Params (the upstream: params block in config).
+
+// upstreamStripTools removes tools named in the strip_tools param, along
+// with a tool_choice that forces one of them.
+type upstreamStripTools struct{}
+
+func (u *upstreamStripTools) Name() string { return "upstream:striptools" }
+
+func (u *upstreamStripTools) TransformRequest(req map[string]interface{}, ctx *TransformContext) error {
+	strip := stringSet(ctx.Params["strip_tools"])
+	tools, ok := req["tools"].([]interface{})
+	if !ok || len(strip) == 0 {
+		return nil
+	}
+	kept := tools[:0]
+	for _, t := range tools {
+		if tool, ok := t.(map[string]interface{}); ok {
+			if name, _ := tool["name"].(string); strip[name] {
+				continue
+			}
+		}
+		kept = append(kept, t)
+	}
+	if len(kept) == 0 {
+		delete(req, "tools")
+		delete(req, "tool_choice")
+		return nil
+	}
+	req["tools"] = kept
+	if tc, ok := req["tool_choice"].(map[string]interface{}); ok {
+		if name, _ := tc["name"].(string); strip[name] {
+			delete(req, "tool_choice")
+		}
+	}
+	return nil
+}
+
+func (u *upstreamStripTools) TransformResponse(body []byte, _ *TransformContext) ([]byte, error) {
+	return body, nil
+}
+
+func (u *upstreamStripTools) TransformStreamChunk(data []byte, _ *TransformContext) ([][]byte, error) {
+	return [][]byte{data}, nil
+}
+
+func (u *upstreamStripTools) streamIdentity() {}
+
+// upstreamSystem appends the system_append param to the system prompt. It is
+// appended rather than prepended so Claude Code's cached system prefix stays
+// valid.
+type upstreamSystem struct{}
+
+func (u *upstreamSystem) Name() string { return "upstream:system" }
+
+func (u *upstreamSystem) TransformRequest(req map[string]interface{}, ctx *TransformContext) error {
+	text, _ := ctx.Params["system_append"].(string)
+	if text == "" {
+		return nil
+	}
+	switch sys := req["system"].(type) {
+	case nil:
+		req["system"] = text
+	case string:
+		if sys == "" {
+			req["system"] = text
+		} else {
+			req["system"] = sys + "\n\n" + text
+		}
+	case []interface{}:
+		req["system"] = append(sys, map[string]interface{}{"type": "text", "text": text})
+	default:
+		return fmt.Errorf("upstream:system: unexpected system type %T", sys)
+	}
+	return nil
+}
+
+func (u *upstreamSystem) TransformResponse(body []byte, _ *TransformContext) ([]byte, error) {
+	return body, nil
+}
+
+func (u *upstreamSystem) TransformStreamChunk(data []byte, _ *TransformContext) ([][]byte, error) {
+	return [][]byte{data}, nil
+}
+
+func (u *upstreamSystem) streamIdentity() {}
+
+// upstreamRedact replaces matches of the redact param's regular expressions
+// with [REDACTED] in message content: text, tool results and tool inputs.
+// Patterns are compiled on first use; an invalid pattern fails every request
+// rather than letting unredacted content through.
+type upstreamRedact struct {
+	once     sync.Once
+	patterns []*regexp.Regexp
+	err      error
+}
+
+func (u *upstreamRedact) Name() string { return "upstream:redact" }
+
+func (u *upstreamRedact) TransformRequest(req map[string]interface{}, ctx *TransformContext) error {
+	u.once.Do(func() {
+		list, _ := ctx.Params["redact"].([]interface{})
+		for _, p := range list {
+			s, _ := p.(string)
+			re, err := regexp.Compile(s)
+			if err != nil {
+				u.err = fmt.Errorf("upstream:redact: bad pattern %q: %w", s, err)
+				return
+			}
+			u.patterns = append(u.patterns, re)
+		}
+	})
+	if u.err != nil {
+		return u.err
+	}
+	if len(u.patterns) == 0 {
+		return nil
+	}
+	msgs, _ := req["messages"].([]interface{})
+	for _, m := range msgs {
+		if msg, ok := m.(map[string]interface{}); ok {
+			msg["content"] = u.redact(msg["content"])
+		}
+	}
+	return nil
+}
+
+// redactSkip lists block fields that identify structure rather than carry
+// user content.
+var redactSkip = map[string]bool{
+	"type": true, "id": true, "tool_use_id": true, "name": true, "cache_control": true, "source": true,
+}
+
+// redact walks a content value, rewriting every string it holds. Thinking
+// blocks are left alone since their signatures cover the text.
+func (u *upstreamRedact) redact(v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		for _, re := range u.patterns {
+			val = re.ReplaceAllString(val, "[REDACTED]")
+		}
+		return val
+	case []interface{}:
+		for i, item := range val {
+			val[i] = u.redact(item)
+		}
+	case map[string]interface{}:
+		if t, _ := val["type"].(string); t == "thinking" || t == "redacted_thinking" {
+			return v
+		}
+		for k, inner := range val {
+			if !redactSkip[k] {
+				val[k] = u.redact(inner)
+			}
+		}
+	}
+	return v
+}
+
+func (u *upstreamRedact) TransformResponse(body []byte, _ *TransformContext) ([]byte, error) {
+	return body, nil
+}
+
+func (u *upstreamRedact) TransformStreamChunk(data []byte, _ *TransformContext) ([][]byte, error) {
+	return [][]byte{data}, nil
+}
+
+func (u *upstreamRedact) streamIdentity() {}
+
+// stringSet collects the strings in a YAML list param.
+func stringSet(v interface{}) map[string]bool {
+	list, _ := v.([]interface{})
+	set := make(map[string]bool, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			set[s] = true
+		}
+	}
+	return set
+}
+
+func init() {
+	RegisterTransform("upstream:striptools", func() Transformer { return &upstreamStripTools{} })
+	RegisterTransform("upstream:system", func() Transformer { return &upstreamSystem{} })
+	RegisterTransform("upstream:redact", func() Transformer { return &upstreamRedact{} })
+}
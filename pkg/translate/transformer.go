@@ -0,0 +1,203 @@
+package translate
+
+import "strings"
+
+// TransformContext holds per-request state shared across transforms in a chain.
+type TransformContext struct {
+	// Accumulated reasoning content for thinking-close block.
+	ReasoningContent strings.Builder
+	ReasoningComplete bool
+	HasTextContent    bool
+
+	// ToolCallBuffers accumulates streaming tool call arguments, keyed by tool call index.
+	ToolCallBuffers map[int]*ToolCallBuffer
+
+	// ExitToolIndex tracks the exit tool for tooluse transform (-1 = inactive).
+	ExitToolIndex int
+	ExitToolArgs  strings.Builder
+
+	ModelName    string
+	ProviderName string
+
+	// Params holds custom parameters from config to inject into the request body.
+	Params map[string]interface{}
+
+	// CallLog is optional; used in tests to record transform ordering.
+	CallLog *[]string
+
+	// Stats is optional; when set, the chain counts errors, suppressed
+	// chunks and repairs per transform.
+	Stats *TransformStats
+
+	// ToolIDs is optional; when set, tool call IDs rewritten on the way to
+	// the client are recorded in it.
+	ToolIDs *ToolIDMap
+
+	// FinishReasons is optional; it maps provider finish reasons to
+	// Anthropic stop reasons ahead of the built-in table.
+	FinishReasons map[string]string
+
+	// Signer is optional; when set, it signs the thinking blocks of the
+	// response in place of the transforms' timestamp signatures.
+	Signer *ThinkingSigner
+
+	// ReasoningDisplay limits the thinking passed on to the client.
+	ReasoningDisplay ReasoningDisplay
+
+	// Citations is what becomes of sources the provider cites: one of the
+	// Citations constants ("" = CitationsReferences).
+	Citations string
+}
+
+// ToolCallBuffer accumulates streaming tool call arguments.
+type ToolCallBuffer struct {
+	ID        string
+	Name      string
+	Arguments strings.Builder
+}
+
+// NewTransformContext creates a TransformContext with sensible defaults.
+func NewTransformContext(model, provider string) *TransformContext {
+	return &TransformContext{
+		ToolCallBuffers: make(map[int]*ToolCallBuffer),
+		ExitToolIndex:   -1,
+		ModelName:       model,
+		ProviderName:    provider,
+	}
+}
+
+// Transformer is a composable unit that can transform requests, responses, and stream chunks.
+type Transformer interface {
+	Name() string
+	TransformRequest(req map[string]interface{}, ctx *TransformContext) error
+	TransformResponse(body []byte, ctx *TransformContext) ([]byte, error)
+	TransformStreamChunk(data []byte, ctx *TransformContext) ([][]byte, error)
+}
+
+// toolCallsKey appears in every stream chunk that carries tool call deltas or
+// a tool_calls finish_reason; transforms that only act on those check for it
+// before decoding.
+var toolCallsKey = []byte(`"tool_calls"`)
+
+// streamIdentity is implemented by transforms whose TransformStreamChunk
+// returns its input unchanged, letting the chain skip them per chunk.
+type streamIdentity interface {
+	streamIdentity()
+}
+
+// streamFlusher is implemented by transforms that hold stream chunks back;
+// the chain asks them for what they still hold when the provider stream ends.
+type streamFlusher interface {
+	flushStream(ctx *TransformContext) ([][]byte, error)
+}
+
+// TransformChain applies a sequence of Transformers.
+// Requests are processed in forward order; responses and stream chunks in reverse order.
+type TransformChain struct {
+	transforms []Transformer
+	streamers  []Transformer // transforms that act on stream chunks
+}
+
+// NewTransformChain creates a chain from the given transformers.
+func NewTransformChain(transforms ...Transformer) *TransformChain {
+	c := &TransformChain{transforms: transforms}
+	for _, t := range transforms {
+		if _, ok := t.(streamIdentity); !ok {
+			c.streamers = append(c.streamers, t)
+		}
+	}
+	return c
+}
+
+// RunRequest applies each transformer's TransformRequest in forward order.
+// Stops on first error.
+func (c *TransformChain) RunRequest(req map[string]interface{}, ctx *TransformContext) error {
+	for _, t := range c.transforms {
+		if err := t.TransformRequest(req, ctx); err != nil {
+			ctx.recordError(t.Name())
+			return err
+		}
+	}
+	return nil
+}
+
+// RunResponse applies each transformer's TransformResponse in reverse order.
+// Stops on first error.
+func (c *TransformChain) RunResponse(body []byte, ctx *TransformContext) ([]byte, error) {
+	var err error
+	for i := len(c.transforms) - 1; i >= 0; i-- {
+		body, err = c.transforms[i].TransformResponse(body, ctx)
+		if err != nil {
+			ctx.recordError(c.transforms[i].Name())
+			return nil, err
+		}
+	}
+	return body, nil
+}
+
+// RunStreamChunk applies each transformer's TransformStreamChunk in reverse order.
+// Each transformer processes all chunks produced by the previous layer.
+// Returns 0 chunks for suppression, 1 for normal, 2+ for expansion.
+func (c *TransformChain) RunStreamChunk(data []byte, ctx *TransformContext) ([][]byte, error) {
+	return c.runStream(len(c.streamers)-1, [][]byte{data}, ctx)
+}
+
+// FlushStream collects the chunks transforms still hold back at the end of
+// the provider stream, each run through the transforms after it.
+func (c *TransformChain) FlushStream(ctx *TransformContext) ([][]byte, error) {
+	var out [][]byte
+	for i := len(c.streamers) - 1; i >= 0; i-- {
+		f, ok := c.streamers[i].(streamFlusher)
+		if !ok {
+			continue
+		}
+		held, err := f.flushStream(ctx)
+		if err != nil {
+			ctx.recordError(c.streamers[i].Name())
+			return nil, err
+		}
+		if len(held) == 0 {
+			continue
+		}
+		held, err = c.runStream(i-1, held, ctx)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, held...)
+	}
+	return out, nil
+}
+
+// runStream passes chunks through streamers[from] down to streamers[0].
+func (c *TransformChain) runStream(from int, chunks [][]byte, ctx *TransformContext) ([][]byte, error) {
+	for i := from; i >= 0; i-- {
+		if len(chunks) == 1 {
+			// Common case: hand the transform's result straight through.
+			result, err := c.streamers[i].TransformStreamChunk(chunks[0], ctx)
+			if err != nil {
+				ctx.recordError(c.streamers[i].Name())
+				return nil, err
+			}
+			if len(result) == 0 {
+				ctx.recordSuppressed(c.streamers[i].Name())
+			}
+			chunks = result
+			continue
+		}
+		var next [][]byte
+		for _, chunk := range chunks {
+			result, err := c.streamers[i].TransformStreamChunk(chunk, ctx)
+			if err != nil {
+				ctx.recordError(c.streamers[i].Name())
+				return nil, err
+			}
+			if len(result) == 0 {
+				ctx.recordSuppressed(c.streamers[i].Name())
+			}
+			next = append(next, result...)
+		}
+		chunks = next
+	}
+
+	return chunks, nil
+}
@@ -0,0 +1,191 @@
+package translate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// SSEValidator checks that an Anthropic SSE stream written to it follows the
+// Messages API's event order: message_start first, content blocks opened
+// and closed one at a time with contiguous indexes, deltas matching their
+// block's type, then one message_delta and message_stop. Each violation is
+// passed to report as it is found. It is a debugging aid: writes never
+// fail, whatever they contain.
+type SSEValidator struct {
+	report     func(violation string)
+	buf        []byte
+	violations int
+
+	started   bool   // message_start seen
+	open      string // type of the open block ("" = none)
+	openIndex int
+	next      int  // index the next content_block_start must use
+	delta     bool // message_delta seen
+	stopped   bool // message_stop or error seen
+}
+
+// NewSSEValidator returns a validator reporting violations to report.
+func NewSSEValidator(report func(violation string)) *SSEValidator {
+	return &SSEValidator{report: report}
+}
+
+// deltaBlock is the block type each content_block_delta type belongs to.
+var deltaBlock = map[string]string{
+	"text_delta":       "text",
+	"citations_delta":  "text",
+	"input_json_delta": "tool_use",
+	"thinking_delta":   "thinking",
+	"signature_delta":  "thinking",
+}
+
+// Write checks every complete event in p.
+func (v *SSEValidator) Write(p []byte) (int, error) {
+	v.buf = append(v.buf, p...)
+	for {
+		i := bytes.Index(v.buf, []byte("\n\n"))
+		if i < 0 {
+			break
+		}
+		v.event(v.buf[:i])
+		v.buf = v.buf[i+2:]
+	}
+	return len(p), nil
+}
+
+// Close reports a stream that ended without finishing its message.
+func (v *SSEValidator) Close() {
+	if len(bytes.TrimSpace(v.buf)) > 0 {
+		v.fail("stream ends inside an event: %q", v.buf)
+	}
+	if v.started && !v.stopped {
+		v.fail("stream ends without message_stop")
+	}
+}
+
+// Violations returns the number of violations reported so far.
+func (v *SSEValidator) Violations() int {
+	return v.violations
+}
+
+func (v *SSEValidator) fail(format string, args ...interface{}) {
+	v.violations++
+	if v.report != nil {
+		v.report(fmt.Sprintf(format, args...))
+	}
+}
+
+func (v *SSEValidator) event(raw []byte) {
+	var name string
+	var data []byte
+	for _, line := range bytes.Split(raw, []byte("\n")) {
+		switch {
+		case len(line) == 0 || line[0] == ':':
+		case bytes.HasPrefix(line, []byte("event:")):
+			name = string(bytes.TrimSpace(line[len("event:"):]))
+		case bytes.HasPrefix(line, []byte("data:")):
+			data = append(data, bytes.TrimSpace(line[len("data:"):])...)
+		default:
+			v.fail("unexpected line %q", line)
+		}
+	}
+	if data == nil {
+		if name != "" {
+			v.fail("event %s has no data", name)
+		}
+		return
+	}
+	var ev struct {
+		Type  string `json:"type"`
+		Index *int   `json:"index"`
+		Block struct {
+			Type string `json:"type"`
+		} `json:"content_block"`
+		Delta struct {
+			Type string `json:"type"`
+		} `json:"delta"`
+	}
+	if err := json.Unmarshal(data, &ev); err != nil {
+		v.fail("event %s: invalid JSON: %v", name, err)
+		return
+	}
+	if name != "" && name != ev.Type {
+		v.fail("event name %s does not match data type %s", name, ev.Type)
+	}
+	if v.stopped {
+		v.fail("%s after the message ended", ev.Type)
+		return
+	}
+	if !v.started && ev.Type != "message_start" && ev.Type != "error" {
+		v.fail("%s before message_start", ev.Type)
+	}
+
+	switch ev.Type {
+	case "message_start":
+		if v.started {
+			v.fail("second message_start")
+		}
+		v.started = true
+	case "ping":
+	case "content_block_start":
+		switch {
+		case v.delta:
+			v.fail("content_block_start after message_delta")
+		case v.open != "":
+			v.fail("content_block_start while block %d is open", v.openIndex)
+		}
+		if ev.Index == nil || *ev.Index != v.next {
+			v.fail("content_block_start index %s, want %d", fmtIndex(ev.Index), v.next)
+		}
+		if ev.Index != nil {
+			v.next = *ev.Index + 1
+			v.openIndex = *ev.Index
+		}
+		v.open = ev.Block.Type
+		if v.open == "" {
+			v.fail("content_block_start without a block type")
+			v.open = "?"
+		}
+	case "content_block_delta":
+		switch {
+		case v.open == "":
+			v.fail("%s with no open block", ev.Delta.Type)
+		case ev.Index == nil || *ev.Index != v.openIndex:
+			v.fail("content_block_delta index %s, open block is %d", fmtIndex(ev.Index), v.openIndex)
+		case deltaBlock[ev.Delta.Type] != v.open && v.open != "?":
+			v.fail("%s in a %s block", ev.Delta.Type, v.open)
+		}
+	case "content_block_stop":
+		switch {
+		case v.open == "":
+			v.fail("content_block_stop with no open block")
+		case ev.Index == nil || *ev.Index != v.openIndex:
+			v.fail("content_block_stop index %s, open block is %d", fmtIndex(ev.Index), v.openIndex)
+		}
+		v.open = ""
+	case "message_delta":
+		if v.open != "" {
+			v.fail("message_delta while block %d is open", v.openIndex)
+		}
+		if v.delta {
+			v.fail("second message_delta")
+		}
+		v.delta = true
+	case "message_stop":
+		if !v.delta {
+			v.fail("message_stop without message_delta")
+		}
+		v.stopped = true
+	case "error":
+		v.stopped = true
+	default:
+		v.fail("unknown event type %q", ev.Type)
+	}
+}
+
+func fmtIndex(i *int) string {
+	if i == nil {
+		return "missing"
+	}
+	return fmt.Sprint(*i)
+}
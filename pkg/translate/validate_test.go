@@ -0,0 +1,66 @@
+package translate
+
+import (
+	"strings"
+	"testing"
+)
+
+func sseEvents(events ...string) string {
+	var b strings.Builder
+	for _, e := range events {
+		typ := e[strings.Index(e, `"type":"`)+8:]
+		typ = typ[:strings.Index(typ, `"`)]
+		b.WriteString("event: " + typ + "\ndata: " + e + "\n\n")
+	}
+	return b.String()
+}
+
+const (
+	evStart      = `{"type":"message_start","message":{}}`
+	evTextStart0 = `{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`
+	evTextDelta0 = `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"hi"}}`
+	evStop0      = `{"type":"content_block_stop","index":0}`
+	evToolStart1 = `{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"t","name":"x","input":{}}}`
+	evMsgDelta   = `{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":1}}`
+	evMsgStop    = `{"type":"message_stop"}`
+)
+
+func TestSSEValidator(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   string // substring of the first violation ("" = valid)
+	}{
+		{"valid", sseEvents(evStart, evTextStart0, evTextDelta0, evStop0, evMsgDelta, evMsgStop), ""},
+		{"ping and comment", sseEvents(evStart, `{"type":"ping"}`, evMsgDelta, evMsgStop) + ": note\n\n", ""},
+		{"block after message_delta", sseEvents(evStart, evMsgDelta, evTextStart0, evStop0, evMsgStop), "after message_delta"},
+		{"index gap", sseEvents(evStart, evToolStart1), "index 1, want 0"},
+		{"delta type", sseEvents(evStart, evTextStart0,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{"}}`), "input_json_delta in a text block"},
+		{"open block at end", sseEvents(evStart, evTextStart0, evMsgDelta), "while block 0 is open"},
+		{"no message_start", sseEvents(evTextStart0), "before message_start"},
+		{"unfinished", sseEvents(evStart, evTextStart0, evTextDelta0, evStop0), "without message_stop"},
+		{"after stop", sseEvents(evStart, evMsgDelta, evMsgStop, evMsgStop), "after the message ended"},
+		{"error ends stream", sseEvents(evStart, `{"type":"error","error":{"type":"api_error"}}`), ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			v := NewSSEValidator(func(s string) { got = append(got, s) })
+			// Split writes must not matter.
+			for i := 0; i < len(tc.stream); i += 7 {
+				v.Write([]byte(tc.stream[i:min(i+7, len(tc.stream))]))
+			}
+			v.Close()
+			switch {
+			case tc.want == "" && len(got) > 0:
+				t.Errorf("unexpected violations: %q", got)
+			case tc.want != "" && (len(got) == 0 || !strings.Contains(got[0], tc.want)):
+				t.Errorf("violations %q, want one containing %q", got, tc.want)
+			}
+			if v.Violations() != len(got) {
+				t.Errorf("Violations() = %d, reported %d", v.Violations(), len(got))
+			}
+		})
+	}
+}